@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,8 +13,14 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/diode"
 	"github.com/spf13/cobra"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
 	"github.com/tomarrell/lbadd/internal/executor"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/profile"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
 	"github.com/tomarrell/lbadd/internal/master"
+	"github.com/tomarrell/lbadd/internal/parser"
 	"github.com/tomarrell/lbadd/internal/worker"
 )
 
@@ -50,6 +58,12 @@ var (
 	verbose bool
 	logfile string
 	addr    string
+
+	memoryLimit      string
+	queryMemoryLimit string
+	tempDir          string
+
+	explainJSON bool
 )
 
 // documentation strings
@@ -72,6 +86,12 @@ waiting for incoming connections from lbadd worker nodes.`
 	startWorkerCmdLongDoc  = `Start a worker node and connect it to the address that is specified
 in the addr flag. This will start an lbadd worker node, that
 connects to a already running master node on the given address.`
+
+	explainCmdShortDoc = "Run a statement on a database file, and print its operator statistics"
+	explainCmdLongDoc  = `Run a statement with EXPLAIN ANALYZE on the database in the given file,
+and print the actual rows, loops, time, memory and spill of every operator
+of its plan as a tree, or as JSON with the json flag. Like EXPLAIN ANALYZE,
+the statement is executed, and its changes are kept.`
 )
 
 var (
@@ -112,19 +132,32 @@ var (
 		Run:   startWorker,
 		Args:  cobra.NoArgs,
 	}
+
+	explainCmd = &cobra.Command{
+		Use:   "explain <database file> <statement>",
+		Short: explainCmdShortDoc,
+		Long:  explainCmdLongDoc,
+		Run:   explain,
+		Args:  cobra.ExactArgs(2),
+	}
 )
 
 func init() {
-	rootCmd.AddCommand(startCmd, versionCmd)
+	rootCmd.AddCommand(startCmd, versionCmd, explainCmd)
 	startCmd.AddCommand(startMasterCmd, startWorkerCmd)
 
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "print more logs")
 
 	startCmd.PersistentFlags().StringVar(&logfile, "logfile", "lbadd.log", "define a log file to write logs to")
+	startCmd.PersistentFlags().StringVar(&memoryLimit, "memory-limit", "0", "limit the memory of all queries together, like 1GB (0 means unlimited)")
+	startCmd.PersistentFlags().StringVar(&queryMemoryLimit, "query-memory-limit", "64MB", "limit the memory of a single query, before it spills to disk (0 means unlimited)")
+	startCmd.PersistentFlags().StringVar(&tempDir, "temp-dir", "", "write spilled query data to this directory (default is the temporary directory of the system)")
 
 	startMasterCmd.PersistentFlags().StringVar(&addr, "addr", ":34213", "serve the database on this address")
 
 	startWorkerCmd.PersistentFlags().StringVar(&addr, "addr", ":34213", "connect to a master node on this address")
+
+	explainCmd.Flags().BoolVar(&explainJSON, "json", false, "print the operators as JSON")
 }
 
 func main() {
@@ -169,6 +202,7 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	ctx = context.WithValue(ctx, ctxKeyStdout, stdout)
 	ctx = context.WithValue(ctx, ctxKeyStderr, stderr)
 
+	rootCmd.SetArgs(args[1:])
 	return rootCmd.ExecuteContext(ctx)
 }
 
@@ -213,6 +247,66 @@ func startWorker(cmd *cobra.Command, args []string) {
 	}
 }
 
+func explain(cmd *cobra.Command, args []string) {
+	log := cmd.Context().Value(ctxKeyLog).(zerolog.Logger)
+	stdout := cmd.Context().Value(ctxKeyStdout).(io.Writer)
+
+	operators, err := explainAnalyze(cmd.Context(), log, args[0], args[1])
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("explain analyze")
+		os.Exit(ExitAbnormal)
+	}
+
+	if explainJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(operators)
+	} else {
+		_, err = fmt.Fprint(stdout, operators)
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("print operators")
+		os.Exit(ExitAbnormal)
+	}
+}
+
+// explainAnalyze runs the given statement with EXPLAIN ANALYZE on the database
+// in the given file, and returns its measured operators. The statement may
+// start with EXPLAIN ANALYZE.
+func explainAnalyze(ctx context.Context, log zerolog.Logger, file, query string) (*profile.Operator, error) {
+	stmt, errs, ok := parser.New(query).Next()
+	if !ok {
+		return nil, errors.New("no statement")
+	}
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	c, err := command.From(stmt)
+	if err != nil {
+		return nil, err
+	}
+	c.SQL = query
+	c.Explain = command.ExplainAnalyze
+
+	session, err := attach.Open(wal.OS, file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = session.Close() }()
+
+	exec := executor.NewSession(log.With().Str("component", "executor").Logger(), session)
+	result, err := exec.Execute(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	operators, _ := executor.Profile(result)
+	return operators, nil
+}
+
 func createLogger(stdin io.Reader, stdout, stderr io.Writer) zerolog.Logger {
 	// open the log file
 	file, err := os.OpenFile(logfile, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0600)
@@ -257,6 +351,21 @@ func createExecutor(log zerolog.Logger) executor.Executor {
 		Str("component", "executor").
 		Logger()
 
-	exec := executor.New(execLog)
+	memory, err := spill.ParseSize(memoryLimit)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("parse memory limit")
+		os.Exit(ExitAbnormal)
+	}
+	queryMemory, err := spill.ParseSize(queryMemoryLimit)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("parse query memory limit")
+		os.Exit(ExitAbnormal)
+	}
+
+	exec := executor.New(execLog, executor.WithMemory(spill.NewPool(memory, queryMemory, tempDir)))
 	return exec
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/driver"
+)
+
+func TestExplain(t *testing.T) {
+	logfile = filepath.Join(t.TempDir(), "lbadd.log")
+	file := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("lbadd", driver.DataSourcePrefix+file)
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, grp INTEGER)",
+		"INSERT INTO t WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 100) SELECT cnt.n, cnt.n % 10 FROM cnt",
+	} {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+	require.NoError(t, db.Close())
+
+	var stdout, stderr bytes.Buffer
+	err = run([]string{ApplicationName, "explain", "--json", file, "SELECT t.grp, count(*) FROM t WHERE t.id > 50 GROUP BY t.grp"}, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Empty(t, stderr.String())
+
+	type operator struct {
+		Detail     string     `json:"detail"`
+		ActualRows int64      `json:"actual_rows"`
+		Loops      int64      `json:"loops"`
+		TimeMS     float64    `json:"time_ms"`
+		Children   []operator `json:"children"`
+	}
+	var root operator
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &root), stdout.String())
+	assert.Equal(t, "PROJECT t.grp, count(*)", root.Detail)
+	assert.Equal(t, int64(10), root.ActualRows)
+	assert.Equal(t, int64(1), root.Loops)
+	assert.Greater(t, root.TimeMS, 0.0)
+
+	var details []string
+	var rows []int64
+	for op := &root; ; op = &op.Children[0] {
+		details = append(details, op.Detail)
+		rows = append(rows, op.ActualRows)
+		if len(op.Children) == 0 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"PROJECT t.grp, count(*)", "AGGREGATE count(*) GROUP BY t.grp", "FILTER t.id > 50", "SCAN t (grp, id)"}, details)
+	assert.Equal(t, []int64{10, 10, 50, 100}, rows)
+}
@@ -2,8 +2,13 @@ package driver
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
 	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/executor"
 )
 
 var _ driver.Conn = (*Conn)(nil)
@@ -15,6 +20,13 @@ var _ driver.QueryerContext = (*Conn)(nil)
 // Conn represents a connection to the database. It can be used to prepare and
 // execute statements.
 type Conn struct {
+	driver *Driver
+	// file is the path of the database file, that is released when the
+	// connection is closed.
+	file    string
+	session *attach.Session
+	exec    executor.Executor
+	closed  bool
 }
 
 // Prepare prepares a statement. The returned Stmt is an SQL prepared statement,
@@ -26,18 +38,33 @@ type Conn struct {
 //  stmt, err := conn.Prepare(`INSERT INTO users VALUES (?)`) // CORRECT
 //  result, err := stmt.Exec("jdoe")
 func (c *Conn) Prepare(query string) (driver.Stmt, error) {
-	stmt, err := parse(query)
+	if c.closed {
+		return nil, ErrConnectionClosed
+	}
+	cmd, err := parse(query)
 	if err != nil {
 		return nil, fmt.Errorf("parse: %w", err)
 	}
-	return stmt, nil
+	return &Stmt{
+		conn: c,
+		cmd:  cmd,
+	}, nil
 }
 
 // Close closes this connection. If a connection is closed, it cannot be used as
 // idle connection in the connection pool and a new connection needs to be
 // established.
 func (c *Conn) Close() error {
-	return nil // TODO(TimSatke): implement
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	err := c.session.Close()
+	if releaseErr := c.driver.release(c.file); err == nil {
+		err = releaseErr
+	}
+	return err
 }
 
 // Begin is deprecated. Use BeginTx instead.
@@ -46,8 +73,39 @@ func (c *Conn) Begin() (driver.Tx, error) {
 }
 
 // BeginTx creates a transaction that can be either committed or rolled back.
+// The isolation levels ReadUncommitted and ReadCommitted are executed as
+// ReadCommitted, RepeatableRead and Snapshot as Snapshot, and Serializable as
+// Serializable. The default isolation level is Serializable.
 func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	return nil, fmt.Errorf("unimplemented") // TODO(TimSatke): implement
+	if c.closed {
+		return nil, ErrConnectionClosed
+	}
+	txOpts, err := txOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.session.Begin(ctx, txOpts); err != nil {
+		return nil, err
+	}
+	return &Tx{conn: c}, nil
+}
+
+// txOptions converts the given driver options to transaction options.
+func txOptions(opts driver.TxOptions) (transaction.Options, error) {
+	txOpts := transaction.Options{
+		ReadOnly: opts.ReadOnly,
+	}
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault, sql.LevelSerializable:
+		txOpts.Isolation = transaction.Serializable
+	case sql.LevelRepeatableRead, sql.LevelSnapshot:
+		txOpts.Isolation = transaction.Snapshot
+	case sql.LevelReadUncommitted, sql.LevelReadCommitted:
+		txOpts.Isolation = transaction.ReadCommitted
+	default:
+		return transaction.Options{}, fmt.Errorf("%w: %v", ErrUnsupportedIsolation, sql.IsolationLevel(opts.Isolation))
+	}
+	return txOpts, nil
 }
 
 // ExecContext executes the given query with the given arguments under the given
@@ -69,7 +127,10 @@ func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.Name
 // Ping pings the database, failing if the connection is closed or the database
 // failed.
 func (c *Conn) Ping(ctx context.Context) error {
-	return nil // TODO(TimSatke): implement
+	if c.closed {
+		return ErrConnectionClosed
+	}
+	return nil
 }
 
 // QueryContext executes the given query with the given arguments under the
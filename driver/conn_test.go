@@ -0,0 +1,152 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+)
+
+func TestTxOptions(t *testing.T) {
+	tests := []struct {
+		level   sql.IsolationLevel
+		want    transaction.Isolation
+		wantErr bool
+	}{
+		{sql.LevelDefault, transaction.Serializable, false},
+		{sql.LevelReadUncommitted, transaction.ReadCommitted, false},
+		{sql.LevelReadCommitted, transaction.ReadCommitted, false},
+		{sql.LevelWriteCommitted, 0, true},
+		{sql.LevelRepeatableRead, transaction.Snapshot, false},
+		{sql.LevelSnapshot, transaction.Snapshot, false},
+		{sql.LevelSerializable, transaction.Serializable, false},
+		{sql.LevelLinearizable, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := txOptions(driver.TxOptions{
+				Isolation: driver.IsolationLevel(tt.level),
+				ReadOnly:  true,
+			})
+			if tt.wantErr {
+				assert.True(errors.Is(err, ErrUnsupportedIsolation))
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, got.Isolation)
+			assert.True(got.ReadOnly)
+		})
+	}
+}
+
+func TestConn_QueryExplain(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	query := func(query string) ([]string, [][]interface{}) {
+		rows, err := conn.QueryContext(ctx, query)
+		require.NoError(t, err)
+		defer func() { _ = rows.Close() }()
+		columns, err := rows.Columns()
+		require.NoError(t, err)
+		var result [][]interface{}
+		for rows.Next() {
+			row := make([]interface{}, len(columns))
+			dest := make([]interface{}, len(columns))
+			for i := range row {
+				dest[i] = &row[i]
+			}
+			require.NoError(t, rows.Scan(dest...))
+			result = append(result, row)
+		}
+		require.NoError(t, rows.Err())
+		return columns, result
+	}
+
+	columns, rows := query("EXPLAIN BEGIN IMMEDIATE")
+	assert.Equal(t, []string{"addr", "opcode", "p1", "p2", "p3", "comment"}, columns)
+	assert.Equal(t, [][]interface{}{
+		{int64(0), "Begin", int64(1), int64(0), "", "IMMEDIATE"},
+		{int64(1), "Halt", int64(0), int64(0), "", ""},
+	}, rows)
+	assert.False(t, active(t, conn), "EXPLAIN must not execute the statement")
+
+	columns, rows = query("EXPLAIN QUERY PLAN SAVEPOINT a")
+	assert.Equal(t, []string{"id", "parent", "detail", "rows", "cost"}, columns)
+	assert.Empty(t, rows)
+	assert.False(t, active(t, conn))
+}
+
+func TestConn_Attach(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	attached := func(name string) bool {
+		var ok bool
+		require.NoError(t, conn.Raw(func(driverConn interface{}) error {
+			_, ok = driverConn.(*Conn).session.Database(name)
+			return nil
+		}))
+		return ok
+	}
+
+	archive := filepath.Join(t.TempDir(), "archive.db")
+	_, err = conn.ExecContext(ctx, "ATTACH DATABASE '"+archive+"' AS archive")
+	require.NoError(t, err)
+	assert.True(t, attached("archive"))
+	_, err = conn.ExecContext(ctx, "ATTACH '"+archive+"' AS other")
+	assert.True(t, errors.Is(err, attach.ErrAlreadyAttached))
+
+	_, err = conn.ExecContext(ctx, "DETACH archive")
+	require.NoError(t, err)
+	assert.False(t, attached("archive"))
+}
+
+func TestConn_Statements(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	_, err := db.ExecContext(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT UNIQUE)")
+	require.NoError(t, err)
+	result, err := db.ExecContext(ctx, "INSERT INTO users (email) VALUES ('a@example.com'), ('b@example.com')")
+	require.NoError(t, err)
+	affected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+	id, err := result.LastInsertId()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO users (email) VALUES ('a@example.com')")
+	var cerr *ConstraintError
+	require.True(t, errors.As(err, &cerr), err)
+	assert.Equal(t, UniqueConstraint, cerr.Kind)
+	assert.Equal(t, ConflictAbort, cerr.Conflict)
+
+	rows, err := db.QueryContext(ctx, "SELECT users.id, users.email FROM users ORDER BY users.id")
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	var emails []string
+	for rows.Next() {
+		var id int64
+		var email string
+		require.NoError(t, rows.Scan(&id, &email))
+		emails = append(emails, email)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"a@example.com", "b@example.com"}, emails)
+}
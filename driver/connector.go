@@ -3,6 +3,12 @@ package driver
 import (
 	"context"
 	"database/sql/driver"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/executor"
 )
 
 var _ driver.Connector = (*Connector)(nil)
@@ -12,13 +18,28 @@ var _ driver.Connector = (*Connector)(nil)
 // used to prepare and execute statements.
 type Connector struct {
 	driver *Driver
+	// file is the path of the database file.
+	file string
 }
 
 // Connect opens a connection to the database that the connector is configured
 // to connect to. The opening of the connection pays respect to deadlines or
 // timeouts configured in the context.
 func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
-	return &Conn{}, nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m, err := c.driver.acquire(c.file)
+	if err != nil {
+		return nil, fmt.Errorf("open %v: %w", c.file, err)
+	}
+	session := attach.New(wal.OS, m)
+	return &Conn{
+		driver:  c.driver,
+		file:    c.file,
+		session: session,
+		exec:    executor.NewSession(zerolog.Nop(), session),
+	}, nil
 }
 
 // Driver returns the underlying driver, that the connector has been created
@@ -1,2 +1,10 @@
 // Package driver implements an SQL driver for an lbadd database.
+//
+// The driver is registered with the name "lbadd". The data source name is
+// the path of a local database file, prefixed with "file:".
+//
+//  db, err := sql.Open("lbadd", "file:/var/lib/lbadd/main.db")
+//
+// All connections to the same file share the database, which is closed when
+// its last connection is closed.
 package driver
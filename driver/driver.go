@@ -5,6 +5,12 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
 )
 
 func init() {
@@ -17,8 +23,23 @@ var _ driver.DriverContext = (*Driver)(nil)
 // Driver is the database driver that can communicate with an lbadd database. It
 // will be registered with the name "lbadd".
 type Driver struct {
+	mu sync.Mutex
+	// databases holds the open database files by their cleaned path. All
+	// connections to a file share its transaction manager.
+	databases map[string]*database
+}
+
+// database is a database file, that is open while there are connections to
+// it.
+type database struct {
+	m    *transaction.Manager
+	refs int
 }
 
+// DataSourcePrefix is the prefix of data source names, that refer to a local
+// database file, like "file:/var/lib/lbadd/main.db".
+const DataSourcePrefix = "file:"
+
 // Open creates a new connector and uses that connector to open a new
 // connection. The context that is used to open the new connection is
 // context.Background().
@@ -38,8 +59,55 @@ func (d *Driver) Open(name string) (driver.Conn, error) {
 // OpenConnector creates a connector that can be used to open a connection to a
 // data source. The data source is specified by the given name. A connector can
 // only open connections to his data source.
+//
+// The name must be DataSourcePrefix, followed by the path of a database file.
+// The file is created when the first connection is opened, if it does not
+// exist.
 func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	if !strings.HasPrefix(name, DataSourcePrefix) || len(name) == len(DataSourcePrefix) {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedDataSource, name)
+	}
 	return &Connector{
 		driver: d,
+		file:   filepath.Clean(strings.TrimPrefix(name, DataSourcePrefix)),
 	}, nil
 }
+
+// acquire opens the given database file, or returns its manager if it is
+// already open. Every call must be followed by a call to release.
+func (d *Driver) acquire(file string) (*transaction.Manager, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if db, ok := d.databases[file]; ok {
+		db.refs++
+		return db.m, nil
+	}
+	m, err := transaction.Open(wal.OS, file)
+	if err != nil {
+		return nil, err
+	}
+	if d.databases == nil {
+		d.databases = make(map[string]*database)
+	}
+	d.databases[file] = &database{m: m, refs: 1}
+	return m, nil
+}
+
+// release closes the given database file, if it was released as often as it
+// was acquired.
+func (d *Driver) release(file string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	db, ok := d.databases[file]
+	if !ok {
+		return nil
+	}
+	db.refs--
+	if db.refs > 0 {
+		return nil
+	}
+	delete(d.databases, file)
+	return db.m.Close()
+}
@@ -1,5 +1,13 @@
 package driver
 
+import (
+	"errors"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+)
+
 // Error provides constant errors to the driver package.
 type Error string
 
@@ -7,5 +15,54 @@ func (e Error) Error() string { return string(e) }
 
 // Constant errors
 const (
-	ErrConnectionClosed = Error("connection is closed")
+	ErrConnectionClosed      = Error("connection is closed")
+	ErrUnsupportedIsolation  = Error("unsupported isolation level")
+	ErrUnsupportedDataSource = Error("unsupported data source name")
+	ErrEmptyQuery            = Error("query does not contain a statement")
+	ErrMultipleStatements    = Error("query contains more than one statement")
+)
+
+// IsRetryable returns whether the given error indicates that a transaction was
+// rolled back because of a concurrent transaction, either because it was
+// chosen as the victim of a deadlock, or because of a serialization conflict.
+// Such a transaction can be retried as a whole.
+func IsRetryable(err error) bool {
+	return errors.Is(err, lock.ErrDeadlock) || errors.Is(err, transaction.ErrConflict)
+}
+
+// ErrConstraint is matched by all errors of statements that violate a
+// constraint, with errors.Is.
+const ErrConstraint = constraint.ErrConstraint
+
+// ConstraintError is the error of a statement that violates a constraint.
+// Use errors.As to inspect it.
+type ConstraintError = constraint.Violation
+
+// ConstraintKind is the kind of a violated constraint.
+type ConstraintKind = constraint.Kind
+
+// Kinds of violated constraints.
+const (
+	NotNullConstraint    = constraint.NotNull
+	UniqueConstraint     = constraint.Unique
+	PrimaryKeyConstraint = constraint.PrimaryKey
+	CheckConstraint      = constraint.Check
+	ForeignKeyConstraint = constraint.ForeignKey
+)
+
+// ConflictResolution is the conflict resolution algorithm, that resolved a
+// violated constraint. It tells the application what happened to the
+// statement and the transaction.
+type ConflictResolution = constraint.Conflict
+
+// Conflict resolution algorithms of a ConstraintError.
+const (
+	// ConflictRollback means that the transaction was rolled back.
+	ConflictRollback = constraint.Rollback
+	// ConflictAbort means that the statement was reverted, but the
+	// transaction remains active.
+	ConflictAbort = constraint.Abort
+	// ConflictFail means that the changes of the statement before the
+	// violation were kept.
+	ConflictFail = constraint.Fail
 )
@@ -0,0 +1,38 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(lock.ErrDeadlock))
+	assert.True(t, IsRetryable(fmt.Errorf("exec: %w", lock.ErrDeadlock)))
+	assert.True(t, IsRetryable(transaction.ErrConflict))
+	assert.False(t, IsRetryable(ErrConnectionClosed))
+	assert.False(t, IsRetryable(nil))
+}
+
+func TestConstraintError(t *testing.T) {
+	var err error = fmt.Errorf("exec: %w", &constraint.Violation{
+		Kind:       constraint.Unique,
+		Table:      "users",
+		Constraint: "users.email",
+		Conflict:   constraint.Abort,
+	})
+
+	assert.True(t, errors.Is(err, ErrConstraint))
+	var cerr *ConstraintError
+	if assert.True(t, errors.As(err, &cerr)) {
+		assert.Equal(t, UniqueConstraint, cerr.Kind)
+		assert.Equal(t, ConflictAbort, cerr.Conflict)
+	}
+	assert.EqualError(t, err, "exec: UNIQUE constraint failed: users.email")
+	assert.False(t, IsRetryable(err))
+}
@@ -0,0 +1,46 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"io"
+
+	"github.com/tomarrell/lbadd/internal/executor"
+)
+
+var _ driver.Rows = (*Rows)(nil)
+
+// Rows is an iterator over the rows of the result of a query.
+type Rows struct {
+	result executor.Result
+	// next is the index of the next row.
+	next int
+}
+
+// Columns returns the names of the columns of the result.
+func (r *Rows) Columns() []string {
+	return r.result.Columns()
+}
+
+// Close closes the rows. Next returns io.EOF after the rows were closed.
+func (r *Rows) Close() error {
+	r.next = len(r.result.Rows())
+	return nil
+}
+
+// Next copies the values of the next row into the given slice, which has one
+// element for every column. If there are no more rows, io.EOF is returned.
+func (r *Rows) Next(dest []driver.Value) error {
+	rows := r.result.Rows()
+	if r.next >= len(rows) {
+		return io.EOF
+	}
+	for i, value := range rows[r.next] {
+		v, err := driver.DefaultParameterConverter.ConvertValue(value)
+		if err != nil {
+			return err
+		}
+		dest[i] = v
+	}
+	r.next++
+	return nil
+}
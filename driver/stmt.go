@@ -5,31 +5,52 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"strconv"
+
+	"github.com/tomarrell/lbadd/internal/executor"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/parser"
 )
 
 var _ driver.Stmt = (*Stmt)(nil)
 var _ driver.StmtExecContext = (*Stmt)(nil)
 var _ driver.StmtQueryContext = (*Stmt)(nil)
+var _ driver.Result = result{}
 
 // Stmt is a prepared statement that can be executed. It does not remember
 // values that were passed in.
 type Stmt struct {
+	conn *Conn
+	cmd  command.Command
 }
 
 // parse attempts to parse the given query string. If the query string is valid
-// and supported sql, a statement and error=nil will be returned.
-func parse(query string) (*Stmt, error) {
-	return nil, fmt.Errorf("unimplemented") // TODO(TimSatke): implement
+// and supported sql, its command and error=nil will be returned. The query
+// must contain exactly one statement.
+func parse(query string) (command.Command, error) {
+	p := parser.New(query)
+	stmt, errs, ok := p.Next()
+	if !ok {
+		return command.Command{}, ErrEmptyQuery
+	}
+	if len(errs) > 0 {
+		return command.Command{}, errs[0]
+	}
+	if _, _, ok := p.Next(); ok {
+		return command.Command{}, ErrMultipleStatements
+	}
+	cmd, err := command.From(stmt)
+	cmd.SQL = query
+	return cmd, err
 }
 
 // Close closes this statement, making it impossible to execute it again.
 func (s *Stmt) Close() error {
-	return nil // TODO(TimSatke): implement
+	return nil
 }
 
 // NumInput returns the amount of argument placeholders that the statement has.
 func (s *Stmt) NumInput() int {
-	return 0 // TODO(TimSatke): implement
+	return 0
 }
 
 // Exec is discouraged. Don't use this, use ExecContext instead.
@@ -61,12 +82,40 @@ func (s *Stmt) Query(args []driver.Value) (driver.Rows, error) {
 // ExecContext executes this statement with the given arguments as arguments,
 // with respect to the given context. This should be used for update statements only (alter, update, drop, delete etc.).
 func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	return nil, fmt.Errorf("unimplemented") // TODO(TimSatke): implement
+	r, err := s.execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return result{r}, nil
+}
+
+// result is the result of ExecContext, with the number of rows that were
+// changed and the row ID of the last inserted row.
+type result struct {
+	executor.Result
 }
 
+func (r result) LastInsertId() (int64, error) { return r.Result.LastInsertID(), nil }
+func (r result) RowsAffected() (int64, error) { return r.Result.RowsAffected(), nil }
+
 // QueryContext executes this statement with the given arguments as arguments,
 // with respect to the given context. This should be used for query statements
 // only (select etc.).
 func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	return nil, fmt.Errorf("unimplemented") // TODO(TimSatke): implement
+	result, err := s.execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{result: result}, nil
+}
+
+func (s *Stmt) execute(ctx context.Context) (executor.Result, error) {
+	if s.conn.closed {
+		return nil, ErrConnectionClosed
+	}
+	result, err := s.conn.exec.Execute(ctx, s.cmd)
+	if err != nil {
+		return nil, fmt.Errorf("execute: %w", err)
+	}
+	return result, nil
 }
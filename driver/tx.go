@@ -0,0 +1,38 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/tomarrell/lbadd/internal/executor/command"
+)
+
+var _ driver.Tx = (*Tx)(nil)
+
+// Tx is a transaction of a connection. It spans all databases, that the
+// connection accesses until it is committed or rolled back.
+type Tx struct {
+	conn *Conn
+}
+
+// Commit commits the transaction. Like COMMIT, it fails if deferred foreign
+// keys are violated, in which case the transaction is rolled back, since
+// database/sql cannot use it anymore.
+func (tx *Tx) Commit() error {
+	_, err := tx.conn.exec.Execute(context.Background(), command.Command{Op: command.Commit})
+	if err != nil && tx.conn.session.Tx() != nil {
+		_ = tx.Rollback()
+	}
+	return err
+}
+
+// Rollback rolls back the transaction. It does nothing, if the transaction
+// was already rolled back, because it was the victim of a deadlock, or a
+// violated constraint was resolved with ROLLBACK.
+func (tx *Tx) Rollback() error {
+	if tx.conn.session.Tx() == nil {
+		return nil
+	}
+	_, err := tx.conn.exec.Execute(context.Background(), command.Command{Op: command.Rollback})
+	return err
+}
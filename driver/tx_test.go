@@ -0,0 +1,294 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("lbadd", DataSourcePrefix+filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// onMain returns the transaction on the main database, that the given
+// connection has begun.
+func onMain(t *testing.T, conn *sql.Conn) *transaction.Tx {
+	var tx *transaction.Tx
+	require.NoError(t, conn.Raw(func(driverConn interface{}) error {
+		active := driverConn.(*Conn).session.Tx()
+		if active == nil {
+			return errors.New("no active transaction")
+		}
+		var err error
+		tx, err = active.On(attach.Main)
+		return err
+	}))
+	return tx
+}
+
+// active returns whether the given connection has an active transaction.
+func active(t *testing.T, conn *sql.Conn) bool {
+	var active bool
+	require.NoError(t, conn.Raw(func(driverConn interface{}) error {
+		active = driverConn.(*Conn).session.Tx() != nil
+		return nil
+	}))
+	return active
+}
+
+func TestOpenConnector(t *testing.T) {
+	_, err := sql.Open("lbadd", "localhost:57263")
+	assert.True(t, errors.Is(err, ErrUnsupportedDataSource))
+	_, err = sql.Open("lbadd", DataSourcePrefix)
+	assert.True(t, errors.Is(err, ErrUnsupportedDataSource))
+}
+
+func TestConn_BeginTx(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	// commit a value with a serializable transaction
+	c1, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = c1.Close() }()
+	tx, err := c1.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	require.NoError(t, err)
+	require.NoError(t, onMain(t, c1).Put([]byte("k"), []byte("1")))
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, sql.ErrTxDone, tx.Commit())
+
+	// a serializable transaction fails, if a key that it read was modified
+	// concurrently
+	c2, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = c2.Close() }()
+	readers := []struct {
+		level    sql.IsolationLevel
+		conflict bool
+	}{
+		{sql.LevelSerializable, true},
+		{sql.LevelSnapshot, false},
+	}
+	for _, reader := range readers {
+		tx1, err := c1.BeginTx(ctx, &sql.TxOptions{Isolation: reader.level})
+		require.NoError(t, err)
+		main := onMain(t, c1)
+		value, _, err := main.Get([]byte("k"))
+		require.NoError(t, err)
+		require.NoError(t, main.Put([]byte("copy"), value))
+
+		tx2, err := c2.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		require.NoError(t, onMain(t, c2).Put([]byte("k"), []byte("2")))
+		require.NoError(t, tx2.Commit())
+
+		err = tx1.Commit()
+		assert.Equal(t, reader.conflict, errors.Is(err, transaction.ErrConflict), "%v: %v", reader.level, err)
+	}
+
+	// read-only transactions cannot write
+	tx, err = c1.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, transaction.ErrReadOnly, onMain(t, c1).Put([]byte("k"), []byte("3")))
+	require.NoError(t, tx.Rollback())
+
+	_, err = c1.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelLinearizable})
+	assert.True(t, errors.Is(err, ErrUnsupportedIsolation))
+}
+
+func TestTransactionStatements(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	exec := func(query string) error {
+		_, err := conn.ExecContext(ctx, query)
+		return err
+	}
+	get := func() string {
+		value, _, err := onMain(t, conn).Get([]byte("k"))
+		require.NoError(t, err)
+		return string(value)
+	}
+	put := func(value string) {
+		require.NoError(t, onMain(t, conn).Put([]byte("k"), []byte(value)))
+	}
+
+	require.NoError(t, exec("BEGIN IMMEDIATE TRANSACTION"))
+	assert.True(t, active(t, conn))
+	put("1")
+	require.NoError(t, exec("SAVEPOINT a"))
+	put("2")
+	require.NoError(t, exec("ROLLBACK TO a"))
+	assert.Equal(t, "1", get())
+	require.NoError(t, exec("RELEASE SAVEPOINT a"))
+	require.NoError(t, exec("COMMIT"))
+	assert.False(t, active(t, conn))
+
+	// a savepoint outside of a transaction begins one, which is committed
+	// when the savepoint is released
+	require.NoError(t, exec("SAVEPOINT b"))
+	assert.True(t, active(t, conn))
+	put("3")
+	require.NoError(t, exec("RELEASE b"))
+	assert.False(t, active(t, conn))
+
+	require.NoError(t, exec("BEGIN"))
+	put("4")
+	require.NoError(t, exec("ROLLBACK"))
+	assert.False(t, active(t, conn))
+
+	// another connection sees the committed value
+	other, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+	tx, err := other.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	value, _, err := onMain(t, other).Get([]byte("k"))
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(value))
+	require.NoError(t, tx.Rollback())
+
+	// statements without rows return no rows
+	rows, err := conn.QueryContext(ctx, "BEGIN")
+	require.NoError(t, err)
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	assert.Empty(t, columns)
+	assert.False(t, rows.Next())
+	require.NoError(t, rows.Close())
+	require.NoError(t, exec("END"))
+
+	assert.True(t, errors.Is(exec("COMMIT"), attach.ErrNoTx))
+	assert.True(t, errors.Is(exec("RELEASE a"), transaction.ErrNoSavepoint))
+	assert.True(t, errors.Is(exec("BEGIN; COMMIT"), ErrMultipleStatements))
+	assert.True(t, errors.Is(exec("DETACH archive"), attach.ErrNoSuchDatabase))
+	assert.True(t, errors.Is(exec("VACUUM"), command.ErrUnsupported))
+	assert.True(t, errors.Is(exec("BEGIN TRANSACTION TRANSACTION"), parser.ErrUnexpectedToken))
+	assert.False(t, active(t, conn))
+}
+
+func TestBeginImmediateBusy(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	c1, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = c1.Close() }()
+	c2, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() { _ = c2.Close() }()
+
+	_, err = c1.ExecContext(ctx, "BEGIN IMMEDIATE")
+	require.NoError(t, err)
+
+	// the second immediate transaction waits for the reserved lock until
+	// its deadline
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = c2.ExecContext(timeout, "BEGIN IMMEDIATE")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	assert.False(t, active(t, c2))
+
+	_, err = c1.ExecContext(ctx, "COMMIT")
+	require.NoError(t, err)
+	_, err = c2.ExecContext(ctx, "BEGIN EXCLUSIVE")
+	require.NoError(t, err)
+	_, err = c2.ExecContext(ctx, "ROLLBACK")
+	require.NoError(t, err)
+}
+
+func TestDeadlock(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	_, err := db.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO t VALUES (1, 1), (2, 2)")
+	require.NoError(t, err)
+
+	update := func(tx *sql.Tx, id int) error {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE t SET v = t.v + 10 WHERE t.id = %d", id))
+		return err
+	}
+	tx1, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	tx2, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, update(tx1, 1))
+	require.NoError(t, update(tx2, 2))
+
+	// each transaction waits for the row, that the other one has updated,
+	// and the younger one is rolled back
+	updated := make(chan error)
+	go func() { updated <- update(tx1, 2) }()
+	err = update(tx2, 1)
+	assert.True(t, errors.Is(err, lock.ErrDeadlock), "%v", err)
+	assert.True(t, IsRetryable(err))
+	assert.NoError(t, tx2.Rollback())
+	require.NoError(t, <-updated)
+	require.NoError(t, tx1.Commit())
+
+	// the rolled back transaction is retried
+	tx2, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, update(tx2, 2))
+	require.NoError(t, update(tx2, 1))
+	require.NoError(t, tx2.Commit())
+
+	var v1, v2 int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT a.v, b.v FROM t AS a, t AS b WHERE a.id = 1 AND b.id = 2").Scan(&v1, &v2))
+	assert.Equal(t, 21, v1)
+	assert.Equal(t, 22, v2)
+}
+
+func TestLockTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	_, err := db.ExecContext(ctx, "CREATE TABLE t (id INTEGER PRIMARY KEY, v INTEGER)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO t VALUES (1, 1)")
+	require.NoError(t, err)
+
+	tx1, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx1.ExecContext(ctx, "UPDATE t SET v = 2 WHERE t.id = 1")
+	require.NoError(t, err)
+
+	// the statement waits for the row until the deadline of its context,
+	// and the transaction remains active
+	tx2, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = tx2.ExecContext(timeout, "DELETE FROM t WHERE t.id = 1")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	var count int
+	require.NoError(t, tx2.QueryRowContext(ctx, "SELECT count(*) FROM t").Scan(&count))
+	assert.Equal(t, 1, count)
+	require.NoError(t, tx2.Rollback())
+
+	// DROP TABLE waits for the transactions, that write the rows of the
+	// table
+	timeout, cancel = context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = db.ExecContext(timeout, "DROP TABLE t")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	require.NoError(t, tx1.Commit())
+	_, err = db.ExecContext(ctx, "DROP TABLE t")
+	require.NoError(t, err)
+}
@@ -1,15 +1,24 @@
 module github.com/tomarrell/lbadd
 
-go 1.13
+go 1.18
 
 require (
 	github.com/google/go-cmp v0.4.0
-	github.com/kr/pretty v0.2.0 // indirect
 	github.com/rs/zerolog v1.18.0
 	github.com/spf13/cobra v0.0.6
 	github.com/stretchr/testify v1.4.0
-	golang.org/x/text v0.3.2
-	golang.org/x/tools v0.0.0-20190828213141-aed303cbaa74
+	golang.org/x/text v0.3.7
+	golang.org/x/tools v0.1.12
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/kr/pretty v0.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.3 // indirect
+	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4 // indirect
+	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 	gopkg.in/yaml.v2 v2.2.8 // indirect
 )
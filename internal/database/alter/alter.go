@@ -0,0 +1,352 @@
+package alter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+)
+
+// RenameTable renames the table with the given name, as in ALTER TABLE name
+// RENAME TO newName. The views and triggers that refer to the table, and the
+// foreign keys that reference it, are changed to the new name.
+func (s *Schema) RenameTable(name, newName string) error {
+	t, ok := s.Table(name)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchTable, name)
+	}
+	if !strings.EqualFold(name, newName) {
+		if err := s.available(newName); err != nil {
+			return err
+		}
+	}
+	if err := s.views.RenameTable(t.def.Name, newName, s.columns); err != nil {
+		return err
+	}
+	s.triggers.RenameTable(t.def.Name, newName)
+	if seq, ok := s.seq.Get(t.def.Name); ok {
+		s.seq.Drop(t.def.Name)
+		s.seq.Set(newName, seq)
+	}
+
+	old := t.def.Name
+	t.def.Name = newName
+	return s.rebuildReferencing(old, t, func(fk *constraint.ForeignKeyConstraint) {
+		fk.Parent = newName
+	})
+}
+
+// RenameColumn renames a column of the table with the given name, as in
+// ALTER TABLE table RENAME COLUMN column TO newName. The views and triggers
+// that refer to the column, and the foreign keys that reference it, are
+// changed to the new name.
+func (s *Schema) RenameColumn(table, column, newName string) error {
+	t, ok := s.Table(table)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchTable, table)
+	}
+	i := t.column(column)
+	if i < 0 {
+		return fmt.Errorf("%w: %v.%v", ErrNoSuchColumn, t.def.Name, column)
+	}
+	if j := t.column(newName); j >= 0 && j != i {
+		return fmt.Errorf("%w: %v", ErrColumnExists, newName)
+	}
+	column = t.def.Columns[i].Name
+
+	if err := s.views.RenameColumn(t.def.Name, column, newName, s.columns); err != nil {
+		return err
+	}
+	s.triggers.RenameColumn(t.def.Name, column, newName)
+
+	t.def.Columns = append([]constraint.Column{}, t.def.Columns...)
+	t.def.Columns[i].Name = newName
+	return s.rebuildReferencing(t.def.Name, t, func(fk *constraint.ForeignKeyConstraint) {
+		for j, parent := range fk.ParentColumns {
+			if strings.EqualFold(parent, column) {
+				fk.ParentColumns[j] = newName
+			}
+		}
+	})
+}
+
+// AddColumn adds the given column to the end of the columns of the table with
+// the given name, as in ALTER TABLE table ADD COLUMN. The stored rows are not
+// changed, they get the default value of the column when they are read. The
+// default value must therefore be constant.
+//
+// Like in SQLite, the column cannot be an INTEGER PRIMARY KEY, and a NOT NULL
+// column must have a default value other than NULL.
+func (s *Schema) AddColumn(table string, col constraint.Column) error {
+	t, ok := s.Table(table)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchTable, table)
+	}
+	if t.column(col.Name) >= 0 {
+		return fmt.Errorf("%w: %v", ErrColumnExists, col.Name)
+	}
+	if col.RowID || col.Autoincrement {
+		return fmt.Errorf("%w: cannot add a PRIMARY KEY column", ErrCannotAdd)
+	}
+	if col.NotNull {
+		var value constraint.Value
+		if col.Default != nil {
+			var err error
+			if value, err = col.Default(); err != nil {
+				return fmt.Errorf("default of %v: %w", col.Name, err)
+			}
+		}
+		if value == nil {
+			return fmt.Errorf("%w: cannot add a NOT NULL column with default value NULL", ErrCannotAdd)
+		}
+	}
+
+	old := t.def
+	t.def.Columns = append(append([]constraint.Column{}, t.def.Columns...), col)
+	if err := t.build(s); err != nil {
+		t.def = old
+		return err
+	}
+	return nil
+}
+
+// DropColumn removes a column from the table with the given name, as in ALTER
+// TABLE table DROP COLUMN column, and rewrites all rows of the table without
+// it.
+//
+// The column cannot be dropped, if it is the only column, or if it is part of
+// a PRIMARY KEY, a UNIQUE constraint, a foreign key, a CHECK constraint or an
+// index, or if a view or the UPDATE OF clause of a trigger refers to it. The
+// WHEN conditions and programs of the triggers of the table, that get the rows
+// of the table, as well as CHECK constraints and indexes, which may refer to
+// the positions of columns, continue to get rows with the old positions of
+// the columns, with NULL as the value of the dropped column.
+func (s *Schema) DropColumn(table, column string) error {
+	t, ok := s.Table(table)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchTable, table)
+	}
+	i := t.column(column)
+	if i < 0 {
+		return fmt.Errorf("%w: %v.%v", ErrNoSuchColumn, t.def.Name, column)
+	}
+	column = t.def.Columns[i].Name
+	if err := s.droppable(t, i); err != nil {
+		return fmt.Errorf("%w: %v: %v", ErrCannotDrop, column, err)
+	}
+
+	// read all rows before they are changed
+	type entry struct {
+		id  index.RowID
+		row constraint.Row
+	}
+	var entries []entry
+	if err := t.Rows().Scan(func(id index.RowID, row constraint.Row) error {
+		entries = append(entries, entry{id, remove(row, i)})
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	def := t.def
+	def.Columns = removeColumn(def.Columns, i)
+	def.Uniques = nil
+	for _, u := range t.def.Uniques {
+		u.Columns = shift(u.Columns, i)
+		def.Uniques = append(def.Uniques, u)
+	}
+	def.ForeignKeys = nil
+	for _, fk := range t.def.ForeignKeys {
+		fk.Columns = shift(fk.Columns, i)
+		def.ForeignKeys = append(def.ForeignKeys, fk)
+	}
+	def.Checks = nil
+	for _, c := range t.def.Checks {
+		c.Expr = adaptExpr(c.Expr, i)
+		c.Columns = shift(c.Columns, i)
+		def.Checks = append(def.Checks, c)
+	}
+	var defs []Index
+	for _, ix := range t.defs {
+		ix.Definition.Key = adaptKey(ix.Definition.Key, i)
+		if ix.Definition.Where != nil {
+			ix.Definition.Where = adaptWhere(ix.Definition.Where, i)
+		}
+		ix.Columns = shift(ix.Columns, i)
+		defs = append(defs, ix)
+	}
+
+	for _, e := range entries {
+		if err := t.stored.Put(e.id, e.row); err != nil {
+			return fmt.Errorf("rewrite row %d: %w", e.id, err)
+		}
+	}
+	for _, tr := range s.triggers.Triggers() {
+		if strings.EqualFold(tr.Table, t.def.Name) {
+			adaptTrigger(tr, i)
+		}
+	}
+	t.def, t.defs = def, defs
+	return t.build(s)
+}
+
+// droppable checks that nothing refers to the column at the given position of
+// the given table.
+func (s *Schema) droppable(t *Table, i int) error {
+	name := t.def.Columns[i].Name
+	switch {
+	case len(t.def.Columns) == 1:
+		return fmt.Errorf("no other columns exist")
+	case t.def.Columns[i].RowID:
+		return fmt.Errorf("PRIMARY KEY")
+	}
+	for _, u := range t.def.Uniques {
+		if contains(u.Columns, i) {
+			if u.PrimaryKey {
+				return fmt.Errorf("PRIMARY KEY")
+			}
+			return fmt.Errorf("UNIQUE")
+		}
+	}
+	for _, fk := range t.def.ForeignKeys {
+		if contains(fk.Columns, i) {
+			return fmt.Errorf("foreign key")
+		}
+	}
+	for _, c := range t.def.Checks {
+		if c.Columns == nil || contains(c.Columns, i) {
+			return fmt.Errorf("CHECK constraint %v", c.Name)
+		}
+	}
+	for _, ix := range t.defs {
+		if ix.Columns == nil || contains(ix.Columns, i) {
+			return fmt.Errorf("index %v", ix.Definition.Name)
+		}
+	}
+	for _, other := range s.tables {
+		for _, fk := range other.def.ForeignKeys {
+			if !strings.EqualFold(fk.Parent, t.def.Name) {
+				continue
+			}
+			for _, parent := range fk.ParentColumns {
+				if strings.EqualFold(parent, name) {
+					return fmt.Errorf("foreign key of %v", other.def.Name)
+				}
+			}
+		}
+	}
+	views, err := s.views.Dependent(t.def.Name, name, s.columns)
+	if err != nil {
+		return err
+	}
+	if len(views) != 0 {
+		return fmt.Errorf("view %v", views[0])
+	}
+	for _, tr := range s.triggers.Triggers() {
+		if !strings.EqualFold(tr.Table, t.def.Name) {
+			continue
+		}
+		for _, col := range tr.Columns {
+			if strings.EqualFold(col, name) {
+				return fmt.Errorf("trigger %v", tr.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// rebuildReferencing applies the given change to the foreign keys that
+// reference the table with the given name, and rebuilds the given table and
+// all tables that have such foreign keys.
+func (s *Schema) rebuildReferencing(name string, changed *Table, change func(fk *constraint.ForeignKeyConstraint)) error {
+	for _, t := range s.tables {
+		referencing := false
+		fks := make([]constraint.ForeignKeyConstraint, len(t.def.ForeignKeys))
+		for i, fk := range t.def.ForeignKeys {
+			if strings.EqualFold(fk.Parent, name) {
+				fk.ParentColumns = append([]string{}, fk.ParentColumns...)
+				change(&fk)
+				referencing = true
+			}
+			fks[i] = fk
+		}
+		if !referencing && t != changed {
+			continue
+		}
+		t.def.ForeignKeys = fks
+		if err := t.build(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(columns []int, i int) bool {
+	for _, c := range columns {
+		if c == i {
+			return true
+		}
+	}
+	return false
+}
+
+// shift returns the given column positions, after the column at position i
+// was removed.
+func shift(columns []int, i int) []int {
+	if columns == nil {
+		return nil
+	}
+	shifted := make([]int, len(columns))
+	for j, c := range columns {
+		if c > i {
+			c--
+		}
+		shifted[j] = c
+	}
+	return shifted
+}
+
+func remove(row constraint.Row, i int) constraint.Row {
+	return append(append(constraint.Row{}, row[:i]...), row[i+1:]...)
+}
+
+func removeColumn(columns []constraint.Column, i int) []constraint.Column {
+	return append(append([]constraint.Column{}, columns[:i]...), columns[i+1:]...)
+}
+
+// insert returns the given row with NULL inserted at position i, which gives
+// it the positions of the columns before the column at i was dropped.
+func insert(row constraint.Row, i int) constraint.Row {
+	if row == nil {
+		return nil
+	}
+	old := make(constraint.Row, 0, len(row)+1)
+	old = append(old, row[:i]...)
+	old = append(old, nil)
+	return append(old, row[i:]...)
+}
+
+func adaptExpr(expr func(row constraint.Row) (constraint.Value, error), i int) func(row constraint.Row) (constraint.Value, error) {
+	return func(row constraint.Row) (constraint.Value, error) { return expr(insert(row, i)) }
+}
+
+func adaptKey(key func(row constraint.Row) (index.Key, error), i int) func(row constraint.Row) (index.Key, error) {
+	return func(row constraint.Row) (index.Key, error) { return key(insert(row, i)) }
+}
+
+func adaptWhere(where func(row constraint.Row) (bool, error), i int) func(row constraint.Row) (bool, error) {
+	return func(row constraint.Row) (bool, error) { return where(insert(row, i)) }
+}
+
+func adaptTrigger(t *trigger.Trigger, i int) {
+	if when := t.When; when != nil {
+		t.When = func(old, new trigger.Row) (bool, error) { return when(insert(old, i), insert(new, i)) }
+	}
+	if program := t.Program; program != nil {
+		t.Program = func(x *trigger.Execution, old, new trigger.Row) error {
+			return program(x, insert(old, i), insert(new, i))
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package alter
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/database/view"
+	"github.com/tomarrell/lbadd/internal/parser"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+type memRows map[index.RowID]constraint.Row
+
+func (m memRows) Get(id index.RowID) (constraint.Row, bool, error) {
+	row, ok := m[id]
+	return row, ok, nil
+}
+
+func (m memRows) Put(id index.RowID, row constraint.Row) error {
+	m[id] = row
+	return nil
+}
+
+func (m memRows) Delete(id index.RowID) error {
+	delete(m, id)
+	return nil
+}
+
+func (m memRows) Last() (index.RowID, bool, error) {
+	var last index.RowID
+	for id := range m {
+		if id > last {
+			last = id
+		}
+	}
+	return last, len(m) > 0, nil
+}
+
+func (m memRows) Scan(fn func(id index.RowID, row constraint.Row) error) error {
+	ids := make([]index.RowID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if err := fn(id, m[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func constant(v constraint.Value) func() (constraint.Value, error) {
+	return func() (constraint.Value, error) { return v, nil }
+}
+
+// at returns a token for the first occurrence of v in sql after the given
+// offset.
+func at(sql, v string, after int) token.Token {
+	offset := after + strings.Index(sql[after:], v)
+	return token.New(1, offset+1, offset, len(v), token.Literal, v)
+}
+
+// music creates the tables
+//
+//	CREATE TABLE artists (id INTEGER PRIMARY KEY, name TEXT)
+//	CREATE TABLE tracks (id INTEGER PRIMARY KEY, artist INTEGER REFERENCES artists(id), title TEXT)
+//
+// the view
+//
+//	CREATE VIEW names AS SELECT name FROM artists
+//
+// and a trigger on UPDATE OF name on artists.
+func music(t *testing.T) (*Schema, memRows, memRows) {
+	views := view.NewCatalog()
+	triggers := trigger.NewCatalog()
+	s := NewSchema(views, triggers)
+
+	artists, tracks := memRows{}, memRows{}
+	_, err := s.Create(constraint.Table{
+		Name:    "artists",
+		Columns: []constraint.Column{{Name: "id", RowID: true}, {Name: "name"}},
+		Uniques: []constraint.UniqueConstraint{{Columns: []int{0}, PrimaryKey: true}},
+	}, artists)
+	require.NoError(t, err)
+	_, err = s.Create(constraint.Table{
+		Name:        "tracks",
+		Columns:     []constraint.Column{{Name: "id", RowID: true}, {Name: "artist"}, {Name: "title"}},
+		Uniques:     []constraint.UniqueConstraint{{Columns: []int{0}, PrimaryKey: true}},
+		ForeignKeys: []constraint.ForeignKeyConstraint{{Columns: []int{1}, Parent: "artists", ParentColumns: []string{"id"}}},
+	}, tracks)
+	require.NoError(t, err)
+	s.Constraints().SetForeignKeys(true)
+
+	sql := "CREATE VIEW names AS SELECT name FROM artists"
+	require.NoError(t, views.Create(&view.View{
+		Name: "names",
+		Select: &ast.SelectStmt{SelectCore: []*ast.SelectCore{{
+			ResultColumn:    []*ast.ResultColumn{{Expr: &ast.Expr{ColumnName: at(sql, "name", 20)}}},
+			TableOrSubquery: []*ast.TableOrSubquery{{TableName: at(sql, "artists", 0)}},
+		}}},
+		SQL: sql,
+	}, false, s.columns))
+	require.NoError(t, triggers.Create(&trigger.Trigger{
+		Name: "renamed", Table: "artists", Time: trigger.After, Event: trigger.Update, Columns: []string{"name"},
+	}, false, false))
+	return s, artists, tracks
+}
+
+func insertRow(t *testing.T, s *Schema, table string, values ...constraint.Value) index.RowID {
+	t.Helper()
+	tbl, ok := s.Table(table)
+	require.True(t, ok)
+	stmt := tbl.Enforcer().Begin(constraint.Default)
+	id, _, err := stmt.Insert(nil, values)
+	require.NoError(t, err)
+	require.NoError(t, stmt.Commit())
+	return id
+}
+
+func TestRename(t *testing.T) {
+	s, _, _ := music(t)
+	insertRow(t, s, "artists", int64(1), "Nina")
+
+	assert.True(t, errors.Is(s.RenameTable("artists", "tracks"), ErrTableExists))
+	assert.True(t, errors.Is(s.RenameTable("artists", "NAMES"), ErrTableExists))
+	assert.True(t, errors.Is(s.RenameTable("albums", "records"), ErrNoSuchTable))
+	require.NoError(t, s.RenameTable("artists", "singers"))
+
+	_, ok := s.Table("artists")
+	assert.False(t, ok)
+	v, _ := s.views.View("names")
+	assert.Equal(t, "CREATE VIEW names AS SELECT name FROM singers", v.SQL)
+	tr, _ := s.triggers.Trigger("renamed")
+	assert.Equal(t, "singers", tr.Table)
+	tracks, _ := s.Table("tracks")
+	assert.Equal(t, "singers", tracks.Definition().ForeignKeys[0].Parent)
+
+	// the foreign key still resolves the renamed parent
+	insertRow(t, s, "tracks", int64(1), int64(1), "Sinnerman")
+	stmt := tracks.Enforcer().Begin(constraint.Default)
+	_, _, err := stmt.Insert(nil, constraint.Row{int64(2), int64(2), "Feeling Good"})
+	require.NoError(t, err)
+	assert.True(t, errors.Is(stmt.Commit(), constraint.ErrConstraint))
+
+	assert.True(t, errors.Is(s.RenameColumn("singers", "age", "years"), ErrNoSuchColumn))
+	assert.True(t, errors.Is(s.RenameColumn("singers", "name", "ID"), ErrColumnExists))
+	require.NoError(t, s.RenameColumn("singers", "Name", "fullname"))
+	require.NoError(t, s.RenameColumn("singers", "id", "singer_id"))
+
+	singers, _ := s.Table("singers")
+	assert.Equal(t, "fullname", singers.Definition().Columns[1].Name)
+	v, _ = s.views.View("names")
+	assert.Equal(t, "CREATE VIEW names AS SELECT fullname FROM singers", v.SQL)
+	assert.Equal(t, []string{"fullname"}, tr.Columns)
+	assert.Equal(t, []string{"singer_id"}, tracks.Definition().ForeignKeys[0].ParentColumns)
+	insertRow(t, s, "tracks", int64(2), int64(1), "Feeling Good")
+}
+
+func TestAddColumn(t *testing.T) {
+	s, artists, _ := music(t)
+	id := insertRow(t, s, "artists", nil, "Nina")
+
+	assert.True(t, errors.Is(s.AddColumn("artists", constraint.Column{Name: "NAME"}), ErrColumnExists))
+	assert.True(t, errors.Is(s.AddColumn("artists", constraint.Column{Name: "born", NotNull: true}), ErrCannotAdd))
+	assert.True(t, errors.Is(s.AddColumn("artists", constraint.Column{Name: "key", RowID: true}), ErrCannotAdd))
+	require.NoError(t, s.AddColumn("artists", constraint.Column{Name: "country", NotNull: true, Default: constant("US")}))
+
+	// the stored row is not rewritten, but completed when it is read
+	assert.Equal(t, constraint.Row{int64(1), "Nina"}, artists[id])
+	tbl, _ := s.Table("artists")
+	row, ok, err := tbl.Rows().Get(id)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, constraint.Row{int64(1), "Nina", "US"}, row)
+
+	id = insertRow(t, s, "artists", nil, "Miriam", "ZA")
+	assert.Equal(t, constraint.Row{int64(2), "Miriam", "ZA"}, artists[id])
+
+	stmt := tbl.Enforcer().Begin(constraint.Default)
+	_, err = stmt.Update(1, []int{2}, constraint.Row{nil})
+	assert.True(t, errors.Is(err, constraint.ErrConstraint))
+}
+
+func TestDropColumn(t *testing.T) {
+	s, artists, _ := music(t)
+	rows := memRows{}
+	_, err := s.Create(constraint.Table{
+		Name:    "albums",
+		Columns: []constraint.Column{{Name: "id", RowID: true}, {Name: "notes"}, {Name: "title"}, {Name: "year"}, {Name: "code"}},
+		Uniques: []constraint.UniqueConstraint{{Columns: []int{0}, PrimaryKey: true}, {Columns: []int{4}}},
+		Checks: []constraint.CheckConstraint{{
+			Name:    "year_positive",
+			Columns: []int{3},
+			Expr: func(row constraint.Row) (constraint.Value, error) {
+				if year, ok := row[3].(int64); ok && year <= 0 {
+					return int64(0), nil
+				}
+				return int64(1), nil
+			},
+		}},
+	}, rows, Index{
+		Definition: index.Definition[constraint.Row]{
+			Name:    "albums_title",
+			Columns: []index.Column{{}},
+			Key: func(row constraint.Row) (index.Key, error) {
+				title, _ := row[2].(string)
+				return index.Key{index.Value(title)}, nil
+			},
+		},
+		Columns: []int{2},
+	})
+	require.NoError(t, err)
+	insertRow(t, s, "albums", nil, "live", "Pastel Blues", int64(1965), "P1")
+
+	for _, column := range []string{"id", "title", "year", "code"} {
+		assert.True(t, errors.Is(s.DropColumn("albums", column), ErrCannotDrop), column)
+	}
+	assert.True(t, errors.Is(s.DropColumn("albums", "genre"), ErrNoSuchColumn))
+	// referenced by the view, the trigger and the foreign key of tracks
+	assert.True(t, errors.Is(s.DropColumn("artists", "name"), ErrCannotDrop))
+	assert.True(t, errors.Is(s.DropColumn("artists", "id"), ErrCannotDrop))
+	assert.True(t, errors.Is(s.DropColumn("tracks", "artist"), ErrCannotDrop))
+	assert.Len(t, artists, 0)
+
+	require.NoError(t, s.DropColumn("albums", "NOTES"))
+	assert.Equal(t, constraint.Row{int64(1), "Pastel Blues", int64(1965), "P1"}, rows[1])
+
+	albums, _ := s.Table("albums")
+	assert.Equal(t, []int{3}, albums.Definition().Uniques[1].Columns)
+	assert.Equal(t, []int{2}, albums.Definition().Checks[0].Columns)
+	ix, ok := albums.Indexes().Index("albums_title")
+	require.True(t, ok)
+	n, err := ix.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// the constraints and the index still see the columns at their
+	// positions, and the enforcer keeps the index up to date
+	id := insertRow(t, s, "albums", nil, "Wild Is the Wind", int64(1966), "P2")
+	n, err = ix.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	var found []index.RowID
+	require.NoError(t, ix.Lookup(index.Key{index.Value("Wild Is the Wind")}, func(id index.RowID) bool {
+		found = append(found, id)
+		return true
+	}))
+	assert.Equal(t, []index.RowID{id}, found)
+
+	for _, row := range []constraint.Row{
+		{nil, "Broadway", int64(-1), "P3"},
+		{nil, "Broadway", int64(1964), "P1"},
+	} {
+		_, _, err = albums.Enforcer().Begin(constraint.Default).Insert(nil, row)
+		assert.True(t, errors.Is(err, constraint.ErrConstraint))
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	s, artists, _ := music(t)
+	insertRow(t, s, "artists", nil, "Nina")
+	insertRow(t, s, "artists", nil, "nina")
+
+	table, _ := s.Table("artists")
+	byName := Index{
+		Definition: index.Definition[constraint.Row]{
+			Name:    "artists_name",
+			Unique:  true,
+			Columns: []index.Column{{Collation: index.NoCase}},
+			Key: func(row constraint.Row) (index.Key, error) {
+				name, _ := row[1].(string)
+				return index.Key{index.Value(name)}, nil
+			},
+		},
+		Columns: []int{1},
+	}
+	assert.True(t, errors.Is(table.CreateIndex(byName, false), index.ErrUniqueViolation))
+	_, ok := table.Indexes().Index("artists_name")
+	assert.False(t, ok)
+
+	require.NoError(t, table.Enforcer().Begin(constraint.Default).Delete(2))
+	require.NoError(t, table.CreateIndex(byName, false))
+	assert.True(t, errors.Is(table.CreateIndex(byName, false), index.ErrExists))
+	assert.NoError(t, table.CreateIndex(byName, true))
+
+	// the unique index is enforced like a UNIQUE constraint
+	_, _, err := table.Enforcer().Begin(constraint.Default).Insert(nil, constraint.Row{nil, "NINA"})
+	assert.True(t, errors.Is(err, constraint.ErrConstraint))
+	assert.Len(t, artists, 1)
+
+	// the index survives a change of the table
+	require.NoError(t, s.AddColumn("artists", constraint.Column{Name: "born"}))
+	table, _ = s.Table("artists")
+	_, _, err = table.Enforcer().Begin(constraint.Default).Insert(nil, constraint.Row{nil, "nina", nil})
+	assert.True(t, errors.Is(err, constraint.ErrConstraint))
+	assert.NoError(t, table.Reindex(""))
+
+	require.NoError(t, table.DropIndex("ARTISTS_NAME", false))
+	assert.True(t, errors.Is(table.DropIndex("artists_name", false), index.ErrNotExist))
+	assert.NoError(t, table.DropIndex("artists_name", true))
+	insertRow(t, s, "artists", nil, "NINA", nil)
+}
+
+func TestExec(t *testing.T) {
+	s, _, _ := music(t)
+	insertRow(t, s, "artists", nil, "Nina")
+
+	p := parser.New(strings.Join([]string{
+		"ALTER TABLE artists ADD COLUMN born INTEGER DEFAULT -1",
+		"ALTER TABLE artists ADD note TEXT NOT NULL DEFAULT 'unknown'",
+		`ALTER TABLE artists RENAME COLUMN born TO "year"`,
+		"ALTER TABLE artists RENAME TO singers",
+		`ALTER TABLE singers DROP COLUMN "year"`,
+		"ALTER TABLE singers ADD COLUMN id2 INTEGER PRIMARY KEY",
+		"ALTER TABLE singers DROP name",
+	}, "; "))
+	var errs []error
+	for {
+		stmt, errors, ok := p.Next()
+		if !ok {
+			break
+		}
+		require.Empty(t, errors)
+		errs = append(errs, s.Exec(stmt.AlterTableStmt))
+	}
+	require.Len(t, errs, 7)
+	for _, err := range errs[:5] {
+		assert.NoError(t, err)
+	}
+	assert.True(t, errors.Is(errs[5], ErrCannotAdd))
+	assert.True(t, errors.Is(errs[6], ErrCannotDrop))
+
+	singers, ok := s.Table("singers")
+	require.True(t, ok)
+	row, _, err := singers.Rows().Get(1)
+	require.NoError(t, err)
+	assert.Equal(t, constraint.Row{int64(1), "Nina", "unknown"}, row)
+}
@@ -0,0 +1,12 @@
+// Package alter executes ALTER TABLE. It holds the tables of a schema, with
+// their constraints, rows and indexes, together with the views and triggers
+// of the schema, which depend on the tables.
+//
+// RENAME TO and RENAME COLUMN change the names in the definitions of the
+// tables, and in the views and triggers that refer to them. ADD COLUMN
+// doesn't rewrite the stored rows. Rows that were stored before a column was
+// added are shorter than the table, and are completed with the default
+// values of the added columns when they are read. DROP COLUMN rewrites all
+// rows of the table. Like in SQLite, a column cannot be dropped while any
+// constraint, index, view or trigger refers to it.
+package alter
@@ -0,0 +1,16 @@
+package alter
+
+// Error provides constant errors to the alter package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrNoSuchTable  = Error("no such table")
+	ErrTableExists  = Error("there is already another table or view with this name")
+	ErrNoSuchColumn = Error("no such column")
+	ErrColumnExists = Error("duplicate column name")
+	ErrCannotAdd    = Error("cannot add column")
+	ErrCannotDrop   = Error("cannot drop column")
+)
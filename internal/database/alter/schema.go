@@ -0,0 +1,292 @@
+package alter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/database/view"
+)
+
+// Index is a secondary index of a table.
+type Index struct {
+	Definition index.Definition[constraint.Row]
+	// Columns are the columns that the key and the WHERE clause of the index
+	// refer to, which cannot be dropped from the table. If they are nil, the
+	// index may refer to any column.
+	Columns []int
+}
+
+// Table is a table of a schema.
+type Table struct {
+	def constraint.Table
+	// stored holds the rows as they are stored, which may be shorter than
+	// the columns of the table.
+	stored   constraint.Rows
+	enforcer *constraint.Enforcer
+	// defs holds the definitions of the indexes in indexes.
+	defs    []Index
+	indexes *index.Catalog[constraint.Row]
+}
+
+// Definition returns the definition of the table.
+func (t *Table) Definition() constraint.Table {
+	return t.def
+}
+
+// Rows returns the rows of the table. Rows that were stored before columns
+// were added are completed with the default values of the added columns.
+func (t *Table) Rows() constraint.Rows {
+	return rows{t.stored, t}
+}
+
+// Enforcer returns the enforcer of the constraints of the table, through
+// which all changes of rows must be made. The enforcer is replaced by every
+// change of the table.
+func (t *Table) Enforcer() *constraint.Enforcer {
+	return t.enforcer
+}
+
+// Indexes returns the secondary indexes of the table.
+func (t *Table) Indexes() *index.Catalog[constraint.Row] {
+	return t.indexes
+}
+
+// CreateIndex creates a secondary index of the table, and builds it from the
+// rows of the table. If an index with the same name exists, index.ErrExists
+// is returned, unless ifNotExists is set.
+func (t *Table) CreateIndex(ix Index, ifNotExists bool) error {
+	if _, exists := t.indexes.Index(ix.Definition.Name); exists && ifNotExists {
+		return nil
+	}
+	if err := t.indexes.Create(ix.Definition, false, t.Rows().Scan); err != nil {
+		return err
+	}
+	t.defs = append(t.defs, ix)
+	return nil
+}
+
+// DropIndex drops the secondary index with the given name. If no such index
+// exists, index.ErrNotExist is returned, unless ifExists is set.
+func (t *Table) DropIndex(name string, ifExists bool) error {
+	if err := t.indexes.Drop(name, ifExists); err != nil {
+		return err
+	}
+	for i, ix := range t.defs {
+		if strings.EqualFold(ix.Definition.Name, name) {
+			t.defs = append(t.defs[:i:i], t.defs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Reindex rebuilds the secondary index with the given name from the rows of
+// the table, or all secondary indexes if the name is empty.
+func (t *Table) Reindex(name string) error {
+	return t.indexes.Reindex(name, t.Rows().Scan)
+}
+
+// column returns the position of the column with the given name, or -1.
+func (t *Table) column(name string) int {
+	for i, col := range t.def.Columns {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// complete appends the default values of the columns, that were added after
+// the given row was stored.
+func (t *Table) complete(row constraint.Row) (constraint.Row, error) {
+	if len(row) >= len(t.def.Columns) {
+		return row, nil
+	}
+	complete := make(constraint.Row, len(row), len(t.def.Columns))
+	copy(complete, row)
+	for _, col := range t.def.Columns[len(row):] {
+		var value constraint.Value
+		if col.Default != nil {
+			var err error
+			if value, err = col.Default(); err != nil {
+				return nil, fmt.Errorf("default of %v: %w", col.Name, err)
+			}
+		}
+		complete = append(complete, value)
+	}
+	return complete, nil
+}
+
+// rows completes the rows of a table when they are read, and provides the
+// secondary indexes of the table to its enforcer.
+type rows struct {
+	constraint.Rows
+	t *Table
+}
+
+func (r rows) IndexStore() (index.Store, []byte) {
+	if stored, ok := r.Rows.(constraint.StoredRows); ok {
+		return stored.IndexStore()
+	}
+	return nil, nil
+}
+
+func (r rows) Indexes() []*index.Index[constraint.Row] {
+	if r.t.indexes == nil {
+		return nil
+	}
+	return r.t.indexes.Indexes()
+}
+
+func (r rows) Get(id index.RowID) (constraint.Row, bool, error) {
+	row, ok, err := r.Rows.Get(id)
+	if err != nil || !ok {
+		return row, ok, err
+	}
+	row, err = r.t.complete(row)
+	return row, err == nil, err
+}
+
+func (r rows) Scan(fn func(id index.RowID, row constraint.Row) error) error {
+	return r.Rows.Scan(func(id index.RowID, row constraint.Row) error {
+		row, err := r.t.complete(row)
+		if err != nil {
+			return err
+		}
+		return fn(id, row)
+	})
+}
+
+// Schema holds the tables, views and triggers of a schema. A Schema is not
+// safe for concurrent use.
+type Schema struct {
+	views       *view.Catalog
+	triggers    *trigger.Catalog
+	constraints *constraint.Schema
+	seq         *constraint.Sequences
+	// tables holds all tables, in the order in which they were created.
+	tables []*Table
+}
+
+// NewSchema creates a new schema without tables, with the given views and
+// triggers.
+func NewSchema(views *view.Catalog, triggers *trigger.Catalog) *Schema {
+	return &Schema{
+		views:       views,
+		triggers:    triggers,
+		constraints: constraint.NewSchema(),
+		seq:         constraint.NewSequences(),
+	}
+}
+
+// Constraints returns the schema that enforces the foreign keys between the
+// tables.
+func (s *Schema) Constraints() *constraint.Schema {
+	return s.constraints
+}
+
+// Sequences returns the AUTOINCREMENT sequences of the tables.
+func (s *Schema) Sequences() *constraint.Sequences {
+	return s.seq
+}
+
+// Create adds a table with the given definition, whose rows are stored in
+// the given rows, and builds its indexes.
+func (s *Schema) Create(def constraint.Table, stored constraint.Rows, indexes ...Index) (*Table, error) {
+	if err := s.available(def.Name); err != nil {
+		return nil, err
+	}
+
+	t := &Table{def: def, stored: stored, defs: indexes}
+	if err := t.build(s); err != nil {
+		return nil, err
+	}
+	s.tables = append(s.tables, t)
+	return t, nil
+}
+
+// Table returns the table with the given name.
+func (s *Schema) Table(name string) (*Table, bool) {
+	t, i := s.find(name)
+	return t, i >= 0
+}
+
+// Tables returns all tables, in the order in which they were created.
+func (s *Schema) Tables() []*Table {
+	return append([]*Table{}, s.tables...)
+}
+
+// columns returns the names of the columns of the table with the given name,
+// which resolves the tables of views.
+func (s *Schema) columns(name string) ([]string, bool) {
+	t, ok := s.Table(name)
+	if !ok {
+		return nil, false
+	}
+	columns := make([]string, len(t.def.Columns))
+	for i, col := range t.def.Columns {
+		columns[i] = col.Name
+	}
+	return columns, true
+}
+
+// available checks that no table or view with the given name exists.
+func (s *Schema) available(name string) error {
+	if _, ok := s.Table(name); ok {
+		return fmt.Errorf("%w: %v", ErrTableExists, name)
+	}
+	if _, ok := s.views.View(name); ok {
+		return fmt.Errorf("%w: %v", ErrTableExists, name)
+	}
+	return nil
+}
+
+func (s *Schema) find(name string) (*Table, int) {
+	for i, t := range s.tables {
+		if strings.EqualFold(t.def.Name, name) {
+			return t, i
+		}
+	}
+	return nil, -1
+}
+
+// build creates the enforcer and the indexes of the table from its
+// definition, and replaces the previous enforcer in the constraints of the
+// schema. If the rows store the indexes, they are opened instead of built.
+func (t *Table) build(s *Schema) error {
+	e, err := constraint.New(t.def, t.Rows(), s.seq)
+	if err != nil {
+		return err
+	}
+	var indexes *index.Catalog[constraint.Row]
+	if store, prefix := (rows{t.stored, t}).IndexStore(); store != nil {
+		indexes = index.NewStoredCatalog[constraint.Row](store, func(name string) []byte {
+			key := append(append([]byte{}, prefix...), 'x')
+			return append(append(key, strings.ToLower(name)...), 0)
+		})
+		for _, ix := range t.defs {
+			if err := indexes.Open(ix.Definition); err != nil {
+				return err
+			}
+		}
+	} else {
+		indexes = index.NewCatalog[constraint.Row]()
+		for _, ix := range t.defs {
+			if err := indexes.Create(ix.Definition, false, t.Rows().Scan); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.enforcer != nil {
+		s.constraints.Remove(t.enforcer.Table().Name)
+	}
+	if err := s.constraints.Add(e); err != nil {
+		return err
+	}
+	t.enforcer, t.indexes = e, indexes
+	return nil
+}
@@ -0,0 +1,128 @@
+package alter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// Exec executes the given ALTER TABLE statement on the schema. The schema
+// name of the statement must be resolved by the caller.
+func (s *Schema) Exec(stmt *ast.AlterTableStmt) error {
+	table := token.Unquote(stmt.TableName)
+	switch {
+	case stmt.NewTableName != nil:
+		return s.RenameTable(table, token.Unquote(stmt.NewTableName))
+	case stmt.Rename != nil:
+		return s.RenameColumn(table, token.Unquote(stmt.ColumnName), token.Unquote(stmt.NewColumnName))
+	case stmt.Add != nil:
+		col, err := columnFrom(stmt.ColumnDef)
+		if err != nil {
+			return err
+		}
+		return s.AddColumn(table, col)
+	case stmt.Drop != nil:
+		return s.DropColumn(table, token.Unquote(stmt.ColumnName))
+	}
+	return fmt.Errorf("unsupported ALTER TABLE statement")
+}
+
+// columnFrom converts the definition of a column, that is added with ADD
+// COLUMN. Like in SQLite, the column cannot have a PRIMARY KEY or UNIQUE
+// constraint, and its default value must be constant. The CHECK, REFERENCES
+// and GENERATED constraints are not supported yet. The collation of the
+// column is ignored.
+func columnFrom(def *ast.ColumnDef) (constraint.Column, error) {
+	col := constraint.Column{Name: token.Unquote(def.ColumnName)}
+	for _, c := range def.ColumnConstraint {
+		switch {
+		case c.Primary != nil:
+			return col, fmt.Errorf("%w: cannot add a PRIMARY KEY column", ErrCannotAdd)
+		case c.Unique != nil:
+			return col, fmt.Errorf("%w: cannot add a UNIQUE column", ErrCannotAdd)
+		case c.Not != nil:
+			col.NotNull = true
+			col.OnNull = conflict(c.ConflictClause)
+		case c.Default != nil:
+			value, err := defaultValue(c)
+			if err != nil {
+				return col, err
+			}
+			col.Default = func() (constraint.Value, error) { return value, nil }
+		case c.Check != nil:
+			return col, fmt.Errorf("%w: CHECK constraints are not supported", ErrCannotAdd)
+		case c.ForeignKeyClause != nil:
+			return col, fmt.Errorf("%w: REFERENCES constraints are not supported", ErrCannotAdd)
+		case c.Generated != nil, c.As != nil:
+			return col, fmt.Errorf("%w: cannot add a generated column", ErrCannotAdd)
+		}
+	}
+	return col, nil
+}
+
+func conflict(clause *ast.ConflictClause) constraint.Conflict {
+	switch {
+	case clause == nil:
+		return constraint.Default
+	case clause.Rollback != nil:
+		return constraint.Rollback
+	case clause.Abort != nil:
+		return constraint.Abort
+	case clause.Fail != nil:
+		return constraint.Fail
+	case clause.Ignore != nil:
+		return constraint.Ignore
+	case clause.Replace != nil:
+		return constraint.Replace
+	}
+	return constraint.Default
+}
+
+// defaultValue returns the value of the DEFAULT constraint of a column, which
+// must be a literal or a signed number.
+func defaultValue(c *ast.ColumnConstraint) (constraint.Value, error) {
+	if num := c.SignedNumber; num != nil && num.NumericLiteral != nil {
+		sign := ""
+		if num.Sign != nil {
+			sign = num.Sign.Value()
+		}
+		return literal(sign + num.NumericLiteral.Value())
+	}
+	tk := c.LiteralValue
+	if tk == nil || tk.Type() != token.Literal && tk.Type() != token.KeywordNull {
+		return nil, fmt.Errorf("%w: cannot add a column with non-constant default", ErrCannotAdd)
+	}
+	return literal(tk.Value())
+}
+
+// literal converts the value of a literal token.
+func literal(v string) (constraint.Value, error) {
+	switch upper := strings.ToUpper(v); {
+	case upper == "NULL":
+		return nil, nil
+	case upper == "TRUE":
+		return int64(1), nil
+	case upper == "FALSE":
+		return int64(0), nil
+	case len(v) >= 3 && (v[0] == 'x' || v[0] == 'X') && v[1] == '\'' && v[len(v)-1] == '\'':
+		b, err := hex.DecodeString(v[2 : len(v)-1])
+		if err != nil {
+			return nil, fmt.Errorf("blob %v: %w", v, err)
+		}
+		return b, nil
+	case len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'':
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'"), nil
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f, nil
+	}
+	return v, nil
+}
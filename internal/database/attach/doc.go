@@ -0,0 +1,20 @@
+// Package attach manages the database files of a session. Every session has
+// the main database, and additional database files can be attached under a
+// schema name with ATTACH, and detached again with DETACH.
+//
+// Every session also has its own temp database, which holds the tables,
+// views and triggers that are created with CREATE TEMP. It is created when
+// it is first used, is not visible to other sessions, and is deleted when
+// the session is closed.
+//
+// Tables are qualified by the schema name of their database. Unqualified
+// table names are searched in the temp database first, then in the main
+// database, and then in the attached databases, in the order in which they
+// were attached, so queries and joins can span all databases of the session.
+//
+// A transaction of a session begins a transaction in every database that it
+// accesses. If it writes to more than one database, it is committed with a
+// super-journal, so that the writes to all database files are atomic.
+// Savepoints are created in all databases of a transaction, and SAVEPOINT,
+// RELEASE and ROLLBACK TO behave like in SQLite.
+package attach
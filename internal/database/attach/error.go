@@ -0,0 +1,24 @@
+package attach
+
+// Error provides constant errors to the attach package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrReserved        = Error("schema name is reserved")
+	ErrAlreadyInUse    = Error("database is already in use")
+	ErrAlreadyAttached = Error("database is already attached")
+	ErrTooMany         = Error("too many attached databases")
+	ErrNoSuchDatabase  = Error("no such database")
+	ErrCannotDetach    = Error("cannot detach database")
+	ErrLocked          = Error("database is locked")
+	ErrNoSuchTable     = Error("no such table")
+	ErrSessionClosed   = Error("session is closed")
+	ErrTxActive        = Error("cannot start a transaction within a transaction")
+	ErrNoTx            = Error("no transaction is active")
+	ErrNotLiteral      = Error("file name and schema name must be literals")
+	ErrVacuumInTx      = Error("cannot VACUUM from within a transaction")
+	ErrQualifiedTemp   = Error("temporary table name must be unqualified")
+)
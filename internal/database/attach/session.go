@@ -0,0 +1,212 @@
+package attach
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+)
+
+// Reserved schema names, that cannot be used for attached databases.
+const (
+	Main = "main"
+	Temp = "temp"
+)
+
+// MaxAttached is the maximum number of databases that can be attached to a
+// session, in addition to the main database.
+const MaxAttached = 10
+
+// Database is a database file that is open in a session.
+type Database struct {
+	// Name is the schema name of the database, which is Main for the main
+	// database, Temp for the temp database, and the alias given to ATTACH
+	// for attached databases.
+	Name    string
+	File    string
+	Manager *transaction.Manager
+}
+
+// Session holds the main database and the attached databases of a session.
+// A Session is not safe for concurrent use.
+type Session struct {
+	fs wal.FileSystem
+	// databases holds the main database, followed by the attached databases
+	// in the order in which they were attached.
+	databases []*Database
+	// temp is the temp database, or nil if it has not been used yet.
+	// tempDir is the directory that holds it.
+	temp    *Database
+	tempDir string
+	// tx is the active transaction, or nil.
+	tx *Tx
+	// shared is set if the main database was opened by the caller of New,
+	// and is not closed by Close.
+	shared bool
+	closed bool
+}
+
+// Open opens a session with the given file as main database.
+func Open(fsys wal.FileSystem, file string) (*Session, error) {
+	m, err := transaction.Open(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		fs:        fsys,
+		databases: []*Database{{Name: Main, File: file, Manager: m}},
+	}, nil
+}
+
+// New creates a session with the database of the given manager as main
+// database. The manager can be shared by multiple sessions, and is not closed
+// when the session is closed.
+func New(fsys wal.FileSystem, m *transaction.Manager) *Session {
+	return &Session{
+		fs:        fsys,
+		databases: []*Database{{Name: Main, File: m.Name(), Manager: m}},
+		shared:    true,
+	}
+}
+
+// Attach opens the given database file and attaches it under the given
+// schema name. Schema names are case insensitive.
+func (s *Session) Attach(file, name string) error {
+	if s.closed {
+		return ErrSessionClosed
+	}
+	if strings.EqualFold(name, Main) || strings.EqualFold(name, Temp) {
+		return fmt.Errorf("%w: %v", ErrReserved, name)
+	}
+	if _, i := s.find(name); i >= 0 {
+		return fmt.Errorf("%w: %v", ErrAlreadyInUse, name)
+	}
+	for _, db := range s.databases {
+		if filepath.Clean(db.File) == filepath.Clean(file) {
+			return fmt.Errorf("%w: %v as %v", ErrAlreadyAttached, file, db.Name)
+		}
+	}
+	if len(s.databases)-1 >= MaxAttached {
+		return fmt.Errorf("%w: the limit is %d", ErrTooMany, MaxAttached)
+	}
+
+	m, err := transaction.Open(s.fs, file)
+	if err != nil {
+		return fmt.Errorf("attach %v: %w", file, err)
+	}
+	s.databases = append(s.databases, &Database{Name: name, File: file, Manager: m})
+	return nil
+}
+
+// Detach closes the database with the given schema name. The main and the
+// temp database cannot be detached, and neither can a database that is
+// accessed by the active transaction.
+func (s *Session) Detach(name string) error {
+	if strings.EqualFold(name, Temp) {
+		return fmt.Errorf("%w: %v", ErrCannotDetach, name)
+	}
+	db, i := s.find(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %v", ErrNoSuchDatabase, name)
+	}
+	if i == 0 {
+		return fmt.Errorf("%w: %v", ErrCannotDetach, name)
+	}
+	if s.tx != nil && s.tx.txs[db] != nil {
+		return fmt.Errorf("%w: %v", ErrLocked, name)
+	}
+
+	s.databases = append(s.databases[:i], s.databases[i+1:]...)
+	return db.Manager.Close()
+}
+
+// Database returns the database with the given schema name, and false if no
+// such database exists. The temp database only exists after it was created
+// with TempDatabase.
+func (s *Session) Database(name string) (*Database, bool) {
+	if strings.EqualFold(name, Temp) {
+		return s.temp, s.temp != nil
+	}
+	db, i := s.find(name)
+	return db, i >= 0
+}
+
+// Databases returns all databases of the session, starting with the main
+// database and the temp database, if it exists, followed by the attached
+// databases, in the order in which they were attached.
+func (s *Session) Databases() []*Database {
+	if len(s.databases) == 0 || s.temp == nil {
+		return append([]*Database(nil), s.databases...)
+	}
+	return append([]*Database{s.databases[0], s.temp}, s.databases[1:]...)
+}
+
+// Resolve returns the database that contains the table, that is referenced
+// by the given schema and table name, as in schema.table. If the schema name
+// is empty, the first database in search order is returned, for which has
+// reports that it contains the table. Like in SQLite, the temp database is
+// searched first, so temporary tables hide the tables of the other
+// databases.
+func (s *Session) Resolve(schema, table string, has func(db *Database, table string) bool) (*Database, error) {
+	if schema != "" {
+		db, ok := s.Database(schema)
+		if !ok && strings.EqualFold(schema, Temp) {
+			return nil, fmt.Errorf("%w: %v.%v", ErrNoSuchTable, schema, table)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrNoSuchDatabase, schema)
+		}
+		if !has(db, table) {
+			return nil, fmt.Errorf("%w: %v.%v", ErrNoSuchTable, schema, table)
+		}
+		return db, nil
+	}
+	if s.temp != nil && has(s.temp, table) {
+		return s.temp, nil
+	}
+	for _, db := range s.databases {
+		if has(db, table) {
+			return db, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v", ErrNoSuchTable, table)
+}
+
+// Close rolls back the active transaction, if any, and closes all databases
+// of the session, except for a main database that was given to New. The temp
+// database is deleted.
+func (s *Session) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var firstErr error
+	if s.tx != nil {
+		firstErr = s.tx.Rollback()
+	}
+	for i, db := range s.databases {
+		if i == 0 && s.shared {
+			continue
+		}
+		if err := db.Manager.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := s.closeTemp(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	s.databases = nil
+	return firstErr
+}
+
+func (s *Session) find(name string) (*Database, int) {
+	for i, db := range s.databases {
+		if strings.EqualFold(db.Name, name) {
+			return db, i
+		}
+	}
+	return nil, -1
+}
@@ -0,0 +1,346 @@
+package attach
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/parser"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+)
+
+func openTestSession(t *testing.T) (*Session, string) {
+	dir := t.TempDir()
+	s, err := Open(wal.OS, filepath.Join(dir, "main.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s, dir
+}
+
+func names(s *Session) []string {
+	var names []string
+	for _, db := range s.Databases() {
+		names = append(names, db.Name)
+	}
+	return names
+}
+
+func TestAttach(t *testing.T) {
+	s, dir := openTestSession(t)
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	require.NoError(t, s.Attach(file("archive.db"), "archive"))
+	assert.True(t, errors.Is(s.Attach(file("other.db"), "ARCHIVE"), ErrAlreadyInUse))
+	assert.True(t, errors.Is(s.Attach(file("archive.db"), "other"), ErrAlreadyAttached))
+	assert.True(t, errors.Is(s.Attach(file("other.db"), "main"), ErrReserved))
+	assert.True(t, errors.Is(s.Attach(file("other.db"), "Temp"), ErrReserved))
+	for i := 1; i < MaxAttached; i++ {
+		require.NoError(t, s.Attach(file(strconv.Itoa(i)+".db"), "s"+strconv.Itoa(i)))
+	}
+	assert.True(t, errors.Is(s.Attach(file("other.db"), "other"), ErrTooMany))
+
+	db, ok := s.Database("Archive")
+	require.True(t, ok)
+	assert.Equal(t, file("archive.db"), db.File)
+
+	assert.True(t, errors.Is(s.Detach("main"), ErrCannotDetach))
+	assert.True(t, errors.Is(s.Detach("other"), ErrNoSuchDatabase))
+	for i := 1; i < MaxAttached; i++ {
+		require.NoError(t, s.Detach("s"+strconv.Itoa(i)))
+	}
+	assert.Equal(t, []string{"main", "archive"}, names(s))
+	require.NoError(t, s.Detach("archive"))
+	_, ok = s.Database("archive")
+	assert.False(t, ok)
+}
+
+func TestNew(t *testing.T) {
+	m, err := transaction.Open(wal.OS, filepath.Join(t.TempDir(), "main.db"))
+	require.NoError(t, err)
+	defer func() { _ = m.Close() }()
+
+	// two sessions share the main database
+	s1, s2 := New(wal.OS, m), New(wal.OS, m)
+	tx, err := s1.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	assert.Same(t, tx, s1.Tx())
+	assert.Nil(t, s2.Tx())
+	main, err := tx.On(Main)
+	require.NoError(t, err)
+	require.NoError(t, main.Put([]byte("k"), []byte("v")))
+	require.NoError(t, tx.Commit())
+	assert.Nil(t, s1.Tx())
+	require.NoError(t, s1.Close())
+
+	// the manager is still open after the first session was closed
+	tx, err = s2.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	main, err = tx.On(Main)
+	require.NoError(t, err)
+	value, ok, err := main.Get([]byte("k"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "v", string(value))
+	require.NoError(t, tx.Rollback())
+	require.NoError(t, s2.Close())
+}
+
+func TestBeginImmediate(t *testing.T) {
+	dir := t.TempDir()
+	m, err := transaction.Open(wal.OS, filepath.Join(dir, "main.db"))
+	require.NoError(t, err)
+	defer func() { _ = m.Close() }()
+	s1, s2 := New(wal.OS, m), New(wal.OS, m)
+	defer func() { _ = s1.Close() }()
+	defer func() { _ = s2.Close() }()
+	require.NoError(t, s1.Attach(filepath.Join(dir, "archive.db"), "archive"))
+
+	// an immediate transaction holds the reserved lock of every database
+	// from the start
+	tx, err := s1.Begin(context.Background(), transaction.Options{Mode: transaction.Immediate})
+	require.NoError(t, err)
+	assert.Len(t, tx.order, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = s2.Begin(ctx, transaction.Options{Mode: transaction.Immediate})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	assert.Nil(t, s2.Tx())
+
+	// deferred transactions still begin, and can read
+	deferred, err := s2.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	_, err = deferred.On(Main)
+	require.NoError(t, err)
+	require.NoError(t, deferred.Rollback())
+
+	require.NoError(t, tx.Commit())
+	tx, err = s2.Begin(context.Background(), transaction.Options{Mode: transaction.Exclusive})
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+}
+
+func TestExec(t *testing.T) {
+	s, dir := openTestSession(t)
+
+	p := parser.New("ATTACH DATABASE '" + filepath.Join(dir, "shard.db") + "' AS \"shard\"; DETACH shard")
+	stmt, errs, ok := p.Next()
+	require.True(t, ok)
+	require.Empty(t, errs)
+	require.NoError(t, s.ExecAttach(stmt.AttachStmt))
+	db, ok := s.Database("shard")
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "shard.db"), db.File)
+
+	stmt, errs, ok = p.Next()
+	require.True(t, ok)
+	require.Empty(t, errs)
+	require.NoError(t, s.ExecDetach(stmt.DetachStmt))
+	assert.Equal(t, []string{"main"}, names(s))
+}
+
+func TestExecVacuum(t *testing.T) {
+	s, dir := openTestSession(t)
+	require.NoError(t, s.Attach(filepath.Join(dir, "shard.db"), "shard"))
+	copyName := filepath.Join(dir, "copy.db")
+
+	p := parser.New("VACUUM; VACUUM shard INTO '" + copyName + "'; VACUUM other")
+	exec := func() error {
+		stmt, errs, ok := p.Next()
+		require.True(t, ok)
+		require.Empty(t, errs)
+		return s.ExecVacuum(context.Background(), stmt.VacuumStmt)
+	}
+
+	tx, err := s.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, ErrVacuumInTx, s.ExecVacuum(context.Background(), &ast.VacuumStmt{}))
+	require.NoError(t, tx.Rollback())
+
+	assert.NoError(t, exec())
+	_, err = os.Stat(copyName)
+	assert.True(t, os.IsNotExist(err))
+	assert.NoError(t, exec())
+	_, err = os.Stat(copyName)
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(exec(), ErrNoSuchDatabase))
+}
+
+func TestResolve(t *testing.T) {
+	s, dir := openTestSession(t)
+	require.NoError(t, s.Attach(filepath.Join(dir, "a.db"), "a"))
+	require.NoError(t, s.Attach(filepath.Join(dir, "b.db"), "b"))
+
+	tables := map[string][]string{
+		"main": {"users"},
+		"a":    {"users", "orders"},
+		"b":    {"orders", "items"},
+	}
+	has := func(db *Database, table string) bool {
+		for _, t := range tables[db.Name] {
+			if t == table {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		schema, table string
+		want          string
+		err           error
+	}{
+		{"", "users", "main", nil},
+		{"", "orders", "a", nil},
+		{"", "items", "b", nil},
+		{"b", "orders", "b", nil},
+		{"A", "users", "a", nil},
+		{"main", "items", "", ErrNoSuchTable},
+		{"c", "items", "", ErrNoSuchDatabase},
+		{"", "invoices", "", ErrNoSuchTable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema+"."+tt.table, func(t *testing.T) {
+			db, err := s.Resolve(tt.schema, tt.table, has)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, db.Name)
+		})
+	}
+}
+
+func TestTx(t *testing.T) {
+	s, dir := openTestSession(t)
+	archive := filepath.Join(dir, "archive.db")
+	require.NoError(t, s.Attach(archive, "archive"))
+
+	// move a row from the main database to the archive
+	tx, err := s.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	_, err = s.Begin(context.Background(), transaction.Options{})
+	assert.Equal(t, ErrTxActive, err)
+	main, err := tx.On("main")
+	require.NoError(t, err)
+	require.NoError(t, main.Put([]byte("order:1"), []byte("shipped")))
+	require.NoError(t, tx.Commit())
+
+	tx, err = s.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	main, err = tx.On("main")
+	require.NoError(t, err)
+	arch, err := tx.On("archive")
+	require.NoError(t, err)
+	value, _, err := main.Get([]byte("order:1"))
+	require.NoError(t, err)
+	require.NoError(t, arch.Put([]byte("order:1"), value))
+	require.NoError(t, main.Delete([]byte("order:1")))
+	assert.True(t, errors.Is(s.Detach("archive"), ErrLocked))
+	_, err = tx.On("other")
+	assert.True(t, errors.Is(err, ErrNoSuchDatabase))
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, transaction.ErrTxDone, tx.Commit())
+
+	// a rolled back transaction changes nothing
+	tx, err = s.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	arch, err = tx.On("archive")
+	require.NoError(t, err)
+	require.NoError(t, arch.Delete([]byte("order:1")))
+	require.NoError(t, tx.Rollback())
+
+	require.NoError(t, s.Detach("archive"))
+	require.NoError(t, s.Close())
+
+	s, err = Open(wal.OS, filepath.Join(dir, "main.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+	require.NoError(t, s.Attach(archive, "archive"))
+	tx, err = s.Begin(context.Background(), transaction.Options{ReadOnly: true})
+	require.NoError(t, err)
+	main, err = tx.On("main")
+	require.NoError(t, err)
+	arch, err = tx.On("archive")
+	require.NoError(t, err)
+	_, ok, err := main.Get([]byte("order:1"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	value, ok, err = arch.Get([]byte("order:1"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "shipped", string(value))
+	require.NoError(t, tx.Rollback())
+}
+
+func TestSavepoint(t *testing.T) {
+	ctx := context.Background()
+	s, dir := openTestSession(t)
+	require.NoError(t, s.Attach(filepath.Join(dir, "archive.db"), "archive"))
+
+	get := func(tx *Tx, name, key string) string {
+		t.Helper()
+		db, err := tx.On(name)
+		require.NoError(t, err)
+		value, _, err := db.Get([]byte(key))
+		require.NoError(t, err)
+		return string(value)
+	}
+	put := func(tx *Tx, name, key, value string) {
+		t.Helper()
+		db, err := tx.On(name)
+		require.NoError(t, err)
+		require.NoError(t, db.Put([]byte(key), []byte(value)))
+	}
+
+	assert.True(t, errors.Is(s.Commit(), ErrNoTx))
+	assert.True(t, errors.Is(s.Rollback(), ErrNoTx))
+	assert.True(t, errors.Is(s.Release("a"), transaction.ErrNoSavepoint))
+	assert.True(t, errors.Is(s.RollbackTo("a"), transaction.ErrNoSavepoint))
+
+	// a savepoint outside of a transaction begins one
+	require.NoError(t, s.Savepoint(ctx, "a"))
+	tx := s.Tx()
+	require.NotNil(t, tx)
+	put(tx, "main", "k", "1")
+	require.NoError(t, s.Savepoint(ctx, "b"))
+	put(tx, "main", "k", "2")
+	// the archive is accessed after the savepoint was created
+	put(tx, "archive", "k", "2")
+
+	require.NoError(t, s.RollbackTo("B"))
+	assert.Equal(t, "1", get(tx, "main", "k"))
+	assert.Equal(t, "", get(tx, "archive", "k"))
+	put(tx, "archive", "k", "3")
+	// the savepoint is kept after rolling back to it
+	require.NoError(t, s.RollbackTo("b"))
+	assert.Equal(t, "", get(tx, "archive", "k"))
+	put(tx, "archive", "k", "4")
+	require.NoError(t, s.Release("b"))
+	assert.True(t, errors.Is(s.Release("b"), transaction.ErrNoSavepoint))
+	assert.Same(t, tx, s.Tx())
+
+	// releasing the outermost savepoint commits the transaction
+	require.NoError(t, s.Release("a"))
+	assert.Nil(t, s.Tx())
+
+	// the savepoint of an explicit transaction does not commit it
+	tx, err := s.Begin(ctx, transaction.Options{ReadOnly: true})
+	require.NoError(t, err)
+	require.NoError(t, s.Savepoint(ctx, "a"))
+	require.NoError(t, s.Release("a"))
+	assert.Same(t, tx, s.Tx())
+	assert.Equal(t, "1", get(tx, "main", "k"))
+	assert.Equal(t, "4", get(tx, "archive", "k"))
+	require.NoError(t, s.Rollback())
+	assert.Nil(t, s.Tx())
+}
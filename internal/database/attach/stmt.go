@@ -0,0 +1,52 @@
+package attach
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// ExecAttach executes the given ATTACH statement. The file name must be a
+// literal.
+func (s *Session) ExecAttach(stmt *ast.AttachStmt) error {
+	if stmt.Expr == nil || stmt.Expr.LiteralValue == nil || stmt.SchemaName == nil {
+		return ErrNotLiteral
+	}
+	return s.Attach(token.Unquote(stmt.Expr.LiteralValue), token.Unquote(stmt.SchemaName))
+}
+
+// ExecDetach executes the given DETACH statement.
+func (s *Session) ExecDetach(stmt *ast.DetachStmt) error {
+	if stmt.SchemaName == nil {
+		return fmt.Errorf("%w: missing schema name", ErrNoSuchDatabase)
+	}
+	return s.Detach(token.Unquote(stmt.SchemaName))
+}
+
+// ExecVacuum executes the given VACUUM statement on the database with the
+// schema name of the statement, or the main database. With INTO, a compacted
+// copy is written to the given file instead. Like in SQLite, VACUUM cannot be
+// executed within a transaction.
+func (s *Session) ExecVacuum(ctx context.Context, stmt *ast.VacuumStmt) error {
+	if s.tx != nil {
+		return ErrVacuumInTx
+	}
+	name := Main
+	if stmt.SchemaName != nil {
+		name = token.Unquote(stmt.SchemaName)
+	}
+	db, ok := s.Database(name)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrNoSuchDatabase, name)
+	}
+
+	if stmt.Into != nil {
+		if stmt.Filename == nil {
+			return ErrNotLiteral
+		}
+		return db.Manager.VacuumInto(token.Unquote(stmt.Filename))
+	}
+	return db.Manager.Compact(ctx)
+}
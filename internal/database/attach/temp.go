@@ -0,0 +1,107 @@
+package attach
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// tempFile is the name of the file of the temp database, in the private
+// directory of the session.
+const tempFile = "temp.db"
+
+// TempDatabase returns the temp database of the session, and creates it when
+// it is first used. The temp database is stored in a new directory in the
+// temporary directory of the operating system, that only the session uses.
+// It is deleted when the session is closed.
+func (s *Session) TempDatabase() (*Database, error) {
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+	if s.temp != nil {
+		return s.temp, nil
+	}
+
+	dir, err := os.MkdirTemp("", "lbadd-temp-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp database: %w", err)
+	}
+	file := filepath.Join(dir, tempFile)
+	m, err := transaction.Open(wal.OS, file)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("create temp database: %w", err)
+	}
+	s.temp = &Database{Name: Temp, File: file, Manager: m}
+	s.tempDir = dir
+	return s.temp, nil
+}
+
+// closeTemp closes the temp database, if it was created, and deletes its
+// directory.
+func (s *Session) closeTemp() error {
+	if s.temp == nil {
+		return nil
+	}
+	err := s.temp.Manager.Close()
+	if removeErr := os.RemoveAll(s.tempDir); err == nil {
+		err = removeErr
+	}
+	s.temp, s.tempDir = nil, ""
+	return err
+}
+
+// CreateTable returns the database, in which the given CREATE TABLE
+// statement creates the table. CREATE TEMP TABLE creates the table in the
+// temp database, which is created if necessary.
+func (s *Session) CreateTable(stmt *ast.CreateTableStmt) (*Database, error) {
+	return s.createIn(stmt.Temp, stmt.Temporary, stmt.SchemaName)
+}
+
+// CreateView returns the database, in which the given CREATE VIEW statement
+// creates the view. CREATE TEMP VIEW creates the view in the temp database,
+// which is created if necessary.
+func (s *Session) CreateView(stmt *ast.CreateViewStmt) (*Database, error) {
+	return s.createIn(stmt.Temp, stmt.Temporary, stmt.SchemaName)
+}
+
+// CreateTrigger returns the database, in which the given CREATE TRIGGER
+// statement creates the trigger. CREATE TEMP TRIGGER creates the trigger in
+// the temp database, which is created if necessary.
+func (s *Session) CreateTrigger(stmt *ast.CreateTriggerStmt) (*Database, error) {
+	return s.createIn(stmt.Temp, stmt.Temporary, stmt.SchemaName)
+}
+
+// createIn returns the database, in which a CREATE statement with the given
+// tokens creates its object. Like in SQLite, the name of a temporary object
+// can only be qualified with the schema name temp, and an object that is
+// created in the schema temp is temporary.
+func (s *Session) createIn(temp, temporary, schema token.Token) (*Database, error) {
+	name := Main
+	if schema != nil {
+		name = token.Unquote(schema)
+	}
+	if temp != nil || temporary != nil {
+		if schema != nil && !strings.EqualFold(name, Temp) {
+			return nil, fmt.Errorf("%w: %v", ErrQualifiedTemp, name)
+		}
+		name = Temp
+	}
+	if strings.EqualFold(name, Temp) {
+		return s.TempDatabase()
+	}
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+	db, ok := s.Database(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNoSuchDatabase, name)
+	}
+	return db, nil
+}
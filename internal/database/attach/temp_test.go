@@ -0,0 +1,136 @@
+package attach
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+func literal(v string) token.Token {
+	return token.New(1, 1, 0, len(v), token.Literal, v)
+}
+
+func TestCreateIn(t *testing.T) {
+	s, dir := openTestSession(t)
+	require.NoError(t, s.Attach(filepath.Join(dir, "archive.db"), "archive"))
+	temp := token.New(1, 8, 7, 4, token.KeywordTemp, "TEMP")
+
+	tests := []struct {
+		name      string
+		temp      token.Token
+		temporary token.Token
+		schema    token.Token
+		want      string
+		err       error
+	}{
+		{"table", nil, nil, nil, Main, nil},
+		{"archive table", nil, nil, literal("archive"), "archive", nil},
+		{"temp table", temp, nil, nil, Temp, nil},
+		{"temporary table", nil, literal("TEMPORARY"), nil, Temp, nil},
+		{"qualified temp table", temp, nil, literal(`"TEMP"`), Temp, nil},
+		{"table in temp", nil, nil, literal("temp"), Temp, nil},
+		{"temp table in main", temp, nil, literal("main"), "", ErrQualifiedTemp},
+		{"temp table in archive", temp, nil, literal("archive"), "", ErrQualifiedTemp},
+		{"table in unknown", nil, nil, literal("other"), "", ErrNoSuchDatabase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := s.CreateTable(&ast.CreateTableStmt{Temp: tt.temp, Temporary: tt.temporary, SchemaName: tt.schema})
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, db.Name)
+		})
+	}
+
+	db, err := s.CreateView(&ast.CreateViewStmt{Temporary: literal("TEMPORARY")})
+	require.NoError(t, err)
+	assert.Equal(t, Temp, db.Name)
+	db, err = s.CreateTrigger(&ast.CreateTriggerStmt{})
+	require.NoError(t, err)
+	assert.Equal(t, Main, db.Name)
+}
+
+func TestTemp(t *testing.T) {
+	s, dir := openTestSession(t)
+	require.NoError(t, s.Attach(filepath.Join(dir, "archive.db"), "archive"))
+
+	// the temp database is only created when it is used
+	_, ok := s.Database("temp")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"main", "archive"}, names(s))
+	tables := map[string][]string{
+		"main": {"users", "orders"},
+		"temp": {"users"},
+	}
+	has := func(db *Database, table string) bool {
+		for _, t := range tables[db.Name] {
+			if t == table {
+				return true
+			}
+		}
+		return false
+	}
+	_, err := s.Resolve("temp", "users", has)
+	assert.True(t, errors.Is(err, ErrNoSuchTable))
+	db, err := s.Resolve("", "users", has)
+	require.NoError(t, err)
+	assert.Equal(t, Main, db.Name)
+
+	tx, err := s.Begin(context.Background(), transaction.Options{})
+	require.NoError(t, err)
+	temp, err := tx.On("TEMP")
+	require.NoError(t, err)
+	require.NoError(t, temp.Put([]byte("staging:1"), []byte("row")))
+	require.NoError(t, tx.Commit())
+
+	assert.Equal(t, []string{"main", "temp", "archive"}, names(s))
+	assert.True(t, errors.Is(s.Detach("temp"), ErrCannotDetach))
+	assert.True(t, errors.Is(s.Attach(filepath.Join(dir, "temp.db"), "temp"), ErrReserved))
+
+	// temporary tables hide the tables of the other databases
+	db, err = s.Resolve("", "users", has)
+	require.NoError(t, err)
+	assert.Equal(t, Temp, db.Name)
+	db, err = s.Resolve("", "orders", has)
+	require.NoError(t, err)
+	assert.Equal(t, Main, db.Name)
+	db, err = s.Resolve("main", "users", has)
+	require.NoError(t, err)
+	assert.Equal(t, Main, db.Name)
+
+	// other sessions don't see the temp database of the session
+	other, err := Open(wal.OS, filepath.Join(dir, "main.db"))
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+	_, ok = other.Database("temp")
+	assert.False(t, ok)
+	otherTemp, err := other.TempDatabase()
+	require.NoError(t, err)
+	tempDB, _ := s.Database("temp")
+	assert.NotEqual(t, tempDB.File, otherTemp.File)
+	otherTx, err := otherTemp.Manager.Begin(context.Background(), transaction.Options{ReadOnly: true})
+	require.NoError(t, err)
+	_, ok, err = otherTx.Get([]byte("staging:1"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, otherTx.Rollback())
+
+	// the temp database is deleted when the session is closed
+	require.NoError(t, s.Close())
+	_, err = os.Stat(filepath.Dir(tempDB.File))
+	assert.True(t, os.IsNotExist(err))
+	_, err = s.TempDatabase()
+	assert.Equal(t, ErrSessionClosed, err)
+}
@@ -0,0 +1,269 @@
+package attach
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+)
+
+// Tx is a transaction of a session, that can span all databases of the
+// session. It begins a transaction in a database when the database is first
+// accessed, with the context and options that were given to Begin.
+type Tx struct {
+	s    *Session
+	ctx  context.Context
+	opts transaction.Options
+	// txs holds the transactions of the accessed databases.
+	txs map[*Database]*transaction.Tx
+	// order holds the accessed databases, in the order in which they were
+	// accessed.
+	order []*Database
+	// savepoints holds the names of the savepoints, from the oldest to the
+	// most recent. They are created in every accessed database, also in
+	// databases that are accessed after the savepoint was created.
+	savepoints []string
+	// implicit is set, if the transaction was begun by a savepoint outside
+	// of a transaction. It is committed when its outermost savepoint is
+	// released.
+	implicit bool
+}
+
+// Begin starts a transaction. A session can only have one active
+// transaction. Like in SQLite, a deferred transaction only begins in a
+// database when the database is first accessed, while immediate and exclusive
+// transactions begin in all attached databases right away. Begin then waits
+// for their locks until the context is done, and fails if they cannot be
+// acquired.
+func (s *Session) Begin(ctx context.Context, opts transaction.Options) (*Tx, error) {
+	if s.closed {
+		return nil, ErrSessionClosed
+	}
+	if s.tx != nil {
+		return nil, ErrTxActive
+	}
+	tx := &Tx{
+		s:    s,
+		ctx:  ctx,
+		opts: opts,
+		txs:  make(map[*Database]*transaction.Tx),
+	}
+	s.tx = tx
+	if opts.Mode != transaction.Deferred {
+		for _, db := range s.Databases() {
+			if _, err := tx.On(db.Name); err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+	return tx, nil
+}
+
+// Tx returns the active transaction of the session, or nil if there is none.
+func (s *Session) Tx() *Tx {
+	return s.tx
+}
+
+// On returns the transaction of the database with the given schema name,
+// and begins it if the database has not been accessed yet. The temp database
+// is created if necessary.
+func (tx *Tx) On(name string) (*transaction.Tx, error) {
+	if tx.s.tx != tx {
+		return nil, transaction.ErrTxDone
+	}
+	db, ok := tx.s.Database(name)
+	if !ok && strings.EqualFold(name, Temp) {
+		var err error
+		if db, err = tx.s.TempDatabase(); err != nil {
+			return nil, err
+		}
+		ok = true
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNoSuchDatabase, name)
+	}
+	if t, ok := tx.txs[db]; ok {
+		return t, nil
+	}
+
+	t, err := db.Manager.Begin(tx.ctx, tx.opts)
+	if err != nil {
+		return nil, fmt.Errorf("begin on %v: %w", db.Name, err)
+	}
+	for _, name := range tx.savepoints {
+		if err := t.Savepoint(name); err != nil {
+			_ = t.Rollback()
+			return nil, err
+		}
+	}
+	tx.txs[db] = t
+	tx.order = append(tx.order, db)
+	return t, nil
+}
+
+// Savepoint creates a savepoint with the given name in all databases, like
+// (*transaction.Tx).Savepoint.
+func (tx *Tx) Savepoint(name string) error {
+	if tx.s.tx != tx {
+		return transaction.ErrTxDone
+	}
+	for _, db := range tx.order {
+		if err := tx.txs[db].Savepoint(name); err != nil {
+			return err
+		}
+	}
+	tx.savepoints = append(tx.savepoints, name)
+	return nil
+}
+
+// Release releases the most recent savepoint with the given name in all
+// databases, like (*transaction.Tx).Release.
+func (tx *Tx) Release(name string) error {
+	i, err := tx.savepoint(name)
+	if err != nil {
+		return err
+	}
+	for _, db := range tx.order {
+		if err := tx.txs[db].Release(name); err != nil {
+			return err
+		}
+	}
+	tx.savepoints = tx.savepoints[:i]
+	return nil
+}
+
+// RollbackTo rolls back all databases to the most recent savepoint with the
+// given name, like (*transaction.Tx).RollbackTo.
+func (tx *Tx) RollbackTo(name string) error {
+	i, err := tx.savepoint(name)
+	if err != nil {
+		return err
+	}
+	for _, db := range tx.order {
+		if err := tx.txs[db].RollbackTo(name); err != nil {
+			return err
+		}
+	}
+	tx.savepoints = tx.savepoints[:i+1]
+	return nil
+}
+
+func (tx *Tx) savepoint(name string) (int, error) {
+	if tx.s.tx != tx {
+		return 0, transaction.ErrTxDone
+	}
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if strings.EqualFold(tx.savepoints[i], name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %v", transaction.ErrNoSavepoint, name)
+}
+
+// Commit commits the transactions of all accessed databases atomically.
+func (tx *Tx) Commit() error {
+	if tx.s.tx != tx {
+		return transaction.ErrTxDone
+	}
+	tx.s.tx = nil
+
+	txs := make([]*transaction.Tx, len(tx.order))
+	for i, db := range tx.order {
+		txs[i] = tx.txs[db]
+	}
+	super, err := tx.s.superJournal()
+	if err != nil {
+		for _, t := range txs {
+			_ = t.Rollback()
+		}
+		return err
+	}
+	return transaction.CommitAll(tx.s.fs, super, txs...)
+}
+
+// Rollback rolls back the transactions of all accessed databases.
+func (tx *Tx) Rollback() error {
+	if tx.s.tx != tx {
+		return transaction.ErrTxDone
+	}
+	tx.s.tx = nil
+
+	var firstErr error
+	for _, db := range tx.order {
+		if err := tx.txs[db].Rollback(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Commit commits the active transaction, like COMMIT.
+func (s *Session) Commit() error {
+	if s.tx == nil {
+		return fmt.Errorf("cannot commit: %w", ErrNoTx)
+	}
+	return s.tx.Commit()
+}
+
+// Rollback rolls back the active transaction, like ROLLBACK.
+func (s *Session) Rollback() error {
+	if s.tx == nil {
+		return fmt.Errorf("cannot rollback: %w", ErrNoTx)
+	}
+	return s.tx.Rollback()
+}
+
+// Savepoint creates a savepoint in the active transaction, like SAVEPOINT.
+// Outside of a transaction, a deferred transaction is begun with the given
+// context, which is committed when the savepoint is released.
+func (s *Session) Savepoint(ctx context.Context, name string) error {
+	tx := s.tx
+	if tx == nil {
+		var err error
+		if tx, err = s.Begin(ctx, transaction.Options{}); err != nil {
+			return err
+		}
+		tx.implicit = true
+	}
+	return tx.Savepoint(name)
+}
+
+// Release releases a savepoint of the active transaction, like RELEASE. If
+// the transaction was begun by Savepoint, and the released savepoint was its
+// outermost one, the transaction is committed.
+func (s *Session) Release(name string) error {
+	if s.tx == nil {
+		return fmt.Errorf("%w: %v", transaction.ErrNoSavepoint, name)
+	}
+	if err := s.tx.Release(name); err != nil {
+		return err
+	}
+	if s.tx.implicit && len(s.tx.savepoints) == 0 {
+		return s.tx.Commit()
+	}
+	return nil
+}
+
+// RollbackTo rolls back the active transaction to a savepoint, like ROLLBACK
+// TO. The savepoint and the transaction remain active.
+func (s *Session) RollbackTo(name string) error {
+	if s.tx == nil {
+		return fmt.Errorf("%w: %v", transaction.ErrNoSavepoint, name)
+	}
+	return s.tx.RollbackTo(name)
+}
+
+// superJournal returns a new name for a super-journal, which is the name of
+// the main database file, followed by "-mj" and a random suffix, like in
+// SQLite.
+func (s *Session) superJournal() (string, error) {
+	var suffix [4]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", fmt.Errorf("super-journal name: %w", err)
+	}
+	return s.databases[0].File + "-mj" + hex.EncodeToString(suffix[:]), nil
+}
@@ -0,0 +1,42 @@
+package constraint
+
+//go:generate stringer -type=Conflict
+
+// Conflict is a conflict resolution algorithm, which defines how a violation
+// of a constraint is resolved.
+type Conflict uint8
+
+// Known conflict resolution algorithms.
+const (
+	// Default uses the algorithm of the violated constraint, or Abort if the
+	// constraint doesn't define one.
+	Default Conflict = iota
+	// Rollback reverts the statement and returns a violation, after which
+	// the caller must roll back the transaction.
+	Rollback
+	// Abort reverts the statement and returns a violation. The transaction
+	// remains active.
+	Abort
+	// Fail returns a violation, but keeps the changes that the statement
+	// made before the violation.
+	Fail
+	// Ignore skips the row that violates the constraint, and continues the
+	// statement.
+	Ignore
+	// Replace deletes the rows that violate a UNIQUE or PRIMARY KEY
+	// constraint, and replaces a NULL in a NOT NULL column with its default
+	// value. Other violations are resolved with Abort.
+	Replace
+)
+
+// resolve returns the algorithm that resolves a violation of a constraint
+// with the given algorithm, in a statement with this algorithm.
+func (c Conflict) resolve(constraint Conflict) Conflict {
+	switch {
+	case c != Default:
+		return c
+	case constraint != Default:
+		return constraint
+	}
+	return Abort
+}
@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=Conflict ./internal/database/constraint"; DO NOT EDIT.
+
+package constraint
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Default-0]
+	_ = x[Rollback-1]
+	_ = x[Abort-2]
+	_ = x[Fail-3]
+	_ = x[Ignore-4]
+	_ = x[Replace-5]
+}
+
+const _Conflict_name = "DefaultRollbackAbortFailIgnoreReplace"
+
+var _Conflict_index = [...]uint8{0, 7, 15, 20, 24, 30, 37}
+
+func (i Conflict) String() string {
+	if i >= Conflict(len(_Conflict_index)-1) {
+		return "Conflict(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Conflict_name[_Conflict_index[i]:_Conflict_index[i+1]]
+}
@@ -0,0 +1,21 @@
+// Package constraint enforces the constraints of a table while a statement
+// inserts, updates or deletes rows. It checks NOT NULL, CHECK, UNIQUE and
+// PRIMARY KEY constraints, fills in DEFAULT values, assigns row IDs, including
+// AUTOINCREMENT sequences, and resolves violations with the conflict
+// resolution algorithms ROLLBACK, ABORT, FAIL, IGNORE and REPLACE.
+//
+// The rows of a table are kept by the caller, and accessed through the Rows
+// interface. The indexes of the constraints are built from the rows in memory,
+// unless the rows are StoredRows, which keep the indexes in the same store as
+// the rows. Secondary indexes of IndexedRows are kept up to date with the
+// rows. Every statement keeps an undo log of its changes, so that it can
+// be reverted, if a constraint is violated.
+//
+// Foreign keys span multiple tables, so they are enforced by a Schema, which
+// holds the enforcers of all tables. Like in SQLite, a statement counts the
+// child rows that it leaves without a parent. Immediate foreign keys must have
+// no violations at the end of the statement, and deferred foreign keys at the
+// end of the transaction. The actions CASCADE, SET NULL and SET DEFAULT
+// change child rows, which are found with an index on the child columns of
+// every foreign key.
+package constraint
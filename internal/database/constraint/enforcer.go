@@ -0,0 +1,259 @@
+package constraint
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// Enforcer enforces the constraints of a table. It maintains a unique index
+// for every UNIQUE and PRIMARY KEY constraint, except for an INTEGER PRIMARY
+// KEY, which is enforced by the row IDs. All changes to the rows of the table
+// must be made through the statements of the Enforcer. An Enforcer is not
+// safe for concurrent use.
+type Enforcer struct {
+	table Table
+	rows  Rows
+	seq   *Sequences
+	// schema is the schema that the table was added to, which enforces its
+	// foreign keys.
+	schema *Schema
+	// rowID is the index of the INTEGER PRIMARY KEY column, or -1.
+	rowID int
+	// onRowID resolves violations of the INTEGER PRIMARY KEY.
+	onRowID Conflict
+	// uniques holds the UNIQUE and PRIMARY KEY constraints, that are
+	// enforced by the index with the same position in indexes.
+	uniques []UniqueConstraint
+	indexes []*index.Index[Row]
+	// fks holds the foreign keys of the table, with an index on their
+	// child columns.
+	fks []*foreignKey
+}
+
+// New creates an enforcer for the given table, whose rows are stored in the
+// given rows. The indexes of the constraints are built from the existing
+// rows, unless they are StoredRows, which already hold the indexes. The
+// sequences are only used by tables with an AUTOINCREMENT column.
+func New(table Table, rows Rows, seq *Sequences) (*Enforcer, error) {
+	if err := table.validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Enforcer{
+		table: table,
+		rows:  rows,
+		seq:   seq,
+		rowID: table.rowID(),
+	}
+	for i, u := range table.Uniques {
+		if u.PrimaryKey && len(u.Columns) == 1 && u.Columns[0] == e.rowID {
+			e.onRowID = u.OnConflict
+			continue
+		}
+
+		ix, err := e.buildIndex(fmt.Sprintf("autoindex_%s_%d", table.Name, i+1), 'u', i, true, u.Columns)
+		if err != nil {
+			return nil, err
+		}
+		e.uniques = append(e.uniques, u)
+		e.indexes = append(e.indexes, ix)
+	}
+	for i, fk := range table.ForeignKeys {
+		ix, err := e.buildIndex(fmt.Sprintf("fkindex_%s_%d", table.Name, i+1), 'f', i, false, fk.Columns)
+		if err != nil {
+			return nil, err
+		}
+		e.fks = append(e.fks, &foreignKey{ForeignKeyConstraint: fk, child: e, index: ix})
+	}
+	return e, nil
+}
+
+// buildIndex builds an index on the given columns of the existing rows. If
+// the rows are StoredRows, the index is opened in their store instead, under
+// a prefix made of the given kind and position of the constraint.
+func (e *Enforcer) buildIndex(name string, kind byte, i int, unique bool, columns []int) (*index.Index[Row], error) {
+	def := index.Definition[Row]{
+		Name:    name,
+		Unique:  unique,
+		Columns: make([]index.Column, len(columns)),
+		Key: func(row Row) (index.Key, error) {
+			key := make(index.Key, len(columns))
+			for i, c := range columns {
+				key[i] = Encode(row[c])
+			}
+			return key, nil
+		},
+	}
+	if stored, ok := e.rows.(StoredRows); ok {
+		if store, prefix := stored.IndexStore(); store != nil {
+			prefix = append(append([]byte{}, prefix...), kind, 0, 0, 0, 0)
+			binary.BigEndian.PutUint32(prefix[len(prefix)-4:], uint32(i))
+			return index.Open(def, store, prefix), nil
+		}
+	}
+
+	ix := index.New(def)
+	if err := ix.Build(e.rows.Scan); err != nil {
+		return nil, fmt.Errorf("build index of %v: %w", e.table.columnNames(columns), err)
+	}
+	return ix, nil
+}
+
+// Table returns the table, whose constraints are enforced.
+func (e *Enforcer) Table() Table {
+	return e.table
+}
+
+// Begin starts a new statement. A conflict algorithm other than Default, like
+// in INSERT OR REPLACE, overrides the algorithms of all constraints.
+func (e *Enforcer) Begin(or Conflict) *Statement {
+	s := &Statement{e: e, or: or}
+	if e.schema != nil {
+		s.deferred = e.schema.deferred
+	}
+	return s
+}
+
+// foreignKeys reports whether the foreign keys of the table, and those that
+// reference it, are enforced.
+func (e *Enforcer) foreignKeys() bool {
+	return e.schema != nil && e.schema.enabled
+}
+
+// secondary returns the secondary indexes of the table.
+func (e *Enforcer) secondary() []*index.Index[Row] {
+	if indexed, ok := e.rows.(IndexedRows); ok {
+		return indexed.Indexes()
+	}
+	return nil
+}
+
+// uniqueIndex is an index, that enforces uniqueness.
+type uniqueIndex struct {
+	index      *index.Index[Row]
+	onConflict Conflict
+	// kind and constraint describe the violation of the index.
+	kind       Kind
+	constraint string
+}
+
+// uniqueIndexes returns the indexes of the UNIQUE and PRIMARY KEY
+// constraints, followed by the unique secondary indexes.
+func (e *Enforcer) uniqueIndexes() []uniqueIndex {
+	var indexes []uniqueIndex
+	for i, ix := range e.indexes {
+		u := e.uniques[i]
+		kind := Unique
+		if u.PrimaryKey {
+			kind = PrimaryKey
+		}
+		indexes = append(indexes, uniqueIndex{ix, u.OnConflict, kind, e.table.columnNames(u.Columns)})
+	}
+	for _, ix := range e.secondary() {
+		if def := ix.Definition(); def.Unique {
+			indexes = append(indexes, uniqueIndex{ix, Default, Unique, "index '" + def.Name + "'"})
+		}
+	}
+	return indexes
+}
+
+// insert stores a new row and adds it to the indexes.
+func (e *Enforcer) insert(id index.RowID, row Row) error {
+	if err := e.rows.Put(id, row); err != nil {
+		return err
+	}
+	for _, ix := range e.secondary() {
+		if err := ix.Insert(id, row); err != nil {
+			return err
+		}
+	}
+	for _, ix := range e.indexes {
+		if err := ix.Insert(id, row); err != nil {
+			return err
+		}
+	}
+	for _, fk := range e.fks {
+		if err := fk.index.Insert(id, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delete removes the given row from the indexes and deletes it.
+func (e *Enforcer) delete(id index.RowID, row Row) error {
+	for _, ix := range e.secondary() {
+		if err := ix.Delete(id, row); err != nil {
+			return err
+		}
+	}
+	for _, ix := range e.indexes {
+		if err := ix.Delete(id, row); err != nil {
+			return err
+		}
+	}
+	for _, fk := range e.fks {
+		if err := fk.index.Delete(id, row); err != nil {
+			return err
+		}
+	}
+	return e.rows.Delete(id)
+}
+
+// autoincrement reports whether the table has an AUTOINCREMENT column.
+func (e *Enforcer) autoincrement() bool {
+	return e.rowID >= 0 && e.table.Columns[e.rowID].Autoincrement
+}
+
+// nextRowID returns the row ID for a new row, that doesn't specify one.
+func (e *Enforcer) nextRowID() (index.RowID, error) {
+	last, ok, err := e.rows.Last()
+	if err != nil {
+		return 0, err
+	}
+	if e.autoincrement() {
+		if !ok {
+			last = 0
+		}
+		return e.seq.next(e.table.Name, last)
+	}
+	switch {
+	case !ok:
+		return 1, nil
+	case last == 1<<63-1:
+		return 0, ErrFull
+	}
+	return last + 1, nil
+}
+
+// conflicts returns the rows, other than the row with ID self, that have the
+// same key as the given row in the given index.
+func (e *Enforcer) conflicts(ix *index.Index[Row], row Row, self index.RowID) ([]index.RowID, error) {
+	def := ix.Definition()
+	if def.Where != nil {
+		ok, err := def.Where(row)
+		if err != nil || !ok {
+			return nil, err
+		}
+	}
+	key, err := def.Key(row)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range key {
+		if v == nil {
+			return nil, nil
+		}
+	}
+
+	var ids []index.RowID
+	err = ix.Lookup(key, func(id index.RowID) bool {
+		if id != self {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	return ids, err
+}
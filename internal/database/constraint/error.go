@@ -0,0 +1,23 @@
+package constraint
+
+// Error provides constant errors to the constraint package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	// ErrConstraint is matched by all constraint violations with errors.Is.
+	ErrConstraint         = Error("constraint failed")
+	ErrStatementDone      = Error("statement has already been completed")
+	ErrColumnCount        = Error("number of values does not match the number of columns")
+	ErrNoSuchColumn       = Error("no such column")
+	ErrNoSuchRow          = Error("no such row")
+	ErrMismatch           = Error("datatype mismatch")
+	ErrFull               = Error("database or disk is full")
+	ErrAutoincrement      = Error("AUTOINCREMENT is only allowed on an INTEGER PRIMARY KEY")
+	ErrInvalidColumns     = Error("constraint refers to an invalid column")
+	ErrForeignKeyMismatch = Error("foreign key mismatch")
+	ErrTableExists        = Error("table already exists")
+	ErrNoSuchTable        = Error("no such table")
+)
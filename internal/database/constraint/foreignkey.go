@@ -0,0 +1,210 @@
+package constraint
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+//go:generate stringer -type=Action
+
+// Action is the action of a foreign key, that is taken when a parent key is
+// deleted or updated.
+type Action uint8
+
+// Known actions.
+const (
+	// NoAction counts the child rows that reference a missing parent key as
+	// violations, which must be resolved by the end of the statement, or by
+	// the end of the transaction for deferred foreign keys.
+	NoAction Action = iota
+	// Restrict fails immediately, if a parent key with child rows is
+	// deleted or updated, even if the foreign key is deferred.
+	Restrict
+	// SetNull sets the child key of the child rows to NULL.
+	SetNull
+	// SetDefault sets the child key of the child rows to its default
+	// value.
+	SetDefault
+	// Cascade deletes the child rows, if the parent row is deleted, and
+	// updates the child key, if the parent key is updated.
+	Cascade
+)
+
+// ForeignKeyConstraint is a FOREIGN KEY constraint of a child table. It
+// requires the values of the child columns to be a parent key, that is the
+// values of the parent columns of a row of the parent table. Child keys with
+// a NULL value satisfy the constraint.
+type ForeignKeyConstraint struct {
+	Columns []int
+	Parent  string
+	// ParentColumns are the names of the parent columns. They must be
+	// covered by a UNIQUE or PRIMARY KEY constraint of the parent table. If
+	// they are empty, the PRIMARY KEY of the parent table is used.
+	ParentColumns []string
+	OnDelete      Action
+	OnUpdate      Action
+	// Deferred indicates that violations are checked when the transaction
+	// commits, like DEFERRABLE INITIALLY DEFERRED.
+	Deferred bool
+}
+
+// foreignKey is a foreign key of an enforcer, with an index on the child
+// columns.
+type foreignKey struct {
+	ForeignKeyConstraint
+	child *Enforcer
+	index *index.Index[Row]
+}
+
+// parentKey locates the parent rows of a foreign key.
+type parentKey struct {
+	parent *Enforcer
+	// columns are the indexes of the parent columns, in the order of the
+	// child columns.
+	columns []int
+	// unique is the index of the UNIQUE constraint that covers the parent
+	// columns, or -1 if the parent key is the INTEGER PRIMARY KEY.
+	unique int
+	// order maps the columns of the unique index to the position in
+	// columns.
+	order []int
+}
+
+// resolve locates the parent table and the constraint that covers the
+// parent key. It returns false if the parent table doesn't exist.
+func (fk *foreignKey) resolve() (parentKey, bool, error) {
+	schema := fk.child.schema
+	parent, ok := schema.tables[strings.ToLower(fk.Parent)]
+	if !ok {
+		return parentKey{}, false, nil
+	}
+	t := parent.table
+
+	pk := parentKey{parent: parent, unique: -1}
+	if len(fk.ParentColumns) == 0 {
+		if parent.rowID >= 0 {
+			pk.columns = []int{parent.rowID}
+		}
+		for _, u := range t.Uniques {
+			if u.PrimaryKey {
+				pk.columns = u.Columns
+			}
+		}
+	}
+	for _, name := range fk.ParentColumns {
+		c := -1
+		for i, col := range t.Columns {
+			if strings.EqualFold(col.Name, name) {
+				c = i
+			}
+		}
+		if c < 0 {
+			return parentKey{}, false, ErrForeignKeyMismatch
+		}
+		pk.columns = append(pk.columns, c)
+	}
+	if len(pk.columns) != len(fk.Columns) {
+		return parentKey{}, false, ErrForeignKeyMismatch
+	}
+	if len(pk.columns) == 1 && pk.columns[0] == parent.rowID {
+		return pk, true, nil
+	}
+
+	for i, u := range parent.uniques {
+		if order, ok := permutation(u.Columns, pk.columns); ok {
+			pk.unique, pk.order = i, order
+			return pk, true, nil
+		}
+	}
+	return parentKey{}, false, ErrForeignKeyMismatch
+}
+
+// permutation returns the position in columns of every column of the unique
+// constraint, if both contain the same columns.
+func permutation(unique, columns []int) ([]int, bool) {
+	if len(unique) != len(columns) {
+		return nil, false
+	}
+	order := make([]int, len(unique))
+	for i, u := range unique {
+		order[i] = -1
+		for j, c := range columns {
+			if c == u {
+				order[i] = j
+			}
+		}
+		if order[i] < 0 {
+			return nil, false
+		}
+	}
+	return order, true
+}
+
+// childKey returns the values of the child key of the given row, and false if
+// any of them is NULL.
+func (fk *foreignKey) childKey(row Row) (Row, bool) {
+	key := make(Row, len(fk.Columns))
+	for i, c := range fk.Columns {
+		if row[c] == nil {
+			return nil, false
+		}
+		key[i] = row[c]
+	}
+	return key, true
+}
+
+// parentExists reports whether a parent row with the given key exists.
+func (fk *foreignKey) parentExists(key Row) (bool, error) {
+	pk, ok, err := fk.resolve()
+	if err != nil || !ok {
+		return false, err
+	}
+	if pk.unique < 0 {
+		id, ok := rowID(key[0])
+		if !ok {
+			return false, nil
+		}
+		_, exists, err := pk.parent.rows.Get(id)
+		return exists, err
+	}
+
+	lookup := make(index.Key, len(pk.order))
+	for i, j := range pk.order {
+		lookup[i] = Encode(key[j])
+	}
+	exists := false
+	err = pk.parent.indexes[pk.unique].Lookup(lookup, func(index.RowID) bool {
+		exists = true
+		return false
+	})
+	return exists, err
+}
+
+// children returns the IDs of the child rows, that reference the parent key
+// of the given parent row.
+func (fk *foreignKey) children(pk parentKey, parentRow Row) ([]index.RowID, error) {
+	lookup := make(index.Key, len(pk.columns))
+	for i, c := range pk.columns {
+		if parentRow[c] == nil {
+			return nil, nil
+		}
+		lookup[i] = Encode(parentRow[c])
+	}
+	var ids []index.RowID
+	err := fk.index.Lookup(lookup, func(id index.RowID) bool {
+		ids = append(ids, id)
+		return true
+	})
+	return ids, err
+}
+
+// keyChanged reports whether the given columns differ between the rows.
+func keyChanged(columns []int, old, row Row) bool {
+	for _, c := range columns {
+		if string(Encode(old[c])) != string(Encode(row[c])) {
+			return true
+		}
+	}
+	return false
+}
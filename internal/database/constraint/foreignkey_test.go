@@ -0,0 +1,239 @@
+package constraint
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// music creates the tables
+//
+//	CREATE TABLE artists (
+//		id INTEGER PRIMARY KEY,
+//		name TEXT UNIQUE
+//	);
+//	CREATE TABLE tracks (
+//		id INTEGER PRIMARY KEY,
+//		artist INTEGER DEFAULT 1 REFERENCES artists <fk>,
+//		title TEXT
+//	)
+//
+// with the artists (1, 'unknown') and (2, 'queen'), and the tracks
+// (1, 2, 'bohemian rhapsody') and (2, 2, 'we will rock you'). Foreign keys are
+// enforced.
+func music(t *testing.T, fk ForeignKeyConstraint) (schema *Schema, artists, tracks *Enforcer, trackRows memRows) {
+	artistRows := memRows{1: {int64(1), "unknown"}, 2: {int64(2), "queen"}}
+	trackRows = memRows{
+		1: {int64(1), int64(2), "bohemian rhapsody"},
+		2: {int64(2), int64(2), "we will rock you"},
+	}
+
+	artists, err := New(Table{
+		Name:    "artists",
+		Columns: []Column{{Name: "id", RowID: true}, {Name: "name"}},
+		Uniques: []UniqueConstraint{{Columns: []int{0}, PrimaryKey: true}, {Columns: []int{1}}},
+	}, artistRows, nil)
+	require.NoError(t, err)
+
+	fk.Columns, fk.Parent = []int{1}, "artists"
+	tracks, err = New(Table{
+		Name:        "tracks",
+		Columns:     []Column{{Name: "id", RowID: true}, {Name: "artist", Default: constant(int64(1))}, {Name: "title"}},
+		ForeignKeys: []ForeignKeyConstraint{fk},
+	}, trackRows, nil)
+	require.NoError(t, err)
+
+	schema = NewSchema()
+	require.NoError(t, schema.Add(artists))
+	require.NoError(t, schema.Add(tracks))
+	schema.SetForeignKeys(true)
+	return
+}
+
+func requireForeignKeyViolation(t *testing.T, err error) {
+	var v *Violation
+	require.True(t, errors.As(err, &v), "expected a violation, got %v", err)
+	assert.Equal(t, ForeignKey, v.Kind)
+	assert.Equal(t, "FOREIGN KEY constraint failed", v.Error())
+}
+
+func TestForeignKeyImmediate(t *testing.T) {
+	schema, _, tracks, rows := music(t, ForeignKeyConstraint{})
+
+	s := tracks.Begin(Default)
+	_, _, err := s.Insert([]int{1, 2}, Row{int64(3), "radio ga ga"})
+	require.NoError(t, err)
+	_, _, err = s.Insert([]int{1, 2}, Row{nil, "no artist"})
+	require.NoError(t, err)
+	requireForeignKeyViolation(t, s.Commit())
+	assert.Len(t, rows, 2)
+
+	// a statement can resolve its own violations
+	s = tracks.Begin(Default)
+	_, _, err = s.Insert([]int{1, 2}, Row{int64(3), "radio ga ga"})
+	require.NoError(t, err)
+	_, err = s.Update(3, []int{1}, Row{int64(2)})
+	require.NoError(t, err)
+	require.NoError(t, s.Commit())
+	assert.Len(t, rows, 3)
+
+	schema.SetForeignKeys(false)
+	s = tracks.Begin(Default)
+	_, _, err = s.Insert([]int{1, 2}, Row{int64(3), "radio ga ga"})
+	require.NoError(t, err)
+	require.NoError(t, s.Commit())
+
+	violations, err := schema.ForeignKeyCheck("")
+	require.NoError(t, err)
+	assert.Equal(t, []ForeignKeyViolation{{Table: "tracks", RowID: 4, Parent: "artists"}}, violations)
+	_, err = schema.ForeignKeyCheck("albums")
+	assert.True(t, errors.Is(err, ErrNoSuchTable))
+}
+
+func TestForeignKeyDeferred(t *testing.T) {
+	schema, artists, tracks, rows := music(t, ForeignKeyConstraint{Deferred: true})
+
+	s := tracks.Begin(Default)
+	_, _, err := s.Insert([]int{1, 2}, Row{int64(3), "radio ga ga"})
+	require.NoError(t, err)
+	require.NoError(t, s.Commit())
+	assert.Len(t, rows, 3)
+	requireForeignKeyViolation(t, schema.Commit())
+
+	// a reverted statement doesn't change the deferred violations
+	s = tracks.Begin(Default)
+	_, _, err = s.Insert([]int{1, 2}, Row{int64(4), "under pressure"})
+	require.NoError(t, err)
+	require.NoError(t, s.Rollback())
+
+	s = artists.Begin(Default)
+	_, _, err = s.Insert([]int{0, 1}, Row{int64(3), "freddie"})
+	require.NoError(t, err)
+	require.NoError(t, s.Commit())
+	assert.NoError(t, schema.Commit())
+
+	s = artists.Begin(Default)
+	require.NoError(t, s.Delete(3))
+	require.NoError(t, s.Commit())
+	requireForeignKeyViolation(t, schema.Commit())
+	schema.Rollback()
+	assert.NoError(t, schema.Commit())
+}
+
+func TestForeignKeyActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		fk     ForeignKeyConstraint
+		update bool
+		// want is the artist of the tracks after the artist queen was
+		// deleted, or its id was changed to 3
+		want   []Value
+		errFn  func(t *testing.T, err error)
+		commit bool
+	}{
+		{"delete no action", ForeignKeyConstraint{}, false, nil, nil, false},
+		{"delete restrict", ForeignKeyConstraint{OnDelete: Restrict}, false, nil, requireForeignKeyViolation, true},
+		{"delete set null", ForeignKeyConstraint{OnDelete: SetNull}, false, []Value{nil, nil}, nil, true},
+		{"delete set default", ForeignKeyConstraint{OnDelete: SetDefault}, false, []Value{int64(1), int64(1)}, nil, true},
+		{"delete cascade", ForeignKeyConstraint{OnDelete: Cascade}, false, []Value{}, nil, true},
+		{"update no action", ForeignKeyConstraint{}, true, nil, nil, false},
+		{"update restrict", ForeignKeyConstraint{OnUpdate: Restrict}, true, nil, requireForeignKeyViolation, true},
+		{"update set null", ForeignKeyConstraint{OnUpdate: SetNull}, true, []Value{nil, nil}, nil, true},
+		{"update cascade", ForeignKeyConstraint{OnUpdate: Cascade}, true, []Value{int64(3), int64(3)}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, artists, _, rows := music(t, tt.fk)
+
+			s := artists.Begin(Default)
+			var err error
+			if tt.update {
+				_, err = s.Update(2, []int{0}, Row{int64(3)})
+			} else {
+				err = s.Delete(2)
+			}
+			if tt.errFn != nil {
+				tt.errFn(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			if !tt.commit {
+				requireForeignKeyViolation(t, s.Commit())
+				tt.want = []Value{int64(2), int64(2)}
+			} else {
+				require.NoError(t, s.Commit())
+			}
+			got := []Value{}
+			for _, id := range rows.ids() {
+				got = append(got, rows[id][1])
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestForeignKeySelfReference(t *testing.T) {
+	// CREATE TABLE nodes (
+	//	id INTEGER PRIMARY KEY,
+	//	parent INTEGER REFERENCES nodes ON DELETE CASCADE
+	// )
+	rows := memRows{}
+	nodes, err := New(Table{
+		Name:        "nodes",
+		Columns:     []Column{{Name: "id", RowID: true}, {Name: "parent"}},
+		ForeignKeys: []ForeignKeyConstraint{{Columns: []int{1}, Parent: "nodes", OnDelete: Cascade}},
+	}, rows, nil)
+	require.NoError(t, err)
+	schema := NewSchema()
+	require.NoError(t, schema.Add(nodes))
+	schema.SetForeignKeys(true)
+
+	s := nodes.Begin(Default)
+	for _, row := range []Row{{int64(1), int64(1)}, {int64(2), int64(1)}, {int64(3), int64(2)}, {int64(4), nil}} {
+		_, _, err := s.Insert(nil, row)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.Commit())
+
+	s = nodes.Begin(Default)
+	require.NoError(t, s.Delete(1))
+	require.NoError(t, s.Commit())
+	assert.Equal(t, []index.RowID{4}, rows.ids())
+}
+
+func TestForeignKeyMismatch(t *testing.T) {
+	parent, err := New(Table{
+		Name:    "parent",
+		Columns: []Column{{Name: "a"}, {Name: "b"}},
+		Uniques: []UniqueConstraint{{Columns: []int{0, 1}}},
+	}, memRows{}, nil)
+	require.NoError(t, err)
+	child, err := New(Table{
+		Name:        "child",
+		Columns:     []Column{{Name: "x"}},
+		ForeignKeys: []ForeignKeyConstraint{{Columns: []int{0}, Parent: "parent", ParentColumns: []string{"a"}}},
+	}, memRows{}, nil)
+	require.NoError(t, err)
+
+	schema := NewSchema()
+	require.NoError(t, schema.Add(parent))
+	require.NoError(t, schema.Add(child))
+	assert.True(t, errors.Is(schema.Add(child), ErrTableExists))
+	schema.SetForeignKeys(true)
+
+	_, _, err = child.Begin(Default).Insert(nil, Row{int64(1)})
+	assert.True(t, errors.Is(err, ErrForeignKeyMismatch))
+	_, _, err = parent.Begin(Default).Insert(nil, Row{int64(1), int64(2)})
+	assert.True(t, errors.Is(err, ErrForeignKeyMismatch))
+
+	_, err = New(Table{
+		Name:        "child",
+		Columns:     []Column{{Name: "x"}},
+		ForeignKeys: []ForeignKeyConstraint{{Columns: []int{0}, Parent: "parent", ParentColumns: []string{"a", "b"}}},
+	}, memRows{}, nil)
+	assert.True(t, errors.Is(err, ErrForeignKeyMismatch))
+}
@@ -0,0 +1,85 @@
+package constraint
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// Tags of encoded values. Integral REAL values are encoded like INTEGER
+// values, so that 1 and 1.0 are equal.
+const (
+	tagInteger byte = iota + 1
+	tagReal
+	tagText
+	tagBlob
+)
+
+// Encode encodes a value as an index value. Equal values have equal
+// encodings, and NULL is encoded as nil.
+func Encode(v Value) index.Value {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case int64:
+		return encodeInt(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+			return encodeInt(int64(v))
+		}
+		buf := make([]byte, 9)
+		buf[0] = tagReal
+		binary.BigEndian.PutUint64(buf[1:], math.Float64bits(v))
+		return buf
+	case string:
+		return append([]byte{tagText}, v...)
+	case []byte:
+		return append([]byte{tagBlob}, v...)
+	}
+	return nil
+}
+
+func encodeInt(i int64) index.Value {
+	buf := make([]byte, 9)
+	buf[0] = tagInteger
+	binary.BigEndian.PutUint64(buf[1:], uint64(i)^(1<<63))
+	return buf
+}
+
+// rowID converts a value of an INTEGER PRIMARY KEY column to a row ID. TEXT
+// and REAL values are converted if they are integers.
+func rowID(v Value) (index.RowID, bool) {
+	switch v := v.(type) {
+	case int64:
+		return index.RowID(v), true
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+			return index.RowID(v), true
+		}
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return index.RowID(i), true
+		}
+	}
+	return 0, false
+}
+
+// isFalse reports whether the result of a CHECK expression violates the
+// constraint, which is the case if it is zero when converted to a number.
+func isFalse(v Value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case []byte:
+		return isFalse(string(v))
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v.(string)), 64)
+	return err != nil || f == 0
+}
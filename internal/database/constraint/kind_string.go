@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=Kind ./internal/database/constraint"; DO NOT EDIT.
+
+package constraint
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[NotNull-0]
+	_ = x[Unique-1]
+	_ = x[PrimaryKey-2]
+	_ = x[Check-3]
+	_ = x[ForeignKey-4]
+}
+
+const _Kind_name = "NotNullUniquePrimaryKeyCheckForeignKey"
+
+var _Kind_index = [...]uint8{0, 7, 13, 23, 28, 38}
+
+func (i Kind) String() string {
+	if i >= Kind(len(_Kind_index)-1) {
+		return "Kind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Kind_name[_Kind_index[i]:_Kind_index[i+1]]
+}
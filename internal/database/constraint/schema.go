@@ -0,0 +1,134 @@
+package constraint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// Schema holds the enforcers of all tables of a database, and enforces the
+// foreign keys between them. Like in SQLite, foreign keys are not enforced
+// until they are enabled with SetForeignKeys, which corresponds to PRAGMA
+// foreign_keys. A Schema is not safe for concurrent use.
+type Schema struct {
+	tables  map[string]*Enforcer
+	enabled bool
+	// deferred is the number of violations of deferred foreign keys in the
+	// current transaction.
+	deferred int
+}
+
+// NewSchema creates a new schema without tables.
+func NewSchema() *Schema {
+	return &Schema{tables: make(map[string]*Enforcer)}
+}
+
+// Add adds the enforcer of a table to the schema.
+func (s *Schema) Add(e *Enforcer) error {
+	name := strings.ToLower(e.table.Name)
+	if _, ok := s.tables[name]; ok {
+		return fmt.Errorf("%w: %v", ErrTableExists, e.table.Name)
+	}
+	s.tables[name] = e
+	e.schema = s
+	return nil
+}
+
+// Remove removes the table with the given name from the schema.
+func (s *Schema) Remove(table string) {
+	name := strings.ToLower(table)
+	if e, ok := s.tables[name]; ok {
+		e.schema = nil
+		delete(s.tables, name)
+	}
+}
+
+// SetForeignKeys enables or disables the enforcement of foreign keys.
+func (s *Schema) SetForeignKeys(enabled bool) {
+	s.enabled = enabled
+}
+
+// ForeignKeys reports whether foreign keys are enforced.
+func (s *Schema) ForeignKeys() bool {
+	return s.enabled
+}
+
+// Commit checks the deferred foreign keys before the transaction commits. If
+// any are violated, it returns a violation, and the transaction must not
+// commit. It can commit after the violations are resolved, or it must be
+// rolled back.
+func (s *Schema) Commit() error {
+	if s.deferred > 0 {
+		return &Violation{Kind: ForeignKey, Conflict: Abort}
+	}
+	return nil
+}
+
+// Rollback discards the violations of deferred foreign keys, after the
+// transaction was rolled back.
+func (s *Schema) Rollback() {
+	s.deferred = 0
+}
+
+// ForeignKeyViolation is a child row, whose parent key doesn't exist.
+type ForeignKeyViolation struct {
+	Table  string
+	RowID  index.RowID
+	Parent string
+	// ForeignKey is the position of the violated foreign key in the foreign
+	// keys of the table.
+	ForeignKey int
+}
+
+// ForeignKeyCheck returns all rows of the given table, or of all tables if
+// the name is empty, that violate a foreign key, like PRAGMA
+// foreign_key_check. It works even if foreign keys are not enforced.
+func (s *Schema) ForeignKeyCheck(table string) ([]ForeignKeyViolation, error) {
+	var tables []*Enforcer
+	if table != "" {
+		e, ok := s.tables[strings.ToLower(table)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrNoSuchTable, table)
+		}
+		tables = append(tables, e)
+	} else {
+		for _, e := range s.tables {
+			tables = append(tables, e)
+		}
+	}
+
+	var violations []ForeignKeyViolation
+	for _, e := range tables {
+		for i, fk := range e.fks {
+			err := e.rows.Scan(func(id index.RowID, row Row) error {
+				key, ok := fk.childKey(row)
+				if !ok {
+					return nil
+				}
+				exists, err := fk.parentExists(key)
+				if err == nil && !exists {
+					violations = append(violations, ForeignKeyViolation{e.table.Name, id, fk.Parent, i})
+				}
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return violations, nil
+}
+
+// referencing returns the foreign keys, that reference the given table.
+func (s *Schema) referencing(parent string) []*foreignKey {
+	var fks []*foreignKey
+	for _, e := range s.tables {
+		for _, fk := range e.fks {
+			if strings.EqualFold(fk.Parent, parent) {
+				fks = append(fks, fk)
+			}
+		}
+	}
+	return fks
+}
@@ -0,0 +1,60 @@
+package constraint
+
+import (
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// Sequences holds the largest row ID that was ever used by every table with
+// an AUTOINCREMENT column, like the sqlite_sequence table of SQLite. Table
+// names are case insensitive. Sequences are safe for concurrent use.
+type Sequences struct {
+	mu sync.Mutex
+	m  map[string]index.RowID
+}
+
+// NewSequences creates a new, empty set of sequences.
+func NewSequences() *Sequences {
+	return &Sequences{m: make(map[string]index.RowID)}
+}
+
+// Get returns the sequence of the given table, and whether it exists.
+func (s *Sequences) Get(table string) (index.RowID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.m[strings.ToLower(table)]
+	return id, ok
+}
+
+// Set sets the sequence of the given table.
+func (s *Sequences) Set(table string, id index.RowID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[strings.ToLower(table)] = id
+}
+
+// Drop removes the sequence of the given table, for example when the table
+// is dropped.
+func (s *Sequences) Drop(table string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, strings.ToLower(table))
+}
+
+// next returns the next row ID for an AUTOINCREMENT table, given the
+// largest row ID that is in use.
+func (s *Sequences) next(table string, last index.RowID) (index.RowID, error) {
+	if seq, ok := s.Get(table); ok && seq > last {
+		last = seq
+	}
+	if last == math.MaxInt64 {
+		return 0, ErrFull
+	}
+	if last < 0 {
+		last = 0
+	}
+	return last + 1, nil
+}
@@ -0,0 +1,548 @@
+package constraint
+
+import (
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// Statement makes the changes of a single statement to the rows of a table,
+// and enforces the constraints of the table. If foreign keys are enforced,
+// the actions of foreign keys may also change the rows of other tables. It
+// keeps an undo log, so that the changes can be reverted when a violation is
+// resolved with Abort or Rollback. After a violation or an error, the
+// statement is done.
+type Statement struct {
+	e    *Enforcer
+	or   Conflict
+	undo []undoEntry
+	// seqs holds the sequences of the tables before the statement changed
+	// them.
+	seqs map[*Enforcer]sequence
+	// immediate is the number of violations of immediate foreign keys, and
+	// deferred is the number of violations of deferred foreign keys in the
+	// transaction before the statement.
+	immediate int
+	deferred  int
+	done      bool
+}
+
+// undoEntry records the row with the given ID of the table of the given
+// enforcer before it was changed. A nil row means that the row didn't exist.
+type undoEntry struct {
+	e   *Enforcer
+	id  index.RowID
+	row Row
+}
+
+// sequence is a saved sequence, that didn't exist if ok is false.
+type sequence struct {
+	id index.RowID
+	ok bool
+}
+
+// Insert inserts a row with the given values of the given columns. If
+// columns is nil, the values are the values of all columns. Columns without
+// a value are set to their default value. If the table has an INTEGER
+// PRIMARY KEY that is NULL, it is set to a new row ID. Insert returns the ID
+// of the row, and false if the row was skipped, because a violation was
+// resolved with Ignore.
+func (s *Statement) Insert(columns []int, values Row) (index.RowID, bool, error) {
+	if s.done {
+		return 0, false, ErrStatementDone
+	}
+	t := s.e.table
+
+	row := make(Row, len(t.Columns))
+	given := make([]bool, len(t.Columns))
+	if err := assign(t, row, given, columns, values); err != nil {
+		return 0, false, s.abort(err)
+	}
+	for i, col := range t.Columns {
+		if given[i] || col.Default == nil {
+			continue
+		}
+		v, err := col.Default()
+		if err != nil {
+			return 0, false, s.abort(err)
+		}
+		row[i] = v
+	}
+
+	var id index.RowID
+	if s.e.rowID >= 0 && row[s.e.rowID] != nil {
+		var ok bool
+		if id, ok = rowID(row[s.e.rowID]); !ok {
+			return 0, false, s.abort(ErrMismatch)
+		}
+	} else {
+		var err error
+		if id, err = s.e.nextRowID(); err != nil {
+			return 0, false, s.abort(err)
+		}
+	}
+	if s.e.rowID >= 0 {
+		row[s.e.rowID] = int64(id)
+	}
+
+	ok, err := s.write(s.e, id, nil, 0, row)
+	return id, ok, err
+}
+
+// Update sets the given columns of the row with the given ID to the given
+// values. If columns is nil, the values are the values of all columns. The
+// row ID changes, if the INTEGER PRIMARY KEY is updated. Update returns false
+// if the row was not updated, because a violation was resolved with Ignore.
+func (s *Statement) Update(id index.RowID, columns []int, values Row) (bool, error) {
+	if s.done {
+		return false, ErrStatementDone
+	}
+	t := s.e.table
+
+	old, exists, err := s.e.rows.Get(id)
+	if err != nil {
+		return false, s.abort(err)
+	}
+	if !exists {
+		return false, s.abort(ErrNoSuchRow)
+	}
+	row := append(Row{}, old...)
+	if err := assign(t, row, make([]bool, len(t.Columns)), columns, values); err != nil {
+		return false, s.abort(err)
+	}
+	return s.update(s.e, id, old, row)
+}
+
+// Delete deletes the row with the given ID.
+func (s *Statement) Delete(id index.RowID) error {
+	if s.done {
+		return ErrStatementDone
+	}
+	old, exists, err := s.e.rows.Get(id)
+	if err != nil {
+		return s.abort(err)
+	}
+	if !exists {
+		return s.abort(ErrNoSuchRow)
+	}
+	if err := s.change(s.e, id, old, 0, nil); err != nil {
+		return s.abort(err)
+	}
+	return nil
+}
+
+// Commit completes the statement, and keeps all its changes. If the changes
+// violate an immediate foreign key, the statement is reverted, and a
+// violation is returned.
+func (s *Statement) Commit() error {
+	if s.done {
+		return ErrStatementDone
+	}
+	if s.immediate > 0 {
+		return s.violate(&Violation{Kind: ForeignKey, Table: s.e.table.Name}, Abort)
+	}
+	s.done = true
+	s.undo = nil
+	return nil
+}
+
+// Rollback completes the statement, and reverts all its changes. It is
+// called automatically, if a violation is resolved with Abort or Rollback.
+func (s *Statement) Rollback() error {
+	if s.done {
+		return ErrStatementDone
+	}
+	s.done = true
+	return s.revert()
+}
+
+// assign sets the given columns of the row to the given values, and marks
+// them as given.
+func assign(t Table, row Row, given []bool, columns []int, values Row) error {
+	if columns == nil {
+		if len(values) != len(t.Columns) {
+			return ErrColumnCount
+		}
+		copy(row, values)
+		for i := range given {
+			given[i] = true
+		}
+		return nil
+	}
+
+	if len(values) != len(columns) {
+		return ErrColumnCount
+	}
+	for i, c := range columns {
+		if c < 0 || c >= len(t.Columns) {
+			return ErrNoSuchColumn
+		}
+		row[c] = values[i]
+		given[c] = true
+	}
+	return nil
+}
+
+// update replaces the row with the given ID in the table of the given
+// enforcer with the given row. The row ID changes, if the INTEGER PRIMARY KEY
+// is updated.
+func (s *Statement) update(e *Enforcer, id index.RowID, old, row Row) (bool, error) {
+	newID := id
+	if e.rowID >= 0 {
+		var ok bool
+		if newID, ok = rowID(row[e.rowID]); !ok {
+			return false, s.abort(ErrMismatch)
+		}
+		row[e.rowID] = int64(newID)
+	}
+	return s.write(e, newID, old, id, row)
+}
+
+// write checks the constraints for the new row with the given ID in the
+// table of the given enforcer, resolves violations, and stores the row. For
+// an update, old is the current row with the ID oldID, otherwise old is nil.
+func (s *Statement) write(e *Enforcer, id index.RowID, old Row, oldID index.RowID, row Row) (bool, error) {
+	t := e.table
+	self := id
+	if old != nil {
+		self = oldID
+	}
+
+	for i, col := range t.Columns {
+		if row[i] != nil || !col.NotNull {
+			continue
+		}
+		c := s.or.resolve(col.OnNull)
+		if c == Replace && col.Default != nil {
+			v, err := col.Default()
+			if err != nil {
+				return false, s.abort(err)
+			}
+			if v != nil {
+				row[i] = v
+				continue
+			}
+		}
+		if c == Ignore {
+			return false, nil
+		}
+		return false, s.violate(&Violation{Kind: NotNull, Table: t.Name, Constraint: t.columnNames([]int{i})}, c)
+	}
+
+	for _, check := range t.Checks {
+		v, err := check.Expr(row)
+		if err != nil {
+			return false, s.abort(err)
+		}
+		if !isFalse(v) {
+			continue
+		}
+		c := s.or.resolve(Default)
+		if c == Ignore {
+			return false, nil
+		}
+		return false, s.violate(&Violation{Kind: Check, Table: t.Name, Constraint: check.Name}, c)
+	}
+
+	if old == nil || id != oldID {
+		existing, exists, err := e.rows.Get(id)
+		if err != nil {
+			return false, s.abort(err)
+		}
+		if exists {
+			switch c := s.or.resolve(e.onRowID); c {
+			case Ignore:
+				return false, nil
+			case Replace:
+				if err := s.change(e, id, existing, 0, nil); err != nil {
+					return false, s.abort(err)
+				}
+			default:
+				return false, s.violate(&Violation{Kind: PrimaryKey, Table: t.Name, Constraint: t.columnNames([]int{e.rowID})}, c)
+			}
+		}
+	}
+
+	for _, u := range e.uniqueIndexes() {
+		ids, err := e.conflicts(u.index, row, self)
+		if err != nil {
+			return false, s.abort(err)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		c := s.or.resolve(u.onConflict)
+		switch c {
+		case Ignore:
+			return false, nil
+		case Replace:
+			for _, conflicting := range ids {
+				// an action of a foreign key may already have deleted it
+				existing, exists, err := e.rows.Get(conflicting)
+				if err == nil && exists {
+					err = s.change(e, conflicting, existing, 0, nil)
+				}
+				if err != nil {
+					return false, s.abort(err)
+				}
+			}
+			continue
+		}
+		return false, s.violate(&Violation{Kind: u.kind, Table: t.Name, Constraint: u.constraint}, c)
+	}
+
+	if err := s.change(e, oldID, old, id, row); err != nil {
+		return false, s.abort(err)
+	}
+	if e.autoincrement() {
+		if seq, ok := e.seq.Get(t.Name); !ok || id > seq {
+			s.saveSequence(e, seq, ok)
+			e.seq.Set(t.Name, id)
+		}
+	}
+	return true, nil
+}
+
+// change replaces the row old with the ID oldID by the given row with the ID
+// id in the table of the given enforcer, and enforces the foreign keys. Old
+// is nil for an insert, and row is nil for a delete.
+func (s *Statement) change(e *Enforcer, oldID index.RowID, old Row, id index.RowID, row Row) error {
+	enforce := e.foreignKeys()
+	if enforce && old != nil {
+		// child keys that are removed no longer violate their foreign key
+		for _, fk := range e.fks {
+			if row == nil || keyChanged(fk.Columns, old, row) {
+				if err := s.count(fk, old, -1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if old != nil {
+		s.undo = append(s.undo, undoEntry{e, oldID, old})
+		if err := e.delete(oldID, old); err != nil {
+			return err
+		}
+	}
+	if row != nil {
+		if old == nil || id != oldID {
+			s.undo = append(s.undo, undoEntry{e, id, nil})
+		}
+		if err := e.insert(id, row); err != nil {
+			return err
+		}
+	}
+	if !enforce {
+		return nil
+	}
+
+	if row != nil {
+		for _, fk := range e.fks {
+			if old == nil || keyChanged(fk.Columns, old, row) {
+				if err := s.count(fk, row, 1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, fk := range e.schema.referencing(e.table.Name) {
+		pk, ok, err := fk.resolve()
+		if err != nil {
+			return fmt.Errorf("%w: %v referencing %v", err, fk.child.table.Name, fk.Parent)
+		}
+		if !ok || pk.parent != e {
+			continue
+		}
+		if old != nil && (row == nil || keyChanged(pk.columns, old, row)) {
+			if err := s.act(fk, pk, old, row); err != nil {
+				return err
+			}
+		}
+		if row != nil && (old == nil || keyChanged(pk.columns, old, row)) {
+			// child rows of the new parent key no longer violate the
+			// foreign key, except for the row itself, which was never
+			// counted
+			children, err := fk.children(pk, row)
+			if err != nil {
+				return err
+			}
+			for _, child := range children {
+				if fk.child != e || child != id {
+					s.add(fk, -1)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// count adds delta to the violations of the given foreign key, if the child
+// key of the given row has no parent.
+func (s *Statement) count(fk *foreignKey, row Row, delta int) error {
+	key, ok := fk.childKey(row)
+	if !ok {
+		return nil
+	}
+	exists, err := fk.parentExists(key)
+	if err != nil {
+		return fmt.Errorf("%w: %v referencing %v", err, fk.child.table.Name, fk.Parent)
+	}
+	if !exists {
+		s.add(fk, delta)
+	}
+	return nil
+}
+
+// add adds delta to the violations of the given foreign key.
+func (s *Statement) add(fk *foreignKey, delta int) {
+	if fk.Deferred {
+		fk.child.schema.deferred += delta
+	} else {
+		s.immediate += delta
+	}
+}
+
+// act takes the action of the given foreign key for the child rows of the
+// parent row old, which was deleted if row is nil, or updated to row.
+func (s *Statement) act(fk *foreignKey, pk parentKey, old, row Row) error {
+	ids, err := fk.children(pk, old)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	action := fk.OnUpdate
+	if row == nil {
+		action = fk.OnDelete
+	}
+
+	switch action {
+	case NoAction:
+		s.add(fk, len(ids))
+		return nil
+	case Restrict:
+		return s.violate(&Violation{Kind: ForeignKey, Table: fk.child.table.Name}, Abort)
+	}
+
+	child := fk.child
+	for _, id := range ids {
+		// an earlier action may already have changed or deleted the row
+		current, exists, err := child.rows.Get(id)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		if action == Cascade && row == nil {
+			if err := s.change(child, id, current, 0, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		updated := append(Row{}, current...)
+		for i, c := range fk.Columns {
+			switch action {
+			case SetNull:
+				updated[c] = nil
+			case SetDefault:
+				updated[c] = nil
+				if def := child.table.Columns[c].Default; def != nil {
+					if updated[c], err = def(); err != nil {
+						return err
+					}
+				}
+			case Cascade:
+				updated[c] = row[pk.columns[i]]
+			}
+		}
+		if _, err := s.update(child, id, current, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Statement) saveSequence(e *Enforcer, id index.RowID, ok bool) {
+	if s.seqs == nil {
+		s.seqs = make(map[*Enforcer]sequence)
+	}
+	if _, saved := s.seqs[e]; !saved {
+		s.seqs[e] = sequence{id, ok}
+	}
+}
+
+// revert undoes all changes of the statement, in reverse order.
+func (s *Statement) revert() error {
+	var errs []error
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		u := s.undo[i]
+		current, exists, err := u.e.rows.Get(u.id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if exists {
+			errs = append(errs, u.e.delete(u.id, current))
+		}
+		if u.row != nil {
+			errs = append(errs, u.e.insert(u.id, u.row))
+		}
+	}
+	s.undo = nil
+
+	for e, seq := range s.seqs {
+		if seq.ok {
+			e.seq.Set(e.table.Name, seq.id)
+		} else {
+			e.seq.Drop(e.table.Name)
+		}
+	}
+	s.seqs = nil
+	s.immediate = 0
+	if s.e.schema != nil {
+		s.e.schema.deferred = s.deferred
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// violate completes the statement after a violation, that is resolved with
+// the given algorithm.
+func (s *Statement) violate(v *Violation, c Conflict) error {
+	if s.done {
+		// an action of a foreign key already completed the statement
+		return v
+	}
+	if c == Replace {
+		c = Abort
+	}
+	v.Conflict = c
+	s.done = true
+	if c == Fail {
+		s.undo = nil
+		return v
+	}
+	if err := s.revert(); err != nil {
+		return err
+	}
+	return v
+}
+
+// abort completes the statement after an error, and reverts its changes.
+func (s *Statement) abort(err error) error {
+	if s.done {
+		// the error is a violation, that already completed the statement
+		return err
+	}
+	s.done = true
+	if revertErr := s.revert(); revertErr != nil {
+		return revertErr
+	}
+	return err
+}
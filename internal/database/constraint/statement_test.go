@@ -0,0 +1,457 @@
+package constraint
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// memRows stores rows in memory.
+type memRows map[index.RowID]Row
+
+func (m memRows) Get(id index.RowID) (Row, bool, error) {
+	row, ok := m[id]
+	return row, ok, nil
+}
+
+func (m memRows) Put(id index.RowID, row Row) error {
+	m[id] = row
+	return nil
+}
+
+func (m memRows) Delete(id index.RowID) error {
+	delete(m, id)
+	return nil
+}
+
+func (m memRows) Last() (index.RowID, bool, error) {
+	ids := m.ids()
+	if len(ids) == 0 {
+		return 0, false, nil
+	}
+	return ids[len(ids)-1], true, nil
+}
+
+func (m memRows) Scan(fn func(id index.RowID, row Row) error) error {
+	for _, id := range m.ids() {
+		if err := fn(id, m[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m memRows) ids() []index.RowID {
+	ids := make([]index.RowID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func constant(v Value) func() (Value, error) {
+	return func() (Value, error) { return v, nil }
+}
+
+// users is the table
+//
+//	CREATE TABLE users (
+//		id INTEGER PRIMARY KEY AUTOINCREMENT,
+//		email TEXT NOT NULL UNIQUE,
+//		name TEXT NOT NULL ON CONFLICT REPLACE DEFAULT 'anon',
+//		age INTEGER CONSTRAINT age_positive CHECK (age >= 0),
+//		created INTEGER DEFAULT 42
+//	)
+func users() Table {
+	return Table{
+		Name: "users",
+		Columns: []Column{
+			{Name: "id", RowID: true, Autoincrement: true},
+			{Name: "email", NotNull: true},
+			{Name: "name", NotNull: true, OnNull: Replace, Default: constant("anon")},
+			{Name: "age"},
+			{Name: "created", Default: constant(int64(42))},
+		},
+		Uniques: []UniqueConstraint{
+			{Columns: []int{0}, PrimaryKey: true},
+			{Columns: []int{1}},
+		},
+		Checks: []CheckConstraint{
+			{Name: "age_positive", Expr: func(row Row) (Value, error) {
+				if row[3] == nil {
+					return nil, nil
+				}
+				if row[3].(int64) >= 0 {
+					return int64(1), nil
+				}
+				return int64(0), nil
+			}},
+		},
+	}
+}
+
+func newUsers(t *testing.T) (*Enforcer, memRows, *Sequences) {
+	rows := memRows{}
+	seq := NewSequences()
+	e, err := New(users(), rows, seq)
+	require.NoError(t, err)
+	return e, rows, seq
+}
+
+func insertEmail(t *testing.T, s *Statement, email string) index.RowID {
+	id, ok, err := s.Insert([]int{1}, Row{email})
+	require.NoError(t, err)
+	require.True(t, ok)
+	return id
+}
+
+func requireViolation(t *testing.T, err error, kind Kind, constraint string, conflict Conflict) {
+	t.Helper()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrConstraint))
+	var v *Violation
+	require.True(t, errors.As(err, &v), "%v", err)
+	assert.Equal(t, kind, v.Kind)
+	assert.Equal(t, constraint, v.Constraint)
+	assert.Equal(t, conflict, v.Conflict)
+}
+
+func TestInsertDefaults(t *testing.T) {
+	e, rows, seq := newUsers(t)
+
+	s := e.Begin(Default)
+	assert.Equal(t, index.RowID(1), insertEmail(t, s, "a@x"))
+	id, ok, err := s.Insert(nil, Row{int64(10), "b@x", nil, int64(30), nil})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, index.RowID(10), id)
+	require.NoError(t, s.Commit())
+
+	assert.Equal(t, Row{int64(1), "a@x", "anon", nil, int64(42)}, rows[1])
+	// an explicit NULL is replaced by the default because of ON CONFLICT
+	// REPLACE, but an explicit NULL in a nullable column is kept
+	assert.Equal(t, Row{int64(10), "b@x", "anon", int64(30), nil}, rows[10])
+	last, ok := seq.Get("USERS")
+	assert.True(t, ok)
+	assert.Equal(t, index.RowID(10), last)
+
+	_, _, err = s.Insert([]int{1}, Row{"c@x"})
+	assert.Equal(t, ErrStatementDone, err)
+}
+
+func TestInsertViolations(t *testing.T) {
+	e, rows, _ := newUsers(t)
+	s := e.Begin(Default)
+	insertEmail(t, s, "a@x")
+	require.NoError(t, s.Commit())
+
+	tests := []struct {
+		name       string
+		columns    []int
+		values     Row
+		kind       Kind
+		constraint string
+	}{
+		{"not null", []int{2}, Row{"name"}, NotNull, "users.email"},
+		{"check", []int{1, 3}, Row{"b@x", int64(-1)}, Check, "age_positive"},
+		{"unique", []int{1}, Row{"a@x"}, Unique, "users.email"},
+		{"primary key", []int{0, 1}, Row{int64(1), "b@x"}, PrimaryKey, "users.id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := e.Begin(Default)
+			insertEmail(t, s, "other@x")
+			_, _, err := s.Insert(tt.columns, tt.values)
+			requireViolation(t, err, tt.kind, tt.constraint, Abort)
+
+			// the statement was reverted
+			assert.Len(t, rows, 1)
+			_, _, err = s.Insert([]int{1}, Row{"c@x"})
+			assert.Equal(t, ErrStatementDone, err)
+		})
+	}
+
+	_, _, err := e.Begin(Default).Insert([]int{1}, Row{"a@x"})
+	assert.EqualError(t, err, "UNIQUE constraint failed: users.email")
+	_, _, err = e.Begin(Default).Insert([]int{0, 1}, Row{"x", "b@x"})
+	assert.Equal(t, ErrMismatch, err)
+	_, _, err = e.Begin(Default).Insert([]int{1}, Row{"a", "b"})
+	assert.Equal(t, ErrColumnCount, err)
+	_, _, err = e.Begin(Default).Insert([]int{7}, Row{"a"})
+	assert.Equal(t, ErrNoSuchColumn, err)
+}
+
+func TestConflictResolution(t *testing.T) {
+	setup := func(t *testing.T) (*Enforcer, memRows) {
+		e, rows, _ := newUsers(t)
+		s := e.Begin(Default)
+		insertEmail(t, s, "a@x")
+		require.NoError(t, s.Commit())
+		return e, rows
+	}
+
+	t.Run("rollback", func(t *testing.T) {
+		e, rows := setup(t)
+		s := e.Begin(Rollback)
+		insertEmail(t, s, "b@x")
+		_, _, err := s.Insert([]int{1}, Row{"a@x"})
+		requireViolation(t, err, Unique, "users.email", Rollback)
+		assert.Len(t, rows, 1)
+	})
+	t.Run("fail", func(t *testing.T) {
+		e, rows := setup(t)
+		s := e.Begin(Fail)
+		insertEmail(t, s, "b@x")
+		_, _, err := s.Insert([]int{1}, Row{"a@x"})
+		requireViolation(t, err, Unique, "users.email", Fail)
+		assert.Len(t, rows, 2)
+	})
+	t.Run("ignore", func(t *testing.T) {
+		e, rows := setup(t)
+		s := e.Begin(Ignore)
+		_, ok, err := s.Insert([]int{1}, Row{"a@x"})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		_, ok, err = s.Insert([]int{1, 3}, Row{"b@x", int64(-1)})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		insertEmail(t, s, "c@x")
+		require.NoError(t, s.Commit())
+		assert.Len(t, rows, 2)
+	})
+	t.Run("replace", func(t *testing.T) {
+		e, rows := setup(t)
+		s := e.Begin(Replace)
+		id, ok, err := s.Insert([]int{1, 3}, Row{"a@x", int64(5)})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, index.RowID(2), id)
+		assert.Len(t, rows, 1)
+		assert.Equal(t, int64(5), rows[2][3])
+
+		_, ok, err = s.Insert([]int{0, 1}, Row{int64(2), "b@x"})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "b@x", rows[2][1])
+
+		// CHECK violations are aborted, even with REPLACE
+		_, _, err = s.Insert([]int{1, 3}, Row{"c@x", int64(-1)})
+		requireViolation(t, err, Check, "age_positive", Abort)
+		assert.Equal(t, Row{int64(1), "a@x", "anon", nil, int64(42)}, rows[1])
+		assert.Len(t, rows, 1)
+	})
+	t.Run("constraint conflict clause", func(t *testing.T) {
+		table := users()
+		table.Uniques[1].OnConflict = Ignore
+		e, err := New(table, memRows{}, NewSequences())
+		require.NoError(t, err)
+		s := e.Begin(Default)
+		insertEmail(t, s, "a@x")
+		_, ok, err := s.Insert([]int{1}, Row{"a@x"})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		// the conflict clause of the statement takes precedence
+		_, _, err = e.Begin(Abort).Insert([]int{1}, Row{"a@x"})
+		requireViolation(t, err, Unique, "users.email", Abort)
+	})
+}
+
+func TestAutoincrement(t *testing.T) {
+	e, rows, seq := newUsers(t)
+	s := e.Begin(Default)
+	for _, email := range []string{"a", "b", "c"} {
+		insertEmail(t, s, email)
+	}
+	assert.NoError(t, s.Delete(3))
+	assert.Equal(t, index.RowID(4), insertEmail(t, s, "d"))
+	require.NoError(t, s.Commit())
+
+	// an aborted statement doesn't advance the sequence
+	s = e.Begin(Default)
+	assert.Equal(t, index.RowID(5), insertEmail(t, s, "e"))
+	assert.NoError(t, s.Rollback())
+	last, _ := seq.Get("users")
+	assert.Equal(t, index.RowID(4), last)
+	assert.Len(t, rows, 3)
+
+	// without AUTOINCREMENT, row IDs are reused
+	table := users()
+	table.Columns[0].Autoincrement = false
+	plain, err := New(table, memRows{}, nil)
+	require.NoError(t, err)
+	s = plain.Begin(Default)
+	insertEmail(t, s, "a")
+	insertEmail(t, s, "b")
+	assert.NoError(t, s.Delete(2))
+	assert.Equal(t, index.RowID(2), insertEmail(t, s, "c"))
+}
+
+func TestUpdateDelete(t *testing.T) {
+	e, rows, _ := newUsers(t)
+	s := e.Begin(Default)
+	insertEmail(t, s, "a@x")
+	insertEmail(t, s, "b@x")
+	require.NoError(t, s.Commit())
+
+	s = e.Begin(Default)
+	ok, err := s.Update(1, []int{3}, Row{int64(20)})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// updating a row to its own values doesn't conflict with itself
+	ok, err = s.Update(1, []int{1}, Row{"a@x"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// changing the INTEGER PRIMARY KEY moves the row
+	ok, err = s.Update(1, []int{0}, Row{int64(7)})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, s.Commit())
+	assert.Equal(t, Row{int64(7), "a@x", "anon", int64(20), int64(42)}, rows[7])
+	assert.NotContains(t, rows, index.RowID(1))
+
+	s = e.Begin(Default)
+	_, err = s.Update(7, []int{1}, Row{"b@x"})
+	requireViolation(t, err, Unique, "users.email", Abort)
+	_, err = e.Begin(Default).Update(7, []int{0}, Row{int64(2)})
+	requireViolation(t, err, PrimaryKey, "users.id", Abort)
+	_, err = e.Begin(Default).Update(7, []int{1}, Row{nil})
+	requireViolation(t, err, NotNull, "users.email", Abort)
+	_, err = e.Begin(Default).Update(1, []int{1}, Row{"c@x"})
+	assert.Equal(t, ErrNoSuchRow, err)
+
+	// the unique index follows deletes and updates
+	s = e.Begin(Default)
+	assert.NoError(t, s.Delete(2))
+	ok, err = s.Update(7, []int{1}, Row{"b@x"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	insertEmail(t, s, "a@x")
+	require.NoError(t, s.Commit())
+	assert.Len(t, rows, 2)
+	assert.Equal(t, ErrNoSuchRow, e.Begin(Default).Delete(2))
+}
+
+func TestNew(t *testing.T) {
+	// rows with NULL in a UNIQUE column don't conflict
+	table := Table{
+		Name:    "t",
+		Columns: []Column{{Name: "a"}, {Name: "b"}},
+		Uniques: []UniqueConstraint{{Columns: []int{0, 1}}},
+	}
+	rows := memRows{1: {int64(1), nil}, 2: {int64(1), nil}, 3: {1.0, "x"}}
+	e, err := New(table, rows, nil)
+	require.NoError(t, err)
+	_, _, err = e.Begin(Default).Insert(nil, Row{int64(1), "x"})
+	requireViolation(t, err, Unique, "t.a, t.b", Abort)
+
+	rows[4] = Row{int64(1), "x"}
+	_, err = New(table, rows, nil)
+	assert.True(t, errors.Is(err, index.ErrUniqueViolation))
+
+	table.Columns[0].Autoincrement = true
+	_, err = New(table, memRows{}, nil)
+	assert.True(t, errors.Is(err, ErrAutoincrement))
+
+	table = users()
+	table.Uniques = append(table.Uniques, UniqueConstraint{Columns: []int{9}})
+	_, err = New(table, memRows{}, nil)
+	assert.True(t, errors.Is(err, ErrInvalidColumns))
+}
+
+// sortedStore is an index store in memory.
+type sortedStore map[string][]byte
+
+func (s sortedStore) Put(key, value []byte) error {
+	s[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (s sortedStore) Delete(key []byte) error {
+	delete(s, string(key))
+	return nil
+}
+
+func (s sortedStore) Scan(start, end []byte, fn func(key, value []byte) (bool, error)) error {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		if key >= string(start) && (end == nil || key < string(end)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if more, err := fn([]byte(key), s[key]); err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedRows keeps the indexes of the constraints in a store, and has
+// secondary indexes.
+type storedRows struct {
+	memRows
+	store     sortedStore
+	secondary []*index.Index[Row]
+}
+
+func (r storedRows) IndexStore() (index.Store, []byte) { return r.store, []byte("users:") }
+
+func (r storedRows) Indexes() []*index.Index[Row] { return r.secondary }
+
+func TestStoredRows(t *testing.T) {
+	rows := storedRows{memRows: memRows{}, store: sortedStore{}}
+	byAge := index.New(index.Definition[Row]{
+		Name:    "users_age",
+		Unique:  true,
+		Columns: []index.Column{{}},
+		Key: func(row Row) (index.Key, error) {
+			return index.Key{Encode(row[3])}, nil
+		},
+	})
+	rows.secondary = []*index.Index[Row]{byAge}
+
+	e, err := New(users(), rows, NewSequences())
+	require.NoError(t, err)
+	s := e.Begin(Default)
+	_, _, err = s.Insert([]int{1, 3}, Row{"a@x", int64(30)})
+	require.NoError(t, err)
+	_, _, err = s.Insert([]int{1, 3}, Row{"b@x", int64(40)})
+	require.NoError(t, err)
+	require.NoError(t, s.Commit())
+	assert.Len(t, rows.store, 2, "the index of the email must be stored")
+
+	// the indexes are opened from the store, not built from the rows
+	rows.memRows[9] = Row{int64(9), "c@x", "anon", nil, nil}
+	e, err = New(users(), rows, NewSequences())
+	require.NoError(t, err)
+	_, _, err = e.Begin(Default).Insert([]int{1}, Row{"a@x"})
+	requireViolation(t, err, Unique, "users.email", Abort)
+	delete(rows.memRows, 9)
+
+	// unique secondary indexes are enforced like UNIQUE constraints
+	_, _, err = e.Begin(Default).Insert([]int{1, 3}, Row{"c@x", int64(30)})
+	requireViolation(t, err, Unique, "index 'users_age'", Abort)
+	_, ok, err := e.Begin(Ignore).Insert([]int{1, 3}, Row{"c@x", int64(30)})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	s = e.Begin(Replace)
+	id, ok, err := s.Insert([]int{1, 3}, Row{"c@x", int64(30)})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, s.Commit())
+	assert.Equal(t, []index.RowID{2, id}, rows.ids())
+	var ages []index.RowID
+	assert.NoError(t, byAge.Lookup(index.Key{Encode(int64(30))}, func(id index.RowID) bool {
+		ages = append(ages, id)
+		return true
+	}))
+	assert.Equal(t, []index.RowID{id}, ages)
+}
@@ -0,0 +1,163 @@
+package constraint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+)
+
+// Value is a value of a column. It is either nil (NULL), int64, float64,
+// string or []byte.
+type Value = interface{}
+
+// Row holds the values of the columns of a row, in the order of the columns
+// of the table.
+type Row []Value
+
+// Rows is the storage of the rows of a table.
+type Rows interface {
+	// Get returns the row with the given ID, and whether it exists.
+	Get(id index.RowID) (Row, bool, error)
+	// Put inserts or overwrites the row with the given ID.
+	Put(id index.RowID, row Row) error
+	// Delete removes the row with the given ID.
+	Delete(id index.RowID) error
+	// Last returns the largest row ID, and false if there are no rows.
+	Last() (index.RowID, bool, error)
+	// Scan calls the given function for every row.
+	Scan(fn func(id index.RowID, row Row) error) error
+}
+
+// StoredRows are Rows, that also store the indexes of the constraints of
+// their table. The indexes of an Enforcer on StoredRows are opened instead of
+// being built from the rows, and changes to them are made in the store, like
+// the changes to the rows. Without it, the indexes are kept in memory.
+type StoredRows interface {
+	Rows
+	// IndexStore returns the store of the indexes, and the prefix of their
+	// entries in it. If the store is nil, the indexes are kept in memory.
+	IndexStore() (index.Store, []byte)
+}
+
+// IndexedRows are Rows of a table with secondary indexes, like the ones of
+// CREATE INDEX. An Enforcer keeps them up to date, and enforces unique
+// indexes like UNIQUE constraints.
+type IndexedRows interface {
+	Rows
+	// Indexes returns the secondary indexes of the table.
+	Indexes() []*index.Index[Row]
+}
+
+// Table describes the columns and constraints of a table.
+type Table struct {
+	Name    string
+	Columns []Column
+	// Uniques holds the UNIQUE and PRIMARY KEY constraints.
+	Uniques     []UniqueConstraint
+	Checks      []CheckConstraint
+	ForeignKeys []ForeignKeyConstraint
+}
+
+// Column describes a column and its column constraints.
+type Column struct {
+	Name string
+	// NotNull indicates a NOT NULL constraint, whose violations are resolved
+	// with OnNull.
+	NotNull bool
+	OnNull  Conflict
+	// Default computes the DEFAULT value of the column. If it is nil, the
+	// default value is NULL.
+	Default func() (Value, error)
+	// RowID indicates that the column is an INTEGER PRIMARY KEY, which is an
+	// alias of the row ID. At most one column can be the row ID.
+	RowID bool
+	// Autoincrement prevents the reuse of row IDs, that were used by deleted
+	// rows. It requires RowID to be set.
+	Autoincrement bool
+}
+
+// UniqueConstraint is a UNIQUE or PRIMARY KEY constraint. Rows with NULL in
+// any of the columns never violate it.
+type UniqueConstraint struct {
+	Columns    []int
+	PrimaryKey bool
+	OnConflict Conflict
+}
+
+// CheckConstraint is a CHECK constraint. It is violated by rows, for which
+// the expression is zero. NULL satisfies the constraint.
+type CheckConstraint struct {
+	Name string
+	Expr func(row Row) (Value, error)
+	// Columns are the columns that Expr refers to, which cannot be dropped
+	// from the table. If they are nil, Expr may refer to any column.
+	Columns []int
+}
+
+// validate checks that the constraints refer to valid columns.
+func (t Table) validate() error {
+	rowID := -1
+	for i, col := range t.Columns {
+		switch {
+		case col.Autoincrement && !col.RowID:
+			return fmt.Errorf("%w: %v.%v", ErrAutoincrement, t.Name, col.Name)
+		case col.RowID && rowID >= 0:
+			return fmt.Errorf("%w: %v has two INTEGER PRIMARY KEY columns", ErrInvalidColumns, t.Name)
+		case col.RowID:
+			rowID = i
+		}
+	}
+	for _, u := range t.Uniques {
+		if len(u.Columns) == 0 {
+			return fmt.Errorf("%w: %v has a constraint without columns", ErrInvalidColumns, t.Name)
+		}
+		for _, c := range u.Columns {
+			if c < 0 || c >= len(t.Columns) {
+				return fmt.Errorf("%w: %v has no column %d", ErrInvalidColumns, t.Name, c)
+			}
+		}
+	}
+	for _, c := range t.Checks {
+		for _, col := range c.Columns {
+			if col < 0 || col >= len(t.Columns) {
+				return fmt.Errorf("%w: %v has no column %d", ErrInvalidColumns, t.Name, col)
+			}
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		switch {
+		case len(fk.Columns) == 0:
+			return fmt.Errorf("%w: %v has a foreign key without columns", ErrInvalidColumns, t.Name)
+		case len(fk.ParentColumns) != 0 && len(fk.ParentColumns) != len(fk.Columns):
+			return fmt.Errorf("%w: foreign key of %v has %d columns, but references %d", ErrForeignKeyMismatch, t.Name, len(fk.Columns), len(fk.ParentColumns))
+		}
+		for _, c := range fk.Columns {
+			if c < 0 || c >= len(t.Columns) {
+				return fmt.Errorf("%w: %v has no column %d", ErrInvalidColumns, t.Name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// rowID returns the index of the column that is an alias of the row ID, or
+// -1 if there is no such column.
+func (t Table) rowID() int {
+	for i, col := range t.Columns {
+		if col.RowID {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnNames returns the qualified names of the given columns, like
+// "t.a, t.b".
+func (t Table) columnNames(columns []int) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = t.Name + "." + t.Columns[c].Name
+	}
+	return strings.Join(names, ", ")
+}
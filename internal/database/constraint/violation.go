@@ -0,0 +1,53 @@
+package constraint
+
+//go:generate stringer -type=Kind
+
+// Kind is the kind of a constraint.
+type Kind uint8
+
+// Known kinds of constraints.
+const (
+	NotNull Kind = iota
+	Unique
+	PrimaryKey
+	Check
+	ForeignKey
+)
+
+var kindNames = map[Kind]string{
+	NotNull:    "NOT NULL",
+	Unique:     "UNIQUE",
+	PrimaryKey: "UNIQUE",
+	Check:      "CHECK",
+	ForeignKey: "FOREIGN KEY",
+}
+
+// Violation is the error that is returned, if a statement violates a
+// constraint.
+type Violation struct {
+	Kind Kind
+	// Table is the name of the table that the constraint belongs to.
+	Table string
+	// Constraint identifies the violated constraint. It is the name of a
+	// CHECK constraint, the qualified names of the columns of other
+	// constraints, like "t.a, t.b", and "index 'name'" for a unique
+	// secondary index. It is empty for foreign keys, which are
+	// checked at the end of a statement or transaction, when the violating
+	// rows are no longer known.
+	Constraint string
+	// Conflict is the algorithm that resolved the violation, which is
+	// Rollback, Abort or Fail.
+	Conflict Conflict
+}
+
+func (v *Violation) Error() string {
+	if v.Constraint == "" {
+		return kindNames[v.Kind] + " constraint failed"
+	}
+	return kindNames[v.Kind] + " constraint failed: " + v.Constraint
+}
+
+// Is makes all violations match ErrConstraint.
+func (v *Violation) Is(target error) bool {
+	return target == ErrConstraint
+}
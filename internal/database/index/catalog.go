@@ -0,0 +1,164 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Catalog manages all indexes of a table with rows of type R. Index names are
+// case insensitive. A Catalog is not safe for concurrent use.
+type Catalog[R any] struct {
+	// indexes holds all indexes, in the order in which they were created.
+	indexes []*Index[R]
+	// store holds the entries of the indexes, under the prefix that prefix
+	// returns for the name of an index. If it is nil, every index is kept
+	// in memory.
+	store  Store
+	prefix func(name string) []byte
+}
+
+// NewCatalog creates a new catalog without any indexes, that keeps its
+// indexes in memory.
+func NewCatalog[R any]() *Catalog[R] {
+	return &Catalog[R]{}
+}
+
+// NewStoredCatalog creates a new catalog without any indexes, that stores the
+// entries of every index in the given store, under the prefix that the given
+// function returns for the name of the index.
+func NewStoredCatalog[R any](store Store, prefix func(name string) []byte) *Catalog[R] {
+	return &Catalog[R]{store: store, prefix: prefix}
+}
+
+// Open adds an index with the given definition, that was created in the
+// store of this catalog before, without building it again.
+func (c *Catalog[R]) Open(def Definition[R]) error {
+	if _, i := c.find(def.Name); i >= 0 {
+		return fmt.Errorf("%w: %v", ErrExists, def.Name)
+	}
+	c.indexes = append(c.indexes, c.open(def))
+	return nil
+}
+
+func (c *Catalog[R]) open(def Definition[R]) *Index[R] {
+	if c.store == nil {
+		return New(def)
+	}
+	return Open(def, c.store, c.prefix(def.Name))
+}
+
+// Create creates a new index with the given definition, and fills it with the
+// rows of the given scan. If an index with the same name already exists,
+// ErrExists is returned, unless ifNotExists is set, in which case nothing
+// happens. If the index cannot be filled, it is not created.
+func (c *Catalog[R]) Create(def Definition[R], ifNotExists bool, scan Scan[R]) error {
+	if _, i := c.find(def.Name); i >= 0 {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrExists, def.Name)
+	}
+
+	ix := c.open(def)
+	if err := ix.Build(scan); err != nil {
+		return fmt.Errorf("build %v: %w", def.Name, err)
+	}
+	c.indexes = append(c.indexes, ix)
+	return nil
+}
+
+// Drop removes the index with the given name and all of its entries. If no
+// such index exists, ErrNotExist is returned, unless ifExists is set.
+func (c *Catalog[R]) Drop(name string, ifExists bool) error {
+	ix, i := c.find(name)
+	if i < 0 {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrNotExist, name)
+	}
+	if err := ix.clear(); err != nil {
+		return err
+	}
+	c.indexes = append(c.indexes[:i:i], c.indexes[i+1:]...)
+	return nil
+}
+
+// Reindex rebuilds the index with the given name from the rows of the given
+// scan. If the name is empty, all indexes are rebuilt.
+func (c *Catalog[R]) Reindex(name string, scan Scan[R]) error {
+	indexes := c.indexes
+	if name != "" {
+		ix, i := c.find(name)
+		if i < 0 {
+			return fmt.Errorf("%w: %v", ErrNotExist, name)
+		}
+		indexes = []*Index[R]{ix}
+	}
+
+	for _, ix := range indexes {
+		if err := ix.Build(scan); err != nil {
+			return fmt.Errorf("build %v: %w", ix.def.Name, err)
+		}
+	}
+	return nil
+}
+
+// Index returns the index with the given name.
+func (c *Catalog[R]) Index(name string) (*Index[R], bool) {
+	ix, i := c.find(name)
+	return ix, i >= 0
+}
+
+// Indexes returns all indexes, in the order in which they were created.
+func (c *Catalog[R]) Indexes() []*Index[R] {
+	return append([]*Index[R]{}, c.indexes...)
+}
+
+// Insert adds the given row to all indexes. If it cannot be added to any of
+// them, all indexes are left unchanged.
+func (c *Catalog[R]) Insert(id RowID, row R) error {
+	for i, ix := range c.indexes {
+		if err := ix.Insert(id, row); err != nil {
+			for _, done := range c.indexes[:i] {
+				_ = done.Delete(id, row)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Update replaces the old values of the given row with the new ones in all
+// indexes. If the row cannot be updated in any of them, all indexes are left
+// unchanged.
+func (c *Catalog[R]) Update(id RowID, old, new R) error {
+	for i, ix := range c.indexes {
+		if err := ix.Update(id, old, new); err != nil {
+			for _, done := range c.indexes[:i] {
+				_ = done.Update(id, new, old)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes the given row from all indexes.
+func (c *Catalog[R]) Delete(id RowID, row R) error {
+	for _, ix := range c.indexes {
+		if err := ix.Delete(id, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Catalog[R]) find(name string) (*Index[R], int) {
+	for i, ix := range c.indexes {
+		if strings.EqualFold(ix.def.Name, name) {
+			return ix, i
+		}
+	}
+	return nil, -1
+}
@@ -0,0 +1,101 @@
+package index
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	assert := assert.New(t)
+	rows := append([]testRow{}, testRows...)
+
+	c := NewCatalog[testRow]()
+	names := Definition[testRow]{Name: "names", Columns: []Column{{}}, Key: byName}
+	emails := Definition[testRow]{Name: "emails", Unique: true, Columns: []Column{{}}, Key: byEmail}
+
+	assert.NoError(c.Create(names, false, scanRows(rows)))
+	assert.NoError(c.Create(emails, false, scanRows(rows)))
+	assert.True(errors.Is(c.Create(names, false, scanRows(rows)), ErrExists))
+	assert.NoError(c.Create(names, true, scanRows(rows)))
+	assert.Len(c.Indexes(), 2)
+
+	// a row that violates the unique index is not added to any index
+	err := c.Insert(6, testRow{"frank", "carol@example.com", 60})
+	assert.True(errors.Is(err, ErrUniqueViolation))
+	ix, ok := c.Index("NAMES")
+	assert.True(ok)
+	assert.Empty(ids(ix, Key{Value("frank")}))
+
+	assert.NoError(c.Insert(6, testRow{"frank", "frank@example.com", 60}))
+	assert.Equal([]RowID{6}, ids(ix, Key{Value("frank")}))
+
+	// a failed update is rolled back in all indexes
+	err = c.Update(6, testRow{"frank", "frank@example.com", 60}, testRow{"grace", "carol@example.com", 60})
+	assert.True(errors.Is(err, ErrUniqueViolation))
+	assert.Equal([]RowID{6}, ids(ix, Key{Value("frank")}))
+	assert.Empty(ids(ix, Key{Value("grace")}))
+
+	assert.NoError(c.Delete(6, testRow{"frank", "frank@example.com", 60}))
+	assert.Empty(ids(ix, Key{Value("frank")}))
+
+	assert.NoError(c.Drop("Names", false))
+	assert.True(errors.Is(c.Drop("names", false), ErrNotExist))
+	assert.NoError(c.Drop("names", true))
+	_, ok = c.Index("names")
+	assert.False(ok)
+}
+
+func TestCatalog_CreateFails(t *testing.T) {
+	c := NewCatalog[testRow]()
+	// the names are not unique
+	err := c.Create(Definition[testRow]{Name: "names", Unique: true, Columns: []Column{{}}, Key: byName}, false, scanRows(testRows))
+	assert.True(t, errors.Is(err, ErrUniqueViolation))
+	assert.Empty(t, c.Indexes())
+}
+
+func TestCatalog_Reindex(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCatalog[testRow]()
+	assert.NoError(c.Create(Definition[testRow]{Name: "names", Columns: []Column{{}}, Key: byName}, false, scanRows(testRows)))
+	assert.NoError(c.Create(Definition[testRow]{Name: "emails", Columns: []Column{{}}, Key: byEmail}, false, scanRows(testRows)))
+
+	rows := testRows[:2]
+	assert.NoError(c.Reindex("names", scanRows(rows)))
+	names, _ := c.Index("names")
+	emails, _ := c.Index("emails")
+	assert.Equal(2, length(names))
+	assert.Equal(len(testRows), length(emails))
+
+	assert.NoError(c.Reindex("", scanRows(rows)))
+	assert.Equal(2, length(emails))
+
+	assert.True(errors.Is(c.Reindex("unknown", scanRows(rows)), ErrNotExist))
+}
+
+func TestCatalog_Stored(t *testing.T) {
+	assert := assert.New(t)
+
+	store := newMemoryStore()
+	prefix := func(name string) []byte { return []byte("ix:" + name + ":") }
+	names := Definition[testRow]{Name: "names", Columns: []Column{{}}, Key: byName}
+	emails := Definition[testRow]{Name: "emails", Columns: []Column{{}}, Key: byEmail}
+
+	c := NewStoredCatalog[testRow](store, prefix)
+	assert.NoError(c.Create(names, false, scanRows(testRows)))
+	assert.NoError(c.Create(emails, false, scanRows(testRows)))
+
+	// another catalog opens the indexes from the store
+	opened := NewStoredCatalog[testRow](store, prefix)
+	assert.NoError(opened.Open(names))
+	assert.True(errors.Is(opened.Open(names), ErrExists))
+	ix, _ := opened.Index("names")
+	assert.Equal([]RowID{2, 5}, ids(ix, Key{Value("alice")}))
+
+	assert.NoError(c.Drop("names", false))
+	assert.Empty(ids(ix, Key{Value("alice")}), "entries must be removed from the store")
+	emailIndex, _ := c.Index("emails")
+	assert.Equal(len(testRows), length(emailIndex))
+}
@@ -0,0 +1,57 @@
+package index
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Collation defines the order of values. It returns the sort key of a value,
+// and values are ordered by comparing their sort keys byte by byte. Values
+// with equal sort keys are equal.
+type Collation func(v []byte) []byte
+
+// Built-in collations, as in SQLite.
+var (
+	// Binary compares values byte by byte.
+	Binary Collation = func(v []byte) []byte { return v }
+	// NoCase compares values byte by byte, but ignores the case of ASCII
+	// letters.
+	NoCase Collation = func(v []byte) []byte {
+		key := make([]byte, len(v))
+		for i, c := range v {
+			key[i] = toLower(c)
+		}
+		return key
+	}
+	// RTrim compares values byte by byte, but ignores trailing spaces.
+	RTrim Collation = func(v []byte) []byte {
+		return bytes.TrimRight(v, " ")
+	}
+)
+
+// Compare returns a negative number if a is less than b, a positive number if
+// a is greater than b, and 0 if they are equal.
+func (c Collation) Compare(a, b []byte) int {
+	return bytes.Compare(c(a), c(b))
+}
+
+// CollationByName returns the built-in collation with the given name, which is
+// case insensitive.
+func CollationByName(name string) (Collation, bool) {
+	switch strings.ToUpper(name) {
+	case "BINARY":
+		return Binary, true
+	case "NOCASE":
+		return NoCase, true
+	case "RTRIM":
+		return RTrim, true
+	}
+	return nil, false
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,18 @@
+// Package index implements secondary indexes on the rows of a table.
+//
+// An index maps the indexed values of every row to the ID of the row. Its
+// entries are stored in an ordered key-value Store, like a transaction of the
+// storage engine, so that changes to an index are part of the transaction that
+// makes them. The key of an entry encodes the indexed values in the order of
+// the index, with the collation and direction of every column.
+//
+// The values are computed from the row by the definition of the index, which
+// allows to index both columns and expressions. A partial index only contains
+// the rows that satisfy its predicate. A unique index rejects rows whose
+// values are equal to the values of another row, unless any of the values is
+// NULL.
+//
+// All indexes of a table are managed by a Catalog, which creates, drops and
+// rebuilds them, and keeps them up to date when rows are inserted, updated or
+// deleted.
+package index
@@ -0,0 +1,15 @@
+package index
+
+// Error provides constant errors to the index package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrUniqueViolation = Error("unique constraint violated")
+	ErrExists          = Error("index already exists")
+	ErrNotExist        = Error("no such index")
+	ErrKeyLength       = Error("key length does not match the amount of columns")
+	ErrCorrupt         = Error("corrupt index entry")
+)
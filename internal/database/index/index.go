@@ -0,0 +1,245 @@
+package index
+
+import "fmt"
+
+// treeOrder is the order of the btree of an index in memory.
+const treeOrder = 32
+
+// Definition defines an index on rows of type R.
+type Definition[R any] struct {
+	Name   string
+	Unique bool
+	// Columns describe the order of the indexed values.
+	Columns []Column
+	// Key computes the indexed values of a row, one for every column. The
+	// values may be the values of columns of the row, or the results of
+	// expressions that are evaluated on the row. The key must only depend on
+	// the row.
+	Key func(row R) (Key, error)
+	// Where is the predicate of a partial index. Only rows that satisfy it
+	// are indexed. If it is nil, all rows are indexed.
+	Where func(row R) (bool, error)
+}
+
+// Scan calls the given function for every row of a table, until the function
+// returns an error. The error is returned by Scan.
+type Scan[R any] func(fn func(id RowID, row R) error) error
+
+// Index is a secondary index on rows of type R. Its entries are stored in a
+// Store under a common prefix. The key of an entry is the encoding of the
+// indexed values followed by the row ID, so that the entries are ordered like
+// the index. An Index is not safe for concurrent modification, since a
+// modification consists of multiple operations on the store.
+type Index[R any] struct {
+	def    Definition[R]
+	store  Store
+	prefix []byte
+}
+
+// New creates a new, empty index with the given definition in memory. To
+// fill the index with existing rows, use Build.
+func New[R any](def Definition[R]) *Index[R] {
+	return Open(def, newMemoryStore(), nil)
+}
+
+// Open opens the index with the given definition, whose entries are stored
+// under the given prefix in the given store. The entries are not checked, so
+// the index must have been built with the same definition before, or be
+// built with Build.
+func Open[R any](def Definition[R], store Store, prefix []byte) *Index[R] {
+	return &Index[R]{
+		def:    def,
+		store:  store,
+		prefix: append([]byte{}, prefix...),
+	}
+}
+
+// Definition returns the definition of this index.
+func (ix *Index[R]) Definition() Definition[R] {
+	return ix.def
+}
+
+// Len returns the amount of rows in this index.
+func (ix *Index[R]) Len() (int, error) {
+	n := 0
+	err := ix.store.Scan(ix.prefix, prefixEnd(ix.prefix), func(_, _ []byte) (bool, error) {
+		n++
+		return true, nil
+	})
+	return n, err
+}
+
+// Build removes all rows from this index, and adds all rows of the given scan.
+// If the rows cannot be added, for example because they violate the
+// uniqueness of the index, the index is left empty.
+func (ix *Index[R]) Build(scan Scan[R]) error {
+	if err := ix.clear(); err != nil {
+		return err
+	}
+	if err := scan(ix.Insert); err != nil {
+		if clearErr := ix.clear(); clearErr != nil {
+			return clearErr
+		}
+		return err
+	}
+	return nil
+}
+
+// Insert adds the given row to this index, if it satisfies the predicate of
+// the index. If the index is unique, and another row with the same key
+// exists, ErrUniqueViolation is returned.
+func (ix *Index[R]) Insert(id RowID, row R) error {
+	key, ok, err := ix.key(row)
+	if err != nil || !ok {
+		return err
+	}
+	if ix.def.Unique && !key.hasNull() {
+		other, exists, err := ix.first(key)
+		if err != nil {
+			return err
+		}
+		if exists && other != id {
+			return fmt.Errorf("%w: index %v", ErrUniqueViolation, ix.def.Name)
+		}
+	}
+	return ix.store.Put(appendRowID(ix.encode(key), id), encodeKey(key))
+}
+
+// Delete removes the given row from this index. The row must be equal to the
+// row that was inserted.
+func (ix *Index[R]) Delete(id RowID, row R) error {
+	key, ok, err := ix.key(row)
+	if err != nil || !ok {
+		return err
+	}
+	return ix.store.Delete(appendRowID(ix.encode(key), id))
+}
+
+// Update replaces the old values of the given row with the new ones. If the
+// new row cannot be inserted, the index is left unchanged.
+func (ix *Index[R]) Update(id RowID, old, new R) error {
+	if err := ix.Delete(id, old); err != nil {
+		return err
+	}
+	if err := ix.Insert(id, new); err != nil {
+		// the old row could be inserted before, so this cannot fail
+		_ = ix.Insert(id, old)
+		return err
+	}
+	return nil
+}
+
+// Lookup calls the given function with the IDs of all rows, whose key starts
+// with the given prefix, in the order of the index, until the function
+// returns false. The prefix must not be longer than the key.
+func (ix *Index[R]) Lookup(prefix Key, fn func(id RowID) bool) error {
+	if len(prefix) > len(ix.def.Columns) {
+		return fmt.Errorf("%w: got %d values, index %v has %d columns", ErrKeyLength, len(prefix), ix.def.Name, len(ix.def.Columns))
+	}
+	start := ix.encode(prefix)
+	return ix.store.Scan(start, prefixEnd(start), func(key, _ []byte) (bool, error) {
+		id, err := decodeRowID(key)
+		if err != nil {
+			return false, err
+		}
+		return fn(id), nil
+	})
+}
+
+// Scan calls the given function with the keys and IDs of all rows in the
+// order of the index, until the function returns false.
+func (ix *Index[R]) Scan(fn func(key Key, id RowID) bool) error {
+	return ix.store.Scan(ix.prefix, prefixEnd(ix.prefix), func(entry, value []byte) (bool, error) {
+		id, err := decodeRowID(entry)
+		if err != nil {
+			return false, err
+		}
+		key, err := decodeKey(value)
+		if err != nil {
+			return false, err
+		}
+		return fn(key, id), nil
+	})
+}
+
+// DistinctPrefixes returns at position i the amount of distinct values of the
+// first i+1 columns of all rows in this index.
+func (ix *Index[R]) DistinctPrefixes() ([]int64, error) {
+	distinct := make([]int64, len(ix.def.Columns))
+	var prev Key
+	err := ix.Scan(func(key Key, _ RowID) bool {
+		// all prefixes that are at least as long as the first column that
+		// differs from the previous key are distinct
+		i := 0
+		if prev != nil {
+			for i < len(key) && i < len(prev) && compareValues(ix.def.Columns[i], prev[i], key[i]) == 0 {
+				i++
+			}
+		}
+		for ; i < len(key) && i < len(distinct); i++ {
+			distinct[i]++
+		}
+		prev = key
+		return true
+	})
+	return distinct, err
+}
+
+// first returns the ID of the first row with the given key.
+func (ix *Index[R]) first(key Key) (id RowID, exists bool, err error) {
+	err = ix.Lookup(key, func(found RowID) bool {
+		id, exists = found, true
+		return false
+	})
+	return
+}
+
+// key computes the key of the given row, and returns whether the row is
+// indexed.
+func (ix *Index[R]) key(row R) (Key, bool, error) {
+	if ix.def.Where != nil {
+		ok, err := ix.def.Where(row)
+		if err != nil {
+			return nil, false, fmt.Errorf("where: %w", err)
+		}
+		if !ok {
+			return nil, false, nil
+		}
+	}
+
+	key, err := ix.def.Key(row)
+	if err != nil {
+		return nil, false, fmt.Errorf("key: %w", err)
+	}
+	if len(key) != len(ix.def.Columns) {
+		return nil, false, fmt.Errorf("%w: got %d values, index %v has %d columns", ErrKeyLength, len(key), ix.def.Name, len(ix.def.Columns))
+	}
+	return key, true, nil
+}
+
+// encode returns the prefix of the entries of the given key, or of all keys
+// that start with it.
+func (ix *Index[R]) encode(key Key) []byte {
+	buf := append([]byte{}, ix.prefix...)
+	for i, v := range key {
+		buf = appendValue(buf, ix.def.Columns[i], v)
+	}
+	return buf
+}
+
+// clear removes all entries of this index.
+func (ix *Index[R]) clear() error {
+	var keys [][]byte
+	if err := ix.store.Scan(ix.prefix, prefixEnd(ix.prefix), func(key, _ []byte) (bool, error) {
+		keys = append(keys, key)
+		return true, nil
+	}); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := ix.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRow struct {
+	name  string
+	email string // empty is NULL
+	age   int
+}
+
+func value(s string) Value {
+	if s == "" {
+		return nil
+	}
+	return Value(s)
+}
+
+func byName(row testRow) (Key, error) {
+	return Key{value(row.name)}, nil
+}
+
+func byEmail(row testRow) (Key, error) {
+	return Key{value(row.email)}, nil
+}
+
+var testRows = []testRow{
+	{"carol", "carol@example.com", 41},
+	{"alice", "alice@example.com", 30},
+	{"Bob", "", 25},
+	{"dave", "", 17},
+	{"alice", "alice2@example.com", 52},
+}
+
+func scanRows(rows []testRow) Scan[testRow] {
+	return func(fn func(id RowID, row testRow) error) error {
+		for i, row := range rows {
+			if err := fn(RowID(i+1), row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func ids(ix *Index[testRow], prefix Key) []RowID {
+	var result []RowID
+	if err := ix.Lookup(prefix, func(id RowID) bool {
+		result = append(result, id)
+		return true
+	}); err != nil {
+		return nil
+	}
+	return result
+}
+
+func scanIDs(ix *Index[testRow]) []RowID {
+	var result []RowID
+	if err := ix.Scan(func(_ Key, id RowID) bool {
+		result = append(result, id)
+		return true
+	}); err != nil {
+		return nil
+	}
+	return result
+}
+
+func length(ix *Index[testRow]) int {
+	n, err := ix.Len()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func TestIndex_Order(t *testing.T) {
+	tests := []struct {
+		name   string
+		column Column
+		want   []RowID
+	}{
+		{"binary", Column{}, []RowID{3, 2, 5, 1, 4}},
+		{"binary desc", Column{Desc: true}, []RowID{4, 1, 2, 5, 3}},
+		{"nocase", Column{Collation: NoCase}, []RowID{2, 5, 3, 1, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix := New(Definition[testRow]{
+				Name:    "ix",
+				Columns: []Column{tt.column},
+				Key:     byName,
+			})
+			assert.NoError(t, ix.Build(scanRows(testRows)))
+			assert.Equal(t, tt.want, scanIDs(ix))
+		})
+	}
+}
+
+func TestIndex_Encoding(t *testing.T) {
+	// the values contain bytes, that are used by the encoding
+	rows := []testRow{{name: "a\x00"}, {name: "ab"}, {name: "a"}, {}, {name: "a\xff"}, {name: "\x00"}}
+	tests := []struct {
+		name   string
+		column Column
+		want   []RowID
+	}{
+		{"asc", Column{}, []RowID{4, 6, 3, 1, 2, 5}},
+		{"desc", Column{Desc: true}, []RowID{5, 2, 1, 3, 6, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix := New(Definition[testRow]{
+				Name:    "ix",
+				Columns: []Column{tt.column, {}},
+				Key: func(row testRow) (Key, error) {
+					return Key{value(row.name), Value("x")}, nil
+				},
+			})
+			assert.NoError(t, ix.Build(scanRows(rows)))
+			assert.Equal(t, tt.want, scanIDs(ix))
+			assert.Equal(t, []RowID{3}, ids(ix, Key{Value("a")}))
+			assert.Equal(t, []RowID{1}, ids(ix, Key{Value("a\x00"), Value("x")}))
+			assert.Equal(t, []RowID{4}, ids(ix, Key{nil}))
+
+			var keys []Key
+			assert.NoError(t, ix.Scan(func(key Key, _ RowID) bool {
+				keys = append(keys, key)
+				return true
+			}))
+			assert.Contains(t, keys, Key{nil, Value("x")})
+			assert.Contains(t, keys, Key{Value("a\x00"), Value("x")})
+		})
+	}
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	ix := New(Definition[testRow]{
+		Name:    "ix",
+		Columns: []Column{{Collation: NoCase}, {}},
+		Key: func(row testRow) (Key, error) {
+			return Key{value(row.name), Value(fmt.Sprintf("%03d", row.age))}, nil
+		},
+	})
+	assert.NoError(t, ix.Build(scanRows(testRows)))
+
+	assert.Equal(t, []RowID{2, 5}, ids(ix, Key{Value("ALICE")}))
+	assert.Equal(t, []RowID{5}, ids(ix, Key{Value("alice"), Value("052")}))
+	assert.Equal(t, []RowID{3}, ids(ix, Key{Value("bob")}))
+	assert.Empty(t, ids(ix, Key{Value("eve")}))
+	assert.Len(t, ids(ix, Key{}), len(testRows))
+}
+
+func TestIndex_DistinctPrefixes(t *testing.T) {
+	ix := New(Definition[testRow]{
+		Name:    "ix",
+		Columns: []Column{{Collation: NoCase}, {}},
+		Key: func(row testRow) (Key, error) {
+			return Key{value(row.name), value(row.email)}, nil
+		},
+	})
+	distinct, err := ix.DistinctPrefixes()
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{0, 0}, distinct)
+
+	assert.NoError(t, ix.Build(scanRows(append(testRows, testRow{name: "BOB"}))))
+	distinct, err = ix.DistinctPrefixes()
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{4, 5}, distinct)
+}
+
+func TestIndex_Unique(t *testing.T) {
+	assert := assert.New(t)
+
+	ix := New(Definition[testRow]{
+		Name:    "email",
+		Unique:  true,
+		Columns: []Column{{Collation: NoCase}},
+		Key:     byEmail,
+	})
+	assert.NoError(ix.Build(scanRows(testRows)))
+	assert.Equal(len(testRows), length(ix))
+
+	// NULLs are distinct
+	assert.NoError(ix.Insert(10, testRow{name: "eve"}))
+
+	err := ix.Insert(11, testRow{email: "ALICE@example.com"})
+	assert.True(errors.Is(err, ErrUniqueViolation))
+
+	// updating a row to its own key is allowed
+	assert.NoError(ix.Update(2, testRows[1], testRows[1]))
+
+	// a failed update leaves the index unchanged
+	err = ix.Update(1, testRows[0], testRow{email: "alice@example.com"})
+	assert.True(errors.Is(err, ErrUniqueViolation))
+	assert.Equal([]RowID{1}, ids(ix, Key{Value("carol@example.com")}))
+
+	assert.NoError(ix.Update(1, testRows[0], testRow{email: "carol@example.org"}))
+	assert.Empty(ids(ix, Key{Value("carol@example.com")}))
+	assert.Equal([]RowID{1}, ids(ix, Key{Value("carol@example.org")}))
+
+	// a failed build leaves the index empty
+	err = ix.Build(scanRows(append(testRows, testRows[0])))
+	assert.True(errors.Is(err, ErrUniqueViolation))
+	assert.Equal(0, length(ix))
+}
+
+func TestIndex_Partial(t *testing.T) {
+	assert := assert.New(t)
+
+	adult := func(row testRow) (bool, error) { return row.age >= 18, nil }
+	ix := New(Definition[testRow]{
+		Name:    "adults",
+		Unique:  true,
+		Columns: []Column{{}},
+		Key:     byName,
+		Where:   adult,
+	})
+	// alice is not unique, but only adults are indexed
+	assert.NoError(ix.Build(scanRows([]testRow{{"alice", "", 30}, {"alice", "", 12}})))
+	assert.Equal([]RowID{1}, scanIDs(ix))
+
+	// the row leaves the index when it stops satisfying the predicate
+	assert.NoError(ix.Update(1, testRow{"alice", "", 30}, testRow{"alice", "", 10}))
+	assert.Equal(0, length(ix))
+	assert.NoError(ix.Update(2, testRow{"alice", "", 12}, testRow{"alice", "", 20}))
+	assert.Equal([]RowID{2}, scanIDs(ix))
+
+	// deleting a row that is not indexed is a no-op
+	assert.NoError(ix.Delete(1, testRow{"alice", "", 10}))
+	assert.NoError(ix.Delete(2, testRow{"alice", "", 20}))
+	assert.Equal(0, length(ix))
+}
+
+func TestIndex_Expression(t *testing.T) {
+	// index on lower(name)
+	ix := New(Definition[testRow]{
+		Name:    "lower_name",
+		Columns: []Column{{}},
+		Key: func(row testRow) (Key, error) {
+			return Key{Value(strings.ToLower(row.name))}, nil
+		},
+	})
+	assert.NoError(t, ix.Build(scanRows(testRows)))
+	assert.Equal(t, []RowID{3}, ids(ix, Key{Value("bob")}))
+}
+
+func TestIndex_Error(t *testing.T) {
+	errBroken := errors.New("broken")
+	ix := New(Definition[testRow]{
+		Name:    "ix",
+		Columns: []Column{{}, {}},
+		Key:     byName,
+	})
+	assert.True(t, errors.Is(ix.Insert(1, testRows[0]), ErrKeyLength))
+
+	ix = New(Definition[testRow]{
+		Name:    "ix",
+		Columns: []Column{{}},
+		Key:     func(testRow) (Key, error) { return nil, errBroken },
+	})
+	assert.True(t, errors.Is(ix.Insert(1, testRows[0]), errBroken))
+}
+
+func TestCollation(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"BINARY", "a", "B", 1},
+		{"NOCASE", "a", "B", -1},
+		{"nocase", "ABC", "abc", 0},
+		{"NOCASE", "ab", "ABC", -1},
+		{"RTRIM", "abc  ", "abc", 0},
+		{"RTRIM", "abc  ", "abd", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.a+"/"+tt.b, func(t *testing.T) {
+			collation, ok := CollationByName(tt.name)
+			assert.True(t, ok)
+
+			got := collation.Compare([]byte(tt.a), []byte(tt.b))
+			switch {
+			case tt.want < 0:
+				assert.Less(t, got, 0)
+			case tt.want > 0:
+				assert.Greater(t, got, 0)
+			default:
+				assert.Zero(t, got)
+			}
+		})
+	}
+
+	_, ok := CollationByName("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,143 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Value is an indexed value. Values are compared with the collation of their
+// column. A nil Value is NULL, which is less than all other values.
+type Value []byte
+
+// Key is the list of indexed values of a row, one for every column of the
+// index.
+type Key []Value
+
+// hasNull returns whether any value of the key is NULL.
+func (k Key) hasNull() bool {
+	for _, v := range k {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RowID identifies a row of a table.
+type RowID int64
+
+// Column describes how the values of a column of an index are ordered.
+type Column struct {
+	// Collation compares the values of the column. If it is nil, Binary is
+	// used.
+	Collation Collation
+	// Desc indicates that the values are ordered descending.
+	Desc bool
+}
+
+// Tags of encoded values.
+const (
+	tagNull  byte = 0x01
+	tagValue byte = 0x02
+)
+
+// appendValue appends the encoding of the given value to buf. The encodings of
+// the values of a column are ordered like the values, and no encoding is a
+// prefix of another one, so that the encodings of keys can be concatenated.
+//
+// NULL is encoded as a single byte. Other values are encoded as their sort
+// key, where every 0x00 is escaped as 0x00 0xff, and are terminated by 0x00
+// 0x01. For descending columns, all bytes are inverted.
+func appendValue(buf []byte, col Column, v Value) []byte {
+	start := len(buf)
+	if v == nil {
+		buf = append(buf, tagNull)
+	} else {
+		collation := col.Collation
+		if collation == nil {
+			collation = Binary
+		}
+		buf = append(buf, tagValue)
+		for _, c := range collation(v) {
+			if c == 0x00 {
+				buf = append(buf, 0x00, 0xff)
+			} else {
+				buf = append(buf, c)
+			}
+		}
+		buf = append(buf, 0x00, 0x01)
+	}
+	if col.Desc {
+		for i := start; i < len(buf); i++ {
+			buf[i] = ^buf[i]
+		}
+	}
+	return buf
+}
+
+// appendRowID appends the encoding of the given row ID to buf, which is
+// ordered like the row IDs.
+func appendRowID(buf []byte, id RowID) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(id)^(1<<63))
+	return append(buf, b[:]...)
+}
+
+// decodeRowID decodes the row ID at the end of the given entry key.
+func decodeRowID(key []byte) (RowID, error) {
+	if len(key) < 8 {
+		return 0, fmt.Errorf("%w: entry of %d bytes", ErrCorrupt, len(key))
+	}
+	return RowID(binary.BigEndian.Uint64(key[len(key)-8:]) ^ (1 << 63)), nil
+}
+
+// encodeKey encodes the original values of a key, which are stored with the
+// entry of the key.
+func encodeKey(key Key) []byte {
+	var buf []byte
+	for _, v := range key {
+		if v == nil {
+			buf = append(buf, 0)
+			continue
+		}
+		var n [binary.MaxVarintLen64]byte
+		buf = append(buf, n[:binary.PutUvarint(n[:], uint64(len(v))+1)]...)
+		buf = append(buf, v...)
+	}
+	return buf
+}
+
+// decodeKey decodes a key, that was encoded with encodeKey.
+func decodeKey(buf []byte) (Key, error) {
+	var key Key
+	for len(buf) > 0 {
+		n, size := binary.Uvarint(buf)
+		if size <= 0 {
+			return nil, fmt.Errorf("%w: key", ErrCorrupt)
+		}
+		buf = buf[size:]
+		if n == 0 {
+			key = append(key, nil)
+			continue
+		}
+		if n-1 > uint64(len(buf)) {
+			return nil, fmt.Errorf("%w: key", ErrCorrupt)
+		}
+		key = append(key, Value(append([]byte{}, buf[:n-1]...)))
+		buf = buf[n-1:]
+	}
+	return key, nil
+}
+
+// compareValues compares two values of the given column in the order of the
+// index.
+func compareValues(col Column, a, b Value) int {
+	x, y := appendValue(nil, col, a), appendValue(nil, col, b)
+	switch {
+	case string(x) < string(y):
+		return -1
+	case string(x) > string(y):
+		return 1
+	}
+	return 0
+}
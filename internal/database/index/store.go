@@ -0,0 +1,62 @@
+package index
+
+import "github.com/tomarrell/lbadd/internal/database/storage/btree"
+
+// Store is the ordered key-value storage of the entries of an index. Keys are
+// ordered byte by byte. A transaction of the storage engine is a Store, which
+// makes the changes to an index part of the transaction.
+type Store interface {
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	// Scan calls the given function with every key from start (inclusive)
+	// to end (exclusive) and its value, in ascending order of the keys,
+	// until the function returns false or an error. A nil end is
+	// unbounded.
+	Scan(start, end []byte, fn func(key, value []byte) (bool, error)) error
+}
+
+// memoryStore is a Store that holds its keys in memory.
+type memoryStore struct {
+	tree btree.Btree[string, []byte]
+}
+
+// newMemoryStore creates a new, empty Store in memory.
+func newMemoryStore() Store {
+	return &memoryStore{
+		tree: btree.New[string, []byte](treeOrder, func(a, b string) bool { return a < b }),
+	}
+}
+
+func (s *memoryStore) Put(key, value []byte) error {
+	s.tree.Put(string(key), append([]byte{}, value...))
+	return nil
+}
+
+func (s *memoryStore) Delete(key []byte) error {
+	s.tree.Delete(string(key))
+	return nil
+}
+
+func (s *memoryStore) Scan(start, end []byte, fn func(key, value []byte) (bool, error)) error {
+	cur := s.tree.Cursor()
+	for cur.Seek(string(start)); cur.Valid() && (end == nil || cur.Key() < string(end)); cur.Next() {
+		more, err := fn([]byte(cur.Key()), cur.Value())
+		if err != nil || !more {
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixEnd returns the smallest key that is greater than all keys with the
+// given prefix, or nil if there is no such key.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+// Package lock implements a lock manager for hierarchical locks on tables and
+// rows, as needed for strict two-phase locking.
+//
+// Locks are held by transactions, which are identified by a TxID. A
+// transaction locks a row in a table by acquiring an intention lock on the
+// table first, which the lock manager does automatically. A transaction that
+// already holds a lock on a resource can upgrade it by locking the resource
+// again, with a stronger mode.
+//
+// If a lock cannot be granted immediately, the transaction waits until it can.
+// Every time a transaction starts to wait, the lock manager checks the graph of
+// transactions that wait for each other for a cycle. If there is one, the
+// youngest transaction in the cycle, which is the one with the highest TxID, is
+// chosen as victim, and its waiting request fails with ErrDeadlock. The victim
+// should release all of its locks and retry.
+package lock
@@ -0,0 +1,13 @@
+package lock
+
+// Error provides constant errors to the lock package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	// ErrDeadlock indicates that a transaction was chosen as the victim of a
+	// deadlock. The transaction should be rolled back and retried.
+	ErrDeadlock = Error("deadlock detected")
+)
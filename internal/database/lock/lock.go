@@ -0,0 +1,336 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// TxID identifies a transaction that holds locks. Transactions that started
+// later must have higher IDs, since the youngest transaction is chosen as the
+// victim of a deadlock.
+type TxID uint64
+
+// Resource is a lockable resource, which is either a table, or a row of a
+// table.
+type Resource struct {
+	Table string
+	// Row identifies a row of the table. If it is empty, the resource is the
+	// table itself.
+	Row string
+}
+
+// table returns the resource for the table of this resource.
+func (r Resource) table() Resource {
+	return Resource{Table: r.Table}
+}
+
+func (r Resource) String() string {
+	if r.Row == "" {
+		return r.Table
+	}
+	return fmt.Sprintf("%v[%v]", r.Table, r.Row)
+}
+
+// Manager manages the locks of all transactions. A Manager is safe for
+// concurrent use by multiple goroutines. A single transaction must not
+// request multiple locks concurrently.
+type Manager struct {
+	mu    sync.Mutex
+	locks map[Resource]*lock
+	// held holds the resources that every transaction holds locks on.
+	held map[TxID]map[Resource]struct{}
+	// waiting holds the request that every transaction waits for.
+	waiting map[TxID]*request
+}
+
+// lock is the lock on a single resource.
+type lock struct {
+	resource Resource
+	// holders holds the granted mode of every transaction that holds this
+	// lock.
+	holders map[TxID]Mode
+	// queue holds the requests that wait for this lock, in the order in
+	// which they will be granted. Upgrades are queued before all other
+	// requests.
+	queue []*request
+}
+
+// request is a request of a transaction for a mode of a lock, that has to wait
+// until it can be granted.
+type request struct {
+	tx   TxID
+	lock *lock
+	mode Mode
+	// upgrade indicates that the transaction already holds the lock with a
+	// weaker mode.
+	upgrade bool
+	// done is closed after the request was either granted or failed.
+	done chan struct{}
+	// err is set if the request failed.
+	err error
+}
+
+// New creates a new lock manager, that does not hold any locks.
+func New() *Manager {
+	return &Manager{
+		locks:   make(map[Resource]*lock),
+		held:    make(map[TxID]map[Resource]struct{}),
+		waiting: make(map[TxID]*request),
+	}
+}
+
+// Lock acquires the given resource with the given mode for the given
+// transaction. If the resource is a row, the table is locked with the
+// intention mode first. If the transaction already holds the resource, the
+// lock is upgraded to a mode that covers both the held and the given mode.
+//
+// If the lock cannot be granted immediately, Lock waits until it can be
+// granted, until the context is done, or until the transaction was chosen as
+// the victim of a deadlock, in which case ErrDeadlock is returned. Locks that
+// were granted before are kept in any case.
+func (m *Manager) Lock(ctx context.Context, tx TxID, res Resource, mode Mode) error {
+	if res.Row != "" {
+		if err := m.lock(ctx, tx, res.table(), mode.Intention()); err != nil {
+			return err
+		}
+	}
+	return m.lock(ctx, tx, res, mode)
+}
+
+func (m *Manager) lock(ctx context.Context, tx TxID, res Resource, mode Mode) error {
+	m.mu.Lock()
+
+	l, ok := m.locks[res]
+	if !ok {
+		l = &lock{
+			resource: res,
+			holders:  make(map[TxID]Mode),
+		}
+		m.locks[res] = l
+	}
+
+	held, upgrade := l.holders[tx]
+	mode = held.Supremum(mode)
+	if upgrade && held == mode {
+		m.mu.Unlock()
+		return nil
+	}
+
+	if l.grantable(tx, mode) && (upgrade || len(l.queue) == 0) {
+		m.grant(l, tx, mode)
+		m.mu.Unlock()
+		return nil
+	}
+
+	req := &request{
+		tx:      tx,
+		lock:    l,
+		mode:    mode,
+		upgrade: upgrade,
+		done:    make(chan struct{}),
+	}
+	l.enqueue(req)
+	m.waiting[tx] = req
+	m.detectDeadlock(tx)
+	m.mu.Unlock()
+
+	select {
+	case <-req.done:
+		return req.err
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-req.done:
+		// the request was completed while the lock was being acquired
+		return req.err
+	default:
+	}
+	m.fail(req, ctx.Err())
+	return ctx.Err()
+}
+
+// Mode returns the mode that the given transaction holds on the given
+// resource, or None.
+func (m *Manager) Mode(tx TxID, res Resource) Mode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if l, ok := m.locks[res]; ok {
+		return l.holders[tx]
+	}
+	return None
+}
+
+// ReleaseAll releases all locks that the given transaction holds.
+func (m *Manager) ReleaseAll(tx TxID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for res := range m.held[tx] {
+		l := m.locks[res]
+		delete(l.holders, tx)
+		m.wake(l)
+	}
+	delete(m.held, tx)
+}
+
+// grant grants the given mode of the given lock to the given transaction.
+func (m *Manager) grant(l *lock, tx TxID, mode Mode) {
+	l.holders[tx] = mode
+	if m.held[tx] == nil {
+		m.held[tx] = make(map[Resource]struct{})
+	}
+	m.held[tx][l.resource] = struct{}{}
+}
+
+// wake grants as many waiting requests of the given lock as possible, in the
+// order of the queue. If the lock is not held and not requested anymore, it
+// is removed.
+func (m *Manager) wake(l *lock) {
+	for len(l.queue) > 0 {
+		req := l.queue[0]
+		if !l.grantable(req.tx, req.mode) {
+			break
+		}
+		l.queue = l.queue[1:]
+		delete(m.waiting, req.tx)
+		m.grant(l, req.tx, req.mode)
+		close(req.done)
+	}
+
+	if len(l.holders) == 0 && len(l.queue) == 0 {
+		delete(m.locks, l.resource)
+	}
+}
+
+// fail removes the given waiting request, and completes it with the given
+// error.
+func (m *Manager) fail(req *request, err error) {
+	l := req.lock
+	for i, queued := range l.queue {
+		if queued == req {
+			l.queue = append(l.queue[:i:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	delete(m.waiting, req.tx)
+	req.err = err
+	close(req.done)
+
+	// requests behind the failed one may be grantable now
+	m.wake(l)
+}
+
+// grantable returns whether the given mode is compatible with the modes of all
+// other holders of this lock.
+func (l *lock) grantable(tx TxID, mode Mode) bool {
+	for holder, held := range l.holders {
+		if holder != tx && !mode.Compatible(held) {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueue adds the given request to the queue. Upgrades are added after all
+// upgrades that are already waiting, other requests are added at the end.
+func (l *lock) enqueue(req *request) {
+	if !req.upgrade {
+		l.queue = append(l.queue, req)
+		return
+	}
+
+	i := 0
+	for i < len(l.queue) && l.queue[i].upgrade {
+		i++
+	}
+	l.queue = append(l.queue, nil)
+	copy(l.queue[i+1:], l.queue[i:])
+	l.queue[i] = req
+}
+
+// blockers returns the transactions that the given waiting request waits for.
+// These are all other holders of the lock with an incompatible mode, and all
+// other requests that are queued before it, since requests are granted in
+// order.
+func (l *lock) blockers(req *request) []TxID {
+	var txs []TxID
+	for holder, held := range l.holders {
+		if holder != req.tx && !req.mode.Compatible(held) {
+			txs = append(txs, holder)
+		}
+	}
+	for _, queued := range l.queue {
+		if queued == req {
+			break
+		}
+		if queued.tx != req.tx {
+			txs = append(txs, queued.tx)
+		}
+	}
+	return txs
+}
+
+// detectDeadlock checks whether the given transaction, which just started to
+// wait, is part of a cycle in the wait-for graph. As long as there is such a
+// cycle, the youngest transaction in it is chosen as the victim, and its
+// request fails with ErrDeadlock.
+func (m *Manager) detectDeadlock(tx TxID) {
+	for {
+		if _, ok := m.waiting[tx]; !ok {
+			return
+		}
+		cycle := m.findCycle(tx)
+		if cycle == nil {
+			return
+		}
+
+		victim := cycle[0]
+		for _, t := range cycle {
+			if t > victim {
+				victim = t
+			}
+		}
+		m.fail(m.waiting[victim], ErrDeadlock)
+	}
+}
+
+// findCycle returns the transactions on a cycle in the wait-for graph that
+// contains the given transaction, or nil if there is no such cycle.
+func (m *Manager) findCycle(start TxID) []TxID {
+	visited := make(map[TxID]bool)
+	var path []TxID
+
+	var visit func(tx TxID) bool
+	visit = func(tx TxID) bool {
+		req, ok := m.waiting[tx]
+		if !ok {
+			return false
+		}
+		path = append(path, tx)
+		for _, next := range req.lock.blockers(req) {
+			if next == start {
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	visited[start] = true
+	if visit(start) {
+		return path
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	row1 = Resource{Table: "t", Row: "1"}
+	row2 = Resource{Table: "t", Row: "2"}
+	row3 = Resource{Table: "t", Row: "3"}
+)
+
+// lockAsync requests a lock in a new goroutine. The returned channel receives
+// the result.
+func lockAsync(m *Manager, tx TxID, res Resource, mode Mode) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- m.Lock(context.Background(), tx, res, mode)
+	}()
+	return result
+}
+
+// awaitWaiting waits until the given transaction waits for a lock.
+func awaitWaiting(t *testing.T, m *Manager, tx TxID) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		_, ok := m.waiting[tx]
+		return ok
+	}, time.Second, time.Millisecond, "transaction %d must wait", tx)
+}
+
+func awaitResult(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("lock was not granted")
+		return nil
+	}
+}
+
+func TestLock_Shared(t *testing.T) {
+	assert := assert.New(t)
+	m := New()
+	ctx := context.Background()
+
+	assert.NoError(m.Lock(ctx, 1, row1, Shared))
+	assert.NoError(m.Lock(ctx, 2, row1, Shared))
+	assert.NoError(m.Lock(ctx, 3, row2, Exclusive))
+
+	assert.Equal(Shared, m.Mode(1, row1))
+	assert.Equal(IntentionShared, m.Mode(1, row1.table()))
+	assert.Equal(IntentionExclusive, m.Mode(3, row2.table()))
+	assert.Equal(None, m.Mode(3, row1))
+
+	for tx := TxID(1); tx <= 3; tx++ {
+		m.ReleaseAll(tx)
+	}
+	assert.Empty(m.locks)
+	assert.Empty(m.held)
+}
+
+func TestLock_Wait(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	assert.NoError(t, m.Lock(ctx, 1, row1, Exclusive))
+	result := lockAsync(m, 2, row1, Shared)
+	awaitWaiting(t, m, 2)
+
+	// a table lock waits for the intention lock of the row lock
+	tableResult := lockAsync(m, 3, Resource{Table: "t"}, Shared)
+	awaitWaiting(t, m, 3)
+
+	m.ReleaseAll(1)
+	assert.NoError(t, awaitResult(t, result))
+	assert.NoError(t, awaitResult(t, tableResult))
+	assert.Equal(t, Shared, m.Mode(2, row1))
+	assert.Equal(t, Shared, m.Mode(3, Resource{Table: "t"}))
+}
+
+func TestLock_Upgrade(t *testing.T) {
+	assert := assert.New(t)
+	m := New()
+	ctx := context.Background()
+
+	assert.NoError(m.Lock(ctx, 1, row1, Shared))
+	assert.NoError(m.Lock(ctx, 1, row1, Exclusive))
+	assert.Equal(Exclusive, m.Mode(1, row1))
+	assert.Equal(IntentionExclusive, m.Mode(1, row1.table()))
+
+	// downgrades are ignored
+	assert.NoError(m.Lock(ctx, 1, row1, Shared))
+	assert.Equal(Exclusive, m.Mode(1, row1))
+
+	table := Resource{Table: "u"}
+	assert.NoError(m.Lock(ctx, 1, table, Shared))
+	assert.NoError(m.Lock(ctx, 1, table, IntentionExclusive))
+	assert.Equal(SharedIntentionExclusive, m.Mode(1, table))
+}
+
+func TestLock_UpgradeBeforeWaiters(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	assert.NoError(t, m.Lock(ctx, 1, row1, Shared))
+	assert.NoError(t, m.Lock(ctx, 2, row1, Shared))
+	waiter := lockAsync(m, 3, row1, Exclusive)
+	awaitWaiting(t, m, 3)
+
+	upgrade := lockAsync(m, 1, row1, Exclusive)
+	awaitWaiting(t, m, 1)
+
+	// the upgrade is granted before the request that waited longer
+	m.ReleaseAll(2)
+	assert.NoError(t, awaitResult(t, upgrade))
+	m.ReleaseAll(1)
+	assert.NoError(t, awaitResult(t, waiter))
+}
+
+func TestLock_Context(t *testing.T) {
+	m := New()
+
+	assert.NoError(t, m.Lock(context.Background(), 1, row1, Shared))
+	waiter := lockAsync(m, 3, row1, Shared)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, m.Lock(ctx, 2, row1, Exclusive))
+
+	// the shared lock was queued behind the exclusive lock, and is granted
+	// as soon as the exclusive lock stops waiting
+	assert.NoError(t, awaitResult(t, waiter))
+	assert.Equal(t, IntentionExclusive, m.Mode(2, row1.table()), "intention lock must be kept")
+}
+
+func TestDeadlock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("requester is victim", func(t *testing.T) {
+		m := New()
+		assert.NoError(t, m.Lock(ctx, 1, row1, Exclusive))
+		assert.NoError(t, m.Lock(ctx, 2, row2, Exclusive))
+
+		result := lockAsync(m, 1, row2, Exclusive)
+		awaitWaiting(t, m, 1)
+		assert.Equal(t, ErrDeadlock, m.Lock(ctx, 2, row1, Exclusive))
+
+		m.ReleaseAll(2)
+		assert.NoError(t, awaitResult(t, result))
+	})
+	t.Run("waiter is victim", func(t *testing.T) {
+		m := New()
+		assert.NoError(t, m.Lock(ctx, 2, row1, Exclusive))
+		assert.NoError(t, m.Lock(ctx, 1, row2, Exclusive))
+
+		victim := lockAsync(m, 2, row2, Exclusive)
+		awaitWaiting(t, m, 2)
+		result := lockAsync(m, 1, row1, Exclusive)
+		assert.Equal(t, ErrDeadlock, awaitResult(t, victim))
+
+		m.ReleaseAll(2)
+		assert.NoError(t, awaitResult(t, result))
+	})
+	t.Run("upgrade", func(t *testing.T) {
+		m := New()
+		assert.NoError(t, m.Lock(ctx, 1, row1, Shared))
+		assert.NoError(t, m.Lock(ctx, 2, row1, Shared))
+
+		result := lockAsync(m, 1, row1, Exclusive)
+		awaitWaiting(t, m, 1)
+		assert.Equal(t, ErrDeadlock, m.Lock(ctx, 2, row1, Exclusive))
+
+		m.ReleaseAll(2)
+		assert.NoError(t, awaitResult(t, result))
+	})
+	t.Run("three transactions", func(t *testing.T) {
+		m := New()
+		assert.NoError(t, m.Lock(ctx, 1, row1, Exclusive))
+		assert.NoError(t, m.Lock(ctx, 2, row2, Exclusive))
+		assert.NoError(t, m.Lock(ctx, 3, row3, Exclusive))
+
+		first := lockAsync(m, 1, row2, Shared)
+		awaitWaiting(t, m, 1)
+		victim := lockAsync(m, 3, row1, Shared)
+		awaitWaiting(t, m, 3)
+		second := lockAsync(m, 2, row3, Shared)
+		assert.Equal(t, ErrDeadlock, awaitResult(t, victim))
+
+		m.ReleaseAll(3)
+		assert.NoError(t, awaitResult(t, second))
+		m.ReleaseAll(2)
+		assert.NoError(t, awaitResult(t, first))
+	})
+	t.Run("queue order", func(t *testing.T) {
+		// 3 waits for 2 only because 2 was queued first
+		m := New()
+		assert.NoError(t, m.Lock(ctx, 1, row1, Shared))
+		assert.NoError(t, m.Lock(ctx, 3, row2, Exclusive))
+
+		blocked := lockAsync(m, 2, row1, Exclusive)
+		awaitWaiting(t, m, 2)
+		victim := lockAsync(m, 3, row1, IntentionShared)
+		awaitWaiting(t, m, 3)
+		result := lockAsync(m, 1, row2, Shared)
+		assert.Equal(t, ErrDeadlock, awaitResult(t, victim))
+
+		m.ReleaseAll(3)
+		assert.NoError(t, awaitResult(t, result))
+		m.ReleaseAll(1)
+		assert.NoError(t, awaitResult(t, blocked))
+	})
+}
+
+func TestLock_Concurrent(t *testing.T) {
+	const (
+		workers = 8
+		rows    = 4
+		rounds  = 50
+	)
+
+	m := New()
+	var nextTx TxID
+	var txMu sync.Mutex
+	newTx := func() TxID {
+		txMu.Lock()
+		defer txMu.Unlock()
+		nextTx++
+		return nextTx
+	}
+
+	// owners tracks the exclusive owner of every row, to check mutual
+	// exclusion
+	var ownersMu sync.Mutex
+	owners := make(map[Resource]TxID)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			// attempt locks two random rows exclusively, and reports
+			// whether it succeeded
+			attempt := func(tx TxID) bool {
+				defer m.ReleaseAll(tx)
+
+				var locked []Resource
+				for _, r := range rnd.Perm(rows)[:2] {
+					res := Resource{Table: "t", Row: fmt.Sprint(r)}
+					if err := m.Lock(context.Background(), tx, res, Exclusive); err != nil {
+						assert.Equal(t, ErrDeadlock, err)
+						return false
+					}
+					locked = append(locked, res)
+				}
+
+				ownersMu.Lock()
+				for _, res := range locked {
+					assert.Zero(t, owners[res], "row %v is locked by two transactions", res)
+					owners[res] = tx
+				}
+				ownersMu.Unlock()
+
+				runtime.Gosched()
+
+				ownersMu.Lock()
+				for _, res := range locked {
+					delete(owners, res)
+				}
+				ownersMu.Unlock()
+				return true
+			}
+
+			for i := 0; i < rounds; i++ {
+				for !attempt(newTx()) {
+				}
+			}
+		}(int64(w))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("transactions did not finish, deadlock was not detected")
+	}
+	assert.Empty(t, m.locks)
+}
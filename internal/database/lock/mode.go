@@ -0,0 +1,84 @@
+package lock
+
+//go:generate stringer -type=Mode
+
+// Mode is the mode of a lock.
+type Mode uint8
+
+// Supported lock modes.
+const (
+	// None is not a lock mode, but the absence of a lock.
+	None Mode = iota
+	// IntentionShared is acquired on a table before a row of it is locked
+	// with Shared.
+	IntentionShared
+	// IntentionExclusive is acquired on a table before a row of it is locked
+	// with Exclusive.
+	IntentionExclusive
+	// Shared allows reading a resource.
+	Shared
+	// SharedIntentionExclusive is the combination of Shared and
+	// IntentionExclusive. It allows to read a table, and to lock single rows
+	// of it with Exclusive to update them.
+	SharedIntentionExclusive
+	// Exclusive allows reading and writing a resource.
+	Exclusive
+)
+
+// compatibility indicates, which modes can be held by different transactions
+// on the same resource at the same time.
+var compatibility = [...][6]bool{
+	//                          None  IS     IX     S      SIX    X
+	None:                     {true, true, true, true, true, true},
+	IntentionShared:          {true, true, true, true, true, false},
+	IntentionExclusive:       {true, true, true, false, false, false},
+	Shared:                   {true, true, false, true, false, false},
+	SharedIntentionExclusive: {true, true, false, false, false, false},
+	Exclusive:                {true, false, false, false, false, false},
+}
+
+// Compatible returns whether the given mode can be held by a transaction,
+// while another transaction holds this mode.
+func (m Mode) Compatible(other Mode) bool {
+	return compatibility[m][other]
+}
+
+// Covers returns whether this mode grants at least all the rights that the
+// given mode grants.
+func (m Mode) Covers(other Mode) bool {
+	switch {
+	case m == other, other == None, m == Exclusive:
+		return true
+	case m == SharedIntentionExclusive:
+		return other != Exclusive
+	case m == Shared, m == IntentionExclusive:
+		return other == IntentionShared
+	}
+	return false
+}
+
+// Supremum returns the weakest mode that covers both this and the given mode.
+// This is the mode that results from upgrading a lock.
+func (m Mode) Supremum(other Mode) Mode {
+	switch {
+	case m.Covers(other):
+		return m
+	case other.Covers(m):
+		return other
+	}
+	// the only modes that do not cover each other are Shared and
+	// IntentionExclusive
+	return SharedIntentionExclusive
+}
+
+// Intention returns the mode that must be held on a table, before a row of it
+// can be locked with this mode.
+func (m Mode) Intention() Mode {
+	switch m {
+	case None:
+		return None
+	case IntentionShared, Shared:
+		return IntentionShared
+	}
+	return IntentionExclusive
+}
@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=Mode ./internal/database/lock"; DO NOT EDIT.
+
+package lock
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[None-0]
+	_ = x[IntentionShared-1]
+	_ = x[IntentionExclusive-2]
+	_ = x[Shared-3]
+	_ = x[SharedIntentionExclusive-4]
+	_ = x[Exclusive-5]
+}
+
+const _Mode_name = "NoneIntentionSharedIntentionExclusiveSharedSharedIntentionExclusiveExclusive"
+
+var _Mode_index = [...]uint8{0, 4, 19, 37, 43, 67, 76}
+
+func (i Mode) String() string {
+	if i >= Mode(len(_Mode_index)-1) {
+		return "Mode(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Mode_name[_Mode_index[i]:_Mode_index[i+1]]
+}
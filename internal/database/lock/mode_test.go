@@ -0,0 +1,56 @@
+package lock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var modes = []Mode{None, IntentionShared, IntentionExclusive, Shared, SharedIntentionExclusive, Exclusive}
+
+func TestMode_Compatible(t *testing.T) {
+	for _, a := range modes {
+		for _, b := range modes {
+			assert.Equal(t, a.Compatible(b), b.Compatible(a), "compatibility of %v and %v must be symmetric", a, b)
+		}
+	}
+	assert.True(t, Shared.Compatible(IntentionShared))
+	assert.False(t, Shared.Compatible(IntentionExclusive))
+	assert.True(t, IntentionExclusive.Compatible(IntentionExclusive))
+	assert.False(t, Exclusive.Compatible(IntentionShared))
+}
+
+func TestMode_Supremum(t *testing.T) {
+	tests := []struct {
+		a, b, want Mode
+	}{
+		{None, Shared, Shared},
+		{IntentionShared, IntentionExclusive, IntentionExclusive},
+		{IntentionShared, Shared, Shared},
+		{IntentionExclusive, Shared, SharedIntentionExclusive},
+		{Shared, SharedIntentionExclusive, SharedIntentionExclusive},
+		{SharedIntentionExclusive, Exclusive, Exclusive},
+		{Exclusive, IntentionShared, Exclusive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a.String()+"+"+tt.b.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.a.Supremum(tt.b))
+			assert.Equal(t, tt.want, tt.b.Supremum(tt.a))
+		})
+	}
+
+	// the supremum must cover both modes, and must not be compatible with
+	// more modes than any of them
+	for _, a := range modes {
+		for _, b := range modes {
+			sup := a.Supremum(b)
+			assert.True(t, sup.Covers(a))
+			assert.True(t, sup.Covers(b))
+			for _, other := range modes {
+				if sup.Compatible(other) {
+					assert.True(t, a.Compatible(other) && b.Compatible(other))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// Buckets is the maximum amount of buckets of a histogram.
+	Buckets = 32
+	// sampleSize is the maximum amount of values of a column, that the
+	// histogram of the column is built from.
+	sampleSize = 10000
+	// exactDistinct is the maximum amount of distinct values of a column,
+	// that are counted exactly. Beyond that, the amount is estimated with a
+	// HyperLogLog sketch, so that the memory of ANALYZE does not grow with
+	// the size of the table.
+	exactDistinct = 4096
+)
+
+// Scan calls the given function for every row of a table, until the function
+// returns an error, which is then returned by Scan. A row holds a value for
+// every column, where a nil value is NULL.
+type Scan func(fn func(row [][]byte) error) error
+
+// AnalyzeTable computes the statistics of a table with the given amount of
+// columns from all rows of the given scan. The amount of distinct values is
+// exact for columns with few distinct values, and estimated otherwise. The
+// histograms are built from a sample of the values.
+func AnalyzeTable(columns int, scan Scan) (*Table, error) {
+	// the sample is deterministic, so that analyzing the same data twice
+	// yields the same statistics
+	rnd := rand.New(rand.NewSource(1))
+
+	collectors := make([]columnCollector, columns)
+	for i := range collectors {
+		collectors[i].distinct = make(map[string]struct{})
+		collectors[i].sketch = new(sketch)
+	}
+
+	var rows int64
+	if err := scan(func(row [][]byte) error {
+		if len(row) != columns {
+			return fmt.Errorf("%w: got %d, expected %d", ErrColumnCount, len(row), columns)
+		}
+		for i, v := range row {
+			collectors[i].add(rnd, v)
+		}
+		rows++
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	t := &Table{
+		Rows:    rows,
+		Columns: make([]Column, columns),
+		Indexes: make(map[string]Index),
+	}
+	for i := range collectors {
+		t.Columns[i] = collectors[i].column()
+	}
+	return t, nil
+}
+
+// AnalyzeIndex computes the statistics of an index with the given amount of
+// rows, where distinct holds at position i the amount of distinct values of
+// the first i+1 columns of the index.
+func AnalyzeIndex(rows int64, distinct []int64) Index {
+	ix := Index{
+		Rows:       rows,
+		RowsPerKey: make([]float64, len(distinct)),
+	}
+	for i, d := range distinct {
+		if d > 0 {
+			ix.RowsPerKey[i] = float64(rows) / float64(d)
+		}
+	}
+	return ix
+}
+
+// columnCollector collects the statistics of a single column.
+type columnCollector struct {
+	// distinct holds the distinct values that are not NULL, until there are
+	// more than exactDistinct of them, and is nil after that. The sketch
+	// holds all values that are not NULL.
+	distinct map[string]struct{}
+	sketch   *sketch
+	nulls    int64
+	values   int64
+	// sample is a uniform sample of the values that are not NULL.
+	sample [][]byte
+}
+
+func (c *columnCollector) add(rnd *rand.Rand, v []byte) {
+	if v == nil {
+		c.nulls++
+		return
+	}
+	c.sketch.add(v)
+	if c.distinct != nil {
+		c.distinct[string(v)] = struct{}{}
+		if len(c.distinct) > exactDistinct {
+			c.distinct = nil
+		}
+	}
+	c.values++
+
+	// reservoir sampling
+	if len(c.sample) < sampleSize {
+		c.sample = append(c.sample, append([]byte{}, v...))
+	} else if i := rnd.Int63n(c.values); i < sampleSize {
+		c.sample[i] = append([]byte{}, v...)
+	}
+}
+
+func (c *columnCollector) column() Column {
+	col := Column{
+		Distinct: int64(len(c.distinct)),
+		Nulls:    c.nulls,
+	}
+	if c.distinct == nil {
+		// the estimate is kept between the amount of distinct values,
+		// that were counted exactly, and the amount of values
+		col.Distinct = c.sketch.estimate()
+		if col.Distinct > c.values {
+			col.Distinct = c.values
+		}
+		if col.Distinct <= exactDistinct {
+			col.Distinct = exactDistinct + 1
+		}
+	}
+	if len(c.sample) == 0 {
+		return col
+	}
+
+	sort.Slice(c.sample, func(i, j int) bool {
+		return bytes.Compare(c.sample[i], c.sample[j]) < 0
+	})
+
+	// scale the counts of the sample to the counts of the column
+	sampleDistinct := 1
+	for i := 1; i < len(c.sample); i++ {
+		if !bytes.Equal(c.sample[i-1], c.sample[i]) {
+			sampleDistinct++
+		}
+	}
+	countScale := float64(c.values) / float64(len(c.sample))
+	distinctScale := float64(col.Distinct) / float64(sampleDistinct)
+
+	depth := (len(c.sample) + Buckets - 1) / Buckets
+	var count, distinct int
+	for i, v := range c.sample {
+		count++
+		if i == 0 || !bytes.Equal(c.sample[i-1], v) {
+			distinct++
+		}
+
+		// equal values must be in the same bucket
+		last := i == len(c.sample)-1
+		if last || (count >= depth && !bytes.Equal(v, c.sample[i+1])) {
+			col.Histogram = append(col.Histogram, Bucket{
+				Upper:    v,
+				Count:    int64(math.Round(float64(count) * countScale)),
+				Distinct: int64(math.Max(1, math.Round(float64(distinct)*distinctScale))),
+			})
+			count, distinct = 0, 0
+		}
+	}
+	return col
+}
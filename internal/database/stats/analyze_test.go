@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scanRows returns a scan over the given rows.
+func scanRows(rows [][][]byte) Scan {
+	return func(fn func(row [][]byte) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// generate returns n rows with the given column generators.
+func generate(n int, columns ...func(i int) []byte) [][][]byte {
+	rows := make([][][]byte, n)
+	for i := range rows {
+		rows[i] = make([][]byte, len(columns))
+		for j, col := range columns {
+			rows[i][j] = col(i)
+		}
+	}
+	return rows
+}
+
+func TestAnalyzeTable(t *testing.T) {
+	assert := assert.New(t)
+
+	rows := generate(1000,
+		// unique
+		func(i int) []byte { return []byte(fmt.Sprintf("%04d", i)) },
+		// 10 values, every 4th is NULL
+		func(i int) []byte {
+			if i%4 == 0 {
+				return nil
+			}
+			return []byte(fmt.Sprint(i % 10))
+		},
+	)
+	table, err := AnalyzeTable(2, scanRows(rows))
+	assert.NoError(err)
+
+	assert.Equal(int64(1000), table.Rows)
+	assert.Equal(int64(1000), table.Columns[0].Distinct)
+	assert.Equal(int64(0), table.Columns[0].Nulls)
+	assert.Equal(int64(10), table.Columns[1].Distinct)
+	assert.Equal(int64(250), table.Columns[1].Nulls)
+
+	for _, col := range table.Columns {
+		assert.LessOrEqual(len(col.Histogram), Buckets)
+		var count int64
+		for i, b := range col.Histogram {
+			count += b.Count
+			if i > 0 {
+				assert.Less(string(col.Histogram[i-1].Upper), string(b.Upper), "buckets must be ordered")
+			}
+		}
+		assert.Equal(table.Rows, count+col.Nulls)
+	}
+}
+
+func TestAnalyzeTable_Sample(t *testing.T) {
+	rows := generate(5*sampleSize, func(i int) []byte { return []byte(fmt.Sprintf("%06d", i%1000)) })
+	table, err := AnalyzeTable(1, scanRows(rows))
+	assert.NoError(t, err)
+
+	col := table.Columns[0]
+	assert.Equal(t, int64(1000), col.Distinct)
+	assert.InDelta(t, 1.0/1000, col.EqualSelectivity([]byte("000500")), 0.0005)
+	assert.InDelta(t, 0.5, col.RangeSelectivity(nil, []byte("000499")), 0.05)
+}
+
+func TestAnalyzeTable_Error(t *testing.T) {
+	_, err := AnalyzeTable(2, scanRows([][][]byte{{[]byte("a")}}))
+	assert.True(t, errors.Is(err, ErrColumnCount))
+
+	errScan := errors.New("scan")
+	_, err = AnalyzeTable(2, func(func([][]byte) error) error { return errScan })
+	assert.Equal(t, errScan, err)
+}
+
+func TestAnalyzeIndex(t *testing.T) {
+	ix := AnalyzeIndex(1000, []int64{10, 500, 0})
+	assert.Equal(t, int64(1000), ix.Rows)
+	assert.Equal(t, []float64{100, 2, 0}, ix.RowsPerKey)
+}
+
+func TestAnalyzeTable_Estimate(t *testing.T) {
+	assert := assert.New(t)
+
+	const n = 200000
+	rows := generate(n,
+		// unique
+		func(i int) []byte { return []byte(fmt.Sprint(i)) },
+		// just above the amount that is counted exactly
+		func(i int) []byte { return []byte(fmt.Sprint(i % (exactDistinct + 10))) },
+	)
+	table, err := AnalyzeTable(2, scanRows(rows))
+	assert.NoError(err)
+
+	assert.InEpsilon(int64(n), table.Columns[0].Distinct, 0.03)
+	assert.InEpsilon(int64(exactDistinct+10), table.Columns[1].Distinct, 0.03)
+	assert.Greater(table.Columns[1].Distinct, int64(exactDistinct))
+	assert.InDelta(1.0/n, table.Columns[0].EqualSelectivity([]byte("100")), 0.1/n)
+}
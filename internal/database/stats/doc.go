@@ -0,0 +1,14 @@
+// Package stats implements statistics about the data in tables and indexes, as
+// gathered by ANALYZE and used by the query planner to estimate the amount of
+// rows that operations produce.
+//
+// For every column of a table, the statistics contain the amount of distinct
+// values, the amount of NULLs, and an equi-depth histogram of the values. For
+// every index, they contain the average amount of rows that share the same
+// values in the first columns of the index, like sqlite_stat1 does.
+//
+// ANALYZE reads every row, but needs only constant memory per column. The
+// histograms are built from a sample, and the amount of distinct values of a
+// column is estimated with HyperLogLog, once it is too large to be counted
+// exactly.
+package stats
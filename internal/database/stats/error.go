@@ -0,0 +1,11 @@
+package stats
+
+// Error provides constant errors to the stats package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrColumnCount = Error("row has wrong amount of columns")
+)
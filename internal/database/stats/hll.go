@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// precision is the amount of bits of a hash, that select the register of a
+// sketch. A sketch has 2^precision registers of one byte, and a standard
+// error of 1.04/sqrt(2^precision), which is about 0.8%.
+const precision = 14
+
+// sketch is a HyperLogLog sketch, that estimates the amount of distinct
+// values that were added to it in constant memory.
+type sketch struct {
+	registers [1 << precision]uint8
+}
+
+// add adds the given value to the sketch.
+func (s *sketch) add(v []byte) {
+	h := hash(v)
+	// the first bits select the register, and the register holds the
+	// maximum position of the first 1 bit in the remaining bits
+	i := h >> (64 - precision)
+	w := h<<precision | 1<<(precision-1)
+	if rank := uint8(bits.LeadingZeros64(w) + 1); rank > s.registers[i] {
+		s.registers[i] = rank
+	}
+}
+
+// estimate returns the estimated amount of distinct values in the sketch.
+func (s *sketch) estimate() int64 {
+	const m = float64(len(s.registers))
+	alpha := 0.7213 / (1 + 1.079/m)
+
+	var sum float64
+	zeros := 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := alpha * m * m / sum
+	// linear counting is more accurate for small cardinalities, and with 64
+	// bit hashes, large cardinalities need no correction
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(e))
+}
+
+// hash returns the FNV-1a hash of the given value, with the bits mixed by
+// the finalizer of MurmurHash3, since HyperLogLog needs all bits of the hash
+// to be uniformly distributed.
+func hash(v []byte) uint64 {
+	f := fnv.New64a()
+	_, _ = f.Write(v)
+	h := f.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,24 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketch(t *testing.T) {
+	tests := []int{0, 1, 100, 5000, 100000, 1000000}
+	for _, n := range tests {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			var s sketch
+			for i := 0; i < n; i++ {
+				v := []byte(fmt.Sprint(i))
+				// duplicates don't change the estimate
+				s.add(v)
+				s.add(v)
+			}
+			assert.InEpsilon(t, float64(n)+1, float64(s.estimate())+1, 0.03)
+		})
+	}
+}
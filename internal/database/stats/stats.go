@@ -0,0 +1,148 @@
+package stats
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Table holds the statistics of a table.
+type Table struct {
+	Rows int64
+	// Columns holds the statistics of every column, in the order of the
+	// columns of the table.
+	Columns []Column
+	// Indexes holds the statistics of the indexes of the table by their
+	// lowercase name.
+	Indexes map[string]Index
+}
+
+// Column holds the statistics of a column.
+type Column struct {
+	Distinct int64
+	Nulls    int64
+	// Histogram holds the distribution of all values that are not NULL.
+	Histogram Histogram
+}
+
+// Histogram is an equi-depth histogram. Every bucket holds roughly the same
+// amount of values. The buckets are ordered by their upper bound.
+type Histogram []Bucket
+
+// Bucket is a bucket of a histogram. It holds all values that are greater than
+// the upper bound of the previous bucket, and less than or equal to its own
+// upper bound.
+type Bucket struct {
+	Upper    []byte
+	Count    int64
+	Distinct int64
+}
+
+// Index holds the statistics of an index.
+type Index struct {
+	Rows int64
+	// RowsPerKey holds at position i the average amount of rows that have
+	// equal values in the first i+1 columns of the index.
+	RowsPerKey []float64
+}
+
+// EqualSelectivity estimates the fraction of rows, whose value in this column
+// is equal to the given value. A nil value is NULL.
+func (c Column) EqualSelectivity(v []byte) float64 {
+	total := c.total()
+	if total == 0 {
+		return 0
+	}
+	if v == nil {
+		return float64(c.Nulls) / float64(total)
+	}
+
+	i := sort.Search(len(c.Histogram), func(i int) bool {
+		return bytes.Compare(v, c.Histogram[i].Upper) <= 0
+	})
+	if i == len(c.Histogram) {
+		// the value is greater than all values that were seen
+		return 0
+	}
+	b := c.Histogram[i]
+	if b.Distinct == 0 {
+		return 0
+	}
+	return float64(b.Count) / float64(b.Distinct) / float64(total)
+}
+
+// RangeSelectivity estimates the fraction of rows, whose value in this column
+// is between the given bounds, inclusive. A nil bound is unbounded. Buckets
+// that are only partially covered by the range are counted half.
+func (c Column) RangeSelectivity(low, high []byte) float64 {
+	total := c.total()
+	if total == 0 {
+		return 0
+	}
+
+	var rows float64
+	var lower []byte // upper bound of the previous bucket, nil for the first one
+	for _, b := range c.Histogram {
+		below := low != nil && bytes.Compare(b.Upper, low) < 0
+		above := high != nil && lower != nil && bytes.Compare(lower, high) >= 0
+		fromLow := low == nil || (lower != nil && bytes.Compare(lower, low) >= 0)
+		toHigh := high == nil || bytes.Compare(b.Upper, high) <= 0
+
+		switch {
+		case below || above:
+		case fromLow && toHigh:
+			rows += float64(b.Count)
+		default:
+			rows += float64(b.Count) / 2
+		}
+		lower = b.Upper
+	}
+	return rows / float64(total)
+}
+
+func (c Column) total() int64 {
+	total := c.Nulls
+	for _, b := range c.Histogram {
+		total += b.Count
+	}
+	return total
+}
+
+// Catalog holds the statistics of all tables, by their case insensitive name.
+// A Catalog is safe for concurrent use.
+type Catalog struct {
+	mu     sync.RWMutex
+	tables map[string]*Table
+}
+
+// NewCatalog creates a new, empty catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{
+		tables: make(map[string]*Table),
+	}
+}
+
+// Put stores the statistics of the table with the given name, and replaces the
+// previous statistics.
+func (c *Catalog) Put(name string, t *Table) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tables[strings.ToLower(name)] = t
+}
+
+// Table returns the statistics of the table with the given name, if the table
+// has been analyzed.
+func (c *Catalog) Table(name string) (*Table, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, ok := c.tables[strings.ToLower(name)]
+	return t, ok
+}
+
+// Delete removes the statistics of the table with the given name.
+func (c *Catalog) Delete(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tables, strings.ToLower(name))
+}
@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumn_Selectivity(t *testing.T) {
+	// 10 NULLs, and 90 values in 3 buckets: (-, b], (b, d], (d, f]
+	col := Column{
+		Distinct: 6,
+		Nulls:    10,
+		Histogram: Histogram{
+			{Upper: []byte("b"), Count: 30, Distinct: 2},
+			{Upper: []byte("d"), Count: 30, Distinct: 2},
+			{Upper: []byte("f"), Count: 30, Distinct: 2},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"= NULL", col.EqualSelectivity(nil), 0.1},
+		{"= a", col.EqualSelectivity([]byte("a")), 0.15},
+		{"= c", col.EqualSelectivity([]byte("c")), 0.15},
+		{"= z", col.EqualSelectivity([]byte("z")), 0},
+		{"all", col.RangeSelectivity(nil, nil), 0.9},
+		{"<= d", col.RangeSelectivity(nil, []byte("d")), 0.6},
+		{"> d", col.RangeSelectivity([]byte("e"), nil), 0.15},
+		{"between b and d", col.RangeSelectivity([]byte("b"), []byte("d")), 0.45},
+		{"between c and c", col.RangeSelectivity([]byte("c"), []byte("c")), 0.15},
+		{"> z", col.RangeSelectivity([]byte("z"), nil), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.got, 1e-9)
+		})
+	}
+
+	assert.Zero(t, Column{}.EqualSelectivity([]byte("a")))
+	assert.Zero(t, Column{}.RangeSelectivity(nil, nil))
+}
+
+func TestCatalog(t *testing.T) {
+	c := NewCatalog()
+	_, ok := c.Table("users")
+	assert.False(t, ok)
+
+	users := &Table{Rows: 10}
+	c.Put("Users", users)
+	got, ok := c.Table("USERS")
+	assert.True(t, ok)
+	assert.Same(t, users, got)
+
+	c.Delete("users")
+	_, ok = c.Table("users")
+	assert.False(t, ok)
+}
@@ -1,35 +1,63 @@
 package btree
 
+import (
+	"sync"
+	"sync/atomic"
+)
+
 const defaultOrder = 3
 
-// Btree describes a btree.
-type Btree interface {
-	get(k key) (v *entry, exists bool)
-	insert(k key, v value)
-	remove(k key) (removed bool)
-	getAll(limit int) []*entry
-	getAbove(k key, limit int) []*entry
-	getBelow(k key, limit int) []*entry
-	getBetween(low, high key, limit int) []*entry
+// Btree describes a btree, which is an ordered map from keys to values. The
+// order of the keys is defined by the less function that the btree was created
+// with.
+//
+//  tree := btree.New[int, string](3, func(a, b int) bool { return a < b })
+//  tree.Put(1, "one")
+//  v, ok := tree.Get(1)
+//
+// A Btree is safe for concurrent use by multiple goroutines.
+type Btree[K, V any] interface {
+	// Get returns the value that is stored with the given key, and whether
+	// the key exists in the tree.
+	Get(k K) (v V, exists bool)
+	// Put stores the given value with the given key. If the key already
+	// exists in the tree, its value is replaced.
+	Put(k K, v V)
+	// Delete removes the given key from the tree, and returns whether the
+	// key existed in the tree.
+	Delete(k K) (removed bool)
+	// Len returns the amount of entries in the tree.
+	Len() int
+	// Range calls fn for every entry with a key in the range [low, high], in
+	// ascending order of the keys. If fn returns false, the iteration stops.
+	Range(low, high K, fn func(k K, v V) bool)
+	// Cursor returns a new cursor over the tree, which can be used for
+	// forward and reverse scans.
+	Cursor() *Cursor[K, V]
 }
 
-type (
-	key   int
-	value interface{}
-)
-
 // node defines the stuct which contains keys (entries) and
-// the child nodes of a particular node in the b-tree
-type node struct {
-	parent   *node
-	entries  []*entry
-	children []*node
+// the child nodes of a particular node in the b-tree.
+//
+// The latch of a node protects its entries and children.
+// Operations on the tree use latch coupling: the latch of
+// a child is acquired before the latch of its parent is
+// released, and latches are only ever acquired from the
+// root downwards, which makes deadlocks impossible.
+type node[K, V any] struct {
+	latch    sync.RWMutex
+	parent   *node[K, V]
+	entries  []*entry[K, V]
+	children []*node[K, V]
 }
 
-// entry is a key/value pair that is stored in the b-tree
-type entry struct {
-	key   key
-	value value
+// entry is a key/value pair that is stored in the b-tree.
+// Entries are never modified after they have been created,
+// so they can safely be used after the latch of the node
+// that holds them has been released.
+type entry[K, V any] struct {
+	key   K
+	value V
 }
 
 // btree is the main structure.
@@ -37,82 +65,158 @@ type entry struct {
 // "order" invariants:
 // - every node except root must contain at least order-1 keys
 // - every node may contain at most (2*order)-1 keys
-type btree struct {
-	root  *node
-	size  int
+//
+// The root node is never replaced. When the height of the
+// tree changes, the root is modified in place, so that no
+// latch is needed to protect the root pointer.
+type btree[K, V any] struct {
+	// size is accessed atomically, and is the first field
+	// to guarantee 64-bit alignment on 32-bit platforms
+	size  int64
+	root  *node[K, V]
 	order int
+	less  func(a, b K) bool
 }
 
-// newBtree creates a new instance of Btree
-func newBtree() *btree {
-	return &btree{
-		root:  nil,
-		size:  0,
-		order: defaultOrder,
+// New creates a new, empty Btree of the given order. The given less function
+// defines the order of the keys, and must not be nil. Two keys a and b are
+// considered equal, if neither less(a, b) nor less(b, a) is true. If the given
+// order is less than 2, a default order is used.
+func New[K, V any](order int, less func(a, b K) bool) Btree[K, V] {
+	return newBtree[K, V](order, less)
+}
+
+// Load creates a new Btree of the given order, that contains the given keys
+// and values. The keys must be in strictly ascending order according to the
+// given less function, and there must be exactly one value for every key.
+// Loading sorted input is considerably faster than putting every entry into
+// the tree individually, since no nodes have to be split.
+func Load[K, V any](order int, less func(a, b K) bool, keys []K, values []V) (Btree[K, V], error) {
+	if len(keys) != len(values) {
+		return nil, ErrLengthMismatch
+	}
+
+	entries := make([]*entry[K, V], len(keys))
+	for i := range keys {
+		if i > 0 && !less(keys[i-1], keys[i]) {
+			return nil, ErrUnsorted
+		}
+		entries[i] = &entry[K, V]{keys[i], values[i]}
 	}
+
+	b := newBtree[K, V](order, less)
+	b.load(entries)
+	return b, nil
 }
 
-func newBtreeOrder(order int) *btree {
-	return &btree{
-		root:  nil,
-		size:  0,
+func newBtree[K, V any](order int, less func(a, b K) bool) *btree[K, V] {
+	if order < 2 {
+		order = defaultOrder
+	}
+
+	return &btree[K, V]{
+		size: 0,
+		root: &node[K, V]{
+			entries:  []*entry[K, V]{},
+			children: []*node[K, V]{},
+		},
 		order: order,
+		less:  less,
 	}
 }
 
-// get searches for a specific key in the btree,
-// returning a pointer to the resulting entry
-// and a boolean as to whether it exists in the tree
-func (b *btree) get(k key) (result *entry, exists bool) {
-	if b.root == nil || len(b.root.entries) == 0 {
-		return nil, false
+// Get returns the value that is stored with the given key, and whether the key
+// exists in the tree.
+func (b *btree[K, V]) Get(k K) (v V, exists bool) {
+	e, exists := b.get(k)
+	if !exists {
+		return
 	}
+	return e.value, true
+}
 
-	return b.getNode(b.root, k)
+// Put stores the given value with the given key. If the key already exists in
+// the tree, its value is replaced.
+func (b *btree[K, V]) Put(k K, v V) {
+	b.insert(k, v)
 }
 
-func (b *btree) getNode(node *node, k key) (result *entry, exists bool) {
-	i, exists := b.search(node.entries, k)
-	if exists {
-		return node.entries[i], true
+// Delete removes the given key from the tree, and returns whether the key
+// existed in the tree.
+func (b *btree[K, V]) Delete(k K) (removed bool) {
+	return b.remove(k)
+}
+
+// Len returns the amount of entries in the tree.
+func (b *btree[K, V]) Len() int {
+	return int(atomic.LoadInt64(&b.size))
+}
+
+// Range calls fn for every entry with a key in the range [low, high], in
+// ascending order of the keys. If fn returns false, the iteration stops.
+func (b *btree[K, V]) Range(low, high K, fn func(k K, v V) bool) {
+	c := b.Cursor()
+	for c.Seek(low); c.Valid() && !b.less(high, c.Key()); c.Next() {
+		if !fn(c.Key(), c.Value()) {
+			return
+		}
 	}
+}
 
-	if i > len(node.children) {
+// get searches for a specific key in the btree,
+// returning a pointer to the resulting entry
+// and a boolean as to whether it exists in the tree
+func (b *btree[K, V]) get(k K) (result *entry[K, V], exists bool) {
+	if b.root == nil {
 		return nil, false
 	}
 
-	return b.getNode(node.children[i], k)
-}
+	node := b.root
+	node.latch.RLock()
+	for {
+		i, exists := b.search(node.entries, k)
+		if exists {
+			result = node.entries[i]
+			node.latch.RUnlock()
+			return result, true
+		}
 
-// insert takes a key and value, creats a new
-// entry and inserts it in the tree according to the key
-func (b *btree) insert(k key, v value) {
-	if b.root == nil {
-		b.size++
-		b.root = &node{
-			parent:   nil,
-			entries:  []*entry{{k, v}},
-			children: []*node{},
+		if node.isLeaf() || i >= len(node.children) {
+			node.latch.RUnlock()
+			return nil, false
 		}
-		return
-	}
 
-	b.insertNode(b.root, &entry{k, v})
+		child := node.children[i]
+		child.latch.RLock()
+		node.latch.RUnlock()
+		node = child
+	}
 }
 
-// insertNode takes a node and the entry to insert
-func (b *btree) insertNode(node *node, entry *entry) (inserted bool) {
+// insert takes a key and value, creats a new
+// entry and inserts it in the tree according to the key
+func (b *btree[K, V]) insert(k K, v V) {
+	b.root.latch.Lock()
+
 	// If the root node is already full, we need to split it
-	if node == b.root && node.isFull(b.order) {
-		b.root = node.split()
+	if b.root.isFull(b.order) {
+		b.root.split()
 	}
 
+	b.insertNode(b.root, &entry[K, V]{k, v})
+}
+
+// insertNode takes a node and the entry to insert. The node
+// must be write latched and must not be full. The latch is
+// released before insertNode returns.
+func (b *btree[K, V]) insertNode(node *node[K, V], entry *entry[K, V]) (inserted bool) {
 	// Search for the key in the node's entries
 	idx, exists := b.search(node.entries, entry.key)
 
 	// The entry already exists, so it should be updated
 	if exists {
 		node.entries[idx] = entry
+		node.latch.Unlock()
 		return false
 	}
 
@@ -123,7 +227,8 @@ func (b *btree) insertNode(node *node, entry *entry) (inserted bool) {
 		node.entries = append(node.entries, nil)
 		copy(node.entries[idx+1:], node.entries[idx:])
 		node.entries[idx] = entry
-		b.size++
+		atomic.AddInt64(&b.size, 1)
+		node.latch.Unlock()
 		return true
 	}
 
@@ -131,92 +236,266 @@ func (b *btree) insertNode(node *node, entry *entry) (inserted bool) {
 	// if the appropriate child is already full,
 	// and conditionally split it. Otherwise traverse
 	// to that child.
-	if node.children[idx].isFull(b.order) {
-		node.children[idx] = node.children[idx].split()
+	child := node.children[idx]
+	child.latch.Lock()
+	if child.isFull(b.order) {
+		node.splitChild(idx)
+
+		// The median of the child has been moved up into
+		// this node, so we need to check which side of it
+		// the entry belongs on.
+		switch median := node.entries[idx]; {
+		case b.less(median.key, entry.key):
+			child.latch.Unlock()
+			child = node.children[idx+1]
+			child.latch.Lock()
+		case !b.less(entry.key, median.key):
+			node.entries[idx] = entry
+			child.latch.Unlock()
+			node.latch.Unlock()
+			return false
+		}
 	}
 
-	return b.insertNode(node.children[idx], entry)
+	node.latch.Unlock()
+	return b.insertNode(child, entry)
 }
 
 // remove tries to delete an entry from the tree, and
 // returns true if the entry was removed, and false if
 // the key was not found in the tree
-func (b *btree) remove(k key) (removed bool) {
+func (b *btree[K, V]) remove(k K) (removed bool) {
 	if b.root == nil {
 		return false
 	}
 
-	return b.removeNode(b.root, k)
+	b.root.latch.Lock()
+	return b.removeNode(b.root, func(entries []*entry[K, V], _ bool) (int, bool) {
+		return b.search(entries, k)
+	}) != nil
+}
+
+// locator finds the position of the entry that should be
+// removed in the given entries of a node. If the entry is
+// not in the entries, it returns the index of the child
+// that the entry has to be searched in.
+type locator[K, V any] func(entries []*entry[K, V], leaf bool) (index int, exists bool)
+
+// locateMin locates the entry with the smallest key.
+func locateMin[K, V any](entries []*entry[K, V], leaf bool) (int, bool) {
+	return 0, leaf && len(entries) > 0
 }
 
-// removeNode takes a node and key and bool, and recursively deletes
-// k from the node, while maintaining the order invariants
-func (b *btree) removeNode(node *node, k key) (removed bool) {
-	idx, exists := b.search(node.entries, k)
+// locateMax locates the entry with the largest key.
+func locateMax[K, V any](entries []*entry[K, V], leaf bool) (int, bool) {
+	if leaf {
+		return len(entries) - 1, len(entries) > 0
+	}
+	return len(entries), false
+}
+
+// removeNode takes a node and a locator, and recursively deletes
+// the located entry from the node, while maintaining the order
+// invariants. The removed entry is returned, or nil if there was
+// no such entry.
+//
+// The deletion happens in a single pass down the tree. Before
+// descending into a child, it is ensured that the child contains
+// at least order entries, so that removing an entry from it can
+// never leave it with fewer than order-1 entries.
+//
+// The given node must be write latched, and must either be the
+// root or contain at least order entries. The latch is released
+// before removeNode returns.
+func (b *btree[K, V]) removeNode(node *node[K, V], locate locator[K, V]) (removed *entry[K, V]) {
+	idx, exists := locate(node.entries, node.isLeaf())
 
 	// If the key exists in a leaf node, we can simply remove
 	// it outright
 	if node.isLeaf() {
 		if exists {
-			b.size--
+			removed = node.entries[idx]
+			atomic.AddInt64(&b.size, -1)
 			node.entries = append(node.entries[:idx], node.entries[idx+1:]...)
-			return true
 		}
-		// We've reached the bottom and couldn't find the key
-		return false
+		// Otherwise we've reached the bottom and couldn't find the key
+		node.latch.Unlock()
+		return
 	}
 
 	// If the key exists in the node, but it is not a leaf
 	if exists {
-		child := node.children[idx]
-		// There are enough entries in left child to take one
-		if child.canSteal(b.order) {
-			stolen := child.entries[len(child.entries)-1]
-			node.entries[idx] = stolen
-			return b.removeNode(child, stolen.key)
+		removed = node.entries[idx]
+		left, right := node.children[idx], node.children[idx+1]
+
+		// There are enough entries in the left child to take one.
+		// This node stays latched until the entry has been replaced.
+		left.latch.Lock()
+		if left.canSteal(b.order) {
+			node.entries[idx] = b.removeNode(left, locateMax[K, V])
+			node.latch.Unlock()
+			return
 		}
 
-		// child = node.children[idx]
 		// There are enough entries in the right child to take one
-		// if child.canSteal(b.order) {
-		// TODO implement this
-		// }
+		right.latch.Lock()
+		if right.canSteal(b.order) {
+			left.latch.Unlock()
+			node.entries[idx] = b.removeNode(right, locateMin[K, V])
+			node.latch.Unlock()
+			return
+		}
 
 		// Both children don't have enough entries, so we need
-		// to merge the left and right children and take a key
-		// TODO
+		// to merge the left and right children, moving the key
+		// down into the merged node
+		node.merge(idx)
+		right.latch.Unlock()
+		b.descend(node, left, locate)
+		return
+	}
+
+	// The key is not in this node, so make sure that the child
+	// we descend into has enough entries to remove one
+	child := node.children[idx]
+	child.latch.Lock()
+	if !child.canSteal(b.order) {
+		child = node.fill(idx, b.order)
 	}
 
-	return b.removeNode(node.children[idx], k)
+	return b.descend(node, child, locate)
 }
 
-//
-func (b *btree) getAll(limit int) []*entry {
-	if b.size == 0 || limit == 0 {
-		return []*entry{}
+// descend continues the removal in the given child, and
+// releases the latch of the node. If the node is the root
+// and has no entries left after a merge, the child is
+// merged into the root, and the removal continues in the
+// root. This is the only case in which the height of the
+// tree decreases.
+func (b *btree[K, V]) descend(node, child *node[K, V], locate locator[K, V]) (removed *entry[K, V]) {
+	if node == b.root && len(node.entries) == 0 {
+		node.entries, node.children = child.entries, nil
+		node.adopt(child.children...)
+		child.latch.Unlock()
+		return b.removeNode(node, locate)
 	}
 
-	// TODO unimplemented
+	node.latch.Unlock()
+	return b.removeNode(child, locate)
+}
+
+// load builds the tree bottom up from the given entries,
+// which must be sorted in strictly ascending order. Any
+// entries that were in the tree before are discarded.
+func (b *btree[K, V]) load(entries []*entry[K, V]) {
+	b.size = int64(len(entries))
+	if len(entries) == 0 {
+		b.root = &node[K, V]{
+			entries:  []*entry[K, V]{},
+			children: []*node[K, V]{},
+		}
+		return
+	}
 
-	return nil
+	var children []*node[K, V]
+	for {
+		var nodes []*node[K, V]
+		nodes, entries = b.pack(entries, children)
+		if len(nodes) == 1 {
+			b.root = nodes[0]
+			return
+		}
+		children = nodes
+	}
 }
 
-//
-func (b *btree) getAbove(k key, limit int) []*entry {
-	// TODO unimplemented
-	return []*entry{}
+// pack distributes the given entries evenly over as few
+// nodes as possible, while making sure that every node
+// satisfies the order invariants. One entry between every
+// two nodes is left over as separator, to be packed into
+// the next level of the tree. If children is not nil, it
+// must contain exactly one child more than there are
+// entries, and the children are distributed accordingly.
+func (b *btree[K, V]) pack(entries []*entry[K, V], children []*node[K, V]) (nodes []*node[K, V], separators []*entry[K, V]) {
+	// every node but the last one takes up at most
+	// 2*order-1 entries plus one separator
+	count := (len(entries) + 2*b.order) / (2 * b.order)
+	perNode := len(entries) - (count - 1)
+
+	for i := 0; i < count; i++ {
+		size := perNode / count
+		if i < perNode%count {
+			size++
+		}
+
+		n := &node[K, V]{
+			entries:  append([]*entry[K, V]{}, entries[:size]...),
+			children: []*node[K, V]{},
+		}
+		entries = entries[size:]
+		if children != nil {
+			n.adopt(children[:size+1]...)
+			children = children[size+1:]
+		}
+		nodes = append(nodes, n)
+
+		if i < count-1 {
+			separators = append(separators, entries[0])
+			entries = entries[1:]
+		}
+	}
+	return
 }
 
-//
-func (b *btree) getBelow(k key, limit int) []*entry {
-	// TODO unimplemented
-	return []*entry{}
+// getAll returns all entries of the tree in ascending
+// order of their keys. If limit is not negative, at most
+// limit entries are returned.
+func (b *btree[K, V]) getAll(limit int) []*entry[K, V] {
+	c := b.Cursor()
+	c.First()
+	return c.collect(limit, func(K) bool { return true })
 }
 
-//
-func (b *btree) getBetween(low, high key, limit int) []*entry {
-	// TODO unimplemented
-	return []*entry{}
+// getAbove returns all entries with a key strictly greater
+// than k in ascending order of their keys. If limit is not
+// negative, at most limit entries are returned.
+func (b *btree[K, V]) getAbove(k K, limit int) []*entry[K, V] {
+	c := b.Cursor()
+	c.Seek(k)
+	if c.Valid() && !b.less(k, c.Key()) {
+		c.Next()
+	}
+	return c.collect(limit, func(K) bool { return true })
+}
+
+// getBelow returns all entries with a key strictly less
+// than k in ascending order of their keys. If limit is not
+// negative, at most limit entries are returned.
+func (b *btree[K, V]) getBelow(k K, limit int) []*entry[K, V] {
+	c := b.Cursor()
+	c.First()
+	return c.collect(limit, func(ck K) bool { return b.less(ck, k) })
+}
+
+// getBetween returns all entries with a key in the range
+// [low, high] in ascending order of their keys. If limit is
+// not negative, at most limit entries are returned.
+func (b *btree[K, V]) getBetween(low, high K, limit int) []*entry[K, V] {
+	c := b.Cursor()
+	c.Seek(low)
+	return c.collect(limit, func(ck K) bool { return !b.less(high, ck) })
+}
+
+// collect moves the cursor forward, collecting entries as
+// long as the given function returns true for their keys,
+// and until the limit is reached. A negative limit means
+// that there is no limit.
+func (c *Cursor[K, V]) collect(limit int, while func(K) bool) []*entry[K, V] {
+	entries := []*entry[K, V]{}
+	for ; c.Valid() && limit != len(entries) && while(c.Key()); c.Next() {
+		entries = append(entries, c.current)
+	}
+	return entries
 }
 
 // search takes a slice of entries and a key, and returns
@@ -224,7 +503,7 @@ func (b *btree) getBetween(low, high key, limit int) []*entry {
 // other entries' keys.
 // e.g.
 //       b.search([1, 2, 4], 3) => (2, false)
-func (b *btree) search(entries []*entry, k key) (index int, exists bool) {
+func (b *btree[K, V]) search(entries []*entry[K, V], k K) (index int, exists bool) {
 	var (
 		low  = 0
 		mid  = 0
@@ -236,11 +515,11 @@ func (b *btree) search(entries []*entry, k key) (index int, exists bool) {
 
 		entryKey := entries[mid].key
 		switch {
-		case k > entryKey:
+		case b.less(entryKey, k):
 			low = mid + 1
-		case k < entryKey:
+		case b.less(k, entryKey):
 			high = mid - 1
-		case k == entryKey:
+		default:
 			return mid, true
 		}
 	}
@@ -248,44 +527,185 @@ func (b *btree) search(entries []*entry, k key) (index int, exists bool) {
 	return low, false
 }
 
-func (n *node) isLeaf() bool {
+func (n *node[K, V]) isLeaf() bool {
 	return len(n.children) == 0
 }
 
 // isFull returns a bool indication whether the node
 // already contains the maximum number of entries
 // allowed for a given order
-func (n *node) isFull(order int) bool {
+func (n *node[K, V]) isFull(order int) bool {
 	return len(n.entries) >= ((order * 2) - 1)
 }
 
 // canSteal returns a bool indicating whether or not
 // the node contains enough entries to be able to take one
-func (n *node) canSteal(order int) bool {
-	return len(n.entries)-1 > order-1
+// without violating the minimum of order-1 entries
+func (n *node[K, V]) canSteal(order int) bool {
+	return len(n.entries)-1 >= order-1
 }
 
 // Splits a full node to have a single, median,
 // entry, and two child nodes containing the left
 // and right halves of the entries
-func (n *node) split() *node {
+func (n *node[K, V]) split() *node[K, V] {
 	if len(n.entries) == 0 {
 		return n
 	}
 
+	left, median, right := n.halves()
+	left.parent, right.parent = n, n
+
+	n.entries = []*entry[K, V]{median}
+	n.children = []*node[K, V]{left, right}
+
+	return n
+}
+
+// splitChild splits the full child at index i, moving the
+// median entry of the child up into this node. The child
+// keeps the left half of its entries, and a new node
+// containing the right half is inserted right of it.
+func (n *node[K, V]) splitChild(i int) {
+	child := n.children[i]
+	mid := len(child.entries) / 2
+	median := child.entries[mid]
+
+	right := &node[K, V]{
+		parent:   n,
+		entries:  append([]*entry[K, V]{}, child.entries[mid+1:]...),
+		children: []*node[K, V]{},
+	}
+	if !child.isLeaf() {
+		right.adopt(child.children[mid+1:]...)
+		child.children = child.children[:mid+1]
+	}
+	child.entries = child.entries[:mid]
+
+	n.entries = append(n.entries, nil)
+	copy(n.entries[i+1:], n.entries[i:])
+	n.entries[i] = median
+
+	n.children = append(n.children, nil)
+	copy(n.children[i+2:], n.children[i+1:])
+	n.children[i+1] = right
+}
+
+// halves returns two new nodes holding the entries and
+// children left and right of the median entry of this
+// node, as well as the median entry itself.
+func (n *node[K, V]) halves() (left *node[K, V], median *entry[K, V], right *node[K, V]) {
 	mid := len(n.entries) / 2
 
-	left := &node{
-		parent:  n,
-		entries: append([]*entry{}, n.entries[:mid]...),
+	left = &node[K, V]{
+		entries:  append([]*entry[K, V]{}, n.entries[:mid]...),
+		children: []*node[K, V]{},
 	}
-	right := &node{
-		parent:  n,
-		entries: append([]*entry{}, n.entries[mid:]...),
+	right = &node[K, V]{
+		entries:  append([]*entry[K, V]{}, n.entries[mid+1:]...),
+		children: []*node[K, V]{},
 	}
 
-	n.entries = []*entry{{n.entries[mid].key, nil}}
-	n.children = append(n.children, left, right)
+	if !n.isLeaf() {
+		left.adopt(n.children[:mid+1]...)
+		right.adopt(n.children[mid+1:]...)
+	}
 
-	return n
+	return left, n.entries[mid], right
+}
+
+// rotateLeft moves the entry at index i down into the
+// child left of it, and replaces it with the smallest
+// entry of the child right of it. If the right child is
+// not a leaf, its first child is moved over as well.
+func (n *node[K, V]) rotateLeft(i int) {
+	left, right := n.children[i], n.children[i+1]
+
+	left.entries = append(left.entries, n.entries[i])
+	n.entries[i] = right.entries[0]
+	right.entries = append([]*entry[K, V]{}, right.entries[1:]...)
+
+	if !right.isLeaf() {
+		left.adopt(right.children[0])
+		right.children = append([]*node[K, V]{}, right.children[1:]...)
+	}
+}
+
+// rotateRight moves the entry at index i down into the
+// child right of it, and replaces it with the largest
+// entry of the child left of it. If the left child is
+// not a leaf, its last child is moved over as well.
+func (n *node[K, V]) rotateRight(i int) {
+	left, right := n.children[i], n.children[i+1]
+
+	right.entries = append([]*entry[K, V]{n.entries[i]}, right.entries...)
+	n.entries[i] = left.entries[len(left.entries)-1]
+	left.entries = left.entries[:len(left.entries)-1]
+
+	if !left.isLeaf() {
+		last := left.children[len(left.children)-1]
+		left.children = left.children[:len(left.children)-1]
+		last.parent = right
+		right.children = append([]*node[K, V]{last}, right.children...)
+	}
+}
+
+// merge merges the child right of the entry at index i
+// into the child left of it, moving the entry down into
+// the merged node. The right child is removed.
+func (n *node[K, V]) merge(i int) {
+	left, right := n.children[i], n.children[i+1]
+
+	left.entries = append(left.entries, n.entries[i])
+	left.entries = append(left.entries, right.entries...)
+	left.adopt(right.children...)
+
+	n.entries = append(n.entries[:i], n.entries[i+1:]...)
+	n.children = append(n.children[:i+1], n.children[i+2:]...)
+}
+
+// fill makes sure that the child at index i, which must
+// contain only order-1 entries, contains at least order
+// entries, by either taking an entry from one of its
+// siblings or merging it with one of its siblings. The
+// node and the child must be write latched. The child
+// that contains the entries of the original child after
+// the operation is returned, and is still write latched.
+func (n *node[K, V]) fill(i, order int) *node[K, V] {
+	child := n.children[i]
+
+	if i > 0 {
+		left := n.children[i-1]
+		left.latch.Lock()
+		if left.canSteal(order) {
+			n.rotateRight(i - 1)
+			left.latch.Unlock()
+			return child
+		}
+		if i == len(n.entries) {
+			n.merge(i - 1)
+			child.latch.Unlock()
+			return left
+		}
+		left.latch.Unlock()
+	}
+
+	right := n.children[i+1]
+	right.latch.Lock()
+	if right.canSteal(order) {
+		n.rotateLeft(i)
+	} else {
+		n.merge(i)
+	}
+	right.latch.Unlock()
+	return child
+}
+
+// adopt appends the given nodes to the children of this
+// node, and sets this node as their parent.
+func (n *node[K, V]) adopt(children ...*node[K, V]) {
+	for _, child := range children {
+		child.parent = n
+	}
+	n.children = append(n.children, children...)
 }
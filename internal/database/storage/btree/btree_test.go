@@ -1,22 +1,37 @@
 package btree
 
 import (
+	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// The tests use int keys, and values of arbitrary types.
+type (
+	testBtree = btree[int, interface{}]
+	testNode  = node[int, interface{}]
+	testEntry = entry[int, interface{}]
+)
+
+func intLess(a, b int) bool { return a < b }
+
+func newTestBtree(order int) *testBtree {
+	return newBtree[int, interface{}](order, intLess)
+}
+
 func TestBTree(t *testing.T) {
 	t.Skip()
 	cases := []struct {
 		name   string
-		insert []entry
-		get    []entry
+		insert []testEntry
+		get    []testEntry
 	}{
 		{
 			name:   "set and get",
-			insert: []entry{{1, 1}},
-			get:    []entry{{1, 1}},
+			insert: []testEntry{{1, 1}},
+			get:    []testEntry{{1, 1}},
 		},
 	}
 
@@ -24,7 +39,7 @@ func TestBTree(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			bt := newBtreeOrder(order)
+			bt := newTestBtree(order)
 
 			for _, e := range tc.insert {
 				bt.insert(e.key, e.value)
@@ -33,7 +48,7 @@ func TestBTree(t *testing.T) {
 			for _, g := range tc.get {
 				assert.Equal(t,
 					g.value,
-					func() *entry {
+					func() *testEntry {
 						e, _ := bt.get(g.key)
 						return e
 					}(),
@@ -46,8 +61,8 @@ func TestBTree(t *testing.T) {
 func TestGet(t *testing.T) {
 	cases := []struct {
 		name           string
-		root           *node
-		key            key
+		root           *testNode
+		key            int
 		expectedExists bool
 	}{
 		{
@@ -57,22 +72,22 @@ func TestGet(t *testing.T) {
 		},
 		{
 			name:           "empty root",
-			root:           &node{},
+			root:           &testNode{},
 			expectedExists: false,
 		},
 		{
 			name:           "entries only in root",
-			root:           &node{entries: []*entry{{1, 1}, {2, 2}, {3, 3}}},
+			root:           &testNode{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}}},
 			key:            2,
 			expectedExists: true,
 		},
 		{
 			name: "entry one level deep left of root",
-			root: &node{
-				entries: []*entry{{2, nil}},
-				children: []*node{
-					{entries: []*entry{{1, 1}}},
-					{entries: []*entry{{2, 2}, {3, 3}}},
+			root: &testNode{
+				entries: []*testEntry{{2, nil}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{2, 2}, {3, 3}}},
 				},
 			},
 			key:            1,
@@ -80,11 +95,11 @@ func TestGet(t *testing.T) {
 		},
 		{
 			name: "entry one level deep right of root",
-			root: &node{
-				entries: []*entry{{2, nil}},
-				children: []*node{
-					{entries: []*entry{{1, 1}}},
-					{entries: []*entry{{2, 2}, {3, 3}}},
+			root: &testNode{
+				entries: []*testEntry{{2, nil}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{2, 2}, {3, 3}}},
 				},
 			},
 			key:            3,
@@ -92,11 +107,11 @@ func TestGet(t *testing.T) {
 		},
 		{
 			name: "depth > 1 and key not exist",
-			root: &node{
-				entries: []*entry{{2, 2}},
-				children: []*node{
-					{entries: []*entry{{1, 1}}},
-					{entries: []*entry{{2, 2}, {3, 3}}},
+			root: &testNode{
+				entries: []*testEntry{{2, 2}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{2, 2}, {3, 3}}},
 				},
 			},
 			key:            4,
@@ -104,15 +119,15 @@ func TestGet(t *testing.T) {
 		},
 		{
 			name: "depth = 3 found",
-			root: &node{
-				entries: []*entry{{2, nil}},
-				children: []*node{
-					{entries: []*entry{{1, 1}}},
+			root: &testNode{
+				entries: []*testEntry{{2, nil}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
 					{
-						entries: []*entry{{3, nil}},
-						children: []*node{
-							{entries: []*entry{{2, 2}}},
-							{entries: []*entry{{3, 3}, {4, 4}}},
+						entries: []*testEntry{{3, nil}},
+						children: []*testNode{
+							{entries: []*testEntry{{2, 2}}},
+							{entries: []*testEntry{{3, 3}, {4, 4}}},
 						},
 					},
 				},
@@ -122,15 +137,15 @@ func TestGet(t *testing.T) {
 		},
 		{
 			name: "depth = 3 not found",
-			root: &node{
-				entries: []*entry{{2, nil}},
-				children: []*node{
-					{entries: []*entry{{1, 1}}},
+			root: &testNode{
+				entries: []*testEntry{{2, nil}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
 					{
-						entries: []*entry{{3, nil}},
-						children: []*node{
-							{entries: []*entry{{2, 2}}},
-							{entries: []*entry{{3, 3}, {4, 4}}},
+						entries: []*testEntry{{3, nil}},
+						children: []*testNode{
+							{entries: []*testEntry{{2, 2}}},
+							{entries: []*testEntry{{3, 3}, {4, 4}}},
 						},
 					},
 				},
@@ -142,7 +157,7 @@ func TestGet(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			btree := newBtree()
+			btree := newTestBtree(defaultOrder)
 			btree.root = tc.root
 
 			_, exists := btree.get(tc.key)
@@ -154,42 +169,42 @@ func TestGet(t *testing.T) {
 func TestKeySearch(t *testing.T) {
 	cases := []struct {
 		name    string
-		entries []*entry
-		key     key
+		entries []*testEntry
+		key     int
 		exists  bool
 		index   int
 	}{
 		{
 			name:    "single value",
-			entries: []*entry{{key: 1}},
+			entries: []*testEntry{{key: 1}},
 			key:     2,
 			exists:  false,
 			index:   1,
 		},
 		{
 			name:    "single value, already exists",
-			entries: []*entry{{key: 1}},
+			entries: []*testEntry{{key: 1}},
 			key:     1,
 			exists:  true,
 			index:   0,
 		},
 		{
 			name:    "already exists",
-			entries: []*entry{{key: 1}, {key: 2}, {key: 4}, {key: 5}},
+			entries: []*testEntry{{key: 1}, {key: 2}, {key: 4}, {key: 5}},
 			key:     4,
 			exists:  true,
 			index:   2,
 		},
 		{
 			name:    "doc example",
-			entries: []*entry{{key: 1}, {key: 2}, {key: 4}},
+			entries: []*testEntry{{key: 1}, {key: 2}, {key: 4}},
 			key:     3,
 			exists:  false,
 			index:   2,
 		},
 		{
 			name:    "no entries",
-			entries: []*entry{},
+			entries: []*testEntry{},
 			key:     2,
 			exists:  false,
 			index:   0,
@@ -198,7 +213,7 @@ func TestKeySearch(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			bt := newBtree()
+			bt := newTestBtree(defaultOrder)
 
 			idx, exists := bt.search(tc.entries, tc.key)
 
@@ -209,66 +224,99 @@ func TestKeySearch(t *testing.T) {
 }
 
 func TestNodeSplit(t *testing.T) {
-	parent := &node{}
+	parent := &testNode{}
 
 	cases := []struct {
 		name     string
 		root     bool
-		input    *node
-		expected *node
+		input    *testNode
+		expected *testNode
 	}{
 		{
 			name:  "simple node",
-			input: &node{parent: parent, entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}},
-			expected: &node{
+			input: &testNode{parent: parent, entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}},
+			expected: &testNode{
 				parent:  parent,
-				entries: []*entry{{3, nil}},
-				children: []*node{
-					{entries: []*entry{{1, 1}, {2, 2}}},
-					{entries: []*entry{{3, 3}, {4, 4}, {5, 5}}},
+				entries: []*testEntry{{3, 3}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}, {2, 2}}},
+					{entries: []*testEntry{{4, 4}, {5, 5}}},
 				},
 			},
 		},
 		{
 			name:  "even entries node",
-			input: &node{parent: parent, entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}}},
-			expected: &node{
+			input: &testNode{parent: parent, entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}}},
+			expected: &testNode{
 				parent:  parent,
-				entries: []*entry{{3, nil}},
-				children: []*node{
-					{entries: []*entry{{1, 1}, {2, 2}}},
-					{entries: []*entry{{3, 3}, {4, 4}}},
+				entries: []*testEntry{{3, 3}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}, {2, 2}}},
+					{entries: []*testEntry{{4, 4}}},
 				},
 			},
 		},
 		{
 			name:  "no parent",
-			input: &node{entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}},
+			input: &testNode{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}},
 			root:  true,
-			expected: &node{
-				entries: []*entry{{3, nil}},
-				children: []*node{
-					{entries: []*entry{{1, 1}, {2, 2}}},
-					{entries: []*entry{{3, 3}, {4, 4}, {5, 5}}},
+			expected: &testNode{
+				entries: []*testEntry{{3, 3}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}, {2, 2}}},
+					{entries: []*testEntry{{4, 4}, {5, 5}}},
+				},
+			},
+		},
+		{
+			name: "node with children",
+			input: &testNode{
+				parent:  parent,
+				entries: []*testEntry{{2, 2}, {4, 4}, {6, 6}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{3, 3}}},
+					{entries: []*testEntry{{5, 5}}},
+					{entries: []*testEntry{{7, 7}}},
+				},
+			},
+			expected: &testNode{
+				parent:  parent,
+				entries: []*testEntry{{4, 4}},
+				children: []*testNode{
+					{
+						entries: []*testEntry{{2, 2}},
+						children: []*testNode{
+							{entries: []*testEntry{{1, 1}}},
+							{entries: []*testEntry{{3, 3}}},
+						},
+					},
+					{
+						entries: []*testEntry{{6, 6}},
+						children: []*testNode{
+							{entries: []*testEntry{{5, 5}}},
+							{entries: []*testEntry{{7, 7}}},
+						},
+					},
 				},
 			},
 		},
 		{
 			name:  "empty node",
-			input: &node{parent: parent, entries: []*entry{}},
-			expected: &node{
+			input: &testNode{parent: parent, entries: []*testEntry{}},
+			expected: &testNode{
 				parent:   parent,
-				entries:  []*entry{},
-				children: []*node{},
+				entries:  []*testEntry{},
+				children: []*testNode{},
 			},
 		},
 		{
 			name:  "single entry",
-			input: &node{parent: parent, entries: []*entry{{1, 1}}},
-			expected: &node{
+			input: &testNode{parent: parent, entries: []*testEntry{{1, 1}}},
+			expected: &testNode{
 				parent:   parent,
-				entries:  []*entry{{1, nil}},
-				children: []*node{},
+				entries:  []*testEntry{{1, 1}},
+				children: []*testNode{},
 			},
 		},
 	}
@@ -284,7 +332,11 @@ func TestNodeSplit(t *testing.T) {
 
 				assert.Equal(t, &tc.input, &newChild.parent)
 				assert.Equal(t, expectedChild.entries, newChild.entries)
-				assert.Equal(t, expectedChild.children, newChild.children)
+				assert.Equal(t, len(expectedChild.children), len(newChild.children))
+				for j := range expectedChild.children {
+					assert.Equal(t, expectedChild.children[j].entries, newChild.children[j].entries)
+					assert.Same(t, newChild, newChild.children[j].parent)
+				}
 			}
 		})
 	}
@@ -292,12 +344,12 @@ func TestNodeSplit(t *testing.T) {
 
 func TestInsertNode(t *testing.T) {
 	type fields struct {
-		root *node
+		root *testNode
 		size int
 	}
 	type args struct {
-		node  *node
-		entry *entry
+		node  *testNode
+		entry *testEntry
 	}
 
 	tests := []struct {
@@ -310,14 +362,14 @@ func TestInsertNode(t *testing.T) {
 		{
 			name:         "insert single entry",
 			fields:       fields{},
-			args:         args{&node{}, &entry{1, 1}},
+			args:         args{&testNode{}, &testEntry{1, 1}},
 			wantSize:     1,
 			wantInserted: true,
 		},
 		{
 			name:         "entry already exists",
 			fields:       fields{size: 1},
-			args:         args{&node{entries: []*entry{{1, 1}}}, &entry{1, 1}},
+			args:         args{&testNode{entries: []*testEntry{{1, 1}}}, &testEntry{1, 1}},
 			wantSize:     1,
 			wantInserted: false,
 		},
@@ -325,14 +377,14 @@ func TestInsertNode(t *testing.T) {
 			name:   "entry exists one level down right",
 			fields: fields{size: 2},
 			args: args{
-				&node{
-					entries: []*entry{{2, nil}},
-					children: []*node{
-						{entries: []*entry{{1, 1}}},
-						{entries: []*entry{{2, 2}}},
+				&testNode{
+					entries: []*testEntry{{2, nil}},
+					children: []*testNode{
+						{entries: []*testEntry{{1, 1}}},
+						{entries: []*testEntry{{2, 2}}},
 					},
 				},
-				&entry{2, 2},
+				&testEntry{2, 2},
 			},
 			wantSize:     2,
 			wantInserted: false,
@@ -341,14 +393,14 @@ func TestInsertNode(t *testing.T) {
 			name:   "entry exists one level down right unbalanced",
 			fields: fields{size: 2},
 			args: args{
-				&node{
-					entries: []*entry{{1, nil}},
-					children: []*node{
+				&testNode{
+					entries: []*testEntry{{1, nil}},
+					children: []*testNode{
 						{},
-						{entries: []*entry{{1, 1}, {2, 2}}},
+						{entries: []*testEntry{{1, 1}, {2, 2}}},
 					},
 				},
-				&entry{2, 2},
+				&testEntry{2, 2},
 			},
 			wantSize:     2,
 			wantInserted: false,
@@ -357,14 +409,14 @@ func TestInsertNode(t *testing.T) {
 			name:   "entry exists one level down left",
 			fields: fields{size: 2},
 			args: args{
-				&node{
-					entries: []*entry{{2, nil}},
-					children: []*node{
-						{entries: []*entry{{1, 1}}},
-						{entries: []*entry{{2, 2}}},
+				&testNode{
+					entries: []*testEntry{{2, nil}},
+					children: []*testNode{
+						{entries: []*testEntry{{1, 1}}},
+						{entries: []*testEntry{{2, 2}}},
 					},
 				},
-				&entry{1, 1},
+				&testEntry{1, 1},
 			},
 			wantSize:     2,
 			wantInserted: false,
@@ -373,14 +425,14 @@ func TestInsertNode(t *testing.T) {
 			name:   "entry inserted one level down right",
 			fields: fields{size: 2},
 			args: args{
-				&node{
-					entries: []*entry{{3, nil}},
-					children: []*node{
-						{entries: []*entry{{1, 1}}},
-						{entries: []*entry{{3, 3}}},
+				&testNode{
+					entries: []*testEntry{{3, nil}},
+					children: []*testNode{
+						{entries: []*testEntry{{1, 1}}},
+						{entries: []*testEntry{{3, 3}}},
 					},
 				},
-				&entry{4, 4},
+				&testEntry{4, 4},
 			},
 			wantSize:     3,
 			wantInserted: true,
@@ -389,14 +441,14 @@ func TestInsertNode(t *testing.T) {
 			name:   "entry inserted one level down left, would overflow",
 			fields: fields{size: 6},
 			args: args{
-				&node{
-					entries: []*entry{{10, nil}},
-					children: []*node{
-						{entries: []*entry{{3, 3}, {4, 4}, {5, 5}, {6, 6}, {7, 7}}},
-						{entries: []*entry{{10, 10}}},
+				&testNode{
+					entries: []*testEntry{{10, nil}},
+					children: []*testNode{
+						{entries: []*testEntry{{3, 3}, {4, 4}, {5, 5}, {6, 6}, {7, 7}}},
+						{entries: []*testEntry{{10, 10}}},
 					},
 				},
-				&entry{1, 1},
+				&testEntry{1, 1},
 			},
 			wantSize:     7,
 			wantInserted: true,
@@ -405,14 +457,14 @@ func TestInsertNode(t *testing.T) {
 			name:   "entry inserted one level down right, would more than overflow",
 			fields: fields{size: 4},
 			args: args{
-				&node{
-					entries: []*entry{{10, nil}},
-					children: []*node{
-						{entries: []*entry{{3, 3}, {4, 4}, {5, 5}}},
-						{entries: []*entry{{10, 10}, {11, 11}, {12, 12}, {13, 13}, {14, 14}, {15, 15}, {16, 16}, {17, 17}, {18, 18}, {19, 19}, {29, 29}}},
+				&testNode{
+					entries: []*testEntry{{10, nil}},
+					children: []*testNode{
+						{entries: []*testEntry{{3, 3}, {4, 4}, {5, 5}}},
+						{entries: []*testEntry{{10, 10}, {11, 11}, {12, 12}, {13, 13}, {14, 14}, {15, 15}, {16, 16}, {17, 17}, {18, 18}, {19, 19}, {29, 29}}},
 					},
 				},
-				&entry{30, 30},
+				&testEntry{30, 30},
 			},
 			wantSize:     5,
 			wantInserted: true,
@@ -421,14 +473,14 @@ func TestInsertNode(t *testing.T) {
 			name:   "entry inserted one level down right, would more than overflow",
 			fields: fields{size: 4},
 			args: args{
-				&node{
-					entries: []*entry{{10, nil}},
-					children: []*node{
-						{entries: []*entry{{3, 3}, {4, 4}, {5, 5}}},
-						{entries: []*entry{{10, 10}, {11, 11}, {12, 12}, {13, 13}, {14, 14}, {15, 15}, {16, 16}, {17, 17}, {18, 18}, {19, 19}, {29, 29}}},
+				&testNode{
+					entries: []*testEntry{{10, nil}},
+					children: []*testNode{
+						{entries: []*testEntry{{3, 3}, {4, 4}, {5, 5}}},
+						{entries: []*testEntry{{10, 10}, {11, 11}, {12, 12}, {13, 13}, {14, 14}, {15, 15}, {16, 16}, {17, 17}, {18, 18}, {19, 19}, {29, 29}}},
 					},
 				},
-				&entry{30, 30},
+				&testEntry{30, 30},
 			},
 			wantSize:     5,
 			wantInserted: true,
@@ -437,27 +489,29 @@ func TestInsertNode(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := &btree{
+			b := &testBtree{
+				less:  intLess,
 				root:  tt.fields.root,
-				size:  tt.fields.size,
+				size:  int64(tt.fields.size),
 				order: 3,
 			}
 
+			tt.args.node.latch.Lock()
 			got := b.insertNode(tt.args.node, tt.args.entry)
 			assert.Equal(t, tt.wantInserted, got)
-			assert.Equal(t, tt.wantSize, b.size)
+			assert.Equal(t, tt.wantSize, b.Len())
 		})
 	}
 }
 
 func TestRemove(t *testing.T) {
 	type fields struct {
-		root  *node
+		root  *testNode
 		size  int
 		order int
 	}
 	type args struct {
-		k key
+		k int
 	}
 
 	tests := []struct {
@@ -476,7 +530,7 @@ func TestRemove(t *testing.T) {
 		},
 		{
 			name:        "remove entry from root",
-			fields:      fields{root: &node{entries: []*entry{{1, 1}}}, size: 1, order: 3},
+			fields:      fields{root: &testNode{entries: []*testEntry{{1, 1}}}, size: 1, order: 3},
 			args:        args{k: 1},
 			wantRemoved: true,
 			wantSize:    0,
@@ -486,11 +540,11 @@ func TestRemove(t *testing.T) {
 			fields: fields{
 				size:  8,
 				order: 3,
-				root: &node{
-					entries: []*entry{{5, 5}},
-					children: []*node{
-						{entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}}},
-						{entries: []*entry{{6, 6}, {7, 7}, {8, 8}}},
+				root: &testNode{
+					entries: []*testEntry{{5, 5}},
+					children: []*testNode{
+						{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}}},
+						{entries: []*testEntry{{6, 6}, {7, 7}, {8, 8}}},
 					},
 				},
 			},
@@ -503,11 +557,11 @@ func TestRemove(t *testing.T) {
 			fields: fields{
 				size:  3,
 				order: 2,
-				root: &node{
-					entries: []*entry{{1, 1}},
-					children: []*node{
+				root: &testNode{
+					entries: []*testEntry{{1, 1}},
+					children: []*testNode{
 						{},
-						{entries: []*entry{{2, 2}, {3, 3}}},
+						{entries: []*testEntry{{2, 2}, {3, 3}}},
 					},
 				},
 			},
@@ -520,10 +574,10 @@ func TestRemove(t *testing.T) {
 			fields: fields{
 				size:  3,
 				order: 2,
-				root: &node{
-					entries: []*entry{{3, 3}},
-					children: []*node{
-						{entries: []*entry{{1, 1}, {2, 2}}},
+				root: &testNode{
+					entries: []*testEntry{{3, 3}},
+					children: []*testNode{
+						{entries: []*testEntry{{1, 1}, {2, 2}}},
 						{},
 					},
 				},
@@ -537,10 +591,10 @@ func TestRemove(t *testing.T) {
 			fields: fields{
 				size:  2,
 				order: 2,
-				root: &node{
-					entries: []*entry{{2, 2}},
-					children: []*node{
-						{entries: []*entry{{1, 1}}},
+				root: &testNode{
+					entries: []*testEntry{{2, 2}},
+					children: []*testNode{
+						{entries: []*testEntry{{1, 1}}},
 						{},
 					},
 				},
@@ -553,24 +607,25 @@ func TestRemove(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := &btree{
+			b := &testBtree{
+				less:  intLess,
 				root:  tt.fields.root,
-				size:  tt.fields.size,
+				size:  int64(tt.fields.size),
 				order: tt.fields.order,
 			}
 
 			gotRemoved := b.remove(tt.args.k)
 			assert.Equal(t, tt.wantRemoved, gotRemoved)
-			assert.Equal(t, tt.wantSize, b.size)
+			assert.Equal(t, tt.wantSize, b.Len())
 		})
 	}
 }
 
 func TestNode_isFull(t *testing.T) {
 	type fields struct {
-		parent   *node
-		entries  []*entry
-		children []*node
+		parent   *testNode
+		entries  []*testEntry
+		children []*testNode
 	}
 	type args struct {
 		order int
@@ -584,43 +639,43 @@ func TestNode_isFull(t *testing.T) {
 	}{
 		{
 			name:   "order 3, node not full",
-			fields: fields{entries: []*entry{}},
+			fields: fields{entries: []*testEntry{}},
 			args:   args{order: 3},
 			want:   false,
 		},
 		{
 			name:   "order 3, node full",
-			fields: fields{entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}},
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}},
 			args:   args{order: 3},
 			want:   true,
 		},
 		{
 			name:   "order 3, node nearly full",
-			fields: fields{entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}}},
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}}},
 			args:   args{order: 3},
 			want:   false,
 		},
 		{
 			name:   "order 3, node over filled (bug case)",
-			fields: fields{entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}, {6, 6}}},
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}, {6, 6}}},
 			args:   args{order: 3},
 			want:   true,
 		},
 		{
 			name:   "order 5, node full",
-			fields: fields{entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}, {6, 6}, {7, 7}, {8, 8}, {9, 9}}},
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}, {6, 6}, {7, 7}, {8, 8}, {9, 9}}},
 			args:   args{order: 5},
 			want:   true,
 		},
 		{
 			name:   "order 5, node almost full",
-			fields: fields{entries: []*entry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}, {6, 6}, {7, 7}, {8, 8}}},
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}, {6, 6}, {7, 7}, {8, 8}}},
 			args:   args{order: 5},
 			want:   false,
 		},
 		{
 			name:   "order 5, node empty",
-			fields: fields{entries: []*entry{}},
+			fields: fields{entries: []*testEntry{}},
 			args:   args{order: 5},
 			want:   false,
 		},
@@ -628,7 +683,7 @@ func TestNode_isFull(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			n := &node{
+			n := &testNode{
 				parent:   tt.fields.parent,
 				entries:  tt.fields.entries,
 				children: tt.fields.children,
@@ -642,9 +697,9 @@ func TestNode_isFull(t *testing.T) {
 
 func TestNode_canSteal(t *testing.T) {
 	type fields struct {
-		parent   *node
-		entries  []*entry
-		children []*node
+		parent   *testNode
+		entries  []*testEntry
+		children []*testNode
 	}
 	type args struct {
 		order int
@@ -656,12 +711,47 @@ func TestNode_canSteal(t *testing.T) {
 		args   args
 		want   bool
 	}{
-		// TODO add test cases
+		{
+			name:   "order 3, empty node",
+			fields: fields{entries: []*testEntry{}},
+			args:   args{order: 3},
+			want:   false,
+		},
+		{
+			name:   "order 3, node at minimum",
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}}},
+			args:   args{order: 3},
+			want:   false,
+		},
+		{
+			name:   "order 3, node one above minimum",
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}}},
+			args:   args{order: 3},
+			want:   true,
+		},
+		{
+			name:   "order 3, node full",
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}}},
+			args:   args{order: 3},
+			want:   true,
+		},
+		{
+			name:   "order 2, node at minimum",
+			fields: fields{entries: []*testEntry{{1, 1}}},
+			args:   args{order: 2},
+			want:   false,
+		},
+		{
+			name:   "order 2, node one above minimum",
+			fields: fields{entries: []*testEntry{{1, 1}, {2, 2}}},
+			args:   args{order: 2},
+			want:   true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			n := &node{
+			n := &testNode{
 				parent:   tt.fields.parent,
 				entries:  tt.fields.entries,
 				children: tt.fields.children,
@@ -675,7 +765,7 @@ func TestNode_canSteal(t *testing.T) {
 
 func Test_btree_getAll(t *testing.T) {
 	type fields struct {
-		root  *node
+		root  *testNode
 		size  int
 		order int
 	}
@@ -683,20 +773,20 @@ func Test_btree_getAll(t *testing.T) {
 		limit int
 	}
 
-	root := &node{}
-	root.entries = []*entry{{4, 4}, {8, 8}}
-	root.children = []*node{
+	root := &testNode{}
+	root.entries = []*testEntry{{4, 4}, {8, 8}}
+	root.children = []*testNode{
 		{
 			parent:  root,
-			entries: []*entry{{0, 0}, {1, 1}, {2, 2}},
+			entries: []*testEntry{{0, 0}, {1, 1}, {2, 2}},
 		},
 		{
 			parent:  root,
-			entries: []*entry{{5, 5}, {7, 7}},
+			entries: []*testEntry{{5, 5}, {7, 7}},
 		},
 		{
 			parent:  root,
-			entries: []*entry{{9, 9}, {11, 11}, {12, 12}},
+			entries: []*testEntry{{9, 9}, {11, 11}, {12, 12}},
 		},
 	}
 
@@ -706,34 +796,46 @@ func Test_btree_getAll(t *testing.T) {
 		name   string
 		fields fields
 		args   args
-		want   []*entry
+		want   []*testEntry
 	}{
 		{
 			name:   "empty tree returns empty entries",
 			fields: fields{size: 0},
 			args:   args{limit: 10},
-			want:   []*entry{},
+			want:   []*testEntry{},
 		},
 		{
 			name:   "limit of 0 returns empty entries",
 			fields: f,
 			args:   args{limit: 0},
-			want:   []*entry{},
+			want:   []*testEntry{},
 		},
 		{
 			name:   "returns all entries up to limit",
 			fields: f,
-			args:   args{limit: 0},
-			want:   []*entry{},
+			args:   args{limit: 4},
+			want:   []*testEntry{{0, 0}, {1, 1}, {2, 2}, {4, 4}},
+		},
+		{
+			name:   "limit larger than size returns all entries",
+			fields: f,
+			args:   args{limit: 20},
+			want:   []*testEntry{{0, 0}, {1, 1}, {2, 2}, {4, 4}, {5, 5}, {7, 7}, {8, 8}, {9, 9}, {11, 11}, {12, 12}},
+		},
+		{
+			name:   "negative limit returns all entries",
+			fields: f,
+			args:   args{limit: -1},
+			want:   []*testEntry{{0, 0}, {1, 1}, {2, 2}, {4, 4}, {5, 5}, {7, 7}, {8, 8}, {9, 9}, {11, 11}, {12, 12}},
 		},
-		// TODO add more cases
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			b := &btree{
+			b := &testBtree{
+				less:  intLess,
 				root:  tt.fields.root,
-				size:  tt.fields.size,
+				size:  int64(tt.fields.size),
 				order: tt.fields.order,
 			}
 
@@ -742,3 +844,528 @@ func Test_btree_getAll(t *testing.T) {
 		})
 	}
 }
+
+func Test_btree_getAbove(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     int
+		limit int
+		want  []*testEntry
+	}{
+		{"key in leaf", 9, -1, []*testEntry{{11, 11}, {12, 12}}},
+		{"key in root", 4, 3, []*testEntry{{5, 5}, {7, 7}, {8, 8}}},
+		{"key not in tree", 3, 2, []*testEntry{{4, 4}, {5, 5}}},
+		{"key below all keys", -5, 1, []*testEntry{{0, 0}}},
+		{"key above all keys", 13, -1, []*testEntry{}},
+		{"largest key", 12, -1, []*testEntry{}},
+		{"limit of 0", 4, 0, []*testEntry{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newRangeTestTree()
+			assert.Equal(t, tt.want, b.getAbove(tt.k, tt.limit))
+		})
+	}
+}
+
+func Test_btree_getBelow(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     int
+		limit int
+		want  []*testEntry
+	}{
+		{"key in leaf", 2, -1, []*testEntry{{0, 0}, {1, 1}}},
+		{"key in root", 8, -1, []*testEntry{{0, 0}, {1, 1}, {2, 2}, {4, 4}, {5, 5}, {7, 7}}},
+		{"key not in tree", 6, 3, []*testEntry{{0, 0}, {1, 1}, {2, 2}}},
+		{"key below all keys", -5, -1, []*testEntry{}},
+		{"smallest key", 0, -1, []*testEntry{}},
+		{"limit of 0", 8, 0, []*testEntry{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newRangeTestTree()
+			assert.Equal(t, tt.want, b.getBelow(tt.k, tt.limit))
+		})
+	}
+}
+
+func Test_btree_getBetween(t *testing.T) {
+	tests := []struct {
+		name      string
+		low, high int
+		limit     int
+		want      []*testEntry
+	}{
+		{"bounds in tree", 2, 8, -1, []*testEntry{{2, 2}, {4, 4}, {5, 5}, {7, 7}, {8, 8}}},
+		{"bounds not in tree", 3, 10, -1, []*testEntry{{4, 4}, {5, 5}, {7, 7}, {8, 8}, {9, 9}}},
+		{"with limit", 3, 10, 2, []*testEntry{{4, 4}, {5, 5}}},
+		{"single key", 7, 7, -1, []*testEntry{{7, 7}}},
+		{"empty range", 10, 10, -1, []*testEntry{}},
+		{"inverted range", 8, 2, -1, []*testEntry{}},
+		{"limit of 0", 2, 8, 0, []*testEntry{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newRangeTestTree()
+			assert.Equal(t, tt.want, b.getBetween(tt.low, tt.high, tt.limit))
+		})
+	}
+}
+
+// newRangeTestTree creates a tree of order 3 with the keys
+// 0, 1, 2, 4, 5, 7, 8, 9, 11 and 12.
+func newRangeTestTree() *testBtree {
+	root := &testNode{}
+	root.entries = []*testEntry{{4, 4}, {8, 8}}
+	root.children = []*testNode{
+		{entries: []*testEntry{{0, 0}, {1, 1}, {2, 2}}},
+		{entries: []*testEntry{{5, 5}, {7, 7}}},
+		{entries: []*testEntry{{9, 9}, {11, 11}, {12, 12}}},
+	}
+	linkParents(root)
+
+	return &testBtree{
+		root:  root,
+		size:  10,
+		order: 3,
+		less:  intLess,
+	}
+}
+
+func TestRemove_Rebalance(t *testing.T) {
+	tests := []struct {
+		name     string
+		root     *testNode
+		k        int
+		wantRoot *testNode
+	}{
+		{
+			name: "internal key, take from right child",
+			root: &testNode{
+				entries: []*testEntry{{3, 3}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{4, 4}, {5, 5}}},
+				},
+			},
+			k: 3,
+			wantRoot: &testNode{
+				entries: []*testEntry{{4, 4}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{5, 5}}},
+				},
+			},
+		},
+		{
+			name: "internal key, merge children and shrink root",
+			root: &testNode{
+				entries: []*testEntry{{3, 3}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{4, 4}}},
+				},
+			},
+			k:        3,
+			wantRoot: &testNode{entries: []*testEntry{{1, 1}, {4, 4}}},
+		},
+		{
+			name: "borrow from left sibling",
+			root: &testNode{
+				entries: []*testEntry{{3, 3}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}, {2, 2}}},
+					{entries: []*testEntry{{4, 4}}},
+				},
+			},
+			k: 4,
+			wantRoot: &testNode{
+				entries: []*testEntry{{2, 2}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{3, 3}}},
+				},
+			},
+		},
+		{
+			name: "borrow from right sibling",
+			root: &testNode{
+				entries: []*testEntry{{2, 2}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{3, 3}, {4, 4}}},
+				},
+			},
+			k: 1,
+			wantRoot: &testNode{
+				entries: []*testEntry{{3, 3}},
+				children: []*testNode{
+					{entries: []*testEntry{{2, 2}}},
+					{entries: []*testEntry{{4, 4}}},
+				},
+			},
+		},
+		{
+			name: "merge with right sibling",
+			root: &testNode{
+				entries: []*testEntry{{2, 2}, {4, 4}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{3, 3}}},
+					{entries: []*testEntry{{5, 5}}},
+				},
+			},
+			k: 1,
+			wantRoot: &testNode{
+				entries: []*testEntry{{4, 4}},
+				children: []*testNode{
+					{entries: []*testEntry{{2, 2}, {3, 3}}},
+					{entries: []*testEntry{{5, 5}}},
+				},
+			},
+		},
+		{
+			name: "merge with left sibling",
+			root: &testNode{
+				entries: []*testEntry{{2, 2}, {4, 4}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{3, 3}}},
+					{entries: []*testEntry{{5, 5}}},
+				},
+			},
+			k: 5,
+			wantRoot: &testNode{
+				entries: []*testEntry{{2, 2}},
+				children: []*testNode{
+					{entries: []*testEntry{{1, 1}}},
+					{entries: []*testEntry{{3, 3}, {4, 4}}},
+				},
+			},
+		},
+		{
+			name: "borrow from left sibling moves child",
+			root: &testNode{
+				entries: []*testEntry{{6, 6}},
+				children: []*testNode{
+					{
+						entries: []*testEntry{{2, 2}, {4, 4}},
+						children: []*testNode{
+							{entries: []*testEntry{{1, 1}}},
+							{entries: []*testEntry{{3, 3}}},
+							{entries: []*testEntry{{5, 5}}},
+						},
+					},
+					{
+						entries: []*testEntry{{8, 8}},
+						children: []*testNode{
+							{entries: []*testEntry{{7, 7}}},
+							{entries: []*testEntry{{9, 9}}},
+						},
+					},
+				},
+			},
+			k: 9,
+			wantRoot: &testNode{
+				entries: []*testEntry{{4, 4}},
+				children: []*testNode{
+					{
+						entries: []*testEntry{{2, 2}},
+						children: []*testNode{
+							{entries: []*testEntry{{1, 1}}},
+							{entries: []*testEntry{{3, 3}}},
+						},
+					},
+					{
+						entries: []*testEntry{{6, 6}},
+						children: []*testNode{
+							{entries: []*testEntry{{5, 5}}},
+							{entries: []*testEntry{{7, 7}, {8, 8}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkParents(tt.root)
+			b := &testBtree{
+				less:  intLess,
+				root:  tt.root,
+				size:  int64(countEntries(tt.root)),
+				order: 2,
+			}
+			size := b.Len()
+
+			assert.True(t, b.remove(tt.k))
+			assert.Equal(t, size-1, b.Len())
+			assertSameShape(t, tt.wantRoot, b.root)
+			assertInvariants(t, b)
+		})
+	}
+}
+
+func TestBtree_Invariants(t *testing.T) {
+	for _, order := range []int{2, 3, 5} {
+		t.Run(fmt.Sprintf("order %d", order), func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(int64(order)))
+			b := newTestBtree(order)
+			present := map[int]bool{}
+
+			for _, k := range rnd.Perm(500) {
+				b.insert(k, k)
+				present[k] = true
+				assertInvariants(t, b)
+			}
+			assert.Equal(t, 500, b.Len())
+
+			for _, k := range rnd.Perm(600) {
+				assert.Equal(t, present[k], b.remove(k))
+				delete(present, k)
+				assertInvariants(t, b)
+
+				_, exists := b.get(k)
+				assert.False(t, exists)
+			}
+			assert.Equal(t, 0, b.Len())
+		})
+	}
+}
+
+func TestBtree_InsertRemoveInterleaved(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	b := newTestBtree(3)
+	present := map[int]int{}
+
+	for i := 0; i < 5000; i++ {
+		k := rnd.Intn(300)
+		if rnd.Intn(2) == 0 {
+			b.insert(k, i)
+			present[k] = i
+		} else {
+			_, ok := present[k]
+			assert.Equal(t, ok, b.remove(k))
+			delete(present, k)
+		}
+	}
+	assertInvariants(t, b)
+	assert.Equal(t, len(present), b.Len())
+
+	for k, v := range present {
+		e, exists := b.get(k)
+		if assert.True(t, exists) {
+			assert.Equal(t, v, e.value)
+		}
+	}
+}
+
+// assertInvariants checks that the given tree satisfies the order
+// invariants documented on btree, that all leaves are on the same
+// depth, that the keys are ordered, and that the size is correct.
+func assertInvariants(t *testing.T, b *testBtree) {
+	t.Helper()
+
+	if b.root == nil {
+		assert.Equal(t, 0, b.Len())
+		return
+	}
+	assert.Nil(t, b.root.parent, "root must not have a parent")
+
+	leafDepth := -1
+	var check func(n *testNode, depth int, low, high *int)
+	check = func(n *testNode, depth int, low, high *int) {
+		if n != b.root {
+			assert.GreaterOrEqual(t, len(n.entries), b.order-1, "node has too few entries")
+		}
+		assert.LessOrEqual(t, len(n.entries), 2*b.order-1, "node has too many entries")
+
+		for i, e := range n.entries {
+			if i > 0 {
+				assert.Less(t, n.entries[i-1].key, e.key, "entries are not ordered")
+			}
+			if low != nil {
+				assert.Less(t, *low, e.key, "entry is not in the range of its parent")
+			}
+			if high != nil {
+				assert.Less(t, e.key, *high, "entry is not in the range of its parent")
+			}
+		}
+
+		if n.isLeaf() {
+			if leafDepth == -1 {
+				leafDepth = depth
+			}
+			assert.Equal(t, leafDepth, depth, "leaves are on different depths")
+			return
+		}
+
+		if !assert.Equal(t, len(n.entries)+1, len(n.children), "wrong amount of children") {
+			return
+		}
+		for i, child := range n.children {
+			assert.Same(t, n, child.parent, "wrong parent")
+
+			childLow, childHigh := low, high
+			if i > 0 {
+				childLow = &n.entries[i-1].key
+			}
+			if i < len(n.entries) {
+				childHigh = &n.entries[i].key
+			}
+			check(child, depth+1, childLow, childHigh)
+		}
+	}
+	check(b.root, 0, nil, nil)
+
+	assert.Equal(t, countEntries(b.root), b.Len())
+}
+
+// assertSameShape checks that the two given trees have the same
+// entries in the same structure.
+func assertSameShape(t *testing.T, expected, actual *testNode) {
+	t.Helper()
+
+	assert.Equal(t, expected.entries, actual.entries)
+	if assert.Equal(t, len(expected.children), len(actual.children)) {
+		for i := range expected.children {
+			assertSameShape(t, expected.children[i], actual.children[i])
+		}
+	}
+}
+
+func countEntries(n *testNode) int {
+	if n == nil {
+		return 0
+	}
+	count := len(n.entries)
+	for _, child := range n.children {
+		count += countEntries(child)
+	}
+	return count
+}
+
+func linkParents(n *testNode) {
+	for _, child := range n.children {
+		child.parent = n
+		linkParents(child)
+	}
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+
+	tree := New[string, int](2, func(a, b string) bool { return a < b })
+	assert.Equal(0, tree.Len())
+
+	for i, k := range []string{"delta", "alpha", "echo", "charlie", "bravo"} {
+		tree.Put(k, i)
+	}
+	assert.Equal(5, tree.Len())
+
+	v, ok := tree.Get("charlie")
+	assert.True(ok)
+	assert.Equal(3, v)
+
+	tree.Put("charlie", 10)
+	assert.Equal(5, tree.Len())
+	v, ok = tree.Get("charlie")
+	assert.True(ok)
+	assert.Equal(10, v)
+
+	_, ok = tree.Get("foxtrot")
+	assert.False(ok)
+
+	var keys []string
+	tree.Range("b", "d", func(k string, _ int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	assert.Equal([]string{"bravo", "charlie"}, keys)
+
+	keys = nil
+	tree.Range("alpha", "echo", func(k string, _ int) bool {
+		keys = append(keys, k)
+		return len(keys) < 3
+	})
+	assert.Equal([]string{"alpha", "bravo", "charlie"}, keys)
+
+	assert.True(tree.Delete("alpha"))
+	assert.False(tree.Delete("alpha"))
+	assert.Equal(4, tree.Len())
+
+	c := tree.Cursor()
+	c.Last()
+	assert.Equal("echo", c.Key())
+	assert.Equal(2, c.Value())
+}
+
+func TestNew_InvalidOrder(t *testing.T) {
+	tree := newBtree[int, int](1, intLess)
+	assert.Equal(t, defaultOrder, tree.order)
+}
+
+func TestLoad(t *testing.T) {
+	for _, order := range []int{2, 3, 5} {
+		for _, size := range []int{0, 1, 2, 3, 4, 5, 9, 10, 11, 50, 99, 100, 101, 1000} {
+			t.Run(fmt.Sprintf("order %d size %d", order, size), func(t *testing.T) {
+				keys := make([]int, size)
+				values := make([]interface{}, size)
+				for i := range keys {
+					keys[i] = 2 * i
+					values[i] = i
+				}
+
+				tree, err := Load(order, intLess, keys, values)
+				assert.NoError(t, err)
+				assert.Equal(t, size, tree.Len())
+
+				b := tree.(*testBtree)
+				assertInvariants(t, b)
+
+				for i, k := range keys {
+					v, ok := tree.Get(k)
+					assert.True(t, ok)
+					assert.Equal(t, i, v)
+
+					_, ok = tree.Get(k + 1)
+					assert.False(t, ok)
+				}
+
+				// the tree must remain usable after loading
+				tree.Put(-1, -1)
+				for _, k := range keys {
+					assert.True(t, tree.Delete(k))
+				}
+				assert.Equal(t, 1, tree.Len())
+				assertInvariants(t, b)
+			})
+		}
+	}
+}
+
+func TestLoad_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []int
+		values  []interface{}
+		wantErr error
+	}{
+		{"length mismatch", []int{1, 2}, []interface{}{1}, ErrLengthMismatch},
+		{"unsorted", []int{2, 1}, []interface{}{2, 1}, ErrUnsorted},
+		{"duplicate keys", []int{1, 1}, []interface{}{1, 1}, ErrUnsorted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := Load(3, intLess, tt.keys, tt.values)
+			assert.Nil(t, tree)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
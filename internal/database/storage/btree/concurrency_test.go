@@ -0,0 +1,200 @@
+package btree
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The tests in this file are intended to be run with the race detector.
+
+func TestConcurrent_DisjointWriters(t *testing.T) {
+	const (
+		writers = 8
+		keys    = 1000
+	)
+
+	for _, order := range []int{2, 3, 5} {
+		b := newTestBtree(order)
+
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+
+				rnd := rand.New(rand.NewSource(int64(w)))
+				// every writer owns all keys k with k%writers == w
+				for _, i := range rnd.Perm(keys) {
+					b.insert(i*writers+w, w)
+				}
+				// remove every other key again
+				for _, i := range rnd.Perm(keys) {
+					if i%2 == 0 {
+						assert.True(t, b.remove(i*writers+w))
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		assertInvariants(t, b)
+		assert.Equal(t, writers*keys/2, b.Len())
+		for k := 0; k < writers*keys; k++ {
+			e, exists := b.get(k)
+			if (k/writers)%2 == 0 {
+				assert.False(t, exists)
+			} else if assert.True(t, exists) {
+				assert.Equal(t, k%writers, e.value)
+			}
+		}
+	}
+}
+
+func TestConcurrent_Interleaved(t *testing.T) {
+	const (
+		writers = 4
+		readers = 4
+		ops     = 3000
+		keys    = 500
+	)
+
+	b := newTestBtree(2)
+
+	// the even keys are never removed, so readers must always find them
+	for k := 0; k < keys; k += 2 {
+		b.insert(k, k)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < ops; i++ {
+				k := rnd.Intn(keys/2)*2 + 1
+				if rnd.Intn(2) == 0 {
+					b.insert(k, k)
+				} else {
+					b.remove(k)
+				}
+			}
+		}(w)
+	}
+
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(int64(writers + r)))
+			for i := 0; i < ops/10; i++ {
+				k := rnd.Intn(keys/2) * 2
+				e, exists := b.get(k)
+				if assert.True(t, exists, "stable key %d not found", k) {
+					assert.Equal(t, k, e.value)
+				}
+
+				low, high := k, k+50
+				var seen []int
+				b.Range(low, high, func(k int, v interface{}) bool {
+					assert.Equal(t, k, v)
+					seen = append(seen, k)
+					return true
+				})
+				assertScan(t, seen, low, high, true)
+
+				// reverse scan, starting at the largest key <= high
+				c := b.Cursor()
+				seen = seen[:0]
+				c.Seek(high)
+				if !c.Valid() {
+					c.Last()
+				} else if c.Key() > high {
+					c.Prev()
+				}
+				for ; c.Valid() && c.Key() >= low; c.Prev() {
+					seen = append(seen, c.Key())
+				}
+				for i, j := 0, len(seen)-1; i < j; i, j = i+1, j-1 {
+					seen[i], seen[j] = seen[j], seen[i]
+				}
+				assertScan(t, seen, low, high, true)
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	assertInvariants(t, b)
+	assert.Equal(t, countEntries(b.root), b.Len())
+}
+
+func TestConcurrent_Cursor(t *testing.T) {
+	b := newTestBtree(3)
+	for k := 0; k < 1000; k++ {
+		b.insert(k, k)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// remove all odd keys while the cursor is scanning
+		for k := 999; k > 0; k -= 2 {
+			assert.True(t, b.remove(k))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+
+		var seen []int
+		c := b.Cursor()
+		for c.First(); c.Valid(); c.Next() {
+			seen = append(seen, c.Key())
+		}
+		// the scan must be ascending, and must contain every even key,
+		// since those are never removed
+		assertScan(t, seen, 0, 999, false)
+		for k := 0; k < 1000; k += 2 {
+			assert.Contains(t, seen, k)
+		}
+	}()
+	wg.Wait()
+
+	assertInvariants(t, b)
+	assert.Equal(t, 500, b.Len())
+}
+
+// assertScan checks that the given keys are strictly ascending and
+// within [low, high]. If stableEven is true, every even key in the
+// range must have been seen.
+func assertScan(t *testing.T, seen []int, low, high int, stableEven bool) {
+	t.Helper()
+
+	for i, k := range seen {
+		assert.True(t, low <= k && k <= high, "key %d out of range [%d, %d]", k, low, high)
+		if i > 0 {
+			assert.Less(t, seen[i-1], k, "scan is not ascending")
+		}
+	}
+
+	if stableEven {
+		expected := 0
+		for k := low; k <= high; k++ {
+			if k%2 == 0 && k < 500 {
+				expected++
+			}
+		}
+		evens := 0
+		for _, k := range seen {
+			if k%2 == 0 {
+				evens++
+			}
+		}
+		assert.Equal(t, expected, evens, "not all stable keys in [%d, %d] were seen", low, high)
+	}
+}
@@ -0,0 +1,185 @@
+package btree
+
+// Cursor is used to iterate over the entries of a btree in order of
+// their keys. A cursor can be moved in both directions, allowing
+// forward and reverse scans.
+//
+//  c := tree.Cursor()
+//  for c.Seek(low); c.Valid() && !less(high, c.Key()); c.Next() {
+//      ...
+//  }
+//
+// A cursor remains usable while the tree is modified. Every move
+// positions the cursor relative to the key that it is currently
+// positioned at, so it observes all modifications that were completed
+// before the move. A cursor itself must not be used by multiple
+// goroutines concurrently.
+type Cursor[K, V any] struct {
+	tree    *btree[K, V]
+	current *entry[K, V]
+}
+
+// Cursor returns a new, unpositioned cursor over this tree.
+func (b *btree[K, V]) Cursor() *Cursor[K, V] {
+	return &Cursor[K, V]{
+		tree: b,
+	}
+}
+
+// Valid returns whether the cursor is positioned at an entry. If this
+// returns false, Key and Value must not be called.
+func (c *Cursor[K, V]) Valid() bool {
+	return c.current != nil
+}
+
+// Key returns the key of the entry that the cursor is positioned at.
+func (c *Cursor[K, V]) Key() K {
+	return c.current.key
+}
+
+// Value returns the value of the entry that the cursor is positioned at.
+func (c *Cursor[K, V]) Value() V {
+	return c.current.value
+}
+
+// First positions the cursor at the entry with the smallest key. If the
+// tree is empty, the cursor will be invalid.
+func (c *Cursor[K, V]) First() {
+	c.current = c.tree.edge(false)
+}
+
+// Last positions the cursor at the entry with the largest key. If the
+// tree is empty, the cursor will be invalid.
+func (c *Cursor[K, V]) Last() {
+	c.current = c.tree.edge(true)
+}
+
+// Seek positions the cursor at the entry with the smallest key that is
+// greater than or equal to the given key. If there is no such entry,
+// the cursor will be invalid.
+func (c *Cursor[K, V]) Seek(k K) {
+	c.current = c.tree.ceiling(k, false)
+}
+
+// Next moves the cursor to the entry with the next larger key. If there
+// is no such entry, the cursor will be invalid.
+func (c *Cursor[K, V]) Next() {
+	if !c.Valid() {
+		return
+	}
+	c.current = c.tree.ceiling(c.current.key, true)
+}
+
+// Prev moves the cursor to the entry with the next smaller key. If
+// there is no such entry, the cursor will be invalid.
+func (c *Cursor[K, V]) Prev() {
+	if !c.Valid() {
+		return
+	}
+	c.current = c.tree.floor(c.current.key, true)
+}
+
+// edge returns the entry with the smallest key, or the entry with the
+// largest key if last is true. If the tree is empty, nil is returned.
+func (b *btree[K, V]) edge(last bool) *entry[K, V] {
+	if b.root == nil {
+		return nil
+	}
+
+	node := b.root
+	node.latch.RLock()
+	for !node.isLeaf() {
+		child := node.children[0]
+		if last {
+			child = node.children[len(node.children)-1]
+		}
+		child.latch.RLock()
+		node.latch.RUnlock()
+		node = child
+	}
+	defer node.latch.RUnlock()
+
+	switch {
+	case len(node.entries) == 0:
+		return nil
+	case last:
+		return node.entries[len(node.entries)-1]
+	default:
+		return node.entries[0]
+	}
+}
+
+// ceiling returns the entry with the smallest key that is greater than
+// or equal to the given key, or strictly greater than the given key if
+// strict is true. If there is no such entry, nil is returned.
+func (b *btree[K, V]) ceiling(k K, strict bool) (result *entry[K, V]) {
+	if b.root == nil {
+		return nil
+	}
+
+	node := b.root
+	node.latch.RLock()
+	for {
+		idx, exists := b.search(node.entries, k)
+		if exists {
+			if !strict {
+				result = node.entries[idx]
+				node.latch.RUnlock()
+				return
+			}
+			idx++
+		}
+
+		// The entry right of the child that we descend into is
+		// the result, if there is no larger key in the child.
+		if idx < len(node.entries) {
+			result = node.entries[idx]
+		}
+
+		if node.isLeaf() {
+			node.latch.RUnlock()
+			return
+		}
+
+		child := node.children[idx]
+		child.latch.RLock()
+		node.latch.RUnlock()
+		node = child
+	}
+}
+
+// floor returns the entry with the largest key that is less than or
+// equal to the given key, or strictly less than the given key if strict
+// is true. If there is no such entry, nil is returned.
+func (b *btree[K, V]) floor(k K, strict bool) (result *entry[K, V]) {
+	if b.root == nil {
+		return nil
+	}
+
+	node := b.root
+	node.latch.RLock()
+	for {
+		idx, exists := b.search(node.entries, k)
+		if exists && !strict {
+			result = node.entries[idx]
+			node.latch.RUnlock()
+			return
+		}
+
+		// The entry left of the child that we descend into is
+		// the result, if there is no smaller key in the child.
+		if idx > 0 {
+			result = node.entries[idx-1]
+		}
+
+		if node.isLeaf() {
+			node.latch.RUnlock()
+			return
+		}
+
+		child := node.children[idx]
+		child.latch.RLock()
+		node.latch.RUnlock()
+		node = child
+	}
+}
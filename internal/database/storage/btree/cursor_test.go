@@ -0,0 +1,103 @@
+package btree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor_Empty(t *testing.T) {
+	for _, b := range []*testBtree{
+		newTestBtree(defaultOrder),
+		{root: &testNode{}, order: defaultOrder, less: intLess},
+	} {
+		c := b.Cursor()
+
+		c.First()
+		assert.False(t, c.Valid())
+		c.Last()
+		assert.False(t, c.Valid())
+		c.Seek(1)
+		assert.False(t, c.Valid())
+
+		// moving an invalid cursor keeps it invalid
+		c.Next()
+		assert.False(t, c.Valid())
+		c.Prev()
+		assert.False(t, c.Valid())
+	}
+}
+
+func TestCursor_Seek(t *testing.T) {
+	tests := []struct {
+		name      string
+		k         int
+		wantValid bool
+		wantKey   int
+	}{
+		{"key in leaf", 5, true, 5},
+		{"key in root", 8, true, 8},
+		{"key between leaves", 3, true, 4},
+		{"key at end of leaf", 10, true, 11},
+		{"key after leaf, before root entry", 6, true, 7},
+		{"key below all keys", -1, true, 0},
+		{"key above all keys", 13, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRangeTestTree().Cursor()
+			c.Seek(tt.k)
+
+			if assert.Equal(t, tt.wantValid, c.Valid()) && tt.wantValid {
+				assert.Equal(t, tt.wantKey, c.Key())
+				assert.Equal(t, tt.wantKey, c.Value())
+			}
+		})
+	}
+}
+
+func TestCursor_Scan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+
+	for _, order := range []int{2, 3, 5} {
+		b := newTestBtree(order)
+		var keys []int
+		for _, k := range rnd.Perm(1000)[:400] {
+			b.insert(k, k)
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+
+		c := b.Cursor()
+
+		var forward []int
+		for c.First(); c.Valid(); c.Next() {
+			forward = append(forward, c.Key())
+		}
+		assert.Equal(t, keys, forward)
+
+		var reverse []int
+		for c.Last(); c.Valid(); c.Prev() {
+			reverse = append(reverse, c.Key())
+		}
+		for i, j := 0, len(reverse)-1; i < j; i, j = i+1, j-1 {
+			reverse[i], reverse[j] = reverse[j], reverse[i]
+		}
+		assert.Equal(t, keys, reverse)
+
+		// change directions in the middle of the tree
+		for i := 1; i < len(keys)-1; i += 17 {
+			c.Seek(keys[i])
+			c.Next()
+			assert.Equal(t, keys[i+1], c.Key())
+			c.Prev()
+			c.Prev()
+			assert.Equal(t, keys[i-1], c.Key())
+			c.Next()
+			assert.Equal(t, keys[i], c.Key())
+		}
+	}
+}
@@ -1,9 +1,20 @@
 // Package btree contains the btree struct, which is used as the primary data store of
-// the database.
+// the database. A btree is an ordered map, that is generic over the types of
+// its keys and values. The order of the keys is defined by a less function.
+//
+//  tree := btree.New[int, string](3, func(a, b int) bool { return a < b })
 //
 // The btree supports 3 primary operations:
-// - get: given a key, retrieve the corresponding entry
-// - put: given a key and a value, create an entry in the btree
-// - remove: given a key, remove the corresponding entry in the tree if it
+// - Get: given a key, retrieve the corresponding value
+// - Put: given a key and a value, create an entry in the btree
+// - Delete: given a key, remove the corresponding entry in the tree if it
 // exists
+//
+// Entries can be iterated over in order of their keys, either with Range, or
+// with a Cursor, which supports both forward and reverse scans. A btree can be
+// bulk loaded from sorted input with Load.
+//
+// A btree is safe for concurrent use. Readers and writers synchronize on the
+// nodes of the tree using latch coupling, so that operations in different parts
+// of the tree can proceed in parallel.
 package btree
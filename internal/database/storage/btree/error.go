@@ -0,0 +1,12 @@
+package btree
+
+// Error provides constant errors to the btree package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrLengthMismatch = Error("amount of keys and values differs")
+	ErrUnsorted       = Error("keys are not in strictly ascending order")
+)
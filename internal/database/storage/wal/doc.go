@@ -0,0 +1,15 @@
+// Package wal implements a write-ahead log, that makes the state of a storage
+// component durable.
+//
+// Every modification of the state is written to the log as a batch of logical
+// redo records, before it is applied to the state. A batch is durable as soon
+// as Write returns, and is recovered either completely or not at all.
+// Concurrent writers are committed together, with a single fsync for all of
+// them (group commit).
+//
+// A checkpoint writes a snapshot of the entire state into the main file, and
+// resets the log. On startup, the state is loaded from the main file, and all
+// records that were written to the log after the last checkpoint are applied
+// to it again (redo-only recovery). A partially written batch at the end of the
+// log, as left behind by a crash, is discarded.
+package wal
@@ -0,0 +1,13 @@
+package wal
+
+// Error provides constant errors to the wal package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrClosed  = Error("log is closed")
+	ErrCorrupt = Error("file is corrupt")
+	ErrExists  = Error("file already exists")
+)
@@ -0,0 +1,71 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem describes the file system operations that the log needs. Use OS
+// for the file system of the operating system.
+type FileSystem interface {
+	// OpenFile opens the named file, just like os.OpenFile.
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	// Rename renames a file, replacing the target if it already exists. After
+	// Rename returns, the rename must be durable.
+	Rename(oldname, newname string) error
+	// Remove removes the named file. After Remove returns, the removal must
+	// be durable.
+	Remove(name string) error
+}
+
+// File describes a file that was opened by a FileSystem.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	// Sync commits the content of the file to stable storage.
+	Sync() error
+	// Truncate changes the size of the file.
+	Truncate(size int64) error
+}
+
+// OS is the file system of the operating system.
+var OS FileSystem = osFS{}
+
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm) // #nosec G304 the name is given by the caller
+}
+
+// Rename renames the file and syncs the directory that contains the file, in
+// order to make the rename durable.
+func (osFS) Rename(oldname, newname string) error {
+	if err := os.Rename(oldname, newname); err != nil {
+		return err
+	}
+	return syncDir(newname)
+}
+
+// Remove removes the file and syncs the directory that contained the file,
+// in order to make the removal durable.
+func (osFS) Remove(name string) error {
+	if err := os.Remove(name); err != nil {
+		return err
+	}
+	return syncDir(name)
+}
+
+// syncDir syncs the directory that contains the named file.
+func syncDir(name string) error {
+	dir, err := os.Open(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	if err := dir.Sync(); err != nil {
+		_ = dir.Close()
+		return err
+	}
+	return dir.Close()
+}
@@ -0,0 +1,210 @@
+package wal
+
+import (
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// errCrash is returned by all operations of a memFS after it crashed.
+const errCrash = Error("crashed")
+
+// memFS is an in-memory file system, that can simulate a crash of the
+// process after a given amount of bytes has been written. After the crash,
+// all operations fail. The file system as it would be found after a restart
+// can be obtained with restart.
+//
+// Written data is only guaranteed to survive a crash after the file was
+// synced. Of the data that was written since the last sync, an arbitrary
+// prefix survives. Renames are durable immediately.
+type memFS struct {
+	mu    sync.Mutex
+	files map[string]*memData
+	// budget is the amount of bytes that can be written before the crash.
+	// A negative budget means that the file system never crashes.
+	budget  int
+	crashed bool
+	// written is the amount of bytes that were written in total.
+	written int
+	// syncs is the amount of calls to Sync.
+	syncs int
+	// syncDelay is the time that every sync takes.
+	syncDelay time.Duration
+}
+
+type memData struct {
+	data   []byte
+	synced []byte
+}
+
+func newMemFS(budget int) *memFS {
+	return &memFS{
+		files:  map[string]*memData{},
+		budget: budget,
+	}
+}
+
+// restart returns the file system as it would be found after a restart
+// following a crash. The returned file system never crashes.
+func (m *memFS) restart(rnd *rand.Rand) *memFS {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restarted := newMemFS(-1)
+	for name, d := range m.files {
+		restarted.files[name] = &memData{
+			data:   d.survivor(rnd),
+			synced: nil,
+		}
+		restarted.files[name].synced = append([]byte{}, restarted.files[name].data...)
+	}
+	return restarted
+}
+
+// survivor returns the content of the file as it survives a crash.
+func (d *memData) survivor(rnd *rand.Rand) []byte {
+	common := 0
+	for common < len(d.data) && common < len(d.synced) && d.data[common] == d.synced[common] {
+		common++
+	}
+
+	if common < len(d.synced) && rnd.Intn(2) == 0 {
+		// the file was truncated after the last sync, and the
+		// truncation did not survive
+		return append([]byte{}, d.synced...)
+	}
+	return append([]byte{}, d.data[:common+rnd.Intn(len(d.data)-common+1)]...)
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.crashed {
+		return nil, errCrash
+	}
+
+	d, ok := m.files[name]
+	if !ok {
+		if flag&os.O_CREATE == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		d = &memData{}
+		m.files[name] = d
+	}
+	if flag&os.O_TRUNC != 0 {
+		d.data = nil
+	}
+	return &memFile{fs: m, d: d}, nil
+}
+
+func (m *memFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.crashed {
+		return errCrash
+	}
+	d, ok := m.files[oldname]
+	if !ok {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrNotExist}
+	}
+	m.files[newname] = d
+	delete(m.files, oldname)
+	return nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.crashed {
+		return errCrash
+	}
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memFS) size(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.files[name].data)
+}
+
+// memFile is an open file of a memFS. All writes are appended to the end
+// of the file.
+type memFile struct {
+	fs     *memFS
+	d      *memData
+	offset int
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return 0, errCrash
+	}
+	if f.offset >= len(f.d.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.d.data[f.offset:])
+	f.offset += n
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return 0, errCrash
+	}
+	if f.fs.budget >= 0 && len(p) > f.fs.budget {
+		p = p[:f.fs.budget]
+		f.fs.crashed = true
+	}
+	f.fs.budget -= len(p)
+	f.fs.written += len(p)
+	f.d.data = append(f.d.data, p...)
+
+	if f.fs.crashed {
+		return len(p), errCrash
+	}
+	return len(p), nil
+}
+
+func (f *memFile) Sync() error {
+	f.fs.mu.Lock()
+	if f.fs.crashed {
+		f.fs.mu.Unlock()
+		return errCrash
+	}
+	f.d.synced = append([]byte{}, f.d.data...)
+	f.fs.syncs++
+	delay := f.fs.syncDelay
+	f.fs.mu.Unlock()
+
+	time.Sleep(delay)
+	return nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.fs.crashed {
+		return errCrash
+	}
+	f.d.data = f.d.data[:size:size]
+	return nil
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package wal
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// Op is the operation of a record.
+type Op uint8
+
+// Supported operations.
+const (
+	// OpPut stores a value with a key, replacing the value that was
+	// previously stored with the key.
+	OpPut Op = iota + 1
+	// OpDelete removes a key. The value of the record is empty.
+	OpDelete
+	// OpSuper names the super-journal of a transaction, that spans multiple
+	// logs. The key is the name of the super-journal, and the value is
+	// empty. The record is not applied to the state.
+	OpSuper
+)
+
+// Record is a logical redo record, that describes a single modification of
+// the state.
+type Record struct {
+	Op    Op
+	Key   []byte
+	Value []byte
+}
+
+// frameHeaderSize is the size of the header of a frame, which consists of
+// the length of the payload and the checksum of the payload.
+const frameHeaderSize = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// appendFrame appends a frame containing the given batch of records to the
+// given buffer. A frame is laid out as follows.
+//
+//  length   uint32   length of the payload
+//  checksum uint32   crc32 (Castagnoli) of the payload
+//  payload
+//    lsn    uint64   log sequence number of the batch
+//    count  uvarint  amount of records in the batch
+//    records, each consisting of
+//      op     uint8
+//      keylen uvarint, key
+//      vallen uvarint, value
+func appendFrame(buf []byte, lsn uint64, records []Record) []byte {
+	start := len(buf)
+	buf = append(buf, make([]byte, frameHeaderSize)...)
+
+	buf = appendUint64(buf, lsn)
+	buf = appendUvarint(buf, uint64(len(records)))
+	for _, rec := range records {
+		buf = append(buf, byte(rec.Op))
+		buf = appendUvarint(buf, uint64(len(rec.Key)))
+		buf = append(buf, rec.Key...)
+		buf = appendUvarint(buf, uint64(len(rec.Value)))
+		buf = append(buf, rec.Value...)
+	}
+
+	payload := buf[start+frameHeaderSize:]
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[start+4:], crc32.Checksum(payload, crcTable))
+	return buf
+}
+
+// readFrame reads the frame at the start of the given data. It returns the
+// size of the frame in bytes. If the data does not start with a complete and
+// valid frame, ok is false.
+func readFrame(data []byte) (lsn uint64, records []Record, size int, ok bool) {
+	if len(data) < frameHeaderSize {
+		return 0, nil, 0, false
+	}
+
+	length := binary.LittleEndian.Uint32(data)
+	checksum := binary.LittleEndian.Uint32(data[4:])
+	if uint64(length) > uint64(len(data)-frameHeaderSize) {
+		return 0, nil, 0, false
+	}
+
+	payload := data[frameHeaderSize : frameHeaderSize+int(length)]
+	if crc32.Checksum(payload, crcTable) != checksum {
+		return 0, nil, 0, false
+	}
+
+	lsn, records, err := decodePayload(payload)
+	if err != nil {
+		return 0, nil, 0, false
+	}
+	return lsn, records, frameHeaderSize + int(length), true
+}
+
+func decodePayload(payload []byte) (lsn uint64, records []Record, err error) {
+	d := decoder{data: payload}
+
+	lsn = d.uint64()
+	count := d.uvarint()
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		op := Op(d.byte())
+		key := d.bytes()
+		value := d.bytes()
+		records = append(records, Record{op, key, value})
+	}
+
+	if d.err == nil && len(d.data) != 0 {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.data))
+	}
+	return lsn, records, d.err
+}
+
+// decoder decodes values from a byte slice. After the first error, all
+// methods return zero values, and the error is available in err.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.data) < 1 {
+		d.fail()
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil || len(d.data) < 8 {
+		d.fail()
+		return 0
+	}
+	v := binary.LittleEndian.Uint64(d.data)
+	d.data = d.data[8:]
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil || uint64(len(d.data)) < n {
+		d.fail()
+		return nil
+	}
+	b := append([]byte{}, d.data[:n]...)
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = fmt.Errorf("unexpected end of data")
+	}
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
@@ -0,0 +1,40 @@
+package wal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame(t *testing.T) {
+	records := []Record{
+		{Op: OpPut, Key: []byte("key"), Value: []byte("value")},
+		{Op: OpDelete, Key: []byte("other"), Value: []byte{}},
+		{Op: OpPut, Key: []byte{}, Value: []byte{}},
+	}
+
+	buf := appendFrame([]byte("prefix"), 42, records)
+	frame := buf[len("prefix"):]
+
+	lsn, got, size, ok := readFrame(append(frame, "suffix"...))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(42), lsn)
+	assert.Equal(t, records, got)
+	assert.Equal(t, len(frame), size)
+}
+
+func TestFrame_Invalid(t *testing.T) {
+	frame := appendFrame(nil, 1, []Record{{Op: OpPut, Key: []byte("key"), Value: []byte("value")}})
+
+	for i := 0; i < len(frame); i++ {
+		_, _, _, ok := readFrame(frame[:i])
+		assert.False(t, ok, "frame truncated to %d bytes must be invalid", i)
+	}
+
+	for i := 0; i < len(frame); i++ {
+		corrupt := append([]byte{}, frame...)
+		corrupt[i] ^= 0x01
+		_, _, _, ok := readFrame(corrupt)
+		assert.False(t, ok, "frame with flipped bit in byte %d must be invalid", i)
+	}
+}
@@ -0,0 +1,97 @@
+package wal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+const superMagic = "lbaddSJ\x00"
+
+// WriteSuper durably creates the super-journal with the given name, which
+// lists the main files of the logs, that take part in a transaction.
+//
+// A transaction that spans multiple logs writes a batch to every log, that
+// contains an OpSuper record with the name of the super-journal. While the
+// super-journal exists, these batches are skipped during recovery. Removing
+// the super-journal after all batches have been written commits the
+// transaction in all logs at once. If the transaction fails before, the
+// super-journal must be kept, until every log that the batch was written to
+// has been checkpointed. Open removes such a stale super-journal, once none of
+// the logs that it lists contains a batch of the transaction anymore.
+func WriteSuper(fsys FileSystem, name string, files []string) error {
+	return replaceFile(fsys, name, []byte(superMagic), []byte(strings.Join(files, "\n")))
+}
+
+// committed reports whether the given batch is committed, which is the case
+// unless it names a super-journal that still exists. The name of that
+// super-journal is returned as well.
+func (l *Log) committed(records []Record) (bool, string, error) {
+	for _, rec := range records {
+		if rec.Op != OpSuper {
+			continue
+		}
+		file, err := l.fs.OpenFile(string(rec.Key), os.O_RDONLY, filePerm)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, "", err
+		}
+		_ = file.Close()
+		return false, string(rec.Key), nil
+	}
+	return true, "", nil
+}
+
+// resolveSuper removes the given stale super-journal, if none of the logs
+// that it lists contains a batch that names it anymore. A transaction whose
+// super-journal still exists is rolled back by every log during recovery, so
+// it may only be removed after all logs have discarded their batches of the
+// transaction. Otherwise, a log that is recovered later would find the
+// super-journal gone, and apply its batch.
+func resolveSuper(fsys FileSystem, super string) error {
+	data, err := readFile(fsys, super)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) < len(superMagic) || string(data[:len(superMagic)]) != superMagic {
+		return ErrCorrupt
+	}
+	for _, name := range strings.Split(string(data[len(superMagic):]), "\n") {
+		referenced, err := references(fsys, name+walSuffix, super)
+		if err != nil || referenced {
+			return err
+		}
+	}
+	return fsys.Remove(super)
+}
+
+// references reports whether the given log file contains a batch, that names
+// the given super-journal.
+func references(fsys FileSystem, walName, super string) (bool, error) {
+	data, err := readFile(fsys, walName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for offset := len(walMagic); offset < len(data); {
+		_, records, size, ok := readFrame(data[offset:])
+		if !ok {
+			break
+		}
+		offset += size
+		for _, rec := range records {
+			if rec.Op == OpSuper && string(rec.Key) == super {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,466 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+const (
+	mainMagic = "lbaddDB\x00"
+	walMagic  = "lbaddWAL"
+
+	// mainHeaderSize is the size of the header of the main file, which
+	// consists of the magic, the lsn of the checkpoint, the length of the
+	// snapshot and the checksum of the snapshot.
+	mainHeaderSize = len(mainMagic) + 8 + 8 + 4
+
+	walSuffix  = "-wal"
+	tempSuffix = ".tmp"
+
+	filePerm = 0600
+)
+
+// State describes the state that is made durable by a log.
+type State interface {
+	// Load replaces the state with the snapshot that is read from the given
+	// reader. The snapshot was written by Snapshot.
+	Load(r io.Reader) error
+	// Snapshot writes the entire state to the given writer.
+	Snapshot(w io.Writer) error
+	// Apply applies a single record to the state.
+	Apply(rec Record) error
+}
+
+// Log is a write-ahead log for a State. The state is stored in a main file,
+// and all modifications since the last checkpoint are stored in a log file,
+// which has the name of the main file with the suffix "-wal".
+//
+//  log, err := wal.Open(wal.OS, "lbadd.db", state)
+//  ...
+//  err = log.Write(wal.Record{Op: wal.OpPut, Key: k, Value: v})
+//  // the record is durable now, apply it to the state
+//
+// A Log is safe for concurrent use by multiple goroutines.
+type Log struct {
+	fs    FileSystem
+	name  string
+	state State
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	file File
+	// pending holds the encoded frames that have not been written yet.
+	pending []byte
+	// nextLSN is the lsn that the next batch will get.
+	nextLSN uint64
+	// flushed is the lsn of the last batch that is durable.
+	flushed uint64
+	// flushing indicates that a writer is currently writing the pending
+	// frames to the log file.
+	flushing bool
+	// err is set after the log file could not be written. Since the content
+	// of the file is unknown after that, the log cannot be used anymore.
+	err    error
+	closed bool
+}
+
+// Open opens the main file with the given name and its log file, and recovers
+// the given state. The state is loaded from the main file, and all batches in
+// the log file are applied to it. Before Open returns, a partially written
+// batch at the end of the log file is removed. If neither the main file nor
+// the log file exist, a new, empty log is created.
+func Open(fsys FileSystem, name string, state State) (*Log, error) {
+	l := &Log{
+		fs:    fsys,
+		name:  name,
+		state: state,
+	}
+	l.cond = sync.NewCond(&l.mu)
+
+	checkpoint, err := l.load()
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", name, err)
+	}
+
+	last, stale, err := l.replay(checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: %w", name+walSuffix, err)
+	}
+
+	l.nextLSN = last + 1
+	l.flushed = last
+	if len(stale) > 0 {
+		if err := l.rollbackSuper(stale); err != nil {
+			_ = l.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// rollbackSuper discards the batches of the transactions with the given
+// stale super-journals, which were skipped during recovery, by checkpointing
+// the log. After that, the super-journals are removed, unless other logs
+// still contain batches of their transactions.
+func (l *Log) rollbackSuper(stale []string) error {
+	if err := l.Checkpoint(); err != nil {
+		return fmt.Errorf("roll back: %w", err)
+	}
+	for _, super := range stale {
+		if err := resolveSuper(l.fs, super); err != nil {
+			return fmt.Errorf("resolve super-journal %s: %w", super, err)
+		}
+	}
+	return nil
+}
+
+// Write writes the given records to the log as a single batch. When Write
+// returns without an error, the batch is durable, and will be recovered
+// completely, even if the process crashes. If Write returns an error, the
+// batch may or may not be recovered, but never partially.
+//
+// Concurrent calls to Write are committed together, with a single sync of
+// the log file.
+func (l *Log) Write(records ...Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.usable(); err != nil {
+		return err
+	}
+
+	lsn := l.nextLSN
+	l.nextLSN++
+	l.pending = appendFrame(l.pending, lsn, records)
+
+	// Either wait until another writer has flushed the batch, or become the
+	// writer that flushes all pending batches.
+	for l.flushed < lsn && l.err == nil {
+		if l.flushing {
+			l.cond.Wait()
+			continue
+		}
+		l.flush()
+	}
+	return l.err
+}
+
+// flush writes all pending frames to the log file and syncs it. The mutex of
+// the log must be held, and is released while writing.
+func (l *Log) flush() {
+	buf, last := l.pending, l.nextLSN-1
+	l.pending = nil
+	l.flushing = true
+	l.mu.Unlock()
+
+	_, err := l.file.Write(buf)
+	if err == nil {
+		err = l.file.Sync()
+	}
+
+	l.mu.Lock()
+	l.flushing = false
+	if err != nil {
+		l.err = fmt.Errorf("write %s: %w", l.name+walSuffix, err)
+	} else {
+		l.flushed = last
+	}
+	l.cond.Broadcast()
+}
+
+// Checkpoint writes a snapshot of the state into the main file, and resets
+// the log file. Writes are blocked while a checkpoint is in progress.
+//
+// Every batch that has been written must be applied to the state before
+// Checkpoint is called, since the log file no longer contains the batches
+// after the checkpoint.
+func (l *Log) Checkpoint() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.usable(); err != nil {
+		return err
+	}
+
+	// make sure that there is no batch left that is not durable yet
+	for l.flushing || len(l.pending) > 0 {
+		if l.flushing {
+			l.cond.Wait()
+		} else {
+			l.flush()
+		}
+		if l.err != nil {
+			return l.err
+		}
+	}
+
+	header, snapshot, err := mainFile(l.flushed, l.state)
+	if err != nil {
+		return err
+	}
+	if err := l.replace(l.name, header, snapshot); err != nil {
+		return fmt.Errorf("write %s: %w", l.name, err)
+	}
+
+	// The checkpoint is complete now. If the log file cannot be reset, the
+	// batches in it will be skipped during recovery, since the main file
+	// already contains them.
+	if err := l.reset(); err != nil {
+		l.err = fmt.Errorf("reset %s: %w", l.name+walSuffix, err)
+		return l.err
+	}
+	return nil
+}
+
+// Export writes a snapshot of the given state into a new main file with the
+// given name, which can be opened with Open. Neither the main file nor its
+// log file must exist yet.
+func Export(fsys FileSystem, name string, state State) error {
+	for _, n := range []string{name, name + walSuffix} {
+		file, err := fsys.OpenFile(n, os.O_RDONLY, filePerm)
+		if err == nil {
+			_ = file.Close()
+			return fmt.Errorf("%w: %s", ErrExists, n)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	header, snapshot, err := mainFile(0, state)
+	if err != nil {
+		return err
+	}
+	if err := replaceFile(fsys, name, header, snapshot); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// mainFile returns the header and the content of a main file, that contains
+// a snapshot of the given state, taken at the checkpoint with the given lsn.
+func mainFile(checkpoint uint64, state State) (header, snapshot []byte, err error) {
+	var buf bytes.Buffer
+	if err := state.Snapshot(&buf); err != nil {
+		return nil, nil, fmt.Errorf("snapshot: %w", err)
+	}
+
+	header = make([]byte, 0, mainHeaderSize)
+	header = append(header, mainMagic...)
+	header = appendUint64(header, checkpoint)
+	header = appendUint64(header, uint64(buf.Len()))
+	header = appendUint32(header, crc32.Checksum(buf.Bytes(), crcTable))
+	return header, buf.Bytes(), nil
+}
+
+// Close closes the log file. The log cannot be used after it has been
+// closed.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	for l.flushing {
+		l.cond.Wait()
+	}
+	l.closed = true
+	return l.file.Close()
+}
+
+// Fail makes the log unusable, so that Write and Checkpoint return the given
+// error. It is used when a batch was written, whose outcome cannot be decided
+// until the log is recovered, like a batch of a transaction whose
+// super-journal could not be removed. The log must be closed and opened
+// again.
+func (l *Log) Fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+func (l *Log) usable() error {
+	if l.closed {
+		return ErrClosed
+	}
+	return l.err
+}
+
+// load loads the state from the main file, and returns the lsn of the
+// checkpoint that the main file was written at. If there is no main file,
+// the state is not loaded, and the lsn is 0.
+func (l *Log) load() (checkpoint uint64, err error) {
+	data, err := l.readFile(l.name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if len(data) < mainHeaderSize || string(data[:len(mainMagic)]) != mainMagic {
+		return 0, ErrCorrupt
+	}
+	d := decoder{data: data[len(mainMagic):]}
+	checkpoint = d.uint64()
+	length := d.uint64()
+	checksum := binary.LittleEndian.Uint32(d.data)
+	snapshot := d.data[4:]
+	if uint64(len(snapshot)) != length || crc32.Checksum(snapshot, crcTable) != checksum {
+		return 0, ErrCorrupt
+	}
+
+	if err := l.state.Load(bytes.NewReader(snapshot)); err != nil {
+		return 0, fmt.Errorf("load snapshot: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// replay applies all batches in the log file, that were written after the
+// given checkpoint, to the state, and returns the lsn of the last batch,
+// together with the super-journals of the skipped batches, whose transactions
+// were not committed. A partially written batch at the end of the log file is
+// removed. If there is no log file, a new one is created.
+func (l *Log) replay(checkpoint uint64) (last uint64, stale []string, err error) {
+	walName := l.name + walSuffix
+
+	data, err := l.readFile(walName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return checkpoint, nil, l.reset()
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) < len(walMagic) || string(data[:len(walMagic)]) != walMagic {
+		return 0, nil, ErrCorrupt
+	}
+
+	last = checkpoint
+	offset := len(walMagic)
+	for {
+		lsn, records, size, ok := readFrame(data[offset:])
+		if !ok {
+			break
+		}
+		offset += size
+
+		if lsn <= checkpoint {
+			// already contained in the main file
+			continue
+		}
+		if lsn != last+1 {
+			return 0, nil, ErrCorrupt
+		}
+		last = lsn
+
+		committed, super, err := l.committed(records)
+		if err != nil {
+			return 0, nil, fmt.Errorf("batch %d: %w", lsn, err)
+		}
+		if !committed {
+			stale = append(stale, super)
+			continue
+		}
+		for _, rec := range records {
+			if rec.Op == OpSuper {
+				continue
+			}
+			if err := l.state.Apply(rec); err != nil {
+				return 0, nil, fmt.Errorf("apply batch %d: %w", lsn, err)
+			}
+		}
+	}
+
+	l.file, err = l.fs.OpenFile(walName, os.O_RDWR|os.O_APPEND, filePerm)
+	if err != nil {
+		return 0, nil, err
+	}
+	if offset < len(data) {
+		// remove the partially written batch
+		if err := l.file.Truncate(int64(offset)); err != nil {
+			_ = l.file.Close()
+			return 0, nil, err
+		}
+		if err := l.file.Sync(); err != nil {
+			_ = l.file.Close()
+			return 0, nil, err
+		}
+	}
+	return last, stale, nil
+}
+
+// reset replaces the log file with an empty one, and opens it.
+func (l *Log) reset() error {
+	walName := l.name + walSuffix
+	if err := l.replace(walName, []byte(walMagic), nil); err != nil {
+		return err
+	}
+
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+	file, err := l.fs.OpenFile(walName, os.O_RDWR|os.O_APPEND, filePerm)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	return nil
+}
+
+// replace atomically replaces the named file with a file with the given
+// header and content, by writing a temporary file and renaming it.
+func (l *Log) replace(name string, header, content []byte) error {
+	return replaceFile(l.fs, name, header, content)
+}
+
+func replaceFile(fsys FileSystem, name string, header, content []byte) error {
+	tempName := name + tempSuffix
+
+	file, err := fsys.OpenFile(tempName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, filePerm)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(header, content...))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = fsys.Rename(tempName, name)
+	}
+
+	if err != nil {
+		_ = fsys.Remove(tempName)
+	}
+	return err
+}
+
+func (l *Log) readFile(name string) ([]byte, error) {
+	return readFile(l.fs, name)
+}
+
+func readFile(fsys FileSystem, name string) ([]byte, error) {
+	file, err := fsys.OpenFile(name, os.O_RDONLY, filePerm)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return data, err
+}
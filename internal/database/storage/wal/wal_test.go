@@ -0,0 +1,462 @@
+package wal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapState is a State that holds a map of strings.
+type mapState struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMapState() *mapState {
+	return &mapState{values: map[string]string{}}
+}
+
+func (s *mapState) Load(r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = map[string]string{}
+	return json.NewDecoder(r).Decode(&s.values)
+}
+
+func (s *mapState) Snapshot(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(w).Encode(s.values)
+}
+
+func (s *mapState) Apply(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch rec.Op {
+	case OpPut:
+		s.values[string(rec.Key)] = string(rec.Value)
+	case OpDelete:
+		delete(s.values, string(rec.Key))
+	default:
+		return fmt.Errorf("unknown op %d", rec.Op)
+	}
+	return nil
+}
+
+func (s *mapState) applyAll(records []Record) {
+	for _, rec := range records {
+		_ = s.Apply(rec)
+	}
+}
+
+func (s *mapState) copy() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := map[string]string{}
+	for k, v := range s.values {
+		values[k] = v
+	}
+	return values
+}
+
+func put(k, v string) Record {
+	return Record{Op: OpPut, Key: []byte(k), Value: []byte(v)}
+}
+
+func del(k string) Record {
+	return Record{Op: OpDelete, Key: []byte(k)}
+}
+
+func TestOpen_Empty(t *testing.T) {
+	fsys := newMemFS(-1)
+
+	log, err := Open(fsys, "db", newMapState())
+	require.NoError(t, err)
+	assert.Equal(t, len(walMagic), fsys.size("db-wal"))
+	assert.NoError(t, log.Close())
+
+	_, err = fsys.OpenFile("db", 0, 0)
+	assert.Error(t, err, "main file must not be created before the first checkpoint")
+}
+
+func TestWrite_Recover(t *testing.T) {
+	assert := assert.New(t)
+	fsys := newMemFS(-1)
+
+	log, err := Open(fsys, "db", newMapState())
+	require.NoError(t, err)
+	assert.NoError(log.Write(put("a", "1"), put("b", "2")))
+	assert.NoError(log.Write(put("a", "3"), del("b")))
+	assert.NoError(log.Write(put("c", "4")))
+	assert.NoError(log.Close())
+
+	state := newMapState()
+	log, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a": "3", "c": "4"}, state.copy())
+
+	// batches written after recovery continue the sequence
+	assert.NoError(log.Write(del("c")))
+	assert.NoError(log.Close())
+
+	state = newMapState()
+	log, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a": "3"}, state.copy())
+	assert.NoError(log.Close())
+}
+
+func TestCheckpoint(t *testing.T) {
+	assert := assert.New(t)
+	fsys := newMemFS(-1)
+
+	state := newMapState()
+	log, err := Open(fsys, "db", state)
+	require.NoError(t, err)
+
+	for _, batch := range [][]Record{
+		{put("a", "1"), put("b", "2")},
+		{put("c", "3")},
+	} {
+		assert.NoError(log.Write(batch...))
+		state.applyAll(batch)
+	}
+	assert.NoError(log.Checkpoint())
+	assert.Equal(len(walMagic), fsys.size("db-wal"), "log file must be reset")
+
+	batch := []Record{del("a"), put("d", "4")}
+	assert.NoError(log.Write(batch...))
+	state.applyAll(batch)
+	assert.NoError(log.Close())
+
+	recovered := newMapState()
+	log, err = Open(fsys, "db", recovered)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"b": "2", "c": "3", "d": "4"}, recovered.copy())
+	assert.NoError(log.Close())
+}
+
+func TestExport(t *testing.T) {
+	fsys := newMemFS(-1)
+
+	state := newMapState()
+	state.applyAll([]Record{put("a", "1"), put("b", "2")})
+	require.NoError(t, Export(fsys, "copy", state))
+	assert.True(t, errors.Is(Export(fsys, "copy", state), ErrExists))
+
+	recovered := newMapState()
+	log, err := Open(fsys, "copy", recovered)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, recovered.copy())
+	require.NoError(t, log.Close())
+}
+
+func TestRecover_SkipsCheckpointedBatches(t *testing.T) {
+	fsys := newMemFS(-1)
+
+	state := newMapState()
+	log, err := Open(fsys, "db", state)
+	require.NoError(t, err)
+	batch := []Record{put("a", "1")}
+	require.NoError(t, log.Write(batch...))
+	state.applyAll(batch)
+
+	// keep the log file as it was before the checkpoint, as if the process
+	// crashed before the log file was reset
+	oldLog := append([]byte{}, fsys.files["db-wal"].data...)
+	require.NoError(t, log.Checkpoint())
+	require.NoError(t, log.Close())
+	fsys.files["db-wal"] = &memData{data: oldLog}
+
+	// the batch must not be applied twice, which is detectable by applying
+	// a delete to a state that already contains the key
+	recovered := newMapState()
+	log, err = Open(fsys, "db", recovered)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1"}, recovered.copy())
+	require.NoError(t, log.Write(del("a")))
+	require.NoError(t, log.Close())
+
+	recovered = newMapState()
+	_, err = Open(fsys, "db", recovered)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{}, recovered.copy())
+}
+
+func TestRecover_TornTail(t *testing.T) {
+	assert := assert.New(t)
+	fsys := newMemFS(-1)
+
+	log, err := Open(fsys, "db", newMapState())
+	require.NoError(t, err)
+	assert.NoError(log.Write(put("a", "1")))
+	assert.NoError(log.Close())
+	size := fsys.size("db-wal")
+
+	// append half of a frame
+	frame := appendFrame(nil, 2, []Record{put("b", "2")})
+	d := fsys.files["db-wal"]
+	d.data = append(d.data, frame[:len(frame)/2]...)
+
+	state := newMapState()
+	log, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a": "1"}, state.copy())
+	assert.Equal(size, fsys.size("db-wal"), "torn frame must be removed")
+
+	assert.NoError(log.Write(put("c", "3")))
+	assert.NoError(log.Close())
+
+	state = newMapState()
+	_, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a": "1", "c": "3"}, state.copy())
+}
+
+func TestRecover_Super(t *testing.T) {
+	assert := assert.New(t)
+	fsys := newMemFS(-1)
+	super := Record{Op: OpSuper, Key: []byte("db-mj1")}
+
+	// the super-journal was removed, so the transaction was committed
+	log, err := Open(fsys, "db", newMapState())
+	require.NoError(t, err)
+	require.NoError(t, WriteSuper(fsys, "db-mj1", []string{"db", "other"}))
+	assert.NoError(log.Write(put("a", "1"), super))
+	assert.NoError(log.Close())
+	require.NoError(t, fsys.Remove("db-mj1"))
+
+	state := newMapState()
+	log, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a": "1"}, state.copy())
+
+	// the super-journal still exists, so the transaction was not committed
+	stale := Record{Op: OpSuper, Key: []byte("db-mj2")}
+	require.NoError(t, WriteSuper(fsys, "db-mj2", []string{"db", "other"}))
+	assert.NoError(log.Write(put("b", "2"), stale))
+	assert.NoError(log.Write(put("c", "3")))
+	assert.NoError(log.Close())
+	other, err := Open(fsys, "other", newMapState())
+	require.NoError(t, err)
+	assert.NoError(other.Write(put("b", "2"), stale))
+	assert.NoError(other.Close())
+
+	state = newMapState()
+	log, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a": "1", "c": "3"}, state.copy())
+	assert.NoError(log.Close())
+	// the log of the other database still contains the transaction
+	assert.Contains(fsys.files, "db-mj2")
+
+	state = newMapState()
+	other, err = Open(fsys, "other", state)
+	require.NoError(t, err)
+	assert.Empty(state.copy())
+	assert.NoError(other.Close())
+	assert.NotContains(fsys.files, "db-mj2", "stale super-journal must be removed")
+
+	// the transaction stays rolled back after the super-journal is gone
+	state = newMapState()
+	log, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Equal(map[string]string{"a": "1", "c": "3"}, state.copy())
+	assert.NoError(log.Close())
+}
+
+func TestOpen_Corrupt(t *testing.T) {
+	fsys := newMemFS(-1)
+
+	state := newMapState()
+	log, err := Open(fsys, "db", state)
+	require.NoError(t, err)
+	require.NoError(t, log.Write(put("a", "1")))
+	state.applyAll([]Record{put("a", "1")})
+	require.NoError(t, log.Checkpoint())
+	require.NoError(t, log.Close())
+
+	t.Run("main file", func(t *testing.T) {
+		d := fsys.files["db"]
+		d.data[len(d.data)-2] ^= 0xff
+		defer func() { d.data[len(d.data)-2] ^= 0xff }()
+
+		_, err := Open(fsys, "db", newMapState())
+		assert.True(t, errors.Is(err, ErrCorrupt), "got %v", err)
+	})
+	t.Run("log file", func(t *testing.T) {
+		d := fsys.files["db-wal"]
+		d.data[0] ^= 0xff
+		defer func() { d.data[0] ^= 0xff }()
+
+		_, err := Open(fsys, "db", newMapState())
+		assert.True(t, errors.Is(err, ErrCorrupt), "got %v", err)
+	})
+}
+
+func TestClose(t *testing.T) {
+	log, err := Open(newMemFS(-1), "db", newMapState())
+	require.NoError(t, err)
+	assert.NoError(t, log.Close())
+
+	assert.Equal(t, ErrClosed, log.Write(put("a", "1")))
+	assert.Equal(t, ErrClosed, log.Checkpoint())
+	assert.Equal(t, ErrClosed, log.Close())
+}
+
+func TestWrite_GroupCommit(t *testing.T) {
+	const (
+		writers = 8
+		batches = 25
+	)
+
+	fsys := newMemFS(-1)
+	fsys.syncDelay = time.Millisecond
+
+	log, err := Open(fsys, "db", newMapState())
+	require.NoError(t, err)
+	syncs := fsys.syncs
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				assert.NoError(t, log.Write(put(fmt.Sprintf("%d-%d", w, i), "v")))
+			}
+		}(w)
+	}
+	wg.Wait()
+	assert.NoError(t, log.Close())
+
+	assert.Less(t, fsys.syncs-syncs, writers*batches, "concurrent writes must share syncs")
+
+	state := newMapState()
+	_, err = Open(fsys, "db", state)
+	require.NoError(t, err)
+	assert.Len(t, state.copy(), writers*batches)
+}
+
+func TestOS(t *testing.T) {
+	assert := assert.New(t)
+	name := filepath.Join(t.TempDir(), "lbadd.db")
+
+	state := newMapState()
+	log, err := Open(OS, name, state)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		batch := []Record{put(fmt.Sprint(i), fmt.Sprint(i*i))}
+		assert.NoError(log.Write(batch...))
+		state.applyAll(batch)
+		if i == 5 {
+			assert.NoError(log.Checkpoint())
+		}
+	}
+	assert.NoError(log.Close())
+
+	recovered := newMapState()
+	log, err = Open(OS, name, recovered)
+	require.NoError(t, err)
+	assert.Equal(state.copy(), recovered.copy())
+	assert.NoError(log.Close())
+}
+
+// workload returns a deterministic sequence of batches.
+func workload() [][]Record {
+	rnd := rand.New(rand.NewSource(1))
+	var batches [][]Record
+	for i := 0; i < 40; i++ {
+		var batch []Record
+		for j := rnd.Intn(3); j >= 0; j-- {
+			k := fmt.Sprint(rnd.Intn(10))
+			if rnd.Intn(4) == 0 {
+				batch = append(batch, del(k))
+			} else {
+				batch = append(batch, put(k, fmt.Sprint(i)))
+			}
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// runWorkload writes the workload to a log on the given file system, with
+// a checkpoint after every 10 batches. It returns the state that contains
+// all batches that were acknowledged, and the batch that was in flight when
+// an error occurred.
+func runWorkload(fsys FileSystem) (acked *mapState, inFlight []Record) {
+	acked = newMapState()
+
+	state := newMapState()
+	log, err := Open(fsys, "db", state)
+	if err != nil {
+		return acked, nil
+	}
+
+	for i, batch := range workload() {
+		if i > 0 && i%10 == 0 {
+			if err := log.Checkpoint(); err != nil {
+				return acked, nil
+			}
+		}
+		if err := log.Write(batch...); err != nil {
+			return acked, batch
+		}
+		acked.applyAll(batch)
+		state.applyAll(batch)
+	}
+	_ = log.Close()
+	return acked, nil
+}
+
+// TestCrashRecovery crashes the process at every possible point of the
+// workload, and checks that all acknowledged batches are recovered, and
+// that the batch that was in flight is either recovered completely, or not
+// at all.
+func TestCrashRecovery(t *testing.T) {
+	full := newMemFS(-1)
+	runWorkload(full)
+	total := full.written
+
+	rnd := rand.New(rand.NewSource(1))
+	for budget := 0; budget <= total; budget++ {
+		fsys := newMemFS(budget)
+		acked, inFlight := runWorkload(fsys)
+		restarted := fsys.restart(rnd)
+
+		recovered := newMapState()
+		log, err := Open(restarted, "db", recovered)
+		if !assert.NoError(t, err, "budget %d", budget) {
+			continue
+		}
+
+		withInFlight := newMapState()
+		withInFlight.values = acked.copy()
+		withInFlight.applyAll(inFlight)
+
+		got := recovered.copy()
+		if !assert.Contains(t, []map[string]string{acked.copy(), withInFlight.copy()}, got, "budget %d", budget) {
+			continue
+		}
+
+		// the log must be usable after recovery
+		assert.NoError(t, log.Write(put("after", "crash")))
+		assert.NoError(t, log.Close())
+
+		again := newMapState()
+		_, err = Open(restarted, "db", again)
+		assert.NoError(t, err)
+		got["after"] = "crash"
+		assert.Equal(t, got, again.copy(), "budget %d", budget)
+	}
+}
@@ -0,0 +1,26 @@
+// Package transaction implements transactions on top of the write-ahead log of
+// the storage.
+//
+// A transaction buffers all of its writes, and commits them to the log as a
+// single batch, which makes the commit atomic. Before the commit, all or part
+// of the writes can be discarded by rolling back the transaction, or by rolling
+// back to one of its savepoints.
+//
+// Transactions are isolated from each other with multi-version concurrency
+// control. Every commit creates new versions of the keys that it writes, and
+// every version is valid from the commit that created it until the commit
+// that replaced or deleted it. Readers never wait for writers, since they only
+// see the versions that are visible at the time of their snapshot. How
+// snapshots are taken, and which conflicts are detected on commit, depends on
+// the Isolation of a transaction. Versions that are not visible to any active
+// transaction anymore are removed by Vacuum, or automatically, depending on
+// the AutoVacuum mode of the database. Compact additionally rebuilds the
+// database file and truncates the log, like the VACUUM statement.
+//
+// Additionally, a transaction acquires a lock on the whole database, whose
+// level depends on the Mode that the transaction was started with. The lock
+// allows to run transactions without any concurrency, if required. Within the
+// database, a transaction can lock tables and rows with Lock, which are
+// managed by a lock.Manager of the database and held until the transaction
+// ends.
+package transaction
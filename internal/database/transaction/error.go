@@ -0,0 +1,20 @@
+package transaction
+
+// Error provides constant errors to the transaction package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	// ErrConflict indicates that a transaction could not be committed,
+	// because a concurrent transaction modified data that it depends on. The
+	// transaction was rolled back and should be retried.
+	ErrConflict    = Error("could not serialize access due to a concurrent update")
+	ErrReadOnly    = Error("transaction is read-only")
+	ErrTxDone      = Error("transaction has already been committed or rolled back")
+	ErrNoSavepoint = Error("no such savepoint")
+	// ErrSameDatabase indicates that two transactions, that should be
+	// committed together, belong to the same database.
+	ErrSameDatabase = Error("transactions belong to the same database")
+)
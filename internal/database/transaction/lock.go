@@ -0,0 +1,150 @@
+package transaction
+
+import (
+	"context"
+	"sync"
+)
+
+// lockLevel is the level of the database lock that a transaction holds.
+type lockLevel uint8
+
+const (
+	unlocked lockLevel = iota
+	// shared is held by every transaction. It only conflicts with an
+	// exclusive lock.
+	shared
+	// reserved can only be held by a single transaction at the same time.
+	// It implies a shared lock.
+	reserved
+	// exclusive can only be held while no other transaction holds any lock.
+	// It implies a reserved lock.
+	exclusive
+)
+
+// dbLock is a lock on the whole database. It has the levels of a SQLite
+// database lock.
+type dbLock struct {
+	mu sync.Mutex
+	// changed is closed and replaced every time that the lock is released,
+	// to wake up all waiting transactions.
+	changed chan struct{}
+
+	// readers is the amount of transactions that hold a shared lock or
+	// higher.
+	readers int
+	// reserved indicates that a transaction holds a reserved lock or higher.
+	reserved bool
+	// pending indicates that the holder of the reserved lock waits for an
+	// exclusive lock. No new shared locks are granted while pending is set.
+	pending   bool
+	exclusive bool
+}
+
+func newDBLock() *dbLock {
+	return &dbLock{
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire acquires the given level, which implies acquiring all lower levels.
+// If the lock cannot be acquired immediately, acquire waits until it can, or
+// until the context is done.
+func (l *dbLock) acquire(ctx context.Context, level lockLevel) error {
+	if level >= reserved {
+		// acquire the reserved lock before the shared lock, so that the
+		// shared lock is not held while waiting
+		if err := l.wait(ctx, func() bool {
+			if l.reserved {
+				return false
+			}
+			l.reserved = true
+			return true
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := l.wait(ctx, func() bool {
+		if l.pending || l.exclusive {
+			return false
+		}
+		l.readers++
+		return true
+	}); err != nil {
+		if level >= reserved {
+			l.mu.Lock()
+			l.reserved = false
+			l.notify()
+			l.mu.Unlock()
+		}
+		return err
+	}
+
+	if level >= exclusive {
+		l.mu.Lock()
+		l.pending = true
+		l.mu.Unlock()
+
+		if err := l.wait(ctx, func() bool {
+			if l.readers > 1 {
+				return false
+			}
+			l.pending = false
+			l.exclusive = true
+			return true
+		}); err != nil {
+			l.mu.Lock()
+			l.pending = false
+			l.mu.Unlock()
+			// release the reserved and the shared lock
+			l.release(reserved)
+			return err
+		}
+	}
+	return nil
+}
+
+// release releases the given held level completely.
+func (l *dbLock) release(held lockLevel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if held >= exclusive {
+		l.exclusive = false
+	}
+	if held >= reserved {
+		l.reserved = false
+	}
+	if held >= shared {
+		l.readers--
+	}
+	l.notify()
+}
+
+// wait calls try with the lock held, until try returns true, or the context
+// is done.
+func (l *dbLock) wait(ctx context.Context, try func() bool) error {
+	for {
+		l.mu.Lock()
+		ok := try()
+		changed := l.changed
+		l.mu.Unlock()
+
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// notify wakes up all waiting transactions. It must be called with the lock
+// held.
+func (l *dbLock) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
@@ -0,0 +1,240 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+)
+
+// Mode is the mode that a transaction is started with. It determines the
+// level of the database lock that the transaction acquires.
+type Mode uint8
+
+const (
+	// Deferred transactions run concurrently with all other transactions,
+	// except for exclusive ones. This is the default.
+	Deferred Mode = iota
+	// Immediate transactions do not run concurrently with other immediate
+	// or exclusive transactions.
+	Immediate
+	// Exclusive transactions do not run concurrently with any other
+	// transaction. Begin waits until all other transactions have finished,
+	// and no other transaction can begin until the exclusive transaction has
+	// finished.
+	Exclusive
+)
+
+// Isolation is the isolation level of a transaction.
+type Isolation uint8
+
+const (
+	// Serializable transactions behave as if all transactions were executed
+	// one after another. In addition to the checks of Snapshot, a commit
+	// fails if any key that the transaction has read was modified since
+	// the snapshot was taken. This is the default.
+	Serializable Isolation = iota
+	// Snapshot transactions read from a snapshot that is taken when the
+	// transaction begins. If two concurrent transactions write the same
+	// key, the first one to commit wins, and the commit of the other one
+	// fails.
+	Snapshot
+	// ReadCommitted transactions take a new snapshot for every read, and
+	// therefore see all changes that were committed before the read.
+	// Concurrent writes of the same key are not detected, the last one to
+	// commit wins.
+	ReadCommitted
+)
+
+// Options are the options of a transaction.
+type Options struct {
+	Mode      Mode
+	Isolation Isolation
+	// ReadOnly transactions cannot write.
+	ReadOnly bool
+}
+
+// Manager manages the transactions on a database, which is made durable by a
+// write-ahead log.
+//
+// A Manager is safe for concurrent use by multiple goroutines. A single
+// transaction is not.
+type Manager struct {
+	fs wal.FileSystem
+	// name is the name of the database file.
+	name  string
+	log   *wal.Log
+	store *store
+	lock  *dbLock
+	// locks manages the table and row locks of the transactions.
+	locks *lock.Manager
+
+	// commitMu serializes commits, checkpoints and vacuums.
+	commitMu sync.Mutex
+
+	mu sync.Mutex
+	// clock is the timestamp of the last commit.
+	clock uint64
+	// lastID is the ID of the last transaction, that was begun.
+	lastID lock.TxID
+	// autoVacuum is the auto-vacuum mode of the database.
+	autoVacuum AutoVacuum
+	// snapshots holds the timestamps of the snapshots of all active
+	// transactions.
+	snapshots map[*Tx]uint64
+}
+
+// Open opens the database file with the given name, and recovers its
+// committed state.
+func Open(fsys wal.FileSystem, name string) (*Manager, error) {
+	store := newStore()
+	log, err := wal.Open(fsys, name, store)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+	return &Manager{
+		fs:        fsys,
+		name:      name,
+		log:       log,
+		store:     store,
+		lock:      newDBLock(),
+		locks:     lock.New(),
+		snapshots: make(map[*Tx]uint64),
+	}, nil
+}
+
+// Begin starts a new transaction with the given options. Begin waits for the
+// database lock that the mode of the transaction requires, until the context
+// is done.
+func (m *Manager) Begin(ctx context.Context, opts Options) (*Tx, error) {
+	var level lockLevel
+	switch opts.Mode {
+	case Deferred:
+		level = shared
+	case Immediate:
+		level = reserved
+	case Exclusive:
+		level = exclusive
+	default:
+		return nil, fmt.Errorf("unknown transaction mode %d", opts.Mode)
+	}
+	if opts.Isolation > ReadCommitted {
+		return nil, fmt.Errorf("unknown isolation level %d", opts.Isolation)
+	}
+
+	if err := m.lock.acquire(ctx, level); err != nil {
+		return nil, err
+	}
+
+	tx := &Tx{
+		m:     m,
+		id:    m.nextID(),
+		opts:  opts,
+		level: level,
+	}
+	if opts.Isolation == Serializable {
+		tx.reads = make(map[string]struct{})
+	}
+	tx.snapshot = m.takeSnapshot(tx)
+	return tx, nil
+}
+
+// nextID returns the ID of a new transaction. Transactions, that begin later,
+// have higher IDs, so that the youngest transaction is the victim of a
+// deadlock.
+func (m *Manager) nextID() lock.TxID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	return m.lastID
+}
+
+// takeSnapshot registers a new snapshot for the given transaction, and
+// returns its timestamp.
+func (m *Manager) takeSnapshot(tx *Tx) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.snapshots[tx] = m.clock
+	return m.clock
+}
+
+// releaseSnapshot removes the snapshot of the given transaction.
+func (m *Manager) releaseSnapshot(tx *Tx) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.snapshots, tx)
+}
+
+// Name returns the name of the database file.
+func (m *Manager) Name() string {
+	return m.name
+}
+
+// publish makes the writes of the given transaction, which are durable in the
+// log, visible to transactions that take their snapshot afterwards. The commit
+// mutex must be held.
+func (m *Manager) publish(tx *Tx, writes []wal.Record) {
+	// the snapshot of the committing transaction doesn't need to be kept
+	// for auto-vacuum
+	m.releaseSnapshot(tx)
+
+	// commits are serialized, so the clock cannot change until the commit
+	// is published below
+	m.mu.Lock()
+	ts := m.clock + 1
+	m.mu.Unlock()
+
+	m.store.commit(ts, writes)
+
+	m.mu.Lock()
+	m.clock = ts
+	full := m.autoVacuum == AutoVacuumFull
+	m.mu.Unlock()
+
+	if full {
+		m.store.prune(writes, m.horizon())
+	}
+}
+
+// Vacuum removes all versions of keys that are not visible to any active
+// transaction anymore, and returns the amount of removed versions.
+func (m *Manager) Vacuum() int {
+	m.commitMu.Lock()
+	defer m.commitMu.Unlock()
+
+	return m.store.vacuum(m.horizon(), 0)
+}
+
+// horizon returns the timestamp of the oldest snapshot that is still in use.
+// Versions that ended before the horizon are not visible anymore.
+func (m *Manager) horizon() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	horizon := m.clock
+	for _, ts := range m.snapshots {
+		if ts < horizon {
+			horizon = ts
+		}
+	}
+	return horizon
+}
+
+// Checkpoint writes all committed transactions into the database file.
+func (m *Manager) Checkpoint() error {
+	m.commitMu.Lock()
+	defer m.commitMu.Unlock()
+
+	return m.log.Checkpoint()
+}
+
+// Close closes the write-ahead log. Transactions that have not been committed
+// yet are lost.
+func (m *Manager) Close() error {
+	return m.log.Close()
+}
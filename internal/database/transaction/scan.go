@@ -0,0 +1,137 @@
+package transaction
+
+import (
+	"github.com/tomarrell/lbadd/internal/database/storage/btree"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+)
+
+// Scan calls the given function with every key from start (inclusive) to end
+// (exclusive) and its value, in ascending order of the keys, until the
+// function returns false or an error. A nil end is unbounded. The scan sees
+// the writes of the transaction. The function may write to the transaction,
+// and the scan sees those writes if they are ahead of it.
+//
+// Serializable transactions remember the scanned range, and fail to commit if
+// any key in it was modified since the snapshot was taken, which includes
+// keys that were inserted into the range.
+func (tx *Tx) Scan(start, end []byte, fn func(key, value []byte) (bool, error)) error {
+	return tx.scan(start, end, false, fn)
+}
+
+// ScanReverse is like Scan, but calls the function in descending order of the
+// keys.
+func (tx *Tx) ScanReverse(start, end []byte, fn func(key, value []byte) (bool, error)) error {
+	return tx.scan(start, end, true, fn)
+}
+
+func (tx *Tx) scan(start, end []byte, reverse bool, fn func(key, value []byte) (bool, error)) error {
+	if tx.done {
+		return ErrTxDone
+	}
+
+	r := keyRange{start: string(start), end: string(end), unbounded: end == nil}
+	if tx.opts.Isolation == ReadCommitted {
+		tx.snapshot = tx.m.takeSnapshot(tx)
+	}
+	if tx.reads != nil {
+		tx.ranges = append(tx.ranges, r)
+	}
+
+	committed := newRangeCursor(tx.m.store.tree, r, reverse)
+	var own *rangeCursor[wal.Record]
+	if tx.latest != nil {
+		own = newRangeCursor(tx.latest, r, reverse)
+	}
+	for {
+		hasCommitted, hasOwn := committed.valid(), own != nil && own.valid()
+		var (
+			key   string
+			value []byte
+			ok    bool
+		)
+		switch {
+		case !hasCommitted && !hasOwn:
+			return nil
+		case hasOwn && (!hasCommitted || own.before(committed.key())):
+			key, value, ok = own.key(), own.value().Value, own.value().Op != wal.OpDelete
+			own.next()
+		case hasOwn && own.key() == committed.key():
+			// the write of the transaction hides the committed versions
+			key, value, ok = own.key(), own.value().Value, own.value().Op != wal.OpDelete
+			own.next()
+			committed.next()
+		default:
+			key = committed.key()
+			for _, v := range committed.value() {
+				if v.visible(tx.snapshot) {
+					value, ok = v.value, true
+					break
+				}
+			}
+			committed.next()
+		}
+		if !ok {
+			continue
+		}
+
+		more, err := fn([]byte(key), append([]byte{}, value...))
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// rangeCursor iterates over the keys of a btree in a range, in either
+// direction.
+type rangeCursor[V any] struct {
+	cur     *btree.Cursor[string, V]
+	r       keyRange
+	reverse bool
+}
+
+func newRangeCursor[V any](tree btree.Btree[string, V], r keyRange, reverse bool) *rangeCursor[V] {
+	c := &rangeCursor[V]{cur: tree.Cursor(), r: r, reverse: reverse}
+	switch {
+	case !reverse:
+		c.cur.Seek(r.start)
+	case r.unbounded:
+		c.cur.Last()
+	default:
+		c.cur.Seek(r.end)
+		if c.cur.Valid() {
+			c.cur.Prev()
+		} else {
+			c.cur.Last()
+		}
+	}
+	return c
+}
+
+func (c *rangeCursor[V]) valid() bool {
+	return c.cur.Valid() && c.r.contains(c.cur.Key())
+}
+
+func (c *rangeCursor[V]) key() string {
+	return c.cur.Key()
+}
+
+func (c *rangeCursor[V]) value() V {
+	return c.cur.Value()
+}
+
+// before reports whether the current key comes before the given key in the
+// direction of the cursor.
+func (c *rangeCursor[V]) before(key string) bool {
+	if c.reverse {
+		return c.cur.Key() > key
+	}
+	return c.cur.Key() < key
+}
+
+func (c *rangeCursor[V]) next() {
+	if c.reverse {
+		c.cur.Prev()
+	} else {
+		c.cur.Next()
+	}
+}
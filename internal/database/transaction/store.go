@@ -0,0 +1,295 @@
+package transaction
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/tomarrell/lbadd/internal/database/storage/btree"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+)
+
+var _ wal.State = (*store)(nil)
+
+const (
+	// storeOrder is the order of the btree of a store.
+	storeOrder = 32
+
+	// infinity is the end of a version that has not been replaced or
+	// deleted yet.
+	infinity = math.MaxUint64
+)
+
+// version is a version of the value of a key. It is visible to all snapshots
+// from begin (inclusive) to end (exclusive).
+type version struct {
+	value      []byte
+	begin, end uint64
+}
+
+func (v version) visible(ts uint64) bool {
+	return v.begin <= ts && ts < v.end
+}
+
+// store holds the committed versions of all keys, ordered by key. The versions
+// of a key are ordered from the newest to the oldest. Slices of versions are
+// never modified after they have been put into the tree, so that they can be
+// read without synchronization.
+//
+// Only one goroutine can call commit or vacuum at the same time.
+type store struct {
+	tree btree.Btree[string, []version]
+}
+
+func newStore() *store {
+	return &store{
+		tree: newStoreTree(),
+	}
+}
+
+func newStoreTree() btree.Btree[string, []version] {
+	return btree.New[string, []version](storeOrder, func(a, b string) bool { return a < b })
+}
+
+// get returns the value of the given key, that is visible to a snapshot with
+// the given timestamp.
+func (s *store) get(key []byte, ts uint64) ([]byte, bool) {
+	versions, _ := s.tree.Get(string(key))
+	for _, v := range versions {
+		if v.visible(ts) {
+			return v.value, true
+		}
+	}
+	return nil, false
+}
+
+// modified returns the timestamp of the last commit that modified the given
+// key, or 0 if the key was not modified since the store was loaded.
+func (s *store) modified(key []byte) uint64 {
+	versions, _ := s.tree.Get(string(key))
+	return lastModified(versions)
+}
+
+// modifiedIn reports whether any key in the given range was modified by a
+// commit after the given timestamp.
+func (s *store) modifiedIn(r keyRange, ts uint64) bool {
+	cur := s.tree.Cursor()
+	for cur.Seek(r.start); cur.Valid() && r.contains(cur.Key()); cur.Next() {
+		if lastModified(cur.Value()) > ts {
+			return true
+		}
+	}
+	return false
+}
+
+// lastModified returns the timestamp of the last commit that modified the key
+// with the given versions.
+func lastModified(versions []version) uint64 {
+	if len(versions) == 0 {
+		return 0
+	}
+	newest := versions[0]
+	if newest.end != infinity {
+		return newest.end
+	}
+	return newest.begin
+}
+
+// commit applies the given records with the given commit timestamp, which
+// must be greater than all timestamps that were committed before.
+func (s *store) commit(ts uint64, records []wal.Record) {
+	for _, rec := range records {
+		old, _ := s.tree.Get(string(rec.Key))
+		current := len(old) > 0 && old[0].end == infinity
+		if rec.Op == wal.OpDelete && !current {
+			continue
+		}
+
+		versions := make([]version, 0, len(old)+1)
+		if rec.Op == wal.OpPut {
+			versions = append(versions, version{
+				value: rec.Value,
+				begin: ts,
+				end:   infinity,
+			})
+		}
+		versions = append(versions, old...)
+		if current {
+			versions[len(versions)-len(old)].end = ts
+		}
+		s.tree.Put(string(rec.Key), versions)
+	}
+}
+
+// vacuum removes all versions that are not visible to any snapshot with a
+// timestamp of at least the given horizon. If limit is positive, at most limit
+// versions are removed. It returns the amount of removed versions.
+func (s *store) vacuum(horizon uint64, limit int) int {
+	type update struct {
+		key      string
+		versions []version
+	}
+
+	var updates []update
+	removed := 0
+	cur := s.tree.Cursor()
+	for cur.First(); cur.Valid(); cur.Next() {
+		old := cur.Value()
+		if limit > 0 && removed >= limit {
+			break
+		}
+		versions := visible(old, horizon, limit-removed)
+		if len(versions) < len(old) {
+			removed += len(old) - len(versions)
+			updates = append(updates, update{cur.Key(), versions})
+		}
+	}
+
+	for _, u := range updates {
+		if len(u.versions) == 0 {
+			s.tree.Delete(u.key)
+		} else {
+			s.tree.Put(u.key, u.versions)
+		}
+	}
+	return removed
+}
+
+// prune removes the versions of the keys of the given records, that are not
+// visible to any snapshot with a timestamp of at least the given horizon.
+func (s *store) prune(records []wal.Record, horizon uint64) {
+	for _, rec := range records {
+		old, ok := s.tree.Get(string(rec.Key))
+		if !ok {
+			continue
+		}
+		versions := visible(old, horizon, 0)
+		switch {
+		case len(versions) == len(old):
+		case len(versions) == 0:
+			s.tree.Delete(string(rec.Key))
+		default:
+			s.tree.Put(string(rec.Key), versions)
+		}
+	}
+}
+
+// visible returns the versions that are visible to a snapshot with a
+// timestamp of at least the given horizon. If limit is positive, at most
+// limit of the other versions are dropped, starting with the oldest ones.
+func visible(versions []version, horizon uint64, limit int) []version {
+	var result []version
+	dropped := 0
+	for i := len(versions) - 1; i >= 0; i-- {
+		if v := versions[i]; v.end <= horizon && (limit <= 0 || dropped < limit) {
+			dropped++
+			continue
+		}
+		result = append(result, versions[i])
+	}
+	// restore the order from the newest to the oldest version
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// rebuild replaces the tree with a new one, that is bulk loaded with the
+// same entries. The nodes of the new tree are filled completely, which undoes
+// the fragmentation that deletes leave behind. No other goroutine may access
+// the store during rebuild.
+func (s *store) rebuild() error {
+	var keys []string
+	var values [][]version
+	cur := s.tree.Cursor()
+	for cur.First(); cur.Valid(); cur.Next() {
+		keys = append(keys, cur.Key())
+		values = append(values, cur.Value())
+	}
+
+	tree, err := btree.Load(storeOrder, func(a, b string) bool { return a < b }, keys, values)
+	if err != nil {
+		return err
+	}
+	s.tree = tree
+	return nil
+}
+
+// Load reads a snapshot, which is a sequence of keys and values, each prefixed
+// with its length. All loaded values are visible to all snapshots.
+func (s *store) Load(r io.Reader) error {
+	br := bufio.NewReader(r)
+	tree := newStoreTree()
+	for {
+		key, err := readBytes(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read key: %w", err)
+		}
+		value, err := readBytes(br)
+		if err != nil {
+			return fmt.Errorf("read value: %w", err)
+		}
+		tree.Put(string(key), []version{{value: value, begin: 0, end: infinity}})
+	}
+	s.tree = tree
+	return nil
+}
+
+// Snapshot writes the current values of all keys in ascending order of the
+// keys.
+func (s *store) Snapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+	write := func(p []byte) {
+		n := binary.PutUvarint(buf[:], uint64(len(p)))
+		_, _ = bw.Write(buf[:n])
+		_, _ = bw.Write(p)
+	}
+
+	cur := s.tree.Cursor()
+	for cur.First(); cur.Valid(); cur.Next() {
+		if newest := cur.Value()[0]; newest.end == infinity {
+			write([]byte(cur.Key()))
+			write(newest.value)
+		}
+	}
+	// errors of the writes above are returned by Flush
+	return bw.Flush()
+}
+
+// Apply applies a record that is recovered from the log. The result is
+// visible to all snapshots.
+func (s *store) Apply(rec wal.Record) error {
+	switch rec.Op {
+	case wal.OpPut:
+		s.tree.Put(string(rec.Key), []version{{value: rec.Value, begin: 0, end: infinity}})
+	case wal.OpDelete:
+		s.tree.Delete(string(rec.Key))
+	default:
+		return fmt.Errorf("unknown op %d", rec.Op)
+	}
+	return nil
+}
+
+// readBytes reads a byte slice that is prefixed with its length. If the reader
+// is empty, io.EOF is returned. If the reader ends in the middle of the slice,
+// io.ErrUnexpectedEOF is returned.
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	p := make([]byte, n)
+	if _, err := io.ReadFull(r, p); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,112 @@
+package transaction
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+)
+
+// CommitAll commits the given transactions of different databases
+// atomically, like a single transaction. Either the writes of all
+// transactions become durable and visible, or none of them.
+//
+// If more than one of the transactions has writes, a super-journal with the
+// given name is created in the given file system, which must be the file
+// system of the databases. The name must be unique, for example by containing
+// a random suffix. The commit is complete when the super-journal is removed.
+// If the commit fails before the super-journal is removed, the super-journal
+// is kept, unless all databases could discard the writes, and the databases
+// roll back the transaction when they are recovered. If the super-journal
+// cannot be removed, the outcome of the commit is unknown, and the databases
+// can only be used again after they were closed and opened, which commits the
+// transaction if the super-journal is gone, and rolls it back otherwise.
+//
+// All transactions are finished, even if the commit fails.
+func CommitAll(fsys wal.FileSystem, super string, txs ...*Tx) error {
+	for _, tx := range txs {
+		if tx.done {
+			return ErrTxDone
+		}
+	}
+
+	var writing []*Tx
+	for _, tx := range txs {
+		if len(tx.writes) == 0 {
+			tx.finish()
+		} else {
+			writing = append(writing, tx)
+		}
+	}
+	if len(writing) <= 1 {
+		for _, tx := range writing {
+			return tx.Commit()
+		}
+		return nil
+	}
+
+	for _, tx := range writing {
+		defer tx.finish()
+	}
+
+	// lock the databases in the order of their names, so that concurrent
+	// commits of the same databases cannot deadlock
+	sort.Slice(writing, func(i, j int) bool { return writing[i].m.name < writing[j].m.name })
+	for i, tx := range writing {
+		if i > 0 && tx.m == writing[i-1].m {
+			return fmt.Errorf("%w: %v", ErrSameDatabase, tx.m.name)
+		}
+	}
+	for _, tx := range writing {
+		tx.m.commitMu.Lock()
+		defer tx.m.commitMu.Unlock()
+	}
+
+	writes := make([][]wal.Record, len(writing))
+	names := make([]string, len(writing))
+	for i, tx := range writing {
+		writes[i] = tx.compact()
+		if err := tx.validate(writes[i]); err != nil {
+			return err
+		}
+		names[i] = tx.m.name
+	}
+
+	if err := wal.WriteSuper(fsys, super, names); err != nil {
+		return fmt.Errorf("write super-journal: %w", err)
+	}
+	superRecord := wal.Record{Op: wal.OpSuper, Key: []byte(super)}
+	for i, tx := range writing {
+		if err := tx.m.log.Write(append(writes[i], superRecord)...); err != nil {
+			discard(fsys, super, writing[:i+1])
+			return fmt.Errorf("write log: %w", err)
+		}
+	}
+	if err := fsys.Remove(super); err != nil {
+		// It is unknown whether the super-journal still exists, and with it,
+		// whether the transaction is committed. This is decided by the
+		// recovery of the databases, which must be opened again.
+		err = fmt.Errorf("remove super-journal: %w", err)
+		for _, tx := range writing {
+			tx.m.log.Fail(err)
+		}
+		return err
+	}
+
+	for i, tx := range writing {
+		tx.m.publish(tx, writes[i])
+	}
+	return nil
+}
+
+// discard removes the batches of a failed commit from the logs of the given
+// transactions, by checkpointing them, since the batches have not been
+// applied to the stores. If that succeeds, the super-journal is removed.
+func discard(fsys wal.FileSystem, super string, txs []*Tx) {
+	for _, tx := range txs {
+		if err := tx.m.log.Checkpoint(); err != nil {
+			return
+		}
+	}
+	_ = fsys.Remove(super)
+}
@@ -0,0 +1,298 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/storage/btree"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+)
+
+// Tx is a transaction. All writes of a transaction are buffered until it is
+// committed, and can be rolled back until then. A transaction always sees its
+// own writes.
+//
+// A Tx is not safe for concurrent use.
+type Tx struct {
+	m *Manager
+	// id identifies the transaction in the lock manager of the database.
+	id   lock.TxID
+	opts Options
+	// level is the level of the database lock that this transaction holds.
+	level lockLevel
+	// snapshot is the timestamp of the snapshot that this transaction reads
+	// from.
+	snapshot uint64
+	// reads holds all keys that this transaction has read from the store.
+	// It is only tracked for serializable transactions.
+	reads map[string]struct{}
+	// ranges holds all key ranges that this transaction has scanned in the
+	// store. Like reads, it is only tracked for serializable transactions.
+	ranges []keyRange
+	// writes are the buffered writes of this transaction, in the order in
+	// which they were made, and latest holds the last write of every key,
+	// ordered by key.
+	writes     []wal.Record
+	latest     btree.Btree[string, wal.Record]
+	savepoints []savepoint
+	done       bool
+}
+
+// keyRange is a range of keys from start (inclusive) to end (exclusive). A
+// nil end is unbounded.
+type keyRange struct {
+	start, end string
+	unbounded  bool
+}
+
+func (r keyRange) contains(key string) bool {
+	return r.start <= key && (r.unbounded || key < r.end)
+}
+
+// savepoint marks a position in the writes of a transaction, which the
+// transaction can be rolled back to.
+type savepoint struct {
+	name string
+	// writes is the amount of writes that the transaction had made when the
+	// savepoint was created.
+	writes int
+}
+
+// Get returns the value of the given key. If the key does not exist, false is
+// returned. The returned value may be modified by the caller.
+func (tx *Tx) Get(key []byte) ([]byte, bool, error) {
+	if tx.done {
+		return nil, false, ErrTxDone
+	}
+
+	if rec, ok := tx.written(string(key)); ok {
+		if rec.Op == wal.OpDelete {
+			return nil, false, nil
+		}
+		return append([]byte{}, rec.Value...), true, nil
+	}
+
+	if tx.opts.Isolation == ReadCommitted {
+		tx.snapshot = tx.m.takeSnapshot(tx)
+	}
+	if tx.reads != nil {
+		tx.reads[string(key)] = struct{}{}
+	}
+
+	value, ok := tx.m.store.get(key, tx.snapshot)
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte{}, value...), true, nil
+}
+
+// Put sets the value of the given key.
+func (tx *Tx) Put(key, value []byte) error {
+	return tx.write(wal.Record{
+		Op:    wal.OpPut,
+		Key:   append([]byte{}, key...),
+		Value: append([]byte{}, value...),
+	})
+}
+
+// Delete deletes the given key. Deleting a key that does not exist is not an
+// error.
+func (tx *Tx) Delete(key []byte) error {
+	return tx.write(wal.Record{
+		Op:  wal.OpDelete,
+		Key: append([]byte{}, key...),
+	})
+}
+
+// Lock acquires the given table or row lock with the given mode, like
+// (*lock.Manager).Lock. It waits until the lock is granted, until the context
+// is done, or until the transaction is chosen as the victim of a deadlock, in
+// which case lock.ErrDeadlock is returned. The locks are held until the
+// transaction is committed or rolled back.
+func (tx *Tx) Lock(ctx context.Context, res lock.Resource, mode lock.Mode) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if tx.opts.ReadOnly && !lock.Shared.Covers(mode) {
+		return ErrReadOnly
+	}
+	return tx.m.locks.Lock(ctx, tx.id, res, mode)
+}
+
+func (tx *Tx) write(rec wal.Record) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if tx.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	tx.writes = append(tx.writes, rec)
+	if tx.latest == nil {
+		tx.latest = newWriteTree()
+	}
+	tx.latest.Put(string(rec.Key), rec)
+	return nil
+}
+
+// written returns the last write of the given key, if this transaction has
+// written it.
+func (tx *Tx) written(key string) (wal.Record, bool) {
+	if tx.latest == nil {
+		return wal.Record{}, false
+	}
+	return tx.latest.Get(key)
+}
+
+func newWriteTree() btree.Btree[string, wal.Record] {
+	return btree.New[string, wal.Record](storeOrder, func(a, b string) bool { return a < b })
+}
+
+// Savepoint creates a savepoint with the given name. Savepoints are nested,
+// and names do not need to be unique. Names are case insensitive.
+func (tx *Tx) Savepoint(name string) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.savepoints = append(tx.savepoints, savepoint{
+		name:   name,
+		writes: len(tx.writes),
+	})
+	return nil
+}
+
+// Release removes the most recent savepoint with the given name, and all
+// savepoints that were created after it. The writes that were made after the
+// savepoint are kept.
+func (tx *Tx) Release(name string) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	i, err := tx.savepoint(name)
+	if err != nil {
+		return err
+	}
+	tx.savepoints = tx.savepoints[:i]
+	return nil
+}
+
+// RollbackTo discards all writes that were made after the most recent
+// savepoint with the given name, and removes all savepoints that were
+// created after it. The savepoint itself is kept, so that the transaction can
+// be rolled back to it again.
+func (tx *Tx) RollbackTo(name string) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	i, err := tx.savepoint(name)
+	if err != nil {
+		return err
+	}
+	tx.writes = tx.writes[:tx.savepoints[i].writes]
+	tx.savepoints = tx.savepoints[:i+1]
+	tx.latest = newWriteTree()
+	for _, rec := range tx.writes {
+		tx.latest.Put(string(rec.Key), rec)
+	}
+	return nil
+}
+
+func (tx *Tx) savepoint(name string) (int, error) {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if strings.EqualFold(tx.savepoints[i].name, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %v", ErrNoSavepoint, name)
+}
+
+// Commit makes all writes of this transaction durable and visible to other
+// transactions, atomically. If the transaction conflicts with a transaction
+// that committed since its snapshot was taken, ErrConflict is returned. If
+// the commit fails, the transaction is rolled back.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	defer tx.finish()
+
+	if len(tx.writes) == 0 {
+		return nil
+	}
+
+	tx.m.commitMu.Lock()
+	defer tx.m.commitMu.Unlock()
+
+	writes := tx.compact()
+	if err := tx.validate(writes); err != nil {
+		return err
+	}
+	if err := tx.m.log.Write(writes...); err != nil {
+		return fmt.Errorf("write log: %w", err)
+	}
+	tx.m.publish(tx, writes)
+	return nil
+}
+
+// compact returns the last write of every key, in the order in which they were
+// made.
+func (tx *Tx) compact() []wal.Record {
+	last := make(map[string]int, len(tx.writes))
+	for i, rec := range tx.writes {
+		last[string(rec.Key)] = i
+	}
+	writes := make([]wal.Record, 0, len(last))
+	for i, rec := range tx.writes {
+		if last[string(rec.Key)] == i {
+			writes = append(writes, rec)
+		}
+	}
+	return writes
+}
+
+// validate checks whether any key that this transaction depends on was
+// modified by a commit after the snapshot of this transaction was taken.
+func (tx *Tx) validate(writes []wal.Record) error {
+	if tx.opts.Isolation == ReadCommitted {
+		return nil
+	}
+	for _, rec := range writes {
+		if tx.m.store.modified(rec.Key) > tx.snapshot {
+			return ErrConflict
+		}
+	}
+	for key := range tx.reads {
+		if tx.m.store.modified([]byte(key)) > tx.snapshot {
+			return ErrConflict
+		}
+	}
+	for _, r := range tx.ranges {
+		if tx.m.store.modifiedIn(r, tx.snapshot) {
+			return ErrConflict
+		}
+	}
+	return nil
+}
+
+// Rollback discards all writes of this transaction.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.finish()
+	return nil
+}
+
+func (tx *Tx) finish() {
+	tx.m.releaseSnapshot(tx)
+	tx.m.lock.release(tx.level)
+	tx.m.locks.ReleaseAll(tx.id)
+	tx.level = unlocked
+	tx.reads = nil
+	tx.ranges = nil
+	tx.writes = nil
+	tx.latest = nil
+	tx.savepoints = nil
+	tx.done = true
+}
@@ -0,0 +1,799 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+)
+
+func openTestManager(t *testing.T) (*Manager, string) {
+	name := filepath.Join(t.TempDir(), "lbadd.db")
+	m, err := Open(wal.OS, name)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = m.Close() })
+	return m, name
+}
+
+func timeout(t *testing.T, d time.Duration) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func begin(t *testing.T, m *Manager, opts Options) *Tx {
+	tx, err := m.Begin(context.Background(), opts)
+	require.NoError(t, err)
+	return tx
+}
+
+func put(t *testing.T, tx *Tx, key, value string) {
+	t.Helper()
+	assert.NoError(t, tx.Put([]byte(key), []byte(value)))
+}
+
+func assertValue(t *testing.T, tx *Tx, key, want string) {
+	t.Helper()
+	value, ok, err := tx.Get([]byte(key))
+	assert.NoError(t, err)
+	if want == "" {
+		assert.False(t, ok, "key %q must not exist", key)
+		return
+	}
+	assert.True(t, ok, "key %q must exist", key)
+	assert.Equal(t, want, string(value))
+}
+
+func TestCommit(t *testing.T) {
+	m, name := openTestManager(t)
+
+	tx := begin(t, m, Options{})
+	put(t, tx, "a", "1")
+	put(t, tx, "b", "2")
+	assert.NoError(t, tx.Delete([]byte("b")))
+	assertValue(t, tx, "a", "1")
+	assertValue(t, tx, "b", "")
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, ErrTxDone, tx.Commit())
+	assert.NoError(t, m.Close())
+
+	m, err := Open(wal.OS, name)
+	require.NoError(t, err)
+	defer func() { _ = m.Close() }()
+
+	tx = begin(t, m, Options{})
+	assertValue(t, tx, "a", "1")
+	assertValue(t, tx, "b", "")
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestRollback(t *testing.T) {
+	m, _ := openTestManager(t)
+
+	tx := begin(t, m, Options{})
+	put(t, tx, "a", "1")
+	assert.NoError(t, tx.Rollback())
+	assert.Equal(t, ErrTxDone, tx.Rollback())
+	assert.Equal(t, ErrTxDone, tx.Put([]byte("a"), []byte("1")))
+
+	tx = begin(t, m, Options{})
+	assertValue(t, tx, "a", "")
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestReadOnly(t *testing.T) {
+	m, _ := openTestManager(t)
+
+	tx := begin(t, m, Options{ReadOnly: true})
+	assert.Equal(t, ErrReadOnly, tx.Put([]byte("a"), []byte("1")))
+	assert.Equal(t, ErrReadOnly, tx.Delete([]byte("a")))
+	assertValue(t, tx, "a", "")
+	assert.NoError(t, tx.Lock(context.Background(), lock.Resource{Table: "t", Row: "1"}, lock.Shared))
+	assert.Equal(t, ErrReadOnly, tx.Lock(context.Background(), lock.Resource{Table: "t", Row: "1"}, lock.Exclusive))
+	assert.NoError(t, tx.Commit())
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	m, _ := openTestManager(t)
+	a := lock.Resource{Table: "t", Row: "a"}
+	b := lock.Resource{Table: "t", Row: "b"}
+
+	tx1 := begin(t, m, Options{})
+	tx2 := begin(t, m, Options{})
+	require.NoError(t, tx1.Lock(ctx, a, lock.Exclusive))
+	require.NoError(t, tx2.Lock(ctx, b, lock.Exclusive))
+
+	// waiting stops at the deadline of the context
+	err := tx2.Lock(timeout(t, 20*time.Millisecond), a, lock.Exclusive)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+
+	// the younger transaction is the victim of the deadlock, no matter which
+	// one starts to wait first
+	locked := make(chan error)
+	go func() { locked <- tx1.Lock(ctx, b, lock.Exclusive) }()
+	err = tx2.Lock(ctx, a, lock.Exclusive)
+	assert.True(t, errors.Is(err, lock.ErrDeadlock), "%v", err)
+
+	// the locks are released when the transaction ends
+	require.NoError(t, tx2.Rollback())
+	require.NoError(t, <-locked)
+	assert.Equal(t, lock.Exclusive, m.locks.Mode(tx1.id, b))
+	require.NoError(t, tx1.Commit())
+	assert.Equal(t, lock.None, m.locks.Mode(tx1.id, a))
+	assert.Equal(t, ErrTxDone, tx1.Lock(ctx, a, lock.Shared))
+}
+
+func TestSavepoint(t *testing.T) {
+	m, _ := openTestManager(t)
+
+	tx := begin(t, m, Options{})
+	put(t, tx, "a", "1")
+	assert.NoError(t, tx.Savepoint("one"))
+	put(t, tx, "a", "2")
+	assert.NoError(t, tx.Savepoint("two"))
+	put(t, tx, "b", "1")
+	assert.NoError(t, tx.Savepoint("three"))
+	put(t, tx, "c", "1")
+
+	// rolling back to a savepoint keeps the savepoint, but removes all
+	// newer ones
+	assert.NoError(t, tx.RollbackTo("TWO"))
+	assertValue(t, tx, "a", "2")
+	assertValue(t, tx, "b", "")
+	assertValue(t, tx, "c", "")
+	assert.True(t, errors.Is(tx.RollbackTo("three"), ErrNoSavepoint))
+
+	put(t, tx, "b", "2")
+	assert.NoError(t, tx.RollbackTo("two"))
+	assertValue(t, tx, "b", "")
+
+	// releasing a savepoint keeps the writes
+	put(t, tx, "b", "3")
+	assert.NoError(t, tx.Release("two"))
+	assertValue(t, tx, "b", "3")
+	assert.True(t, errors.Is(tx.Release("two"), ErrNoSavepoint))
+
+	assert.NoError(t, tx.RollbackTo("one"))
+	assertValue(t, tx, "a", "1")
+	assertValue(t, tx, "b", "")
+	assert.NoError(t, tx.Commit())
+
+	tx = begin(t, m, Options{})
+	assertValue(t, tx, "a", "1")
+	assertValue(t, tx, "b", "")
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestSavepoint_SameName(t *testing.T) {
+	m, _ := openTestManager(t)
+
+	tx := begin(t, m, Options{})
+	defer func() { _ = tx.Rollback() }()
+
+	assert.NoError(t, tx.Savepoint("sp"))
+	put(t, tx, "a", "1")
+	assert.NoError(t, tx.Savepoint("sp"))
+	put(t, tx, "a", "2")
+
+	// the most recent savepoint is used
+	assert.NoError(t, tx.RollbackTo("sp"))
+	assertValue(t, tx, "a", "1")
+	assert.NoError(t, tx.Release("sp"))
+	assert.NoError(t, tx.RollbackTo("sp"))
+	assertValue(t, tx, "a", "")
+}
+
+func TestIsolation(t *testing.T) {
+	tests := []struct {
+		isolation Isolation
+		// repeatable indicates that a transaction does not see commits
+		// that happened after its snapshot was taken.
+		repeatable bool
+		// writeConflict indicates that concurrent writes of the same key
+		// are detected.
+		writeConflict bool
+		// readConflict indicates that a write of a key that was read
+		// concurrently is detected.
+		readConflict bool
+	}{
+		{ReadCommitted, false, false, false},
+		{Snapshot, true, true, false},
+		{Serializable, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(int(tt.isolation)), func(t *testing.T) {
+			m, _ := openTestManager(t)
+			setup := begin(t, m, Options{})
+			put(t, setup, "a", "0")
+			put(t, setup, "b", "0")
+			assert.NoError(t, setup.Commit())
+
+			t.Run("uncommitted", func(t *testing.T) {
+				writer := begin(t, m, Options{})
+				put(t, writer, "a", "1")
+				reader := begin(t, m, Options{Isolation: tt.isolation})
+				assertValue(t, reader, "a", "0")
+				assert.NoError(t, writer.Rollback())
+				assert.NoError(t, reader.Rollback())
+			})
+			t.Run("repeatable", func(t *testing.T) {
+				reader := begin(t, m, Options{Isolation: tt.isolation})
+				assertValue(t, reader, "a", "0")
+
+				writer := begin(t, m, Options{})
+				put(t, writer, "a", "1")
+				assert.NoError(t, writer.Commit())
+
+				if tt.repeatable {
+					assertValue(t, reader, "a", "0")
+				} else {
+					assertValue(t, reader, "a", "1")
+				}
+				assert.NoError(t, reader.Rollback())
+			})
+			t.Run("write conflict", func(t *testing.T) {
+				first := begin(t, m, Options{Isolation: tt.isolation})
+				second := begin(t, m, Options{Isolation: tt.isolation})
+				put(t, first, "b", "first")
+				put(t, second, "b", "second")
+				assert.NoError(t, first.Commit())
+
+				err := second.Commit()
+				if tt.writeConflict {
+					assert.Equal(t, ErrConflict, err)
+					assert.Equal(t, ErrTxDone, second.Rollback(), "transaction must be rolled back")
+				} else {
+					assert.NoError(t, err)
+				}
+			})
+			t.Run("read conflict", func(t *testing.T) {
+				// write skew: both transactions read both keys, and
+				// write one of them
+				first := begin(t, m, Options{Isolation: tt.isolation})
+				second := begin(t, m, Options{Isolation: tt.isolation})
+				for _, tx := range []*Tx{first, second} {
+					_, _, err := tx.Get([]byte("a"))
+					assert.NoError(t, err)
+					_, _, err = tx.Get([]byte("b"))
+					assert.NoError(t, err)
+				}
+				put(t, first, "a", "skew")
+				put(t, second, "b", "skew")
+				assert.NoError(t, first.Commit())
+
+				err := second.Commit()
+				if tt.readConflict {
+					assert.Equal(t, ErrConflict, err)
+				} else {
+					assert.NoError(t, err)
+				}
+			})
+		})
+	}
+}
+
+func scanKeys(t *testing.T, tx *Tx, start, end string, reverse bool) []string {
+	t.Helper()
+	var endKey []byte
+	if end != "" {
+		endKey = []byte(end)
+	}
+	scan := tx.Scan
+	if reverse {
+		scan = tx.ScanReverse
+	}
+	var keys []string
+	assert.NoError(t, scan([]byte(start), endKey, func(key, value []byte) (bool, error) {
+		keys = append(keys, string(key)+"="+string(value))
+		return true, nil
+	}))
+	return keys
+}
+
+func TestScan(t *testing.T) {
+	m, _ := openTestManager(t)
+	setup := begin(t, m, Options{})
+	for _, key := range []string{"a", "b", "c", "d"} {
+		put(t, setup, key, "0")
+	}
+	assert.NoError(t, setup.Commit())
+
+	tx := begin(t, m, Options{Isolation: Snapshot})
+	put(t, tx, "b", "1")
+	put(t, tx, "bb", "1")
+	assert.NoError(t, tx.Delete([]byte("c")))
+
+	other := begin(t, m, Options{})
+	put(t, other, "a", "2")
+	put(t, other, "e", "2")
+	assert.NoError(t, other.Commit())
+
+	assert.Equal(t, []string{"a=0", "b=1", "bb=1", "d=0"}, scanKeys(t, tx, "", "", false))
+	assert.Equal(t, []string{"d=0", "bb=1", "b=1", "a=0"}, scanKeys(t, tx, "", "", true))
+	assert.Equal(t, []string{"b=1", "bb=1"}, scanKeys(t, tx, "b", "c", false))
+	assert.Equal(t, []string{"bb=1", "b=1"}, scanKeys(t, tx, "b", "c", true))
+	assert.Equal(t, []string{"d=0"}, scanKeys(t, tx, "c", "", true))
+
+	assert.NoError(t, tx.Savepoint("s"))
+	put(t, tx, "c", "3")
+	assert.Equal(t, []string{"b=1", "bb=1", "c=3"}, scanKeys(t, tx, "b", "d", false))
+	assert.NoError(t, tx.RollbackTo("s"))
+	assert.Equal(t, []string{"b=1", "bb=1"}, scanKeys(t, tx, "b", "d", false))
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestScan_Phantom(t *testing.T) {
+	tests := []struct {
+		isolation Isolation
+		conflict  bool
+	}{
+		{Snapshot, false},
+		{Serializable, true},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(int(tt.isolation)), func(t *testing.T) {
+			m, _ := openTestManager(t)
+
+			// the reader scans a range, that a concurrent transaction
+			// inserts a key into
+			reader := begin(t, m, Options{Isolation: tt.isolation})
+			assert.Empty(t, scanKeys(t, reader, "k", "l", false))
+			put(t, reader, "count", "0")
+
+			writer := begin(t, m, Options{})
+			put(t, writer, "k1", "v")
+			assert.NoError(t, writer.Commit())
+
+			err := reader.Commit()
+			if tt.conflict {
+				assert.Equal(t, ErrConflict, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBegin_Mode(t *testing.T) {
+	ctx := context.Background()
+	m, _ := openTestManager(t)
+
+	t.Run("immediate", func(t *testing.T) {
+		tx := begin(t, m, Options{Mode: Immediate})
+
+		_, err := m.Begin(timeout(t, 10*time.Millisecond), Options{Mode: Immediate})
+		assert.Equal(t, context.DeadlineExceeded, err)
+
+		// deferred transactions are not blocked
+		reader := begin(t, m, Options{})
+		assert.NoError(t, reader.Rollback())
+
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, begin(t, m, Options{Mode: Immediate}).Rollback())
+	})
+	t.Run("exclusive", func(t *testing.T) {
+		reader := begin(t, m, Options{})
+
+		// an exclusive transaction waits for all other transactions
+		_, err := m.Begin(timeout(t, 10*time.Millisecond), Options{Mode: Exclusive})
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.NoError(t, reader.Rollback())
+
+		tx := begin(t, m, Options{Mode: Exclusive})
+		_, err = m.Begin(timeout(t, 10*time.Millisecond), Options{})
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.NoError(t, tx.Rollback())
+
+		// the lock is released after the rollback
+		assert.NoError(t, begin(t, m, Options{}).Rollback())
+	})
+	t.Run("unknown", func(t *testing.T) {
+		_, err := m.Begin(ctx, Options{Mode: Mode(42)})
+		assert.Error(t, err)
+		_, err = m.Begin(ctx, Options{Isolation: Isolation(42)})
+		assert.Error(t, err)
+	})
+}
+
+func TestVacuum(t *testing.T) {
+	m, _ := openTestManager(t)
+
+	for i := 0; i < 3; i++ {
+		tx := begin(t, m, Options{})
+		put(t, tx, "a", strconv.Itoa(i))
+		put(t, tx, "b", strconv.Itoa(i))
+		assert.NoError(t, tx.Commit())
+	}
+
+	// the old versions are still visible to the reader
+	reader := begin(t, m, Options{Isolation: Snapshot})
+	tx := begin(t, m, Options{})
+	put(t, tx, "a", "3")
+	assert.NoError(t, tx.Delete([]byte("b")))
+	assert.NoError(t, tx.Commit())
+
+	assert.Equal(t, 4, m.Vacuum())
+	assertValue(t, reader, "a", "2")
+	assertValue(t, reader, "b", "2")
+	assert.NoError(t, reader.Rollback())
+
+	assert.Equal(t, 2, m.Vacuum())
+	assert.Equal(t, 0, m.Vacuum())
+
+	tx = begin(t, m, Options{})
+	assertValue(t, tx, "a", "3")
+	assertValue(t, tx, "b", "")
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestConcurrentTransactions(t *testing.T) {
+	const (
+		workers    = 8
+		increments = 20
+	)
+
+	m, _ := openTestManager(t)
+
+	increment := func(opts Options) error {
+		tx, err := m.Begin(context.Background(), opts)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		value, _, err := tx.Get([]byte("counter"))
+		if err != nil {
+			return err
+		}
+		n, _ := strconv.Atoi(string(value))
+		if err := tx.Put([]byte("counter"), []byte(strconv.Itoa(n+1))); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			opts := Options{
+				Mode:      Mode(w % 3),
+				Isolation: Isolation(w % 2), // lost updates are possible with ReadCommitted
+			}
+			for i := 0; i < increments; i++ {
+				err := increment(opts)
+				for err == ErrConflict {
+					err = increment(opts)
+				}
+				assert.NoError(t, err)
+			}
+			if w%4 == 0 {
+				m.Vacuum()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	tx := begin(t, m, Options{})
+	assertValue(t, tx, "counter", strconv.Itoa(workers*increments))
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestCheckpoint(t *testing.T) {
+	m, name := openTestManager(t)
+
+	for i := 0; i < 5; i++ {
+		tx := begin(t, m, Options{})
+		put(t, tx, strconv.Itoa(i), "v")
+		assert.NoError(t, tx.Commit())
+	}
+	assert.NoError(t, m.Checkpoint())
+	assert.NoError(t, m.Close())
+
+	m, err := Open(wal.OS, name)
+	require.NoError(t, err)
+	defer func() { _ = m.Close() }()
+
+	tx := begin(t, m, Options{})
+	for i := 0; i < 5; i++ {
+		assertValue(t, tx, strconv.Itoa(i), "v")
+	}
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestCommitAll(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{filepath.Join(dir, "main.db"), filepath.Join(dir, "aux.db")}
+	open := func() (*Manager, *Manager) {
+		main, err := Open(wal.OS, names[0])
+		require.NoError(t, err)
+		aux, err := Open(wal.OS, names[1])
+		require.NoError(t, err)
+		return main, aux
+	}
+	main, aux := open()
+
+	tx1, tx2 := begin(t, main, Options{}), begin(t, aux, Options{})
+	put(t, tx1, "a", "1")
+	put(t, tx2, "b", "2")
+	super := filepath.Join(dir, "main.db-mj1")
+	assert.NoError(t, CommitAll(wal.OS, super, tx1, tx2))
+	assert.Equal(t, ErrTxDone, CommitAll(wal.OS, super, tx1, tx2))
+	_, err := os.Stat(super)
+	assert.True(t, os.IsNotExist(err), "super-journal must be removed")
+
+	tx1, tx2 = begin(t, main, Options{}), begin(t, main, Options{})
+	put(t, tx1, "c", "3")
+	put(t, tx2, "d", "4")
+	assert.True(t, errors.Is(CommitAll(wal.OS, super, tx1, tx2), ErrSameDatabase))
+	assert.NoError(t, main.Close())
+	assert.NoError(t, aux.Close())
+
+	main, aux = open()
+	defer func() { _ = main.Close() }()
+	defer func() { _ = aux.Close() }()
+	tx1, tx2 = begin(t, main, Options{}), begin(t, aux, Options{})
+	assertValue(t, tx1, "a", "1")
+	assertValue(t, tx1, "c", "")
+	assertValue(t, tx2, "b", "2")
+	// a transaction without writes needs no super-journal
+	put(t, tx2, "e", "5")
+	assert.NoError(t, CommitAll(wal.OS, super, tx1, tx2))
+
+	tx2 = begin(t, aux, Options{})
+	assertValue(t, tx2, "e", "5")
+	assert.NoError(t, tx2.Rollback())
+}
+
+// crashFS is the file system of the operating system, that simulates a crash
+// of the process while a transaction is committed with CommitAll. After the
+// crash, all operations fail, also on files that were opened before.
+type crashFS struct {
+	// at is the point of the commit at which the process crashes.
+	at crashPoint
+	// armed is set when the super-journal was written.
+	armed   bool
+	crashed bool
+}
+
+type crashPoint uint8
+
+const (
+	// afterWriteSuper crashes after the super-journal was written.
+	afterWriteSuper crashPoint = iota
+	// afterWriteLog crashes after the batch was written to the first log.
+	afterWriteLog
+	// beforeRemoveSuper crashes when the super-journal is removed, after all
+	// logs were written.
+	beforeRemoveSuper
+	// afterRemoveSuper crashes after the super-journal was removed.
+	afterRemoveSuper
+)
+
+const errCrash = Error("crashed")
+
+func isSuper(name string) bool {
+	return strings.Contains(filepath.Base(name), "-mj")
+}
+
+func (c *crashFS) OpenFile(name string, flag int, perm os.FileMode) (wal.File, error) {
+	if c.crashed {
+		return nil, errCrash
+	}
+	f, err := wal.OS.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return crashFile{File: f, fs: c}, nil
+}
+
+func (c *crashFS) Rename(oldname, newname string) error {
+	if c.crashed {
+		return errCrash
+	}
+	if err := wal.OS.Rename(oldname, newname); err != nil {
+		return err
+	}
+	if isSuper(newname) {
+		c.armed = true
+		c.crashed = c.at == afterWriteSuper
+	}
+	return nil
+}
+
+func (c *crashFS) Remove(name string) error {
+	if c.crashed {
+		return errCrash
+	}
+	if isSuper(name) && c.at == beforeRemoveSuper {
+		c.crashed = true
+		return errCrash
+	}
+	if err := wal.OS.Remove(name); err != nil {
+		return err
+	}
+	if isSuper(name) && c.at == afterRemoveSuper {
+		c.crashed = true
+		return errCrash
+	}
+	return nil
+}
+
+type crashFile struct {
+	wal.File
+	fs *crashFS
+}
+
+func (f crashFile) Write(p []byte) (int, error) {
+	if f.fs.crashed {
+		return 0, errCrash
+	}
+	return f.File.Write(p)
+}
+
+func (f crashFile) Sync() error {
+	if f.fs.crashed {
+		return errCrash
+	}
+	if err := f.File.Sync(); err != nil {
+		return err
+	}
+	f.fs.crashed = f.fs.armed && f.fs.at == afterWriteLog
+	return nil
+}
+
+func TestCommitAll_Crash(t *testing.T) {
+	tests := []struct {
+		name      string
+		at        crashPoint
+		committed bool
+	}{
+		{"after write super-journal", afterWriteSuper, false},
+		{"after write first log", afterWriteLog, false},
+		{"before remove super-journal", beforeRemoveSuper, false},
+		{"after remove super-journal", afterRemoveSuper, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			names := []string{filepath.Join(dir, "main.db"), filepath.Join(dir, "aux.db")}
+			super := names[0] + "-mj1"
+			fsys := &crashFS{at: tt.at}
+			main, err := Open(fsys, names[0])
+			require.NoError(t, err)
+			aux, err := Open(fsys, names[1])
+			require.NoError(t, err)
+
+			tx1, tx2 := begin(t, main, Options{}), begin(t, aux, Options{})
+			put(t, tx1, "a", "1")
+			put(t, tx2, "b", "2")
+			assert.True(t, errors.Is(CommitAll(fsys, super, tx1, tx2), errCrash))
+			// the databases cannot be used until they are recovered
+			tx1 = begin(t, main, Options{})
+			put(t, tx1, "c", "3")
+			assert.Error(t, tx1.Commit())
+			_ = main.Close()
+			_ = aux.Close()
+
+			// restart, and recover both databases
+			var want [2]string
+			if tt.committed {
+				want = [2]string{"1", "2"}
+			}
+			for i := 0; i < 2; i++ {
+				main, err = Open(wal.OS, names[0])
+				require.NoError(t, err)
+				aux, err = Open(wal.OS, names[1])
+				require.NoError(t, err)
+				tx1, tx2 = begin(t, main, Options{}), begin(t, aux, Options{})
+				assertValue(t, tx1, "a", want[0])
+				assertValue(t, tx1, "c", "")
+				assertValue(t, tx2, "b", want[1])
+				assert.NoError(t, tx1.Rollback())
+				assert.NoError(t, tx2.Rollback())
+				assert.NoError(t, main.Close())
+				assert.NoError(t, aux.Close())
+
+				if tt.at != afterWriteSuper {
+					// without batches that name it, a stale super-journal
+					// has no effect, and is not found by the recovery
+					_, err = os.Stat(super)
+					assert.True(t, os.IsNotExist(err), "stale super-journal must be removed")
+				}
+			}
+		})
+	}
+}
+
+func TestAutoVacuum(t *testing.T) {
+	m, _ := openTestManager(t)
+	commit := func(key, value string) {
+		tx := begin(t, m, Options{})
+		put(t, tx, key, value)
+		assert.NoError(t, tx.Commit())
+	}
+
+	assert.Error(t, m.SetAutoVacuum(AutoVacuum(42)))
+	assert.NoError(t, m.SetAutoVacuum(AutoVacuumFull))
+	commit("a", "1")
+	reader := begin(t, m, Options{Isolation: Snapshot})
+	commit("a", "2")
+	commit("a", "3")
+	// the version that the reader sees is kept
+	assertValue(t, reader, "a", "1")
+	assert.NoError(t, reader.Rollback())
+	commit("a", "4")
+	assert.Equal(t, 0, m.Vacuum())
+
+	assert.NoError(t, m.SetAutoVacuum(AutoVacuumIncremental))
+	for i := 5; i < 10; i++ {
+		commit("a", strconv.Itoa(i))
+	}
+	assert.Equal(t, 2, m.IncrementalVacuum(2))
+	assert.Equal(t, 3, m.IncrementalVacuum(0))
+	assert.Equal(t, 0, m.IncrementalVacuum(0))
+
+	assert.NoError(t, m.SetAutoVacuum(AutoVacuumNone))
+	commit("a", "10")
+	assert.Equal(t, 0, m.IncrementalVacuum(0))
+	assert.Equal(t, 1, m.Vacuum())
+}
+
+func TestCompact(t *testing.T) {
+	m, name := openTestManager(t)
+
+	for i := 0; i < 100; i++ {
+		tx := begin(t, m, Options{})
+		put(t, tx, strconv.Itoa(i), "v")
+		if i%2 == 1 {
+			assert.NoError(t, tx.Delete([]byte(strconv.Itoa(i-1))))
+		}
+		assert.NoError(t, tx.Commit())
+	}
+	info, err := os.Stat(name + "-wal")
+	require.NoError(t, err)
+	walSize := info.Size()
+
+	// Compact waits for the active transaction
+	tx := begin(t, m, Options{})
+	assert.Equal(t, context.DeadlineExceeded, m.Compact(timeout(t, 10*time.Millisecond)))
+	assert.NoError(t, tx.Rollback())
+
+	require.NoError(t, m.Compact(context.Background()))
+	assert.Equal(t, 0, m.Vacuum())
+	info, err = os.Stat(name + "-wal")
+	require.NoError(t, err)
+	assert.Less(t, info.Size(), walSize)
+
+	copyName := name + ".copy"
+	require.NoError(t, m.VacuumInto(copyName))
+	assert.Error(t, m.VacuumInto(copyName))
+	assert.NoError(t, m.Close())
+
+	for _, name := range []string{name, copyName} {
+		m, err := Open(wal.OS, name)
+		require.NoError(t, err)
+		tx := begin(t, m, Options{})
+		for i := 0; i < 100; i++ {
+			if i%2 == 0 {
+				assertValue(t, tx, strconv.Itoa(i), "")
+			} else {
+				assertValue(t, tx, strconv.Itoa(i), "v")
+			}
+		}
+		assert.NoError(t, tx.Rollback())
+		assert.NoError(t, m.Close())
+	}
+}
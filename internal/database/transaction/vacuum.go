@@ -0,0 +1,96 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+)
+
+// AutoVacuum is the auto-vacuum mode of a database. It determines when
+// versions, that are not visible to any transaction anymore, are removed
+// without calling Vacuum or Compact.
+type AutoVacuum uint8
+
+const (
+	// AutoVacuumNone never removes versions automatically. This is the
+	// default.
+	AutoVacuumNone AutoVacuum = iota
+	// AutoVacuumFull removes the old versions of the keys that a commit
+	// writes, as part of the commit. Old versions that are still visible to
+	// an active transaction are removed by a later commit of the same key,
+	// or by Vacuum.
+	AutoVacuumFull
+	// AutoVacuumIncremental removes versions only when IncrementalVacuum is
+	// called, which allows to spread the work over time.
+	AutoVacuumIncremental
+)
+
+// SetAutoVacuum sets the auto-vacuum mode of the database.
+func (m *Manager) SetAutoVacuum(mode AutoVacuum) error {
+	if mode > AutoVacuumIncremental {
+		return fmt.Errorf("unknown auto-vacuum mode %d", mode)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.autoVacuum = mode
+	return nil
+}
+
+// AutoVacuum returns the auto-vacuum mode of the database.
+func (m *Manager) AutoVacuum() AutoVacuum {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.autoVacuum
+}
+
+// IncrementalVacuum removes at most n versions, that are not visible to any
+// active transaction anymore, and returns the amount of removed versions. If
+// n is not positive, all such versions are removed. Like in SQLite, nothing
+// happens unless the auto-vacuum mode is AutoVacuumIncremental.
+func (m *Manager) IncrementalVacuum(n int) int {
+	if m.AutoVacuum() != AutoVacuumIncremental {
+		return 0
+	}
+
+	m.commitMu.Lock()
+	defer m.commitMu.Unlock()
+
+	return m.store.vacuum(m.horizon(), n)
+}
+
+// Compact rebuilds the database, like the VACUUM statement. It removes all
+// old versions, rebuilds the tree that holds the keys without any free
+// space, and writes a checkpoint, which rewrites the database file with only
+// the current values and truncates the write-ahead log.
+//
+// Compact needs an exclusive lock on the database, and waits until all
+// other transactions have finished, or the context is done.
+func (m *Manager) Compact(ctx context.Context) error {
+	if err := m.lock.acquire(ctx, exclusive); err != nil {
+		return err
+	}
+	defer m.lock.release(exclusive)
+
+	m.commitMu.Lock()
+	defer m.commitMu.Unlock()
+
+	// no transaction is active, so only the current versions are visible
+	m.store.vacuum(m.horizon(), 0)
+	if err := m.store.rebuild(); err != nil {
+		return fmt.Errorf("rebuild: %w", err)
+	}
+	return m.log.Checkpoint()
+}
+
+// VacuumInto writes a compacted copy of the database into a new database
+// file with the given name, like VACUUM INTO. The copy contains all
+// transactions that were committed before VacuumInto was called. The file
+// must not exist yet.
+func (m *Manager) VacuumInto(name string) error {
+	m.commitMu.Lock()
+	defer m.commitMu.Unlock()
+
+	return wal.Export(m.fs, name, m.store)
+}
@@ -0,0 +1,126 @@
+package trigger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Catalog manages the triggers of a schema. Trigger names are case
+// insensitive. A Catalog is not safe for concurrent use.
+type Catalog struct {
+	// triggers holds all triggers, in the order in which they were created.
+	triggers []*Trigger
+}
+
+// NewCatalog creates a new catalog without any triggers.
+func NewCatalog() *Catalog {
+	return &Catalog{}
+}
+
+// Create adds the given trigger. View indicates that the trigger is on a
+// view, which only allows INSTEAD OF triggers, which are not allowed on
+// tables. If a trigger with the same name already exists, ErrExists is
+// returned, unless ifNotExists is set, in which case nothing happens.
+func (c *Catalog) Create(t *Trigger, view, ifNotExists bool) error {
+	if _, i := c.find(t.Name); i >= 0 {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrExists, t.Name)
+	}
+
+	switch {
+	case view && t.Time != InsteadOf:
+		return fmt.Errorf("%w: %v", ErrTriggerOnView, t.Table)
+	case !view && t.Time == InsteadOf:
+		return fmt.Errorf("%w: %v", ErrInsteadOfTable, t.Table)
+	case len(t.Columns) != 0 && t.Event != Update:
+		return fmt.Errorf("%w: %v", ErrColumnsNotUpdate, t.Name)
+	}
+	c.triggers = append(c.triggers, t)
+	return nil
+}
+
+// Drop removes the trigger with the given name. If no such trigger exists,
+// ErrNotExist is returned, unless ifExists is set.
+func (c *Catalog) Drop(name string, ifExists bool) error {
+	_, i := c.find(name)
+	if i < 0 {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrNotExist, name)
+	}
+	c.triggers = append(c.triggers[:i:i], c.triggers[i+1:]...)
+	return nil
+}
+
+// DropTable removes all triggers on the given table or view, which is
+// dropped.
+func (c *Catalog) DropTable(table string) {
+	kept := c.triggers[:0:0]
+	for _, t := range c.triggers {
+		if !strings.EqualFold(t.Table, table) {
+			kept = append(kept, t)
+		}
+	}
+	c.triggers = kept
+}
+
+// RenameTable changes the table of all triggers on the table with the given
+// name to the new name.
+func (c *Catalog) RenameTable(table, newName string) {
+	for _, t := range c.triggers {
+		if strings.EqualFold(t.Table, table) {
+			t.Table = newName
+		}
+	}
+}
+
+// RenameColumn changes the given column in the UPDATE OF columns of all
+// triggers on the given table to the new name.
+func (c *Catalog) RenameColumn(table, column, newName string) {
+	for _, t := range c.triggers {
+		if !strings.EqualFold(t.Table, table) {
+			continue
+		}
+		for i, col := range t.Columns {
+			if strings.EqualFold(col, column) {
+				t.Columns[i] = newName
+			}
+		}
+	}
+}
+
+// Trigger returns the trigger with the given name.
+func (c *Catalog) Trigger(name string) (*Trigger, bool) {
+	t, i := c.find(name)
+	return t, i >= 0
+}
+
+// Triggers returns all triggers, in the order in which they were created.
+func (c *Catalog) Triggers() []*Trigger {
+	return append([]*Trigger{}, c.triggers...)
+}
+
+// Fired returns the triggers, that fire for the given change of a row of the
+// given table. Updated holds the names of the updated columns of an UPDATE.
+// Like in SQLite, the most recently created trigger fires first.
+func (c *Catalog) Fired(table string, time Time, event Event, updated []string) []*Trigger {
+	var fired []*Trigger
+	for i := len(c.triggers) - 1; i >= 0; i-- {
+		if t := c.triggers[i]; t.matches(table, time, event, updated) {
+			fired = append(fired, t)
+		}
+	}
+	return fired
+}
+
+func (c *Catalog) find(name string) (*Trigger, int) {
+	for i, t := range c.triggers {
+		if strings.EqualFold(t.Name, name) {
+			return t, i
+		}
+	}
+	return nil, -1
+}
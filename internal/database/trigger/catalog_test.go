@@ -0,0 +1,52 @@
+package trigger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func names(triggers []*Trigger) []string {
+	names := []string{}
+	for _, t := range triggers {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func TestCatalog(t *testing.T) {
+	c := NewCatalog()
+	require.NoError(t, c.Create(&Trigger{Name: "a", Table: "t", Time: Before, Event: Insert}, false, false))
+	require.NoError(t, c.Create(&Trigger{Name: "b", Table: "T", Time: Before, Event: Insert}, false, false))
+	require.NoError(t, c.Create(&Trigger{Name: "c", Table: "t", Time: After, Event: Update, Columns: []string{"x"}}, false, false))
+	require.NoError(t, c.Create(&Trigger{Name: "d", Table: "v", Time: InsteadOf, Event: Delete}, true, false))
+
+	assert.True(t, errors.Is(c.Create(&Trigger{Name: "A", Table: "t"}, false, false), ErrExists))
+	assert.NoError(t, c.Create(&Trigger{Name: "A", Table: "t"}, false, true))
+	assert.True(t, errors.Is(c.Create(&Trigger{Name: "e", Table: "v", Time: After}, true, false), ErrTriggerOnView))
+	assert.True(t, errors.Is(c.Create(&Trigger{Name: "e", Table: "t", Time: InsteadOf}, false, false), ErrInsteadOfTable))
+	assert.True(t, errors.Is(c.Create(&Trigger{Name: "e", Table: "t", Columns: []string{"x"}}, false, false), ErrColumnsNotUpdate))
+
+	assert.Equal(t, []string{"b", "a"}, names(c.Fired("t", Before, Insert, nil)))
+	assert.Empty(t, c.Fired("t", After, Insert, nil))
+	assert.Equal(t, []string{"c"}, names(c.Fired("t", After, Update, []string{"y", "X"})))
+	assert.Empty(t, c.Fired("t", After, Update, []string{"y"}))
+
+	trigger, ok := c.Trigger("D")
+	require.True(t, ok)
+	assert.Equal(t, "v", trigger.Table)
+
+	c.RenameColumn("T", "X", "z")
+	assert.Equal(t, []string{"c"}, names(c.Fired("t", After, Update, []string{"z"})))
+	c.RenameTable("T", "u")
+	assert.Equal(t, []string{"b", "a"}, names(c.Fired("u", Before, Insert, nil)))
+	c.RenameTable("u", "t")
+
+	require.NoError(t, c.Drop("B", false))
+	assert.True(t, errors.Is(c.Drop("b", false), ErrNotExist))
+	assert.NoError(t, c.Drop("b", true))
+	c.DropTable("T")
+	assert.Equal(t, []string{"d"}, names(c.Triggers()))
+}
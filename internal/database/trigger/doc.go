@@ -0,0 +1,16 @@
+// Package trigger stores and runs row triggers.
+//
+// A Catalog holds the triggers of a schema. Every trigger fires BEFORE, AFTER
+// or INSTEAD OF an INSERT, UPDATE or DELETE of a row of a table or view, and
+// runs its program with the OLD and NEW values of the row, if its WHEN
+// condition holds. The statements of the program and the condition are
+// compiled by the caller, and run as functions.
+//
+// A statement that changes rows fires the triggers through an Execution,
+// which it passes on to the programs of the triggers, so that the statements
+// of the programs fire their triggers in the same Execution. Like in SQLite, a
+// trigger does not fire while it is already running, unless recursive
+// triggers are enabled, in which case the depth of triggers is limited.
+// RAISE in a program is an error of type *Raise, which either skips the row,
+// or fails the statement with a conflict resolution algorithm.
+package trigger
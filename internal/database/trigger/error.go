@@ -0,0 +1,16 @@
+package trigger
+
+// Error provides constant errors to the trigger package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrExists           = Error("trigger already exists")
+	ErrNotExist         = Error("no such trigger")
+	ErrInsteadOfTable   = Error("cannot create INSTEAD OF trigger on table")
+	ErrTriggerOnView    = Error("cannot create BEFORE or AFTER trigger on view")
+	ErrColumnsNotUpdate = Error("UPDATE OF columns require an UPDATE trigger")
+	ErrTooDeep          = Error("too many levels of trigger recursion")
+)
@@ -0,0 +1,150 @@
+package trigger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+)
+
+// MaxDepth is the default maximum depth of recursive triggers, like
+// SQLITE_MAX_TRIGGER_DEPTH.
+const MaxDepth = 1000
+
+// Raise is the error of RAISE in the program or WHEN condition of a trigger.
+// Ignore skips the change of the row, that fired the trigger, and the
+// remaining triggers of the change. Rollback, Abort and Fail fail the
+// statement with the given message, and resolve the failure like a
+// violation of a constraint with the same algorithm.
+type Raise struct {
+	Conflict constraint.Conflict
+	Message  string
+}
+
+func (r *Raise) Error() string {
+	return r.Message
+}
+
+// Is makes a Raise, that fails the statement, match constraint.ErrConstraint,
+// since SQLite reports it as a constraint violation.
+func (r *Raise) Is(target error) bool {
+	return target == constraint.ErrConstraint && r.Conflict != constraint.Ignore
+}
+
+// Runner runs the triggers of a catalog. A Runner is not safe for concurrent
+// use.
+type Runner struct {
+	catalog   *Catalog
+	recursive bool
+	maxDepth  int
+}
+
+// NewRunner creates a runner for the triggers of the given catalog. Recursive
+// triggers are disabled.
+func NewRunner(catalog *Catalog) *Runner {
+	return &Runner{catalog: catalog, maxDepth: MaxDepth}
+}
+
+// SetRecursive enables or disables recursive triggers, like PRAGMA
+// recursive_triggers. If they are disabled, a trigger does not fire while it
+// is running.
+func (r *Runner) SetRecursive(recursive bool) {
+	r.recursive = recursive
+}
+
+// SetMaxDepth sets the maximum depth of nested triggers. Firing a trigger
+// deeper than that fails with ErrTooDeep.
+func (r *Runner) SetMaxDepth(depth int) {
+	r.maxDepth = depth
+}
+
+// Begin starts an execution for a statement, that is not run by a trigger.
+func (r *Runner) Begin() *Execution {
+	return &Execution{r: r}
+}
+
+// Execution tracks the triggers, that are running for a statement.
+type Execution struct {
+	r *Runner
+	// running holds the triggers, that are running, from the outermost to
+	// the innermost.
+	running []*Trigger
+}
+
+// Depth returns the number of triggers, that are running.
+func (x *Execution) Depth() int {
+	return len(x.running)
+}
+
+// InsteadOf reports whether a change of a row of the given view is handled by
+// INSTEAD OF triggers. If it isn't, the view cannot be changed.
+func (x *Execution) InsteadOf(view string, event Event, updated []string) bool {
+	return len(x.r.catalog.Fired(view, InsteadOf, event, updated)) != 0
+}
+
+// Fire runs the triggers, that fire for the given change of a row of the
+// given table. Updated holds the names of the updated columns of an UPDATE.
+// Old is nil for an INSERT, and new is nil for a DELETE. Fire returns false,
+// if a trigger skipped the change with RAISE(IGNORE), in which case a BEFORE
+// or INSTEAD OF change must not be made.
+func (x *Execution) Fire(table string, time Time, event Event, updated []string, old, new Row) (bool, error) {
+	for _, t := range x.r.catalog.Fired(table, time, event, updated) {
+		if !x.r.recursive && x.isRunning(t) {
+			continue
+		}
+		ok, err := x.run(t, old, new)
+		if err != nil || !ok {
+			return ok, err
+		}
+	}
+	return true, nil
+}
+
+// run runs a single trigger, and returns false if it raised IGNORE.
+func (x *Execution) run(t *Trigger, old, new Row) (bool, error) {
+	if len(x.running) >= x.r.maxDepth {
+		return false, ErrTooDeep
+	}
+
+	x.running = append(x.running, t)
+	defer func() { x.running = x.running[:len(x.running)-1] }()
+
+	err := x.program(t, old, new)
+	var raise *Raise
+	if errors.As(err, &raise) && raise.Conflict == constraint.Ignore {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (x *Execution) program(t *Trigger, old, new Row) error {
+	if t.When != nil {
+		ok, err := t.When(old, new)
+		if err != nil || !ok {
+			return whenError(t, err)
+		}
+	}
+	if t.Program == nil {
+		return nil
+	}
+	return t.Program(x, old, new)
+}
+
+// whenError wraps an error of the WHEN condition of the given trigger. RAISE
+// is returned as is, so that it keeps its message.
+func whenError(t *Trigger, err error) error {
+	var raise *Raise
+	if err == nil || errors.As(err, &raise) {
+		return err
+	}
+	return fmt.Errorf("when of %v: %w", t.Name, err)
+}
+
+func (x *Execution) isRunning(t *Trigger) bool {
+	for _, running := range x.running {
+		if running == t {
+			return true
+		}
+	}
+	return false
+}
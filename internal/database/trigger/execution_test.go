@@ -0,0 +1,141 @@
+package trigger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+)
+
+// db is a database of tables without constraints, whose changes fire
+// triggers.
+type db struct {
+	runner *Runner
+	tables map[string][]Row
+}
+
+func newDB() (*db, *Catalog) {
+	c := NewCatalog()
+	return &db{runner: NewRunner(c), tables: make(map[string][]Row)}, c
+}
+
+// insert inserts a row into a table, like a statement would.
+func (d *db) insert(x *Execution, table string, row Row) error {
+	ok, err := x.Fire(table, Before, Insert, nil, nil, row)
+	if err != nil || !ok {
+		return err
+	}
+	d.tables[table] = append(d.tables[table], row)
+	_, err = x.Fire(table, After, Insert, nil, nil, row)
+	return err
+}
+
+// log returns a program, that inserts the NEW row into the table log.
+func (d *db) log(x *Execution, _, new Row) error {
+	return d.insert(x, "log", new)
+}
+
+func TestFire(t *testing.T) {
+	d, c := newDB()
+	require.NoError(t, c.Create(&Trigger{
+		Name:    "log_positive",
+		Table:   "t",
+		Time:    After,
+		Event:   Insert,
+		When:    func(_, new Row) (bool, error) { return new[0].(int64) > 0, nil },
+		Program: d.log,
+	}, false, false))
+	require.NoError(t, c.Create(&Trigger{
+		Name:  "skip_zero",
+		Table: "t",
+		Time:  Before,
+		Event: Insert,
+		When:  func(_, new Row) (bool, error) { return new[0].(int64) == 0, nil },
+		Program: func(*Execution, Row, Row) error {
+			return &Raise{Conflict: constraint.Ignore}
+		},
+	}, false, false))
+	require.NoError(t, c.Create(&Trigger{
+		Name:  "abort_large",
+		Table: "t",
+		Time:  Before,
+		Event: Insert,
+		When: func(_, new Row) (bool, error) {
+			if new[0].(int64) > 100 {
+				return false, &Raise{Conflict: constraint.Abort, Message: "too large"}
+			}
+			return true, nil
+		},
+	}, false, false))
+
+	for _, v := range []int64{-1, 0, 1, 2} {
+		require.NoError(t, d.insert(d.runner.Begin(), "t", Row{v}))
+	}
+	assert.Equal(t, []Row{{int64(-1)}, {int64(1)}, {int64(2)}}, d.tables["t"])
+	assert.Equal(t, []Row{{int64(1)}, {int64(2)}}, d.tables["log"])
+
+	err := d.insert(d.runner.Begin(), "t", Row{int64(101)})
+	var raise *Raise
+	require.True(t, errors.As(err, &raise))
+	assert.Equal(t, constraint.Abort, raise.Conflict)
+	assert.Equal(t, "too large", err.Error())
+	assert.True(t, errors.Is(err, constraint.ErrConstraint))
+	assert.Len(t, d.tables["t"], 3)
+}
+
+func TestRecursion(t *testing.T) {
+	d, c := newDB()
+	// CREATE TRIGGER count AFTER INSERT ON t WHEN NEW.n < 10 BEGIN
+	//	INSERT INTO t VALUES (NEW.n + 1);
+	// END
+	require.NoError(t, c.Create(&Trigger{
+		Name:  "count",
+		Table: "t",
+		Time:  After,
+		Event: Insert,
+		When:  func(_, new Row) (bool, error) { return new[0].(int64) < 10, nil },
+		Program: func(x *Execution, _, new Row) error {
+			return d.insert(x, "t", Row{new[0].(int64) + 1})
+		},
+	}, false, false))
+
+	require.NoError(t, d.insert(d.runner.Begin(), "t", Row{int64(1)}))
+	assert.Len(t, d.tables["t"], 2)
+
+	d.tables["t"] = nil
+	d.runner.SetRecursive(true)
+	require.NoError(t, d.insert(d.runner.Begin(), "t", Row{int64(1)}))
+	assert.Len(t, d.tables["t"], 10)
+
+	d.runner.SetMaxDepth(5)
+	err := d.insert(d.runner.Begin(), "t", Row{int64(1)})
+	assert.True(t, errors.Is(err, ErrTooDeep))
+}
+
+func TestInsteadOf(t *testing.T) {
+	d, c := newDB()
+	// CREATE TRIGGER v_delete INSTEAD OF DELETE ON v BEGIN
+	//	INSERT INTO log VALUES (OLD.a);
+	// END
+	require.NoError(t, c.Create(&Trigger{
+		Name:  "v_delete",
+		Table: "v",
+		Time:  InsteadOf,
+		Event: Delete,
+		Program: func(x *Execution, old, _ Row) error {
+			assert.Equal(t, 1, x.Depth())
+			return d.log(x, nil, old)
+		},
+	}, true, false))
+
+	x := d.runner.Begin()
+	assert.False(t, x.InsteadOf("v", Insert, nil))
+	require.True(t, x.InsteadOf("V", Delete, nil))
+	ok, err := x.Fire("v", InsteadOf, Delete, nil, Row{"a"}, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []Row{{"a"}}, d.tables["log"])
+	assert.Equal(t, 0, x.Depth())
+}
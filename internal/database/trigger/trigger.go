@@ -0,0 +1,77 @@
+package trigger
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+)
+
+//go:generate stringer -type=Time,Event
+
+// Time is the time at which a trigger fires, relative to the change of the
+// row.
+type Time uint8
+
+// Known times.
+const (
+	Before Time = iota
+	After
+	// InsteadOf triggers replace the change of a row of a view.
+	InsteadOf
+)
+
+// Event is the kind of change of a row, that fires a trigger.
+type Event uint8
+
+// Known events.
+const (
+	Insert Event = iota
+	Update
+	Delete
+)
+
+// Row holds the values of the columns of a row, in the order of the columns
+// of the table.
+type Row = constraint.Row
+
+// Trigger is a row trigger, as created by CREATE TRIGGER.
+type Trigger struct {
+	Name string
+	// Table is the name of the table or view, whose changes fire the
+	// trigger.
+	Table string
+	Time  Time
+	Event Event
+	// Columns are the names of the columns of UPDATE OF. If they are not
+	// empty, the trigger only fires if any of them is updated.
+	Columns []string
+	// When evaluates the WHEN condition with the OLD and NEW row. If it is
+	// nil, the trigger always fires. OLD is nil for an INSERT, and NEW is
+	// nil for a DELETE.
+	When func(old, new Row) (bool, error)
+	// Program runs the statements of the trigger with the OLD and NEW row.
+	// Changes of rows must fire their triggers in the given execution.
+	Program func(x *Execution, old, new Row) error
+	// SQL is the text of the CREATE TRIGGER statement, that is stored in
+	// the schema.
+	SQL string
+}
+
+// matches reports whether the trigger fires for the given change. Updated
+// holds the names of the updated columns.
+func (t *Trigger) matches(table string, time Time, event Event, updated []string) bool {
+	if !strings.EqualFold(t.Table, table) || t.Time != time || t.Event != event {
+		return false
+	}
+	if event != Update || len(t.Columns) == 0 {
+		return true
+	}
+	for _, c := range t.Columns {
+		for _, u := range updated {
+			if strings.EqualFold(c, u) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+// Package view stores views and expands them into the queries that define
+// them.
+//
+// A Catalog holds the views of a schema, as created by CREATE VIEW. When a
+// query refers to a view, the view is expanded into its SELECT statement, and
+// the names of the result columns of that statement are replaced by the
+// column names of the view. Views may refer to other views, but not to
+// themselves, neither directly nor through other views, which is detected
+// during the expansion.
+//
+// Views are read-only. A change of a row of a view is only possible, if an
+// INSTEAD OF trigger for the change exists, which makes the change instead.
+package view
@@ -0,0 +1,16 @@
+package view
+
+// Error provides constant errors to the view package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrExists      = Error("view already exists")
+	ErrNotExist    = Error("no such view")
+	ErrNoSuchTable = Error("no such table")
+	ErrCircular    = Error("view is circularly defined")
+	ErrColumnCount = Error("number of columns does not match the view definition")
+	ErrReadOnly    = Error("cannot modify a view without an INSTEAD OF trigger")
+)
@@ -0,0 +1,466 @@
+package view
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// Expansion is a view, that was expanded into its SELECT statement.
+type Expansion struct {
+	View *View
+	// Columns are the names of the columns of the view. The result columns
+	// of the SELECT statement must be renamed to them.
+	Columns []string
+}
+
+// Expand expands the view with the given name. It resolves the tables and
+// views that the view refers to, and computes the names of its columns.
+// Tables that are not views are looked up in the given tables.
+func (c *Catalog) Expand(name string, tables Tables) (*Expansion, error) {
+	v, ok := c.View(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNotExist, name)
+	}
+	x := expander{c: c, tables: tables}
+	columns, err := x.view(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Expansion{View: v, Columns: columns}, nil
+}
+
+// expander walks SELECT statements to resolve the tables they refer to.
+type expander struct {
+	c      *Catalog
+	tables Tables
+	// views are the views that are being expanded, from the outermost to
+	// the innermost.
+	views []*View
+	// ctes holds the common table expressions that are in scope, from the
+	// outermost to the innermost scope.
+	ctes []map[string]*cte
+	// scopes holds the sources of the SELECT statements that are being
+	// expanded, from the outermost to the innermost one. Column references
+	// are resolved in them.
+	scopes [][]source
+	// visit is called for every reference to a table, and to a column of a
+	// table, in the outermost view, if it is not nil.
+	visit func(ref reference)
+}
+
+// reference is a reference to a table or a column of a table in a view.
+type reference struct {
+	table string
+	// column is empty for references to the table itself.
+	column string
+	// token is the token of the name of the table or column in the SELECT
+	// statement of the view.
+	token *token.Token
+}
+
+// cte is a common table expression. Its columns are nil while it is being
+// expanded, which is the case when a recursive CTE refers to itself.
+type cte struct {
+	def       *ast.CommonTableExpression
+	columns   []string
+	expanding bool
+}
+
+// source is a table in a FROM clause, with the name it is referred to by.
+type source struct {
+	name    string
+	columns []string
+	// table is the name of the table, if the source is a table, and not a
+	// view, a CTE or a subquery.
+	table string
+	// aliased indicates that the source is referred to by an alias, rather
+	// than by the name of the table.
+	aliased bool
+}
+
+func (x *expander) view(v *View) ([]string, error) {
+	for _, expanding := range x.views {
+		if expanding == v {
+			return nil, fmt.Errorf("%w: %v", ErrCircular, v.Name)
+		}
+	}
+	// CTEs of the query that refers to the view are not visible in the view
+	outer := x.ctes
+	x.views, x.ctes = append(x.views, v), nil
+	defer func() { x.views, x.ctes = x.views[:len(x.views)-1], outer }()
+
+	columns, err := x.selectStmt(v.Select)
+	if err != nil {
+		return nil, fmt.Errorf("view %v: %w", v.Name, err)
+	}
+	if len(v.Columns) == 0 {
+		return columns, nil
+	}
+	if len(v.Columns) != len(columns) {
+		return nil, fmt.Errorf("%w: expected %d columns for %v but got %d", ErrColumnCount, len(v.Columns), v.Name, len(columns))
+	}
+	return v.Columns, nil
+}
+
+// selectStmt resolves the tables of the given statement, and returns the
+// names of its result columns.
+func (x *expander) selectStmt(stmt *ast.SelectStmt) ([]string, error) {
+	if stmt == nil || len(stmt.SelectCore) == 0 {
+		return nil, nil
+	}
+
+	scope := make(map[string]*cte)
+	for _, def := range stmt.CommonTableExpression {
+		scope[strings.ToLower(value(def.TableName))] = &cte{def: def}
+	}
+	x.ctes = append(x.ctes, scope)
+	defer func() { x.ctes = x.ctes[:len(x.ctes)-1] }()
+
+	var columns []string
+	var sources []source
+	for i, core := range stmt.SelectCore {
+		names, found, err := x.selectCore(core)
+		if err != nil {
+			return nil, err
+		}
+		// the columns of a compound SELECT are named by the first SELECT
+		if i == 0 {
+			columns = names
+		}
+		sources = found
+	}
+
+	// the ORDER BY clause refers to the tables of the last SELECT
+	x.scopes = append(x.scopes, sources)
+	defer func() { x.scopes = x.scopes[:len(x.scopes)-1] }()
+	for _, term := range stmt.OrderingTerm {
+		if err := x.expr(term.Expr); err != nil {
+			return nil, err
+		}
+	}
+	if err := x.expr(stmt.Expr1); err != nil {
+		return nil, err
+	}
+	return columns, x.expr(stmt.Expr2)
+}
+
+// selectCore resolves the tables of the given SELECT, and returns the names of
+// its result columns and the tables of its FROM clause.
+func (x *expander) selectCore(core *ast.SelectCore) ([]string, []source, error) {
+	if len(core.ParenthesizedExpressions) != 0 {
+		for _, row := range core.ParenthesizedExpressions {
+			if err := x.exprs(row.Exprs); err != nil {
+				return nil, nil, err
+			}
+		}
+		names := make([]string, len(core.ParenthesizedExpressions[0].Exprs))
+		for i := range names {
+			names[i] = "column" + strconv.Itoa(i+1)
+		}
+		return names, nil, nil
+	}
+
+	var sources []source
+	for _, table := range core.TableOrSubquery {
+		found, err := x.tableOrSubquery(table)
+		if err != nil {
+			return nil, nil, err
+		}
+		sources = append(sources, found...)
+	}
+	found, err := x.joinClause(core.JoinClause)
+	if err != nil {
+		return nil, nil, err
+	}
+	sources = append(sources, found...)
+
+	x.scopes = append(x.scopes, sources)
+	defer func() { x.scopes = x.scopes[:len(x.scopes)-1] }()
+	for _, e := range []*ast.Expr{core.Expr1, core.Expr3} {
+		if err := x.expr(e); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := x.exprs(core.Expr2); err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+	for _, col := range core.ResultColumn {
+		if err := x.expr(col.Expr); err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case col.Asterisk != nil && col.TableName != nil:
+			s, ok := findSource(sources, col.TableName.Value())
+			if !ok {
+				return nil, nil, fmt.Errorf("%w: %v", ErrNoSuchTable, col.TableName.Value())
+			}
+			x.qualifier(s, &col.TableName)
+			names = append(names, s.columns...)
+		case col.Asterisk != nil:
+			for _, s := range sources {
+				names = append(names, s.columns...)
+			}
+		case col.ColumnAlias != nil:
+			names = append(names, col.ColumnAlias.Value())
+		default:
+			names = append(names, exprName(col.Expr, len(names)))
+		}
+	}
+	return unique(names), sources, nil
+}
+
+func (x *expander) joinClause(join *ast.JoinClause) ([]source, error) {
+	if join == nil {
+		return nil, nil
+	}
+	sources, err := x.tableOrSubquery(join.TableOrSubquery)
+	if err != nil || join.JoinClausePart == nil {
+		return sources, err
+	}
+	part := join.JoinClausePart
+	found, err := x.tableOrSubquery(part.TableOrSubquery)
+	if err != nil {
+		return nil, err
+	}
+	sources = append(sources, found...)
+	if c := part.JoinConstraint; c != nil {
+		x.scopes = append(x.scopes, sources)
+		defer func() { x.scopes = x.scopes[:len(x.scopes)-1] }()
+		if err := x.expr(c.Expr); err != nil {
+			return nil, err
+		}
+		// the columns of USING refer to the columns of both tables
+		for i := range c.ColumnName {
+			for _, s := range sources {
+				x.column(s, &c.ColumnName[i])
+			}
+		}
+	}
+	return sources, nil
+}
+
+func (x *expander) tableOrSubquery(table *ast.TableOrSubquery) ([]source, error) {
+	if table == nil {
+		return nil, nil
+	}
+	alias := value(table.TableAlias)
+
+	switch {
+	case table.TableFunctionName != nil:
+		// the columns of table-valued functions are not known here
+		return nil, x.exprs(table.Expr)
+	case table.TableName != nil:
+		name := table.TableName.Value()
+		columns, base, err := x.table(name)
+		if err != nil {
+			return nil, err
+		}
+		s := source{name: alias, columns: columns, aliased: alias != ""}
+		if !s.aliased {
+			s.name = name
+		}
+		if base {
+			s.table = name
+			x.reference(reference{table: name, token: &table.TableName})
+		}
+		return []source{s}, nil
+	case table.SelectStmt != nil:
+		columns, err := x.selectStmt(table.SelectStmt)
+		if err != nil {
+			return nil, err
+		}
+		return []source{{name: alias, columns: columns}}, nil
+	case table.JoinClause != nil:
+		return x.joinClause(table.JoinClause)
+	}
+
+	var sources []source
+	for _, t := range table.TableOrSubquery {
+		found, err := x.tableOrSubquery(t)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, found...)
+	}
+	return sources, nil
+}
+
+// table resolves a table name, which is a CTE, a view or a table, in this
+// order, and returns its columns, and whether it is a table.
+func (x *expander) table(name string) (columns []string, base bool, err error) {
+	key := strings.ToLower(name)
+	for i := len(x.ctes) - 1; i >= 0; i-- {
+		c, ok := x.ctes[i][key]
+		if !ok {
+			continue
+		}
+		if c.expanding || c.columns != nil {
+			return c.columns, false, nil
+		}
+		// the CTE can refer to the CTEs of its own and the outer scopes
+		outer := x.ctes
+		x.ctes = x.ctes[:i+1]
+		c.expanding = true
+		columns, err := x.selectStmt(c.def.SelectStmt)
+		c.expanding = false
+		x.ctes = outer
+		if err != nil {
+			return nil, false, err
+		}
+		if len(c.def.ColumnName) != 0 {
+			columns = make([]string, len(c.def.ColumnName))
+			for i, col := range c.def.ColumnName {
+				columns[i] = col.Value()
+			}
+		}
+		c.columns = columns
+		return columns, false, nil
+	}
+
+	if v, ok := x.c.View(name); ok {
+		columns, err := x.view(v)
+		return columns, false, err
+	}
+	if columns, ok := x.tables(name); ok {
+		return columns, true, nil
+	}
+	return nil, false, fmt.Errorf("%w: %v", ErrNoSuchTable, name)
+}
+
+func (x *expander) exprs(exprs []*ast.Expr) error {
+	for _, e := range exprs {
+		if err := x.expr(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expr resolves the columns that the given expression refers to, and the
+// tables of its subqueries.
+func (x *expander) expr(e *ast.Expr) error {
+	if e == nil {
+		return nil
+	}
+	if e.ColumnName != nil && e.FunctionName == nil {
+		x.columnRef(e)
+	}
+	for _, sub := range []*ast.Expr{e.Expr1, e.Expr2, e.Expr3, e.Expr4} {
+		if err := x.expr(sub); err != nil {
+			return err
+		}
+	}
+	if err := x.exprs(e.Expr); err != nil {
+		return err
+	}
+	_, err := x.selectStmt(e.SelectStmt)
+	return err
+}
+
+// columnRef resolves the column that the given expression refers to, in the
+// innermost scope that has a matching table, and reports references to
+// tables.
+func (x *expander) columnRef(e *ast.Expr) {
+	column := e.ColumnName.Value()
+	for i := len(x.scopes) - 1; i >= 0; i-- {
+		if e.TableName != nil {
+			if s, ok := findSource(x.scopes[i], e.TableName.Value()); ok {
+				x.qualifier(s, &e.TableName)
+				x.column(s, &e.ColumnName)
+				return
+			}
+			continue
+		}
+		for _, s := range x.scopes[i] {
+			if hasColumn(s, column) {
+				x.column(s, &e.ColumnName)
+				return
+			}
+		}
+	}
+}
+
+// qualifier reports the given qualifier of a column or an asterisk as a
+// reference to the table of the given source, if the source is referred to
+// by the name of the table.
+func (x *expander) qualifier(s source, tk *token.Token) {
+	if s.table != "" && !s.aliased {
+		x.reference(reference{table: s.table, token: tk})
+	}
+}
+
+// column reports the given column as a reference to the column of the table
+// of the given source, if the source is a table that has the column.
+func (x *expander) column(s source, tk *token.Token) {
+	if s.table != "" && hasColumn(s, (*tk).Value()) {
+		x.reference(reference{table: s.table, column: (*tk).Value(), token: tk})
+	}
+}
+
+func (x *expander) reference(ref reference) {
+	// references in other views are visited when they are expanded
+	if x.visit != nil && len(x.views) == 1 {
+		x.visit(ref)
+	}
+}
+
+func hasColumn(s source, column string) bool {
+	for _, c := range s.columns {
+		if strings.EqualFold(c, column) {
+			return true
+		}
+	}
+	return false
+}
+
+func findSource(sources []source, name string) (source, bool) {
+	for _, s := range sources {
+		if strings.EqualFold(s.name, name) {
+			return s, true
+		}
+	}
+	return source{}, false
+}
+
+// exprName returns the name of a result column without an alias, which is
+// the name of the column, if the expression is a column, or the literal
+// value, if the expression is a literal. Other expressions are named after
+// the position of the column, like "column3".
+func exprName(e *ast.Expr, i int) string {
+	switch {
+	case e == nil:
+	case e.ColumnName != nil && e.FunctionName == nil && e.Expr1 == nil:
+		return e.ColumnName.Value()
+	case e.LiteralValue != nil && e.Expr1 == nil:
+		return e.LiteralValue.Value()
+	}
+	return "column" + strconv.Itoa(i+1)
+}
+
+// unique makes the given column names unique, by appending ":1", ":2" and so
+// on to repeated names, like SQLite does for the columns of a view.
+func unique(names []string) []string {
+	seen := make(map[string]bool)
+	for i, name := range names {
+		candidate := name
+		for n := 1; seen[strings.ToLower(candidate)]; n++ {
+			candidate = name + ":" + strconv.Itoa(n)
+		}
+		seen[strings.ToLower(candidate)] = true
+		names[i] = candidate
+	}
+	return names
+}
+
+func value(t token.Token) string {
+	if t == nil {
+		return ""
+	}
+	return t.Value()
+}
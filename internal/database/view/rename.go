@@ -0,0 +1,125 @@
+package view
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// RenameTable changes all references to the table with the given name in the
+// SELECT statements and the SQL of the views to the new name. The views are
+// resolved with the given tables, which must still contain the table under
+// its old name. If any view cannot be resolved, no view is changed.
+func (c *Catalog) RenameTable(name, newName string, tables Tables) error {
+	return c.rename(tables, newName, func(ref reference) bool {
+		return ref.column == "" && strings.EqualFold(ref.table, name)
+	})
+}
+
+// RenameColumn changes all references to the given column of the given table
+// in the SELECT statements and the SQL of the views to the new name, like
+// RenameTable.
+func (c *Catalog) RenameColumn(table, column, newName string, tables Tables) error {
+	return c.rename(tables, newName, func(ref reference) bool {
+		return strings.EqualFold(ref.table, table) && strings.EqualFold(ref.column, column)
+	})
+}
+
+// Dependent returns the names of the views that refer to the given table, or
+// to the given column of the table, if the column is not empty.
+func (c *Catalog) Dependent(table, column string, tables Tables) ([]string, error) {
+	var names []string
+	for _, v := range c.views {
+		found := false
+		x := expander{c: c, tables: tables, visit: func(ref reference) {
+			found = found || strings.EqualFold(ref.table, table) && (column == "" || strings.EqualFold(ref.column, column))
+		}}
+		if _, err := x.view(v); err != nil {
+			return nil, err
+		}
+		if found {
+			names = append(names, v.Name)
+		}
+	}
+	return names, nil
+}
+
+// edit replaces the name in a token of a view.
+type edit struct {
+	token *token.Token
+	old   string
+}
+
+func (c *Catalog) rename(tables Tables, newName string, match func(ref reference) bool) error {
+	edits := make([][]edit, len(c.views))
+	for i, v := range c.views {
+		seen := make(map[*token.Token]bool)
+		x := expander{c: c, tables: tables, visit: func(ref reference) {
+			if match(ref) && !seen[ref.token] {
+				seen[ref.token] = true
+				edits[i] = append(edits[i], edit{ref.token, (*ref.token).Value()})
+			}
+		}}
+		if _, err := x.view(v); err != nil {
+			return err
+		}
+	}
+
+	for i, v := range c.views {
+		// edit the SQL from the end, so that the offsets of the remaining
+		// tokens stay valid
+		sort.Slice(edits[i], func(a, b int) bool {
+			return (*edits[i][a].token).Offset() > (*edits[i][b].token).Offset()
+		})
+		for _, e := range edits[i] {
+			tk := *e.token
+			*e.token = token.New(tk.Line(), tk.Col(), tk.Offset(), len(newName), tk.Type(), newName)
+			if sql, ok := replaceAt(v.SQL, tk.Offset(), e.old, newName); ok {
+				v.SQL = sql
+				shift(reflect.ValueOf(v.Select), tk.Offset()+1, len(newName)-len(e.old))
+			}
+		}
+	}
+	return nil
+}
+
+// shift moves the offsets of all tokens in the given AST node, that start at
+// or after the given offset, by delta, so that they match the SQL after an
+// edit.
+func shift(node reflect.Value, from, delta int) {
+	if delta == 0 {
+		return
+	}
+	switch node.Kind() {
+	case reflect.Ptr:
+		if !node.IsNil() {
+			shift(node.Elem(), from, delta)
+		}
+	case reflect.Slice:
+		for i := 0; i < node.Len(); i++ {
+			shift(node.Index(i), from, delta)
+		}
+	case reflect.Struct:
+		for i := 0; i < node.NumField(); i++ {
+			shift(node.Field(i), from, delta)
+		}
+	case reflect.Interface:
+		tk, ok := node.Interface().(token.Token)
+		if !ok || tk == nil || tk.Offset() < from {
+			return
+		}
+		node.Set(reflect.ValueOf(token.New(tk.Line(), tk.Col(), tk.Offset()+delta, tk.Length(), tk.Type(), tk.Value())))
+	}
+}
+
+// replaceAt replaces old at the given offset in the given SQL with new. If
+// old is not at the offset, which is the case if the SQL is not the text that
+// the statement was parsed from, false is returned.
+func replaceAt(sql string, offset int, old, new string) (string, bool) {
+	if offset < 0 || offset+len(old) > len(sql) || sql[offset:offset+len(old)] != old {
+		return sql, false
+	}
+	return sql[:offset] + new + sql[offset+len(old):], true
+}
@@ -0,0 +1,86 @@
+package view
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// at returns a token for the n-th occurrence of v in sql.
+func at(sql, v string, n int) token.Token {
+	offset := -1
+	for i := 0; i <= n; i++ {
+		offset += 1 + strings.Index(sql[offset+1:], v)
+	}
+	return token.New(1, offset+1, offset, len(v), token.Literal, v)
+}
+
+func TestRename(t *testing.T) {
+	named := "CREATE VIEW named AS SELECT users.name, email FROM users WHERE id > 1"
+	aliased := "CREATE VIEW aliased AS SELECT u.name FROM users AS u"
+	c := NewCatalog()
+	require.NoError(t, c.Create(&View{
+		Name: "named",
+		Select: &ast.SelectStmt{SelectCore: []*ast.SelectCore{{
+			ResultColumn: []*ast.ResultColumn{
+				{Expr: &ast.Expr{TableName: at(named, "users", 0), ColumnName: at(named, "name", 2)}},
+				{Expr: &ast.Expr{ColumnName: at(named, "email", 0)}},
+			},
+			TableOrSubquery: []*ast.TableOrSubquery{{TableName: at(named, "users", 1)}},
+			Expr1: &ast.Expr{
+				Expr1:          &ast.Expr{ColumnName: at(named, "id", 0)},
+				BinaryOperator: at(named, ">", 0),
+				Expr2:          &ast.Expr{LiteralValue: at(named, "1", 0)},
+			},
+		}}},
+		SQL: named,
+	}, false, tables))
+	require.NoError(t, c.Create(&View{
+		Name: "aliased",
+		Select: &ast.SelectStmt{SelectCore: []*ast.SelectCore{{
+			ResultColumn: []*ast.ResultColumn{
+				{Expr: &ast.Expr{TableName: at(aliased, "u", 2), ColumnName: at(aliased, "name", 0)}},
+			},
+			TableOrSubquery: []*ast.TableOrSubquery{{TableName: at(aliased, "users", 0), TableAlias: at(aliased, "u", 3)}},
+		}}},
+		SQL: aliased,
+	}, false, tables))
+
+	dependent, err := c.Dependent("users", "email", tables)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"named"}, dependent)
+	dependent, err = c.Dependent("users", "", tables)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"named", "aliased"}, dependent)
+
+	require.NoError(t, c.RenameColumn("users", "name", "fullname", tables))
+	renamed := func(name string) ([]string, bool) {
+		if name == "users" || name == "people" {
+			return []string{"id", "fullname", "email"}, true
+		}
+		return nil, false
+	}
+	require.NoError(t, c.RenameTable("users", "people", renamed))
+
+	v, _ := c.View("named")
+	assert.Equal(t, "CREATE VIEW named AS SELECT people.fullname, email FROM people WHERE id > 1", v.SQL)
+	v, _ = c.View("aliased")
+	assert.Equal(t, "CREATE VIEW aliased AS SELECT u.fullname FROM people AS u", v.SQL)
+
+	people := func(name string) ([]string, bool) {
+		if name == "people" {
+			return renamed(name)
+		}
+		return nil, false
+	}
+	x, err := c.Expand("named", people)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fullname", "email"}, x.Columns)
+	dependent, err = c.Dependent("people", "id", people)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"named"}, dependent)
+}
@@ -0,0 +1,124 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+)
+
+// View is a view, as created by CREATE VIEW.
+type View struct {
+	Name string
+	// Columns are the column names of the view, as in CREATE VIEW v(a, b).
+	// If they are empty, the names of the result columns of the SELECT
+	// statement are used.
+	Columns []string
+	Select  *ast.SelectStmt
+	// SQL is the text of the CREATE VIEW statement, that is stored in the
+	// schema.
+	SQL string
+}
+
+// From converts the given CREATE VIEW statement to a view.
+func From(stmt *ast.CreateViewStmt) *View {
+	v := &View{Select: stmt.SelectStmt}
+	if stmt.ViewName != nil {
+		v.Name = stmt.ViewName.Value()
+	}
+	for _, c := range stmt.ColumnName {
+		v.Columns = append(v.Columns, c.Value())
+	}
+	return v
+}
+
+// Tables returns the column names of the table with the given name, and
+// false if no such table exists.
+type Tables func(name string) ([]string, bool)
+
+// Catalog manages the views of a schema. View names are case insensitive. A
+// Catalog is not safe for concurrent use.
+type Catalog struct {
+	// views holds all views, in the order in which they were created.
+	views []*View
+}
+
+// NewCatalog creates a new catalog without any views.
+func NewCatalog() *Catalog {
+	return &Catalog{}
+}
+
+// Create adds the given view, after checking that it can be expanded with
+// the given tables. If a view with the same name already exists, ErrExists
+// is returned, unless ifNotExists is set, in which case nothing happens.
+func (c *Catalog) Create(v *View, ifNotExists bool, tables Tables) error {
+	if _, i := c.find(v.Name); i >= 0 {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrExists, v.Name)
+	}
+
+	c.views = append(c.views, v)
+	if _, err := c.Expand(v.Name, tables); err != nil {
+		c.views = c.views[:len(c.views)-1]
+		return err
+	}
+	return nil
+}
+
+// Load adds the given view of a stored schema without expanding it, since the
+// tables that it refers to may have been dropped after it was created. Like
+// in SQLite, such a view fails when it is expanded.
+func (c *Catalog) Load(v *View) error {
+	if _, i := c.find(v.Name); i >= 0 {
+		return fmt.Errorf("%w: %v", ErrExists, v.Name)
+	}
+	c.views = append(c.views, v)
+	return nil
+}
+
+// Drop removes the view with the given name. If no such view exists,
+// ErrNotExist is returned, unless ifExists is set. The triggers of the view
+// must be dropped by the caller.
+func (c *Catalog) Drop(name string, ifExists bool) error {
+	_, i := c.find(name)
+	if i < 0 {
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrNotExist, name)
+	}
+	c.views = append(c.views[:i:i], c.views[i+1:]...)
+	return nil
+}
+
+// View returns the view with the given name.
+func (c *Catalog) View(name string) (*View, bool) {
+	v, i := c.find(name)
+	return v, i >= 0
+}
+
+// Views returns all views, in the order in which they were created.
+func (c *Catalog) Views() []*View {
+	return append([]*View{}, c.views...)
+}
+
+func (c *Catalog) find(name string) (*View, int) {
+	for i, v := range c.views {
+		if strings.EqualFold(v.Name, name) {
+			return v, i
+		}
+	}
+	return nil, -1
+}
+
+// Writable checks that the given change of a row of the given view is made by
+// an INSTEAD OF trigger, which must be fired instead of changing the row.
+func Writable(x *trigger.Execution, view string, event trigger.Event, updated []string) error {
+	if !x.InsteadOf(view, event, updated) {
+		return fmt.Errorf("%w: %v", ErrReadOnly, view)
+	}
+	return nil
+}
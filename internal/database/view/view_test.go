@@ -0,0 +1,218 @@
+package view
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+func literal(v string) token.Token {
+	return token.New(1, 1, 0, len(v), token.Literal, v)
+}
+
+// selectFrom creates the statement SELECT <columns> FROM <tables>.
+func selectFrom(columns []*ast.ResultColumn, tables ...*ast.TableOrSubquery) *ast.SelectStmt {
+	return &ast.SelectStmt{
+		SelectCore: []*ast.SelectCore{{ResultColumn: columns, TableOrSubquery: tables}},
+	}
+}
+
+func star() []*ast.ResultColumn {
+	return []*ast.ResultColumn{{Asterisk: literal("*")}}
+}
+
+func columns(names ...string) []*ast.ResultColumn {
+	var columns []*ast.ResultColumn
+	for _, name := range names {
+		columns = append(columns, &ast.ResultColumn{Expr: &ast.Expr{ColumnName: literal(name)}})
+	}
+	return columns
+}
+
+func table(name string) *ast.TableOrSubquery {
+	return &ast.TableOrSubquery{TableName: literal(name)}
+}
+
+// tables has the table users(id, name, email).
+func tables(name string) ([]string, bool) {
+	if name == "users" {
+		return []string{"id", "name", "email"}, true
+	}
+	return nil, false
+}
+
+func TestCatalog(t *testing.T) {
+	c := NewCatalog()
+
+	// CREATE VIEW people(key, who) AS SELECT id, name FROM users
+	v := From(&ast.CreateViewStmt{
+		ViewName:   literal("people"),
+		ColumnName: []token.Token{literal("key"), literal("who")},
+		SelectStmt: selectFrom(columns("id", "name"), table("users")),
+	})
+	assert.Equal(t, "people", v.Name)
+	assert.Equal(t, []string{"key", "who"}, v.Columns)
+	require.NoError(t, c.Create(v, false, tables))
+
+	assert.True(t, errors.Is(c.Create(&View{Name: "PEOPLE"}, false, tables), ErrExists))
+	assert.NoError(t, c.Create(&View{Name: "PEOPLE"}, true, tables))
+
+	err := c.Create(&View{Name: "v", Select: selectFrom(star(), table("accounts"))}, false, tables)
+	assert.True(t, errors.Is(err, ErrNoSuchTable))
+	err = c.Create(&View{Name: "v", Columns: []string{"a"}, Select: selectFrom(star(), table("users"))}, false, tables)
+	assert.True(t, errors.Is(err, ErrColumnCount))
+	_, ok := c.View("v")
+	assert.False(t, ok)
+
+	got, ok := c.View("People")
+	require.True(t, ok)
+	assert.Same(t, v, got)
+	assert.Equal(t, []*View{v}, c.Views())
+
+	require.NoError(t, c.Drop("people", false))
+	assert.True(t, errors.Is(c.Drop("people", false), ErrNotExist))
+	assert.NoError(t, c.Drop("people", true))
+	_, err = c.Expand("people", tables)
+	assert.True(t, errors.Is(err, ErrNotExist))
+
+	// a stored view is loaded, also if its table was dropped
+	stored := &View{Name: "v", Select: selectFrom(star(), table("accounts"))}
+	require.NoError(t, c.Load(stored))
+	assert.True(t, errors.Is(c.Load(stored), ErrExists))
+	_, err = c.Expand("v", tables)
+	assert.True(t, errors.Is(err, ErrNoSuchTable))
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name string
+		// views are created in order, the last one is expanded
+		views []*View
+		want  []string
+		err   error
+	}{
+		{
+			"star",
+			[]*View{{Name: "v", Select: selectFrom(star(), table("users"))}},
+			[]string{"id", "name", "email"},
+			nil,
+		},
+		{
+			"aliases and repeated names",
+			[]*View{{Name: "v", Select: selectFrom([]*ast.ResultColumn{
+				{Expr: &ast.Expr{ColumnName: literal("id")}, ColumnAlias: literal("key")},
+				{Expr: &ast.Expr{ColumnName: literal("name")}},
+				{Expr: &ast.Expr{TableName: literal("u"), ColumnName: literal("name")}},
+				{Expr: &ast.Expr{LiteralValue: literal("42")}},
+				{Expr: &ast.Expr{FunctionName: literal("upper"), Expr: []*ast.Expr{{ColumnName: literal("email")}}}},
+			}, &ast.TableOrSubquery{TableName: literal("users"), TableAlias: literal("u")})}},
+			[]string{"key", "name", "name:1", "42", "column5"},
+			nil,
+		},
+		{
+			"view on view",
+			[]*View{
+				{Name: "v1", Columns: []string{"a", "b"}, Select: selectFrom(columns("id", "name"), table("users"))},
+				{Name: "v2", Select: selectFrom([]*ast.ResultColumn{
+					{Asterisk: literal("*"), TableName: literal("x")},
+					{Asterisk: literal("*"), TableName: literal("users")},
+				}, &ast.TableOrSubquery{TableName: literal("v1"), TableAlias: literal("x")}, table("users"))},
+			},
+			[]string{"a", "b", "id", "name", "email"},
+			nil,
+		},
+		{
+			"common table expression",
+			// WITH users(x) AS (SELECT id FROM users) SELECT * FROM users
+			[]*View{{Name: "v", Select: &ast.SelectStmt{
+				CommonTableExpression: []*ast.CommonTableExpression{{
+					TableName:  literal("users"),
+					ColumnName: []token.Token{literal("x")},
+					SelectStmt: selectFrom(columns("id"), table("users")),
+				}},
+				SelectCore: []*ast.SelectCore{{ResultColumn: star(), TableOrSubquery: []*ast.TableOrSubquery{table("users")}}},
+			}}},
+			[]string{"x"},
+			nil,
+		},
+		{
+			"values",
+			[]*View{{Name: "v", Select: &ast.SelectStmt{SelectCore: []*ast.SelectCore{{
+				ParenthesizedExpressions: []*ast.ParenthesizedExpressions{{Exprs: []*ast.Expr{{}, {}}}},
+			}}}}},
+			[]string{"column1", "column2"},
+			nil,
+		},
+		{
+			"subquery",
+			// SELECT * FROM (SELECT id FROM users) AS s
+			[]*View{{Name: "v", Select: selectFrom(star(), &ast.TableOrSubquery{
+				SelectStmt: selectFrom(columns("id"), table("users")),
+				TableAlias: literal("s"),
+			})}},
+			[]string{"id"},
+			nil,
+		},
+		{
+			"missing table in subquery",
+			[]*View{{Name: "v", Select: &ast.SelectStmt{SelectCore: []*ast.SelectCore{{
+				ResultColumn: []*ast.ResultColumn{{Expr: &ast.Expr{Exists: literal("EXISTS"), SelectStmt: selectFrom(star(), table("accounts"))}}},
+			}}}}},
+			nil,
+			ErrNoSuchTable,
+		},
+		{
+			"self reference",
+			[]*View{{Name: "v", Select: selectFrom(star(), table("v"))}},
+			nil,
+			ErrCircular,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCatalog()
+			last := tt.views[len(tt.views)-1]
+			for _, v := range tt.views[:len(tt.views)-1] {
+				require.NoError(t, c.Create(v, false, tables))
+			}
+
+			err := c.Create(last, false, tables)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			x, err := c.Expand(last.Name, tables)
+			require.NoError(t, err)
+			assert.Same(t, last, x.View)
+			assert.Equal(t, tt.want, x.Columns)
+		})
+	}
+}
+
+func TestCircular(t *testing.T) {
+	c := NewCatalog()
+	require.NoError(t, c.Create(&View{Name: "v2", Select: selectFrom(star(), table("users"))}, false, tables))
+	require.NoError(t, c.Create(&View{Name: "v1", Select: selectFrom(star(), table("v2"))}, false, tables))
+
+	// replacing v2 with a view on v1 makes both views circular
+	require.NoError(t, c.Drop("v2", false))
+	err := c.Create(&View{Name: "v2", Select: selectFrom(star(), table("v1"))}, false, tables)
+	assert.True(t, errors.Is(err, ErrCircular))
+	assert.EqualError(t, err, "view v2: view v1: view is circularly defined: v2")
+}
+
+func TestWritable(t *testing.T) {
+	triggers := trigger.NewCatalog()
+	require.NoError(t, triggers.Create(&trigger.Trigger{Name: "t", Table: "v", Time: trigger.InsteadOf, Event: trigger.Insert}, true, false))
+	x := trigger.NewRunner(triggers).Begin()
+
+	assert.NoError(t, Writable(x, "v", trigger.Insert, nil))
+	err := Writable(x, "v", trigger.Delete, nil)
+	assert.True(t, errors.Is(err, ErrReadOnly))
+}
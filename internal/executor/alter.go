@@ -0,0 +1,343 @@
+package executor
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/alter"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// alterTable executes ALTER TABLE. The table is locked exclusively, so that
+// the statement waits for the transactions, that write its rows. The compiled
+// table is changed by alter.Schema, which checks the change, rewrites the rows
+// of DROP COLUMN and edits the views, that refer to a renamed table or column.
+// Like in SQLite, the definitions of the catalog, that refer to the table, are
+// edited, and the schema is compiled again, so that the statement fails, if a
+// definition does not compile anymore.
+func (x *execution) alterTable(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.AlterTableStmt
+	db, s, t, err := x.table(token.Unquote(stmt.SchemaName), cmd.Name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(strings.ToLower(t.name), "sqlite_") {
+		return nil, fmt.Errorf("%w: %v", ErrReservedName, t.name)
+	}
+	if err := s.store.lock(t.id, "", lock.Exclusive); err != nil {
+		return nil, err
+	}
+	if stmt.NewTableName != nil {
+		name := token.Unquote(stmt.NewTableName)
+		if s.hasView(name) || s.hasIndex(name) {
+			return nil, fmt.Errorf("%w: %v", alter.ErrTableExists, name)
+		}
+		if strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+			return nil, fmt.Errorf("%w: %v", ErrReservedName, name)
+		}
+	}
+
+	if err := x.changeTable(cmd, s, t); err != nil {
+		// the compiled schema may have been changed
+		delete(x.loaded, db)
+		delete(x.e.schemas, db)
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
+
+// changeTable changes the given table of the given schema, and the records of
+// the catalog, as in the given ALTER TABLE command.
+func (x *execution) changeTable(cmd command.Command, s *schema, t *storedTable) error {
+	stmt := cmd.Stmt.AlterTableStmt
+	if err := s.alter.Exec(stmt); err != nil {
+		return err
+	}
+	c := catalog{s.store.tx}
+	objects, err := c.objects()
+	if err != nil {
+		return err
+	}
+	for _, o := range objects {
+		changed, err := alterObject(cmd, s, t, o)
+		if err != nil {
+			return fmt.Errorf("%v %v: %w", o.typ, o.name, err)
+		}
+		if changed == o {
+			continue
+		}
+		if !strings.EqualFold(changed.name, o.name) {
+			if err := c.delete(o.name); err != nil {
+				return err
+			}
+		}
+		if err := c.put(changed); err != nil {
+			return err
+		}
+	}
+
+	if stmt.NewTableName == nil {
+		return nil
+	}
+	// the AUTOINCREMENT sequence is stored by the name of the table
+	seq, ok, err := c.tx.Get(sequenceKey(t.name))
+	if err != nil || !ok {
+		return err
+	}
+	if err := c.tx.Delete(sequenceKey(t.name)); err != nil {
+		return err
+	}
+	return c.tx.Put(sequenceKey(token.Unquote(stmt.NewTableName)), seq)
+}
+
+// alterObject returns the given object of the catalog, as it is after the
+// given ALTER TABLE command changed the given table. The SQL of the views has
+// already been edited by alter.Schema.
+func alterObject(cmd command.Command, s *schema, t *storedTable, o object) (object, error) {
+	if o.typ == typeView {
+		if v, ok := s.views.View(o.name); ok {
+			o.sql = v.SQL
+		}
+		return o, nil
+	}
+	parsed, err := parseObject(o.sql)
+	if err != nil {
+		return o, err
+	}
+	own := strings.EqualFold(o.table, t.name)
+	stmt := cmd.Stmt.AlterTableStmt
+	var edits []edit
+	switch {
+	case stmt.NewTableName != nil:
+		newName := token.Unquote(stmt.NewTableName)
+		edits = renamedTable(parsed, t.name, quote(newName))
+		if own {
+			o.table = newName
+			if o.typ == typeTable {
+				o.name = newName
+			}
+		}
+	case stmt.Rename != nil:
+		text := quote(token.Unquote(stmt.NewColumnName))
+		for _, tk := range columnReferences(parsed, o, own, t.name, token.Unquote(stmt.ColumnName)) {
+			edits = append(edits, replaced(tk, text))
+		}
+	case stmt.Add != nil && own && o.typ == typeTable:
+		// the column is defined after the other columns, which are followed
+		// by the constraints of the table
+		defs := parsed.CreateTableStmt.ColumnDef
+		edits = []edit{{
+			offset: tokensEnd(defs[len(defs)-1]),
+			text:   ", " + cmd.Text(stmt.ColumnDef.ColumnName),
+		}}
+	case stmt.Drop != nil && own && o.typ == typeTable:
+		edits = droppedColumn(parsed.CreateTableStmt, token.Unquote(stmt.ColumnName))
+	case stmt.Drop != nil && o.typ == typeTrigger:
+		// the programs of triggers are compiled when they fire, so they are
+		// checked here
+		column := token.Unquote(stmt.ColumnName)
+		if len(columnReferences(parsed, o, own, t.name, column)) > 0 {
+			return o, fmt.Errorf("%w: %v: trigger %v", alter.ErrCannotDrop, column, o.name)
+		}
+	}
+	o.sql = applyEdits(o.sql, edits)
+	return o, nil
+}
+
+// edit replaces the text at an offset of the SQL of an object. Like the
+// offsets of tokens, the offset and length count runes.
+type edit struct {
+	offset, length int
+	text           string
+}
+
+// replaced returns the edit, that replaces the given token.
+func replaced(tk token.Token, text string) edit {
+	return edit{offset: tk.Offset(), length: tk.Length(), text: text}
+}
+
+// applyEdits returns the given SQL with the given edits. An edit of a token,
+// that appears in more than one field of an AST, is applied once.
+func applyEdits(sql string, edits []edit) string {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].offset > edits[j].offset })
+	text := []rune(sql)
+	applied := make(map[int]bool)
+	for _, e := range edits {
+		if applied[e.offset] || e.offset < 0 || e.offset+e.length > len(text) {
+			continue
+		}
+		applied[e.offset] = true
+		rest := append([]rune(e.text), text[e.offset+e.length:]...)
+		text = append(text[:e.offset], rest...)
+	}
+	return string(text)
+}
+
+// renamedTable returns the edits, that rename all references to the table
+// with the given name in the given statement. The names of common table
+// expressions are not references to tables.
+func renamedTable(stmt *ast.SQLStmt, name, text string) []edit {
+	var edits []edit
+	identifiers(stmt, func(path []interface{}, field string, tk token.Token) {
+		switch path[len(path)-1].(type) {
+		case *ast.CommonTableExpression, *ast.CteTableName:
+			return
+		}
+		if (field == "TableName" || field == "ForeignTable") && strings.EqualFold(token.Unquote(tk), name) {
+			edits = append(edits, replaced(tk, text))
+		}
+	})
+	return edits
+}
+
+// columnReferences returns the tokens, that refer to the given column of the
+// given table in the given statement of the given object. Own is set, if the
+// object is the table or belongs to it. In the statements of a trigger,
+// references to the column are qualified by NEW, OLD or the name of the
+// table, or are columns of an INSERT, UPDATE or DELETE of the table.
+func columnReferences(stmt *ast.SQLStmt, o object, own bool, table, column string) []token.Token {
+	trigger := o.typ == typeTrigger
+	var refs []token.Token
+	identifiers(stmt, func(path []interface{}, field string, tk token.Token) {
+		if field != "ColumnName" || !strings.EqualFold(token.Unquote(tk), column) {
+			return
+		}
+		found := false
+		switch n := path[len(path)-1].(type) {
+		case *ast.ColumnDef, *ast.IndexedColumn, *ast.TableConstraint:
+			found = own && !trigger
+		case *ast.ForeignKeyClause:
+			found = strings.EqualFold(token.Unquote(n.ForeignTable), table)
+		case *ast.CreateTriggerStmt:
+			found = own
+		case *ast.InsertStmt:
+			found = strings.EqualFold(token.Unquote(n.TableName), table)
+		case *ast.UpdateSetter, *ast.ColumnNameList:
+			found = changes(path, table)
+		case *ast.Expr:
+			qualifier := strings.ToUpper(token.Unquote(n.TableName))
+			switch {
+			case n.TableName == nil && trigger:
+				found = changes(path, table)
+			case n.TableName == nil:
+				found = own
+			case qualifier == "NEW" || qualifier == "OLD":
+				found = own && trigger
+			default:
+				found = strings.EqualFold(qualifier, table)
+			}
+		}
+		if found {
+			refs = append(refs, tk)
+		}
+	})
+	return refs
+}
+
+// changes reports whether the innermost statement of the given path is an
+// UPDATE or DELETE of the given table.
+func changes(path []interface{}, table string) bool {
+	for i := len(path) - 1; i >= 0; i-- {
+		var target *ast.QualifiedTableName
+		switch n := path[i].(type) {
+		case *ast.UpdateStmt:
+			target = n.QualifiedTableName
+		case *ast.DeleteStmt:
+			target = n.QualifiedTableName
+		case *ast.InsertStmt, *ast.SelectStmt:
+			return false
+		default:
+			continue
+		}
+		return target != nil && strings.EqualFold(token.Unquote(target.TableName), table)
+	}
+	return false
+}
+
+// droppedColumn returns the edit, that removes the definition of the given
+// column, and the comma, that separates it, from the given CREATE TABLE
+// statement.
+func droppedColumn(stmt *ast.CreateTableStmt, column string) []edit {
+	defs := stmt.ColumnDef
+	for i, def := range defs {
+		if !strings.EqualFold(token.Unquote(def.ColumnName), column) {
+			continue
+		}
+		start, end := def.ColumnName.Offset(), tokensEnd(def)
+		switch {
+		case i+1 < len(defs):
+			end = defs[i+1].ColumnName.Offset()
+		case len(stmt.TableConstraint) > 0:
+			end = ast.Tokens(stmt.TableConstraint[0])[0].Offset()
+		case i > 0:
+			start = tokensEnd(defs[i-1])
+		}
+		return []edit{{offset: start, length: end - start}}
+	}
+	return nil
+}
+
+// tokensEnd returns the offset after the last token of the given node.
+func tokensEnd(node interface{}) int {
+	tokens := ast.Tokens(node)
+	if len(tokens) == 0 {
+		return 0
+	}
+	last := tokens[len(tokens)-1]
+	return last.Offset() + last.Length()
+}
+
+var tokenType = reflect.TypeOf((*token.Token)(nil)).Elem()
+
+// identifiers calls fn for every token of the given node of an AST, with the
+// nodes from the given one to the node, that has the token in the field with
+// the given name.
+func identifiers(node interface{}, fn func(path []interface{}, field string, tk token.Token)) {
+	visitTokens(reflect.ValueOf(node), nil, fn)
+}
+
+func visitTokens(v reflect.Value, path []interface{}, fn func(path []interface{}, field string, tk token.Token)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if v.Elem().Kind() != reflect.Struct {
+			visitTokens(v.Elem(), path, fn)
+			return
+		}
+		path = append(path, v.Interface())
+		node := v.Elem()
+		for i := 0; i < node.NumField(); i++ {
+			field, name := node.Field(i), node.Type().Field(i).Name
+			switch {
+			case field.Type() == tokenType:
+				if !field.IsNil() {
+					fn(path, name, field.Interface().(token.Token))
+				}
+			case field.Kind() == reflect.Slice && field.Type().Elem() == tokenType:
+				for j := 0; j < field.Len(); j++ {
+					if tk := field.Index(j); !tk.IsNil() {
+						fn(path, name, tk.Interface().(token.Token))
+					}
+				}
+			default:
+				visitTokens(field, path, fn)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if elem.Kind() == reflect.Struct {
+				elem = elem.Addr()
+			}
+			visitTokens(elem, path, fn)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/stats"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// analyze executes ANALYZE, which gathers the statistics of all tables, of
+// the tables of a schema, or of a single table. Like in SQLite, the name of
+// an index analyzes its table. The statistics are stored with the rows of the
+// table, like the sqlite_stat1 table of SQLite, and change the version of the
+// catalog, so that every session plans its queries with them.
+func (x *execution) analyze(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.AnalyzeStmt
+	if cmd.Name != "" && stmt.Period == nil {
+		if db, ok := x.e.session.Database(cmd.Name); ok {
+			return table{}, x.analyzeDatabase(db)
+		}
+	}
+	if cmd.Name == "" {
+		for _, db := range x.e.session.Databases() {
+			if err := x.analyzeDatabase(db); err != nil {
+				return nil, err
+			}
+		}
+		return table{}, nil
+	}
+
+	schemaName := ""
+	if stmt.Period != nil {
+		schemaName = token.Unquote(stmt.SchemaName)
+	}
+	db, s, err := x.resolve(schemaName, cmd.Name, func(s *schema, name string) bool {
+		_, ok := s.table(name)
+		return ok || s.hasIndex(name)
+	})
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		return nil, fmt.Errorf("%w: %v", ErrAnalyzeObject, cmd.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, ok := s.table(cmd.Name)
+	if !ok {
+		o := s.indexes[strings.ToLower(cmd.Name)]
+		if t, ok = s.table(o.table); !ok {
+			return nil, fmt.Errorf("%w: no such table: %v", ErrCorrupt, o.table)
+		}
+	}
+	if err := analyzeTable(s, t); err != nil {
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
+
+// analyzeDatabase analyzes all tables of the given database.
+func (x *execution) analyzeDatabase(db *attach.Database) error {
+	s, err := x.schema(db)
+	if err != nil {
+		return err
+	}
+	if len(s.tables) == 0 {
+		return nil
+	}
+	for _, t := range s.tables {
+		if err := analyzeTable(s, t); err != nil {
+			return err
+		}
+	}
+	_, err = x.reload(db)
+	return err
+}
+
+// analyzeTable computes the statistics of the given table and its secondary
+// indexes, and stores them.
+func analyzeTable(s *schema, t *storedTable) error {
+	ts, err := stats.AnalyzeTable(t.width(), func(fn func(row [][]byte) error) error {
+		return t.t.Rows().Scan(func(_ index.RowID, row constraint.Row) error {
+			values := make([][]byte, len(row))
+			for i, v := range row {
+				values[i] = constraint.Encode(v)
+			}
+			return fn(values)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("analyze %v: %w", t.name, err)
+	}
+	for _, ix := range t.t.Indexes().Indexes() {
+		rows, err := ix.Len()
+		if err != nil {
+			return err
+		}
+		distinct, err := ix.DistinctPrefixes()
+		if err != nil {
+			return err
+		}
+		ts.Indexes[strings.ToLower(ix.Definition().Name)] = stats.AnalyzeIndex(int64(rows), distinct)
+	}
+
+	value, err := json.Marshal(ts)
+	if err != nil {
+		return err
+	}
+	c := catalog{s.store.tx}
+	if err := c.tx.Put(statsKey(t.id), value); err != nil {
+		return err
+	}
+	return c.changed()
+}
+
+// loadStats reads the statistics of the tables of the given schema. The
+// statistics of a table, whose columns have changed since it was analyzed,
+// are ignored.
+func loadStats(tx *transaction.Tx, s *schema) error {
+	for _, t := range s.tables {
+		value, ok, err := tx.Get(statsKey(t.id))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		var ts stats.Table
+		if err := json.Unmarshal(value, &ts); err != nil {
+			return fmt.Errorf("%w: statistics of %v: %v", ErrCorrupt, t.name, err)
+		}
+		if len(ts.Columns) == t.width() {
+			s.stats.Put(t.name, &ts)
+		}
+	}
+	return nil
+}
+
+// statsKey returns the key of the statistics of the table with the given ID.
+func statsKey(id int64) []byte {
+	return append(tablePrefixOf(id), statsPrefix)
+}
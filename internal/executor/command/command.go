@@ -1,13 +1,328 @@
 package command
 
-import "github.com/tomarrell/lbadd/internal/parser/ast"
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+//go:generate stringer -type=Op
+
+// Op is the operation of a command.
+type Op uint8
+
+// Supported operations.
+const (
+	// Begin begins a transaction with the Mode of the command.
+	Begin Op = iota + 1
+	// Commit commits the active transaction.
+	Commit
+	// Rollback rolls back the active transaction.
+	Rollback
+	// RollbackTo rolls back the active transaction to the savepoint with the
+	// Name of the command.
+	RollbackTo
+	// Savepoint creates a savepoint with the Name of the command.
+	Savepoint
+	// Release releases the savepoint with the Name of the command.
+	Release
+	// Attach attaches the database File under the schema Name.
+	Attach
+	// Detach detaches the database with the schema Name.
+	Detach
+	// Select returns the rows of the Plan.
+	Select
+	// Insert, Update and Delete modify a relation, as described by the
+	// root of the Plan, with the Conflict algorithm of the command.
+	Insert
+	Update
+	Delete
+	// CreateTable creates the table of the CREATE TABLE statement Stmt. The
+	// Plan of CREATE TABLE ... AS SELECT returns the rows of the table.
+	CreateTable
+	// DropTable drops the table of the DROP TABLE statement Stmt.
+	DropTable
+	// CreateIndex creates the index of the CREATE INDEX statement Stmt.
+	CreateIndex
+	// DropIndex drops the index of the DROP INDEX statement Stmt.
+	DropIndex
+	// Reindex rebuilds the indexes, that the REINDEX statement Stmt names.
+	Reindex
+	// Pragma reads the pragma with the Name of the command, or sets it to
+	// the Value of the command, as in the PRAGMA statement Stmt.
+	Pragma
+	// CreateTrigger creates the trigger with the Name of the command, that
+	// the CREATE TRIGGER statement Stmt defines.
+	CreateTrigger
+	// DropTrigger drops the trigger with the Name of the command, as in the
+	// DROP TRIGGER statement Stmt.
+	DropTrigger
+	// CreateView creates the view with the Name of the command, that the
+	// CREATE VIEW statement Stmt defines.
+	CreateView
+	// DropView drops the view with the Name of the command, as in the DROP
+	// VIEW statement Stmt.
+	DropView
+	// Analyze gathers the statistics of the tables and indexes, that the
+	// ANALYZE statement Stmt names. The Name of the command is the name of
+	// the schema, table or index, or empty for all tables.
+	Analyze
+	// AlterTable changes the table with the Name of the command, as in the
+	// ALTER TABLE statement Stmt.
+	AlterTable
+)
+
+// Explain is the kind of explanation, that is returned instead of executing a
+// command.
+type Explain uint8
+
+// Supported explanations.
+const (
+	// NoExplain executes the command.
+	NoExplain Explain = iota
+	// ExplainProgram returns the program of the command, like EXPLAIN.
+	ExplainProgram
+	// ExplainQueryPlan returns the plan of the command, like EXPLAIN QUERY
+	// PLAN.
+	ExplainQueryPlan
+	// ExplainAnalyze executes the command, and returns the statistics of
+	// the operators of its plan, like EXPLAIN ANALYZE.
+	ExplainAnalyze
+)
 
 // Command is the intermediate representation (IR) of an SQL ast.
 type Command struct {
+	Op Op
+	// Explain is set, if the command is explained instead of executed.
+	Explain Explain
+	// Mode is the mode of the transaction, that Begin begins.
+	Mode transaction.Mode
+	// Name is the name of the savepoint of RollbackTo, Savepoint and
+	// Release, the schema name of Attach and Detach, and the name of the
+	// object, that a statement of the schema creates or drops.
+	Name string
+	// File is the name of the database file of Attach.
+	File string
+	// Value is the value of Pragma without quotes, or empty if the pragma
+	// is only read.
+	Value string
+	// Plan is the plan of the relations that the command reads, or nil if
+	// it does not read any. The executor rewrites it with the rules of the
+	// optimizer, before it is explained or executed.
+	Plan *planner.Node
+	// Conflict is the conflict algorithm of the OR clause of Insert and
+	// Update.
+	Conflict constraint.Conflict
+	// Stmt is the statement of commands, that change the schema. They need
+	// its definitions, which are stored in the schema as SQL text.
+	Stmt *ast.SQLStmt
+	// SQL is the text, that Stmt was parsed from. The offsets of the tokens
+	// of Stmt refer to it.
+	SQL string
+}
+
+// Text returns the SQL text of Stmt, from the given token of it to the end of
+// the statement. Without SQL, the text is rendered from the tokens, which
+// loses the commas between the elements of lists.
+func (c Command) Text(from token.Token) string {
+	tokens := ast.Tokens(c.Stmt)
+	if c.SQL == "" || len(tokens) == 0 {
+		var rest []token.Token
+		for _, t := range tokens {
+			if t.Offset() >= from.Offset() {
+				rest = append(rest, t)
+			}
+		}
+		return ast.Text(rest)
+	}
+	last := tokens[len(tokens)-1]
+	sql := []rune(c.SQL)
+	end := last.Offset() + last.Length()
+	if from.Offset() < 0 || end > len(sql) || from.Offset() > end {
+		return ast.Text(tokens)
+	}
+	return string(sql[from.Offset():end])
 }
 
 // From converts the given (*ast.SQLStmt) to the IR, which is a
 // (command.Command).
 func From(stmt *ast.SQLStmt) (Command, error) {
-	return Command{}, nil
+	explain := NoExplain
+	switch {
+	case stmt.Analyze != nil:
+		explain = ExplainAnalyze
+	case stmt.Query != nil:
+		explain = ExplainQueryPlan
+	case stmt.Explain != nil:
+		explain = ExplainProgram
+	}
+	cmd, err := from(stmt)
+	if err != nil {
+		return Command{}, err
+	}
+	cmd.Explain = explain
+	return cmd, nil
+}
+
+func from(stmt *ast.SQLStmt) (Command, error) {
+	switch {
+	case stmt.BeginStmt != nil:
+		cmd := Command{Op: Begin}
+		switch {
+		case stmt.BeginStmt.Immediate != nil:
+			cmd.Mode = transaction.Immediate
+		case stmt.BeginStmt.Exclusive != nil:
+			cmd.Mode = transaction.Exclusive
+		}
+		return cmd, nil
+	case stmt.CommitStmt != nil:
+		return Command{Op: Commit}, nil
+	case stmt.RollbackStmt != nil:
+		if stmt.RollbackStmt.To == nil {
+			return Command{Op: Rollback}, nil
+		}
+		return savepoint(RollbackTo, stmt.RollbackStmt.SavepointName)
+	case stmt.SavepointStmt != nil:
+		return savepoint(Savepoint, stmt.SavepointStmt.SavepointName)
+	case stmt.ReleaseStmt != nil:
+		return savepoint(Release, stmt.ReleaseStmt.SavepointName)
+	case stmt.AttachStmt != nil:
+		attach := stmt.AttachStmt
+		if attach.Expr == nil || attach.SchemaName == nil {
+			return Command{}, ErrNotLiteral
+		}
+		// a file name in double quotes is parsed as a column name
+		file := attach.Expr.LiteralValue
+		if file == nil && attach.Expr.TableName == nil {
+			file = attach.Expr.ColumnName
+		}
+		if file == nil {
+			return Command{}, ErrNotLiteral
+		}
+		return Command{Op: Attach, File: token.Unquote(file), Name: token.Unquote(attach.SchemaName)}, nil
+	case stmt.DetachStmt != nil:
+		if stmt.DetachStmt.SchemaName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: Detach, Name: token.Unquote(stmt.DetachStmt.SchemaName)}, nil
+	case stmt.SelectStmt != nil:
+		return selectCommand(stmt.SelectStmt)
+	case stmt.InsertStmt != nil:
+		return insertCommand(stmt.InsertStmt)
+	case stmt.UpdateStmt != nil:
+		return updateCommand(stmt.UpdateStmt)
+	case stmt.DeleteStmt != nil:
+		return deleteCommand(stmt.DeleteStmt)
+	case stmt.CreateTableStmt != nil:
+		return createTableCommand(stmt)
+	case stmt.DropTableStmt != nil:
+		if stmt.DropTableStmt.TableName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: DropTable, Name: token.Unquote(stmt.DropTableStmt.TableName), Stmt: stmt}, nil
+	case stmt.CreateIndexStmt != nil:
+		create := stmt.CreateIndexStmt
+		if create.IndexName == nil || create.TableName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: CreateIndex, Name: token.Unquote(create.IndexName), Stmt: stmt}, nil
+	case stmt.DropIndexStmt != nil:
+		if stmt.DropIndexStmt.IndexName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: DropIndex, Name: token.Unquote(stmt.DropIndexStmt.IndexName), Stmt: stmt}, nil
+	case stmt.ReindexStmt != nil:
+		cmd := Command{Op: Reindex, Stmt: stmt}
+		if stmt.ReindexStmt.TableOrIndexName != nil {
+			cmd.Name = token.Unquote(stmt.ReindexStmt.TableOrIndexName)
+		}
+		return cmd, nil
+	case stmt.PragmaStmt != nil:
+		return pragmaCommand(stmt)
+	case stmt.CreateTriggerStmt != nil:
+		create := stmt.CreateTriggerStmt
+		if create.TriggerName == nil || create.TableName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: CreateTrigger, Name: token.Unquote(create.TriggerName), Stmt: stmt}, nil
+	case stmt.DropTriggerStmt != nil:
+		if stmt.DropTriggerStmt.TriggerName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: DropTrigger, Name: token.Unquote(stmt.DropTriggerStmt.TriggerName), Stmt: stmt}, nil
+	case stmt.CreateViewStmt != nil:
+		create := stmt.CreateViewStmt
+		if create.ViewName == nil || create.SelectStmt == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: CreateView, Name: token.Unquote(create.ViewName), Stmt: stmt}, nil
+	case stmt.DropViewStmt != nil:
+		if stmt.DropViewStmt.ViewName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: DropView, Name: token.Unquote(stmt.DropViewStmt.ViewName), Stmt: stmt}, nil
+	case stmt.AnalyzeStmt != nil:
+		cmd := Command{Op: Analyze, Stmt: stmt}
+		if stmt.AnalyzeStmt.TableOrIndexName != nil {
+			cmd.Name = token.Unquote(stmt.AnalyzeStmt.TableOrIndexName)
+		}
+		return cmd, nil
+	case stmt.AlterTableStmt != nil:
+		if stmt.AlterTableStmt.TableName == nil {
+			return Command{}, ErrMissingName
+		}
+		return Command{Op: AlterTable, Name: token.Unquote(stmt.AlterTableStmt.TableName), Stmt: stmt}, nil
+	}
+	return Command{}, ErrUnsupported
+}
+
+// pragmaCommand builds the command of a PRAGMA statement. Pragma names are
+// case insensitive, so the name is lower case.
+func pragmaCommand(stmt *ast.SQLStmt) (Command, error) {
+	pragma := stmt.PragmaStmt
+	if pragma.PragmaName == nil {
+		return Command{}, ErrMissingName
+	}
+	cmd := Command{Op: Pragma, Name: strings.ToLower(token.Unquote(pragma.PragmaName)), Stmt: stmt}
+	if value := pragma.PragmaValue; value != nil {
+		switch {
+		case value.SignedNumber != nil && value.SignedNumber.Sign != nil:
+			cmd.Value = value.SignedNumber.Sign.Value() + value.SignedNumber.NumericLiteral.Value()
+		case value.SignedNumber != nil:
+			cmd.Value = value.SignedNumber.NumericLiteral.Value()
+		default:
+			cmd.Value = token.Unquote(value.Name)
+		}
+	}
+	return cmd, nil
+}
+
+// savepoint creates a command with the given operation, for the savepoint
+// with the given name.
+func savepoint(op Op, name token.Token) (Command, error) {
+	if name == nil {
+		return Command{}, ErrMissingName
+	}
+	return Command{Op: op, Name: token.Unquote(name)}, nil
+}
+
+// createTableCommand builds the command of a CREATE TABLE statement. The
+// plan of CREATE TABLE ... AS SELECT is the plan of its SELECT.
+func createTableCommand(stmt *ast.SQLStmt) (Command, error) {
+	create := stmt.CreateTableStmt
+	if create.TableName == nil {
+		return Command{}, ErrMissingName
+	}
+	cmd := Command{Op: CreateTable, Name: token.Unquote(create.TableName), Stmt: stmt}
+	if create.SelectStmt != nil {
+		var err error
+		if cmd.Plan, _, err = selectPlan(create.SelectStmt); err != nil {
+			return Command{}, err
+		}
+	}
+	return cmd, nil
 }
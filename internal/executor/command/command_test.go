@@ -0,0 +1,344 @@
+package command
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/parser"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    Command
+		wantErr error
+	}{
+		{"begin", "BEGIN", Command{Op: Begin, Mode: transaction.Deferred}, nil},
+		{"begin immediate", "BEGIN IMMEDIATE TRANSACTION", Command{Op: Begin, Mode: transaction.Immediate}, nil},
+		{"begin exclusive", "BEGIN EXCLUSIVE", Command{Op: Begin, Mode: transaction.Exclusive}, nil},
+		{"commit", "COMMIT", Command{Op: Commit}, nil},
+		{"end", "END TRANSACTION", Command{Op: Commit}, nil},
+		{"rollback", "ROLLBACK", Command{Op: Rollback}, nil},
+		{"rollback to", "ROLLBACK TO SAVEPOINT sp", Command{Op: RollbackTo, Name: "sp"}, nil},
+		{"savepoint", `SAVEPOINT "my sp"`, Command{Op: Savepoint, Name: "my sp"}, nil},
+		{"release", "RELEASE sp", Command{Op: Release, Name: "sp"}, nil},
+		{"release savepoint", "RELEASE SAVEPOINT sp", Command{Op: Release, Name: "sp"}, nil},
+		{"attach", "ATTACH DATABASE 'archive.db' AS \"my archive\"", Command{Op: Attach, File: "archive.db", Name: "my archive"}, nil},
+		{"detach", "DETACH DATABASE archive", Command{Op: Detach, Name: "archive"}, nil},
+		{"unsupported", "VACUUM", Command{}, ErrUnsupported},
+		{"explain", "EXPLAIN BEGIN", Command{Op: Begin, Explain: ExplainProgram}, nil},
+		{"explain query plan", "EXPLAIN QUERY PLAN SAVEPOINT sp", Command{Op: Savepoint, Explain: ExplainQueryPlan, Name: "sp"}, nil},
+		{"explain analyze", "EXPLAIN ANALYZE BEGIN", Command{Op: Begin, Explain: ExplainAnalyze, Mode: transaction.Deferred}, nil},
+		{"pragma value", "PRAGMA foreign_keys = ON", Command{Op: Pragma, Name: "foreign_keys", Value: "ON"}, nil},
+		{"pragma argument", "PRAGMA foreign_key_check('t')", Command{Op: Pragma, Name: "foreign_key_check", Value: "t"}, nil},
+		{"pragma number", "PRAGMA cache_size = -2000", Command{Op: Pragma, Name: "cache_size", Value: "-2000"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, errs, ok := parser.New(tt.query).Next()
+			require.True(t, ok)
+			require.Empty(t, errs)
+
+			got, err := From(stmt)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(t, err)
+			// the statement is compared in TestFrom_Schema
+			got.Stmt = nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFrom_Plan(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		op       Op
+		conflict constraint.Conflict
+		// want is the detail of every node of the plan, indented by its
+		// depth
+		want []string
+	}{
+		{
+			"select",
+			"SELECT DISTINCT u.name AS n, count(*) FROM users AS u LEFT JOIN orders o ON o.user = u.id WHERE u.age >= 18 GROUP BY n HAVING count(*) > 1 ORDER BY 2 DESC LIMIT 10 OFFSET 5",
+			Select, constraint.Default,
+			[]string{
+				"LIMIT 10 OFFSET 5",
+				"  DISTINCT",
+				"    PROJECT u.name, count(*)",
+				"      ORDER BY count(*) DESC",
+				"        FILTER count(*) > 1",
+				"          AGGREGATE count(*) GROUP BY u.name",
+				"            FILTER u.age >= 18",
+				"              LEFT JOIN ON o.user = u.id",
+				"                SCAN users AS u",
+				"                SCAN orders AS o",
+			},
+		},
+		{
+			"compound",
+			"SELECT a FROM t UNION SELECT b FROM s UNION SELECT c FROM r EXCEPT SELECT d FROM q ORDER BY a DESC NULLS FIRST LIMIT 2, 3",
+			Select, constraint.Default,
+			[]string{
+				"LIMIT 3 OFFSET 2",
+				"  ORDER BY a DESC NULLS FIRST",
+				"    COMPOUND EXCEPT",
+				"      COMPOUND UNION",
+				"        PROJECT a",
+				"          SCAN t",
+				"        PROJECT b",
+				"          SCAN s",
+				"        PROJECT c",
+				"          SCAN r",
+				"      PROJECT d",
+				"        SCAN q",
+			},
+		},
+		{
+			"values",
+			"VALUES (1, 'a'), (2, x'0A') ORDER BY column2",
+			Select, constraint.Default,
+			[]string{
+				"ORDER BY column2",
+				"  SCAN 2 CONSTANT ROWS",
+			},
+		},
+		{
+			"expressions",
+			"SELECT * FROM t WHERE a IN (1, 2) AND b NOT BETWEEN 1 AND 3 AND c IS NOT NULL AND d NOT LIKE 'a%' AND EXISTS (SELECT 1 FROM s WHERE s.a = t.a)",
+			Select, constraint.Default,
+			[]string{
+				"PROJECT *",
+				"  FILTER a IN (1, 2) AND NOT (b >= 1 AND b <= 3) AND c IS NOT NULL AND NOT d LIKE 'a%' AND EXISTS (SUBQUERY)",
+				"    SCAN t",
+				"    SUBQUERY",
+				"      PROJECT 1",
+				"        FILTER s.a = t.a",
+				"          SCAN s",
+			},
+		},
+		{
+			"case, cast and scalar subquery",
+			"SELECT CASE a WHEN 1 THEN 'one' ELSE 'many' END, CAST(b AS INTEGER), -(SELECT max(c) FROM s) FROM t JOIN s USING (id)",
+			Select, constraint.Default,
+			[]string{
+				"PROJECT CASE WHEN a = 1 THEN 'one' ELSE 'many' END, CAST(b AS INTEGER), -(SUBQUERY)",
+				"  INNER JOIN ON t.id = s.id",
+				"    SCAN t",
+				"    SCAN s",
+				"  SUBQUERY",
+				"    PROJECT max(c)",
+				"      AGGREGATE max(c)",
+				"        SCAN s",
+			},
+		},
+		{
+			"subquery in from",
+			"SELECT sub.a FROM (SELECT a FROM t LIMIT 1) AS sub, s",
+			Select, constraint.Default,
+			[]string{
+				"PROJECT sub.a",
+				"  INNER JOIN",
+				"    PROJECT * AS sub",
+				"      LIMIT 1",
+				"        PROJECT a",
+				"          SCAN t",
+				"    SCAN s",
+			},
+		},
+		{
+			"insert values",
+			"INSERT OR IGNORE INTO main.t (a, b) VALUES (1, -2), (3, 4)",
+			Insert, constraint.Ignore,
+			[]string{
+				"INSERT INTO main.t (a, b)",
+				"  SCAN 2 CONSTANT ROWS",
+			},
+		},
+		{
+			"insert default values",
+			"INSERT INTO t DEFAULT VALUES",
+			Insert, constraint.Default,
+			[]string{
+				"INSERT INTO t DEFAULT VALUES",
+				"  SCAN CONSTANT ROW",
+			},
+		},
+		{
+			"replace select",
+			"REPLACE INTO t SELECT * FROM s",
+			Insert, constraint.Replace,
+			[]string{
+				"INSERT INTO t",
+				"  PROJECT *",
+				"    SCAN s",
+			},
+		},
+		{
+			"update",
+			"UPDATE OR FAIL t AS u SET a = u.a + 1, (b, c) = (1, 2) WHERE id = 3",
+			Update, constraint.Fail,
+			[]string{
+				"UPDATE t SET a = u.a + 1, b = 1, c = 2",
+				"  FILTER id = 3",
+				"    SCAN t AS u",
+			},
+		},
+		{
+			"delete",
+			"DELETE FROM main.t WHERE a = 1",
+			Delete, constraint.Default,
+			[]string{
+				"DELETE FROM main.t",
+				"  FILTER a = 1",
+				"    SCAN main.t",
+			},
+		},
+		{
+			"inlined cte",
+			"WITH big (n) AS (SELECT a FROM t WHERE a > 1) SELECT n FROM big",
+			Select, constraint.Default,
+			[]string{
+				"PROJECT n",
+				"  CTE big (n)",
+				"    PROJECT a",
+				"      FILTER a > 1",
+				"        SCAN t",
+			},
+		},
+		{
+			"cte referenced twice",
+			"WITH s AS (SELECT a FROM t) SELECT s.a FROM s JOIN s AS s2 ON s.a = s2.a",
+			Select, constraint.Default,
+			[]string{
+				"MATERIALIZE s",
+				"  CTE s",
+				"    PROJECT a",
+				"      SCAN t",
+				"  PROJECT s.a",
+				"    INNER JOIN ON s.a = s2.a",
+				"      SCAN s",
+				"      SCAN s AS s2",
+			},
+		},
+		{
+			"recursive cte",
+			"WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM cnt WHERE n < 5), unused AS (SELECT 2) SELECT n FROM cnt",
+			Select, constraint.Default,
+			[]string{
+				"MATERIALIZE cnt",
+				"  RECURSIVE CTE cnt (n)",
+				"    PROJECT 1",
+				"      SCAN CONSTANT ROW",
+				"    PROJECT n + 1",
+				"      FILTER n < 5",
+				"        SCAN cnt",
+				"  PROJECT n",
+				"    SCAN cnt",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, errs, ok := parser.New(tt.query).Next()
+			require.True(t, ok)
+			require.Empty(t, errs)
+
+			got, err := From(stmt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.op, got.Op)
+			assert.Equal(t, tt.conflict, got.Conflict)
+			assert.Equal(t, tt.want, outline(got.Plan))
+		})
+	}
+}
+
+func TestFrom_PlanErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr error
+	}{
+		{"ordinal out of range", "SELECT a FROM t ORDER BY 2", ErrNotResultColumn},
+		{"compound order by expression", "SELECT a FROM t UNION SELECT b FROM s ORDER BY a + 1", ErrNotResultColumn},
+		{"limit not an integer", "SELECT a FROM t LIMIT 'ten'", ErrNotInteger},
+		{"values count", "VALUES (1, 2), (3)", ErrValuesCount},
+		{"natural join", "SELECT a FROM t NATURAL JOIN s", ErrUnsupported},
+		{"duplicate cte", "WITH a AS (SELECT 1), a AS (SELECT 2) SELECT * FROM a", ErrDuplicateCTE},
+		{"circular cte", "WITH RECURSIVE c (n) AS (SELECT n FROM c) SELECT n FROM c", ErrCircularCTE},
+		{"recursive initial select", "WITH c (n) AS (SELECT n FROM c UNION SELECT 1) SELECT n FROM c", ErrCircularCTE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, errs, ok := parser.New(tt.query).Next()
+			require.True(t, ok)
+			require.Empty(t, errs)
+
+			_, err := From(stmt)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+		})
+	}
+}
+
+// outline returns the detail of every node of the given plan, indented by
+// two spaces per level of depth.
+func outline(plan *planner.Node) []string {
+	depth := make(map[int]int)
+	var lines []string
+	for _, row := range planner.Explain(plan) {
+		if row.Parent != 0 {
+			depth[row.ID] = depth[row.Parent] + 1
+		}
+		lines = append(lines, strings.Repeat("  ", depth[row.ID])+row.Detail)
+	}
+	return lines
+}
+
+func TestFrom_Schema(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		op     Op
+		object string
+	}{
+		{"create table", "CREATE TABLE t (a, b)", CreateTable, "t"},
+		{"drop table", "DROP TABLE IF EXISTS t", DropTable, "t"},
+		{"create index", "CREATE UNIQUE INDEX IF NOT EXISTS ix ON t (a, b DESC)", CreateIndex, "ix"},
+		{"drop index", "DROP INDEX main.ix", DropIndex, "ix"},
+		{"reindex", "REINDEX", Reindex, ""},
+		{"reindex table", "REINDEX main.t", Reindex, "t"},
+		{"pragma", "PRAGMA Foreign_Keys", Pragma, "foreign_keys"},
+		{"create trigger", "CREATE TRIGGER tr AFTER INSERT ON t BEGIN SELECT RAISE(IGNORE); END", CreateTrigger, "tr"},
+		{"drop trigger", "DROP TRIGGER IF EXISTS main.tr", DropTrigger, "tr"},
+		{"create view", "CREATE VIEW v (b) AS SELECT a FROM t", CreateView, "v"},
+		{"drop view", "DROP VIEW IF EXISTS main.v", DropView, "v"},
+		{"analyze", "ANALYZE", Analyze, ""},
+		{"analyze table", "ANALYZE main.t", Analyze, "t"},
+		{"rename table", "ALTER TABLE main.t RENAME TO u", AlterTable, "t"},
+		{"drop column", "ALTER TABLE t DROP COLUMN a", AlterTable, "t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, errs, ok := parser.New(tt.query).Next()
+			require.True(t, ok)
+			require.Empty(t, errs)
+
+			got, err := From(stmt)
+			require.NoError(t, err)
+			assert.Equal(t, tt.op, got.Op)
+			assert.Equal(t, tt.object, got.Name)
+			assert.Same(t, stmt, got.Stmt)
+		})
+	}
+}
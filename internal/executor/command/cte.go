@@ -0,0 +1,231 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// withPlan builds the plan of a SELECT statement with a WITH clause. Like in
+// SQLite, a common table expression is recursive, if it refers to itself, also
+// without RECURSIVE. The planner decides, which CTEs are materialized by a
+// With at the root of the plan. The other CTEs are inlined at every reference,
+// so that the optimizer rewrites them together with the query.
+func withPlan(stmt *ast.SelectStmt) (*planner.Node, []string, error) {
+	query := *stmt
+	query.With, query.Recursive, query.CommonTableExpression = nil, nil, nil
+	plan, names, err := selectPlan(&query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defs := make([]*planner.Node, len(stmt.CommonTableExpression))
+	seen := make(map[string]bool)
+	for i, c := range stmt.CommonTableExpression {
+		name := token.Unquote(c.TableName)
+		if seen[strings.ToLower(name)] {
+			return nil, nil, fmt.Errorf("%w: %v", ErrDuplicateCTE, name)
+		}
+		seen[strings.ToLower(name)] = true
+		if defs[i], err = commonTable(c); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// a CTE can only refer to the CTEs before it, so the CTEs are decided
+	// from the last to the first, and every reference of an inlined CTE
+	// counts as a reference of the CTEs, that it refers to
+	var materialized []*planner.Node
+	for i := len(defs) - 1; i >= 0; i-- {
+		def := defs[i]
+		cte := planner.CTE{Name: def.Relation, Recursive: len(def.Children) > 1, Volatile: volatile(def)}
+		for _, n := range append([]*planner.Node{plan}, materialized...) {
+			cte.References += references(n, def.Relation)
+		}
+		switch {
+		case cte.References == 0:
+		case planner.Materialize(cte):
+			materialized = append([]*planner.Node{def}, materialized...)
+		default:
+			plan = inline(plan, def)
+			for _, n := range materialized {
+				inline(n, def)
+			}
+		}
+	}
+	if len(materialized) == 0 {
+		return plan, names, nil
+	}
+	return &planner.Node{Op: planner.With, Children: append(materialized, plan)}, names, nil
+}
+
+// commonTable builds the CommonTable of a common table expression. A
+// recursive CTE must be a compound SELECT, whose last SELECT is the recursive
+// one, which is combined with the others by UNION or UNION ALL. Its ORDER BY
+// and LIMIT apply to the recursion.
+func commonTable(c *ast.CommonTableExpression) (*planner.Node, error) {
+	name := token.Unquote(c.TableName)
+	node := &planner.Node{Op: planner.CommonTable, Relation: name, Count: -1}
+	for _, col := range c.ColumnName {
+		node.Columns = append(node.Columns, token.Unquote(col))
+	}
+	body, names, err := selectPlan(c.SelectStmt)
+	if err != nil {
+		return nil, err
+	}
+	if references(body, name) == 0 {
+		node.Children = []*planner.Node{body}
+		return node, nil
+	}
+
+	stmt := c.SelectStmt
+	cores := stmt.SelectCore
+	last := cores[len(cores)-1]
+	if len(cores) < 2 || stmt.With != nil || last.CompoundOperator == nil ||
+		last.CompoundOperator.Union == nil {
+		return nil, fmt.Errorf("%w: %v", ErrCircularCTE, name)
+	}
+	initial, initialNames, err := selectPlan(&ast.SelectStmt{SelectCore: cores[:len(cores)-1]})
+	if err != nil {
+		return nil, err
+	}
+	if references(initial, name) != 0 {
+		return nil, fmt.Errorf("%w: %v", ErrCircularCTE, name)
+	}
+	recursive, _, err := corePlan(last, nil)
+	if err != nil {
+		return nil, err
+	}
+	names = initialNames
+	if node.Columns != nil {
+		names = node.Columns
+	}
+	if node.Order, err = outputOrder(stmt.OrderingTerm, names); err != nil {
+		return nil, err
+	}
+	if node.Count, node.Offset, err = limits(stmt); err != nil {
+		return nil, err
+	}
+	node.SetOp = setOp(last.CompoundOperator)
+	node.Children = []*planner.Node{initial, recursive}
+	return node, nil
+}
+
+// visit calls the given function for the given node and the nodes below it,
+// including the plans of subqueries. The nodes below a node are skipped, if
+// the function returns false for it.
+func visit(n *planner.Node, fn func(n *planner.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, child := range n.Children {
+		visit(child, fn)
+	}
+	for _, subquery := range n.Subqueries() {
+		visit(subquery, fn)
+	}
+}
+
+// isReference reports whether the given node is a scan of the CTE with the
+// given name.
+func isReference(n *planner.Node, name string) bool {
+	if n.Op != planner.FullScan || n.Schema != "" {
+		return false
+	}
+	table := n.Table
+	if table == "" {
+		table = n.Relation
+	}
+	return strings.EqualFold(table, name)
+}
+
+// defines reports whether the given node is a With, that defines a CTE with
+// the given name, which hides the CTE of an outer WITH clause.
+func defines(n *planner.Node, name string) bool {
+	if n.Op != planner.With {
+		return false
+	}
+	for _, def := range n.Children[:len(n.Children)-1] {
+		if strings.EqualFold(def.Relation, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// references returns the amount of references of the CTE with the given name
+// in the given plan.
+func references(plan *planner.Node, name string) int {
+	count := 0
+	visit(plan, func(n *planner.Node) bool {
+		if isReference(n, name) {
+			count++
+		}
+		return !defines(n, name)
+	})
+	return count
+}
+
+// volatile reports whether the given CTE calls a function, that is not
+// deterministic.
+func volatile(def *planner.Node) bool {
+	found := false
+	visit(def, func(n *planner.Node) bool {
+		for _, e := range n.Exprs() {
+			walkExpr(e, func(e *planner.Expr) {
+				if e.Op != planner.FuncExpr {
+					return
+				}
+				if f, err := functions.Scalar(e.Name, len(e.Args)); err == nil && !f.Deterministic {
+					found = true
+				}
+			})
+		}
+		return !found
+	})
+	return found
+}
+
+// walkExpr calls the given function for the given expression and all its
+// arguments.
+func walkExpr(e *planner.Expr, fn func(e *planner.Expr)) {
+	if e == nil {
+		return
+	}
+	fn(e)
+	for _, arg := range e.Args {
+		walkExpr(arg, fn)
+	}
+}
+
+// inline replaces the references of the given CTE in the given plan with the
+// CTE, and returns the plan. The plan is changed in place, since it was just
+// built.
+func inline(plan *planner.Node, def *planner.Node) *planner.Node {
+	if isReference(plan, def.Relation) {
+		return &planner.Node{
+			Op:       planner.CommonTable,
+			Relation: plan.Relation,
+			Table:    plan.Table,
+			Columns:  def.Columns,
+			Children: def.Children,
+		}
+	}
+	if defines(plan, def.Relation) {
+		return plan
+	}
+	for i, child := range plan.Children {
+		plan.Children[i] = inline(child, def)
+	}
+	for _, e := range plan.Exprs() {
+		walkExpr(e, func(e *planner.Expr) {
+			if e.Subquery != nil {
+				e.Subquery = inline(e.Subquery, def)
+			}
+		})
+	}
+	return plan
+}
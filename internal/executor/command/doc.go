@@ -1,3 +1,7 @@
 // Package command defined a command model, known as the intermediary
 // representation. It can be converted from an *ast.SQLStmt.
+//
+// A command can be lowered to its program, which is a list of instructions
+// that EXPLAIN returns. EXPLAIN QUERY PLAN returns the plan of a command
+// instead.
 package command
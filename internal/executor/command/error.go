@@ -0,0 +1,20 @@
+package command
+
+// Error provides constant errors to the command package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrUnsupported = Error("unsupported statement")
+	ErrMissingName = Error("missing savepoint or schema name")
+	ErrNotLiteral  = Error("file name must be a literal")
+
+	ErrNotResultColumn = Error("term does not match any result column")
+	ErrNotInteger      = Error("LIMIT and OFFSET must be integers")
+	ErrValuesCount     = Error("number of values does not match the number of columns")
+	ErrBadLiteral      = Error("malformed literal")
+	ErrDuplicateCTE    = Error("duplicate WITH table name")
+	ErrCircularCTE     = Error("circular reference")
+)
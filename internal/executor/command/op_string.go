@@ -0,0 +1,47 @@
+// Code generated by "stringer -type=Op ./internal/executor/command"; DO NOT EDIT.
+
+package command
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[Begin-1]
+	_ = x[Commit-2]
+	_ = x[Rollback-3]
+	_ = x[RollbackTo-4]
+	_ = x[Savepoint-5]
+	_ = x[Release-6]
+	_ = x[Attach-7]
+	_ = x[Detach-8]
+	_ = x[Select-9]
+	_ = x[Insert-10]
+	_ = x[Update-11]
+	_ = x[Delete-12]
+	_ = x[CreateTable-13]
+	_ = x[DropTable-14]
+	_ = x[CreateIndex-15]
+	_ = x[DropIndex-16]
+	_ = x[Reindex-17]
+	_ = x[Pragma-18]
+	_ = x[CreateTrigger-19]
+	_ = x[DropTrigger-20]
+	_ = x[CreateView-21]
+	_ = x[DropView-22]
+	_ = x[Analyze-23]
+	_ = x[AlterTable-24]
+}
+
+const _Op_name = "BeginCommitRollbackRollbackToSavepointReleaseAttachDetachSelectInsertUpdateDeleteCreateTableDropTableCreateIndexDropIndexReindexPragmaCreateTriggerDropTriggerCreateViewDropViewAnalyzeAlterTable"
+
+var _Op_index = [...]uint8{0, 5, 11, 19, 29, 38, 45, 51, 57, 63, 69, 75, 81, 92, 101, 112, 121, 128, 134, 147, 158, 168, 176, 183, 193}
+
+func (i Op) String() string {
+	i -= 1
+	if i >= Op(len(_Op_index)-1) {
+		return "Op(" + strconv.FormatInt(int64(i+1), 10) + ")"
+	}
+	return _Op_name[_Op_index[i]:_Op_index[i+1]]
+}
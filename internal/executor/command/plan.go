@@ -0,0 +1,949 @@
+package command
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// functions knows the aggregate functions, whose calls turn a SELECT into an
+// aggregation.
+var functions = function.NewRegistry()
+
+// binaryOps are the operations of the binary operators of the parser.
+var binaryOps = map[string]planner.ExprOp{
+	"OR":  planner.OrExpr,
+	"AND": planner.AndExpr,
+	"=":   planner.EqExpr,
+	"==":  planner.EqExpr,
+	"!=":  planner.NeExpr,
+	"<>":  planner.NeExpr,
+	"<":   planner.LtExpr,
+	"<=":  planner.LeExpr,
+	">":   planner.GtExpr,
+	">=":  planner.GeExpr,
+	"&":   planner.BitAndExpr,
+	"|":   planner.BitOrExpr,
+	"<<":  planner.ShiftLeftExpr,
+	">>":  planner.ShiftRightExpr,
+	"+":   planner.AddExpr,
+	"-":   planner.SubExpr,
+	"*":   planner.MulExpr,
+	"/":   planner.DivExpr,
+	"%":   planner.ModExpr,
+	"||":  planner.ConcatExpr,
+}
+
+// selectCommand builds the command of a SELECT statement.
+func selectCommand(stmt *ast.SelectStmt) (Command, error) {
+	plan, _, err := selectPlan(stmt)
+	if err != nil {
+		return Command{}, err
+	}
+	return Command{Op: Select, Plan: plan}, nil
+}
+
+// selectPlan builds the logical plan of a SELECT statement, and returns it
+// with the names of its result columns.
+func selectPlan(stmt *ast.SelectStmt) (*planner.Node, []string, error) {
+	if stmt.With != nil {
+		return withPlan(stmt)
+	}
+
+	var plan *planner.Node
+	var names []string
+	if len(stmt.SelectCore) == 1 && stmt.SelectCore[0].Values == nil {
+		// the ORDER BY of a single SELECT may use the columns of its FROM
+		// clause, so it is sorted before the projection
+		var err error
+		if plan, names, err = corePlan(stmt.SelectCore[0], stmt.OrderingTerm); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for _, core := range stmt.SelectCore {
+			node, coreNames, err := corePlan(core, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			if plan == nil {
+				plan, names = node, coreNames
+				continue
+			}
+			op := setOp(core.CompoundOperator)
+			if plan.Op == planner.Compound && plan.SetOp == op {
+				plan.Children = append(plan.Children, node)
+				continue
+			}
+			plan = &planner.Node{Op: planner.Compound, SetOp: op, Children: []*planner.Node{plan, node}}
+		}
+		if len(stmt.OrderingTerm) > 0 {
+			order, err := outputOrder(stmt.OrderingTerm, names)
+			if err != nil {
+				return nil, nil, err
+			}
+			plan = &planner.Node{Op: planner.Sort, Order: order, Children: []*planner.Node{plan}}
+		}
+	}
+
+	if stmt.Limit != nil {
+		node := &planner.Node{Op: planner.Limit, Children: []*planner.Node{plan}}
+		var err error
+		if node.Count, node.Offset, err = limits(stmt); err != nil {
+			return nil, nil, err
+		}
+		plan = node
+	}
+	return plan, names, nil
+}
+
+// limits returns the count and the offset of the LIMIT clause of the given
+// statement. The count is negative, if there is no limit.
+func limits(stmt *ast.SelectStmt) (count, offset int64, err error) {
+	if stmt.Limit == nil {
+		return -1, 0, nil
+	}
+	limit, skip := stmt.Expr1, stmt.Expr2
+	if stmt.Comma != nil {
+		// LIMIT offset, count
+		limit, skip = skip, limit
+	}
+	if count, err = integer(limit); err != nil {
+		return 0, 0, err
+	}
+	if skip != nil {
+		if offset, err = integer(skip); err != nil {
+			return 0, 0, err
+		}
+	}
+	if count < 0 {
+		count = -1
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return count, offset, nil
+}
+
+// corePlan builds the plan of a single SELECT or VALUES of a statement, which
+// is sorted by the given terms, and returns it with the names of its result
+// columns. The plan of a SELECT is, from the bottom up, the join of its FROM
+// clause, the Selection of WHERE, the Aggregate and the Selection of HAVING,
+// the Sort, the Projection of the result columns and the Distinct.
+func corePlan(core *ast.SelectCore, terms []*ast.OrderingTerm) (*planner.Node, []string, error) {
+	if core.Values != nil {
+		return valuesPlan(core.ParenthesizedExpressions)
+	}
+	if core.NamedWindow != nil {
+		return nil, nil, ErrUnsupported
+	}
+
+	// a SELECT without FROM computes a single row
+	plan := &planner.Node{Op: planner.Values, Values: [][]*planner.Expr{{}}}
+	if core.JoinClause != nil {
+		var err error
+		if plan, err = joinPlan(core.JoinClause); err != nil {
+			return nil, nil, err
+		}
+	}
+	if core.Expr1 != nil {
+		where, err := expr(core.Expr1)
+		if err != nil {
+			return nil, nil, err
+		}
+		plan = selection(plan, where)
+	}
+
+	projections := make([]*planner.Expr, len(core.ResultColumn))
+	names := make([]string, len(core.ResultColumn))
+	aliases := make(map[string]*planner.Expr)
+	for i, col := range core.ResultColumn {
+		var err error
+		if projections[i], names[i], err = resultColumn(col); err != nil {
+			return nil, nil, err
+		}
+		if col.ColumnAlias != nil {
+			aliases[names[i]] = projections[i]
+		}
+	}
+	// GROUP BY and ORDER BY may use the aliases and the ordinals of the
+	// result columns
+	resolve := func(e *ast.Expr) (*planner.Expr, error) {
+		if n, ok := ordinal(e); ok {
+			if n < 1 || n > int64(len(projections)) || projections[n-1].Op == planner.StarExpr {
+				return nil, ErrNotResultColumn
+			}
+			return projections[n-1], nil
+		}
+		if isColumnName(e) {
+			if p, ok := aliases[token.Unquote(e.ColumnName)]; ok {
+				return p, nil
+			}
+		}
+		return expr(e)
+	}
+
+	groupBy := make([]*planner.Expr, len(core.Expr2))
+	for i, e := range core.Expr2 {
+		var err error
+		if groupBy[i], err = resolve(e); err != nil {
+			return nil, nil, err
+		}
+	}
+	var having *planner.Expr
+	if core.Expr3 != nil {
+		var err error
+		if having, err = expr(core.Expr3); err != nil {
+			return nil, nil, err
+		}
+	}
+	order := make([]planner.OrderTerm, len(terms))
+	for i, term := range terms {
+		e, err := resolve(term.Expr)
+		if err != nil {
+			return nil, nil, err
+		}
+		order[i] = orderTerm(term, e)
+	}
+
+	// the aggregate functions are computed by the Aggregate, and the
+	// expressions above it use their results
+	var aggregates []*planner.Expr
+	seen := make(map[string]bool)
+	for _, e := range projections {
+		collectAggregates(e, seen, &aggregates)
+	}
+	collectAggregates(having, seen, &aggregates)
+	for _, term := range order {
+		collectAggregates(term.Expr, seen, &aggregates)
+	}
+	if len(aggregates) > 0 || len(groupBy) > 0 || having != nil {
+		plan = &planner.Node{Op: planner.Aggregate, GroupBy: groupBy, Projections: aggregates, Children: []*planner.Node{plan}}
+		if having != nil {
+			plan = selection(plan, having)
+		}
+	}
+
+	if len(order) > 0 {
+		plan = &planner.Node{Op: planner.Sort, Order: order, Children: []*planner.Node{plan}}
+	}
+	plan = &planner.Node{Op: planner.Projection, Columns: names, Projections: projections, Children: []*planner.Node{plan}}
+	if core.Distinct != nil {
+		plan = &planner.Node{Op: planner.Distinct, Children: []*planner.Node{plan}}
+	}
+	return plan, names, nil
+}
+
+// valuesPlan builds the plan of a VALUES clause. Like in SQLite, its columns
+// are named column1, column2 and so on.
+func valuesPlan(rows []*ast.ParenthesizedExpressions) (*planner.Node, []string, error) {
+	values, err := valueRows(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	if len(values) > 0 {
+		for i := range values[0] {
+			names = append(names, "column"+strconv.Itoa(i+1))
+		}
+	}
+	return &planner.Node{Op: planner.Values, Values: values}, names, nil
+}
+
+// valueRows converts the rows of a VALUES clause, which all must have the
+// same amount of values.
+func valueRows(rows []*ast.ParenthesizedExpressions) ([][]*planner.Expr, error) {
+	values := make([][]*planner.Expr, len(rows))
+	for i, row := range rows {
+		if len(row.Exprs) != len(rows[0].Exprs) {
+			return nil, ErrValuesCount
+		}
+		var err error
+		if values[i], err = exprs(row.Exprs); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// resultColumn converts a result column of a SELECT to the expression of its
+// Projection, and returns it with the name of the column. The name is the
+// alias of the column, or the name of a column reference, or else the text of
+// the expression.
+func resultColumn(col *ast.ResultColumn) (*planner.Expr, string, error) {
+	if col.Asterisk != nil {
+		star := &planner.Expr{Op: planner.StarExpr, Column: planner.Column{Relation: token.Unquote(col.TableName)}}
+		return star, star.String(), nil
+	}
+	e, err := expr(col.Expr)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case col.ColumnAlias != nil:
+		return e, token.Unquote(col.ColumnAlias), nil
+	case col.Expr.ColumnName != nil && col.Expr.FunctionName == nil:
+		return e, token.Unquote(col.Expr.ColumnName), nil
+	}
+	return e, ast.Text(col.Expr), nil
+}
+
+// outputOrder converts the ORDER BY terms of a compound SELECT or of VALUES,
+// which sort the result rows, so every term must be the ordinal or the name
+// of a result column.
+func outputOrder(terms []*ast.OrderingTerm, names []string) ([]planner.OrderTerm, error) {
+	order := make([]planner.OrderTerm, len(terms))
+	for i, term := range terms {
+		var e *planner.Expr
+		if n, ok := ordinal(term.Expr); ok {
+			if n < 1 || n > int64(len(names)) {
+				return nil, ErrNotResultColumn
+			}
+			e = planner.Ref("", names[n-1])
+		} else if isColumnName(term.Expr) {
+			name := token.Unquote(term.Expr.ColumnName)
+			for _, n := range names {
+				if n == name {
+					e = planner.Ref("", name)
+				}
+			}
+		}
+		if e == nil {
+			return nil, ErrNotResultColumn
+		}
+		order[i] = orderTerm(term, e)
+	}
+	return order, nil
+}
+
+// orderTerm creates the term of a Sort for the given ORDER BY term, that
+// orders by the given expression.
+func orderTerm(term *ast.OrderingTerm, e *planner.Expr) planner.OrderTerm {
+	if term.CollationName != nil {
+		e = &planner.Expr{Op: planner.CollateExpr, Name: token.Unquote(term.CollationName), Args: []*planner.Expr{e}}
+	}
+	t := planner.OrderTerm{Expr: e, Desc: term.Desc != nil}
+	t.NullsFirst = !t.Desc
+	switch {
+	case term.First != nil:
+		t.NullsFirst = true
+	case term.Last != nil:
+		t.NullsFirst = false
+	}
+	return t
+}
+
+// ordinal returns the value of the given expression, if it is an integer
+// literal, which an ORDER BY or GROUP BY term uses to refer to a result
+// column.
+func ordinal(e *ast.Expr) (int64, bool) {
+	if e == nil || e.LiteralValue == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(e.LiteralValue.Value(), 10, 64)
+	return n, err == nil
+}
+
+// isColumnName reports whether the given expression is a column, that is not
+// qualified with a table name.
+func isColumnName(e *ast.Expr) bool {
+	return e != nil && e.ColumnName != nil && e.TableName == nil && e.FunctionName == nil
+}
+
+// collectAggregates appends the calls of aggregate functions in the given
+// expression to the given aggregates, if they are not seen yet. Subqueries
+// are not searched, since they aggregate their own rows.
+func collectAggregates(e *planner.Expr, seen map[string]bool, aggregates *[]*planner.Expr) {
+	if e == nil {
+		return
+	}
+	if e.Op == planner.FuncExpr && functions.IsAggregate(e.Name, len(e.Args)) {
+		if s := e.String(); !seen[s] {
+			seen[s] = true
+			*aggregates = append(*aggregates, e)
+		}
+		return
+	}
+	for _, arg := range e.Args {
+		collectAggregates(arg, seen, aggregates)
+	}
+}
+
+// joinPlan builds the plan of a FROM clause. Commas, CROSS and INNER joins
+// are inner Joins, and LEFT joins are left Joins.
+func joinPlan(join *ast.JoinClause) (*planner.Node, error) {
+	left, err := tablePlan(join.TableOrSubquery)
+	if err != nil {
+		return nil, err
+	}
+	part := join.JoinClausePart
+	if part == nil {
+		return left, nil
+	}
+	if part.JoinOperator.Natural != nil {
+		// a natural join needs the columns of both sides
+		return nil, ErrUnsupported
+	}
+	right, err := tablePlan(part.TableOrSubquery)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &planner.Node{Op: planner.Join, Children: []*planner.Node{left, right}}
+	if part.JoinOperator.Left != nil {
+		node.JoinType = planner.LeftJoin
+	}
+	c := part.JoinConstraint
+	switch {
+	case c == nil:
+	case c.On != nil:
+		if node.Predicate, err = expr(c.Expr); err != nil {
+			return nil, err
+		}
+	default:
+		// the columns of USING must be qualified with the relations of
+		// both sides, which are only known for a single relation
+		l, r := left.Relation, right.Relation
+		if l == "" || r == "" || left.Op == planner.Join {
+			return nil, ErrUnsupported
+		}
+		var conds []*planner.Expr
+		for _, name := range c.ColumnName {
+			column := token.Unquote(name)
+			conds = append(conds, planner.NewExpr(planner.EqExpr, planner.Ref(l, column), planner.Ref(r, column)))
+		}
+		node.Predicate = conjunction(conds)
+	}
+	return node, nil
+}
+
+// tablePlan builds the plan of a table, a subquery or a parenthesized join of
+// a FROM clause. A table is a FullScan, and the plan of a subquery is the
+// child of a Projection, that names its result after the alias of the
+// subquery.
+func tablePlan(t *ast.TableOrSubquery) (*planner.Node, error) {
+	switch {
+	case t.JoinClause != nil:
+		return joinPlan(t.JoinClause)
+	case t.SelectStmt != nil:
+		plan, _, err := selectPlan(t.SelectStmt)
+		if err != nil {
+			return nil, err
+		}
+		return &planner.Node{
+			Op:          planner.Projection,
+			Relation:    token.Unquote(t.TableAlias),
+			Projections: []*planner.Expr{{Op: planner.StarExpr}},
+			Children:    []*planner.Node{plan},
+		}, nil
+	case t.TableName != nil && t.Indexed == nil:
+		return scan(t.SchemaName, t.TableName, t.TableAlias), nil
+	}
+	// table-valued functions and index hints
+	return nil, ErrUnsupported
+}
+
+// scan creates the FullScan of the table with the given name, which is
+// qualified with the given schema and has the given alias, if they are not
+// nil.
+func scan(schema, table, alias token.Token) *planner.Node {
+	node := &planner.Node{Op: planner.FullScan, Schema: token.Unquote(schema), Relation: token.Unquote(table)}
+	if alias != nil {
+		node.Table, node.Relation = node.Relation, token.Unquote(alias)
+	}
+	return node
+}
+
+// selection puts a Selection with the given predicate on top of the given
+// plan.
+func selection(plan *planner.Node, predicate *planner.Expr) *planner.Node {
+	return &planner.Node{Op: planner.Selection, Predicate: predicate, Children: []*planner.Node{plan}}
+}
+
+// conjunction combines the given conditions with AND.
+func conjunction(conds []*planner.Expr) *planner.Expr {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return planner.NewExpr(planner.AndExpr, conds...)
+}
+
+func setOp(op *ast.CompoundOperator) planner.SetOp {
+	switch {
+	case op.Intersect != nil:
+		return planner.Intersect
+	case op.Except != nil:
+		return planner.Except
+	case op.All != nil:
+		return planner.UnionAll
+	}
+	return planner.Union
+}
+
+// integer evaluates the given expression of LIMIT or OFFSET, which must be an
+// integer constant.
+func integer(e *ast.Expr) (int64, error) {
+	v, err := expr(e)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.Value.(int64)
+	if v.Op != planner.ConstExpr || !ok {
+		return 0, ErrNotInteger
+	}
+	return n, nil
+}
+
+// insertCommand builds the command of an INSERT statement. The rows of the
+// Insert are the Values or the plan of the SELECT of the statement, and
+// DEFAULT VALUES is a single row without columns.
+func insertCommand(stmt *ast.InsertStmt) (Command, error) {
+	if stmt.WithClause != nil || stmt.UpsertClause != nil {
+		return Command{}, ErrUnsupported
+	}
+
+	node := &planner.Node{
+		Op:       planner.Insert,
+		Schema:   token.Unquote(stmt.SchemaName),
+		Relation: token.Unquote(stmt.TableName),
+	}
+	for _, name := range stmt.ColumnName {
+		node.Columns = append(node.Columns, token.Unquote(name))
+	}
+	var rows *planner.Node
+	switch {
+	case stmt.Default != nil:
+		node.Columns = []string{}
+		rows = &planner.Node{Op: planner.Values, Values: [][]*planner.Expr{{}}}
+	case stmt.ParenthesizedExpressions != nil:
+		var list []*ast.ParenthesizedExpressions
+		for i := range *stmt.ParenthesizedExpressions {
+			list = append(list, &(*stmt.ParenthesizedExpressions)[i])
+		}
+		values, err := valueRows(list)
+		if err != nil {
+			return Command{}, err
+		}
+		rows = &planner.Node{Op: planner.Values, Values: values}
+	case stmt.SelectStmt != nil:
+		var err error
+		if rows, _, err = selectPlan(stmt.SelectStmt); err != nil {
+			return Command{}, err
+		}
+	default:
+		return Command{}, ErrUnsupported
+	}
+	node.Children = []*planner.Node{rows}
+
+	conflict := conflictOf(stmt.Rollback, stmt.Abort, stmt.Replace, stmt.Fail, stmt.Ignore)
+	return Command{Op: Insert, Conflict: conflict, Plan: node}, nil
+}
+
+// updateCommand builds the command of an UPDATE statement. The child of the
+// Update selects the updated rows with the WHERE clause of the statement.
+func updateCommand(stmt *ast.UpdateStmt) (Command, error) {
+	if stmt.WithClause != nil {
+		return Command{}, ErrUnsupported
+	}
+	rows, err := qualifiedScan(stmt.QualifiedTableName, stmt.Expr)
+	if err != nil {
+		return Command{}, err
+	}
+
+	node := &planner.Node{
+		Op:       planner.Update,
+		Schema:   token.Unquote(stmt.QualifiedTableName.SchemaName),
+		Relation: token.Unquote(stmt.QualifiedTableName.TableName),
+		Children: []*planner.Node{rows},
+	}
+	for _, setter := range stmt.UpdateSetter {
+		if setter.ColumnNameList == nil {
+			value, err := expr(setter.Expr)
+			if err != nil {
+				return Command{}, err
+			}
+			node.Columns = append(node.Columns, token.Unquote(setter.ColumnName))
+			node.Projections = append(node.Projections, value)
+			continue
+		}
+		// (a, b) = (1, 2) sets every column to its value of the list
+		names := setter.ColumnNameList.ColumnName
+		list := setter.Expr.Expr
+		if setter.Expr.LeftParen == nil || setter.Expr.SelectStmt != nil || len(list) != len(names) {
+			return Command{}, ErrValuesCount
+		}
+		for i, name := range names {
+			value, err := expr(list[i])
+			if err != nil {
+				return Command{}, err
+			}
+			node.Columns = append(node.Columns, token.Unquote(name))
+			node.Projections = append(node.Projections, value)
+		}
+	}
+
+	conflict := conflictOf(stmt.Rollback, stmt.Abort, stmt.Replace, stmt.Fail, stmt.Ignore)
+	return Command{Op: Update, Conflict: conflict, Plan: node}, nil
+}
+
+// deleteCommand builds the command of a DELETE statement. The child of the
+// Delete selects the deleted rows with the WHERE clause of the statement.
+func deleteCommand(stmt *ast.DeleteStmt) (Command, error) {
+	if stmt.WithClause != nil {
+		return Command{}, ErrUnsupported
+	}
+	rows, err := qualifiedScan(stmt.QualifiedTableName, stmt.Expr)
+	if err != nil {
+		return Command{}, err
+	}
+	return Command{Op: Delete, Plan: &planner.Node{
+		Op:       planner.Delete,
+		Schema:   token.Unquote(stmt.QualifiedTableName.SchemaName),
+		Relation: token.Unquote(stmt.QualifiedTableName.TableName),
+		Children: []*planner.Node{rows},
+	}}, nil
+}
+
+// qualifiedScan builds the plan, that reads the rows of the given table of an
+// UPDATE or DELETE, for which the given WHERE clause is true, or all rows if
+// it is nil.
+func qualifiedScan(table *ast.QualifiedTableName, where *ast.Expr) (*planner.Node, error) {
+	if table == nil || table.Indexed != nil {
+		return nil, ErrUnsupported
+	}
+	rows := scan(table.SchemaName, table.TableName, table.Alias)
+	if where == nil {
+		return rows, nil
+	}
+	predicate, err := expr(where)
+	if err != nil {
+		return nil, err
+	}
+	return selection(rows, predicate), nil
+}
+
+// conflictOf returns the conflict algorithm of an OR clause, whose keyword is
+// not nil.
+func conflictOf(rollback, abort, replace, fail, ignore token.Token) constraint.Conflict {
+	switch {
+	case rollback != nil:
+		return constraint.Rollback
+	case abort != nil:
+		return constraint.Abort
+	case replace != nil:
+		return constraint.Replace
+	case fail != nil:
+		return constraint.Fail
+	case ignore != nil:
+		return constraint.Ignore
+	}
+	return constraint.Default
+}
+
+// exprs converts the given expressions.
+func exprs(list []*ast.Expr) ([]*planner.Expr, error) {
+	result := make([]*planner.Expr, len(list))
+	for i, e := range list {
+		var err error
+		if result[i], err = expr(e); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Expr converts an expression of the parser to an expression of a logical
+// plan, like the expressions of the plans of commands. It converts the
+// expressions of definitions, like DEFAULT and CHECK.
+func Expr(e *ast.Expr) (*planner.Expr, error) {
+	return expr(e)
+}
+
+// raise converts RAISE to a RaiseExpr.
+func raise(r *ast.RaiseFunction) *planner.Expr {
+	e := &planner.Expr{Op: planner.RaiseExpr}
+	for _, algorithm := range []token.Token{r.Ignore, r.Rollback, r.Abort, r.Fail} {
+		if algorithm != nil {
+			e.Name = strings.ToUpper(algorithm.Value())
+		}
+	}
+	if r.ErrorMessage != nil {
+		e.Value = token.Unquote(r.ErrorMessage)
+	}
+	return e
+}
+
+// expr converts an expression of the parser to an expression of a logical
+// plan. Columns are qualified with the table name that they are written
+// with, and unqualified columns are bound to their relation, when the plan
+// is executed.
+func expr(e *ast.Expr) (*planner.Expr, error) {
+	switch {
+	case e == nil:
+		return nil, ErrUnsupported
+	case e.LiteralValue != nil:
+		return literal(e.LiteralValue)
+	case e.BindParameter != nil:
+		return nil, ErrUnsupported
+	case e.RaiseFunction != nil:
+		return raise(e.RaiseFunction), nil
+	case e.Cast != nil:
+		arg, err := expr(e.Expr1)
+		if err != nil {
+			return nil, err
+		}
+		return &planner.Expr{Op: planner.CastExpr, Name: ast.Text(e.TypeName), Args: []*planner.Expr{arg}}, nil
+	case e.Case != nil:
+		return caseExpr(e)
+	case e.Exists != nil:
+		plan, _, err := selectPlan(e.SelectStmt)
+		if err != nil {
+			return nil, err
+		}
+		return planner.Exists(plan), nil
+	case e.FunctionName != nil:
+		args, err := exprs(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		f := planner.Func(strings.ToLower(token.Unquote(e.FunctionName)), args...)
+		f.Distinct = e.Distinct != nil
+		return f, nil
+	case e.ColumnName != nil:
+		if e.TableName == nil {
+			// TRUE and FALSE are names, unless there is such a column
+			switch strings.ToUpper(e.ColumnName.Value()) {
+			case "TRUE":
+				return planner.Const(true), nil
+			case "FALSE":
+				return planner.Const(false), nil
+			}
+		}
+		return planner.Ref(token.Unquote(e.TableName), token.Unquote(e.ColumnName)), nil
+	case e.Collate != nil:
+		arg, err := expr(e.Expr1)
+		if err != nil {
+			return nil, err
+		}
+		return &planner.Expr{Op: planner.CollateExpr, Name: token.Unquote(e.CollationName), Args: []*planner.Expr{arg}}, nil
+	case e.Isnull != nil, e.Notnull != nil, e.Null != nil:
+		arg, err := expr(e.Expr1)
+		if err != nil {
+			return nil, err
+		}
+		return negate(e.Isnull == nil, planner.NewExpr(planner.IsNullExpr, arg)), nil
+	case e.Between != nil:
+		args, err := exprs([]*ast.Expr{e.Expr1, e.Expr2, e.Expr3})
+		if err != nil {
+			return nil, err
+		}
+		between := planner.NewExpr(planner.AndExpr,
+			planner.NewExpr(planner.GeExpr, args[0], args[1]),
+			planner.NewExpr(planner.LeExpr, args[0], args[2]),
+		)
+		return negate(e.Not != nil, between), nil
+	case e.In != nil:
+		in, err := inExpr(e)
+		if err != nil {
+			return nil, err
+		}
+		return negate(e.Not != nil, in), nil
+	case e.Like != nil, e.Glob != nil, e.Regexp != nil, e.Match != nil:
+		args, err := exprs([]*ast.Expr{e.Expr1, e.Expr2})
+		if err != nil {
+			return nil, err
+		}
+		if e.Escape != nil {
+			escape, err := expr(e.Expr3)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, escape)
+		}
+		op := e.Like
+		for _, t := range []token.Token{e.Glob, e.Regexp, e.Match} {
+			if t != nil {
+				op = t
+			}
+		}
+		like := &planner.Expr{Op: planner.LikeExpr, Name: strings.ToUpper(op.Value()), Args: args}
+		return negate(e.Not != nil, like), nil
+	case e.Is != nil:
+		args, err := exprs([]*ast.Expr{e.Expr1, e.Expr2})
+		if err != nil {
+			return nil, err
+		}
+		if e.Not != nil {
+			return planner.NewExpr(planner.IsNotExpr, args...), nil
+		}
+		return planner.NewExpr(planner.IsExpr, args...), nil
+	case e.BinaryOperator != nil:
+		op, ok := binaryOps[strings.ToUpper(e.BinaryOperator.Value())]
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		args, err := exprs([]*ast.Expr{e.Expr1, e.Expr2})
+		if err != nil {
+			return nil, err
+		}
+		return planner.NewExpr(op, args...), nil
+	case e.UnaryOperator != nil:
+		return unaryExpr(e)
+	case e.Not != nil:
+		arg, err := expr(e.Expr1)
+		if err != nil {
+			return nil, err
+		}
+		return planner.NewExpr(planner.NotExpr, arg), nil
+	case e.SelectStmt != nil:
+		plan, _, err := selectPlan(e.SelectStmt)
+		if err != nil {
+			return nil, err
+		}
+		return &planner.Expr{Op: planner.SubqueryExpr, Subquery: plan}, nil
+	case e.LeftParen != nil && len(e.Expr) == 1:
+		return expr(e.Expr[0])
+	}
+	// row values
+	return nil, ErrUnsupported
+}
+
+// negate wraps the given expression in a NotExpr, if not is set.
+func negate(not bool, e *planner.Expr) *planner.Expr {
+	if not {
+		return planner.NewExpr(planner.NotExpr, e)
+	}
+	return e
+}
+
+// inExpr converts the IN operator of the given expression. The right operand
+// is a list of values, a subquery or a table.
+func inExpr(e *ast.Expr) (*planner.Expr, error) {
+	value, err := expr(e.Expr1)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case e.SelectStmt != nil:
+		plan, _, err := selectPlan(e.SelectStmt)
+		if err != nil {
+			return nil, err
+		}
+		return planner.In(value, plan), nil
+	case e.TableName != nil:
+		plan := &planner.Node{
+			Op:          planner.Projection,
+			Projections: []*planner.Expr{{Op: planner.StarExpr}},
+			Children:    []*planner.Node{scan(e.SchemaName, e.TableName, nil)},
+		}
+		return planner.In(value, plan), nil
+	}
+	list, err := exprs(e.Expr)
+	if err != nil {
+		return nil, err
+	}
+	return planner.NewExpr(planner.InExpr, append([]*planner.Expr{value}, list...)...), nil
+}
+
+// caseExpr converts a CASE expression. A CASE with a base expression is
+// converted to a CASE, that compares the base with every WHEN expression.
+func caseExpr(e *ast.Expr) (*planner.Expr, error) {
+	var base *planner.Expr
+	if e.Expr1 != nil {
+		var err error
+		if base, err = expr(e.Expr1); err != nil {
+			return nil, err
+		}
+	}
+	c := &planner.Expr{Op: planner.CaseExpr}
+	for _, when := range e.WhenClause {
+		args, err := exprs([]*ast.Expr{when.Expr1, when.Expr2})
+		if err != nil {
+			return nil, err
+		}
+		if base != nil {
+			args[0] = planner.NewExpr(planner.EqExpr, base, args[0])
+		}
+		c.Args = append(c.Args, args...)
+	}
+	if e.Else != nil {
+		value, err := expr(e.Expr4)
+		if err != nil {
+			return nil, err
+		}
+		c.Args = append(c.Args, value)
+	}
+	return c, nil
+}
+
+// unaryExpr converts an expression with a unary operator. The negation of a
+// numeric constant is folded into the constant.
+func unaryExpr(e *ast.Expr) (*planner.Expr, error) {
+	arg, err := expr(e.Expr1)
+	if err != nil {
+		return nil, err
+	}
+	switch e.UnaryOperator.Value() {
+	case "+":
+		return arg, nil
+	case "~":
+		return planner.NewExpr(planner.BitNotExpr, arg), nil
+	}
+	if arg.Op == planner.ConstExpr {
+		switch v := arg.Value.(type) {
+		case int64:
+			return planner.Const(-v), nil
+		case float64:
+			return planner.Const(-v), nil
+		}
+	}
+	return planner.NewExpr(planner.NegExpr, arg), nil
+}
+
+// literal converts a literal value, which is a number, a string, a blob like
+// x'0a', NULL, or one of CURRENT_TIME, CURRENT_DATE and CURRENT_TIMESTAMP.
+func literal(tk token.Token) (*planner.Expr, error) {
+	switch tk.Type() {
+	case token.KeywordNull:
+		return planner.Const(nil), nil
+	case token.KeywordCurrentTime:
+		return planner.Func("time", planner.Const("now")), nil
+	case token.KeywordCurrentDate:
+		return planner.Func("date", planner.Const("now")), nil
+	case token.KeywordCurrentTimestamp:
+		return planner.Func("datetime", planner.Const("now")), nil
+	}
+
+	v := tk.Value()
+	switch {
+	case strings.HasPrefix(v, "'"):
+		return planner.Const(token.Unquote(tk)), nil
+	case len(v) >= 3 && (v[0] == 'x' || v[0] == 'X') && v[1] == '\'':
+		blob, err := hex.DecodeString(v[2 : len(v)-1])
+		if err != nil {
+			return nil, ErrBadLiteral
+		}
+		return planner.Const(blob), nil
+	case len(v) > 2 && v[0] == '0' && (v[1] == 'x' || v[1] == 'X'):
+		// hexadecimal integers are 64 bit two's complement numbers
+		n, err := strconv.ParseUint(v[2:], 16, 64)
+		if err != nil {
+			return nil, ErrBadLiteral
+		}
+		return planner.Const(int64(n)), nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return planner.Const(n), nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil && f == 0 {
+		return nil, ErrBadLiteral
+	}
+	// integers, that are too large for 64 bits, are REAL
+	return planner.Const(f), nil
+}
@@ -0,0 +1,113 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// ProgramColumns are the names of the columns of the result of EXPLAIN.
+var ProgramColumns = []string{"addr", "opcode", "p1", "p2", "p3", "comment"}
+
+// Instruction is an instruction of the program of a command, which is a row
+// of the result of EXPLAIN.
+type Instruction struct {
+	// Addr is the address of the instruction, starting at 0.
+	Addr   int
+	Opcode string
+	// P1 and P2 are the addresses of the inputs of an operator of the plan,
+	// or integer operands, and P3 is a text operand.
+	P1, P2  int
+	P3      string
+	Comment string
+}
+
+// Values returns the values of this instruction, in the order of
+// ProgramColumns.
+func (i Instruction) Values() []interface{} {
+	return []interface{}{i.Addr, i.Opcode, i.P1, i.P2, i.P3, i.Comment}
+}
+
+// Program lowers the command to the program that executes it. The operators
+// of the plan come first, every operator after its inputs, followed by a
+// ResultRow instruction for the root of the plan, unless the root is an
+// Insert, Update or Delete, which return no rows. After that comes the
+// operation of the command, and the program ends with Halt. Subqueries, that
+// the optimizer did not turn into joins, are not lowered, and only appear in
+// the comment of the operator, whose expressions contain them.
+func (c Command) Program() []Instruction {
+	var program []Instruction
+	emit := func(i Instruction) int {
+		i.Addr = len(program)
+		program = append(program, i)
+		return i.Addr
+	}
+
+	if c.Plan != nil {
+		var lower func(n *planner.Node) int
+		lower = func(n *planner.Node) int {
+			i := Instruction{
+				Opcode:  n.Op.String(),
+				P3:      n.Relation,
+				Comment: n.Detail(),
+			}
+			if len(n.Children) > 0 {
+				i.P1 = lower(n.Children[0])
+			}
+			if len(n.Children) > 1 {
+				i.P2 = lower(n.Children[1])
+			}
+			return emit(i)
+		}
+		root := lower(c.Plan)
+		switch c.Plan.Op {
+		case planner.Insert, planner.Update, planner.Delete:
+		default:
+			emit(Instruction{Opcode: "ResultRow", P1: root})
+		}
+	}
+
+	switch c.Op {
+	case Begin:
+		emit(Instruction{Opcode: c.Op.String(), P1: int(c.Mode), Comment: mode(c.Mode)})
+	case RollbackTo, Savepoint, Release, Detach, CreateTable, DropTable, CreateIndex, DropIndex, Reindex,
+		CreateTrigger, DropTrigger, CreateView, DropView, Analyze, AlterTable:
+		emit(Instruction{Opcode: c.Op.String(), P3: c.Name})
+	case Pragma:
+		emit(Instruction{Opcode: c.Op.String(), P3: c.Name, Comment: c.Value})
+	case Attach:
+		emit(Instruction{Opcode: c.Op.String(), P3: c.Name, Comment: c.File})
+	case Commit, Rollback:
+		emit(Instruction{Opcode: c.Op.String()})
+	}
+	emit(Instruction{Opcode: "Halt"})
+	return program
+}
+
+// String renders the program of the command, one instruction per line, with
+// the columns of ProgramColumns.
+//
+//  0    Begin                1    0                 IMMEDIATE
+//  1    Halt                 0    0
+func (c Command) String() string {
+	var b strings.Builder
+	for _, i := range c.Program() {
+		line := fmt.Sprintf("%-4d %-20v %-4d %-4d %-12v %v", i.Addr, i.Opcode, i.P1, i.P2, i.P3, i.Comment)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return b.String()
+}
+
+func mode(m transaction.Mode) string {
+	switch m {
+	case transaction.Deferred:
+		return "DEFERRED"
+	case transaction.Immediate:
+		return "IMMEDIATE"
+	case transaction.Exclusive:
+		return "EXCLUSIVE"
+	}
+	return fmt.Sprintf("Mode(%d)", m)
+}
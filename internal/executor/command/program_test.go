@@ -0,0 +1,72 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestCommand_Program(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Command
+		want []Instruction
+	}{
+		{
+			"begin",
+			Command{Op: Begin, Mode: transaction.Exclusive},
+			[]Instruction{
+				{Addr: 0, Opcode: "Begin", P1: 2, Comment: "EXCLUSIVE"},
+				{Addr: 1, Opcode: "Halt"},
+			},
+		},
+		{
+			"release",
+			Command{Op: Release, Name: "sp"},
+			[]Instruction{
+				{Addr: 0, Opcode: "Release", P3: "sp"},
+				{Addr: 1, Opcode: "Halt"},
+			},
+		},
+		{
+			"plan",
+			Command{Plan: &planner.Node{
+				Op: planner.HashJoin,
+				Children: []*planner.Node{
+					{
+						Op: planner.IndexNestedLoopJoin,
+						Children: []*planner.Node{
+							{Op: planner.FullScan, Relation: "customers"},
+							{Op: planner.IndexScan, Relation: "orders", Index: "orders_customer"},
+						},
+					},
+					{Op: planner.FullScan, Relation: "products"},
+				},
+			}},
+			[]Instruction{
+				{Addr: 0, Opcode: "FullScan", P3: "customers", Comment: "SCAN customers"},
+				{Addr: 1, Opcode: "IndexScan", P3: "orders", Comment: "SEARCH orders USING INDEX orders_customer"},
+				{Addr: 2, Opcode: "IndexNestedLoopJoin", P1: 0, P2: 1, Comment: "INDEX NESTED LOOP JOIN"},
+				{Addr: 3, Opcode: "FullScan", P3: "products", Comment: "SCAN products"},
+				{Addr: 4, Opcode: "HashJoin", P1: 2, P2: 3, Comment: "HASH JOIN"},
+				{Addr: 5, Opcode: "ResultRow", P1: 4},
+				{Addr: 6, Opcode: "Halt"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := tt.cmd.Program()
+			assert.Equal(t, tt.want, program)
+			assert.Len(t, program[0].Values(), len(ProgramColumns))
+		})
+	}
+}
+
+func TestCommand_String(t *testing.T) {
+	assert.Equal(t, `0    Savepoint            0    0    sp
+1    Halt                 0    0
+`, Command{Op: Savepoint, Name: "sp"}.String())
+}
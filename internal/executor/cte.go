@@ -0,0 +1,254 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/executor/cte"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// commonTable holds the rows of a materialized common table expression, or
+// the row of the working table of a recursive one, which its references
+// read.
+type commonTable struct {
+	cols []column
+	rows [][]function.Value
+}
+
+// with computes its common table expressions, before it runs the query.
+type with struct {
+	ctes  []*materialized
+	query operator
+}
+
+// with compiles a With. The CTEs are visible to the CTEs after them and to
+// the query, while they are compiled.
+func (x *execution) with(n *planner.Node, outer *scope) (operator, error) {
+	defined := make(map[string]*commonTable)
+	x.ctes = append(x.ctes, defined)
+	defer func() { x.ctes = x.ctes[:len(x.ctes)-1] }()
+
+	w := &with{}
+	for _, def := range n.Children[:len(n.Children)-1] {
+		m, err := x.materialize(def, outer)
+		if err != nil {
+			return nil, err
+		}
+		w.ctes = append(w.ctes, m)
+		defined[strings.ToLower(def.Relation)] = m.table
+	}
+	query, err := x.node(n.Children[len(n.Children)-1], outer)
+	if err != nil {
+		return nil, err
+	}
+	w.query = query
+	return w, nil
+}
+
+func (w *with) columns() []column { return w.query.columns() }
+
+func (w *with) run(outer *frame, emit func(row []function.Value) error) error {
+	for _, m := range w.ctes {
+		if err := m.compute(outer); err != nil {
+			return err
+		}
+	}
+	return w.query.run(outer, emit)
+}
+
+// materialized computes the rows of a CTE of a With.
+type materialized struct {
+	name    string
+	table   *commonTable
+	initial *renamed
+	// recursive is the recursive SELECT of a recursive CTE, which reads
+	// the working table, or nil.
+	recursive *renamed
+	working   *commonTable
+	all       bool
+	order     []sortTerm
+	count     int64
+	offset    int64
+	maxDepth  int
+}
+
+func (x *execution) materialize(n *planner.Node, outer *scope) (*materialized, error) {
+	initial, err := x.commonTable(n, n.Children[0], outer)
+	if err != nil {
+		return nil, err
+	}
+	m := &materialized{
+		name:    n.Relation,
+		table:   &commonTable{cols: initial.cols},
+		initial: initial,
+	}
+	if len(n.Children) == 1 {
+		return m, nil
+	}
+
+	// the recursive SELECT reads the working table by the name of the CTE
+	m.working = &commonTable{cols: initial.cols}
+	x.ctes = append(x.ctes, map[string]*commonTable{strings.ToLower(n.Relation): m.working})
+	m.recursive, err = x.commonTable(n, n.Children[1], outer)
+	x.ctes = x.ctes[:len(x.ctes)-1]
+	if err != nil {
+		return nil, err
+	}
+	if len(m.recursive.cols) != len(initial.cols) {
+		return nil, ErrCompoundColumns
+	}
+
+	s := &scope{columns: initial.cols, outer: outer}
+	for _, term := range n.Order {
+		e, err := x.expr(term.Expr, s)
+		if err != nil {
+			return nil, err
+		}
+		m.order = append(m.order, sortTerm{
+			e:          e,
+			collation:  collationOf(e, expression{}),
+			desc:       term.Desc,
+			nullsFirst: term.NullsFirst,
+		})
+	}
+	m.all = n.SetOp == planner.UnionAll
+	m.count, m.offset = n.Count, n.Offset
+	m.maxDepth = x.e.recursionLimit
+	return m, nil
+}
+
+// compute computes the rows of the CTE.
+func (m *materialized) compute(outer *frame) error {
+	m.table.rows = nil
+	add := func(row cte.Row) error {
+		m.table.rows = append(m.table.rows, row)
+		return nil
+	}
+	if m.recursive == nil {
+		return m.initial.run(outer, add)
+	}
+
+	r := cte.New(m.name, func(emit func(cte.Row) error) error {
+		return m.initial.run(outer, emit)
+	}, func(working cte.Row, emit func(cte.Row) error) error {
+		m.working.rows = [][]function.Value{working}
+		return m.recursive.run(outer, emit)
+	})
+	r.All, r.Limit, r.Offset, r.MaxDepth = m.all, m.count, m.offset, m.maxDepth
+	var orderErr error
+	if len(m.order) > 0 {
+		r.Order = func(a, b cte.Row) int {
+			for _, term := range m.order {
+				va, err := term.e.eval(&frame{row: a, outer: outer})
+				if err != nil {
+					orderErr = err
+					return 0
+				}
+				vb, err := term.e.eval(&frame{row: b, outer: outer})
+				if err != nil {
+					orderErr = err
+					return 0
+				}
+				if c := term.compare(va, vb); c != 0 {
+					return c
+				}
+			}
+			return 0
+		}
+	}
+	if err := r.Run(add); err != nil {
+		return err
+	}
+	return orderErr
+}
+
+// cte returns the CTE with the given name, that is visible where the plan is
+// compiled.
+func (x *execution) cte(name string) (*commonTable, bool) {
+	for i := len(x.ctes) - 1; i >= 0; i-- {
+		if t, ok := x.ctes[i][strings.ToLower(name)]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// cteScan reads the rows of a materialized CTE.
+type cteScan struct {
+	t    *commonTable
+	cols []column
+}
+
+func (x *execution) cteScan(t *commonTable, n *planner.Node) operator {
+	cols := make([]column, len(t.cols))
+	for i, col := range t.cols {
+		col.relation = n.Relation
+		cols[i] = col
+	}
+	return &cteScan{t: t, cols: cols}
+}
+
+func (s *cteScan) columns() []column { return s.cols }
+
+func (s *cteScan) run(_ *frame, emit func(row []function.Value) error) error {
+	for _, row := range s.t.rows {
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renamed passes on the visible columns of its child, which are renamed to
+// the columns of a CTE.
+type renamed struct {
+	child operator
+	// positions are the positions of the visible columns in the rows of the
+	// child.
+	positions []int
+	cols      []column
+}
+
+// commonTable compiles the given child of the given CommonTable, whose
+// columns are renamed to the columns of the CTE, and qualified with its
+// relation.
+func (x *execution) commonTable(n, child *planner.Node, outer *scope) (*renamed, error) {
+	op, err := x.node(child, outer)
+	if err != nil {
+		return nil, err
+	}
+	r := &renamed{child: op}
+	for i, col := range op.columns() {
+		if col.hidden {
+			continue
+		}
+		r.positions = append(r.positions, i)
+		col.relation, col.aggregate = n.Relation, ""
+		r.cols = append(r.cols, col)
+	}
+	if n.Columns != nil && len(n.Columns) != len(r.cols) {
+		table := n.Relation
+		if n.Table != "" {
+			table = n.Table
+		}
+		return nil, fmt.Errorf("%w: table %v has %d values for %d columns", ErrValueCount, table, len(r.cols), len(n.Columns))
+	}
+	for i, name := range n.Columns {
+		r.cols[i].name = name
+	}
+	return r, nil
+}
+
+func (r *renamed) columns() []column { return r.cols }
+
+func (r *renamed) run(outer *frame, emit func(row []function.Value) error) error {
+	return r.child.run(outer, func(row []function.Value) error {
+		values := make([]function.Value, len(r.positions))
+		for i, p := range r.positions {
+			values[i] = row[p]
+		}
+		return emit(values)
+	})
+}
@@ -0,0 +1,16 @@
+// Package cte executes recursive common table expressions, as in WITH
+// RECURSIVE.
+//
+// A recursive CTE is computed with a queue, like in SQLite. The rows of the
+// initial SELECT are added to the queue. Then, until the queue is empty, a
+// single row is extracted from it, becomes a row of the CTE, and the
+// recursive SELECT is run with that row as the only row of the working table.
+// Its result rows are added to the queue. With UNION, rows are only added to
+// the queue if they were never added before, and with UNION ALL, all rows are
+// added. An ORDER BY of the recursive SELECT orders the queue, and its LIMIT
+// and OFFSET apply to the rows of the CTE.
+//
+// Since a recursive CTE may not terminate, the depth of the recursion is
+// limited. Whether a CTE is materialized or inlined into the query is decided
+// by the planner.
+package cte
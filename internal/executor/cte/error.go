@@ -0,0 +1,11 @@
+package cte
+
+// Error provides constant errors to the cte package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrRecursionLimit = Error("recursion limit exceeded")
+)
@@ -0,0 +1,155 @@
+package cte
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/database/storage/btree"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+)
+
+// DefaultMaxDepth is the default maximum depth of the recursion of a
+// recursive CTE.
+const DefaultMaxDepth = 1000
+
+// treeOrder is the order of the btree, that holds the rows that were added
+// to the queue.
+const treeOrder = 32
+
+// Row is a row of a CTE.
+type Row = []function.Value
+
+// Recursive is a recursive CTE of the form
+//
+//	name AS (initial UNION [ALL] recursive [ORDER BY ...] [LIMIT ... [OFFSET ...]])
+type Recursive struct {
+	Name string
+	// Initial runs the initial SELECT, and emits its rows.
+	Initial func(emit func(Row) error) error
+	// Recursive runs the recursive SELECT with the given row as the only row
+	// of the working table, and emits its rows.
+	Recursive func(working Row, emit func(Row) error) error
+	// All indicates UNION ALL, which keeps duplicate rows.
+	All bool
+	// Order compares two rows by the ORDER BY of the recursive SELECT, and
+	// orders the queue. If it is nil, the queue is first in, first out.
+	Order func(a, b Row) int
+	// Limit is the maximum amount of rows of the CTE, or negative if there is
+	// no limit. Offset is the amount of rows, that are skipped before rows
+	// are emitted. Skipped rows are still used for the recursion.
+	Limit  int64
+	Offset int64
+	// MaxDepth is the maximum depth of the recursion. Rows of the initial
+	// SELECT have the depth 0, and every recursion increases it by one.
+	MaxDepth int
+}
+
+// New creates a recursive CTE with the given name, without a limit, and with
+// DefaultMaxDepth.
+func New(name string, initial func(emit func(Row) error) error, recursive func(working Row, emit func(Row) error) error) *Recursive {
+	return &Recursive{
+		Name:      name,
+		Initial:   initial,
+		Recursive: recursive,
+		Limit:     -1,
+		MaxDepth:  DefaultMaxDepth,
+	}
+}
+
+// Run computes the rows of the CTE, and emits them in the order in which
+// they are extracted from the queue. If a row is deeper than MaxDepth, Run
+// fails with ErrRecursionLimit.
+func (r *Recursive) Run(emit func(Row) error) error {
+	q := &queue{order: r.Order}
+	var seen btree.Btree[Row, struct{}]
+	if !r.All {
+		seen = btree.New[Row, struct{}](treeOrder, func(a, b Row) bool { return compareRows(a, b) < 0 })
+	}
+
+	depth := 0
+	add := func(row Row) error {
+		if seen != nil {
+			if _, ok := seen.Get(row); ok {
+				return nil
+			}
+			seen.Put(row, struct{}{})
+		}
+		if depth > r.MaxDepth {
+			return fmt.Errorf("%w: %v is deeper than %d levels", ErrRecursionLimit, r.Name, r.MaxDepth)
+		}
+		q.push(row, depth)
+		return nil
+	}
+
+	if err := r.Initial(add); err != nil {
+		return err
+	}
+	for extracted := int64(0); q.Len() != 0; extracted++ {
+		if r.Limit >= 0 && extracted >= r.Offset+r.Limit {
+			return nil
+		}
+		e := heap.Pop(q).(queued)
+		if extracted >= r.Offset {
+			if err := emit(e.row); err != nil {
+				return err
+			}
+		}
+		depth = e.depth + 1
+		if err := r.Recursive(e.row, add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compareRows compares two rows column by column.
+func compareRows(a, b Row) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := function.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return len(a) - len(b)
+}
+
+// queued is a row in the queue. Seq is the position in which it was added,
+// so that rows with the same order are extracted first in, first out.
+type queued struct {
+	row   Row
+	depth int
+	seq   int
+}
+
+// queue is the queue of a recursive CTE, which implements heap.Interface.
+type queue struct {
+	order func(a, b Row) int
+	rows  []queued
+	seq   int
+}
+
+func (q *queue) push(row Row, depth int) {
+	heap.Push(q, queued{row, depth, q.seq})
+	q.seq++
+}
+
+func (q *queue) Len() int { return len(q.rows) }
+
+func (q *queue) Less(i, j int) bool {
+	a, b := q.rows[i], q.rows[j]
+	if q.order != nil {
+		if c := q.order(a.row, b.row); c != 0 {
+			return c < 0
+		}
+	}
+	return a.seq < b.seq
+}
+
+func (q *queue) Swap(i, j int) { q.rows[i], q.rows[j] = q.rows[j], q.rows[i] }
+
+func (q *queue) Push(x interface{}) { q.rows = append(q.rows, x.(queued)) }
+
+func (q *queue) Pop() interface{} {
+	last := q.rows[len(q.rows)-1]
+	q.rows = q.rows[:len(q.rows)-1]
+	return last
+}
@@ -0,0 +1,127 @@
+package cte
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func run(t *testing.T, r *Recursive) ([]Row, error) {
+	var rows []Row
+	err := r.Run(func(row Row) error {
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
+
+func values(start int64) func(emit func(Row) error) error {
+	return func(emit func(Row) error) error {
+		return emit(Row{start})
+	}
+}
+
+// counter is the recursive SELECT x+1 FROM cnt WHERE x < max.
+func counter(max int64) func(Row, func(Row) error) error {
+	return func(working Row, emit func(Row) error) error {
+		if x := working[0].(int64); x < max {
+			return emit(Row{x + 1})
+		}
+		return nil
+	}
+}
+
+func TestCount(t *testing.T) {
+	// WITH RECURSIVE cnt(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM cnt WHERE x < 5 LIMIT 3 OFFSET 1)
+	r := New("cnt", values(1), counter(5))
+	r.All = true
+	r.Limit, r.Offset = 3, 1
+	rows, err := run(t, r)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{int64(2)}, {int64(3)}, {int64(4)}}, rows)
+
+	r.Limit, r.Offset = -1, 0
+	r.MaxDepth = 3
+	_, err = run(t, r)
+	assert.True(t, errors.Is(err, ErrRecursionLimit))
+	assert.EqualError(t, err, "recursion limit exceeded: cnt is deeper than 3 levels")
+}
+
+// edges is a graph with a cycle.
+var edges = map[string][]string{
+	"a": {"b", "c"},
+	"b": {"d"},
+	"c": {"d"},
+	"d": {"a"},
+}
+
+// reachable is SELECT edges.to FROM reachable JOIN edges ON edges.from = reachable.node.
+func reachable(working Row, emit func(Row) error) error {
+	for _, to := range edges[working[0].(string)] {
+		if err := emit(Row{to}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestUnion(t *testing.T) {
+	r := New("reachable", func(emit func(Row) error) error {
+		return emit(Row{"a"})
+	}, reachable)
+	rows, err := run(t, r)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"a"}, {"b"}, {"c"}, {"d"}}, rows)
+
+	// the cycle never ends with UNION ALL, and doubles the rows every three
+	// levels
+	r.All = true
+	r.MaxDepth = 30
+	_, err = run(t, r)
+	assert.True(t, errors.Is(err, ErrRecursionLimit))
+}
+
+func TestOrder(t *testing.T) {
+	// an org chart with (name, depth) rows, where boss manages alice and bob,
+	// and alice manages carol
+	reports := map[string][]string{
+		"boss":  {"alice", "bob"},
+		"alice": {"carol"},
+	}
+	chart := func(working Row, emit func(Row) error) error {
+		for _, name := range reports[working[0].(string)] {
+			if err := emit(Row{name, working[1].(int64) + 1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	initial := func(emit func(Row) error) error {
+		return emit(Row{"boss", int64(0)})
+	}
+
+	// breadth-first without ORDER BY
+	rows, err := run(t, New("chart", initial, chart))
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"boss", int64(0)}, {"alice", int64(1)}, {"bob", int64(1)}, {"carol", int64(2)}}, rows)
+
+	// depth-first with ORDER BY depth DESC
+	r := New("chart", initial, chart)
+	r.Order = func(a, b Row) int { return int(b[1].(int64) - a[1].(int64)) }
+	rows, err = run(t, r)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"boss", int64(0)}, {"alice", int64(1)}, {"carol", int64(2)}, {"bob", int64(1)}}, rows)
+}
+
+func TestErrors(t *testing.T) {
+	failure := errors.New("failure")
+	r := New("cnt", values(1), counter(5))
+	err := r.Run(func(Row) error { return failure })
+	assert.Equal(t, failure, err)
+
+	r.Recursive = func(Row, func(Row) error) error { return failure }
+	_, err = run(t, r)
+	assert.Equal(t, failure, err)
+}
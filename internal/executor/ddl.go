@@ -0,0 +1,449 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/alter"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// defineTable converts a CREATE TABLE statement to the definition of the
+// table and its columns. DEFAULT and CHECK expressions are compiled, so they
+// cannot contain subqueries.
+func defineTable(stmt *ast.CreateTableStmt) (constraint.Table, []column, error) {
+	def := constraint.Table{Name: token.Unquote(stmt.TableName)}
+	columns := make([]column, len(stmt.ColumnDef))
+	types := make([]string, len(stmt.ColumnDef))
+	for i, cd := range stmt.ColumnDef {
+		name := token.Unquote(cd.ColumnName)
+		for _, col := range def.Columns {
+			if strings.EqualFold(col.Name, name) {
+				return def, nil, fmt.Errorf("%w: %v", alter.ErrColumnExists, name)
+			}
+		}
+		types[i] = typeName(cd.TypeName)
+		columns[i] = column{relation: def.Name, name: name, affinity: function.AffinityOf(types[i]), typed: true}
+		def.Columns = append(def.Columns, constraint.Column{Name: name})
+	}
+	// CHECK constraints can refer to all columns, and are compiled without
+	// an execution, so that they cannot contain subqueries
+	s := &scope{columns: columns}
+	x := &execution{}
+
+	primaryKey := false
+	addPrimaryKey := func(cols []int, conflict constraint.Conflict, desc bool) error {
+		if primaryKey {
+			return fmt.Errorf("%w: %v", ErrPrimaryKey, def.Name)
+		}
+		primaryKey = true
+		if len(cols) == 1 && strings.EqualFold(types[cols[0]], "INTEGER") && !desc {
+			// an INTEGER PRIMARY KEY is an alias of the row ID
+			def.Columns[cols[0]].RowID = true
+		}
+		def.Uniques = append(def.Uniques, constraint.UniqueConstraint{Columns: cols, PrimaryKey: true, OnConflict: conflict})
+		return nil
+	}
+
+	for i, cd := range stmt.ColumnDef {
+		col := &def.Columns[i]
+		for _, c := range cd.ColumnConstraint {
+			switch {
+			case c.Primary != nil:
+				if err := addPrimaryKey([]int{i}, conflictOf(c.ConflictClause), c.Desc != nil); err != nil {
+					return def, nil, err
+				}
+				col.Autoincrement = c.Autoincrement != nil
+			case c.Not != nil:
+				col.NotNull, col.OnNull = true, conflictOf(c.ConflictClause)
+			case c.Unique != nil:
+				def.Uniques = append(def.Uniques, constraint.UniqueConstraint{Columns: []int{i}, OnConflict: conflictOf(c.ConflictClause)})
+			case c.Check != nil:
+				check, err := x.check(c.Name, c.Expr, s)
+				if err != nil {
+					return def, nil, err
+				}
+				def.Checks = append(def.Checks, check)
+			case c.Default != nil:
+				value, err := x.defaultValue(c, columns[i])
+				if err != nil {
+					return def, nil, fmt.Errorf("default of %v: %w", col.Name, err)
+				}
+				col.Default = value
+			case c.Collate != nil:
+				name := token.Unquote(c.CollationName)
+				if _, ok := index.CollationByName(name); !ok {
+					return def, nil, fmt.Errorf("%w: %v", ErrNoSuchCollation, name)
+				}
+				columns[i].collation = name
+			case c.ForeignKeyClause != nil:
+				def.ForeignKeys = append(def.ForeignKeys, foreignKey([]int{i}, c.ForeignKeyClause))
+			case c.Generated != nil:
+				return def, nil, fmt.Errorf("%w: generated column %v", ErrUnsupported, col.Name)
+			}
+		}
+	}
+
+	for _, c := range stmt.TableConstraint {
+		switch {
+		case c.Primary != nil, c.Unique != nil:
+			var cols []int
+			for _, ic := range c.IndexedColumn {
+				name := ic.ColumnName
+				if name == nil && ic.Expr != nil && ic.Expr.TableName == nil {
+					name = ic.Expr.ColumnName
+				}
+				i := columnIndex(def, token.Unquote(name))
+				if i < 0 {
+					return def, nil, fmt.Errorf("%w: %v", ErrNoSuchColumn, ast.Text(ic))
+				}
+				cols = append(cols, i)
+			}
+			if c.Unique != nil {
+				def.Uniques = append(def.Uniques, constraint.UniqueConstraint{Columns: cols, OnConflict: conflictOf(c.ConflictClause)})
+				continue
+			}
+			if err := addPrimaryKey(cols, conflictOf(c.ConflictClause), false); err != nil {
+				return def, nil, err
+			}
+		case c.Check != nil:
+			check, err := x.check(c.Name, c.Expr, s)
+			if err != nil {
+				return def, nil, err
+			}
+			def.Checks = append(def.Checks, check)
+		case c.Foreign != nil:
+			var cols []int
+			for _, name := range c.ColumnName {
+				i := columnIndex(def, token.Unquote(name))
+				if i < 0 {
+					return def, nil, fmt.Errorf("%w: %v", ErrNoSuchColumn, token.Unquote(name))
+				}
+				cols = append(cols, i)
+			}
+			def.ForeignKeys = append(def.ForeignKeys, foreignKey(cols, c.ForeignKeyClause))
+		}
+	}
+	return def, columns, nil
+}
+
+// typeName returns the declared type of a column, without its size.
+func typeName(t *ast.TypeName) string {
+	if t == nil {
+		return ""
+	}
+	names := make([]string, len(t.Name))
+	for i, name := range t.Name {
+		names[i] = token.Unquote(name)
+	}
+	return strings.Join(names, " ")
+}
+
+func columnIndex(def constraint.Table, name string) int {
+	for i, col := range def.Columns {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// check compiles a CHECK constraint. The name of a constraint without a name
+// is the text of its expression.
+func (x *execution) check(name token.Token, e *ast.Expr, s *scope) (constraint.CheckConstraint, error) {
+	p, err := command.Expr(e)
+	if err != nil {
+		return constraint.CheckConstraint{}, err
+	}
+	compiled, err := x.expr(p, s)
+	if err != nil {
+		return constraint.CheckConstraint{}, err
+	}
+	c := constraint.CheckConstraint{
+		Name: token.Unquote(name),
+		Expr: func(row constraint.Row) (constraint.Value, error) {
+			return compiled.eval(&frame{row: row})
+		},
+		Columns: []int{},
+	}
+	if c.Name == "" {
+		c.Name = ast.Text(e)
+	}
+	seen := make(map[int]bool)
+	var resolveErr error
+	walk(p, func(e *planner.Expr) {
+		if e.Op != planner.ColumnExpr || resolveErr != nil {
+			return
+		}
+		_, i, err := s.resolve(e.Column)
+		if err != nil {
+			resolveErr = err
+			return
+		}
+		if !seen[i] {
+			seen[i] = true
+			c.Columns = append(c.Columns, i)
+		}
+	})
+	return c, resolveErr
+}
+
+// walk calls the given function for the expression and all its arguments.
+// Subqueries are not walked.
+func walk(e *planner.Expr, fn func(e *planner.Expr)) {
+	if e == nil {
+		return
+	}
+	fn(e)
+	for _, arg := range e.Args {
+		walk(arg, fn)
+	}
+}
+
+// defaultValue compiles the DEFAULT of a column, whose value is converted
+// with the affinity of the column.
+func (x *execution) defaultValue(c *ast.ColumnConstraint, col column) (func() (constraint.Value, error), error) {
+	e := c.Expr
+	switch {
+	case c.LiteralValue != nil:
+		e = &ast.Expr{LiteralValue: c.LiteralValue}
+	case c.SignedNumber != nil:
+		e = &ast.Expr{LiteralValue: c.SignedNumber.NumericLiteral}
+		if c.SignedNumber.Sign != nil {
+			e = &ast.Expr{UnaryOperator: c.SignedNumber.Sign, Expr1: e}
+		}
+	}
+	p, err := command.Expr(e)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := x.expr(p, &scope{})
+	if err != nil {
+		return nil, err
+	}
+	return func() (constraint.Value, error) {
+		v, err := compiled.eval(&frame{})
+		if err != nil {
+			return nil, err
+		}
+		return col.affinity.Apply(v), nil
+	}, nil
+}
+
+// conflictOf returns the conflict algorithm of an ON CONFLICT clause.
+func conflictOf(c *ast.ConflictClause) constraint.Conflict {
+	switch {
+	case c == nil:
+		return constraint.Default
+	case c.Rollback != nil:
+		return constraint.Rollback
+	case c.Abort != nil:
+		return constraint.Abort
+	case c.Fail != nil:
+		return constraint.Fail
+	case c.Ignore != nil:
+		return constraint.Ignore
+	case c.Replace != nil:
+		return constraint.Replace
+	}
+	return constraint.Default
+}
+
+// foreignKey converts the foreign key clause of the given columns.
+func foreignKey(columns []int, c *ast.ForeignKeyClause) constraint.ForeignKeyConstraint {
+	fk := constraint.ForeignKeyConstraint{
+		Columns:  columns,
+		Parent:   token.Unquote(c.ForeignTable),
+		Deferred: c.Deferrable != nil && c.Not == nil && c.Deferred != nil,
+	}
+	for _, name := range c.ColumnName {
+		fk.ParentColumns = append(fk.ParentColumns, token.Unquote(name))
+	}
+	for _, core := range c.ForeignKeyClauseCore {
+		var action constraint.Action
+		switch {
+		case core.Set != nil && core.Null != nil:
+			action = constraint.SetNull
+		case core.Set != nil:
+			action = constraint.SetDefault
+		case core.Cascade != nil:
+			action = constraint.Cascade
+		case core.Restrict != nil:
+			action = constraint.Restrict
+		}
+		switch {
+		case core.Delete != nil:
+			fk.OnDelete = action
+		case core.Update != nil:
+			fk.OnUpdate = action
+		}
+	}
+	return fk
+}
+
+// createTable executes CREATE TABLE. The table is recorded in the catalog of
+// its database with the text of the statement, from which the schema of the
+// database is compiled again. The rows of CREATE TABLE ... AS SELECT are
+// inserted into the new table.
+func (x *execution) createTable(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.CreateTableStmt
+	db, err := x.e.session.CreateTable(stmt)
+	if err != nil {
+		return nil, err
+	}
+	s, err := x.schema(db)
+	if err != nil {
+		return nil, err
+	}
+	name := token.Unquote(stmt.TableName)
+	if _, exists := s.table(name); exists || s.hasView(name) || s.hasIndex(name) {
+		if stmt.If != nil {
+			return table{}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", alter.ErrTableExists, name)
+	}
+	if strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+		return nil, fmt.Errorf("%w: %v", ErrReservedName, name)
+	}
+
+	var rows [][]function.Value
+	var sql string
+	if cmd.Plan != nil {
+		op, err := x.node(cmd.Plan, x.outer)
+		if err != nil {
+			return nil, err
+		}
+		if rows, err = collect(op, x.outerFrame); err != nil {
+			return nil, err
+		}
+		sql = asSelectDefinition(name, visible(op.columns()))
+	} else {
+		// like SQLite, the definition is stored without TEMP, IF NOT EXISTS
+		// and the schema name
+		sql = "CREATE TABLE " + stmt.TableName.Value() + " " + cmd.Text(stmt.LeftParen)
+	}
+
+	c := catalog{s.store.tx}
+	id, err := c.nextID()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.put(object{typ: typeTable, name: name, table: name, id: id, sql: sql}); err != nil {
+		return nil, err
+	}
+	if s, err = x.reload(db); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return table{}, nil
+	}
+
+	t, _ := s.table(name)
+	insert := t.t.Enforcer().Begin(constraint.Default)
+	for _, row := range rows {
+		if _, _, err := insert.Insert(nil, t.apply(nil, row)); err != nil {
+			return nil, err
+		}
+	}
+	return table{}, insert.Commit()
+}
+
+// asSelectDefinition returns the definition of a table of CREATE TABLE ... AS
+// SELECT, with the given columns. Like in SQLite, the declared type of a
+// column is derived from the affinity of the expression of the column.
+func asSelectDefinition(name string, columns []column) string {
+	defs := make([]string, len(columns))
+	for i, col := range columns {
+		defs[i] = quote(col.name)
+		if !col.typed {
+			continue
+		}
+		switch col.affinity {
+		case function.Text:
+			defs[i] += " TEXT"
+		case function.Numeric:
+			defs[i] += " NUM"
+		case function.Integer:
+			defs[i] += " INT"
+		case function.Real:
+			defs[i] += " REAL"
+		}
+	}
+	return "CREATE TABLE " + quote(name) + "(" + strings.Join(defs, ", ") + ")"
+}
+
+// quote quotes an identifier.
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// dropTable executes DROP TABLE. The table is removed from the catalog, and
+// its rows, indexes and AUTOINCREMENT sequence are deleted.
+func (x *execution) dropTable(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.DropTableStmt
+	db, s, t, err := x.table(token.Unquote(stmt.SchemaName), token.Unquote(stmt.TableName))
+	if err != nil {
+		if stmt.If != nil && errors.Is(err, attach.ErrNoSuchTable) {
+			return table{}, nil
+		}
+		return nil, err
+	}
+	if strings.HasPrefix(strings.ToLower(t.name), "sqlite_") {
+		return nil, fmt.Errorf("%w: %v", ErrReservedName, t.name)
+	}
+	// the table is dropped, after the transactions, that write its rows,
+	// have ended
+	if err := s.store.lock(t.id, "", lock.Exclusive); err != nil {
+		return nil, err
+	}
+
+	c := catalog{s.store.tx}
+	if err := c.delete(t.name); err != nil {
+		return nil, err
+	}
+	for _, ix := range s.indexes {
+		if !strings.EqualFold(ix.table, t.name) {
+			continue
+		}
+		if err := c.delete(ix.name); err != nil {
+			return nil, err
+		}
+	}
+	for _, tr := range s.triggers.Triggers() {
+		if !strings.EqualFold(tr.Table, t.name) {
+			continue
+		}
+		if err := c.delete(tr.Name); err != nil {
+			return nil, err
+		}
+	}
+	// the prefix of the table holds its rows and indexes
+	if err := c.deletePrefix(tablePrefixOf(t.id)); err != nil {
+		return nil, err
+	}
+	if err := s.store.tx.Delete(sequenceKey(t.name)); err != nil {
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
+
+// collect returns all rows of the given operator, with the given frame of the
+// outer scope.
+func collect(op operator, outer *frame) ([][]function.Value, error) {
+	var rows [][]function.Value
+	err := op.run(outer, func(row []function.Value) error {
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
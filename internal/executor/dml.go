@@ -0,0 +1,296 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+)
+
+// column returns the position of the column with the given name, or -1.
+// The hidden row ID is not found.
+func (t *storedTable) column(name string) int {
+	for i, col := range t.columns {
+		if !col.hidden && strings.EqualFold(col.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnsOf returns the positions of the columns with the given names.
+func (t *storedTable) columnsOf(names []string) ([]int, error) {
+	if names == nil {
+		return nil, nil
+	}
+	columns := make([]int, len(names))
+	for i, name := range names {
+		if columns[i] = t.column(name); columns[i] < 0 {
+			return nil, fmt.Errorf("%w: %v.%v", ErrNoSuchColumn, t.name, name)
+		}
+	}
+	return columns, nil
+}
+
+// apply converts the given values of the given columns, or of all columns if
+// columns is nil, with the affinities of the columns.
+func (t *storedTable) apply(columns []int, values []function.Value) constraint.Row {
+	row := make(constraint.Row, len(values))
+	for i, v := range values {
+		c := i
+		if columns != nil {
+			c = columns[i]
+		}
+		row[i] = t.columns[c].affinity.Apply(v)
+	}
+	return row
+}
+
+// width returns the number of columns of the table, without the row ID.
+func (t *storedTable) width() int {
+	return len(t.columns) - 1
+}
+
+// insert executes INSERT. All rows are computed before the first one is
+// inserted, so that INSERT ... SELECT doesn't see its own rows.
+func (x *execution) insert(cmd command.Command) (Result, error) {
+	n := cmd.Plan
+	_, s, t, err := x.table(n.Schema, n.Relation)
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		return x.changeView(cmd, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns, err := t.columnsOf(n.Columns)
+	if err != nil {
+		return nil, err
+	}
+	op, err := x.node(n.Children[0], x.outer)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := collect(op, x.outerFrame)
+	if err != nil {
+		return nil, err
+	}
+	want := len(columns)
+	if columns == nil {
+		want = t.width()
+	}
+	if len(rows) > 0 && len(rows[0]) != want {
+		if columns == nil {
+			return nil, fmt.Errorf("%w: table %v has %d columns but %d values were supplied", ErrValueCount, t.name, want, len(rows[0]))
+		}
+		return nil, fmt.Errorf("%w: %d values for %d columns", ErrValueCount, len(rows[0]), want)
+	}
+
+	stmt := t.t.Enforcer().Begin(cmd.Conflict)
+	for _, row := range rows {
+		values := t.apply(columns, row)
+		new, err := t.newRow(columns, values)
+		if err != nil {
+			_ = stmt.Rollback()
+			return nil, err
+		}
+		if ok, err := x.fire(s, t, trigger.Before, trigger.Insert, nil, nil, new); err != nil || !ok {
+			if err != nil {
+				return nil, x.saveSequences(s, triggerError(stmt, err))
+			}
+			continue
+		}
+		id, ok, err := stmt.Insert(columns, values)
+		if err != nil {
+			return nil, x.saveSequences(s, err)
+		}
+		if !ok {
+			continue
+		}
+		x.changes++
+		x.lastInsertID = int64(id)
+
+		stored, _, err := t.t.Rows().Get(id)
+		if err == nil {
+			_, err = x.fire(s, t, trigger.After, trigger.Insert, nil, nil, t.triggerRow(stored, int64(id)))
+		}
+		if err != nil {
+			return nil, x.saveSequences(s, triggerError(stmt, err))
+		}
+	}
+	return table{}, x.saveSequences(s, stmt.Commit())
+}
+
+// update executes UPDATE. The new values of all rows are computed before the
+// first row is updated, so that they are computed from the old rows.
+func (x *execution) update(cmd command.Command) (Result, error) {
+	n := cmd.Plan
+	_, s, t, err := x.table(n.Schema, n.Relation)
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		return x.changeView(cmd, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns, err := t.columnsOf(n.Columns)
+	if err != nil {
+		return nil, err
+	}
+	op, err := x.node(n.Children[0], x.outer)
+	if err != nil {
+		return nil, err
+	}
+	sc := &scope{columns: op.columns(), outer: x.outer}
+	exprs, err := x.exprs(n.Projections, sc)
+	if err != nil {
+		return nil, err
+	}
+	rowID := len(op.columns()) - 1
+
+	type change struct {
+		id     index.RowID
+		values constraint.Row
+	}
+	var changes []change
+	err = op.run(x.outerFrame, func(row []function.Value) error {
+		f := &frame{row: row, outer: x.outerFrame}
+		values := make([]function.Value, len(exprs))
+		for i, e := range exprs {
+			v, err := e.eval(f)
+			if err != nil {
+				return err
+			}
+			values[i] = v
+		}
+		id, _ := row[rowID].(int64)
+		changes = append(changes, change{index.RowID(id), t.apply(columns, values)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := t.t.Enforcer().Begin(cmd.Conflict)
+	for _, c := range changes {
+		// an action of a foreign key or a trigger may have deleted the row
+		row, exists, err := t.t.Rows().Get(c.id)
+		if err != nil || !exists {
+			if err != nil {
+				_ = stmt.Rollback()
+				return nil, err
+			}
+			continue
+		}
+		old := t.triggerRow(row, int64(c.id))
+		updated := append(constraint.Row{}, row...)
+		for i, v := range c.values {
+			if columns == nil {
+				updated[i] = v
+			} else {
+				updated[columns[i]] = v
+			}
+		}
+		new := t.triggerRow(updated, int64(c.id))
+		if ok, err := x.fire(s, t, trigger.Before, trigger.Update, n.Columns, old, new); err != nil || !ok {
+			if err != nil {
+				return nil, x.saveSequences(s, triggerError(stmt, err))
+			}
+			continue
+		}
+		ok, err := stmt.Update(c.id, columns, c.values)
+		if err != nil {
+			return nil, x.saveSequences(s, err)
+		}
+		if !ok {
+			continue
+		}
+		x.changes++
+		if _, err := x.fire(s, t, trigger.After, trigger.Update, n.Columns, old, new); err != nil {
+			return nil, x.saveSequences(s, triggerError(stmt, err))
+		}
+	}
+	return table{}, x.saveSequences(s, stmt.Commit())
+}
+
+// delete executes DELETE. The deleted rows are found before the first one is
+// deleted.
+func (x *execution) delete(cmd command.Command) (Result, error) {
+	n := cmd.Plan
+	_, s, t, err := x.table(n.Schema, n.Relation)
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		return x.changeView(cmd, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	op, err := x.node(n.Children[0], x.outer)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := rowIDs(op, x.outerFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := t.t.Enforcer().Begin(constraint.Default)
+	for _, id := range ids {
+		row, exists, err := t.t.Rows().Get(id)
+		if err != nil || !exists {
+			if err != nil {
+				_ = stmt.Rollback()
+				return nil, err
+			}
+			continue
+		}
+		old := t.triggerRow(row, int64(id))
+		if ok, err := x.fire(s, t, trigger.Before, trigger.Delete, nil, old, nil); err != nil || !ok {
+			if err != nil {
+				return nil, x.saveSequences(s, triggerError(stmt, err))
+			}
+			continue
+		}
+		if err := stmt.Delete(id); err != nil {
+			return nil, x.saveSequences(s, err)
+		}
+		x.changes++
+		if _, err := x.fire(s, t, trigger.After, trigger.Delete, nil, old, nil); err != nil {
+			return nil, x.saveSequences(s, triggerError(stmt, err))
+		}
+	}
+	return table{}, x.saveSequences(s, stmt.Commit())
+}
+
+// rowIDs returns the row IDs of the rows of an operator, that reads the rows
+// of a single table, whose row ID is its last column, with the given frame of
+// the outer scope.
+func rowIDs(op operator, outer *frame) ([]index.RowID, error) {
+	i := len(op.columns()) - 1
+	var ids []index.RowID
+	err := op.run(outer, func(row []function.Value) error {
+		id, _ := row[i].(int64)
+		ids = append(ids, index.RowID(id))
+		return nil
+	})
+	return ids, err
+}
+
+// saveSequences stores the AUTOINCREMENT sequences of the tables of the given
+// schema after a statement, also if it failed with the given error, since
+// the statement may have kept its changes.
+func (x *execution) saveSequences(s *schema, err error) error {
+	c := catalog{s.store.tx}
+	for _, t := range s.tables {
+		if !t.autoincrement {
+			continue
+		}
+		if saveErr := c.saveSequence(s.alter.Sequences(), t.name); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
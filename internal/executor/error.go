@@ -0,0 +1,28 @@
+package executor
+
+// Error provides constant errors to the executor package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrUnsupported = Error("unsupported command")
+	ErrNoSession   = Error("command can only be executed in a session")
+
+	ErrCorrupt              = Error("database disk image is malformed")
+	ErrNoSuchColumn         = Error("no such column")
+	ErrAmbiguousColumn      = Error("ambiguous column name")
+	ErrNoSuchCollation      = Error("no such collation sequence")
+	ErrMisplacedStar        = Error("* is only allowed in a result column or count(*)")
+	ErrMisuseAggregate      = Error("misuse of aggregate function")
+	ErrSubqueryInDefinition = Error("subqueries are prohibited in the definition of a table")
+	ErrSubqueryColumns      = Error("sub-select returns more than 1 column")
+	ErrCompoundColumns      = Error("SELECTs to the left and right of a compound operator do not have the same number of result columns")
+	ErrValueCount           = Error("number of values does not match the number of columns")
+	ErrPrimaryKey           = Error("table has more than one primary key")
+	ErrReservedName         = Error("object name reserved for internal use")
+	ErrReindexObject        = Error("unable to identify the object to be reindexed")
+	ErrAnalyzeObject        = Error("no such table or index")
+	ErrRaiseOutsideTrigger  = Error("RAISE() may only be used within a trigger-program")
+)
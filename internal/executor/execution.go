@@ -0,0 +1,311 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/profile"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// statementSavepoint is the name of the savepoint, that a statement in a
+// transaction is rolled back to, if it fails. It cannot be the name of a
+// savepoint of SAVEPOINT.
+const statementSavepoint = "\x00statement"
+
+// execution holds the state of the execution of a single statement.
+type execution struct {
+	// e is the executor, or nil while definitions of the schema are
+	// compiled, which cannot contain subqueries.
+	e   *simpleExecutor
+	ctx context.Context
+	tx  *attach.Tx
+	// budget is the memory, that the sorts, aggregations and hash joins of
+	// the statement can use, before they spill to temporary files. It is
+	// nil while definitions of the schema are compiled.
+	budget *spill.Budget
+	// loaded holds the schemas of the databases, that the statement uses,
+	// which are bound to the transactions of the databases.
+	loaded map[*attach.Database]*schema
+	// references holds a set for every subquery, that is being compiled,
+	// to which the scopes are added, whose columns the subquery refers to.
+	references []map[*scope]bool
+	// outer is the scope of the OLD and NEW row of the trigger, whose
+	// program the statement is part of, and outerFrame holds their values.
+	// Both are nil for a statement, that is not run by a trigger.
+	outer      *scope
+	outerFrame *frame
+	// ctes holds the materialized common table expressions, that are
+	// visible in the plan, that is being compiled, from the outermost to
+	// the innermost WITH clause.
+	ctes []map[string]*commonTable
+	// firing holds the triggers, that are running, of the schemas, whose
+	// tables the statement and its triggers change.
+	firing map[*schema]*trigger.Execution
+	// plans holds the nodes, that describe the joins, that the cost-based
+	// planner has planned, by the nodes of the logical plan, while EXPLAIN
+	// QUERY PLAN compiles a statement, or is nil.
+	plans map[*planner.Node]*planner.Node
+	// profile is the profile, that the operators of the nodes, that are
+	// compiled, are added to, while EXPLAIN ANALYZE runs the statement, or
+	// nil.
+	profile      *profile.Operator
+	changes      int64
+	lastInsertID int64
+}
+
+// executeStatement executes a statement, that reads or changes the tables of
+// the session. Outside of a transaction, the statement is executed in its
+// own transaction. In a transaction, a statement that fails is rolled back,
+// unless a violation of a constraint is resolved with FAIL, which keeps the
+// changes, or with ROLLBACK, which rolls back the transaction. The transaction
+// is also rolled back, if it is the victim of a deadlock, so that the other
+// transactions of the deadlock can go on.
+func (e *simpleExecutor) executeStatement(ctx context.Context, cmd command.Command) (Result, error) {
+	tx := e.session.Tx()
+	autocommit := tx == nil
+	if autocommit {
+		var err error
+		if tx, err = e.session.Begin(ctx, transaction.Options{}); err != nil {
+			return nil, err
+		}
+	} else if err := tx.Savepoint(statementSavepoint); err != nil {
+		return nil, err
+	}
+
+	x := &execution{
+		e:      e,
+		ctx:    ctx,
+		tx:     tx,
+		budget: e.settings.Budget(e.memory),
+		loaded: make(map[*attach.Database]*schema),
+		firing: make(map[*schema]*trigger.Execution),
+	}
+	result, err := x.execute(cmd)
+	x.budget.Close()
+	conflict, resolved := resolution(err)
+	switch {
+	case autocommit && (err == nil || resolved && conflict == constraint.Fail):
+		if commitErr := e.commit(); commitErr != nil {
+			e.rollback()
+			return nil, commitErr
+		}
+	case autocommit, resolved && conflict == constraint.Rollback, errors.Is(err, lock.ErrDeadlock):
+		e.rollback()
+	case err == nil, resolved && conflict == constraint.Fail:
+		if releaseErr := tx.Release(statementSavepoint); releaseErr != nil {
+			return nil, releaseErr
+		}
+	default:
+		if rollbackErr := tx.RollbackTo(statementSavepoint); rollbackErr != nil {
+			return nil, rollbackErr
+		}
+		if releaseErr := tx.Release(statementSavepoint); releaseErr != nil {
+			return nil, releaseErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := result.(table); ok {
+		t.changes, t.lastInsertID = x.changes, x.lastInsertID
+		result = t
+	}
+	return result, nil
+}
+
+// resolution returns the conflict resolution algorithm of a violation of a
+// constraint or of RAISE in a trigger, and false for other errors.
+func resolution(err error) (constraint.Conflict, bool) {
+	var v *constraint.Violation
+	if errors.As(err, &v) {
+		return v.Conflict, true
+	}
+	var raise *trigger.Raise
+	if errors.As(err, &raise) {
+		return raise.Conflict, true
+	}
+	return constraint.Default, false
+}
+
+// commit commits the transaction of the session, after checking the
+// deferred foreign keys of all schemas. If they are violated, the
+// transaction remains active, like in SQLite.
+func (e *simpleExecutor) commit() error {
+	for _, s := range e.schemas {
+		if err := s.alter.Constraints().Commit(); err != nil {
+			return err
+		}
+	}
+	err := e.session.Commit()
+	e.resetConstraints()
+	return err
+}
+
+// rollback rolls back the transaction of the session.
+func (e *simpleExecutor) rollback() {
+	_ = e.session.Rollback()
+	e.resetConstraints()
+}
+
+// resetConstraints discards the violations of deferred foreign keys after
+// the transaction ended.
+func (e *simpleExecutor) resetConstraints() {
+	for _, s := range e.schemas {
+		s.alter.Constraints().Rollback()
+	}
+}
+
+// execute executes the command, or describes its plan for EXPLAIN QUERY PLAN
+// and EXPLAIN ANALYZE.
+func (x *execution) execute(cmd command.Command) (Result, error) {
+	switch cmd.Explain {
+	case command.ExplainQueryPlan:
+		return x.explainQueryPlan(cmd)
+	case command.ExplainAnalyze:
+		return x.explainAnalyze(cmd)
+	}
+	switch cmd.Op {
+	case command.Select:
+		op, err := x.node(cmd.Plan, x.outer)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := collect(op, x.outerFrame)
+		if err != nil {
+			return nil, err
+		}
+		result := table{}
+		for _, col := range visible(op.columns()) {
+			result.columns = append(result.columns, col.name)
+		}
+		for _, row := range rows {
+			values := make([]interface{}, len(result.columns))
+			copy(values, row)
+			result.rows = append(result.rows, values)
+		}
+		return result, nil
+	case command.Insert:
+		return x.insert(cmd)
+	case command.Update:
+		return x.update(cmd)
+	case command.Delete:
+		return x.delete(cmd)
+	case command.CreateTable:
+		return x.createTable(cmd)
+	case command.DropTable:
+		return x.dropTable(cmd)
+	case command.CreateIndex:
+		return x.createIndex(cmd)
+	case command.DropIndex:
+		return x.dropIndex(cmd)
+	case command.Reindex:
+		return x.reindex(cmd)
+	case command.Pragma:
+		return x.foreignKeyCheck(cmd)
+	case command.CreateTrigger:
+		return x.createTrigger(cmd)
+	case command.DropTrigger:
+		return x.dropTrigger(cmd)
+	case command.CreateView:
+		return x.createView(cmd)
+	case command.DropView:
+		return x.dropView(cmd)
+	case command.Analyze:
+		return x.analyze(cmd)
+	case command.AlterTable:
+		return x.alterTable(cmd)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnsupported, cmd.Op)
+}
+
+// schema returns the schema of the given database, bound to the transaction
+// of the database. The compiled schema of the executor is reused, as long as
+// the version of the catalog of the database didn't change.
+func (x *execution) schema(db *attach.Database) (*schema, error) {
+	if s, ok := x.loaded[db]; ok {
+		return s, nil
+	}
+	tx, err := x.tx.On(db.Name)
+	if err != nil {
+		return nil, err
+	}
+	c := catalog{tx}
+	version, err := c.version()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := x.e.schemas[db]
+	if !ok || !bytes.Equal(s.version, version) {
+		if s, err = loadSchema(tx, version); err != nil {
+			return nil, err
+		}
+		x.e.schemas[db] = s
+	}
+	s.store.tx, s.store.ctx = tx, x.ctx
+	s.alter.Constraints().SetForeignKeys(x.e.foreignKeys)
+	s.runner.SetRecursive(x.e.recursiveTriggers)
+	s.exec = x
+	for _, t := range s.tables {
+		if !t.autoincrement {
+			continue
+		}
+		if err := c.loadSequence(s.alter.Sequences(), t.name); err != nil {
+			return nil, err
+		}
+	}
+	x.loaded[db] = s
+	return s, nil
+}
+
+// reload compiles the schema of the given database again, after its catalog
+// was changed.
+func (x *execution) reload(db *attach.Database) (*schema, error) {
+	delete(x.loaded, db)
+	delete(x.e.schemas, db)
+	return x.schema(db)
+}
+
+// table returns the table with the given name of the given schema, or of the
+// first database that has such a table, if the schema name is empty.
+func (x *execution) table(schemaName, name string) (*attach.Database, *schema, *storedTable, error) {
+	db, s, err := x.resolve(schemaName, name, func(s *schema, name string) bool {
+		_, ok := s.table(name)
+		return ok
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t, _ := s.table(name)
+	return db, s, t, nil
+}
+
+// resolve returns the database and schema, that has the object with the given
+// name, as reported by has, like attach.Session.Resolve.
+func (x *execution) resolve(schemaName, name string, has func(s *schema, name string) bool) (*attach.Database, *schema, error) {
+	var loadErr error
+	db, err := x.e.session.Resolve(schemaName, name, func(db *attach.Database, name string) bool {
+		s, err := x.schema(db)
+		if err != nil {
+			loadErr = err
+			return false
+		}
+		return has(s, name)
+	})
+	if loadErr != nil {
+		return nil, nil, loadErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return db, x.loaded[db], nil
+}
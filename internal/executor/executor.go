@@ -1,20 +1,47 @@
 package executor
 
 import (
+	"context"
+
 	"github.com/rs/zerolog"
+	"github.com/tomarrell/lbadd/internal/database/attach"
 	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
 )
 
 // Executor describes a component that can execute a command. A command is the
 // intermediate representation of an SQL statement, meaning that it has been
 // parsed.
 type Executor interface {
-	// Execute executes a command. The result of the computation is returned
-	// together with an error, if one occurred.
-	Execute(command.Command) (Result, error)
+	// Execute executes a command under the given context. The result of the
+	// computation is returned together with an error, if one occurred.
+	Execute(context.Context, command.Command) (Result, error)
+}
+
+// Option is an option for New and NewSession.
+type Option func(*simpleExecutor)
+
+// WithMemory sets the memory pool, that limits the memory of all queries of
+// the executor. Without this option, the memory is unlimited, and temporary
+// files are written to the temporary directory of the operating system.
+func WithMemory(pool *spill.Pool) Option {
+	return func(e *simpleExecutor) {
+		e.memory = pool
+	}
 }
 
 // New creates a new, ready to use Executor.
-func New(log zerolog.Logger) Executor {
-	return newSimpleExecutor(log)
+func New(log zerolog.Logger, opts ...Option) Executor {
+	return NewSession(log, nil, opts...)
+}
+
+// NewSession creates a new, ready to use Executor, that executes commands in
+// the given session. Commands that begin or end transactions, or that work
+// with savepoints, can only be executed in a session.
+func NewSession(log zerolog.Logger, session *attach.Session, opts ...Option) Executor {
+	exec := newSimpleExecutor(log, session)
+	for _, opt := range opts {
+		opt(exec)
+	}
+	return exec
 }
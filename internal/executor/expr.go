@@ -0,0 +1,697 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// functions holds the functions, that expressions can call.
+var functions = function.NewRegistry()
+
+// rowIDNames are the names, under which the row ID of a table can be
+// referenced, unless the table has a column with that name.
+var rowIDNames = []string{"rowid", "oid", "_rowid_"}
+
+// column is a column of the rows, that an operator produces.
+type column struct {
+	// relation is the name of the relation, that qualifies the column in
+	// expressions, or empty.
+	relation string
+	name     string
+	// affinity is the affinity of a column of a table, if typed is set. It
+	// is applied to the values, that the column is compared with.
+	affinity  function.Affinity
+	typed     bool
+	collation string
+	// hidden columns, like the row ID of a table, are not expanded by *.
+	hidden bool
+	// aggregate is the text of the call of the aggregate function, whose
+	// result the column holds, or empty.
+	aggregate string
+}
+
+// scope holds the columns, that the expressions of an operator can refer to.
+// The outer scope is the scope of the query, that a subquery is part of, so
+// that it can refer to the columns of the query.
+type scope struct {
+	columns []column
+	outer   *scope
+}
+
+// frame holds the values of the columns of a scope, while an expression is
+// evaluated.
+type frame struct {
+	row   []function.Value
+	outer *frame
+}
+
+// expression is a compiled expression.
+type expression struct {
+	eval func(f *frame) (function.Value, error)
+	// affinity is the affinity of the expression, if typed is set, which is
+	// the case for columns and CAST.
+	affinity function.Affinity
+	typed    bool
+	// collation is the collation of the column or of the COLLATE operator of
+	// the expression. The latter is explicit.
+	collation string
+	explicit  bool
+}
+
+// constant creates an expression with the given value.
+func constant(v function.Value) expression {
+	return expression{eval: func(*frame) (function.Value, error) { return v, nil }}
+}
+
+// resolve finds the column of the given scope or its outer scopes, that the
+// given reference refers to. It returns how many scopes are between the
+// column and the given scope, and the position of the column.
+func (s *scope) resolve(ref planner.Column) (int, int, error) {
+	for depth, sc := 0, s; sc != nil; depth, sc = depth+1, sc.outer {
+		found := -1
+		for i, col := range sc.columns {
+			if col.hidden || col.aggregate != "" || !strings.EqualFold(col.name, ref.Name) {
+				continue
+			}
+			if ref.Relation != "" && !strings.EqualFold(col.relation, ref.Relation) {
+				continue
+			}
+			if found >= 0 {
+				return 0, 0, fmt.Errorf("%w: %v", ErrAmbiguousColumn, ref)
+			}
+			found = i
+		}
+		if found < 0 && isRowIDName(ref.Name) {
+			for i, col := range sc.columns {
+				if col.hidden && (ref.Relation == "" || strings.EqualFold(col.relation, ref.Relation)) {
+					found = i
+					break
+				}
+			}
+		}
+		if found >= 0 {
+			return depth, found, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("%w: %v", ErrNoSuchColumn, ref)
+}
+
+func isRowIDName(name string) bool {
+	for _, n := range rowIDNames {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// at returns the frame of the scope, that is the given number of scopes
+// outside the scope of this frame.
+func (f *frame) at(depth int) *frame {
+	for ; depth > 0; depth-- {
+		f = f.outer
+	}
+	return f
+}
+
+// expr compiles an expression, that refers to the columns of the given
+// scope.
+func (x *execution) expr(e *planner.Expr, s *scope) (expression, error) {
+	switch e.Op {
+	case planner.ColumnExpr:
+		return x.columnExpr(e.Column, s)
+	case planner.ConstExpr:
+		if b, ok := e.Value.(bool); ok {
+			return constant(boolValue(b)), nil
+		}
+		return constant(e.Value), nil
+	case planner.EqExpr, planner.NeExpr, planner.LtExpr, planner.LeExpr, planner.GtExpr, planner.GeExpr, planner.IsExpr, planner.IsNotExpr:
+		return x.comparison(e, s)
+	case planner.AndExpr, planner.OrExpr:
+		return x.logical(e, s)
+	case planner.NotExpr:
+		arg, err := x.expr(e.Args[0], s)
+		if err != nil {
+			return expression{}, err
+		}
+		return expression{eval: func(f *frame) (function.Value, error) {
+			v, err := arg.eval(f)
+			if err != nil {
+				return nil, err
+			}
+			truth, ok := function.Truth(v)
+			if !ok {
+				return nil, nil
+			}
+			return boolValue(!truth), nil
+		}}, nil
+	case planner.IsNullExpr:
+		arg, err := x.expr(e.Args[0], s)
+		if err != nil {
+			return expression{}, err
+		}
+		return expression{eval: func(f *frame) (function.Value, error) {
+			v, err := arg.eval(f)
+			return boolValue(v == nil), err
+		}}, nil
+	case planner.AddExpr, planner.SubExpr, planner.MulExpr, planner.DivExpr, planner.ModExpr, planner.ConcatExpr,
+		planner.BitAndExpr, planner.BitOrExpr, planner.ShiftLeftExpr, planner.ShiftRightExpr:
+		return x.binary(e, s)
+	case planner.NegExpr, planner.BitNotExpr:
+		arg, err := x.expr(e.Args[0], s)
+		if err != nil {
+			return expression{}, err
+		}
+		op := function.Negate
+		if e.Op == planner.BitNotExpr {
+			op = function.BitNot
+		}
+		return expression{eval: func(f *frame) (function.Value, error) {
+			v, err := arg.eval(f)
+			if err != nil {
+				return nil, err
+			}
+			return op(v), nil
+		}}, nil
+	case planner.LikeExpr:
+		return x.like(e, s)
+	case planner.CaseExpr:
+		return x.caseExpr(e, s)
+	case planner.CastExpr:
+		arg, err := x.expr(e.Args[0], s)
+		if err != nil {
+			return expression{}, err
+		}
+		typeName := e.Name
+		return expression{
+			eval: func(f *frame) (function.Value, error) {
+				v, err := arg.eval(f)
+				if err != nil {
+					return nil, err
+				}
+				return function.Cast(v, typeName), nil
+			},
+			affinity: function.AffinityOf(typeName),
+			typed:    true,
+		}, nil
+	case planner.CollateExpr:
+		arg, err := x.expr(e.Args[0], s)
+		if err != nil {
+			return expression{}, err
+		}
+		if _, ok := index.CollationByName(e.Name); !ok {
+			return expression{}, fmt.Errorf("%w: %v", ErrNoSuchCollation, e.Name)
+		}
+		arg.collation, arg.explicit = e.Name, true
+		return arg, nil
+	case planner.FuncExpr:
+		return x.call(e, s)
+	case planner.SubqueryExpr, planner.ExistsExpr:
+		return x.subquery(e, s)
+	case planner.InExpr:
+		return x.in(e, s)
+	case planner.StarExpr:
+		return expression{}, fmt.Errorf("%w: %v", ErrMisplacedStar, e)
+	case planner.RaiseExpr:
+		return x.raise(e)
+	}
+	return expression{}, fmt.Errorf("%w: %v", ErrUnsupported, e.Op)
+}
+
+// exprs compiles the given expressions.
+func (x *execution) exprs(list []*planner.Expr, s *scope) ([]expression, error) {
+	result := make([]expression, len(list))
+	for i, e := range list {
+		var err error
+		if result[i], err = x.expr(e, s); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// columnExpr compiles a reference to a column. A reference to a column of an
+// outer scope makes the subquery, that is being compiled, correlated.
+func (x *execution) columnExpr(ref planner.Column, s *scope) (expression, error) {
+	depth, i, err := s.resolve(ref)
+	if err != nil {
+		return expression{}, err
+	}
+	target := s
+	for d := 0; d < depth; d++ {
+		target = target.outer
+	}
+	for _, refs := range x.references {
+		refs[target] = true
+	}
+	col := target.columns[i]
+	return expression{
+		eval: func(f *frame) (function.Value, error) {
+			return f.at(depth).row[i], nil
+		},
+		affinity:  col.affinity,
+		typed:     col.typed,
+		collation: col.collation,
+	}, nil
+}
+
+// comparison compiles a comparison. Like in SQLite, if one operand is a
+// column with a numeric affinity, the other operand is converted to a
+// number, if it is TEXT that looks like a number. If one operand is a column
+// with TEXT affinity, and the other one has no affinity, the other operand is
+// converted to TEXT. The collation of the comparison is the explicit
+// collation of the left or right operand, or else the collation of the left
+// or right column.
+func (x *execution) comparison(e *planner.Expr, s *scope) (expression, error) {
+	args, err := x.exprs(e.Args, s)
+	if err != nil {
+		return expression{}, err
+	}
+	l, r := args[0], args[1]
+	lconv, rconv := comparisonAffinity(l, r)
+	coll := collationOf(l, r)
+	op := e.Op
+	return expression{eval: func(f *frame) (function.Value, error) {
+		a, err := l.eval(f)
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.eval(f)
+		if err != nil {
+			return nil, err
+		}
+		a, b = lconv(a), rconv(b)
+		switch op {
+		case planner.IsExpr, planner.IsNotExpr:
+			equal := a == nil && b == nil || a != nil && b != nil && compare(a, b, coll) == 0
+			return boolValue(equal == (op == planner.IsExpr)), nil
+		}
+		if a == nil || b == nil {
+			return nil, nil
+		}
+		c := compare(a, b, coll)
+		switch op {
+		case planner.EqExpr:
+			return boolValue(c == 0), nil
+		case planner.NeExpr:
+			return boolValue(c != 0), nil
+		case planner.LtExpr:
+			return boolValue(c < 0), nil
+		case planner.LeExpr:
+			return boolValue(c <= 0), nil
+		case planner.GtExpr:
+			return boolValue(c > 0), nil
+		}
+		return boolValue(c >= 0), nil
+	}}, nil
+}
+
+func identity(v function.Value) function.Value { return v }
+
+func numeric(e expression) bool {
+	return e.typed && (e.affinity == function.Integer || e.affinity == function.Real || e.affinity == function.Numeric)
+}
+
+// comparisonAffinity returns the conversions of the left and right operand
+// of a comparison.
+func comparisonAffinity(l, r expression) (func(function.Value) function.Value, func(function.Value) function.Value) {
+	lconv, rconv := identity, identity
+	lText := l.typed && l.affinity == function.Text
+	rText := r.typed && r.affinity == function.Text
+	switch {
+	case numeric(l) && !numeric(r):
+		rconv = function.Numeric.Apply
+	case numeric(r) && !numeric(l):
+		lconv = function.Numeric.Apply
+	case lText && !r.typed:
+		rconv = function.Text.Apply
+	case rText && !l.typed:
+		lconv = function.Text.Apply
+	}
+	return lconv, rconv
+}
+
+// collationOf returns the collation of a comparison of the given operands.
+func collationOf(l, r expression) index.Collation {
+	if coll, ok := index.CollationByName(collationName(l, r)); ok {
+		return coll
+	}
+	return index.Binary
+}
+
+// collationName returns the name of the collation of a comparison of the
+// given operands, or an empty name, if neither operand has a collation.
+func collationName(l, r expression) string {
+	switch {
+	case l.explicit:
+		return l.collation
+	case r.explicit:
+		return r.collation
+	case l.collation != "":
+		return l.collation
+	}
+	return r.collation
+}
+
+// compare compares two values, that are not NULL, with the sort order of
+// SQLite. TEXT values are compared with the given collation.
+func compare(a, b function.Value, coll index.Collation) int {
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok && coll != nil {
+			return coll.Compare([]byte(as), []byte(bs))
+		}
+	}
+	return function.Compare(a, b)
+}
+
+// logical compiles AND and OR, which evaluate their arguments with three
+// valued logic, and only as far as necessary.
+func (x *execution) logical(e *planner.Expr, s *scope) (expression, error) {
+	args, err := x.exprs(e.Args, s)
+	if err != nil {
+		return expression{}, err
+	}
+	// AND stops at the first false argument, OR at the first true one
+	stop := e.Op == planner.OrExpr
+	return expression{eval: func(f *frame) (function.Value, error) {
+		null := false
+		for _, arg := range args {
+			v, err := arg.eval(f)
+			if err != nil {
+				return nil, err
+			}
+			truth, ok := function.Truth(v)
+			switch {
+			case !ok:
+				null = true
+			case truth == stop:
+				return boolValue(stop), nil
+			}
+		}
+		if null {
+			return nil, nil
+		}
+		return boolValue(!stop), nil
+	}}, nil
+}
+
+// binaryOps are the operators of binary expressions, that compute values.
+var binaryOps = map[planner.ExprOp]func(a, b function.Value) function.Value{
+	planner.AddExpr:        function.Add,
+	planner.SubExpr:        function.Subtract,
+	planner.MulExpr:        function.Multiply,
+	planner.DivExpr:        function.Divide,
+	planner.ModExpr:        function.Remainder,
+	planner.ConcatExpr:     function.Concat,
+	planner.BitAndExpr:     function.BitAnd,
+	planner.BitOrExpr:      function.BitOr,
+	planner.ShiftLeftExpr:  function.ShiftLeft,
+	planner.ShiftRightExpr: function.ShiftRight,
+}
+
+func (x *execution) binary(e *planner.Expr, s *scope) (expression, error) {
+	args, err := x.exprs(e.Args, s)
+	if err != nil {
+		return expression{}, err
+	}
+	op := binaryOps[e.Op]
+	return expression{eval: func(f *frame) (function.Value, error) {
+		a, err := args[0].eval(f)
+		if err != nil {
+			return nil, err
+		}
+		b, err := args[1].eval(f)
+		if err != nil {
+			return nil, err
+		}
+		return op(a, b), nil
+	}}, nil
+}
+
+// like compiles LIKE and GLOB. REGEXP and MATCH need functions, that are not
+// built in.
+func (x *execution) like(e *planner.Expr, s *scope) (expression, error) {
+	name := strings.ToLower(e.Name)
+	if name != "like" && name != "glob" {
+		return expression{}, fmt.Errorf("%w: %v", function.ErrNoSuchFunction, name)
+	}
+	args, err := x.exprs(e.Args, s)
+	if err != nil {
+		return expression{}, err
+	}
+	f, err := functions.Scalar(name, len(args))
+	if err != nil {
+		return expression{}, err
+	}
+	return expression{eval: func(fr *frame) (function.Value, error) {
+		values := make([]function.Value, len(args))
+		for i, arg := range args {
+			v, err := arg.eval(fr)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		// like(pattern, value) has the operands of LIKE swapped
+		values[0], values[1] = values[1], values[0]
+		return f.Call(values...)
+	}}, nil
+}
+
+func (x *execution) caseExpr(e *planner.Expr, s *scope) (expression, error) {
+	args, err := x.exprs(e.Args, s)
+	if err != nil {
+		return expression{}, err
+	}
+	return expression{eval: func(f *frame) (function.Value, error) {
+		i := 0
+		for ; i+1 < len(args); i += 2 {
+			v, err := args[i].eval(f)
+			if err != nil {
+				return nil, err
+			}
+			if truth, _ := function.Truth(v); truth {
+				return args[i+1].eval(f)
+			}
+		}
+		if i < len(args) {
+			return args[i].eval(f)
+		}
+		return nil, nil
+	}}, nil
+}
+
+// call compiles a call of a function. The result of an aggregate function is
+// a column of the scope, that the Aggregate below the expression computes.
+func (x *execution) call(e *planner.Expr, s *scope) (expression, error) {
+	if functions.IsAggregate(e.Name, len(e.Args)) {
+		key := e.String()
+		for i, col := range s.columns {
+			if col.aggregate == key {
+				return expression{eval: func(f *frame) (function.Value, error) {
+					return f.row[i], nil
+				}}, nil
+			}
+		}
+		return expression{}, fmt.Errorf("%w: %v", ErrMisuseAggregate, key)
+	}
+	if e.Distinct {
+		return expression{}, fmt.Errorf("%w: DISTINCT in %v", ErrMisuseAggregate, e)
+	}
+	f, err := functions.Scalar(e.Name, len(e.Args))
+	if err != nil {
+		return expression{}, fmt.Errorf("%w: %v", err, e.Name)
+	}
+	args, err := x.exprs(e.Args, s)
+	if err != nil {
+		return expression{}, err
+	}
+	return expression{eval: func(fr *frame) (function.Value, error) {
+		values := make([]function.Value, len(args))
+		for i, arg := range args {
+			v, err := arg.eval(fr)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return f.Call(values...)
+	}}, nil
+}
+
+// subquery compiles a scalar subquery or EXISTS. A subquery, that doesn't
+// refer to the columns of the query, is only run once.
+func (x *execution) subquery(e *planner.Expr, s *scope) (expression, error) {
+	rows, err := x.subqueryRows(e.Subquery, s, e.Op == planner.SubqueryExpr)
+	if err != nil {
+		return expression{}, err
+	}
+	exists := e.Op == planner.ExistsExpr
+	return expression{eval: func(f *frame) (function.Value, error) {
+		result, err := rows(f)
+		switch {
+		case err != nil:
+			return nil, err
+		case exists:
+			return boolValue(len(result) > 0), nil
+		case len(result) == 0:
+			return nil, nil
+		}
+		return result[0][0], nil
+	}}, nil
+}
+
+// subqueryRows compiles a subquery, and returns a function, that returns its
+// rows. Only the first row of a scalar subquery is computed. The subquery of
+// a scalar subquery and of IN must have a single column.
+func (x *execution) subqueryRows(plan *planner.Node, s *scope, scalar bool) (func(f *frame) ([][]function.Value, error), error) {
+	if x.e == nil {
+		return nil, ErrSubqueryInDefinition
+	}
+	refs := make(map[*scope]bool)
+	x.references = append(x.references, refs)
+	op, err := x.node(plan, s)
+	x.references = x.references[:len(x.references)-1]
+	if err != nil {
+		return nil, err
+	}
+	if scalar && len(visible(op.columns())) != 1 {
+		return nil, fmt.Errorf("%w: %d", ErrSubqueryColumns, len(visible(op.columns())))
+	}
+
+	correlated := false
+	for sc := s; sc != nil; sc = sc.outer {
+		correlated = correlated || refs[sc]
+	}
+	for _, outer := range x.references {
+		for sc := range refs {
+			outer[sc] = true
+		}
+	}
+
+	var cached [][]function.Value
+	done := false
+	return func(f *frame) ([][]function.Value, error) {
+		if done {
+			return cached, nil
+		}
+		var rows [][]function.Value
+		stop := &stopError{}
+		err := op.run(f, func(row []function.Value) error {
+			rows = append(rows, row)
+			if scalar {
+				return stop
+			}
+			return nil
+		})
+		if err != nil && err != error(stop) {
+			return nil, err
+		}
+		if !correlated {
+			cached, done = rows, true
+		}
+		return rows, nil
+	}, nil
+}
+
+// in compiles IN with a list of values or a subquery. Like in SQLite, the
+// affinity of the left operand is applied to the values, and the result is
+// NULL if no value matches and any value is NULL.
+func (x *execution) in(e *planner.Expr, s *scope) (expression, error) {
+	value, err := x.expr(e.Args[0], s)
+	if err != nil {
+		return expression{}, err
+	}
+	conv := identity
+	if numeric(value) {
+		conv = function.Numeric.Apply
+	} else if value.typed && value.affinity == function.Text {
+		conv = function.Text.Apply
+	}
+	coll := collationOf(value, expression{})
+
+	var list func(f *frame) ([]function.Value, error)
+	if e.Subquery != nil {
+		rows, err := x.subqueryRows(e.Subquery, s, true)
+		if err != nil {
+			return expression{}, err
+		}
+		list = func(f *frame) ([]function.Value, error) {
+			result, err := rows(f)
+			if err != nil {
+				return nil, err
+			}
+			values := make([]function.Value, len(result))
+			for i, row := range result {
+				values[i] = row[0]
+			}
+			return values, nil
+		}
+	} else {
+		args, err := x.exprs(e.Args[1:], s)
+		if err != nil {
+			return expression{}, err
+		}
+		list = func(f *frame) ([]function.Value, error) {
+			values := make([]function.Value, len(args))
+			for i, arg := range args {
+				v, err := arg.eval(f)
+				if err != nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			return values, nil
+		}
+	}
+
+	return expression{eval: func(f *frame) (function.Value, error) {
+		v, err := value.eval(f)
+		if err != nil {
+			return nil, err
+		}
+		values, err := list(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return boolValue(false), nil
+		}
+		if v == nil {
+			return nil, nil
+		}
+		null := false
+		for _, candidate := range values {
+			candidate = conv(candidate)
+			if candidate == nil {
+				null = true
+				continue
+			}
+			if compare(v, candidate, coll) == 0 {
+				return boolValue(true), nil
+			}
+		}
+		if null {
+			return nil, nil
+		}
+		return boolValue(false), nil
+	}}, nil
+}
+
+func boolValue(b bool) function.Value {
+	if b {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+// stopError is returned by the function, that an operator emits its rows
+// to, to stop the operator early, like a LIMIT does. Every user has its own
+// instance, so that it knows that it stopped the operator itself.
+type stopError struct{}
+
+func (*stopError) Error() string { return "stopped" }
@@ -0,0 +1,187 @@
+package function
+
+import "strings"
+
+// Aggregate is an aggregate function that is computed over the rows of a
+// group. A FILTER clause is applied by only stepping the rows that satisfy
+// it.
+type Aggregate interface {
+	// Step adds the arguments of a row to the aggregate.
+	Step(args ...Value) error
+	// Final returns the result of the aggregate over all stepped rows. It
+	// may be called more than once, with rows stepped in between, which
+	// window functions do for frames that grow from row to row.
+	Final() (Value, error)
+}
+
+// builtinAggregates are the aggregate functions of a new registry.
+var builtinAggregates = map[string]AggregateFunction{
+	"count":        {MinArgs: 0, MaxArgs: 1, Deterministic: true, New: func() Aggregate { return &count{} }},
+	"sum":          {MinArgs: 1, MaxArgs: 1, Deterministic: true, New: func() Aggregate { return &sum{} }},
+	"total":        {MinArgs: 1, MaxArgs: 1, Deterministic: true, New: func() Aggregate { return &total{} }},
+	"avg":          {MinArgs: 1, MaxArgs: 1, Deterministic: true, New: func() Aggregate { return &avg{} }},
+	"min":          {MinArgs: 1, MaxArgs: 1, Deterministic: true, New: func() Aggregate { return &extremum{sign: -1} }},
+	"max":          {MinArgs: 1, MaxArgs: 1, Deterministic: true, New: func() Aggregate { return &extremum{sign: 1} }},
+	"group_concat": {MinArgs: 1, MaxArgs: 2, Deterministic: true, New: func() Aggregate { return &groupConcat{} }},
+}
+
+// distinctAggregate only steps the wrapped aggregate for rows, whose first
+// argument has not been seen yet.
+type distinctAggregate struct {
+	Aggregate
+	seen map[string]struct{}
+}
+
+func (a *distinctAggregate) Step(args ...Value) error {
+	if len(args) == 0 {
+		return ErrArgumentCount
+	}
+	k := key(args[0])
+	if _, ok := a.seen[k]; ok {
+		return nil
+	}
+	a.seen[k] = struct{}{}
+	return a.Aggregate.Step(args...)
+}
+
+// count implements count(*) if stepped without arguments, and count(x)
+// otherwise.
+type count struct {
+	n int64
+}
+
+func (a *count) Step(args ...Value) error {
+	if len(args) == 0 || args[0] != nil {
+		a.n++
+	}
+	return nil
+}
+
+func (a *count) Final() (Value, error) { return a.n, nil }
+
+// sum implements sum(x). The result is an INTEGER, as long as all non-NULL
+// arguments are integers, and NULL if there are none.
+type sum struct {
+	seen    bool
+	isFloat bool
+	i       int64
+	f       float64
+}
+
+func (a *sum) Step(args ...Value) error {
+	if args[0] == nil {
+		return nil
+	}
+	a.seen = true
+	switch n := toNumber(args[0]).(type) {
+	case int64:
+		a.f += float64(n)
+		if !a.isFloat {
+			r := a.i + n
+			if (r > a.i) != (n > 0) {
+				return ErrIntegerOverflow
+			}
+			a.i = r
+		}
+	case float64:
+		a.f += n
+		a.isFloat = true
+	}
+	return nil
+}
+
+func (a *sum) Final() (Value, error) {
+	switch {
+	case !a.seen:
+		return nil, nil
+	case a.isFloat:
+		return a.f, nil
+	}
+	return a.i, nil
+}
+
+// total implements total(x), which is like sum(x), but always returns a REAL,
+// and 0.0 instead of NULL.
+type total struct {
+	f float64
+}
+
+func (a *total) Step(args ...Value) error {
+	if args[0] != nil {
+		a.f += toFloat(args[0])
+	}
+	return nil
+}
+
+func (a *total) Final() (Value, error) { return a.f, nil }
+
+// avg implements avg(x), which is the average of all non-NULL arguments as
+// a REAL, or NULL if there are none.
+type avg struct {
+	n int64
+	f float64
+}
+
+func (a *avg) Step(args ...Value) error {
+	if args[0] != nil {
+		a.n++
+		a.f += toFloat(args[0])
+	}
+	return nil
+}
+
+func (a *avg) Final() (Value, error) {
+	if a.n == 0 {
+		return nil, nil
+	}
+	return a.f / float64(a.n), nil
+}
+
+// extremum implements min(x) with sign -1 and max(x) with sign 1. NULL
+// arguments are ignored.
+type extremum struct {
+	sign int
+	v    Value
+}
+
+func (a *extremum) Step(args ...Value) error {
+	if args[0] == nil {
+		return nil
+	}
+	if a.v == nil || Compare(args[0], a.v)*a.sign > 0 {
+		a.v = args[0]
+	}
+	return nil
+}
+
+func (a *extremum) Final() (Value, error) { return a.v, nil }
+
+// groupConcat implements group_concat(x[, separator]). NULL arguments are
+// skipped, and the separator defaults to a comma.
+type groupConcat struct {
+	seen bool
+	sb   strings.Builder
+}
+
+func (a *groupConcat) Step(args ...Value) error {
+	if args[0] == nil {
+		return nil
+	}
+	if a.seen {
+		sep := ","
+		if len(args) > 1 {
+			sep = toText(args[1])
+		}
+		a.sb.WriteString(sep)
+	}
+	a.seen = true
+	a.sb.WriteString(toText(args[0]))
+	return nil
+}
+
+func (a *groupConcat) Final() (Value, error) {
+	if !a.seen {
+		return nil, nil
+	}
+	return a.sb.String(), nil
+}
@@ -0,0 +1,75 @@
+package function
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct bool
+		args     [][]Value
+		want     Value
+	}{
+		{"count", false, [][]Value{{}, {}, {}}, int64(3)},
+		{"COUNT", false, [][]Value{{int64(1)}, {nil}, {"a"}}, int64(2)},
+		{"count", true, [][]Value{{int64(1)}, {1.0}, {nil}, {"1"}}, int64(2)},
+		{"count", false, nil, int64(0)},
+		{"sum", false, [][]Value{{int64(1)}, {nil}, {int64(2)}}, int64(3)},
+		{"sum", false, [][]Value{{int64(1)}, {0.5}}, 1.5},
+		{"sum", false, [][]Value{{"3"}, {int64(4)}}, int64(7)},
+		{"sum", false, [][]Value{{nil}}, nil},
+		{"sum", true, [][]Value{{int64(2)}, {int64(2)}, {int64(3)}}, int64(5)},
+		{"total", false, [][]Value{{int64(1)}, {int64(2)}}, 3.0},
+		{"total", false, nil, 0.0},
+		{"avg", false, [][]Value{{int64(1)}, {int64(2)}, {nil}}, 1.5},
+		{"avg", false, nil, nil},
+		{"min", false, [][]Value{{int64(3)}, {nil}, {1.5}, {"a"}}, 1.5},
+		{"max", false, [][]Value{{int64(3)}, {nil}, {1.5}, {"a"}}, "a"},
+		{"max", false, [][]Value{{nil}}, nil},
+		{"group_concat", false, [][]Value{{"a"}, {nil}, {int64(1)}, {2.0}}, "a,1,2.0"},
+		{"group_concat", false, [][]Value{{"a", "-"}, {"b", "-"}}, "a-b"},
+		{"group_concat", true, [][]Value{{"a"}, {"b"}, {"a"}}, "a,b"},
+		{"group_concat", false, nil, nil},
+	}
+	for _, tt := range tests {
+		args := 1
+		if len(tt.args) > 0 {
+			args = len(tt.args[0])
+		}
+		agg, err := NewRegistry().NewAggregate(tt.name, args, tt.distinct)
+		require.NoError(t, err, tt.name)
+		for _, row := range tt.args {
+			require.NoError(t, agg.Step(row...), tt.name)
+		}
+		got, err := agg.Final()
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, "%s(%v)", tt.name, tt.args)
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewRegistry().NewAggregate("median", 1, false)
+	assert.Equal(ErrNoSuchFunction, err)
+	_, err = NewRegistry().NewAggregate("sum", 2, false)
+	assert.Equal(ErrArgumentCount, err)
+	_, err = NewRegistry().NewAggregate("count", 0, true)
+	assert.Equal(ErrArgumentCount, err)
+
+	agg, err := NewRegistry().NewAggregate("sum", 1, false)
+	assert.NoError(err)
+	assert.NoError(agg.Step(int64(math.MaxInt64)))
+	assert.True(errors.Is(agg.Step(int64(1)), ErrIntegerOverflow))
+
+	agg, err = NewRegistry().NewAggregate("sum", 1, false)
+	assert.NoError(err)
+	assert.NoError(agg.Step(float64(math.MaxInt64)))
+	assert.NoError(agg.Step(int64(1)))
+}
@@ -0,0 +1,101 @@
+package function
+
+import "math/rand"
+
+// coreFunctions are the core scalar functions of SQLite, that are not string,
+// math, date and time or JSON functions.
+var coreFunctions = map[string]Function{
+	"coalesce":   {MinArgs: 2, MaxArgs: -1, Deterministic: true, Call: coalesce},
+	"ifnull":     {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: coalesce},
+	"nullif":     {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: nullIf},
+	"iif":        {MinArgs: 3, MaxArgs: 3, Deterministic: true, Call: iif},
+	"typeof":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: typeOf},
+	"min":        {MinArgs: 2, MaxArgs: -1, Deterministic: true, Call: scalarExtremum(-1)},
+	"max":        {MinArgs: 2, MaxArgs: -1, Deterministic: true, Call: scalarExtremum(1)},
+	"random":     {MinArgs: 0, MaxArgs: 0, Call: random},
+	"randomblob": {MinArgs: 1, MaxArgs: 1, Call: randomBlob},
+}
+
+// coalesce returns its first argument that is not NULL, or NULL if all
+// arguments are NULL.
+func coalesce(args ...Value) (Value, error) {
+	for _, arg := range args {
+		if arg != nil {
+			return arg, nil
+		}
+	}
+	return nil, nil
+}
+
+// nullIf returns its first argument, or NULL if both arguments are equal.
+func nullIf(args ...Value) (Value, error) {
+	if Compare(args[0], args[1]) == 0 {
+		return nil, nil
+	}
+	return args[0], nil
+}
+
+// iif returns its second argument if the first one is true, and its third
+// argument otherwise.
+func iif(args ...Value) (Value, error) {
+	if truthy(args[0]) {
+		return args[1], nil
+	}
+	return args[2], nil
+}
+
+// truthy reports whether the given value is true in a boolean context, which
+// is the case if it is a non-zero number.
+func truthy(v Value) bool {
+	if v == nil {
+		return false
+	}
+	return toFloat(v) != 0
+}
+
+func typeOf(args ...Value) (Value, error) {
+	switch args[0].(type) {
+	case nil:
+		return "null", nil
+	case int64:
+		return "integer", nil
+	case float64:
+		return "real", nil
+	case string:
+		return "text", nil
+	}
+	return "blob", nil
+}
+
+// scalarExtremum returns the implementation of the scalar min function with
+// sign -1, and of the scalar max function with sign 1. Unlike the aggregates,
+// they return NULL if any argument is NULL.
+func scalarExtremum(sign int) func(args ...Value) (Value, error) {
+	return func(args ...Value) (Value, error) {
+		result := args[0]
+		for _, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+			if Compare(arg, result)*sign > 0 {
+				result = arg
+			}
+		}
+		return result, nil
+	}
+}
+
+func random(...Value) (Value, error) {
+	return int64(rand.Uint64()), nil
+}
+
+// randomBlob returns a BLOB of N random bytes. N is at least 1.
+func randomBlob(args ...Value) (Value, error) {
+	n := toInt(args[0])
+	if n < 1 {
+		n = 1
+	}
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b, nil
+}
@@ -0,0 +1,291 @@
+package function
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// unixEpochJulianDay is the julian day number of 1970-01-01 00:00:00.
+	unixEpochJulianDay = 2440587.5
+	msPerDay           = 86400000
+	// maxJulianDay is the julian day number of 9999-12-31 23:59:59.999.
+	maxJulianDay = 5373484.499999
+)
+
+var (
+	dateTimeRegexp = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})(?:[T ](\d{2}):(\d{2})(?::(\d{2})(\.\d+)?)?)?\s*(Z|[+-]\d{2}:\d{2})?$`)
+	timeRegexp     = regexp.MustCompile(`^(\d{2}):(\d{2})(?::(\d{2})(\.\d+)?)?\s*(Z|[+-]\d{2}:\d{2})?$`)
+	offsetRegexp   = regexp.MustCompile(`^([+-]?(?:\d+(?:\.\d*)?|\.\d+))\s+(day|hour|minute|second|month|year)s?$`)
+)
+
+// dateTimeFunctions returns the date and time functions of SQLite. They
+// accept a time value, followed by modifiers. A time value is either TEXT
+// in one of the formats YYYY-MM-DD, YYYY-MM-DD HH:MM[:SS[.SSS]] or
+// HH:MM[:SS[.SSS]] with an optional time zone, 'now', or a julian day
+// number. Without arguments, they use the current time. The functions are
+// not deterministic, because of 'now' and 'localtime'.
+func (r *Registry) dateTimeFunctions() map[string]Function {
+	format := func(layout string) func(args ...Value) (Value, error) {
+		return func(args ...Value) (Value, error) {
+			return r.strftime(append([]Value{layout}, args...)...)
+		}
+	}
+	return map[string]Function{
+		"date":     {MinArgs: 0, MaxArgs: -1, Call: format("%Y-%m-%d")},
+		"time":     {MinArgs: 0, MaxArgs: -1, Call: format("%H:%M:%S")},
+		"datetime": {MinArgs: 0, MaxArgs: -1, Call: format("%Y-%m-%d %H:%M:%S")},
+		"julianday": {MinArgs: 0, MaxArgs: -1, Call: func(args ...Value) (Value, error) {
+			t, ok := r.parseTime(args)
+			if !ok {
+				return nil, nil
+			}
+			return julianDay(t), nil
+		}},
+		"unixepoch": {MinArgs: 0, MaxArgs: -1, Call: func(args ...Value) (Value, error) {
+			t, ok := r.parseTime(args)
+			if !ok {
+				return nil, nil
+			}
+			return t.Unix(), nil
+		}},
+		"strftime": {MinArgs: 1, MaxArgs: -1, Call: r.strftime},
+	}
+}
+
+func julianDay(t time.Time) float64 {
+	return float64(t.UnixMilli())/msPerDay + unixEpochJulianDay
+}
+
+func fromJulianDay(jd float64) (time.Time, bool) {
+	if jd < 0 || jd > maxJulianDay {
+		return time.Time{}, false
+	}
+	ms := int64(math.Round((jd - unixEpochJulianDay) * msPerDay))
+	return time.UnixMilli(ms).UTC(), true
+}
+
+// parseTime parses the time value and applies the modifiers in the given
+// arguments. The result is in UTC, unless the localtime modifier is used, in
+// which case the result is the wall clock in UTC.
+func (r *Registry) parseTime(args []Value) (time.Time, bool) {
+	if len(args) == 0 {
+		return r.now().UTC().Round(time.Millisecond), true
+	}
+	for _, arg := range args {
+		if arg == nil {
+			return time.Time{}, false
+		}
+	}
+
+	var t time.Time
+	var ok bool
+	var f float64
+	isNumber := true
+	switch v := args[0].(type) {
+	case int64:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
+		isNumber = false
+		s := strings.TrimSpace(toText(v))
+		if n, isNum := parseWholeNumber(s); isNum {
+			f, isNumber = toFloat(n), true
+		} else if strings.EqualFold(s, "now") {
+			t, ok = r.now().UTC().Round(time.Millisecond), true
+		} else {
+			t, ok = parseTimeText(s)
+		}
+	}
+	modifiers := args[1:]
+	if isNumber {
+		if len(modifiers) > 0 && strings.EqualFold(strings.TrimSpace(toText(modifiers[0])), "unixepoch") {
+			modifiers = modifiers[1:]
+			t, ok = time.UnixMilli(int64(math.Round(f*1000))).UTC(), true
+		} else {
+			t, ok = fromJulianDay(f)
+		}
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+
+	for _, m := range modifiers {
+		if t, ok = applyModifier(t, strings.ToLower(strings.TrimSpace(toText(m)))); !ok {
+			return time.Time{}, false
+		}
+	}
+	if t.Year() < 0 || t.Year() > 9999 {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func parseTimeText(s string) (time.Time, bool) {
+	var date, clock []string
+	var zone string
+	if m := dateTimeRegexp.FindStringSubmatch(s); m != nil {
+		date, clock, zone = m[1:4], m[4:8], m[8]
+	} else if m := timeRegexp.FindStringSubmatch(s); m != nil {
+		date, clock, zone = []string{"2000", "01", "01"}, m[1:5], m[5]
+	} else {
+		return time.Time{}, false
+	}
+
+	atoi := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	year, month, day := atoi(date[0]), atoi(date[1]), atoi(date[2])
+	hour, minute, second := atoi(clock[0]), atoi(clock[1]), atoi(clock[2])
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 24 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+	var fraction float64
+	if clock[3] != "" {
+		fraction, _ = strconv.ParseFloat(clock[3], 64)
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, second, int(math.Round(fraction*1000))*int(time.Millisecond), time.UTC)
+
+	if zone != "" && zone != "Z" {
+		offset := time.Duration(atoi(zone[1:3]))*time.Hour + time.Duration(atoi(zone[4:6]))*time.Minute
+		if zone[0] == '+' {
+			offset = -offset
+		}
+		t = t.Add(offset)
+	}
+	return t, true
+}
+
+// applyModifier applies a date and time modifier to the given time.
+func applyModifier(t time.Time, m string) (time.Time, bool) {
+	switch m {
+	case "start of day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), true
+	case "start of month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), true
+	case "start of year":
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), true
+	case "localtime":
+		l := t.In(time.Local)
+		return time.Date(l.Year(), l.Month(), l.Day(), l.Hour(), l.Minute(), l.Second(), l.Nanosecond(), time.UTC), true
+	case "utc":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local).UTC(), true
+	}
+
+	if strings.HasPrefix(m, "weekday ") {
+		n, err := strconv.Atoi(strings.TrimSpace(m[len("weekday "):]))
+		if err != nil || n < 0 || n > 6 {
+			return time.Time{}, false
+		}
+		days := (n - int(t.Weekday()) + 7) % 7
+		return t.AddDate(0, 0, days), true
+	}
+
+	match := offsetRegexp.FindStringSubmatch(m)
+	if match == nil {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	whole := math.Trunc(n)
+	switch match[2] {
+	case "day":
+		return t.Add(time.Duration(math.Round(n * 24 * float64(time.Hour)))), true
+	case "hour":
+		return t.Add(time.Duration(math.Round(n * float64(time.Hour)))), true
+	case "minute":
+		return t.Add(time.Duration(math.Round(n * float64(time.Minute)))), true
+	case "second":
+		return t.Add(time.Duration(math.Round(n * float64(time.Second)))), true
+	case "month":
+		t = t.AddDate(0, int(whole), 0)
+		return t.Add(time.Duration(math.Round((n - whole) * 30 * 24 * float64(time.Hour)))), true
+	}
+	t = t.AddDate(int(whole), 0, 0)
+	return t.Add(time.Duration(math.Round((n - whole) * 365 * 24 * float64(time.Hour)))), true
+}
+
+// strftime implements strftime(FORMAT, TIME-VALUE, MODIFIER...), which
+// formats the time value with the following conversions.
+//
+//	%d  day of month: 01-31
+//	%f  fractional seconds: SS.SSS
+//	%F  ISO 8601 date: YYYY-MM-DD
+//	%H  hour: 00-24
+//	%j  day of year: 001-366
+//	%J  julian day number
+//	%m  month: 01-12
+//	%M  minute: 00-59
+//	%s  seconds since 1970-01-01
+//	%S  seconds: 00-59
+//	%T  ISO 8601 time: HH:MM:SS
+//	%w  day of week 0-6 with Sunday==0
+//	%W  week of year: 00-53, where the first week starts on the first Monday
+//	%Y  year: 0000-9999
+//	%%  %
+func (r *Registry) strftime(args ...Value) (Value, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	t, ok := r.parseTime(args[1:])
+	if !ok {
+		return nil, nil
+	}
+
+	format := toText(args[0])
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			sb.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i == len(format) {
+			return nil, nil
+		}
+		switch format[i] {
+		case 'd':
+			fmt.Fprintf(&sb, "%02d", t.Day())
+		case 'f':
+			fmt.Fprintf(&sb, "%06.3f", float64(t.Second())+float64(t.Nanosecond()/int(time.Millisecond))/1000)
+		case 'F':
+			fmt.Fprintf(&sb, "%04d-%02d-%02d", t.Year(), t.Month(), t.Day())
+		case 'H':
+			fmt.Fprintf(&sb, "%02d", t.Hour())
+		case 'j':
+			fmt.Fprintf(&sb, "%03d", t.YearDay())
+		case 'J':
+			sb.WriteString(strconv.FormatFloat(julianDay(t), 'g', 16, 64))
+		case 'm':
+			fmt.Fprintf(&sb, "%02d", t.Month())
+		case 'M':
+			fmt.Fprintf(&sb, "%02d", t.Minute())
+		case 's':
+			sb.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			fmt.Fprintf(&sb, "%02d", t.Second())
+		case 'T':
+			fmt.Fprintf(&sb, "%02d:%02d:%02d", t.Hour(), t.Minute(), t.Second())
+		case 'w':
+			sb.WriteString(strconv.Itoa(int(t.Weekday())))
+		case 'W':
+			monday := (int(t.Weekday()) + 6) % 7
+			fmt.Fprintf(&sb, "%02d", (t.YearDay()-1+7-monday)/7)
+		case 'Y':
+			fmt.Fprintf(&sb, "%04d", t.Year())
+		case '%':
+			sb.WriteByte('%')
+		default:
+			return nil, nil
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,57 @@
+package function
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateTimeFunctions(t *testing.T) {
+	r := NewRegistry()
+	r.now = func() time.Time { return time.Date(2021, time.June, 1, 8, 30, 0, 0, time.UTC) }
+
+	runCallTests(t, r, []callTest{
+		{"date", nil, "2021-06-01"},
+		{"datetime", []Value{"now"}, "2021-06-01 08:30:00"},
+		{"date", []Value{"2024-03-15 13:45:30"}, "2024-03-15"},
+		{"time", []Value{"2024-03-15 13:45:30.123"}, "13:45:30"},
+		{"time", []Value{"12:30"}, "12:30:00"},
+		{"datetime", []Value{"2024-03-15T13:45:30Z"}, "2024-03-15 13:45:30"},
+		{"datetime", []Value{"2024-03-15 13:45:30+02:00"}, "2024-03-15 11:45:30"},
+		{"date", []Value{"2024-01-31", "+1 month"}, "2024-03-02"},
+		{"date", []Value{"2024-03-15", "+1 year"}, "2025-03-15"},
+		{"date", []Value{"2024-03-15", "start of month"}, "2024-03-01"},
+		{"datetime", []Value{"2024-03-15 10:11", "start of year"}, "2024-01-01 00:00:00"},
+		{"datetime", []Value{"2024-03-15 10:11", "start of day"}, "2024-03-15 00:00:00"},
+		{"datetime", []Value{"2024-03-15 10:00", "+1.5 days"}, "2024-03-16 22:00:00"},
+		{"datetime", []Value{"2024-03-15 10:00", "-90 minutes", "+30 seconds"}, "2024-03-15 08:30:30"},
+		{"datetime", []Value{"2024-03-15 10:00", "+2 HOURS"}, "2024-03-15 12:00:00"},
+		{"date", []Value{"2024-03-15", "weekday 0"}, "2024-03-17"},
+		{"date", []Value{"2024-03-15", "weekday 5"}, "2024-03-15"},
+		{"julianday", []Value{"2000-01-01 12:00:00"}, 2451545.0},
+		{"date", []Value{2451545.0}, "2000-01-01"},
+		{"date", []Value{"2451545"}, "2000-01-01"},
+		{"datetime", []Value{int64(1700000000), "unixepoch"}, "2023-11-14 22:13:20"},
+		{"unixepoch", []Value{"1970-01-02"}, int64(86400)},
+		{"strftime", []Value{"%Y %j %W %w %s", "2024-03-15"}, "2024 075 11 5 1710460800"},
+		{"strftime", []Value{"%F %T %H:%M %d/%m", "2024-03-15 01:02:03"}, "2024-03-15 01:02:03 01:02 15/03"},
+		{"strftime", []Value{"%f", "12:00:01.5"}, "01.500"},
+		{"strftime", []Value{"%J", "2000-01-01 12:00"}, "2451545"},
+		{"strftime", []Value{"100%%", "2000-01-01"}, "100%"},
+		{"date", []Value{"0000-01-01", "-1 day"}, nil},
+		{"date", []Value{"garbage"}, nil},
+		{"date", []Value{"2024-13-01"}, nil},
+		{"date", []Value{"2024-03-15", "bogus"}, nil},
+		{"date", []Value{"2024-03-15", "unixepoch"}, nil},
+		{"date", []Value{nil}, nil},
+		{"strftime", []Value{"%Q", "2024-03-15"}, nil},
+		{"strftime", []Value{nil, "2024-03-15"}, nil},
+	})
+
+	local, err := r.Call("datetime", "2024-03-15 10:00", "localtime")
+	assert.NoError(t, err)
+	utc, err := r.Call("datetime", local, "utc")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-15 10:00:00", utc)
+}
@@ -0,0 +1,18 @@
+// Package function implements the runtime of SQL functions, that the executor
+// calls while it evaluates a command.
+//
+// Values are represented like in database/sql/driver. A value is either nil
+// (NULL), an int64 (INTEGER), a float64 (REAL), a string (TEXT) or a []byte
+// (BLOB). Values of different types are compared and converted with the rules
+// of SQLite.
+//
+// Aggregate functions are stepped with the arguments of every row of a group,
+// and compute the result from all rows. Window functions compute a result for
+// every row of a partition, optionally from the rows in the frame of the row.
+// All aggregate functions can also be used as window functions.
+//
+// Functions are looked up by name in a Registry, which holds the built-in
+// scalar, aggregate and table-valued functions of SQLite, including the date
+// and time functions and the json1 extension. Applications that embed the
+// database can register their own functions with it.
+package function
@@ -0,0 +1,22 @@
+package function
+
+// Error provides constant errors to the function package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrNoSuchFunction  = Error("no such function")
+	ErrArgumentCount   = Error("wrong number of arguments")
+	ErrIntegerOverflow = Error("integer overflow")
+	ErrFrameOffset     = Error("frame offset must be a non-negative number")
+	ErrFrameBounds     = Error("invalid frame bounds")
+	ErrArgument        = Error("invalid argument")
+	ErrFilter          = Error("FILTER may only be used with aggregate functions")
+	ErrInvalidFunction = Error("invalid function")
+	ErrMalformedJSON   = Error("malformed JSON")
+	ErrJSONPath        = Error("bad JSON path")
+	ErrJSONBlob        = Error("JSON cannot hold BLOB values")
+	ErrEscape          = Error("ESCAPE expression must be a single character")
+)
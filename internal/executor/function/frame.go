@@ -0,0 +1,323 @@
+package function
+
+import "sort"
+
+// FrameType is the type of a window frame, which defines the unit that the
+// offsets of the frame bounds are measured in.
+type FrameType uint8
+
+// Known frame types.
+const (
+	// Range frames measure offsets as the difference between the value of
+	// the single ORDER BY term of a row and the current row. Rows with equal
+	// ORDER BY terms (peers) are always either inside or outside the frame.
+	Range FrameType = iota
+	// Rows frames measure offsets in rows.
+	Rows
+	// Groups frames measure offsets in groups of peers.
+	Groups
+)
+
+// BoundType is the type of the start or end of a window frame.
+type BoundType uint8
+
+// Known bound types.
+const (
+	UnboundedPreceding BoundType = iota
+	Preceding
+	CurrentRow
+	Following
+	UnboundedFollowing
+)
+
+// Bound is the start or end of a window frame.
+type Bound struct {
+	Type BoundType
+	// Offset is the offset of a Preceding or Following bound. It must be a
+	// non-negative INTEGER for Rows and Groups frames, and a non-negative
+	// number for Range frames.
+	Offset Value
+}
+
+// Exclude defines which rows are excluded from a window frame.
+type Exclude uint8
+
+// Known exclusions.
+const (
+	// ExcludeNoOthers doesn't exclude any rows.
+	ExcludeNoOthers Exclude = iota
+	// ExcludeCurrentRow excludes the current row.
+	ExcludeCurrentRow
+	// ExcludeGroup excludes the current row and all its peers.
+	ExcludeGroup
+	// ExcludeTies excludes all peers of the current row, but not the row
+	// itself.
+	ExcludeTies
+)
+
+// Frame is the frame specification of a window, which defines the rows that
+// aggregate functions and first_value, last_value and nth_value are computed
+// from.
+type Frame struct {
+	Type       FrameType
+	Start, End Bound
+	Exclude    Exclude
+}
+
+// DefaultFrame is the frame of a window without a frame specification, which
+// is RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW.
+var DefaultFrame = Frame{
+	Type:  Range,
+	Start: Bound{Type: UnboundedPreceding},
+	End:   Bound{Type: CurrentRow},
+}
+
+// validate checks that the frame can be used with the given number of ORDER
+// BY terms.
+func (f Frame) validate(orderBy int) error {
+	if f.Start.Type == UnboundedFollowing || f.End.Type == UnboundedPreceding {
+		return ErrFrameBounds
+	}
+	if f.Start.Type == Following && (f.End.Type == Preceding || f.End.Type == CurrentRow) ||
+		f.Start.Type == CurrentRow && f.End.Type == Preceding {
+		return ErrFrameBounds
+	}
+	for _, b := range []Bound{f.Start, f.End} {
+		if b.Type != Preceding && b.Type != Following {
+			continue
+		}
+		if f.Type == Range && orderBy != 1 {
+			return ErrFrameBounds
+		}
+		switch n := b.Offset.(type) {
+		case int64:
+			if n < 0 {
+				return ErrFrameOffset
+			}
+		case float64:
+			if f.Type != Range || n < 0 {
+				return ErrFrameOffset
+			}
+		default:
+			return ErrFrameOffset
+		}
+	}
+	return nil
+}
+
+// frames computes the frames of the rows of a partition.
+type frames struct {
+	frame Frame
+	desc  bool
+	keys  [][]Value
+	// group is the index of the peer group of every row, and groups holds
+	// the first row of every peer group, followed by the number of rows.
+	group  []int
+	groups []int
+}
+
+func newFrames(frame Frame, desc bool, keys [][]Value, rows int) *frames {
+	f := &frames{
+		frame: frame,
+		desc:  desc,
+		keys:  keys,
+		group: make([]int, rows),
+	}
+	for i := 0; i < rows; i++ {
+		if i == 0 || !f.peers(i-1, i) {
+			f.groups = append(f.groups, i)
+		}
+		f.group[i] = len(f.groups) - 1
+	}
+	f.groups = append(f.groups, rows)
+	return f
+}
+
+// peers reports whether the rows i and j have equal ORDER BY terms. Without
+// ORDER BY terms, all rows are peers.
+func (f *frames) peers(i, j int) bool {
+	if i >= len(f.keys) || j >= len(f.keys) {
+		return true
+	}
+	for k := range f.keys[i] {
+		if Compare(f.keys[i][k], f.keys[j][k]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bounds returns the first row of the frame of row i, and the first row
+// after it, without regard to exclusions. The frame is empty, if hi <= lo.
+func (f *frames) bounds(i int) (lo, hi int) {
+	return f.bound(i, f.frame.Start, true), f.bound(i, f.frame.End, false)
+}
+
+// rows returns the rows in the frame of the given row, in order.
+func (f *frames) rows(i int) []int {
+	lo, hi := f.bounds(i)
+	var result []int
+	g := f.group[i]
+	for j := lo; j < hi; j++ {
+		switch f.frame.Exclude {
+		case ExcludeCurrentRow:
+			if j == i {
+				continue
+			}
+		case ExcludeGroup:
+			if f.group[j] == g {
+				continue
+			}
+		case ExcludeTies:
+			if j != i && f.group[j] == g {
+				continue
+			}
+		}
+		result = append(result, j)
+	}
+	return result
+}
+
+// nthRow returns the n-th row of the frame of row i, counting from 0, or
+// from the end of the frame if n is negative. It reports false, if the frame
+// has too few rows. Without exclusions, the row is found without listing the
+// rows of the frame.
+func (f *frames) nthRow(i int, n int64) (int, bool) {
+	var rows []int
+	lo, hi := 0, 0
+	if f.frame.Exclude == ExcludeNoOthers {
+		lo, hi = f.bounds(i)
+	} else {
+		rows = f.rows(i)
+		hi = len(rows)
+	}
+	size := int64(hi - lo)
+	if n < 0 {
+		n += size
+	}
+	if n < 0 || n >= size {
+		return 0, false
+	}
+	if rows != nil {
+		return rows[n], true
+	}
+	return lo + int(n), true
+}
+
+// bound returns the first row of the frame of row i if start is set, and
+// the first row after the frame otherwise.
+func (f *frames) bound(i int, b Bound, start bool) int {
+	rows := len(f.group)
+	switch b.Type {
+	case UnboundedPreceding:
+		return 0
+	case UnboundedFollowing:
+		return rows
+	case CurrentRow:
+		if f.frame.Type == Rows {
+			return f.rowBound(i, start)
+		}
+		return f.groupBound(f.group[i], start)
+	}
+
+	switch f.frame.Type {
+	case Rows:
+		return clamp(f.rowBound(i+offset(b, rows), start), 0, rows)
+	case Groups:
+		g := f.group[i] + offset(b, len(f.groups))
+		switch {
+		case g < 0:
+			return 0
+		case g >= len(f.groups)-1:
+			return rows
+		}
+		return f.groupBound(g, start)
+	}
+	return f.rangeBound(i, b, start)
+}
+
+// offset returns the offset of the Preceding or Following bound b, which is
+// negative for Preceding. Offsets larger than limit are saturated to limit, so
+// that adding the offset to a row or group below limit cannot overflow.
+func offset(b Bound, limit int) int {
+	n := b.Offset.(int64)
+	if n > int64(limit) {
+		n = int64(limit)
+	}
+	if b.Type == Preceding {
+		return -int(n)
+	}
+	return int(n)
+}
+
+func (f *frames) rowBound(i int, start bool) int {
+	if start {
+		return i
+	}
+	return i + 1
+}
+
+func (f *frames) groupBound(g int, start bool) int {
+	if start {
+		return f.groups[g]
+	}
+	return f.groups[g+1]
+}
+
+// rangeBound computes the bound of a Range frame with an offset. If the
+// ORDER BY term of the current row is not a number, the bound is the first or
+// last peer of the row.
+func (f *frames) rangeBound(i int, b Bound, start bool) int {
+	value := f.keys[i][0]
+	switch value.(type) {
+	case int64, float64:
+	default:
+		return f.groupBound(f.group[i], start)
+	}
+
+	subtract := b.Type == Preceding
+	if f.desc {
+		subtract = !subtract
+	}
+	target := addNumbers(value, b.Offset, subtract)
+
+	return sort.Search(len(f.group), func(j int) bool {
+		c := Compare(f.keys[j][0], target)
+		if f.desc {
+			c = -c
+		}
+		if start {
+			return c >= 0
+		}
+		return c > 0
+	})
+}
+
+// addNumbers adds or subtracts two numbers. The result is a REAL, if either
+// number is a REAL or the result overflows.
+func addNumbers(a, b Value, subtract bool) Value {
+	x, xok := a.(int64)
+	y, yok := b.(int64)
+	if xok && yok {
+		if subtract {
+			y = -y
+		}
+		if r := x + y; (r > x) == (y > 0) {
+			return r
+		}
+	}
+	if subtract {
+		return toFloat(a) - toFloat(b)
+	}
+	return toFloat(a) + toFloat(b)
+}
+
+func clamp(n, lo, hi int) int {
+	switch {
+	case n < lo:
+		return lo
+	case n > hi:
+		return hi
+	}
+	return n
+}
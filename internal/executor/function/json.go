@@ -0,0 +1,440 @@
+package function
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A JSON document is held as a tree of *jsonObject, *jsonArray, json.Number,
+// string, bool and nil values. Objects keep the order of their members, and
+// numbers keep their text, so that documents are not rewritten
+// unnecessarily.
+type (
+	jsonObject struct {
+		keys   []string
+		values []interface{}
+	}
+	jsonArray struct {
+		elems []interface{}
+	}
+)
+
+// jsonRemoved is returned by editJSON in place of a removed element.
+type jsonRemoved struct{}
+
+func (o *jsonObject) index(key string) int {
+	for i, k := range o.keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseJSON parses the given JSON text.
+func parseJSON(text string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	v, err := decodeJSON(dec)
+	if err != nil {
+		return nil, ErrMalformedJSON
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, ErrMalformedJSON
+	}
+	return v, nil
+}
+
+func decodeJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key.(string))
+			obj.values = append(obj.values, value)
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := &jsonArray{}
+		for dec.More() {
+			elem, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr.elems = append(arr.elems, elem)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// formatJSON returns the minified JSON text of the given value.
+func formatJSON(v interface{}) string {
+	var buf bytes.Buffer
+	writeJSON(&buf, v)
+	return buf.String()
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		buf.WriteString(string(v))
+	case string:
+		buf.WriteString(quoteJSON(v))
+	case *jsonArray:
+		buf.WriteByte('[')
+		for i, elem := range v.elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, elem)
+		}
+		buf.WriteByte(']')
+	case *jsonObject:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(quoteJSON(key))
+			buf.WriteByte(':')
+			writeJSON(buf, v.values[i])
+		}
+		buf.WriteByte('}')
+	}
+}
+
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// toJSON converts an SQL value to a JSON value. TEXT is converted to a JSON
+// string, even if it contains JSON.
+func toJSON(v Value) (interface{}, error) {
+	switch v := v.(type) {
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return nil, nil
+		case math.IsInf(v, 1):
+			return json.Number("9e999"), nil
+		case math.IsInf(v, -1):
+			return json.Number("-9e999"), nil
+		}
+		return json.Number(formatFloat(v)), nil
+	case string:
+		return v, nil
+	case []byte:
+		return nil, ErrJSONBlob
+	}
+	return nil, nil
+}
+
+// fromJSON converts a JSON value to an SQL value. Objects and arrays are
+// converted to their JSON text, and true and false to 1 and 0.
+func fromJSON(v interface{}) Value {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return i
+		}
+		f, _ := strconv.ParseFloat(string(v), 64)
+		return f
+	case string:
+		return v
+	}
+	return formatJSON(v)
+}
+
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		if strings.ContainsAny(string(v), ".eE") {
+			return "real"
+		}
+		return "integer"
+	case string:
+		return "text"
+	case *jsonArray:
+		return "array"
+	}
+	return "object"
+}
+
+// pathStep is a step of a JSON path, which selects either the member of an
+// object with the given key, or the element of an array at the given index.
+// If fromEnd is set, the index counts from the end of the array, so that
+// [#] selects the position after the last element.
+type pathStep struct {
+	isIndex bool
+	key     string
+	index   int
+	fromEnd bool
+}
+
+// parsePath parses a JSON path like $.a."b c"[2][#-1].
+func parsePath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, ErrJSONPath
+	}
+	var steps []pathStep
+	for rest := path[1:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			var key string
+			if strings.HasPrefix(rest, `"`) {
+				// a quoted key is a JSON string, which may contain escaped
+				// quotes, and any other character
+				end := 1
+				for end < len(rest) && rest[end] != '"' {
+					if rest[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				if end >= len(rest) || json.Unmarshal([]byte(rest[:end+1]), &key) != nil {
+					return nil, ErrJSONPath
+				}
+				rest = rest[end+1:]
+			} else {
+				end := strings.IndexAny(rest, ".[")
+				if end < 0 {
+					end = len(rest)
+				}
+				key, rest = rest[:end], rest[end:]
+				if key == "" {
+					return nil, ErrJSONPath
+				}
+			}
+			steps = append(steps, pathStep{key: key})
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, ErrJSONPath
+			}
+			step := pathStep{isIndex: true}
+			index := rest[1:end]
+			if strings.HasPrefix(index, "#") {
+				step.fromEnd = true
+				index = strings.TrimPrefix(strings.TrimSpace(index[1:]), "-")
+				if index == "" {
+					index = "0"
+				}
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(index))
+			if err != nil || n < 0 {
+				return nil, ErrJSONPath
+			}
+			step.index = n
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, ErrJSONPath
+		}
+	}
+	return steps, nil
+}
+
+// resolve returns the index of the array element that the step selects.
+func (s pathStep) resolve(arr *jsonArray) int {
+	if s.fromEnd {
+		return len(arr.elems) - s.index
+	}
+	return s.index
+}
+
+// lookupJSON returns the value at the given path.
+func lookupJSON(v interface{}, steps []pathStep) (interface{}, bool) {
+	for _, step := range steps {
+		switch n := v.(type) {
+		case *jsonObject:
+			i := n.index(step.key)
+			if step.isIndex || i < 0 {
+				return nil, false
+			}
+			v = n.values[i]
+		case *jsonArray:
+			i := step.resolve(n)
+			if !step.isIndex || i < 0 || i >= len(n.elems) {
+				return nil, false
+			}
+			v = n.elems[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// editOp is an operation of editJSON.
+type editOp uint8
+
+const (
+	// editInsert only creates values that don't exist.
+	editInsert editOp = iota
+	// editReplace only overwrites values that exist.
+	editReplace
+	// editSet creates or overwrites values.
+	editSet
+	// editRemove removes values.
+	editRemove
+)
+
+// editJSON applies the operation to the value at the given path below v, and
+// returns the new value of v. Missing objects on the path are created.
+func editJSON(v interface{}, steps []pathStep, op editOp, value interface{}) interface{} {
+	if len(steps) == 0 {
+		switch op {
+		case editInsert:
+			return v
+		case editRemove:
+			return jsonRemoved{}
+		}
+		return value
+	}
+
+	step := steps[0]
+	switch n := v.(type) {
+	case *jsonObject:
+		if step.isIndex {
+			return v
+		}
+		i := n.index(step.key)
+		if i < 0 {
+			if created, ok := createJSON(steps[1:], op, value); ok {
+				n.keys = append(n.keys, step.key)
+				n.values = append(n.values, created)
+			}
+			return v
+		}
+		if child := editJSON(n.values[i], steps[1:], op, value); child == (jsonRemoved{}) {
+			n.keys = append(n.keys[:i], n.keys[i+1:]...)
+			n.values = append(n.values[:i], n.values[i+1:]...)
+		} else {
+			n.values[i] = child
+		}
+	case *jsonArray:
+		if !step.isIndex {
+			return v
+		}
+		i := step.resolve(n)
+		switch {
+		case i == len(n.elems):
+			if created, ok := createJSON(steps[1:], op, value); ok {
+				n.elems = append(n.elems, created)
+			}
+		case i >= 0 && i < len(n.elems):
+			if child := editJSON(n.elems[i], steps[1:], op, value); child == (jsonRemoved{}) {
+				n.elems = append(n.elems[:i], n.elems[i+1:]...)
+			} else {
+				n.elems[i] = child
+			}
+		}
+	}
+	return v
+}
+
+// createJSON creates the value for a path that doesn't exist yet. It reports
+// false if the operation doesn't create values, or if the path requires an
+// array to be created.
+func createJSON(steps []pathStep, op editOp, value interface{}) (interface{}, bool) {
+	if op != editInsert && op != editSet {
+		return nil, false
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].isIndex {
+			return nil, false
+		}
+		value = &jsonObject{keys: []string{steps[i].key}, values: []interface{}{value}}
+	}
+	return value, true
+}
+
+// patchJSON applies the patch to the target, as described by the MergePatch
+// algorithm of RFC 7396, and returns the patched value. Members of the patch
+// that are null are removed from the target.
+func patchJSON(target, patch interface{}) interface{} {
+	p, ok := patch.(*jsonObject)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(*jsonObject)
+	if !ok {
+		t = &jsonObject{}
+	}
+	for j, key := range p.keys {
+		i := t.index(key)
+		switch {
+		case p.values[j] == nil && i >= 0:
+			t.keys = append(t.keys[:i], t.keys[i+1:]...)
+			t.values = append(t.values[:i], t.values[i+1:]...)
+		case p.values[j] == nil:
+		case i >= 0:
+			t.values[i] = patchJSON(t.values[i], p.values[j])
+		default:
+			t.keys = append(t.keys, key)
+			t.values = append(t.values, patchJSON(nil, p.values[j]))
+		}
+	}
+	return t
+}
+
+// appendPath appends a step to a path in the syntax of parsePath.
+func appendPath(path string, key interface{}) string {
+	if i, ok := key.(int); ok {
+		return path + "[" + strconv.Itoa(i) + "]"
+	}
+	k := key.(string)
+	for _, r := range k {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return path + "." + quoteJSON(k)
+		}
+	}
+	if k == "" {
+		return path + `.""`
+	}
+	return path + "." + k
+}
@@ -0,0 +1,176 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFunctions(t *testing.T) {
+	doc := `{"a":{"b":[10,20,{"c":"x"}]},"key with space":true}`
+
+	runCallTests(t, NewRegistry(), []callTest{
+		{"json", []Value{` { "a" : [1, 2.50, true, null] } `}, `{"a":[1,2.50,true,null]}`},
+		{"json", []Value{`"<&>"`}, `"<&>"`},
+		{"json", []Value{nil}, nil},
+		{"json_valid", []Value{`{"a":1}`}, int64(1)},
+		{"json_valid", []Value{`{"a":1} x`}, int64(0)},
+		{"json_quote", []Value{`a"b`}, `"a\"b"`},
+		{"json_quote", []Value{1.0}, `1.0`},
+		{"json_quote", []Value{nil}, `null`},
+		{"json_array", []Value{int64(1), "a", nil, 2.5}, `[1,"a",null,2.5]`},
+		{"json_array", nil, `[]`},
+		{"json_object", []Value{"a", int64(1), "b", "x"}, `{"a":1,"b":"x"}`},
+		{"json_array_length", []Value{`[1,2,3]`}, int64(3)},
+		{"json_array_length", []Value{`{"a":[1]}`, "$.a"}, int64(1)},
+		{"json_array_length", []Value{`{"a":1}`}, int64(0)},
+		{"json_array_length", []Value{`{}`, "$.x"}, nil},
+		{"json_extract", []Value{doc, "$.a.b[1]"}, int64(20)},
+		{"json_extract", []Value{doc, "$.a.b[#-1].c"}, "x"},
+		{"json_extract", []Value{doc, "$.a"}, `{"b":[10,20,{"c":"x"}]}`},
+		{"json_extract", []Value{doc, `$."key with space"`}, int64(1)},
+		{"json_extract", []Value{doc, "$.z"}, nil},
+		{"json_extract", []Value{doc, "$.a.b[3]"}, nil},
+		{"json_extract", []Value{`{"a":1,"b":2}`, "$.a", "$.z"}, `[1,null]`},
+		{"json_extract", []Value{`[1.5]`, "$[0]"}, 1.5},
+		{"json_type", []Value{`[1]`}, "array"},
+		{"json_type", []Value{`{}`}, "object"},
+		{"json_type", []Value{`[1,2.5,"s",true,false,null]`, "$[0]"}, "integer"},
+		{"json_type", []Value{`[1,2.5,"s",true,false,null]`, "$[1]"}, "real"},
+		{"json_type", []Value{`[1,2.5,"s",true,false,null]`, "$[2]"}, "text"},
+		{"json_type", []Value{`[1,2.5,"s",true,false,null]`, "$[3]"}, "true"},
+		{"json_type", []Value{`[1,2.5,"s",true,false,null]`, "$[4]"}, "false"},
+		{"json_type", []Value{`[1,2.5,"s",true,false,null]`, "$[5]"}, "null"},
+		{"json_set", []Value{`{"a":1}`, "$.a", int64(2), "$.b.c", int64(3)}, `{"a":2,"b":{"c":3}}`},
+		{"json_set", []Value{`[1,2]`, "$[0]", "x"}, `["x",2]`},
+		{"json_set", []Value{`{"a":1}`}, `{"a":1}`},
+		{"json_insert", []Value{`{"a":1}`, "$.a", int64(2), "$.b", int64(3)}, `{"a":1,"b":3}`},
+		{"json_insert", []Value{`[1,2]`, "$[#]", int64(3)}, `[1,2,3]`},
+		{"json_replace", []Value{`{"a":1}`, "$.a", int64(2), "$.b", int64(3)}, `{"a":2}`},
+		{"json_replace", []Value{`{"a":1}`, "$", int64(2)}, `2`},
+		{"json_remove", []Value{`{"a":1,"b":[1,2,3]}`, "$.a", "$.b[#-1]"}, `{"b":[1,2]}`},
+		{"json_remove", []Value{`[1]`, "$"}, nil},
+		// quoted keys and json_patch, compared with the output of SQLite 3.50
+		{"json_extract", []Value{`{"a.b":1,"a":{"b":2}}`, `$."a.b"`}, int64(1)},
+		{"json_extract", []Value{`{"a.b":1,"a":{"b":2}}`, `$.a.b`}, int64(2)},
+		{"json_extract", []Value{`{"a[0]":5}`, `$."a[0]"`}, int64(5)},
+		{"json_extract", []Value{`{"":3}`, `$.""`}, int64(3)},
+		{"json_extract", []Value{`{"x":{"y z":[4]}}`, `$.x."y z"[0]`}, int64(4)},
+		{"json_extract", []Value{`{"a\"b":1}`, `$."a\"b"`}, int64(1)},
+		{"json_extract", []Value{`{"a\\b":1}`, `$."a\\b"`}, int64(1)},
+		{"json_set", []Value{`{}`, `$."x.y".z`, int64(1)}, `{"x.y":{"z":1}}`},
+		{"json_remove", []Value{`{"a.b":1,"c":2}`, `$."a.b"`}, `{"c":2}`},
+		{"json_insert", []Value{`{"a":{}}`, `$.a."$"`, int64(1)}, `{"a":{"$":1}}`},
+		{"json_patch", []Value{`{"a":1,"b":2}`, `{"b":null,"c":{"d":3}}`}, `{"a":1,"c":{"d":3}}`},
+		{"json_patch", []Value{`{"b":1,"a":2}`, `{"c":3,"a":null,"b":{"x":null}}`}, `{"b":{},"c":3}`},
+		{"json_patch", []Value{`{"a":{"x":1}}`, `{"a":{"y":2}}`}, `{"a":{"x":1,"y":2}}`},
+		{"json_patch", []Value{`{"a":[1,2]}`, `{"a":[3]}`}, `{"a":[3]}`},
+		{"json_patch", []Value{`[1]`, `{"a":1}`}, `{"a":1}`},
+		{"json_patch", []Value{`{"a":1}`, `[2]`}, `[2]`},
+		{"json_patch", []Value{`{"a":1}`, `3`}, `3`},
+		{"json_patch", []Value{nil, `{}`}, nil},
+		{"json_patch", []Value{`{}`, nil}, nil},
+	})
+
+	r := NewRegistry()
+	for _, args := range [][]Value{
+		{"json", "{bad"},
+		{"json_extract", "{}", "a"},
+		{"json_extract", "{}", "$.a[x]"},
+		{"json_array", []byte{0}},
+		{"json_object", "a"},
+		{"json_object", int64(1), int64(2)},
+		{"json_set", "{}", "$.a"},
+		{"json_extract", "{}", `$."a`},
+		{"json_patch", `{"a":1}`, "x"},
+	} {
+		_, err := r.Call(args[0].(string), args[1:]...)
+		assert.Error(t, err, "%v", args)
+	}
+}
+
+func TestJSONAggregates(t *testing.T) {
+	r := NewRegistry()
+
+	agg, err := r.NewAggregate("json_group_array", 1, false)
+	require.NoError(t, err)
+	for _, v := range []Value{int64(1), "a", nil} {
+		require.NoError(t, agg.Step(v))
+	}
+	got, err := agg.Final()
+	assert.NoError(t, err)
+	assert.Equal(t, `[1,"a",null]`, got)
+
+	agg, err = r.NewAggregate("json_group_object", 2, false)
+	require.NoError(t, err)
+	require.NoError(t, agg.Step("a", int64(1)))
+	require.NoError(t, agg.Step("b", nil))
+	got, err = agg.Final()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1,"b":null}`, got)
+}
+
+func TestJSONTableFunctions(t *testing.T) {
+	tests := []struct {
+		name string
+		args []Value
+		want [][]Value
+	}{
+		{
+			"json_each",
+			[]Value{`{"a":1,"b":[2],"x y":null}`},
+			[][]Value{
+				{"a", int64(1), "integer", int64(1), int64(0), nil, "$.a", "$"},
+				{"b", "[2]", "array", nil, int64(1), nil, "$.b", "$"},
+				{"x y", nil, "null", nil, int64(2), nil, `$."x y"`, "$"},
+			},
+		},
+		{
+			"json_each",
+			[]Value{`{"a.b":1,"":2,"a\"b":3}`},
+			[][]Value{
+				{"a.b", int64(1), "integer", int64(1), int64(0), nil, `$."a.b"`, "$"},
+				{"", int64(2), "integer", int64(2), int64(1), nil, `$.""`, "$"},
+				{`a"b`, int64(3), "integer", int64(3), int64(2), nil, `$."a\"b"`, "$"},
+			},
+		},
+		{
+			"json_each",
+			[]Value{`{"a":[5,6]}`, "$.a"},
+			[][]Value{
+				{int64(0), int64(5), "integer", int64(5), int64(0), nil, "$.a[0]", "$.a"},
+				{int64(1), int64(6), "integer", int64(6), int64(1), nil, "$.a[1]", "$.a"},
+			},
+		},
+		{
+			"json_each",
+			[]Value{`[5]`, "$[0]"},
+			[][]Value{
+				{nil, int64(5), "integer", int64(5), int64(0), nil, "$[0]", "$[0]"},
+			},
+		},
+		{
+			"json_each",
+			[]Value{`[5]`, "$[1]"},
+			nil,
+		},
+		{
+			"json_tree",
+			[]Value{`{"a":[2]}`},
+			[][]Value{
+				{nil, `{"a":[2]}`, "object", nil, int64(0), nil, "$", "$"},
+				{"a", "[2]", "array", nil, int64(1), int64(0), "$.a", "$"},
+				{int64(0), int64(2), "integer", int64(2), int64(2), int64(1), "$.a[0]", "$.a"},
+			},
+		},
+	}
+	r := NewRegistry()
+	for _, tt := range tests {
+		f, err := r.Table(tt.name, len(tt.args))
+		require.NoError(t, err)
+		got, err := f.Call(tt.args...)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, "%s(%v)", tt.name, tt.args)
+	}
+}
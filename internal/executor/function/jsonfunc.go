@@ -0,0 +1,345 @@
+package function
+
+// jsonFunctions are the scalar functions of the json1 extension of SQLite.
+// JSON arguments are TEXT. Since values carry no subtype, the result of a
+// JSON function that is passed as a value to another JSON function is
+// treated as a string.
+var jsonFunctions = map[string]Function{
+	"json":              {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(jsonFunc)},
+	"json_valid":        {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(jsonValid)},
+	"json_quote":        {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: jsonQuote},
+	"json_array":        {MinArgs: 0, MaxArgs: -1, Deterministic: true, Call: jsonArrayFunc},
+	"json_object":       {MinArgs: 0, MaxArgs: -1, Deterministic: true, Call: jsonObjectFunc},
+	"json_array_length": {MinArgs: 1, MaxArgs: 2, Deterministic: true, Call: strict(jsonArrayLength)},
+	"json_extract":      {MinArgs: 2, MaxArgs: -1, Deterministic: true, Call: jsonExtract},
+	"json_type":         {MinArgs: 1, MaxArgs: 2, Deterministic: true, Call: strict(jsonTypeFunc)},
+	"json_insert":       {MinArgs: 1, MaxArgs: -1, Deterministic: true, Call: jsonEdit(editInsert)},
+	"json_replace":      {MinArgs: 1, MaxArgs: -1, Deterministic: true, Call: jsonEdit(editReplace)},
+	"json_set":          {MinArgs: 1, MaxArgs: -1, Deterministic: true, Call: jsonEdit(editSet)},
+	"json_remove":       {MinArgs: 1, MaxArgs: -1, Deterministic: true, Call: jsonRemove},
+	"json_patch":        {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: strict(jsonPatch)},
+}
+
+// jsonAggregates are the aggregate functions of the json1 extension.
+var jsonAggregates = map[string]AggregateFunction{
+	"json_group_array":  {MinArgs: 1, MaxArgs: 1, Deterministic: true, New: func() Aggregate { return &jsonGroupArray{arr: &jsonArray{}} }},
+	"json_group_object": {MinArgs: 2, MaxArgs: 2, Deterministic: true, New: func() Aggregate { return &jsonGroupObject{obj: &jsonObject{}} }},
+}
+
+// jsonTableFunctions are the table-valued functions of the json1 extension.
+var jsonTableFunctions = map[string]TableFunction{
+	"json_each": {MinArgs: 1, MaxArgs: 2, Columns: jsonTableColumns, Call: jsonTable(false)},
+	"json_tree": {MinArgs: 1, MaxArgs: 2, Columns: jsonTableColumns, Call: jsonTable(true)},
+}
+
+var jsonTableColumns = []string{"key", "value", "type", "atom", "id", "parent", "fullkey", "path"}
+
+// jsonFunc returns its argument as minified JSON.
+func jsonFunc(args ...Value) (Value, error) {
+	v, err := parseJSON(toText(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return formatJSON(v), nil
+}
+
+func jsonValid(args ...Value) (Value, error) {
+	if _, err := parseJSON(toText(args[0])); err != nil {
+		return int64(0), nil
+	}
+	return int64(1), nil
+}
+
+// jsonQuote returns its argument as a JSON value.
+func jsonQuote(args ...Value) (Value, error) {
+	v, err := toJSON(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return formatJSON(v), nil
+}
+
+// jsonArrayFunc returns a JSON array of its arguments.
+func jsonArrayFunc(args ...Value) (Value, error) {
+	arr := &jsonArray{}
+	for _, arg := range args {
+		v, err := toJSON(arg)
+		if err != nil {
+			return nil, err
+		}
+		arr.elems = append(arr.elems, v)
+	}
+	return formatJSON(arr), nil
+}
+
+// jsonObjectFunc returns a JSON object of its arguments, which are pairs of
+// TEXT labels and values.
+func jsonObjectFunc(args ...Value) (Value, error) {
+	if len(args)%2 != 0 {
+		return nil, ErrArgumentCount
+	}
+	obj := &jsonObject{}
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			return nil, ErrArgument
+		}
+		v, err := toJSON(args[i+1])
+		if err != nil {
+			return nil, err
+		}
+		obj.keys = append(obj.keys, key)
+		obj.values = append(obj.values, v)
+	}
+	return formatJSON(obj), nil
+}
+
+// lookupArg parses the JSON argument and looks up the optional path
+// argument.
+func lookupArg(args []Value) (interface{}, bool, error) {
+	v, err := parseJSON(toText(args[0]))
+	if err != nil || len(args) < 2 {
+		return v, err == nil, err
+	}
+	steps, err := parsePath(toText(args[1]))
+	if err != nil {
+		return nil, false, err
+	}
+	v, ok := lookupJSON(v, steps)
+	return v, ok, nil
+}
+
+// jsonArrayLength returns the number of elements of the array at the given
+// path, or 0 if it is not an array.
+func jsonArrayLength(args ...Value) (Value, error) {
+	v, ok, err := lookupArg(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if arr, isArray := v.(*jsonArray); isArray {
+		return int64(len(arr.elems)), nil
+	}
+	return int64(0), nil
+}
+
+func jsonTypeFunc(args ...Value) (Value, error) {
+	v, ok, err := lookupArg(args)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return jsonType(v), nil
+}
+
+// jsonExtract returns the SQL value at the given path, or NULL if it doesn't
+// exist. With more than one path, it returns a JSON array of the values at
+// all paths.
+func jsonExtract(args ...Value) (Value, error) {
+	for _, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+	}
+	doc, err := parseJSON(toText(args[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	results := &jsonArray{}
+	for _, path := range args[1:] {
+		steps, err := parsePath(toText(path))
+		if err != nil {
+			return nil, err
+		}
+		v, ok := lookupJSON(doc, steps)
+		if len(args) == 2 {
+			if !ok {
+				return nil, nil
+			}
+			return fromJSON(v), nil
+		}
+		results.elems = append(results.elems, v)
+	}
+	return formatJSON(results), nil
+}
+
+// jsonEdit returns the implementation of json_insert, json_replace and
+// json_set, which take a JSON argument followed by pairs of paths and
+// values.
+func jsonEdit(op editOp) func(args ...Value) (Value, error) {
+	return func(args ...Value) (Value, error) {
+		if len(args)%2 == 0 {
+			return nil, ErrArgumentCount
+		}
+		if args[0] == nil {
+			return nil, nil
+		}
+		doc, err := parseJSON(toText(args[0]))
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(args); i += 2 {
+			if args[i] == nil {
+				return nil, nil
+			}
+			steps, err := parsePath(toText(args[i]))
+			if err != nil {
+				return nil, err
+			}
+			v, err := toJSON(args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			doc = editJSON(doc, steps, op, v)
+		}
+		return formatJSON(doc), nil
+	}
+}
+
+// jsonRemove removes the values at the given paths. It returns NULL if the
+// whole document is removed.
+func jsonRemove(args ...Value) (Value, error) {
+	for _, arg := range args {
+		if arg == nil {
+			return nil, nil
+		}
+	}
+	doc, err := parseJSON(toText(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range args[1:] {
+		steps, err := parsePath(toText(path))
+		if err != nil {
+			return nil, err
+		}
+		if doc = editJSON(doc, steps, editRemove, nil); doc == (jsonRemoved{}) {
+			return nil, nil
+		}
+	}
+	return formatJSON(doc), nil
+}
+
+// jsonGroupArray implements json_group_array(X), which returns a JSON array
+// of all values.
+type jsonGroupArray struct {
+	arr *jsonArray
+}
+
+func (a *jsonGroupArray) Step(args ...Value) error {
+	v, err := toJSON(args[0])
+	if err != nil {
+		return err
+	}
+	a.arr.elems = append(a.arr.elems, v)
+	return nil
+}
+
+func (a *jsonGroupArray) Final() (Value, error) { return formatJSON(a.arr), nil }
+
+// jsonGroupObject implements json_group_object(NAME, VALUE), which returns a
+// JSON object of all pairs.
+type jsonGroupObject struct {
+	obj *jsonObject
+}
+
+func (a *jsonGroupObject) Step(args ...Value) error {
+	if args[0] == nil {
+		return nil
+	}
+	v, err := toJSON(args[1])
+	if err != nil {
+		return err
+	}
+	a.obj.keys = append(a.obj.keys, toText(args[0]))
+	a.obj.values = append(a.obj.values, v)
+	return nil
+}
+
+func (a *jsonGroupObject) Final() (Value, error) { return formatJSON(a.obj), nil }
+
+// jsonTable returns the implementation of json_each(X[, P]), which returns a
+// row for every element of the object or array at path P, and json_tree(X[,
+// P]), which returns a row for the value at P and all its descendants. The
+// id of a row is its position in the result, and parent is the id of the
+// row of the containing object or array.
+func jsonTable(recursive bool) func(args ...Value) ([][]Value, error) {
+	return func(args ...Value) ([][]Value, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		root, ok, err := lookupArg(args)
+		if err != nil || !ok {
+			return nil, err
+		}
+		path := "$"
+		if len(args) > 1 {
+			path = toText(args[1])
+		}
+
+		var rows [][]Value
+		var walk func(key interface{}, v interface{}, parent Value, fullkey, path string)
+		walk = func(key interface{}, v interface{}, parent Value, fullkey, path string) {
+			id := int64(len(rows))
+			var atom Value
+			switch v.(type) {
+			case *jsonObject, *jsonArray:
+			default:
+				atom = fromJSON(v)
+			}
+			var k Value
+			switch key := key.(type) {
+			case int:
+				k = int64(key)
+			case string:
+				k = key
+			}
+			rows = append(rows, []Value{k, fromJSON(v), jsonType(v), atom, id, parent, fullkey, path})
+			if recursive {
+				walkChildren(v, func(key interface{}, child interface{}) {
+					walk(key, child, id, appendPath(fullkey, key), fullkey)
+				})
+			}
+		}
+
+		switch root.(type) {
+		case *jsonObject, *jsonArray:
+			if !recursive {
+				walkChildren(root, func(key interface{}, child interface{}) {
+					walk(key, child, nil, appendPath(path, key), path)
+				})
+				return rows, nil
+			}
+		}
+		walk(nil, root, nil, path, path)
+		return rows, nil
+	}
+}
+
+// walkChildren calls fn for every member of an object or element of an
+// array, with the key or index of it.
+func walkChildren(v interface{}, fn func(key interface{}, child interface{})) {
+	switch v := v.(type) {
+	case *jsonObject:
+		for i, key := range v.keys {
+			fn(key, v.values[i])
+		}
+	case *jsonArray:
+		for i, elem := range v.elems {
+			fn(i, elem)
+		}
+	}
+}
+
+// jsonPatch implements json_patch(T, P), which applies the merge patch P to
+// the JSON value T.
+func jsonPatch(args ...Value) (Value, error) {
+	target, err := parseJSON(toText(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	patch, err := parseJSON(toText(args[1]))
+	if err != nil {
+		return nil, err
+	}
+	return formatJSON(patchJSON(target, patch)), nil
+}
@@ -0,0 +1,158 @@
+package function
+
+import "math"
+
+// mathFunctions are the scalar math functions of SQLite. Unless stated
+// otherwise, they return a REAL, and NULL for NULL arguments and for
+// arguments outside of their domain.
+var mathFunctions = map[string]Function{
+	"abs":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(abs)},
+	"round":   {MinArgs: 1, MaxArgs: 2, Deterministic: true, Call: strict(round)},
+	"sign":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(sign)},
+	"ceil":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(integral(math.Ceil))},
+	"ceiling": {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(integral(math.Ceil))},
+	"floor":   {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(integral(math.Floor))},
+	"trunc":   {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(integral(math.Trunc))},
+	"ln":      {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(logarithm(math.Log))},
+	"log":     {MinArgs: 1, MaxArgs: 2, Deterministic: true, Call: strict(logFunc)},
+	"log10":   {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(logarithm(math.Log10))},
+	"log2":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(logarithm(math.Log2))},
+	"exp":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Exp)},
+	"pow":     {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: binary(math.Pow)},
+	"power":   {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: binary(math.Pow)},
+	"sqrt":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Sqrt)},
+	"mod":     {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: binary(math.Mod)},
+	"pi":      {MinArgs: 0, MaxArgs: 0, Deterministic: true, Call: func(...Value) (Value, error) { return math.Pi, nil }},
+	"sin":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Sin)},
+	"cos":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Cos)},
+	"tan":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Tan)},
+	"asin":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Asin)},
+	"acos":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Acos)},
+	"atan":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Atan)},
+	"atan2":   {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: binary(math.Atan2)},
+	"sinh":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Sinh)},
+	"cosh":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Cosh)},
+	"tanh":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Tanh)},
+	"asinh":   {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Asinh)},
+	"acosh":   {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Acosh)},
+	"atanh":   {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(math.Atanh)},
+	"degrees": {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(func(x float64) float64 { return x * 180 / math.Pi })},
+	"radians": {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: unary(func(x float64) float64 { return x * math.Pi / 180 })},
+}
+
+// realOrNull returns the given float as a Value, or NULL if it is NaN.
+func realOrNull(f float64) Value {
+	if math.IsNaN(f) {
+		return nil
+	}
+	return f
+}
+
+func unary(fn func(float64) float64) func(args ...Value) (Value, error) {
+	return strict(func(args ...Value) (Value, error) {
+		return realOrNull(fn(toFloat(args[0]))), nil
+	})
+}
+
+func binary(fn func(float64, float64) float64) func(args ...Value) (Value, error) {
+	return strict(func(args ...Value) (Value, error) {
+		return realOrNull(fn(toFloat(args[0]), toFloat(args[1]))), nil
+	})
+}
+
+// logarithm restricts a logarithm to positive numbers.
+func logarithm(fn func(float64) float64) func(float64) float64 {
+	return func(x float64) float64 {
+		if x <= 0 {
+			return math.NaN()
+		}
+		return fn(x)
+	}
+}
+
+// integral returns the implementation of ceil, floor and trunc, which return
+// INTEGER arguments unchanged.
+func integral(fn func(float64) float64) func(args ...Value) (Value, error) {
+	return func(args ...Value) (Value, error) {
+		if i, ok := args[0].(int64); ok {
+			return i, nil
+		}
+		return realOrNull(fn(toFloat(args[0]))), nil
+	}
+}
+
+// abs returns the absolute value of its argument. It returns an INTEGER for an
+// INTEGER argument, and a REAL otherwise.
+func abs(args ...Value) (Value, error) {
+	if i, ok := args[0].(int64); ok {
+		if i == math.MinInt64 {
+			return nil, ErrIntegerOverflow
+		}
+		if i < 0 {
+			i = -i
+		}
+		return i, nil
+	}
+	return math.Abs(toFloat(args[0])), nil
+}
+
+// round rounds its argument to Y decimal places, which default to 0. Halfway
+// cases are rounded away from zero.
+func round(args ...Value) (Value, error) {
+	x := toFloat(args[0])
+	digits := int64(0)
+	if len(args) > 1 {
+		digits = toInt(args[1])
+	}
+	switch {
+	case digits < 0:
+		digits = 0
+	case digits > 30:
+		digits = 30
+	}
+	p := math.Pow10(int(digits))
+	if r := math.Round(x*p) / p; !math.IsInf(r, 0) && !math.IsNaN(r) {
+		return r, nil
+	}
+	return x, nil
+}
+
+// sign returns -1, 0 or 1 for negative, zero and positive numbers, and NULL
+// for arguments that are not numbers.
+func sign(args ...Value) (Value, error) {
+	var f float64
+	switch v := args[0].(type) {
+	case int64:
+		f = float64(v)
+	case float64:
+		f = v
+	case string:
+		n, ok := parseWholeNumber(v)
+		if !ok {
+			return nil, nil
+		}
+		f = toFloat(n)
+	default:
+		return nil, nil
+	}
+	switch {
+	case f < 0:
+		return int64(-1), nil
+	case f > 0:
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+// logFunc implements log(X), the base 10 logarithm of X, and log(B, X), the
+// base B logarithm of X.
+func logFunc(args ...Value) (Value, error) {
+	if len(args) == 1 {
+		return realOrNull(math.Log10(toFloat(args[0]))), nil
+	}
+	base, x := toFloat(args[0]), toFloat(args[1])
+	if base <= 0 || base == 1 || x <= 0 {
+		return nil, nil
+	}
+	return realOrNull(math.Log(x) / math.Log(base)), nil
+}
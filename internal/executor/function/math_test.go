@@ -0,0 +1,52 @@
+package function
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMathFunctions(t *testing.T) {
+	runCallTests(t, NewRegistry(), []callTest{
+		{"abs", []Value{int64(-3)}, int64(3)},
+		{"abs", []Value{-2.5}, 2.5},
+		{"abs", []Value{"-4"}, 4.0},
+		{"abs", []Value{nil}, nil},
+		{"round", []Value{2.5}, 3.0},
+		{"round", []Value{-2.5}, -3.0},
+		{"round", []Value{3.14159, int64(2)}, 3.14},
+		{"round", []Value{int64(7)}, 7.0},
+		{"sign", []Value{int64(-7)}, int64(-1)},
+		{"sign", []Value{0.0}, int64(0)},
+		{"sign", []Value{"2.5"}, int64(1)},
+		{"sign", []Value{"abc"}, nil},
+		{"ceil", []Value{1.2}, 2.0},
+		{"ceiling", []Value{int64(3)}, int64(3)},
+		{"floor", []Value{-1.2}, -2.0},
+		{"trunc", []Value{-1.7}, -1.0},
+		{"ln", []Value{math.E}, 1.0},
+		{"ln", []Value{int64(0)}, nil},
+		{"log", []Value{int64(100)}, 2.0},
+		{"log", []Value{int64(2), int64(8)}, 3.0},
+		{"log", []Value{int64(1), int64(8)}, nil},
+		{"log10", []Value{int64(-1)}, nil},
+		{"log2", []Value{int64(1024)}, 10.0},
+		{"exp", []Value{int64(0)}, 1.0},
+		{"pow", []Value{int64(2), int64(10)}, 1024.0},
+		{"power", []Value{int64(4), 0.5}, 2.0},
+		{"sqrt", []Value{int64(16)}, 4.0},
+		{"sqrt", []Value{int64(-1)}, nil},
+		{"mod", []Value{int64(7), int64(3)}, 1.0},
+		{"mod", []Value{int64(7), int64(0)}, nil},
+		{"pi", nil, math.Pi},
+		{"sin", []Value{int64(0)}, 0.0},
+		{"atan2", []Value{int64(1), int64(1)}, math.Pi / 4},
+		{"degrees", []Value{math.Pi}, 180.0},
+		{"radians", []Value{int64(180)}, math.Pi},
+	})
+
+	_, err := NewRegistry().Call("abs", int64(math.MinInt64))
+	assert.True(t, errors.Is(err, ErrIntegerOverflow))
+}
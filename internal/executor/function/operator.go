@@ -0,0 +1,442 @@
+package function
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// The operators of SQL expressions are implemented here, so that they follow
+// the same conversion rules as the functions. All operators return NULL, if
+// any operand is NULL, unless documented otherwise.
+
+// Add returns a + b. Like in SQLite, INTEGER operands, whose sum overflows,
+// and all other operands are computed as REAL.
+func Add(a, b Value) Value {
+	return arithmetic(a, b, func(x, y int64) (int64, bool) {
+		sum := x + y
+		return sum, (sum > x) == (y > 0)
+	}, func(x, y float64) float64 { return x + y })
+}
+
+// Subtract returns a - b, see Add.
+func Subtract(a, b Value) Value {
+	return arithmetic(a, b, func(x, y int64) (int64, bool) {
+		diff := x - y
+		return diff, (diff < x) == (y > 0)
+	}, func(x, y float64) float64 { return x - y })
+}
+
+// Multiply returns a * b, see Add.
+func Multiply(a, b Value) Value {
+	return arithmetic(a, b, func(x, y int64) (int64, bool) {
+		if x == 0 || y == 0 {
+			return 0, true
+		}
+		product := x * y
+		return product, product/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64)
+	}, func(x, y float64) float64 { return x * y })
+}
+
+// Divide returns a / b. The division of INTEGER values truncates, and a
+// division by zero is NULL.
+func Divide(a, b Value) Value {
+	if a == nil || b == nil {
+		return nil
+	}
+	x, y := toNumber(a), toNumber(b)
+	if isZero(y) {
+		return nil
+	}
+	xi, xok := x.(int64)
+	yi, yok := y.(int64)
+	if xok && yok {
+		if xi == math.MinInt64 && yi == -1 {
+			return float64(xi) / float64(yi)
+		}
+		return xi / yi
+	}
+	return realOrNull(toFloat(x) / toFloat(y))
+}
+
+// Remainder returns a % b. The operands are converted to INTEGER, unless one
+// of them is REAL, and a remainder of a division by zero is NULL.
+func Remainder(a, b Value) Value {
+	if a == nil || b == nil {
+		return nil
+	}
+	x, y := toNumber(a), toNumber(b)
+	xi, xok := x.(int64)
+	yi, yok := y.(int64)
+	if xok && yok {
+		if yi == 0 {
+			return nil
+		}
+		if yi == -1 {
+			return int64(0)
+		}
+		return xi % yi
+	}
+	fy := toFloat(y)
+	if fy == 0 {
+		return nil
+	}
+	return realOrNull(math.Mod(toFloat(x), fy))
+}
+
+// Negate returns -a.
+func Negate(a Value) Value {
+	if a == nil {
+		return nil
+	}
+	switch n := toNumber(a).(type) {
+	case int64:
+		if n == math.MinInt64 {
+			return -float64(n)
+		}
+		return -n
+	case float64:
+		return -n
+	}
+	return nil
+}
+
+// Concat returns the concatenation of a and b as TEXT.
+func Concat(a, b Value) Value {
+	if a == nil || b == nil {
+		return nil
+	}
+	return toText(a) + toText(b)
+}
+
+// BitAnd returns a & b of the operands converted to INTEGER.
+func BitAnd(a, b Value) Value {
+	return bitwise(a, b, func(x, y int64) int64 { return x & y })
+}
+
+// BitOr returns a | b of the operands converted to INTEGER.
+func BitOr(a, b Value) Value {
+	return bitwise(a, b, func(x, y int64) int64 { return x | y })
+}
+
+// ShiftLeft returns a << b of the operands converted to INTEGER. A negative
+// shift shifts in the other direction.
+func ShiftLeft(a, b Value) Value {
+	return bitwise(a, b, shift)
+}
+
+// ShiftRight returns a >> b, see ShiftLeft.
+func ShiftRight(a, b Value) Value {
+	return bitwise(a, b, func(x, y int64) int64 {
+		if y == math.MinInt64 {
+			y++
+		}
+		return shift(x, -y)
+	})
+}
+
+// BitNot returns ~a of the operand converted to INTEGER.
+func BitNot(a Value) Value {
+	if a == nil {
+		return nil
+	}
+	return ^toInt(a)
+}
+
+// Truth converts a value to a boolean, like the WHERE clause does. The second
+// result is false if the value is NULL, which is neither true nor false.
+func Truth(v Value) (truth, ok bool) {
+	if v == nil {
+		return false, false
+	}
+	return truthy(v), true
+}
+
+func shift(x, y int64) int64 {
+	switch {
+	case y >= 64:
+		return 0
+	case y >= 0:
+		return x << uint(y)
+	case y <= -64:
+		if x < 0 {
+			return -1
+		}
+		return 0
+	}
+	return x >> uint(-y)
+}
+
+func bitwise(a, b Value, fn func(x, y int64) int64) Value {
+	if a == nil || b == nil {
+		return nil
+	}
+	return fn(toInt(a), toInt(b))
+}
+
+// arithmetic computes an operation with INTEGER operands with the given
+// function on integers, which reports false on overflow, and otherwise with
+// the given function on floats.
+func arithmetic(a, b Value, ints func(x, y int64) (int64, bool), floats func(x, y float64) float64) Value {
+	if a == nil || b == nil {
+		return nil
+	}
+	x, y := toNumber(a), toNumber(b)
+	xi, xok := x.(int64)
+	yi, yok := y.(int64)
+	if xok && yok {
+		if result, ok := ints(xi, yi); ok {
+			return result
+		}
+	}
+	return realOrNull(floats(toFloat(x), toFloat(y)))
+}
+
+func isZero(v Value) bool {
+	switch n := v.(type) {
+	case int64:
+		return n == 0
+	case float64:
+		return n == 0
+	}
+	return false
+}
+
+// Affinity is the type affinity of a column, which is derived from its
+// declared type like in SQLite.
+type Affinity uint8
+
+// Known affinities.
+const (
+	// Blob stores values as they are. It is the affinity of columns
+	// without a declared type.
+	Blob Affinity = iota
+	Text
+	Numeric
+	Integer
+	Real
+)
+
+// AffinityOf returns the affinity of a column with the given declared type,
+// with the rules of SQLite.
+func AffinityOf(typeName string) Affinity {
+	t := strings.ToUpper(typeName)
+	switch {
+	case strings.Contains(t, "INT"):
+		return Integer
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return Text
+	case strings.Contains(t, "BLOB"), t == "":
+		return Blob
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return Real
+	}
+	return Numeric
+}
+
+// Apply converts a value that is stored in a column with the affinity. TEXT
+// columns store numbers as TEXT, and numeric columns store TEXT, that looks
+// like a number, as a number. REAL columns store all numbers as REAL.
+func (a Affinity) Apply(v Value) Value {
+	switch a {
+	case Text:
+		switch v.(type) {
+		case int64, float64:
+			return toText(v)
+		}
+	case Numeric, Integer:
+		if s, ok := v.(string); ok {
+			if n, ok := parseWholeNumber(s); ok {
+				v = n
+			}
+		}
+		if f, ok := v.(float64); ok && isInteger(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f)
+		}
+	case Real:
+		if s, ok := v.(string); ok {
+			if n, ok := parseWholeNumber(s); ok {
+				v = n
+			}
+		}
+		if i, ok := v.(int64); ok {
+			return float64(i)
+		}
+	}
+	return v
+}
+
+// Cast converts a value to the given type, like CAST(v AS typeName). NULL
+// remains NULL.
+func Cast(v Value, typeName string) Value {
+	if v == nil {
+		return nil
+	}
+	switch AffinityOf(typeName) {
+	case Text:
+		return toText(v)
+	case Blob:
+		if s, ok := v.(string); ok {
+			return []byte(s)
+		}
+		if b, ok := v.([]byte); ok {
+			return b
+		}
+		return []byte(toText(v))
+	case Integer:
+		return toInt(v)
+	case Real:
+		return toFloat(v)
+	}
+	n := toNumber(v)
+	if f, ok := n.(float64); ok && isInteger(f) {
+		return int64(f)
+	}
+	return n
+}
+
+// FormatText converts a value to TEXT, like CAST(v AS TEXT), with NULL as an
+// empty string.
+func FormatText(v Value) string {
+	return toText(v)
+}
+
+// Like reports whether the string matches the LIKE pattern, in which '%'
+// matches any sequence of characters and '_' matches any single character.
+// ASCII letters are compared case-insensitively. If escape is not 0, it
+// makes the character after it match itself.
+func Like(pattern, s string, escape rune) bool {
+	return match(pattern, s, '%', '_', escape, true)
+}
+
+// Glob reports whether the string matches the GLOB pattern, in which '*'
+// matches any sequence of characters, '?' any single character and [...] a
+// set of characters. GLOB is case sensitive.
+func Glob(pattern, s string) bool {
+	return match(pattern, s, '*', '?', 0, false)
+}
+
+// match matches a string with a LIKE or GLOB pattern.
+func match(pattern, s string, many, one, escape rune, fold bool) bool {
+	for len(pattern) > 0 {
+		p, size := utf8.DecodeRuneInString(pattern)
+		pattern = pattern[size:]
+		switch {
+		case p == escape && escape != 0:
+			if len(pattern) == 0 {
+				return false
+			}
+			p, size = utf8.DecodeRuneInString(pattern)
+			pattern = pattern[size:]
+		case p == many:
+			for {
+				if match(pattern, s, many, one, escape, fold) {
+					return true
+				}
+				if len(s) == 0 {
+					return false
+				}
+				_, size := utf8.DecodeRuneInString(s)
+				s = s[size:]
+			}
+		case p == one:
+			if len(s) == 0 {
+				return false
+			}
+			_, size := utf8.DecodeRuneInString(s)
+			s = s[size:]
+			continue
+		case p == '[' && !fold:
+			c, size := utf8.DecodeRuneInString(s)
+			if len(s) == 0 {
+				return false
+			}
+			end, ok := matchSet(pattern, c)
+			if !ok {
+				return false
+			}
+			pattern, s = pattern[end:], s[size:]
+			continue
+		}
+		if len(s) == 0 {
+			return false
+		}
+		c, size := utf8.DecodeRuneInString(s)
+		if c != p && !(fold && c < utf8.RuneSelf && p < utf8.RuneSelf && toLower(byte(c)) == toLower(byte(p))) {
+			return false
+		}
+		s = s[size:]
+	}
+	return len(s) == 0
+}
+
+// matchSet matches a character with a set of GLOB, like [a-z] or [^0-9],
+// whose '[' was already consumed. It returns the position after the ']'.
+func matchSet(pattern string, c rune) (int, bool) {
+	i := 0
+	invert := false
+	if strings.HasPrefix(pattern, "^") {
+		invert = true
+		i++
+	}
+	found := false
+	first := true
+	for i < len(pattern) {
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		if r == ']' && !first {
+			return i + size, found != invert
+		}
+		first = false
+		i += size
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi, hsize := utf8.DecodeRuneInString(pattern[i+1:])
+			found = found || r <= c && c <= hi
+			i += 1 + hsize
+			continue
+		}
+		found = found || r == c
+	}
+	return i, false
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// like implements like(pattern, s [, escape]), which is LIKE with swapped
+// operands.
+func like(args ...Value) (Value, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	var escape rune
+	if len(args) > 2 {
+		if args[2] == nil {
+			return nil, nil
+		}
+		e := toText(args[2])
+		if utf8.RuneCountInString(e) != 1 {
+			return nil, ErrEscape
+		}
+		escape, _ = utf8.DecodeRuneInString(e)
+	}
+	return boolValue(Like(toText(args[0]), toText(args[1]), escape)), nil
+}
+
+// glob implements glob(pattern, s), which is GLOB with swapped operands.
+func glob(args ...Value) (Value, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+	return boolValue(Glob(toText(args[0]), toText(args[1]))), nil
+}
+
+func boolValue(b bool) Value {
+	if b {
+		return int64(1)
+	}
+	return int64(0)
+}
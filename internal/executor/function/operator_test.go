@@ -0,0 +1,62 @@
+package function
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperators(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Value
+		want Value
+	}{
+		{"add", Add(int64(1), int64(2)), int64(3)},
+		{"add text", Add("1", 2.5), 3.5},
+		{"add overflow", Add(int64(math.MaxInt64), int64(1)), float64(math.MaxInt64) + 1},
+		{"add null", Add(nil, int64(1)), nil},
+		{"subtract overflow", Subtract(int64(math.MinInt64), int64(1)), float64(math.MinInt64) - 1},
+		{"multiply", Multiply(int64(6), int64(7)), int64(42)},
+		{"multiply overflow", Multiply(int64(math.MaxInt64), int64(2)), float64(math.MaxInt64) * 2},
+		{"divide", Divide(int64(7), int64(2)), int64(3)},
+		{"divide real", Divide(7.0, int64(2)), 3.5},
+		{"divide by zero", Divide(int64(7), int64(0)), nil},
+		{"remainder", Remainder(int64(-7), int64(3)), int64(-1)},
+		{"remainder by zero", Remainder(int64(7), int64(0)), nil},
+		{"negate", Negate("3"), int64(-3)},
+		{"concat", Concat(int64(1), "a"), "1a"},
+		{"bit and", BitAnd(int64(6), int64(3)), int64(2)},
+		{"shift left", ShiftLeft(int64(1), int64(3)), int64(8)},
+		{"shift right negative", ShiftRight(int64(1), int64(-3)), int64(8)},
+		{"shift out", ShiftRight(int64(-8), int64(100)), int64(-1)},
+		{"bit not", BitNot(int64(0)), int64(-1)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.got, tt.name)
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(Integer, AffinityOf("BIGINT"))
+	assert.Equal(Text, AffinityOf("VARCHAR(10)"))
+	assert.Equal(Blob, AffinityOf(""))
+	assert.Equal(Real, AffinityOf("DOUBLE PRECISION"))
+	assert.Equal(Numeric, AffinityOf("DECIMAL(10,2)"))
+
+	assert.Equal(int64(12), Integer.Apply("12"))
+	assert.Equal(int64(3), Numeric.Apply(3.0))
+	assert.Equal("abc", Integer.Apply("abc"))
+	assert.Equal("12", Text.Apply(int64(12)))
+	assert.Equal(2.0, Real.Apply(int64(2)))
+	assert.Equal("12", Blob.Apply("12"))
+
+	assert.Equal(int64(12), Cast("12.7", "INTEGER"))
+	assert.Equal("1.5", Cast(1.5, "TEXT"))
+	assert.Equal([]byte("ab"), Cast("ab", "BLOB"))
+	assert.Equal(int64(3), Cast("3.0", "NUMERIC"))
+	assert.Nil(Cast(nil, "TEXT"))
+}
@@ -0,0 +1,215 @@
+package function
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// printf implements printf(FORMAT, ...) and format(FORMAT, ...), which
+// format their arguments like the C function. Besides the conversions of C,
+// it supports %q, which doubles single quotes in TEXT, %Q, which also encloses
+// TEXT in single quotes and formats NULL as NULL, and %w, which doubles double
+// quotes. The , flag separates the thousands of numbers with commas. Missing
+// arguments are formatted as NULL.
+func printf(args ...Value) (Value, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	format, args := toText(args[0]), args[1:]
+	next := func() Value {
+		if len(args) == 0 {
+			return nil
+		}
+		arg := args[0]
+		args = args[1:]
+		return arg
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			sb.WriteByte(format[i])
+			continue
+		}
+
+		// flags, width and precision collect the parts of the conversion in
+		// the syntax of the fmt package.
+		flags := []byte{'%'}
+		comma := false
+		for i++; i < len(format) && strings.IndexByte("-+ 0#!,", format[i]) >= 0; i++ {
+			switch format[i] {
+			case ',':
+				comma = true
+			case '!':
+			default:
+				flags = append(flags, format[i])
+			}
+		}
+		var width, precision int64
+		var widthSpec, precisionSpec []byte
+		var ok bool
+		if width, ok, i = printfNumber(format, i, next); ok {
+			if width < 0 {
+				// a negative width from an argument left-justifies
+				flags = append(flags, '-')
+				width = -width
+			}
+			width = clampWidth(width)
+			widthSpec = strconv.AppendInt(widthSpec, width, 10)
+		}
+		if i < len(format) && format[i] == '.' {
+			// a negative precision from an argument is ignored
+			if precision, ok, i = printfNumber(format, i+1, next); !ok {
+				precisionSpec = []byte{'.'}
+			} else if precision >= 0 {
+				precisionSpec = strconv.AppendInt([]byte{'.'}, clampWidth(precision), 10)
+			}
+		}
+		spec := string(flags) + string(widthSpec) + string(precisionSpec)
+		for i < len(format) && format[i] == 'l' {
+			i++
+		}
+		if i >= len(format) {
+			sb.WriteString(spec)
+			break
+		}
+
+		verb := format[i]
+		if comma && strings.IndexByte("diufFeEgG", verb) >= 0 {
+			sb.WriteString(printfGrouped(string(flags), int(width), string(precisionSpec), verb, next()))
+			continue
+		}
+		switch verb {
+		case '%':
+			sb.WriteByte('%')
+		case 'd', 'i':
+			fmt.Fprintf(&sb, string(spec)+"d", toInt(next()))
+		case 'u':
+			fmt.Fprintf(&sb, string(spec)+"d", uint64(toInt(next())))
+		case 'x', 'X', 'o':
+			fmt.Fprintf(&sb, string(spec)+string(verb), uint64(toInt(next())))
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			fmt.Fprintf(&sb, string(spec)+string(verb), toFloat(next()))
+		case 's', 'z':
+			fmt.Fprintf(&sb, string(spec)+"s", toText(next()))
+		case 'c':
+			r, _ := utf8.DecodeRuneInString(toText(next()))
+			fmt.Fprintf(&sb, string(spec)+"c", r)
+		case 'q', 'Q':
+			arg := next()
+			s := strings.ReplaceAll(toText(arg), "'", "''")
+			switch {
+			case arg == nil && verb == 'q':
+				s = "(NULL)"
+			case arg == nil:
+				s = "NULL"
+			case verb == 'Q':
+				s = "'" + s + "'"
+			}
+			fmt.Fprintf(&sb, string(spec)+"s", s)
+		case 'w':
+			fmt.Fprintf(&sb, string(spec)+"s", strings.ReplaceAll(toText(next()), `"`, `""`))
+		default:
+			sb.WriteString(spec)
+			sb.WriteByte(verb)
+		}
+	}
+	return sb.String(), nil
+}
+
+// printfGrouped formats a numeric conversion with the , flag, which separates
+// the thousands of the integer part with commas. Like in SQLite, zeros that
+// pad an integer are grouped as well, while zeros that pad a real number are
+// not.
+func printfGrouped(flags string, width int, precision string, verb byte, arg Value) string {
+	left := strings.IndexByte(flags, '-') >= 0
+	zero := !left && strings.IndexByte(flags, '0') >= 0
+	flags = strings.NewReplacer("-", "", "0", "").Replace(flags)
+
+	var s string
+	integer := true
+	switch verb {
+	case 'd', 'i':
+		s = fmt.Sprintf(flags+precision+"d", toInt(arg))
+	case 'u':
+		s = fmt.Sprintf(flags+precision+"d", uint64(toInt(arg)))
+	default:
+		s = fmt.Sprintf(flags+precision+string(verb), toFloat(arg))
+		integer = false
+	}
+
+	var sign string
+	if s != "" && strings.IndexByte("+- ", s[0]) >= 0 {
+		sign, s = s[:1], s[1:]
+	}
+	if zero && integer {
+		s = padLeft(s, width-len(sign), '0')
+	}
+	s = groupThousands(s)
+	if zero && !integer {
+		s = padLeft(s, width-len(sign), '0')
+	}
+	s = sign + s
+	if left && len(s) < width {
+		return s + strings.Repeat(" ", width-len(s))
+	}
+	return padLeft(s, width, ' ')
+}
+
+// groupThousands inserts a comma before every third digit from the end of the
+// leading digits of s.
+func groupThousands(s string) string {
+	n := 0
+	for n < len(s) && '0' <= s[n] && s[n] <= '9' {
+		n++
+	}
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 && (n-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteString(s[n:])
+	return sb.String()
+}
+
+// padLeft prepends pad to s until it is at least width bytes long.
+func padLeft(s string, width int, pad byte) string {
+	if len(s) >= width {
+		return s
+	}
+	return strings.Repeat(string(pad), width-len(s)) + s
+}
+
+// printfMaxWidth is the maximum width and precision of a conversion, so
+// that a large width doesn't allocate an unbounded amount of memory.
+const printfMaxWidth = 1 << 16
+
+// printfNumber parses the width or precision at format[i], and returns it
+// together with the index after it. A * takes the number from the next
+// argument. If there is no number, ok is false.
+func printfNumber(format string, i int, next func() Value) (n int64, ok bool, end int) {
+	if i < len(format) && format[i] == '*' {
+		return toInt(next()), true, i + 1
+	}
+	for ; i < len(format) && '0' <= format[i] && format[i] <= '9'; i++ {
+		if n <= printfMaxWidth {
+			n = n*10 + int64(format[i]-'0')
+		}
+		ok = true
+	}
+	return n, ok, i
+}
+
+// clampWidth limits a non-negative width or precision to printfMaxWidth.
+// The absolute value of math.MinInt64 is still negative, and clamped as
+// well.
+func clampWidth(n int64) int64 {
+	if n < 0 || n > printfMaxWidth {
+		return printfMaxWidth
+	}
+	return n
+}
@@ -0,0 +1,279 @@
+package function
+
+import (
+	"math"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterFunc registers a Go function as a scalar function, like
+// RegisterFunc of github.com/mattn/go-sqlite3. The parameters of impl may be
+// of any integer, float, bool, string, []byte or interface{} type, and the
+// last one may be variadic. It must return a value of such a type, optionally
+// followed by an error. Arguments are converted to the parameter types with
+// the conversion rules of SQLite, where NULL is converted to the zero value
+// of all types except interface{}. A nil []byte is returned as NULL.
+func (r *Registry) RegisterFunc(name string, impl interface{}, deterministic bool) error {
+	fn := reflect.ValueOf(impl)
+	if fn.Kind() != reflect.Func {
+		return ErrInvalidFunction
+	}
+	c, err := newCaller(fn, 0, true)
+	if err != nil {
+		return err
+	}
+	return r.RegisterScalar(name, Function{
+		MinArgs:       c.minArgs(),
+		MaxArgs:       c.maxArgs(),
+		Deterministic: deterministic,
+		Call: func(args ...Value) (Value, error) {
+			return c.call(nil, args)
+		},
+	})
+}
+
+// RegisterAggregator registers a Go type as an aggregate function, like
+// RegisterAggregator of github.com/mattn/go-sqlite3. impl must be a function
+// without parameters, that returns a pointer to a new aggregator. The
+// aggregator must have a Step method, that takes the arguments of a row like
+// the functions of RegisterFunc and optionally returns an error, and a Done
+// method without parameters, that returns the result like the functions of
+// RegisterFunc. Like Final of an Aggregate, Done may be called more than
+// once.
+func (r *Registry) RegisterAggregator(name string, impl interface{}, deterministic bool) error {
+	constructor := reflect.ValueOf(impl)
+	if constructor.Kind() != reflect.Func {
+		return ErrInvalidFunction
+	}
+	ct := constructor.Type()
+	if ct.NumIn() != 0 || ct.NumOut() != 1 {
+		return ErrInvalidFunction
+	}
+	aggType := ct.Out(0)
+	stepMethod, ok1 := aggType.MethodByName("Step")
+	doneMethod, ok2 := aggType.MethodByName("Done")
+	if !ok1 || !ok2 {
+		return ErrInvalidFunction
+	}
+	step, err := newCaller(stepMethod.Func, 1, false)
+	if err != nil {
+		return err
+	}
+	done, err := newCaller(doneMethod.Func, 1, true)
+	if err != nil || done.maxArgs() != 0 {
+		return ErrInvalidFunction
+	}
+
+	return r.RegisterAggregate(name, AggregateFunction{
+		MinArgs:       step.minArgs(),
+		MaxArgs:       step.maxArgs(),
+		Deterministic: deterministic,
+		New: func() Aggregate {
+			return &reflectAggregate{
+				agg:  constructor.Call(nil)[0],
+				step: step,
+				done: done,
+			}
+		},
+	})
+}
+
+// reflectAggregate is an aggregate registered with RegisterAggregator.
+type reflectAggregate struct {
+	agg        reflect.Value
+	step, done *caller
+}
+
+func (a *reflectAggregate) Step(args ...Value) error {
+	_, err := a.step.call([]reflect.Value{a.agg}, args)
+	return err
+}
+
+func (a *reflectAggregate) Final() (Value, error) {
+	return a.done.call([]reflect.Value{a.agg}, nil)
+}
+
+// caller calls a Go function with SQL arguments.
+type caller struct {
+	fn       reflect.Value
+	args     []func(Value) reflect.Value
+	variadic func(Value) reflect.Value
+	result   func(reflect.Value) (Value, error)
+	hasError bool
+}
+
+// newCaller creates a caller for the given function, whose first skip
+// parameters are not SQL arguments, like the receiver of a method. If
+// hasResult is set, the function must return a value.
+func newCaller(fn reflect.Value, skip int, hasResult bool) (*caller, error) {
+	c := &caller{fn: fn}
+	t := fn.Type()
+	in := t.NumIn()
+	if t.IsVariadic() {
+		in--
+		conv, err := argConverter(t.In(in).Elem())
+		if err != nil {
+			return nil, err
+		}
+		c.variadic = conv
+	}
+	for i := skip; i < in; i++ {
+		conv, err := argConverter(t.In(i))
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, conv)
+	}
+
+	out := 0
+	if hasResult {
+		if t.NumOut() < 1 {
+			return nil, ErrInvalidFunction
+		}
+		conv, err := resultConverter(t.Out(0))
+		if err != nil {
+			return nil, err
+		}
+		c.result = conv
+		out = 1
+	}
+	switch t.NumOut() - out {
+	case 0:
+	case 1:
+		if t.Out(out) != errorType {
+			return nil, ErrInvalidFunction
+		}
+		c.hasError = true
+	default:
+		return nil, ErrInvalidFunction
+	}
+	return c, nil
+}
+
+func (c *caller) minArgs() int {
+	return len(c.args)
+}
+
+func (c *caller) maxArgs() int {
+	if c.variadic != nil {
+		return -1
+	}
+	return len(c.args)
+}
+
+// call calls the function with the given leading arguments, followed by the
+// converted SQL arguments.
+func (c *caller) call(prefix []reflect.Value, args []Value) (Value, error) {
+	in := append([]reflect.Value{}, prefix...)
+	for i, arg := range args {
+		if i < len(c.args) {
+			in = append(in, c.args[i](arg))
+		} else {
+			in = append(in, c.variadic(arg))
+		}
+	}
+	out := c.fn.Call(in)
+
+	if c.hasError {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+	}
+	if c.result == nil {
+		return nil, nil
+	}
+	return c.result(out[0])
+}
+
+// argConverter returns a function, that converts an SQL value to the given
+// Go type.
+func argConverter(t reflect.Type) (func(Value) reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v Value) reflect.Value { return reflect.ValueOf(toInt(v)).Convert(t) }, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(v Value) reflect.Value { return reflect.ValueOf(uint64(toInt(v))).Convert(t) }, nil
+	case reflect.Float32, reflect.Float64:
+		return func(v Value) reflect.Value { return reflect.ValueOf(toFloat(v)).Convert(t) }, nil
+	case reflect.Bool:
+		return func(v Value) reflect.Value { return reflect.ValueOf(truthy(v)).Convert(t) }, nil
+	case reflect.String:
+		return func(v Value) reflect.Value { return reflect.ValueOf(toText(v)).Convert(t) }, nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		return func(v Value) reflect.Value {
+			b, ok := v.([]byte)
+			if !ok && v != nil {
+				b = []byte(toText(v))
+			}
+			return reflect.ValueOf(b).Convert(t)
+		}, nil
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			break
+		}
+		return func(v Value) reflect.Value {
+			if v == nil {
+				return reflect.Zero(t)
+			}
+			return reflect.ValueOf(v)
+		}, nil
+	}
+	return nil, ErrInvalidFunction
+}
+
+// resultConverter returns a function, that converts a value of the given Go
+// type to an SQL value.
+func resultConverter(t reflect.Type) (func(reflect.Value) (Value, error), error) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(v reflect.Value) (Value, error) { return v.Int(), nil }, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(v reflect.Value) (Value, error) {
+			if v.Uint() > math.MaxInt64 {
+				return nil, ErrIntegerOverflow
+			}
+			return int64(v.Uint()), nil
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		return func(v reflect.Value) (Value, error) { return v.Float(), nil }, nil
+	case reflect.Bool:
+		return func(v reflect.Value) (Value, error) {
+			if v.Bool() {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}, nil
+	case reflect.String:
+		return func(v reflect.Value) (Value, error) { return v.String(), nil }, nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		return func(v reflect.Value) (Value, error) {
+			if v.IsNil() {
+				return nil, nil
+			}
+			return append([]byte{}, v.Bytes()...), nil
+		}, nil
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			break
+		}
+		return func(v reflect.Value) (Value, error) {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+			conv, err := resultConverter(v.Type())
+			if err != nil || v.Kind() == reflect.Interface {
+				return nil, ErrArgument
+			}
+			return conv(v)
+		}, nil
+	}
+	return nil, ErrInvalidFunction
+}
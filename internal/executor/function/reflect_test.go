@@ -0,0 +1,100 @@
+package function
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type product struct {
+	result float64
+	seen   bool
+}
+
+func (p *product) Step(x float64) {
+	if !p.seen {
+		p.result, p.seen = 1, true
+	}
+	p.result *= x
+}
+
+func (p *product) Done() (interface{}, error) {
+	if !p.seen {
+		return nil, nil
+	}
+	return p.result, nil
+}
+
+func TestRegisterFunc(t *testing.T) {
+	assert := assert.New(t)
+	r := NewRegistry()
+
+	require.NoError(t, r.RegisterFunc("add", func(a, b int) int { return a + b }, true))
+	require.NoError(t, r.RegisterFunc("join", func(sep string, parts ...string) string { return strings.Join(parts, sep) }, true))
+	require.NoError(t, r.RegisterFunc("is_null", func(v interface{}) bool { return v == nil }, true))
+	require.NoError(t, r.RegisterFunc("reverse", func(b []byte) []byte {
+		if b == nil {
+			return nil
+		}
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return b
+	}, true))
+	require.NoError(t, r.RegisterFunc("fail", func() (int64, error) { return 0, errors.New("failed") }, false))
+	require.NoError(t, r.RegisterFunc("any", func(v interface{}) interface{} { return v }, true))
+
+	runCallTests(t, r, []callTest{
+		{"add", []Value{int64(1), "2"}, int64(3)},
+		{"join", []Value{"-", "a", int64(1), 1.5}, "a-1-1.5"},
+		{"join", []Value{","}, ""},
+		{"is_null", []Value{nil}, int64(1)},
+		{"is_null", []Value{int64(0)}, int64(0)},
+		{"reverse", []Value{"abc"}, []byte("cba")},
+		{"reverse", []Value{nil}, nil},
+		{"any", []Value{"x"}, "x"},
+		{"any", []Value{nil}, nil},
+	})
+
+	f, err := r.Scalar("fail", 0)
+	assert.NoError(err)
+	assert.False(f.Deterministic)
+	_, err = f.Call()
+	assert.EqualError(err, "failed")
+
+	_, err = r.Call("add", int64(1))
+	assert.Equal(ErrArgumentCount, err)
+
+	assert.Equal(ErrInvalidFunction, r.RegisterFunc("f", 42, true))
+	assert.Equal(ErrInvalidFunction, r.RegisterFunc("f", func() {}, true))
+	assert.Equal(ErrInvalidFunction, r.RegisterFunc("f", func(map[string]int) int { return 0 }, true))
+	assert.Equal(ErrInvalidFunction, r.RegisterFunc("f", func() (int, int) { return 0, 0 }, true))
+}
+
+func TestRegisterAggregator(t *testing.T) {
+	assert := assert.New(t)
+	r := NewRegistry()
+
+	require.NoError(t, r.RegisterAggregator("product", func() *product { return &product{} }, true))
+	assert.True(r.IsAggregate("product", 1))
+
+	agg, err := r.NewAggregate("product", 1, false)
+	require.NoError(t, err)
+	for _, v := range []Value{int64(2), "3", 1.5} {
+		assert.NoError(agg.Step(v))
+	}
+	got, err := agg.Final()
+	assert.NoError(err)
+	assert.Equal(9.0, got)
+
+	// registered aggregates can be used as window functions
+	results, err := r.EvaluateWindow("product", Window{Frame: DefaultFrame}, Partition{Keys: ints(1, 2, 3), Args: ints(2, 3, 4)})
+	assert.NoError(err)
+	assert.Equal([]Value{2.0, 6.0, 24.0}, results)
+
+	assert.Equal(ErrInvalidFunction, r.RegisterAggregator("f", func() int { return 0 }, true))
+	assert.Equal(ErrInvalidFunction, r.RegisterAggregator("f", &product{}, true))
+}
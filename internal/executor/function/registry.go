@@ -0,0 +1,219 @@
+package function
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Function is a scalar function, that computes a value from the arguments of
+// a single row.
+type Function struct {
+	// MinArgs and MaxArgs are the allowed numbers of arguments. MaxArgs is
+	// negative for functions with any number of arguments.
+	MinArgs, MaxArgs int
+	// Deterministic indicates that the function always returns the same
+	// result for the same arguments, so that calls with constant arguments
+	// may be evaluated only once.
+	Deterministic bool
+	Call          func(args ...Value) (Value, error)
+}
+
+// AggregateFunction is an aggregate function, that computes a value from the
+// arguments of all rows of a group.
+type AggregateFunction struct {
+	// MinArgs and MaxArgs are the allowed numbers of arguments. MaxArgs is
+	// negative for functions with any number of arguments.
+	MinArgs, MaxArgs int
+	// Deterministic indicates that the function always returns the same
+	// result for the same rows.
+	Deterministic bool
+	// New creates a new instance of the aggregate for a group.
+	New func() Aggregate
+}
+
+// TableFunction is a table-valued function, that can be used like a table in
+// the FROM clause.
+type TableFunction struct {
+	// MinArgs and MaxArgs are the allowed numbers of arguments. MaxArgs is
+	// negative for functions with any number of arguments.
+	MinArgs, MaxArgs int
+	// Columns are the names of the columns of the returned rows.
+	Columns []string
+	Call    func(args ...Value) ([][]Value, error)
+}
+
+// Registry holds the functions that can be called by SQL statements. Function
+// names are case-insensitive. A scalar function and an aggregate function may
+// have the same name, if they take a different number of arguments, like the
+// built-in min and max functions. All methods are safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	scalars    map[string]Function
+	aggregates map[string]AggregateFunction
+	tables     map[string]TableFunction
+
+	// now returns the current time for the date and time functions.
+	now func() time.Time
+}
+
+// NewRegistry creates a new registry with the built-in functions of SQLite.
+func NewRegistry() *Registry {
+	r := &Registry{
+		scalars:    make(map[string]Function),
+		aggregates: make(map[string]AggregateFunction),
+		tables:     make(map[string]TableFunction),
+		now:        time.Now,
+	}
+	for _, functions := range []map[string]Function{
+		coreFunctions,
+		stringFunctions,
+		mathFunctions,
+		jsonFunctions,
+		r.dateTimeFunctions(),
+	} {
+		for name, f := range functions {
+			r.scalars[name] = f
+		}
+	}
+	for name, f := range builtinAggregates {
+		r.aggregates[name] = f
+	}
+	for name, f := range jsonAggregates {
+		r.aggregates[name] = f
+	}
+	for name, f := range jsonTableFunctions {
+		r.tables[name] = f
+	}
+	return r
+}
+
+// RegisterScalar registers a scalar function with the given name, replacing
+// any scalar function with the same name.
+func (r *Registry) RegisterScalar(name string, f Function) error {
+	if err := validate(name, f.MinArgs, f.MaxArgs, f.Call != nil); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scalars[strings.ToLower(name)] = f
+	return nil
+}
+
+// RegisterAggregate registers an aggregate function with the given name,
+// replacing any aggregate function with the same name.
+func (r *Registry) RegisterAggregate(name string, f AggregateFunction) error {
+	if err := validate(name, f.MinArgs, f.MaxArgs, f.New != nil); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aggregates[strings.ToLower(name)] = f
+	return nil
+}
+
+// RegisterTable registers a table-valued function with the given name,
+// replacing any table-valued function with the same name.
+func (r *Registry) RegisterTable(name string, f TableFunction) error {
+	if err := validate(name, f.MinArgs, f.MaxArgs, f.Call != nil); err != nil {
+		return err
+	}
+	if len(f.Columns) == 0 {
+		return ErrInvalidFunction
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tables[strings.ToLower(name)] = f
+	return nil
+}
+
+func validate(name string, minArgs, maxArgs int, implemented bool) error {
+	if name == "" || !implemented || minArgs < 0 || (maxArgs >= 0 && maxArgs < minArgs) {
+		return ErrInvalidFunction
+	}
+	return nil
+}
+
+func accepts(minArgs, maxArgs, args int) bool {
+	return args >= minArgs && (maxArgs < 0 || args <= maxArgs)
+}
+
+// Scalar returns the scalar function with the given name, that accepts the
+// given number of arguments.
+func (r *Registry) Scalar(name string, args int) (Function, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.scalars[strings.ToLower(name)]
+	if !ok {
+		return Function{}, ErrNoSuchFunction
+	}
+	if !accepts(f.MinArgs, f.MaxArgs, args) {
+		return Function{}, ErrArgumentCount
+	}
+	return f, nil
+}
+
+// Call calls the scalar function with the given name.
+func (r *Registry) Call(name string, args ...Value) (Value, error) {
+	f, err := r.Scalar(name, len(args))
+	if err != nil {
+		return nil, err
+	}
+	return f.Call(args...)
+}
+
+// Aggregate returns the aggregate function with the given name, that accepts
+// the given number of arguments.
+func (r *Registry) Aggregate(name string, args int) (AggregateFunction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.aggregates[strings.ToLower(name)]
+	if !ok {
+		return AggregateFunction{}, ErrNoSuchFunction
+	}
+	if !accepts(f.MinArgs, f.MaxArgs, args) {
+		return AggregateFunction{}, ErrArgumentCount
+	}
+	return f, nil
+}
+
+// IsAggregate reports whether a call of the function with the given name and
+// number of arguments is a call of an aggregate function.
+func (r *Registry) IsAggregate(name string, args int) bool {
+	_, err := r.Aggregate(name, args)
+	return err == nil
+}
+
+// NewAggregate creates a new instance of the aggregate function with the
+// given name, that is called with the given number of arguments. If distinct
+// is set, every distinct first argument is only stepped once, like in
+// count(DISTINCT x).
+func (r *Registry) NewAggregate(name string, args int, distinct bool) (Aggregate, error) {
+	f, err := r.Aggregate(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if distinct && args == 0 {
+		return nil, ErrArgumentCount
+	}
+	agg := f.New()
+	if distinct {
+		agg = &distinctAggregate{Aggregate: agg, seen: make(map[string]struct{})}
+	}
+	return agg, nil
+}
+
+// Table returns the table-valued function with the given name, that accepts
+// the given number of arguments.
+func (r *Registry) Table(name string, args int) (TableFunction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.tables[strings.ToLower(name)]
+	if !ok {
+		return TableFunction{}, ErrNoSuchFunction
+	}
+	if !accepts(f.MinArgs, f.MaxArgs, args) {
+		return TableFunction{}, ErrArgumentCount
+	}
+	return f, nil
+}
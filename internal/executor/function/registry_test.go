@@ -0,0 +1,105 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callTest is a call of a scalar function and its expected result.
+type callTest struct {
+	name string
+	args []Value
+	want Value
+}
+
+func runCallTests(t *testing.T, r *Registry, tests []callTest) {
+	t.Helper()
+	for _, tt := range tests {
+		got, err := r.Call(tt.name, tt.args...)
+		if assert.NoError(t, err, "%s(%v)", tt.name, tt.args) {
+			assert.Equal(t, tt.want, got, "%s(%v)", tt.name, tt.args)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	assert := assert.New(t)
+	r := NewRegistry()
+
+	_, err := r.Scalar("no_such_function", 0)
+	assert.Equal(ErrNoSuchFunction, err)
+	_, err = r.Scalar("abs", 2)
+	assert.Equal(ErrArgumentCount, err)
+	f, err := r.Scalar("ABS", 1)
+	assert.NoError(err)
+	assert.True(f.Deterministic)
+	f, err = r.Scalar("random", 0)
+	assert.NoError(err)
+	assert.False(f.Deterministic)
+
+	// min and max are aggregates with one argument, and scalar functions
+	// with more.
+	assert.True(r.IsAggregate("max", 1))
+	assert.False(r.IsAggregate("max", 2))
+	assert.False(r.IsAggregate("abs", 1))
+	_, err = r.Scalar("max", 2)
+	assert.NoError(err)
+
+	assert.Equal(ErrInvalidFunction, r.RegisterScalar("", Function{Call: coalesce}))
+	assert.Equal(ErrInvalidFunction, r.RegisterScalar("f", Function{}))
+	assert.Equal(ErrInvalidFunction, r.RegisterScalar("f", Function{MinArgs: 2, MaxArgs: 1, Call: coalesce}))
+	assert.Equal(ErrInvalidFunction, r.RegisterAggregate("f", AggregateFunction{}))
+	assert.Equal(ErrInvalidFunction, r.RegisterTable("f", TableFunction{Call: func(...Value) ([][]Value, error) { return nil, nil }}))
+
+	require.NoError(t, r.RegisterScalar("Double", Function{
+		MinArgs:       1,
+		MaxArgs:       1,
+		Deterministic: true,
+		Call: func(args ...Value) (Value, error) {
+			return toInt(args[0]) * 2, nil
+		},
+	}))
+	got, err := r.Call("double", int64(21))
+	assert.NoError(err)
+	assert.Equal(int64(42), got)
+
+	// a new registry only has the built-in functions
+	_, err = NewRegistry().Scalar("double", 1)
+	assert.Equal(ErrNoSuchFunction, err)
+
+	table, err := r.Table("json_each", 1)
+	assert.NoError(err)
+	assert.Equal("key", table.Columns[0])
+}
+
+func TestCoreFunctions(t *testing.T) {
+	runCallTests(t, NewRegistry(), []callTest{
+		{"coalesce", []Value{nil, nil, int64(3), int64(4)}, int64(3)},
+		{"coalesce", []Value{nil, nil}, nil},
+		{"ifnull", []Value{nil, "b"}, "b"},
+		{"ifnull", []Value{"a", "b"}, "a"},
+		{"nullif", []Value{int64(1), 1.0}, nil},
+		{"nullif", []Value{int64(1), int64(2)}, int64(1)},
+		{"iif", []Value{int64(1), "yes", "no"}, "yes"},
+		{"iif", []Value{0.0, "yes", "no"}, "no"},
+		{"iif", []Value{nil, "yes", "no"}, "no"},
+		{"typeof", []Value{nil}, "null"},
+		{"typeof", []Value{int64(1)}, "integer"},
+		{"typeof", []Value{1.5}, "real"},
+		{"typeof", []Value{"a"}, "text"},
+		{"typeof", []Value{[]byte{}}, "blob"},
+		{"min", []Value{int64(3), 1.5, int64(2)}, 1.5},
+		{"max", []Value{int64(3), "a", int64(2)}, "a"},
+		{"max", []Value{int64(3), nil}, nil},
+	})
+
+	r := NewRegistry()
+	v, err := r.Call("random")
+	assert.NoError(t, err)
+	assert.IsType(t, int64(0), v)
+	v, err = r.Call("randomblob", int64(8))
+	assert.NoError(t, err)
+	assert.Len(t, v, 8)
+}
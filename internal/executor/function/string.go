@@ -0,0 +1,218 @@
+package function
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringFunctions are the scalar functions of SQLite that operate on TEXT
+// and BLOB values.
+var stringFunctions = map[string]Function{
+	"length":    {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(length)},
+	"lower":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(lower)},
+	"upper":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(upper)},
+	"substr":    {MinArgs: 2, MaxArgs: 3, Deterministic: true, Call: strict(substr)},
+	"substring": {MinArgs: 2, MaxArgs: 3, Deterministic: true, Call: strict(substr)},
+	"replace":   {MinArgs: 3, MaxArgs: 3, Deterministic: true, Call: strict(replace)},
+	"trim":      {MinArgs: 1, MaxArgs: 2, Deterministic: true, Call: strict(trimFunc(strings.Trim))},
+	"ltrim":     {MinArgs: 1, MaxArgs: 2, Deterministic: true, Call: strict(trimFunc(strings.TrimLeft))},
+	"rtrim":     {MinArgs: 1, MaxArgs: 2, Deterministic: true, Call: strict(trimFunc(strings.TrimRight))},
+	"instr":     {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: strict(instr)},
+	"hex":       {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: hexFunc},
+	"char":      {MinArgs: 0, MaxArgs: -1, Deterministic: true, Call: char},
+	"unicode":   {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: strict(unicodeFunc)},
+	"quote":     {MinArgs: 1, MaxArgs: 1, Deterministic: true, Call: quote},
+	"printf":    {MinArgs: 1, MaxArgs: -1, Deterministic: true, Call: printf},
+	"format":    {MinArgs: 1, MaxArgs: -1, Deterministic: true, Call: printf},
+	"like":      {MinArgs: 2, MaxArgs: 3, Deterministic: true, Call: like},
+	"glob":      {MinArgs: 2, MaxArgs: 2, Deterministic: true, Call: glob},
+}
+
+// strict wraps a function so that it returns NULL if any argument is NULL.
+func strict(fn func(args ...Value) (Value, error)) func(args ...Value) (Value, error) {
+	return func(args ...Value) (Value, error) {
+		for _, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+		}
+		return fn(args...)
+	}
+}
+
+// length returns the number of characters of a TEXT value, and the number of
+// bytes of a BLOB.
+func length(args ...Value) (Value, error) {
+	if b, ok := args[0].([]byte); ok {
+		return int64(len(b)), nil
+	}
+	return int64(utf8.RuneCountInString(toText(args[0]))), nil
+}
+
+// lower and upper only convert ASCII characters, like SQLite does without
+// the ICU extension.
+func lower(args ...Value) (Value, error) {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, toText(args[0])), nil
+}
+
+func upper(args ...Value) (Value, error) {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return r
+	}, toText(args[0])), nil
+}
+
+// substr implements substr(X, Y[, Z]), which returns Z characters of X,
+// starting at the Y-th character. The first character is 1, and a negative Y
+// counts from the end. A negative Z returns the characters before Y. For a
+// BLOB, it operates on bytes.
+func substr(args ...Value) (Value, error) {
+	blob, isBlob := args[0].([]byte)
+	var runes []rune
+	size := int64(len(blob))
+	if !isBlob {
+		runes = []rune(toText(args[0]))
+		size = int64(len(runes))
+	}
+
+	p1 := toInt(args[1])
+	p2 := size + 1
+	negative := false
+	if len(args) > 2 {
+		p2 = toInt(args[2])
+		if p2 < 0 {
+			p2, negative = -p2, true
+			if p2 < 0 {
+				// -math.MinInt64 overflows
+				p2 = math.MaxInt64
+			}
+		}
+	}
+
+	switch {
+	case p1 < 0:
+		p1 += size
+		if p1 < 0 {
+			p2 += p1
+			if p2 < 0 {
+				p2 = 0
+			}
+			p1 = 0
+		}
+	case p1 > 0:
+		p1--
+	case p2 > 0:
+		p2--
+	}
+	if negative {
+		p1 -= p2
+		if p1 < 0 {
+			p2 += p1
+			p1 = 0
+		}
+	}
+	if p1 > size {
+		p1 = size
+	}
+	if p2 > size-p1 {
+		p2 = size - p1
+	}
+
+	if isBlob {
+		return append([]byte{}, blob[p1:p1+p2]...), nil
+	}
+	return string(runes[p1 : p1+p2]), nil
+}
+
+// replace replaces all occurrences of Y in X with Z.
+func replace(args ...Value) (Value, error) {
+	s, old := toText(args[0]), toText(args[1])
+	if old == "" {
+		return s, nil
+	}
+	return strings.ReplaceAll(s, old, toText(args[2])), nil
+}
+
+// trimFunc returns the implementation of trim(X[, Y]), ltrim(X[, Y]) and
+// rtrim(X[, Y]), which remove the characters in Y, or spaces, from X.
+func trimFunc(trim func(s, cutset string) string) func(args ...Value) (Value, error) {
+	return func(args ...Value) (Value, error) {
+		cutset := " "
+		if len(args) > 1 {
+			cutset = toText(args[1])
+		}
+		return trim(toText(args[0]), cutset), nil
+	}
+}
+
+// instr returns the position of the first character of the first occurrence
+// of Y in X, or 0 if Y is not in X. If X and Y are BLOBs, it returns the
+// position of the first byte.
+func instr(args ...Value) (Value, error) {
+	hay, ok1 := args[0].([]byte)
+	needle, ok2 := args[1].([]byte)
+	if ok1 && ok2 {
+		return int64(bytes.Index(hay, needle) + 1), nil
+	}
+
+	s := toText(args[0])
+	i := strings.Index(s, toText(args[1]))
+	if i < 0 {
+		return int64(0), nil
+	}
+	return int64(utf8.RuneCountInString(s[:i]) + 1), nil
+}
+
+// hexFunc returns the upper-case hexadecimal representation of the bytes of
+// its argument.
+func hexFunc(args ...Value) (Value, error) {
+	b, ok := args[0].([]byte)
+	if !ok {
+		b = []byte(toText(args[0]))
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// char returns a TEXT value of the characters with the given code points.
+func char(args ...Value) (Value, error) {
+	var sb strings.Builder
+	for _, arg := range args {
+		if arg != nil {
+			sb.WriteRune(rune(toInt(arg)))
+		}
+	}
+	return sb.String(), nil
+}
+
+// unicodeFunc returns the code point of the first character of its argument,
+// or NULL if it is empty.
+func unicodeFunc(args ...Value) (Value, error) {
+	r, size := utf8.DecodeRuneInString(toText(args[0]))
+	if size == 0 {
+		return nil, nil
+	}
+	return int64(r), nil
+}
+
+// quote returns its argument as an SQL literal.
+func quote(args ...Value) (Value, error) {
+	switch v := args[0].(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case []byte:
+		return "X'" + strings.ToUpper(hex.EncodeToString(v)) + "'", nil
+	}
+	return toText(args[0]), nil
+}
@@ -0,0 +1,108 @@
+package function
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestStringFunctions(t *testing.T) {
+	runCallTests(t, NewRegistry(), []callTest{
+		{"length", []Value{"héllo"}, int64(5)},
+		{"length", []Value{[]byte("héllo")}, int64(6)},
+		{"length", []Value{int64(-12)}, int64(3)},
+		{"length", []Value{nil}, nil},
+		{"lower", []Value{"AbC É"}, "abc É"},
+		{"upper", []Value{"abc é"}, "ABC é"},
+		{"substr", []Value{"abcdef", int64(2)}, "bcdef"},
+		{"substr", []Value{"abcdef", int64(2), int64(3)}, "bcd"},
+		{"substr", []Value{"abcdef", int64(0), int64(2)}, "a"},
+		{"substr", []Value{"abcdef", int64(-2)}, "ef"},
+		{"substr", []Value{"abcdef", int64(-10), int64(6)}, "ab"},
+		{"substr", []Value{"abcdef", int64(4), int64(-2)}, "bc"},
+		{"substr", []Value{"abcdef", int64(10)}, ""},
+		{"substr", []Value{"héllo", int64(2), int64(2)}, "él"},
+		{"substring", []Value{[]byte("abc"), int64(2)}, []byte("bc")},
+		{"substr", []Value{"abc", nil}, nil},
+		{"substr", []Value{"abc", int64(2), int64(math.MaxInt64)}, "bc"},
+		{"substr", []Value{"abc", int64(math.MaxInt64), int64(math.MaxInt64)}, ""},
+		{"substr", []Value{"abc", int64(math.MinInt64), int64(math.MaxInt64)}, "ab"},
+		{"substr", []Value{"abcdef", int64(4), int64(math.MinInt64)}, "abc"},
+		{"substr", []Value{"abcdef", int64(4), int64(math.MinInt64 + 1)}, "abc"},
+		{"substr", []Value{[]byte("abc"), int64(2), int64(math.MaxInt64)}, []byte("bc")},
+		{"substr", []Value{[]byte("abc"), int64(-1), int64(math.MaxInt64 - 1)}, []byte("c")},
+		{"replace", []Value{"aXbXc", "X", "--"}, "a--b--c"},
+		{"replace", []Value{"abc", "", "x"}, "abc"},
+		{"replace", []Value{"abc", "b", nil}, nil},
+		{"trim", []Value{"  a b  "}, "a b"},
+		{"trim", []Value{"xxaxyx", "xy"}, "a"},
+		{"ltrim", []Value{"  a  "}, "a  "},
+		{"rtrim", []Value{"  a  "}, "  a"},
+		{"instr", []Value{"héllo", "l"}, int64(3)},
+		{"instr", []Value{"hello", "z"}, int64(0)},
+		{"instr", []Value{[]byte("héllo"), []byte("l")}, int64(4)},
+		{"hex", []Value{"Az"}, "417A"},
+		{"hex", []Value{nil}, ""},
+		{"char", []Value{int64(72), int64(105), nil, int64(0x263A)}, "Hi☺"},
+		{"unicode", []Value{"☺x"}, int64(0x263A)},
+		{"unicode", []Value{""}, nil},
+		{"quote", []Value{"it's"}, "'it''s'"},
+		{"quote", []Value{nil}, "NULL"},
+		{"quote", []Value{[]byte{0xab}}, "X'AB'"},
+		{"quote", []Value{2.0}, "2.0"},
+	})
+}
+
+func TestPrintf(t *testing.T) {
+	runCallTests(t, NewRegistry(), []callTest{
+		{"printf", []Value{"plain"}, "plain"},
+		{"printf", []Value{"%d items", "12"}, "12 items"},
+		{"printf", []Value{"%5d|%-5d|%05d", int64(1), int64(2), int64(3)}, "    1|2    |00003"},
+		{"printf", []Value{"%.2f", 3.14159}, "3.14"},
+		{"printf", []Value{"%*d", int64(4), int64(7)}, "   7"},
+		{"printf", []Value{"%*d|", int64(-4), int64(7)}, "7   |"},
+		{"printf", []Value{"%.*f", int64(-2), 5.0}, "5.000000"},
+		{"printf", []Value{"%.*f", int64(1), 5.0}, "5.0"},
+		{"printf", []Value{"%.f", 5.4}, "5"},
+		{"printf", []Value{"%.*s", int64(-1), "abc"}, "abc"},
+		{"printf", []Value{"%x %X %o", int64(255), int64(255), int64(8)}, "ff FF 10"},
+		{"printf", []Value{"%s and %s", "a", nil}, "a and "},
+		{"printf", []Value{"%.3s", "abcdef"}, "abc"},
+		{"printf", []Value{"%c", "xyz"}, "x"},
+		{"printf", []Value{"%q", "it's"}, "it''s"},
+		{"printf", []Value{"%Q %Q", "it's", nil}, "'it''s' NULL"},
+		{"printf", []Value{"%w", `a"b`}, `a""b`},
+		{"printf", []Value{"100%%"}, "100%"},
+		{"printf", []Value{"%lld", int64(5)}, "5"},
+		{"printf", []Value{"%d"}, "0"},
+		// the , flag, compared with the output of SQLite 3.40
+		{"printf", []Value{"%,d", int64(1234567)}, "1,234,567"},
+		{"printf", []Value{"%,d|%,i", int64(-1234567), int64(123)}, "-1,234,567|123"},
+		{"printf", []Value{"[%,12d]", int64(1234567)}, "[   1,234,567]"},
+		{"printf", []Value{"[%-,12d]", int64(-1234567)}, "[-1,234,567  ]"},
+		{"printf", []Value{"%0,12d", int64(1234567)}, "000,001,234,567"},
+		{"printf", []Value{"%,.8d", int64(1234567)}, "01,234,567"},
+		{"printf", []Value{"%+,d|% ,d", int64(1234), int64(1234)}, "+1,234| 1,234"},
+		{"printf", []Value{"%,u", int64(-1)}, "18,446,744,073,709,551,615"},
+		{"printf", []Value{"%,d", int64(math.MinInt64)}, "-9,223,372,036,854,775,808"},
+		{"printf", []Value{"%,f|%,.2f", 1234567.5, -1234567.555}, "1,234,567.500000|-1,234,567.55"},
+		{"printf", []Value{"[%0,15.1f]", 1234567.5}, "[00001,234,567.5]"},
+		{"printf", []Value{"%,g|%,e", 1234.5, 1234567.5}, "1,234.5|1.234568e+06"},
+		{"printf", []Value{"%,x|%,s", int64(1234567), "12345"}, "12d687|12345"},
+		{"format", []Value{"%s-%d", "a", 1.9}, "a-1"},
+		{"printf", []Value{nil, int64(1)}, nil},
+		// widths and precisions are capped at printfMaxWidth
+		{"printf", []Value{"%*d", int64(math.MaxInt64), int64(7)}, strings.Repeat(" ", printfMaxWidth-1) + "7"},
+		{"printf", []Value{"%*d", int64(math.MinInt64), int64(7)}, "7" + strings.Repeat(" ", printfMaxWidth-1)},
+		{"printf", []Value{"%99999999999999999999d", int64(7)}, strings.Repeat(" ", printfMaxWidth-1) + "7"},
+		{"printf", []Value{"%.*f", int64(math.MaxInt64), 0.5}, "0.5" + strings.Repeat("0", printfMaxWidth-1)},
+		{"like", []Value{"a%c", "ABBC"}, int64(1)},
+		{"like", []Value{"a_c", "abbc"}, int64(0)},
+		{"like", []Value{"10!%", "10%", "!"}, int64(1)},
+		{"like", []Value{"10!%", "100", "!"}, int64(0)},
+		{"like", []Value{"a", nil}, nil},
+		{"glob", []Value{"a*[0-9]", "abc7"}, int64(1)},
+		{"glob", []Value{"a?[^0-9]", "ab7"}, int64(0)},
+		{"glob", []Value{"A*", "abc"}, int64(0)},
+	})
+}
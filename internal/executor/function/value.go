@@ -0,0 +1,203 @@
+package function
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value is a value of an SQL expression. It is either nil, int64, float64,
+// string or []byte.
+type Value = interface{}
+
+// typeOrder returns the position of the type of the given value in the sort
+// order of SQLite, which is NULL, numbers, TEXT, BLOB.
+func typeOrder(v Value) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int64, float64:
+		return 1
+	case string:
+		return 2
+	}
+	return 3
+}
+
+// Compare compares two values with the sort order of SQLite. NULL is less
+// than all numbers, numbers are less than all TEXT values, and TEXT values are
+// less than all BLOB values. Numbers are compared numerically, TEXT and BLOB
+// values byte by byte.
+func Compare(a, b Value) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return ta - tb
+	}
+
+	switch a := a.(type) {
+	case nil:
+		return 0
+	case int64:
+		if b, ok := b.(int64); ok {
+			return compareInts(a, b)
+		}
+		return compareFloats(float64(a), b.(float64))
+	case float64:
+		if b, ok := b.(int64); ok {
+			return compareFloats(a, float64(b))
+		}
+		return compareFloats(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return bytes.Compare(a.([]byte), b.([]byte))
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// key returns a string that is equal for two values, if and only if Compare
+// returns 0 for them.
+func key(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "n"
+	case int64:
+		return "i" + strconv.FormatInt(v, 10)
+	case float64:
+		if isInteger(v) {
+			return "i" + strconv.FormatInt(int64(v), 10)
+		}
+		return "f" + strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return "t" + v
+	}
+	return "b" + string(v.([]byte))
+}
+
+// toNumber converts the given value to an int64 or a float64. TEXT and BLOB
+// values are converted from their longest prefix that is a number, or to 0 if
+// there is no such prefix. NULL is converted to 0.
+func toNumber(v Value) Value {
+	switch v := v.(type) {
+	case int64, float64:
+		return v
+	case string:
+		return parseNumber(v)
+	case []byte:
+		return parseNumber(string(v))
+	}
+	return int64(0)
+}
+
+func parseNumber(s string) Value {
+	s = strings.TrimSpace(s)
+	for end := len(s); end > 0; end-- {
+		if n, ok := parseWholeNumber(s[:end]); ok {
+			return n
+		}
+	}
+	return int64(0)
+}
+
+// toFloat converts the given value to a float64, see toNumber.
+func toFloat(v Value) float64 {
+	switch n := toNumber(v).(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// toInt converts the given value to an int64, see toNumber. REAL values are
+// truncated.
+func toInt(v Value) int64 {
+	switch n := toNumber(v).(type) {
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+// toText converts the given value to TEXT. NULL is converted to an empty
+// string.
+func toText(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v)
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// formatFloat formats a float like SQLite does, with 15 significant digits
+// and at least one decimal place.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return ""
+	}
+	s := strconv.FormatFloat(f, 'g', 15, 64)
+	if mantissa := strings.IndexByte(s, 'e'); mantissa >= 0 {
+		if !strings.Contains(s[:mantissa], ".") {
+			s = s[:mantissa] + ".0" + s[mantissa:]
+		}
+		return s
+	}
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// isInteger reports whether the given REAL has an integral value that can be
+// represented as an int64.
+func isInteger(f float64) bool {
+	return f == math.Trunc(f) && math.Abs(f) < 1<<63
+}
+
+// parseWholeNumber parses the given string as an INTEGER or REAL, ignoring
+// surrounding spaces. It reports false if the string is not a number.
+func parseWholeNumber(s string) (Value, bool) {
+	s = strings.TrimSpace(s)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !strings.ContainsAny(s, "xXnN_") {
+		return f, true
+	}
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b Value
+		want int
+	}{
+		{nil, nil, 0},
+		{nil, int64(0), -1},
+		{int64(1), float64(1), 0},
+		{int64(1), 1.5, -1},
+		{2.5, int64(2), 1},
+		{int64(100), "1", -1},
+		{"b", "a", 1},
+		{"z", []byte("a"), -1},
+		{[]byte("a"), []byte("a"), 0},
+	}
+	for _, tt := range tests {
+		got := Compare(tt.a, tt.b)
+		switch {
+		case tt.want < 0:
+			assert.Less(t, got, 0, "%v < %v", tt.a, tt.b)
+		case tt.want > 0:
+			assert.Greater(t, got, 0, "%v > %v", tt.a, tt.b)
+		default:
+			assert.Equal(t, 0, got, "%v = %v", tt.a, tt.b)
+		}
+		assert.Equal(t, got == 0, key(tt.a) == key(tt.b), "key(%v) = key(%v)", tt.a, tt.b)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(int64(12), toNumber("12"))
+	assert.Equal(1.5, toNumber(" 1.5abc"))
+	assert.Equal(int64(0), toNumber("abc"))
+	assert.Equal(int64(0), toNumber(nil))
+	assert.Equal(int64(3), toInt(3.9))
+	assert.Equal(2.0, toFloat([]byte("2")))
+
+	assert.Equal("", toText(nil))
+	assert.Equal("-7", toText(int64(-7)))
+	assert.Equal("1.0", toText(1.0))
+	assert.Equal("0.1", toText(0.1))
+	assert.Equal("1.0e+20", toText(1e20))
+	assert.Equal("x", toText([]byte("x")))
+}
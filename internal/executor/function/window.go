@@ -0,0 +1,261 @@
+package function
+
+import "strings"
+
+// Window is the window definition of a window function.
+type Window struct {
+	Frame Frame
+	// Desc indicates that the single ORDER BY term of the window is
+	// descending. It is only used to compute Range frames with offsets.
+	Desc bool
+}
+
+// Partition holds the rows of a window partition, that are already sorted by
+// the ORDER BY terms of the window.
+type Partition struct {
+	// Keys holds the values of the ORDER BY terms of every row. Rows with
+	// equal terms are peers. If Keys is nil, all rows are peers.
+	Keys [][]Value
+	// Args holds the arguments of the window function for every row.
+	Args [][]Value
+	// Filter holds the result of the FILTER clause for every row, or is nil
+	// if there is no FILTER clause. It may only be used with aggregate
+	// functions.
+	Filter []bool
+}
+
+// windowFunc computes the result of a window function for row i.
+type windowFunc func(p *partition, i int) (Value, error)
+
+type windowDef struct {
+	minArgs, maxArgs int
+	fn               windowFunc
+}
+
+var windowFunctions = map[string]windowDef{
+	"row_number":  {0, 0, rowNumber},
+	"rank":        {0, 0, rank},
+	"dense_rank":  {0, 0, denseRank},
+	"ntile":       {1, 1, ntile},
+	"lag":         {1, 3, lag},
+	"lead":        {1, 3, lead},
+	"first_value": {1, 1, firstValue},
+	"last_value":  {1, 1, lastValue},
+	"nth_value":   {2, 2, nthValue},
+}
+
+// partition is a partition that a window function is computed over.
+type partition struct {
+	Partition
+	*frames
+}
+
+// EvaluateWindow computes the window function or aggregate function with the
+// given name over the given partition, and returns the result for every row.
+func (r *Registry) EvaluateWindow(name string, w Window, p Partition) ([]Value, error) {
+	orderBy := 0
+	if len(p.Keys) > 0 {
+		orderBy = len(p.Keys[0])
+	}
+	args := 0
+	if len(p.Args) > 0 {
+		args = len(p.Args[0])
+	}
+
+	// The number of arguments is unknown for an empty partition, so it is
+	// only checked if there are rows.
+	def, isWindow := windowFunctions[strings.ToLower(name)]
+	var agg AggregateFunction
+	switch {
+	case isWindow && p.Filter != nil:
+		return nil, ErrFilter
+	case isWindow && len(p.Args) > 0 && !accepts(def.minArgs, def.maxArgs, args):
+		return nil, ErrArgumentCount
+	case !isWindow:
+		var err error
+		if agg, err = r.Aggregate(name, args); err == ErrNoSuchFunction || (err != nil && len(p.Args) > 0) {
+			return nil, err
+		}
+	}
+	if err := w.Frame.validate(orderBy); err != nil {
+		return nil, err
+	}
+	if len(p.Args) == 0 {
+		return nil, nil
+	}
+
+	part := &partition{
+		Partition: p,
+		frames:    newFrames(w.Frame, w.Desc, p.Keys, len(p.Args)),
+	}
+	if !isWindow {
+		return part.aggregate(agg)
+	}
+	result := make([]Value, len(p.Args))
+	for i := range p.Args {
+		var err error
+		if result[i], err = def.fn(part, i); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// aggregate computes an aggregate function over the frame of every row.
+//
+// If the frames start at the first row and exclude no rows, like the default
+// frame, the frame of every row extends the frame of the previous row. Then a
+// single aggregate is stepped with the rows that enter the frame, and its
+// result is taken for every row, so that the partition is aggregated in
+// linear time. Otherwise, every frame is aggregated on its own.
+func (p *partition) aggregate(f AggregateFunction) ([]Value, error) {
+	result := make([]Value, len(p.Args))
+	if p.frame.Start.Type == UnboundedPreceding && p.frame.Exclude == ExcludeNoOthers {
+		agg, stepped := f.New(), 0
+		for i := range p.Args {
+			_, hi := p.bounds(i)
+			if hi < stepped {
+				// the frame shrank, which only happens if the ORDER BY
+				// terms are not sorted
+				agg, stepped = f.New(), 0
+			}
+			for ; stepped < hi; stepped++ {
+				if err := p.step(agg, stepped); err != nil {
+					return nil, err
+				}
+			}
+			var err error
+			if result[i], err = agg.Final(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	for i := range p.Args {
+		agg := f.New()
+		for _, j := range p.rows(i) {
+			if err := p.step(agg, j); err != nil {
+				return nil, err
+			}
+		}
+		var err error
+		if result[i], err = agg.Final(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// step steps the given aggregate with row j, unless the FILTER clause
+// excludes it.
+func (p *partition) step(agg Aggregate, j int) error {
+	if p.Filter != nil && !p.Filter[j] {
+		return nil
+	}
+	return agg.Step(p.Args[j]...)
+}
+
+func rowNumber(_ *partition, i int) (Value, error) {
+	return int64(i + 1), nil
+}
+
+func rank(p *partition, i int) (Value, error) {
+	return int64(p.groups[p.group[i]] + 1), nil
+}
+
+func denseRank(p *partition, i int) (Value, error) {
+	return int64(p.group[i] + 1), nil
+}
+
+// ntile divides the partition into N buckets of nearly equal size, and
+// returns the bucket of row i. Larger buckets come first.
+func ntile(p *partition, i int) (Value, error) {
+	n, err := positiveInt(p.Args[i][0])
+	if err != nil {
+		return nil, err
+	}
+	rows := int64(len(p.Args))
+	size, extra := rows/n, rows%n
+	row := int64(i)
+	if row < extra*(size+1) {
+		return row/(size+1) + 1, nil
+	}
+	return (row-extra*(size+1))/size + extra + 1, nil
+}
+
+func lag(p *partition, i int) (Value, error) {
+	return p.offsetValue(i, -1)
+}
+
+func lead(p *partition, i int) (Value, error) {
+	return p.offsetValue(i, 1)
+}
+
+// offsetValue implements lag(x[, offset[, default]]) with sign -1 and
+// lead(x[, offset[, default]]) with sign 1. The offset defaults to 1, and
+// the default to NULL.
+func (p *partition) offsetValue(i, sign int) (Value, error) {
+	args := p.Args[i]
+	offset := int64(1)
+	if len(args) > 1 {
+		if args[1] == nil {
+			return nil, nil
+		}
+		offset = toInt(args[1])
+	}
+	j := int64(i) + int64(sign)*offset
+	if j < 0 || j >= int64(len(p.Args)) {
+		if len(args) > 2 {
+			return args[2], nil
+		}
+		return nil, nil
+	}
+	return p.Args[j][0], nil
+}
+
+func firstValue(p *partition, i int) (Value, error) {
+	j, ok := p.nthRow(i, 0)
+	if !ok {
+		return nil, nil
+	}
+	return p.Args[j][0], nil
+}
+
+func lastValue(p *partition, i int) (Value, error) {
+	j, ok := p.nthRow(i, -1)
+	if !ok {
+		return nil, nil
+	}
+	return p.Args[j][0], nil
+}
+
+// nthValue implements nth_value(x, N), which returns x of the N-th row of
+// the frame, or NULL if the frame has less than N rows.
+func nthValue(p *partition, i int) (Value, error) {
+	n, err := positiveInt(p.Args[i][1])
+	if err != nil {
+		return nil, err
+	}
+	j, ok := p.nthRow(i, n-1)
+	if !ok {
+		return nil, nil
+	}
+	return p.Args[j][0], nil
+}
+
+// positiveInt returns the given value if it is a positive integer, and
+// ErrArgument otherwise.
+func positiveInt(v Value) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		if n > 0 {
+			return n, nil
+		}
+	case float64:
+		if n > 0 && isInteger(n) {
+			return int64(n), nil
+		}
+	}
+	return 0, ErrArgument
+}
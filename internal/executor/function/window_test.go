@@ -0,0 +1,214 @@
+package function
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ints(values ...int64) [][]Value {
+	rows := make([][]Value, len(values))
+	for i, v := range values {
+		rows[i] = []Value{v}
+	}
+	return rows
+}
+
+func withArgs(rows [][]Value, args ...Value) [][]Value {
+	result := make([][]Value, len(rows))
+	for i, row := range rows {
+		result[i] = append(append([]Value{}, row...), args...)
+	}
+	return result
+}
+
+func frame(typ FrameType, start, end Bound) Frame {
+	return Frame{Type: typ, Start: start, End: end}
+}
+
+func TestEvaluateWindow(t *testing.T) {
+	keys := ints(1, 2, 2, 4, 7)
+	unbounded := frame(Rows, Bound{Type: UnboundedPreceding}, Bound{Type: UnboundedFollowing})
+	excluding := func(e Exclude) Frame {
+		f := unbounded
+		f.Exclude = e
+		return f
+	}
+
+	tests := []struct {
+		name   string
+		fn     string
+		window Window
+		p      Partition
+		want   []Value
+	}{
+		{"row_number", "row_number", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: make([][]Value, 5)}, []Value{int64(1), int64(2), int64(3), int64(4), int64(5)}},
+		{"rank", "RANK", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: make([][]Value, 5)}, []Value{int64(1), int64(2), int64(2), int64(4), int64(5)}},
+		{"dense_rank", "dense_rank", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: make([][]Value, 5)}, []Value{int64(1), int64(2), int64(2), int64(3), int64(4)}},
+		{"rank without order", "rank", Window{Frame: DefaultFrame}, Partition{Args: make([][]Value, 3)}, []Value{int64(1), int64(1), int64(1)}},
+		{"ntile", "ntile", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: ints(2, 2, 2, 2, 2)}, []Value{int64(1), int64(1), int64(1), int64(2), int64(2)}},
+		{"ntile larger than partition", "ntile", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: ints(10, 10, 10, 10, 10)}, []Value{int64(1), int64(2), int64(3), int64(4), int64(5)}},
+		{"lag", "lag", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: keys}, []Value{nil, int64(1), int64(2), int64(2), int64(4)}},
+		{"lag with offset and default", "lag", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: withArgs(keys, int64(2), int64(0))}, []Value{int64(0), int64(0), int64(1), int64(2), int64(2)}},
+		{"lead", "lead", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: keys}, []Value{int64(2), int64(2), int64(4), int64(7), nil}},
+		{"first_value", "first_value", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: keys}, []Value{int64(1), int64(1), int64(1), int64(1), int64(1)}},
+		{"last_value includes peers", "last_value", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: keys}, []Value{int64(1), int64(2), int64(2), int64(4), int64(7)}},
+		{"nth_value", "nth_value", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: withArgs(keys, int64(2))}, []Value{nil, int64(2), int64(2), int64(2), int64(2)}},
+		{"sum default frame", "sum", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: keys}, []Value{int64(1), int64(5), int64(5), int64(9), int64(16)}},
+		{"sum without order", "sum", Window{Frame: DefaultFrame}, Partition{Args: keys}, []Value{int64(16), int64(16), int64(16), int64(16), int64(16)}},
+		{"rows", "sum", Window{Frame: frame(Rows, Bound{Preceding, int64(1)}, Bound{Following, int64(1)})}, Partition{Keys: keys, Args: keys}, []Value{int64(3), int64(5), int64(8), int64(13), int64(11)}},
+		{"range", "sum", Window{Frame: frame(Range, Bound{Preceding, int64(2)}, Bound{Type: CurrentRow})}, Partition{Keys: keys, Args: keys}, []Value{int64(1), int64(5), int64(5), int64(8), int64(7)}},
+		{"range with real offset", "sum", Window{Frame: frame(Range, Bound{Preceding, 1.5}, Bound{Type: CurrentRow})}, Partition{Keys: keys, Args: keys}, []Value{int64(1), int64(5), int64(5), int64(4), int64(7)}},
+		{"range descending", "sum", Window{Frame: frame(Range, Bound{Preceding, int64(2)}, Bound{Type: CurrentRow}), Desc: true}, Partition{Keys: ints(7, 4, 2, 2, 1), Args: ints(7, 4, 2, 2, 1)}, []Value{int64(7), int64(4), int64(8), int64(8), int64(5)}},
+		{"range with null key", "sum", Window{Frame: frame(Range, Bound{Preceding, int64(1)}, Bound{Following, int64(1)})}, Partition{Keys: [][]Value{{nil}, {int64(1)}, {int64(3)}}, Args: ints(10, 20, 30)}, []Value{int64(10), int64(20), int64(30)}},
+		{"groups", "sum", Window{Frame: frame(Groups, Bound{Preceding, int64(1)}, Bound{Type: CurrentRow})}, Partition{Keys: keys, Args: keys}, []Value{int64(1), int64(5), int64(5), int64(8), int64(11)}},
+		{"exclude current row", "sum", Window{Frame: excluding(ExcludeCurrentRow)}, Partition{Keys: keys, Args: keys}, []Value{int64(15), int64(14), int64(14), int64(12), int64(9)}},
+		{"exclude group", "sum", Window{Frame: excluding(ExcludeGroup)}, Partition{Keys: keys, Args: keys}, []Value{int64(15), int64(12), int64(12), int64(12), int64(9)}},
+		{"exclude ties", "sum", Window{Frame: excluding(ExcludeTies)}, Partition{Keys: keys, Args: keys}, []Value{int64(16), int64(14), int64(14), int64(16), int64(16)}},
+		{"last_value exclude current row", "last_value", Window{Frame: excluding(ExcludeCurrentRow)}, Partition{Keys: keys, Args: keys}, []Value{int64(7), int64(7), int64(7), int64(7), int64(4)}},
+		{"nth_value exclude group", "nth_value", Window{Frame: excluding(ExcludeGroup)}, Partition{Keys: keys, Args: withArgs(keys, int64(4))}, []Value{int64(7), nil, nil, int64(7), int64(4)}},
+		{"first_value empty frame", "first_value", Window{Frame: frame(Rows, Bound{Following, int64(2)}, Bound{Following, int64(1)})}, Partition{Keys: keys, Args: keys}, []Value{nil, nil, nil, nil, nil}},
+		{"filter", "count", Window{Frame: unbounded}, Partition{Keys: keys, Args: make([][]Value, 5), Filter: []bool{false, false, false, true, true}}, []Value{int64(2), int64(2), int64(2), int64(2), int64(2)}},
+		{"empty partition", "rank", Window{Frame: DefaultFrame}, Partition{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRegistry().EvaluateWindow(tt.fn, tt.window, tt.p)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvaluateWindowMaxOffset(t *testing.T) {
+	keys := ints(1, 2, 2, 4, 7)
+	max := Bound{Following, int64(math.MaxInt64)}
+	maxPreceding := Bound{Preceding, int64(math.MaxInt64)}
+	current := Bound{Type: CurrentRow}
+
+	tests := []struct {
+		name  string
+		frame Frame
+		want  []Value
+	}{
+		{"rows following", frame(Rows, current, max), []Value{int64(16), int64(15), int64(13), int64(11), int64(7)}},
+		{"rows preceding", frame(Rows, maxPreceding, current), []Value{int64(1), int64(3), int64(5), int64(9), int64(16)}},
+		{"rows preceding and following", frame(Rows, maxPreceding, max), []Value{int64(16), int64(16), int64(16), int64(16), int64(16)}},
+		{"rows start following", frame(Rows, max, max), []Value{nil, nil, nil, nil, nil}},
+		{"groups following", frame(Groups, current, max), []Value{int64(16), int64(15), int64(15), int64(11), int64(7)}},
+		{"groups preceding", frame(Groups, maxPreceding, current), []Value{int64(1), int64(5), int64(5), int64(9), int64(16)}},
+		{"groups preceding and following", frame(Groups, maxPreceding, max), []Value{int64(16), int64(16), int64(16), int64(16), int64(16)}},
+		{"groups start following", frame(Groups, max, max), []Value{nil, nil, nil, nil, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRegistry().EvaluateWindow("sum", Window{Frame: tt.frame}, Partition{Keys: keys, Args: keys})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvaluateWindowErrors(t *testing.T) {
+	keys := ints(1, 2, 3)
+
+	tests := []struct {
+		name   string
+		fn     string
+		window Window
+		p      Partition
+		want   error
+	}{
+		{"unknown function", "median", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: keys}, ErrNoSuchFunction},
+		{"argument count", "lag", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: make([][]Value, 3)}, ErrArgumentCount},
+		{"filter on window function", "rank", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: make([][]Value, 3), Filter: []bool{true, true, true}}, ErrFilter},
+		{"ntile of zero", "ntile", Window{Frame: DefaultFrame}, Partition{Keys: keys, Args: ints(0, 0, 0)}, ErrArgument},
+		{"negative offset", "sum", Window{Frame: frame(Rows, Bound{Preceding, int64(-1)}, Bound{Type: CurrentRow})}, Partition{Keys: keys, Args: keys}, ErrFrameOffset},
+		{"real rows offset", "sum", Window{Frame: frame(Rows, Bound{Preceding, 1.5}, Bound{Type: CurrentRow})}, Partition{Keys: keys, Args: keys}, ErrFrameOffset},
+		{"missing offset", "sum", Window{Frame: frame(Groups, Bound{Type: Preceding}, Bound{Type: CurrentRow})}, Partition{Keys: keys, Args: keys}, ErrFrameOffset},
+		{"range offset with two terms", "sum", Window{Frame: frame(Range, Bound{Preceding, int64(1)}, Bound{Type: CurrentRow})}, Partition{Keys: withArgs(keys, int64(0)), Args: keys}, ErrFrameBounds},
+		{"start unbounded following", "sum", Window{Frame: frame(Rows, Bound{Type: UnboundedFollowing}, Bound{Type: UnboundedFollowing})}, Partition{Keys: keys, Args: keys}, ErrFrameBounds},
+		{"start following end current row", "sum", Window{Frame: frame(Rows, Bound{Following, int64(1)}, Bound{Type: CurrentRow})}, Partition{Keys: keys, Args: keys}, ErrFrameBounds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegistry().EvaluateWindow(tt.fn, tt.window, tt.p)
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
+
+// countingSum is sum(x), that counts the rows it is stepped with.
+type countingSum struct {
+	sum
+	steps *int
+}
+
+func (a *countingSum) Step(args ...Value) error {
+	*a.steps++
+	return a.sum.Step(args...)
+}
+
+func TestEvaluateWindowLargePartition(t *testing.T) {
+	const n = 100000
+	keys := make([][]Value, n)
+	filter := make([]bool, n)
+	for i := range keys {
+		// groups of 4 peers
+		keys[i] = []Value{int64(i / 4)}
+		filter[i] = i%3 != 0
+	}
+	// sums[j] and filtered[j] are the sums of the rows before row j, without
+	// and with the filter
+	sums, filtered := make([]int64, n+1), make([]int64, n+1)
+	for i, row := range keys {
+		sums[i+1], filtered[i+1] = sums[i]+row[0].(int64), filtered[i]
+		if filter[i] {
+			filtered[i+1] += row[0].(int64)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		frame  Frame
+		filter []bool
+		// end returns the first row after the frame of row i
+		end func(i int) int
+	}{
+		{"default frame", DefaultFrame, nil, func(i int) int { return i/4*4 + 4 }},
+		{"rows", frame(Rows, Bound{Type: UnboundedPreceding}, Bound{Following, int64(2)}), nil, func(i int) int { return clamp(i+3, 0, n) }},
+		{"groups", frame(Groups, Bound{Type: UnboundedPreceding}, Bound{Preceding, int64(1)}), nil, func(i int) int { return i / 4 * 4 }},
+		{"range", frame(Range, Bound{Type: UnboundedPreceding}, Bound{Following, int64(1)}), nil, func(i int) int { return clamp(i/4*4+8, 0, n) }},
+		{"filter", DefaultFrame, filter, func(i int) int { return i/4*4 + 4 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			steps := 0
+			r := NewRegistry()
+			assert.NoError(r.RegisterAggregate("counting_sum", AggregateFunction{MinArgs: 1, MaxArgs: 1, New: func() Aggregate {
+				return &countingSum{steps: &steps}
+			}}))
+			got, err := r.EvaluateWindow("counting_sum", Window{Frame: tt.frame}, Partition{Keys: keys, Args: keys, Filter: tt.filter})
+			assert.NoError(err)
+
+			// every row is stepped at most once
+			assert.LessOrEqual(steps, n)
+			for i, v := range got {
+				var want Value
+				switch end := tt.end(i); {
+				case end == 0:
+				case tt.filter != nil:
+					want = filtered[end]
+				default:
+					want = sums[end]
+				}
+				if !assert.Equal(want, v, "row %d", i) {
+					return
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/alter"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// defineIndex converts a CREATE INDEX statement to the definition of a
+// secondary index of the given table. Like CHECK constraints, the
+// expressions of the index are compiled without an execution, so that they
+// cannot contain subqueries.
+func defineIndex(stmt *ast.CreateIndexStmt, t *storedTable) (alter.Index, error) {
+	// the rows of an index don't have the row ID
+	s := &scope{columns: t.columns[:t.width()]}
+	x := &execution{}
+	ix := alter.Index{
+		Definition: index.Definition[constraint.Row]{
+			Name:   token.Unquote(stmt.IndexName),
+			Unique: stmt.Unique != nil,
+		},
+		Columns: []int{},
+	}
+	seen := make(map[int]bool)
+	var resolveErr error
+	use := func(e *planner.Expr) {
+		if e.Op != planner.ColumnExpr || resolveErr != nil {
+			return
+		}
+		_, i, err := s.resolve(e.Column)
+		if err != nil {
+			resolveErr = err
+		} else if !seen[i] {
+			seen[i] = true
+			ix.Columns = append(ix.Columns, i)
+		}
+	}
+
+	keys := make([]expression, len(stmt.IndexedColumns))
+	for i, ic := range stmt.IndexedColumns {
+		e := ic.Expr
+		if ic.ColumnName != nil {
+			e = &ast.Expr{ColumnName: ic.ColumnName}
+		}
+		p, err := command.Expr(e)
+		if err != nil {
+			return ix, err
+		}
+		walk(p, use)
+		if keys[i], err = x.expr(p, s); err != nil {
+			return ix, err
+		}
+		col := index.Column{Desc: ic.Desc != nil}
+		name := keys[i].collation
+		if ic.Collate != nil {
+			name = token.Unquote(ic.CollationName)
+		}
+		if name != "" {
+			coll, ok := index.CollationByName(name)
+			if !ok {
+				return ix, fmt.Errorf("%w: %v", ErrNoSuchCollation, name)
+			}
+			col.Collation = coll
+		}
+		ix.Definition.Columns = append(ix.Definition.Columns, col)
+	}
+	if resolveErr != nil {
+		return ix, resolveErr
+	}
+	ix.Definition.Key = func(row constraint.Row) (index.Key, error) {
+		key := make(index.Key, len(keys))
+		for i, k := range keys {
+			v, err := k.eval(&frame{row: row})
+			if err != nil {
+				return nil, err
+			}
+			key[i] = constraint.Encode(v)
+		}
+		return key, nil
+	}
+
+	if stmt.Expr != nil {
+		p, err := command.Expr(stmt.Expr)
+		if err != nil {
+			return ix, err
+		}
+		walk(p, use)
+		if resolveErr != nil {
+			return ix, resolveErr
+		}
+		where, err := x.expr(p, s)
+		if err != nil {
+			return ix, err
+		}
+		ix.Definition.Where = func(row constraint.Row) (bool, error) {
+			v, err := where.eval(&frame{row: row})
+			if err != nil {
+				return false, err
+			}
+			truth, _ := function.Truth(v)
+			return truth, nil
+		}
+	}
+	return ix, nil
+}
+
+// createIndex executes CREATE INDEX. The index is built from the rows of its
+// table, and recorded in the catalog of the database of the table.
+func (x *execution) createIndex(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.CreateIndexStmt
+	db, s, t, err := x.table(token.Unquote(stmt.SchemaName), token.Unquote(stmt.TableName))
+	if err != nil {
+		return nil, err
+	}
+	name := token.Unquote(stmt.IndexName)
+	if s.hasIndex(name) {
+		if stmt.If != nil {
+			return table{}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", index.ErrExists, name)
+	}
+	if _, exists := s.table(name); exists || s.hasView(name) {
+		return nil, fmt.Errorf("%w: %v", alter.ErrTableExists, name)
+	}
+	if strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+		return nil, fmt.Errorf("%w: %v", ErrReservedName, name)
+	}
+
+	ix, err := defineIndex(stmt, t)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.t.CreateIndex(ix, false); err != nil {
+		return nil, indexError(t, name, err)
+	}
+	// like SQLite, the definition is stored without IF NOT EXISTS and the
+	// schema name
+	sql := "CREATE INDEX "
+	if stmt.Unique != nil {
+		sql = "CREATE UNIQUE INDEX "
+	}
+	sql += stmt.IndexName.Value() + " " + cmd.Text(stmt.On)
+
+	c := catalog{s.store.tx}
+	id, err := c.nextID()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.put(object{typ: typeIndex, name: name, table: t.name, id: id, sql: sql}); err != nil {
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
+
+// dropIndex executes DROP INDEX. The entries of the index are deleted, and it
+// is removed from the catalog.
+func (x *execution) dropIndex(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.DropIndexStmt
+	name := token.Unquote(stmt.IndexName)
+	db, s, err := x.resolve(token.Unquote(stmt.SchemaName), name, (*schema).hasIndex)
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		if stmt.If != nil {
+			return table{}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", index.ErrNotExist, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	o := s.indexes[strings.ToLower(name)]
+	t, ok := s.table(o.table)
+	if !ok {
+		return nil, fmt.Errorf("%w: no such table: %v", ErrCorrupt, o.table)
+	}
+	if err := t.t.DropIndex(o.name, false); err != nil {
+		return nil, err
+	}
+	if err := (catalog{s.store.tx}).delete(o.name); err != nil {
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
+
+// reindex executes REINDEX, which rebuilds the secondary indexes of all
+// tables, of a table, or a single index. Like in SQLite, a name without a
+// schema name is the name of a collation, unless a table or index has it. All
+// indexes are rebuilt for a collation, since rebuilding an index that doesn't
+// use it has no effect.
+func (x *execution) reindex(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.ReindexStmt
+	if name := cmd.Name; name != "" {
+		_, s, err := x.resolve(token.Unquote(stmt.SchemaName), name, func(s *schema, name string) bool {
+			_, ok := s.table(name)
+			return ok || s.hasIndex(name)
+		})
+		_, collation := index.CollationByName(name)
+		switch {
+		case err == nil:
+			if t, ok := s.table(name); ok {
+				return table{}, rebuild(t, "")
+			}
+			o := s.indexes[strings.ToLower(name)]
+			t, ok := s.table(o.table)
+			if !ok {
+				return nil, fmt.Errorf("%w: no such table: %v", ErrCorrupt, o.table)
+			}
+			return table{}, rebuild(t, o.name)
+		case !errors.Is(err, attach.ErrNoSuchTable):
+			return nil, err
+		case stmt.CollationName == nil || !collation:
+			return nil, fmt.Errorf("%w: %v", ErrReindexObject, name)
+		}
+	}
+
+	for _, db := range x.e.session.Databases() {
+		s, err := x.schema(db)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range s.tables {
+			if err := rebuild(t, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return table{}, nil
+}
+
+// rebuild rebuilds the secondary index with the given name of the given
+// table, or all of them if the name is empty.
+func rebuild(t *storedTable, name string) error {
+	names := []string{name}
+	if name == "" {
+		names = nil
+		for _, ix := range t.t.Indexes().Indexes() {
+			names = append(names, ix.Definition().Name)
+		}
+	}
+	for _, name := range names {
+		if err := t.t.Reindex(name); err != nil {
+			return indexError(t, name, err)
+		}
+	}
+	return nil
+}
+
+// indexError converts an error of building the index with the given name to
+// a violation of a UNIQUE constraint, if the index is unique and the rows of
+// the table are not.
+func indexError(t *storedTable, name string, err error) error {
+	if !errors.Is(err, index.ErrUniqueViolation) {
+		return err
+	}
+	return &constraint.Violation{
+		Kind:       constraint.Unique,
+		Table:      t.name,
+		Constraint: "index '" + name + "'",
+		Conflict:   constraint.Abort,
+	}
+}
@@ -0,0 +1,909 @@
+package executor
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/stats"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/executor/profile"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// maxRelations is the maximum amount of relations of a join, that the
+// planner chooses the order of.
+const maxRelations = 64
+
+// lookupColumns returns the positions of the columns of the table, that rows
+// can be looked up with in the given index. These are the first keys of the
+// index, that are columns of the table with the BINARY collation, which is
+// the collation of the comparisons, that the planner uses indexes for. Rows
+// cannot be looked up in a partial index.
+func lookupColumns(stmt *ast.CreateIndexStmt, t *storedTable) []int {
+	if stmt.Expr != nil {
+		return nil
+	}
+	var cols []int
+	for _, ic := range stmt.IndexedColumns {
+		name := token.Unquote(ic.ColumnName)
+		if ic.ColumnName == nil {
+			e, err := command.Expr(ic.Expr)
+			if err != nil || e.Op != planner.ColumnExpr {
+				return cols
+			}
+			name = e.Column.Name
+		}
+		i := t.column(name)
+		if i < 0 {
+			return cols
+		}
+		collation := t.columns[i].collation
+		if ic.Collate != nil {
+			collation = token.Unquote(ic.CollationName)
+		}
+		if !binaryCollation(collation) {
+			return cols
+		}
+		cols = append(cols, i)
+	}
+	return cols
+}
+
+// binaryCollation reports whether the collation with the given name is BINARY.
+func binaryCollation(collation string) bool {
+	return collation == "" || strings.EqualFold(collation, "BINARY")
+}
+
+// converted reports whether a comparison of the given operands converts the
+// value of the first one, like comparisonAffinity.
+func converted(e, other expression) bool {
+	return numeric(other) && !numeric(e) || other.typed && other.affinity == function.Text && !e.typed
+}
+
+// joinRelation is a relation of a join, that is planned with statistics.
+type joinRelation struct {
+	node *planner.Node
+	// t is the table, that the relation scans, or nil if the relation is not
+	// a table, like a view or a subquery.
+	t  *storedTable
+	op operator
+	// profile measures op, while EXPLAIN ANALYZE runs the statement, or is
+	// nil.
+	profile *profile.Operator
+	// offset is the position of the first column of the relation in the
+	// rows of the join.
+	offset int
+}
+
+// conjunct is a term of the AND of the predicates of a join.
+type conjunct struct {
+	e    *planner.Expr
+	expr expression
+	// set has a bit for every relation, that the conjunct refers to.
+	set     uint64
+	applied bool
+}
+
+// equality is an equality filter of a column, that an index can look up. The
+// column is equal to the value of the expression, converted by conv.
+type equality struct {
+	expr expression
+	conv func(function.Value) function.Value
+}
+
+// joinPlan is an inner join of relations, whose order, access paths and join
+// algorithms are chosen by the cost-based planner with the statistics of the
+// tables.
+type joinPlan struct {
+	x         *execution
+	outer     *scope
+	rels      []*joinRelation
+	conjuncts []*conjunct
+	// cols are the columns of all relations in the order of the logical
+	// plan. Every operator of the join produces rows with all of them, in
+	// which the relations, that it has not joined, are NULL.
+	cols  []column
+	query planner.Query
+	// equal holds the equality filters of every relation by column.
+	equal []map[int]equality
+}
+
+// planJoin compiles the given Selection or inner Join with the cost-based
+// planner, if any table, that it joins, has been analyzed. Like SQLite
+// without statistics, the joins of tables without statistics are run in the
+// order of the query. It returns false, if the node is not planned.
+func (x *execution) planJoin(n *planner.Node, outer *scope) (operator, bool, error) {
+	if x.e == nil {
+		return nil, false, nil
+	}
+	var leaves []*planner.Node
+	var preds []*planner.Expr
+	flatten(n, &leaves, &preds)
+	if len(leaves) > maxRelations {
+		return nil, false, nil
+	}
+
+	p := &joinPlan{x: x, outer: outer}
+	analyzed := false
+	for i, leaf := range leaves {
+		rel := &joinRelation{node: leaf}
+		r := planner.Relation{Name: strconv.Itoa(i)}
+		if s, t := x.scanned(leaf); t != nil {
+			rel.t = t
+			r.Stats, _ = s.stats.Table(t.name)
+			r.Indexes = lookupIndexes(t, r.Stats)
+			analyzed = analyzed || r.Stats != nil
+		}
+		p.rels = append(p.rels, rel)
+		p.query.Relations = append(p.query.Relations, r)
+	}
+	if !analyzed {
+		return nil, false, nil
+	}
+
+	for _, rel := range p.rels {
+		op, err := x.node(rel.node, outer)
+		if err != nil {
+			return nil, false, err
+		}
+		rel.op, rel.offset = op, len(p.cols)
+		rel.profile = profileOf(op)
+		p.cols = append(p.cols, op.columns()...)
+		p.equal = append(p.equal, make(map[int]equality))
+	}
+	s := &scope{columns: p.cols, outer: outer}
+	for _, e := range preds {
+		expr, err := x.expr(e, s)
+		if err != nil {
+			return nil, false, err
+		}
+		c := &conjunct{e: e, expr: expr, set: p.set(e)}
+		p.conjuncts = append(p.conjuncts, c)
+		if err := p.classify(c); err != nil {
+			return nil, false, err
+		}
+	}
+
+	plan, err := planner.Plan(p.query)
+	if err != nil {
+		return nil, false, err
+	}
+	op, display, _ := p.compile(plan)
+	if x.plans != nil {
+		x.plans[n] = display
+	}
+	if root, ok := op.(*profiled); ok {
+		// the profile of the node is the profile of the root of the join,
+		// and keeps the subqueries of the predicates
+		p.adopt(root.profile)
+		op = root.operator
+	}
+	return op, true, nil
+}
+
+// adopt replaces the profile of the node of the logical plan, that is being
+// compiled, with the given profile of the join.
+func (p *joinPlan) adopt(root *profile.Operator) {
+	rels := make(map[*profile.Operator]bool)
+	for _, rel := range p.rels {
+		rels[rel.profile] = true
+	}
+	children := root.Children
+	for _, child := range p.x.profile.Children {
+		if !rels[child] {
+			children = append(children, child)
+		}
+	}
+	*p.x.profile = *root
+	p.x.profile.Children = children
+}
+
+// measure returns the given operator of the join, measured in a profile,
+// that the given node describes, while EXPLAIN ANALYZE runs the statement.
+// The profiles of the given operators are the children of the profile.
+func (p *joinPlan) measure(op operator, display *planner.Node, children ...operator) operator {
+	if p.x.profile == nil {
+		return op
+	}
+	prof := &profile.Operator{Detail: display.Detail(), Rows: display.Rows, Cost: display.Cost}
+	for _, child := range children {
+		if c := profileOf(child); c != nil {
+			prof.Children = append(prof.Children, c)
+		}
+	}
+	return measure(op, prof)
+}
+
+// profileOf returns the profile, that measures the given operator of a
+// join, or nil.
+func profileOf(op operator) *profile.Operator {
+	switch op := op.(type) {
+	case *profiled:
+		return op.profile
+	case *placed:
+		return profileOf(op.child)
+	}
+	return nil
+}
+
+// flatten adds the relations of the given inner joins and the terms of their
+// predicates, and of the predicates of the Selections above them.
+func flatten(n *planner.Node, leaves *[]*planner.Node, preds *[]*planner.Expr) {
+	switch {
+	case n.Op == planner.Selection:
+		*preds = appendConjuncts(*preds, n.Predicate)
+		flatten(n.Children[0], leaves, preds)
+	case n.Op == planner.Join && n.JoinType == planner.InnerJoin:
+		if n.Predicate != nil {
+			*preds = appendConjuncts(*preds, n.Predicate)
+		}
+		flatten(n.Children[0], leaves, preds)
+		flatten(n.Children[1], leaves, preds)
+	default:
+		*leaves = append(*leaves, n)
+	}
+}
+
+// appendConjuncts appends the terms of the AND of the given expression.
+func appendConjuncts(preds []*planner.Expr, e *planner.Expr) []*planner.Expr {
+	if e.Op != planner.AndExpr {
+		return append(preds, e)
+	}
+	for _, arg := range e.Args {
+		preds = appendConjuncts(preds, arg)
+	}
+	return preds
+}
+
+// scanned returns the table, that the given leaf of a join scans, and its
+// schema, or nil, if the leaf does not scan a table. A leaf, whose table
+// cannot be found, fails when it is compiled.
+func (x *execution) scanned(n *planner.Node) (*schema, *storedTable) {
+	if n.Op != planner.FullScan {
+		return nil, nil
+	}
+	name := n.Relation
+	if n.Table != "" {
+		name = n.Table
+	}
+	if _, ok := x.cte(name); ok && n.Schema == "" {
+		return nil, nil
+	}
+	_, s, err := x.resolve(n.Schema, name, hasRelation)
+	if err != nil {
+		return nil, nil
+	}
+	t, _ := s.table(name)
+	return s, t
+}
+
+// lookupIndexes returns the secondary indexes of the given table, that rows
+// can be looked up with, and their statistics.
+func lookupIndexes(t *storedTable, ts *stats.Table) []planner.Index {
+	var indexes []planner.Index
+	for _, ix := range t.t.Indexes().Indexes() {
+		def := ix.Definition()
+		cols := t.lookups[strings.ToLower(def.Name)]
+		if len(cols) == 0 {
+			continue
+		}
+		pi := planner.Index{
+			Name:    def.Name,
+			Columns: cols,
+			Unique:  def.Unique && len(cols) == len(def.Columns),
+		}
+		if ts != nil {
+			if is, ok := ts.Indexes[strings.ToLower(def.Name)]; ok {
+				pi.Stats = &is
+			}
+		}
+		indexes = append(indexes, pi)
+	}
+	return indexes
+}
+
+// relationAt returns the position of the relation, that the column at the
+// given position of the rows of the join belongs to.
+func (p *joinPlan) relationAt(pos int) int {
+	rel := 0
+	for i, r := range p.rels {
+		if r.offset <= pos {
+			rel = i
+		}
+	}
+	return rel
+}
+
+// set returns the relations, that the given expression refers to. An
+// expression with a subquery refers to all relations.
+func (p *joinPlan) set(e *planner.Expr) uint64 {
+	local := &scope{columns: p.cols}
+	var set uint64
+	walk(e, func(e *planner.Expr) {
+		switch {
+		case e.Subquery != nil:
+			set = 1<<uint(len(p.rels)) - 1
+		case e.Op == planner.ColumnExpr:
+			// columns of the outer scope are not found
+			if _, pos, err := local.resolve(e.Column); err == nil {
+				set |= 1 << uint(p.relationAt(pos))
+			}
+		}
+	})
+	return set
+}
+
+// column returns the relation and the position in the relation of the
+// column, that the given expression is, if it is a column of the join.
+func (p *joinPlan) column(e *planner.Expr) (planner.ColumnRef, bool) {
+	if e.Op != planner.ColumnExpr {
+		return planner.ColumnRef{}, false
+	}
+	_, pos, err := (&scope{columns: p.cols}).resolve(e.Column)
+	if err != nil {
+		return planner.ColumnRef{}, false
+	}
+	rel := p.relationAt(pos)
+	return planner.ColumnRef{Relation: rel, Column: pos - p.rels[rel].offset}, true
+}
+
+// mirrored are the comparisons, that are equal to the comparisons with
+// swapped operands.
+var mirrored = map[planner.ExprOp]planner.ExprOp{
+	planner.EqExpr: planner.EqExpr,
+	planner.LtExpr: planner.GtExpr,
+	planner.LeExpr: planner.GeExpr,
+	planner.GtExpr: planner.LtExpr,
+	planner.GeExpr: planner.LeExpr,
+}
+
+// classify adds the given conjunct to the query of the planner as a filter
+// of a single relation, or as a join condition between two relations.
+func (p *joinPlan) classify(c *conjunct) error {
+	e := c.e
+	_, comparison := mirrored[e.Op]
+	switch {
+	case e.Op == planner.IsNullExpr:
+		if ref, ok := p.column(e.Args[0]); ok {
+			p.query.Filters = append(p.query.Filters, planner.Filter{Column: ref, Op: planner.IsNull})
+			return nil
+		}
+	case comparison:
+		l, r := e.Args[0], e.Args[1]
+		lref, lok := p.column(l)
+		rref, rok := p.column(r)
+		switch {
+		case lok && rok && lref.Relation != rref.Relation:
+			if e.Op == planner.EqExpr {
+				return p.join(lref, rref, l, r)
+			}
+			return nil
+		case lok && p.set(r) == 0:
+			return p.filter(lref, e.Op, l, r)
+		case rok && p.set(l) == 0:
+			return p.filter(rref, mirrored[e.Op], r, l)
+		}
+	}
+	for rel := range p.rels {
+		if c.set == 1<<uint(rel) {
+			p.query.Filters = append(p.query.Filters, planner.Filter{
+				Column: planner.ColumnRef{Relation: rel},
+				Op:     planner.Other,
+			})
+		}
+	}
+	return nil
+}
+
+// join adds a join condition between the given columns, if the columns can be
+// compared by their values, without conversions and collations, which is how
+// hash joins and indexes find equal values.
+func (p *joinPlan) join(lref, rref planner.ColumnRef, l, r *planner.Expr) error {
+	s := &scope{columns: p.cols, outer: p.outer}
+	le, err := p.x.expr(l, s)
+	if err != nil {
+		return err
+	}
+	re, err := p.x.expr(r, s)
+	if err != nil {
+		return err
+	}
+	if binaryCollation(collationName(le, re)) && !converted(le, re) && !converted(re, le) {
+		p.query.Joins = append(p.query.Joins, planner.JoinCondition{Left: lref, Right: rref})
+	}
+	return nil
+}
+
+// filter adds a filter of the given column, that is compared with the given
+// operation with the value of the given expression, which does not refer to
+// the join. The value is known while planning, if the expression has no
+// columns.
+func (p *joinPlan) filter(ref planner.ColumnRef, op planner.ExprOp, col, value *planner.Expr) error {
+	s := &scope{columns: p.cols, outer: p.outer}
+	ce, err := p.x.expr(col, s)
+	if err != nil {
+		return err
+	}
+	ve, err := p.x.expr(value, s)
+	if err != nil {
+		return err
+	}
+	_, conv := comparisonAffinity(ce, ve)
+	var known []byte
+	if constantExpr(value) {
+		if v, err := ve.eval(&frame{}); err == nil && v != nil {
+			known = constraint.Encode(conv(v))
+		}
+	}
+
+	f := planner.Filter{Column: ref, Op: planner.Other}
+	switch op {
+	case planner.EqExpr:
+		if !binaryCollation(collationName(ce, ve)) || converted(ce, ve) {
+			break
+		}
+		f.Op, f.Value = planner.Equal, known
+		if _, ok := p.equal[ref.Relation][ref.Column]; !ok {
+			p.equal[ref.Relation][ref.Column] = equality{expr: ve, conv: conv}
+		}
+	case planner.LtExpr, planner.LeExpr:
+		f.Op, f.High = planner.Range, known
+	case planner.GtExpr, planner.GeExpr:
+		f.Op, f.Low = planner.Range, known
+	}
+	p.query.Filters = append(p.query.Filters, f)
+	return nil
+}
+
+// constantExpr reports whether the given expression has neither columns nor
+// subqueries.
+func constantExpr(e *planner.Expr) bool {
+	constant := true
+	walk(e, func(e *planner.Expr) {
+		if e.Op == planner.ColumnExpr || e.Subquery != nil {
+			constant = false
+		}
+	})
+	return constant
+}
+
+// take returns the conjuncts, that only refer to the given relations, and
+// have not been applied, as a single predicate, or nil. The conjuncts are
+// applied by the caller.
+func (p *joinPlan) take(set uint64) (*expression, *planner.Expr) {
+	var exprs []expression
+	var terms []*planner.Expr
+	for _, c := range p.conjuncts {
+		if c.applied || c.set&^set != 0 {
+			continue
+		}
+		c.applied = true
+		exprs = append(exprs, c.expr)
+		terms = append(terms, c.e)
+	}
+	switch len(exprs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &exprs[0], terms[0]
+	}
+	predicate := expression{eval: func(f *frame) (function.Value, error) {
+		ok, err := holds(exprs, f)
+		return boolValue(ok), err
+	}}
+	return &predicate, &planner.Expr{Op: planner.AndExpr, Args: terms}
+}
+
+// holds reports whether all given predicates are true.
+func holds(preds []expression, f *frame) (bool, error) {
+	for _, e := range preds {
+		v, err := e.eval(f)
+		if err != nil {
+			return false, err
+		}
+		if truth, _ := function.Truth(v); !truth {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// relation returns the position of the relation, that the given node of the
+// plan of the planner reads.
+func (p *joinPlan) relation(n *planner.Node) int {
+	rel, _ := strconv.Atoi(n.Relation)
+	return rel
+}
+
+// spans returns the positions of the columns of the given relations in the
+// rows of the join, as pairs of the first and the last position plus one.
+func (p *joinPlan) spans(set uint64) [][2]int {
+	var spans [][2]int
+	for i, rel := range p.rels {
+		if set&(1<<uint(i)) != 0 {
+			spans = append(spans, [2]int{rel.offset, rel.offset + len(rel.op.columns())})
+		}
+	}
+	return spans
+}
+
+// compile compiles the given node of the plan of the planner. It returns the
+// relations, that the operator joins, and the node, that describes what the
+// operator does, in which the relations are described like in the logical
+// plan.
+func (p *joinPlan) compile(n *planner.Node) (operator, *planner.Node, uint64) {
+	if len(n.Children) == 0 {
+		rel := p.relation(n)
+		set := uint64(1) << uint(rel)
+		op, display := p.leaf(rel, n)
+		if prof := profileOf(op); prof != nil {
+			prof.Detail, prof.Rows, prof.Cost = display.Detail(), display.Rows, display.Cost
+		} else {
+			op = p.measure(op, display)
+		}
+		if predicate, term := p.take(set); predicate != nil {
+			child := op
+			display = &planner.Node{Op: planner.Selection, Predicate: term, Children: []*planner.Node{display}}
+			op = p.measure(&filter{child: child, predicate: *predicate}, display, child)
+		}
+		return op, display, set
+	}
+
+	left, ldisplay, lset := p.compile(n.Children[0])
+	var right operator
+	var rdisplay *planner.Node
+	var rset uint64
+	kind := n.Op
+	if kind == planner.IndexNestedLoopJoin {
+		rel := p.relation(n.Children[1])
+		rset = 1 << uint(rel)
+		if scan, ok := p.lookup(rel, n.Children[1], lset); ok {
+			right = scan
+			rdisplay = p.described(rel, n.Children[1])
+		} else {
+			// the index cannot look up the rows, so they are scanned
+			kind = planner.NestedLoopJoin
+			right, rdisplay, rset = p.compile(&planner.Node{Op: planner.FullScan, Relation: n.Children[1].Relation})
+		}
+	} else {
+		right, rdisplay, rset = p.compile(n.Children[1])
+	}
+
+	set := lset | rset
+	j := joined{left: left, cols: p.cols, spans: p.spans(rset)}
+	var term *planner.Expr
+	j.predicate, term = p.take(set)
+	display := &planner.Node{
+		Op:        kind,
+		Children:  []*planner.Node{ldisplay, rdisplay},
+		Predicate: term,
+		Rows:      n.Rows,
+		Cost:      n.Cost,
+	}
+	switch kind {
+	case planner.HashJoin:
+		h := &hashJoin{joined: j, right: right, budget: p.x.budget}
+		for _, c := range p.query.Joins {
+			l, r := c.Left, c.Right
+			if lset&(1<<uint(r.Relation)) != 0 {
+				l, r = r, l
+			}
+			if lset&(1<<uint(l.Relation)) == 0 || rset&(1<<uint(r.Relation)) == 0 {
+				continue
+			}
+			h.probe = append(h.probe, p.rels[l.Relation].offset+l.Column)
+			h.build = append(h.build, p.rels[r.Relation].offset+r.Column)
+		}
+		return p.measure(h, display, left, right), display, set
+	case planner.IndexNestedLoopJoin:
+		ij := &indexJoin{joined: j, right: right.(*indexScan)}
+		op := p.measure(ij, display, left)
+		if prof := profileOf(op); prof != nil {
+			// every lookup is a loop of the right child
+			ij.lookups = &profile.Operator{Detail: rdisplay.Detail(), Rows: rdisplay.Rows, Cost: rdisplay.Cost}
+			prof.Children = append(prof.Children, ij.lookups)
+		}
+		return op, display, set
+	}
+	return p.measure(&loopJoin{joined: j, right: right}, display, left, right), display, set
+}
+
+// leaf compiles the given leaf of the plan of the planner, which reads the
+// given relation.
+func (p *joinPlan) leaf(rel int, n *planner.Node) (operator, *planner.Node) {
+	r := p.rels[rel]
+	if n.Op == planner.IndexScan {
+		if scan, ok := p.search(rel, n.Index); ok {
+			return scan, p.described(rel, n)
+		}
+		n = &planner.Node{Op: planner.FullScan, Relation: n.Relation, Rows: n.Rows, Cost: n.Cost}
+	}
+	return &placed{child: r.op, offset: r.offset, cols: p.cols}, p.described(rel, n)
+}
+
+// described returns the node, that describes the given leaf of the plan of
+// the planner, which reads the given relation.
+func (p *joinPlan) described(rel int, n *planner.Node) *planner.Node {
+	r := p.rels[rel]
+	if r.t == nil {
+		return r.node
+	}
+	display := *r.node
+	display.Op, display.Index, display.Rows, display.Cost = n.Op, n.Index, n.Rows, n.Cost
+	return &display
+}
+
+// search returns a scan of the given index of the given relation, that looks
+// up the rows with the values of the equality filters of its first columns.
+// It returns false, if the first column has no such filter, since rows can
+// only be looked up by equal values.
+func (p *joinPlan) search(rel int, name string) (*indexScan, bool) {
+	scan, ok := p.indexScan(rel, name)
+	if !ok {
+		return nil, false
+	}
+	scan.keys = p.prefix(rel, name, 0)
+	return scan, len(scan.keys) > 0
+}
+
+// lookup returns a scan of the given index of the given relation, that looks
+// up the rows, that match the row of the given outer relations, whose column
+// is joined with the first column of the index. Equality filters of the next
+// columns of the index look up fewer rows.
+func (p *joinPlan) lookup(rel int, n *planner.Node, outer uint64) (*indexScan, bool) {
+	scan, ok := p.indexScan(rel, n.Index)
+	if !ok {
+		return nil, false
+	}
+	first := p.rels[rel].t.lookups[strings.ToLower(n.Index)][0]
+	for _, c := range p.query.Joins {
+		l, r := c.Left, c.Right
+		if l.Relation == rel {
+			l, r = r, l
+		}
+		if r.Relation != rel || r.Column != first || outer&(1<<uint(l.Relation)) == 0 {
+			continue
+		}
+		pos := p.rels[l.Relation].offset + l.Column
+		scan.keys = append([]equality{{
+			expr: expression{eval: func(f *frame) (function.Value, error) { return f.row[pos], nil }},
+			conv: identity,
+		}}, p.prefix(rel, n.Index, 1)...)
+		return scan, true
+	}
+	return nil, false
+}
+
+// indexScan returns a scan of the given index of the given relation without
+// keys.
+func (p *joinPlan) indexScan(rel int, name string) (*indexScan, bool) {
+	r := p.rels[rel]
+	if r.t == nil {
+		return nil, false
+	}
+	ix, ok := r.t.t.Indexes().Index(name)
+	if !ok || len(r.t.lookups[strings.ToLower(name)]) == 0 {
+		return nil, false
+	}
+	return &indexScan{t: r.t, ix: ix, offset: r.offset, cols: p.cols}, true
+}
+
+// prefix returns the equality filters of the columns of the given index of
+// the given relation, that rows can be looked up with, starting at the given
+// column of the index.
+func (p *joinPlan) prefix(rel int, name string, from int) []equality {
+	var keys []equality
+	for _, col := range p.rels[rel].t.lookups[strings.ToLower(name)][from:] {
+		eq, ok := p.equal[rel][col]
+		if !ok {
+			break
+		}
+		keys = append(keys, eq)
+	}
+	return keys
+}
+
+// placed passes on the rows of a relation of a join as rows of the join.
+type placed struct {
+	child  operator
+	offset int
+	cols   []column
+}
+
+func (p *placed) columns() []column { return p.cols }
+
+func (p *placed) run(outer *frame, emit func(row []function.Value) error) error {
+	return p.child.run(outer, func(row []function.Value) error {
+		values := make([]function.Value, len(p.cols))
+		copy(values[p.offset:], row)
+		return emit(values)
+	})
+}
+
+// indexScan looks up the rows of a table, whose keys in an index start with
+// the values of the keys of the scan, as rows of a join.
+type indexScan struct {
+	t      *storedTable
+	ix     *index.Index[constraint.Row]
+	keys   []equality
+	offset int
+	cols   []column
+}
+
+func (s *indexScan) columns() []column { return s.cols }
+
+func (s *indexScan) run(outer *frame, emit func(row []function.Value) error) error {
+	return s.lookup(&frame{row: make([]function.Value, len(s.cols)), outer: outer}, emit)
+}
+
+// lookup looks up the rows with the values of the keys, which are evaluated
+// with the given frame. NULL is equal to no value.
+func (s *indexScan) lookup(f *frame, emit func(row []function.Value) error) error {
+	key := make(index.Key, len(s.keys))
+	for i, k := range s.keys {
+		v, err := k.expr.eval(f)
+		if err != nil {
+			return err
+		}
+		if v = k.conv(v); v == nil {
+			return nil
+		}
+		key[i] = constraint.Encode(v)
+	}
+	var ids []index.RowID
+	if err := s.ix.Lookup(key, func(id index.RowID) bool {
+		ids = append(ids, id)
+		return true
+	}); err != nil {
+		return err
+	}
+
+	rows := s.t.t.Rows()
+	for _, id := range ids {
+		row, ok, err := rows.Get(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		values := make([]function.Value, len(s.cols))
+		copy(values[s.offset:], row)
+		values[s.offset+len(row)] = int64(id)
+		if err := emit(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joined holds what all joins of a joinPlan have in common.
+type joined struct {
+	left operator
+	// spans are the positions of the columns of the relations of the right
+	// child, which are copied to the rows of the left child.
+	spans [][2]int
+	// predicate holds the conjuncts, that are applied to the joined rows,
+	// or is nil.
+	predicate *expression
+	cols      []column
+}
+
+func (j *joined) columns() []column { return j.cols }
+
+// emit merges the given rows of the children, and emits the joined row, if
+// it satisfies the predicate.
+func (j *joined) emit(outer *frame, left, right []function.Value, emit func(row []function.Value) error) error {
+	row := append([]function.Value{}, left...)
+	for _, s := range j.spans {
+		copy(row[s[0]:s[1]], right[s[0]:s[1]])
+	}
+	if j.predicate != nil {
+		v, err := j.predicate.eval(&frame{row: row, outer: outer})
+		if err != nil {
+			return err
+		}
+		if truth, _ := function.Truth(v); !truth {
+			return nil
+		}
+	}
+	return emit(row)
+}
+
+// loopJoin joins every row of its left child with every row of its right
+// child, which is read once per run.
+type loopJoin struct {
+	joined
+	right operator
+}
+
+func (j *loopJoin) run(outer *frame, emit func(row []function.Value) error) error {
+	inner, err := collect(j.right, outer)
+	if err != nil {
+		return err
+	}
+	return j.left.run(outer, func(row []function.Value) error {
+		for _, r := range inner {
+			if err := j.emit(outer, row, r, emit); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// indexJoin looks up the matching rows of its right child in an index for
+// every row of its left child.
+type indexJoin struct {
+	joined
+	right *indexScan
+	// lookups measures the lookups of the right child, while EXPLAIN
+	// ANALYZE runs the statement, or is nil.
+	lookups *profile.Operator
+}
+
+func (j *indexJoin) run(outer *frame, emit func(row []function.Value) error) error {
+	return j.left.run(outer, func(row []function.Value) error {
+		lookup := func(emit func(r []function.Value) error) error {
+			return j.right.lookup(&frame{row: row, outer: outer}, emit)
+		}
+		join := func(r []function.Value) error {
+			return j.emit(outer, row, r, emit)
+		}
+		if j.lookups != nil {
+			return j.lookups.Run(lookup, join)
+		}
+		return lookup(join)
+	})
+}
+
+// hashJoin builds a hash table of the rows of its right child, and probes it
+// with the rows of its left child. If the rows of the right child don't fit
+// into the budget of the statement, the rows of both children are
+// partitioned on disk.
+type hashJoin struct {
+	joined
+	measured
+	right operator
+	// probe and build are the positions of the joined columns in the rows
+	// of the left and the right child.
+	probe, build []int
+	budget       *spill.Budget
+}
+
+func (j *hashJoin) run(outer *frame, emit func(row []function.Value) error) error {
+	h := spill.NewHashJoin(keyOf(j.build), keyOf(j.probe), j.budget)
+	join := func(probe, build spill.Row) error {
+		return j.emit(outer, probe, build, emit)
+	}
+	err := j.right.run(outer, h.Build)
+	if err == nil {
+		err = j.left.run(outer, func(row []function.Value) error {
+			return h.Probe(row, join)
+		})
+	}
+	if err != nil {
+		_ = h.Close()
+		return err
+	}
+	defer func() { j.spilled(h.Stats()) }()
+	return h.Finish(join)
+}
+
+// keyOf returns a function, that returns the values at the given positions
+// of a row.
+func keyOf(positions []int) func(row spill.Row) (spill.Row, error) {
+	return func(row spill.Row) (spill.Row, error) {
+		key := make(spill.Row, len(positions))
+		for i, pos := range positions {
+			key[i] = row[pos]
+		}
+		return key, nil
+	}
+}
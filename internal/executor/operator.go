@@ -0,0 +1,828 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/executor/profile"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// operator produces the rows of a node of a plan. The rows are passed to the
+// given function, which may stop the operator by returning an error. The
+// outer frame holds the row of the query, that a correlated subquery is
+// evaluated for, or is nil.
+type operator interface {
+	columns() []column
+	run(outer *frame, emit func(row []function.Value) error) error
+}
+
+// visible returns the columns, that are not hidden.
+func visible(columns []column) []column {
+	var result []column
+	for _, col := range columns {
+		if !col.hidden {
+			result = append(result, col)
+		}
+	}
+	return result
+}
+
+// node compiles the given node of a plan. The given scope is the scope of the
+// query, that the node is a subquery of, or nil. While EXPLAIN ANALYZE runs
+// the statement, the operator is measured.
+func (x *execution) node(n *planner.Node, outer *scope) (operator, error) {
+	if x.profile == nil {
+		return x.compile(n, outer)
+	}
+	parent := x.profile
+	p := &profile.Operator{Detail: n.Detail(), Rows: n.Rows, Cost: n.Cost}
+	parent.Children = append(parent.Children, p)
+	x.profile = p
+	op, err := x.compile(n, outer)
+	x.profile = parent
+	if err != nil {
+		return nil, err
+	}
+	return measure(op, p), nil
+}
+
+// compile compiles the given node of a plan, like node.
+func (x *execution) compile(n *planner.Node, outer *scope) (operator, error) {
+	switch n.Op {
+	case planner.FullScan:
+		return x.scan(n)
+	case planner.Selection:
+		if op, ok, err := x.planJoin(n, outer); ok || err != nil {
+			return op, err
+		}
+		return x.filter(n, outer)
+	case planner.Projection:
+		return x.projection(n, outer)
+	case planner.Limit:
+		child, err := x.node(n.Children[0], outer)
+		if err != nil {
+			return nil, err
+		}
+		return &limit{child: child, count: n.Count, offset: n.Offset}, nil
+	case planner.Join:
+		if n.JoinType == planner.InnerJoin {
+			if op, ok, err := x.planJoin(n, outer); ok || err != nil {
+				return op, err
+			}
+		}
+		return x.join(n, outer)
+	case planner.Sort:
+		return x.sort(n, outer)
+	case planner.Aggregate:
+		return x.aggregate(n, outer)
+	case planner.Distinct:
+		child, err := x.node(n.Children[0], outer)
+		if err != nil {
+			return nil, err
+		}
+		return &distinct{child: child, budget: x.budget}, nil
+	case planner.Values:
+		return x.values(n, outer)
+	case planner.Compound:
+		return x.compound(n, outer)
+	case planner.With:
+		return x.with(n, outer)
+	case planner.CommonTable:
+		return x.commonTable(n, n.Children[0], outer)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnsupported, n.Op)
+}
+
+// tableScan reads all rows of a table. Its columns are the columns of the
+// table, followed by the hidden row ID.
+type tableScan struct {
+	t    *storedTable
+	cols []column
+}
+
+func (x *execution) scan(n *planner.Node) (operator, error) {
+	name := n.Relation
+	if n.Table != "" {
+		name = n.Table
+	}
+	if t, ok := x.cte(name); ok && n.Schema == "" {
+		return x.cteScan(t, n), nil
+	}
+	_, s, err := x.resolve(n.Schema, name, hasRelation)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := s.table(name)
+	if !ok {
+		return x.viewScan(s, name, n)
+	}
+	cols := make([]column, len(t.columns))
+	for i, col := range t.columns {
+		col.relation = n.Relation
+		cols[i] = col
+	}
+	return &tableScan{t: t, cols: cols}, nil
+}
+
+func (s *tableScan) columns() []column { return s.cols }
+
+func (s *tableScan) run(_ *frame, emit func(row []function.Value) error) error {
+	return s.t.t.Rows().Scan(func(id index.RowID, row constraint.Row) error {
+		values := make([]function.Value, len(row)+1)
+		copy(values, row)
+		values[len(row)] = int64(id)
+		return emit(values)
+	})
+}
+
+// filter passes on the rows of its child, for which the predicate is true.
+type filter struct {
+	child     operator
+	predicate expression
+}
+
+func (x *execution) filter(n *planner.Node, outer *scope) (operator, error) {
+	child, err := x.node(n.Children[0], outer)
+	if err != nil {
+		return nil, err
+	}
+	predicate, err := x.expr(n.Predicate, &scope{columns: child.columns(), outer: outer})
+	if err != nil {
+		return nil, err
+	}
+	return &filter{child: child, predicate: predicate}, nil
+}
+
+func (f *filter) columns() []column { return f.child.columns() }
+
+func (f *filter) run(outer *frame, emit func(row []function.Value) error) error {
+	return f.child.run(outer, func(row []function.Value) error {
+		v, err := f.predicate.eval(&frame{row: row, outer: outer})
+		if err != nil {
+			return err
+		}
+		if truth, _ := function.Truth(v); truth {
+			return emit(row)
+		}
+		return nil
+	})
+}
+
+// projection computes the result columns of a SELECT. The columns of * are
+// copied from the row of its child.
+type projection struct {
+	child operator
+	cols  []column
+	exprs []expression
+}
+
+func (x *execution) projection(n *planner.Node, outer *scope) (operator, error) {
+	child, err := x.node(n.Children[0], outer)
+	if err != nil {
+		return nil, err
+	}
+	s := &scope{columns: child.columns(), outer: outer}
+	p := &projection{child: child}
+	for i, e := range n.Projections {
+		if e.Op == planner.StarExpr {
+			found := false
+			for j, col := range s.columns {
+				if col.hidden || col.aggregate != "" || e.Column.Relation != "" && !strings.EqualFold(col.relation, e.Column.Relation) {
+					continue
+				}
+				j := j
+				p.exprs = append(p.exprs, expression{eval: func(f *frame) (function.Value, error) {
+					return f.row[j], nil
+				}})
+				col.relation = n.Relation
+				p.cols = append(p.cols, col)
+				found = true
+			}
+			if !found && e.Column.Relation != "" {
+				return nil, fmt.Errorf("%w: %v", attach.ErrNoSuchTable, e.Column.Relation)
+			}
+			continue
+		}
+
+		compiled, err := x.expr(e, s)
+		if err != nil {
+			return nil, err
+		}
+		name := e.String()
+		if i < len(n.Columns) {
+			name = n.Columns[i]
+		}
+		p.exprs = append(p.exprs, compiled)
+		p.cols = append(p.cols, column{
+			relation:  n.Relation,
+			name:      name,
+			affinity:  compiled.affinity,
+			typed:     compiled.typed,
+			collation: compiled.collation,
+		})
+	}
+	return p, nil
+}
+
+func (p *projection) columns() []column { return p.cols }
+
+func (p *projection) run(outer *frame, emit func(row []function.Value) error) error {
+	return p.child.run(outer, func(row []function.Value) error {
+		f := &frame{row: row, outer: outer}
+		result := make([]function.Value, len(p.exprs))
+		for i, e := range p.exprs {
+			v, err := e.eval(f)
+			if err != nil {
+				return err
+			}
+			result[i] = v
+		}
+		return emit(result)
+	})
+}
+
+// limit passes on at most count rows of its child, after skipping offset
+// rows. A negative count passes on all rows.
+type limit struct {
+	child         operator
+	count, offset int64
+}
+
+func (l *limit) columns() []column { return l.child.columns() }
+
+func (l *limit) run(outer *frame, emit func(row []function.Value) error) error {
+	if l.count == 0 {
+		return nil
+	}
+	skipped, emitted := int64(0), int64(0)
+	stop := &stopError{}
+	err := l.child.run(outer, func(row []function.Value) error {
+		if skipped < l.offset {
+			skipped++
+			return nil
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+		emitted++
+		if l.count >= 0 && emitted >= l.count {
+			return stop
+		}
+		return nil
+	})
+	if err == error(stop) {
+		return nil
+	}
+	return err
+}
+
+// nestedLoopJoin joins every row of its left child with every row of its
+// right child, which is read once per run.
+type nestedLoopJoin struct {
+	left, right operator
+	joinType    planner.JoinType
+	// predicate is the join condition, or nil.
+	predicate *expression
+	cols      []column
+}
+
+func (x *execution) join(n *planner.Node, outer *scope) (operator, error) {
+	left, err := x.node(n.Children[0], outer)
+	if err != nil {
+		return nil, err
+	}
+	right, err := x.node(n.Children[1], outer)
+	if err != nil {
+		return nil, err
+	}
+	j := &nestedLoopJoin{left: left, right: right, joinType: n.JoinType}
+	both := append(append([]column{}, left.columns()...), right.columns()...)
+	if n.Predicate != nil {
+		predicate, err := x.expr(n.Predicate, &scope{columns: both, outer: outer})
+		if err != nil {
+			return nil, err
+		}
+		j.predicate = &predicate
+	}
+	j.cols = both
+	if n.JoinType == planner.SemiJoin || n.JoinType == planner.AntiJoin {
+		j.cols = left.columns()
+	}
+	return j, nil
+}
+
+func (j *nestedLoopJoin) columns() []column { return j.cols }
+
+func (j *nestedLoopJoin) run(outer *frame, emit func(row []function.Value) error) error {
+	var inner [][]function.Value
+	if err := j.right.run(outer, func(row []function.Value) error {
+		inner = append(inner, row)
+		return nil
+	}); err != nil {
+		return err
+	}
+	width := len(j.right.columns())
+
+	return j.left.run(outer, func(row []function.Value) error {
+		matched := false
+		for _, r := range inner {
+			joined := make([]function.Value, 0, len(row)+len(r))
+			joined = append(append(joined, row...), r...)
+			if j.predicate != nil {
+				v, err := j.predicate.eval(&frame{row: joined, outer: outer})
+				if err != nil {
+					return err
+				}
+				if truth, _ := function.Truth(v); !truth {
+					continue
+				}
+			}
+			matched = true
+			switch j.joinType {
+			case planner.SemiJoin:
+				return emit(row)
+			case planner.AntiJoin:
+				return nil
+			}
+			if err := emit(joined); err != nil {
+				return err
+			}
+		}
+		switch {
+		case !matched && j.joinType == planner.AntiJoin:
+			return emit(row)
+		case !matched && j.joinType == planner.LeftJoin:
+			return emit(append(append([]function.Value{}, row...), make([]function.Value, width)...))
+		}
+		return nil
+	})
+}
+
+// sorter orders the rows of its child. Rows that don't fit into the budget of
+// the statement are sorted in runs on disk, and merged.
+type sorter struct {
+	measured
+	child  operator
+	terms  []sortTerm
+	budget *spill.Budget
+}
+
+type sortTerm struct {
+	e          expression
+	collation  index.Collation
+	desc       bool
+	nullsFirst bool
+}
+
+func (x *execution) sort(n *planner.Node, outer *scope) (operator, error) {
+	child, err := x.node(n.Children[0], outer)
+	if err != nil {
+		return nil, err
+	}
+	s := &scope{columns: child.columns(), outer: outer}
+	op := &sorter{child: child, budget: x.budget}
+	for _, term := range n.Order {
+		e, err := x.expr(term.Expr, s)
+		if err != nil {
+			return nil, err
+		}
+		op.terms = append(op.terms, sortTerm{
+			e:          e,
+			collation:  collationOf(e, expression{}),
+			desc:       term.Desc,
+			nullsFirst: term.NullsFirst,
+		})
+	}
+	return op, nil
+}
+
+func (s *sorter) columns() []column { return s.child.columns() }
+
+func (s *sorter) run(outer *frame, emit func(row []function.Value) error) error {
+	// the sorted rows are the values of the terms, followed by the row
+	n := len(s.terms)
+	sorted := spill.NewSorter(func(a, b spill.Row) int {
+		for k, term := range s.terms {
+			if c := term.compare(a[k], b[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, s.budget)
+	err := s.child.run(outer, func(row []function.Value) error {
+		f := &frame{row: row, outer: outer}
+		keyed := make([]function.Value, n, n+len(row))
+		for i, term := range s.terms {
+			v, err := term.e.eval(f)
+			if err != nil {
+				return err
+			}
+			keyed[i] = v
+		}
+		return sorted.Add(append(keyed, row...))
+	})
+	if err != nil {
+		_ = sorted.Close()
+		return err
+	}
+	defer func() { s.spilled(sorted.Stats()) }()
+	return sorted.Sort(func(row spill.Row) error {
+		return emit(row[n:])
+	})
+}
+
+// compare compares two values of the term, in the order of the term.
+func (t sortTerm) compare(a, b function.Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil && t.nullsFirst, b == nil && !t.nullsFirst:
+		return -1
+	case a == nil, b == nil:
+		return 1
+	}
+	c := compare(a, b, t.collation)
+	if t.desc {
+		return -c
+	}
+	return c
+}
+
+// aggregate groups the rows of its child, and computes the aggregate
+// functions for every group. Its rows are the last row of every group,
+// followed by the results of the aggregate functions. The groups are
+// returned in the order of their keys. Groups that don't fit into the budget
+// of the statement are computed from partitions on disk.
+type aggregate struct {
+	measured
+	child   operator
+	groupBy []expression
+	calls   []aggregateCall
+	cols    []column
+	budget  *spill.Budget
+}
+
+type aggregateCall struct {
+	name     string
+	args     []expression
+	distinct bool
+}
+
+func (x *execution) aggregate(n *planner.Node, outer *scope) (operator, error) {
+	child, err := x.node(n.Children[0], outer)
+	if err != nil {
+		return nil, err
+	}
+	s := &scope{columns: child.columns(), outer: outer}
+	op := &aggregate{child: child, budget: x.budget}
+	if op.groupBy, err = x.exprs(n.GroupBy, s); err != nil {
+		return nil, err
+	}
+	op.cols = append([]column{}, child.columns()...)
+	for _, e := range n.Projections {
+		if _, err := functions.NewAggregate(e.Name, len(e.Args), e.Distinct); err != nil {
+			return nil, fmt.Errorf("%w: %v", err, e.Name)
+		}
+		args, err := x.exprs(e.Args, s)
+		if err != nil {
+			return nil, err
+		}
+		op.calls = append(op.calls, aggregateCall{name: e.Name, args: args, distinct: e.Distinct})
+		key := e.String()
+		op.cols = append(op.cols, column{name: key, aggregate: key})
+	}
+	return op, nil
+}
+
+func (a *aggregate) columns() []column { return a.cols }
+
+// newAggregates creates the aggregate functions of a new group.
+func (a *aggregate) newAggregates() ([]function.Aggregate, error) {
+	var aggs []function.Aggregate
+	for _, call := range a.calls {
+		agg, err := functions.NewAggregate(call.name, len(call.args), call.distinct)
+		if err != nil {
+			return nil, err
+		}
+		aggs = append(aggs, agg)
+	}
+	return aggs, nil
+}
+
+// lastRow is the first aggregate of every group, which holds the last row of
+// the group. The groups of DISTINCT hold their first row in it.
+type lastRow struct {
+	row []function.Value
+}
+
+func (l *lastRow) Step(args ...function.Value) error {
+	l.row = args
+	return nil
+}
+
+func (l *lastRow) Final() (function.Value, error) { return nil, nil }
+
+func (a *aggregate) run(outer *frame, emit func(row []function.Value) error) error {
+	var newErr error
+	groups := spill.NewHashAggregate(func(row spill.Row) (spill.Row, error) {
+		f := &frame{row: row, outer: outer}
+		key := make([]function.Value, len(a.groupBy))
+		for i, e := range a.groupBy {
+			v, err := e.eval(f)
+			if err != nil {
+				return nil, err
+			}
+			key[i] = v
+		}
+		return key, nil
+	}, func() []function.Aggregate {
+		aggs, err := a.newAggregates()
+		if err != nil {
+			newErr = err
+		}
+		return append([]function.Aggregate{&lastRow{}}, aggs...)
+	}, func(aggs []function.Aggregate, row spill.Row) error {
+		if newErr != nil {
+			return newErr
+		}
+		if err := aggs[0].Step(row...); err != nil {
+			return err
+		}
+		f := &frame{row: row, outer: outer}
+		for i, call := range a.calls {
+			args := make([]function.Value, len(call.args))
+			for j, arg := range call.args {
+				v, err := arg.eval(f)
+				if err != nil {
+					return err
+				}
+				args[j] = v
+			}
+			if err := aggs[i+1].Step(args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, a.budget)
+	if err := a.child.run(outer, groups.Add); err != nil {
+		_ = groups.Close()
+		return err
+	}
+	defer func() { a.spilled(groups.Stats()) }()
+
+	// the groups are sorted by their keys, which precede the rows
+	n := len(a.groupBy)
+	sorted := spill.NewSorter(func(a, b spill.Row) int {
+		for k := 0; k < n; k++ {
+			switch {
+			case a[k] == nil && b[k] == nil:
+				continue
+			case a[k] == nil:
+				return -1
+			case b[k] == nil:
+				return 1
+			}
+			if c := function.Compare(a[k], b[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, a.budget)
+	empty := true
+	err := groups.Groups(func(key spill.Row, aggs []function.Aggregate) error {
+		empty = false
+		row := append(append([]function.Value{}, key...), aggs[0].(*lastRow).row...)
+		row, err := final(row, aggs[1:])
+		if err != nil {
+			return err
+		}
+		return sorted.Add(row)
+	})
+	if err != nil {
+		_ = sorted.Close()
+		return err
+	}
+	if empty && n == 0 {
+		// an aggregate without GROUP BY has a single row, also without
+		// input rows
+		aggs, err := a.newAggregates()
+		if err == nil {
+			var row []function.Value
+			if row, err = final(make([]function.Value, len(a.child.columns())), aggs); err == nil {
+				err = sorted.Add(row)
+			}
+		}
+		if err != nil {
+			_ = sorted.Close()
+			return err
+		}
+	}
+	defer func() { a.spilled(sorted.Stats()) }()
+	return sorted.Sort(func(row spill.Row) error {
+		return emit(row[n:])
+	})
+}
+
+// final appends the results of the given aggregates to the given row.
+func final(row []function.Value, aggs []function.Aggregate) ([]function.Value, error) {
+	for _, agg := range aggs {
+		v, err := agg.Final()
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, v)
+	}
+	return row, nil
+}
+
+// distinct passes on the rows of its child, that are not equal to an earlier
+// row, in the order of the child. Rows that don't fit into the budget of the
+// statement are compared in partitions on disk.
+type distinct struct {
+	measured
+	child  operator
+	budget *spill.Budget
+}
+
+func (d *distinct) columns() []column { return d.child.columns() }
+
+func (d *distinct) run(outer *frame, emit func(row []function.Value) error) error {
+	// every row is followed by its position in the rows of the child, and
+	// the first row of every group is sorted by it
+	rows := spill.NewHashAggregate(func(row spill.Row) (spill.Row, error) {
+		return row[:len(row)-1], nil
+	}, func() []function.Aggregate {
+		return []function.Aggregate{&lastRow{}}
+	}, func(aggs []function.Aggregate, row spill.Row) error {
+		if first := aggs[0].(*lastRow); first.row == nil {
+			first.row = row
+		}
+		return nil
+	}, d.budget)
+	var position int64
+	err := d.child.run(outer, func(row []function.Value) error {
+		position++
+		return rows.Add(append(row[:len(row):len(row)], position))
+	})
+	if err != nil {
+		_ = rows.Close()
+		return err
+	}
+	defer func() { d.spilled(rows.Stats()) }()
+	sorted := spill.NewSorter(func(a, b spill.Row) int {
+		return function.Compare(a[len(a)-1], b[len(b)-1])
+	}, d.budget)
+	err = rows.Groups(func(_ spill.Row, aggs []function.Aggregate) error {
+		return sorted.Add(aggs[0].(*lastRow).row)
+	})
+	if err != nil {
+		_ = sorted.Close()
+		return err
+	}
+	defer func() { d.spilled(sorted.Stats()) }()
+	return sorted.Sort(func(row spill.Row) error {
+		return emit(row[:len(row)-1])
+	})
+}
+
+// rowKey encodes the given values, so that equal rows have equal keys. Like
+// in SQLite, a REAL value with an integral value is equal to the INTEGER.
+func rowKey(row []function.Value) string {
+	normalized := make([]function.Value, len(row))
+	for i, v := range row {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			v = int64(f)
+		}
+		normalized[i] = v
+	}
+	return string(encodeRecord(normalized))
+}
+
+// values produces rows of expressions.
+type values struct {
+	rows [][]expression
+	cols []column
+}
+
+func (x *execution) values(n *planner.Node, outer *scope) (operator, error) {
+	op := &values{}
+	s := &scope{outer: outer}
+	for _, row := range n.Values {
+		compiled, err := x.exprs(row, s)
+		if err != nil {
+			return nil, err
+		}
+		op.rows = append(op.rows, compiled)
+	}
+	if len(n.Values) > 0 {
+		for i := range n.Values[0] {
+			op.cols = append(op.cols, column{name: "column" + strconv.Itoa(i+1)})
+		}
+	}
+	return op, nil
+}
+
+func (v *values) columns() []column { return v.cols }
+
+func (v *values) run(outer *frame, emit func(row []function.Value) error) error {
+	f := &frame{outer: outer}
+	for _, exprs := range v.rows {
+		row := make([]function.Value, len(exprs))
+		for i, e := range exprs {
+			value, err := e.eval(f)
+			if err != nil {
+				return err
+			}
+			row[i] = value
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compound combines the rows of its children with a set operation. Its
+// columns are the columns of the first child.
+type compound struct {
+	children []operator
+	op       planner.SetOp
+}
+
+func (x *execution) compound(n *planner.Node, outer *scope) (operator, error) {
+	op := &compound{op: n.SetOp}
+	for _, child := range n.Children {
+		c, err := x.node(child, outer)
+		if err != nil {
+			return nil, err
+		}
+		if len(op.children) > 0 && len(visible(c.columns())) != len(visible(op.children[0].columns())) {
+			return nil, ErrCompoundColumns
+		}
+		op.children = append(op.children, c)
+	}
+	return op, nil
+}
+
+func (c *compound) columns() []column { return c.children[0].columns() }
+
+func (c *compound) run(outer *frame, emit func(row []function.Value) error) error {
+	if c.op == planner.UnionAll {
+		for _, child := range c.children {
+			if err := child.run(outer, emit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// keys holds the rows, that are returned, and the order in which they
+	// were found
+	rows := make(map[string][]function.Value)
+	var keys []string
+	for i, child := range c.children {
+		found := make(map[string]bool)
+		err := child.run(outer, func(row []function.Value) error {
+			k := rowKey(row)
+			switch {
+			case i == 0 || c.op == planner.Union:
+				if _, ok := rows[k]; !ok {
+					rows[k] = row
+					keys = append(keys, k)
+				}
+			default:
+				found[k] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if i == 0 || c.op == planner.Union {
+			continue
+		}
+		for k := range rows {
+			if found[k] != (c.op == planner.Intersect) {
+				delete(rows, k)
+			}
+		}
+	}
+	for _, k := range keys {
+		row, ok := rows[k]
+		if !ok {
+			continue
+		}
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// Pragmas, that the executor knows, in addition to spill.PragmaQueryMemory.
+// Like in SQLite, other pragmas are ignored.
+const (
+	PragmaForeignKeys       = "foreign_keys"
+	PragmaForeignKeyCheck   = "foreign_key_check"
+	PragmaRecursiveTriggers = "recursive_triggers"
+	PragmaRecursionLimit    = "recursion_limit"
+)
+
+// executePragma executes PRAGMA.
+func (e *simpleExecutor) executePragma(ctx context.Context, cmd command.Command) (Result, error) {
+	if value, ok, err := e.settings.ExecPragma(cmd.Stmt.PragmaStmt); ok {
+		if err != nil {
+			return nil, err
+		}
+		if cmd.Value != "" {
+			return table{}, nil
+		}
+		return table{columns: []string{spill.PragmaQueryMemory}, rows: [][]interface{}{{value}}}, nil
+	}
+	switch cmd.Name {
+	case PragmaForeignKeys:
+		if cmd.Value == "" {
+			return table{columns: []string{PragmaForeignKeys}, rows: [][]interface{}{{boolValue(e.foreignKeys)}}}, nil
+		}
+		// like in SQLite, foreign keys cannot be enabled or disabled in a
+		// transaction
+		if e.session.Tx() != nil {
+			return table{}, nil
+		}
+		e.foreignKeys = pragmaBool(cmd.Value)
+		for _, s := range e.schemas {
+			s.alter.Constraints().SetForeignKeys(e.foreignKeys)
+		}
+		return table{}, nil
+	case PragmaForeignKeyCheck:
+		return e.executeStatement(ctx, cmd)
+	case PragmaRecursiveTriggers:
+		if cmd.Value == "" {
+			return table{columns: []string{PragmaRecursiveTriggers}, rows: [][]interface{}{{boolValue(e.recursiveTriggers)}}}, nil
+		}
+		e.recursiveTriggers = pragmaBool(cmd.Value)
+		for _, s := range e.schemas {
+			s.runner.SetRecursive(e.recursiveTriggers)
+		}
+		return table{}, nil
+	case PragmaRecursionLimit:
+		if cmd.Value == "" {
+			return table{columns: []string{PragmaRecursionLimit}, rows: [][]interface{}{{int64(e.recursionLimit)}}}, nil
+		}
+		// like SQLite, invalid values are ignored
+		if limit, err := strconv.Atoi(cmd.Value); err == nil && limit >= 0 {
+			e.recursionLimit = limit
+		}
+		return table{}, nil
+	}
+	return table{}, nil
+}
+
+// pragmaBool converts the value of a pragma to a boolean, like SQLite. Values
+// that are not a boolean are false.
+func pragmaBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "on", "yes", "true":
+		return true
+	}
+	return false
+}
+
+// foreignKeyCheck executes PRAGMA foreign_key_check, which returns the rows,
+// that violate a foreign key, of the table that is the value of the pragma,
+// or of all tables of the schema of the pragma, or of all databases.
+func (x *execution) foreignKeyCheck(cmd command.Command) (Result, error) {
+	schemaName := token.Unquote(cmd.Stmt.PragmaStmt.SchemaName)
+	var schemas []*schema
+	if cmd.Value != "" {
+		_, s, t, err := x.table(schemaName, cmd.Value)
+		if err != nil {
+			return nil, err
+		}
+		return foreignKeyCheck([]*schema{s}, t.name)
+	}
+
+	var dbs []*attach.Database
+	if schemaName != "" {
+		db, ok := x.e.session.Database(schemaName)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", attach.ErrNoSuchDatabase, schemaName)
+		}
+		dbs = append(dbs, db)
+	} else {
+		dbs = x.e.session.Databases()
+	}
+	for _, db := range dbs {
+		s, err := x.schema(db)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, s)
+	}
+	return foreignKeyCheck(schemas, "")
+}
+
+// foreignKeyCheck returns the result of PRAGMA foreign_key_check for the
+// given table of the given schemas, or all of their tables if it is empty.
+func foreignKeyCheck(schemas []*schema, name string) (Result, error) {
+	result := table{columns: []string{"table", "rowid", "parent", "fkid"}}
+	for _, s := range schemas {
+		violations, err := s.alter.Constraints().ForeignKeyCheck(name)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(violations, func(i, j int) bool {
+			if violations[i].Table != violations[j].Table {
+				return violations[i].Table < violations[j].Table
+			}
+			return violations[i].RowID < violations[j].RowID
+		})
+		for _, v := range violations {
+			result.rows = append(result.rows, []interface{}{v.Table, int64(v.RowID), v.Parent, int64(v.ForeignKey)})
+		}
+	}
+	return result, nil
+}
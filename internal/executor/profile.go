@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/executor/profile"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+)
+
+// explainAnalyze executes the command, and returns the result of EXPLAIN
+// ANALYZE, which are the statistics of the operators of its plan. Like in
+// PostgreSQL, the statement is executed, and its changes are kept.
+func (x *execution) explainAnalyze(cmd command.Command) (Result, error) {
+	root := &profile.Operator{}
+	if cmd.Op != command.Select && cmd.Plan != nil {
+		root = &profile.Operator{Detail: cmd.Plan.Detail(), Rows: cmd.Plan.Rows, Cost: cmd.Plan.Cost}
+	}
+	cmd.Explain = command.NoExplain
+	x.profile = root
+	start := time.Now()
+	_, err := x.execute(cmd)
+	x.profile = nil
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cmd.Op != command.Select:
+		// the statement itself emits no rows, and counts its changes
+		root.Stats.Loops, root.Stats.Rows, root.Stats.Time = 1, x.changes, time.Since(start)
+	case len(root.Children) == 1:
+		root = root.Children[0]
+	}
+
+	result := analyzed{table: table{columns: profile.ExplainColumns}, profile: root}
+	for _, row := range profile.Explain(root) {
+		result.rows = append(result.rows, row.Values())
+	}
+	return result, nil
+}
+
+// profiled measures the rows, loops and time of its operator.
+type profiled struct {
+	operator
+	profile *profile.Operator
+}
+
+func (p *profiled) run(outer *frame, emit func(row []function.Value) error) error {
+	return p.profile.Run(func(emit func(profile.Row) error) error {
+		return p.operator.run(outer, emit)
+	}, emit)
+}
+
+// measure returns the given operator, measured in the given profile.
+// Operators, that use the memory budget of the statement, report their
+// memory and spill statistics to it as well.
+func measure(op operator, p *profile.Operator) operator {
+	if m, ok := op.(interface{ measure(p *profile.Operator) }); ok {
+		m.measure(p)
+	}
+	return &profiled{operator: op, profile: p}
+}
+
+// measured is embedded in the operators, that use the memory budget of the
+// statement. Its profile is nil, unless EXPLAIN ANALYZE runs the statement.
+type measured struct {
+	profile *profile.Operator
+}
+
+func (m *measured) measure(p *profile.Operator) {
+	m.profile = p
+}
+
+// spilled records the memory, that a sort, an aggregation or a join of the
+// operator reserved in a run, and how much it spilled.
+func (m *measured) spilled(stats spill.Stats) {
+	if m.profile != nil {
+		m.profile.ObserveMemory(stats.Memory)
+		m.profile.AddSpill(stats)
+	}
+}
@@ -0,0 +1,12 @@
+// Package profile measures the operators of a query for EXPLAIN ANALYZE.
+//
+// An Operator is created for every node of the plan of a query, and for every
+// operator that the executor adds to the plan, like a sort. While the query
+// runs, every Operator counts the rows that it emits and the times that it
+// runs, measures the time that it and its children take, and records the
+// pages that it read, its peak memory and how much it spilled to disk.
+//
+// After the query, Explain returns the statistics of all operators as the
+// result of EXPLAIN ANALYZE, String renders them as a tree, and the JSON
+// encoding of an Operator describes the whole tree for tools.
+package profile
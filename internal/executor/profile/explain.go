@@ -0,0 +1,163 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+)
+
+// ExplainColumns are the names of the columns of the result of EXPLAIN
+// ANALYZE. The first columns are the same as the ones of EXPLAIN QUERY PLAN.
+var ExplainColumns = []string{"id", "parent", "detail", "rows", "cost", "actual_rows", "loops", "time_ms", "pages_hit", "pages_read", "memory", "spill"}
+
+// ExplainRow is a row of the result of EXPLAIN ANALYZE, that describes a
+// single operator.
+type ExplainRow struct {
+	// ID identifies the operator. IDs are assigned in depth-first order,
+	// starting at 1.
+	ID int
+	// Parent is the ID of the parent operator, or 0 for the root operator.
+	Parent int
+	Detail string
+	Rows   float64
+	Cost   float64
+	Stats  Stats
+}
+
+// Values returns the values of this row, in the order of ExplainColumns. The
+// time is in milliseconds, and the spill is described like in
+// (spill.Stats).String.
+func (r ExplainRow) Values() []interface{} {
+	return []interface{}{
+		r.ID, r.Parent, r.Detail, r.Rows, r.Cost,
+		r.Stats.Rows, r.Stats.Loops, milliseconds(r.Stats.Time),
+		r.Stats.PagesHit, r.Stats.PagesRead, r.Stats.Memory, r.Stats.Spill.String(),
+	}
+}
+
+// Explain returns the rows of the result of EXPLAIN ANALYZE for the given
+// operator, in depth-first order.
+func Explain(o *Operator) []ExplainRow {
+	var rows []ExplainRow
+	var visit func(o *Operator, parent int)
+	visit = func(o *Operator, parent int) {
+		id := len(rows) + 1
+		rows = append(rows, ExplainRow{
+			ID:     id,
+			Parent: parent,
+			Detail: o.Detail,
+			Rows:   o.Rows,
+			Cost:   o.Cost,
+			Stats:  o.Stats,
+		})
+		for _, child := range o.Children {
+			visit(child, id)
+		}
+	}
+	visit(o, 0)
+	return rows
+}
+
+// String renders the operator and its children as a tree, like
+// (*planner.Node).String, with the measured statistics after the estimates.
+//
+//  QUERY PLAN
+//  `--HASH JOIN (rows=100 cost=2000) (actual rows=96 loops=1 time=1.250ms hit=12 read=0 memory=24.0 KB)
+//     |--SCAN big (rows=1000 cost=1000) (actual rows=1000 loops=1 time=0.800ms hit=10 read=0 memory=0 B)
+//     `--SCAN small (rows=10 cost=10) (actual rows=12 loops=1 time=0.020ms hit=2 read=0 memory=0 B)
+func (o *Operator) String() string {
+	var b strings.Builder
+	b.WriteString("QUERY PLAN\n")
+
+	var visit func(o *Operator, prefix string, last bool)
+	visit = func(o *Operator, prefix string, last bool) {
+		branch, indent := "|--", "|  "
+		if last {
+			branch, indent = "`--", "   "
+		}
+		fmt.Fprintf(&b, "%v%v%v", prefix, branch, o.Detail)
+		if o.Rows != 0 || o.Cost != 0 {
+			fmt.Fprintf(&b, " (rows=%.0f cost=%.0f)", o.Rows, o.Cost)
+		}
+		s := o.Stats
+		fmt.Fprintf(&b, " (actual rows=%d loops=%d time=%.3fms hit=%d read=%d memory=%v", s.Rows, s.Loops, milliseconds(s.Time), s.PagesHit, s.PagesRead, spill.FormatBytes(s.Memory))
+		if s.Spill.Spilled() {
+			fmt.Fprintf(&b, ", %v", s.Spill)
+		}
+		b.WriteString(")\n")
+		for i, child := range o.Children {
+			visit(child, prefix+indent, i == len(o.Children)-1)
+		}
+	}
+	visit(o, "", true)
+	return b.String()
+}
+
+// jsonOperator is the JSON encoding of an Operator.
+type jsonOperator struct {
+	Detail     string         `json:"detail"`
+	Rows       float64        `json:"rows"`
+	Cost       float64        `json:"cost"`
+	ActualRows int64          `json:"actual_rows"`
+	Loops      int64          `json:"loops"`
+	TimeMS     float64        `json:"time_ms"`
+	PagesHit   int64          `json:"pages_hit"`
+	PagesRead  int64          `json:"pages_read"`
+	Memory     int64          `json:"memory"`
+	Spill      *jsonSpill     `json:"spill,omitempty"`
+	Children   []jsonOperator `json:"children,omitempty"`
+}
+
+// jsonSpill is the JSON encoding of spill.Stats. It is omitted, if the
+// operator didn't spill.
+type jsonSpill struct {
+	Files  int   `json:"files"`
+	Rows   int64 `json:"rows"`
+	Bytes  int64 `json:"bytes"`
+	Passes int   `json:"passes"`
+}
+
+// MarshalJSON encodes the operator and its children as a JSON object, like
+//
+//  {"detail":"SCAN t","rows":10,"cost":10,"actual_rows":12,"loops":1,
+//   "time_ms":0.02,"pages_hit":2,"pages_read":0,"memory":0}
+//
+// Children are in the "children" array, and the spill statistics in the
+// "spill" object, if the operator spilled.
+func (o *Operator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.json())
+}
+
+func (o *Operator) json() jsonOperator {
+	s := o.Stats
+	result := jsonOperator{
+		Detail:     o.Detail,
+		Rows:       o.Rows,
+		Cost:       o.Cost,
+		ActualRows: s.Rows,
+		Loops:      s.Loops,
+		TimeMS:     milliseconds(s.Time),
+		PagesHit:   s.PagesHit,
+		PagesRead:  s.PagesRead,
+		Memory:     s.Memory,
+	}
+	if s.Spill.Spilled() {
+		result.Spill = &jsonSpill{
+			Files:  s.Spill.Files,
+			Rows:   s.Spill.Rows,
+			Bytes:  s.Spill.Bytes,
+			Passes: s.Spill.Passes,
+		}
+	}
+	for _, child := range o.Children {
+		result.Children = append(result.Children, child.json())
+	}
+	return result
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,72 @@
+package profile
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+)
+
+func testOperator() *Operator {
+	return &Operator{
+		Detail: "SORT",
+		Stats: Stats{
+			Rows:   96,
+			Loops:  1,
+			Time:   2 * time.Millisecond,
+			Memory: 65536,
+			Spill:  spill.Stats{Files: 2, Rows: 96, Bytes: 4096, Passes: 1},
+		},
+		Children: []*Operator{
+			{
+				Detail: "HASH JOIN",
+				Rows:   100,
+				Cost:   2000,
+				Stats:  Stats{Rows: 96, Loops: 1, Time: 1250 * time.Microsecond, Memory: 24576},
+				Children: []*Operator{
+					{Detail: "SCAN big", Rows: 1000, Cost: 1000, Stats: Stats{Rows: 1000, Loops: 1, Time: 800 * time.Microsecond, PagesHit: 10, PagesRead: 2}},
+					{Detail: "SCAN small", Rows: 10, Cost: 10, Stats: Stats{Rows: 12, Loops: 1, Time: 20 * time.Microsecond, PagesHit: 2}},
+				},
+			},
+		},
+	}
+}
+
+func TestExplain(t *testing.T) {
+	rows := Explain(testOperator())
+	assert.Len(t, rows, 4)
+	assert.Equal(t, []interface{}{1, 0, "SORT", 0.0, 0.0, int64(96), int64(1), 2.0, int64(0), int64(0), int64(65536), "spilled 2 files, 96 rows, 4.0 KB, 1 passes"}, rows[0].Values())
+	assert.Equal(t, []interface{}{3, 2, "SCAN big", 1000.0, 1000.0, int64(1000), int64(1), 0.8, int64(10), int64(2), int64(0), "in memory"}, rows[2].Values())
+	assert.Equal(t, 2, rows[3].Parent)
+	assert.Len(t, rows[0].Values(), len(ExplainColumns))
+}
+
+func TestOperator_String(t *testing.T) {
+	assert.Equal(t, `QUERY PLAN
+`+"`"+`--SORT (actual rows=96 loops=1 time=2.000ms hit=0 read=0 memory=64.0 KB, spilled 2 files, 96 rows, 4.0 KB, 1 passes)
+   `+"`"+`--HASH JOIN (rows=100 cost=2000) (actual rows=96 loops=1 time=1.250ms hit=0 read=0 memory=24.0 KB)
+      |--SCAN big (rows=1000 cost=1000) (actual rows=1000 loops=1 time=0.800ms hit=10 read=2 memory=0 B)
+      `+"`"+`--SCAN small (rows=10 cost=10) (actual rows=12 loops=1 time=0.020ms hit=2 read=0 memory=0 B)
+`, testOperator().String())
+}
+
+func TestOperator_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(testOperator().Children[0].Children[1])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"detail":"SCAN small","rows":10,"cost":10,"actual_rows":12,"loops":1,"time_ms":0.02,"pages_hit":2,"pages_read":0,"memory":0}`, string(data))
+
+	data, err = json.Marshal(testOperator())
+	assert.NoError(t, err)
+	var decoded struct {
+		Spill    map[string]int `json:"spill"`
+		Children []struct {
+			Children []json.RawMessage `json:"children"`
+		} `json:"children"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, map[string]int{"files": 2, "rows": 96, "bytes": 4096, "passes": 1}, decoded.Spill)
+	assert.Len(t, decoded.Children, 1)
+	assert.Len(t, decoded.Children[0].Children, 2)
+}
@@ -0,0 +1,112 @@
+package profile
+
+import (
+	"time"
+
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// Row is a row, that an operator emits.
+type Row = []function.Value
+
+// Stats are the statistics of an operator, that are measured while the query
+// runs.
+type Stats struct {
+	// Rows is the amount of rows that the operator emitted in all loops.
+	Rows int64
+	// Loops is the amount of times that the operator ran. The inner side of
+	// a nested loop join runs once for every row of the outer side.
+	Loops int64
+	// Time is the time that the operator and its children ran in all loops,
+	// without the time that its parent spent on the emitted rows.
+	Time time.Duration
+	// PagesHit is the amount of pages that were found in the buffer pool,
+	// and PagesRead is the amount of pages that were read from disk.
+	PagesHit, PagesRead int64
+	// Memory is the peak amount of bytes, that the operator reserved from the
+	// budget of the query.
+	Memory int64
+	// Spill describes how much the operator spilled to temporary files.
+	Spill spill.Stats
+}
+
+// Operator is an operator of a query, that is measured for EXPLAIN ANALYZE.
+// An Operator is not safe for concurrent use.
+type Operator struct {
+	Detail string
+	// Rows and Cost are the estimates of the planner. Both are 0 for
+	// operators, that were not planned.
+	Rows, Cost float64
+	Children   []*Operator
+	Stats      Stats
+}
+
+// New creates an operator with the given description and children, that was
+// not planned.
+func New(detail string, children ...*Operator) *Operator {
+	return &Operator{
+		Detail:   detail,
+		Children: children,
+	}
+}
+
+// FromPlan creates the operators for the given plan and all of its children.
+func FromPlan(n *planner.Node) *Operator {
+	op := &Operator{
+		Detail: n.Detail(),
+		Rows:   n.Rows,
+		Cost:   n.Cost,
+	}
+	for _, child := range n.Children {
+		op.Children = append(op.Children, FromPlan(child))
+	}
+	return op
+}
+
+// Run runs the given function as one loop of this operator, and passes the
+// rows that it emits on to the given emit function. The time that the emit
+// function takes is not counted as time of this operator.
+func (o *Operator) Run(run func(emit func(Row) error) error, emit func(Row) error) error {
+	o.Stats.Loops++
+	start := time.Now()
+	err := run(func(row Row) error {
+		o.Stats.Rows++
+		o.Stats.Time += time.Since(start)
+		err := emit(row)
+		start = time.Now()
+		return err
+	})
+	o.Stats.Time += time.Since(start)
+	return err
+}
+
+// Wrap returns a function, that runs the given function as a loop of this
+// operator, like Run.
+func (o *Operator) Wrap(run func(emit func(Row) error) error) func(emit func(Row) error) error {
+	return func(emit func(Row) error) error {
+		return o.Run(run, emit)
+	}
+}
+
+// AddPages adds pages, that were found in the buffer pool (hit), or read
+// from disk (read).
+func (o *Operator) AddPages(hit, read int64) {
+	o.Stats.PagesHit += hit
+	o.Stats.PagesRead += read
+}
+
+// ObserveMemory records the amount of bytes, that the operator currently
+// reserves, like the Used bytes of its budget. The peak is kept.
+func (o *Operator) ObserveMemory(used int64) {
+	if used > o.Stats.Memory {
+		o.Stats.Memory = used
+	}
+}
+
+// AddSpill adds the spill statistics of a sort, an aggregation or a join,
+// that the operator used.
+func (o *Operator) AddSpill(stats spill.Stats) {
+	o.Stats.Spill.Add(stats)
+}
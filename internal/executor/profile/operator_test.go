@@ -0,0 +1,107 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestFromPlan(t *testing.T) {
+	plan := &planner.Node{
+		Op:   planner.HashJoin,
+		Rows: 50,
+		Cost: 1250,
+		Children: []*planner.Node{
+			{Op: planner.FullScan, Relation: "customers", Rows: 10, Cost: 1000},
+			{Op: planner.IndexScan, Relation: "orders", Index: "orders_customer", Rows: 10, Cost: 10},
+		},
+	}
+
+	op := FromPlan(plan)
+	assert.Equal(t, &Operator{
+		Detail: "HASH JOIN",
+		Rows:   50,
+		Cost:   1250,
+		Children: []*Operator{
+			{Detail: "SCAN customers", Rows: 10, Cost: 1000},
+			{Detail: "SEARCH orders USING INDEX orders_customer", Rows: 10, Cost: 10},
+		},
+	}, op)
+}
+
+func TestOperator_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	scan := New("SCAN t")
+	loop := New("NESTED LOOP JOIN", scan)
+	rows := func(n int) func(emit func(Row) error) error {
+		return func(emit func(Row) error) error {
+			for i := 0; i < n; i++ {
+				if err := emit(Row{int64(i)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	// the inner side runs once for every row of the outer side
+	var got []Row
+	err := loop.Run(func(emit func(Row) error) error {
+		return rows(3)(func(outer Row) error {
+			return scan.Run(rows(2), func(inner Row) error {
+				return emit(Row{outer[0], inner[0]})
+			})
+		})
+	}, func(row Row) error {
+		got = append(got, row)
+		// time of the parent, that is not counted
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Len(got, 6)
+
+	assert.EqualValues(6, loop.Stats.Rows)
+	assert.EqualValues(1, loop.Stats.Loops)
+	assert.EqualValues(2*3, scan.Stats.Rows)
+	assert.EqualValues(3, scan.Stats.Loops)
+	assert.Less(int64(loop.Stats.Time), int64(50*time.Millisecond))
+	assert.GreaterOrEqual(int64(loop.Stats.Time), int64(scan.Stats.Time))
+}
+
+func TestOperator_Wrap(t *testing.T) {
+	assert := assert.New(t)
+
+	op := New("SCAN t")
+	run := op.Wrap(func(emit func(Row) error) error {
+		return emit(Row{"a"})
+	})
+	assert.NoError(run(func(Row) error { return nil }))
+	assert.NoError(run(func(Row) error { return nil }))
+	assert.EqualValues(2, op.Stats.Rows)
+	assert.EqualValues(2, op.Stats.Loops)
+}
+
+func TestOperator_Statistics(t *testing.T) {
+	assert := assert.New(t)
+
+	op := New("SORT")
+	op.AddPages(3, 1)
+	op.AddPages(2, 0)
+	op.ObserveMemory(100)
+	op.ObserveMemory(400)
+	op.ObserveMemory(200)
+	op.AddSpill(spill.Stats{Files: 2, Rows: 10, Bytes: 100, Passes: 1})
+	op.AddSpill(spill.Stats{Files: 1, Rows: 5, Bytes: 50, Passes: 2})
+
+	assert.Equal(Stats{
+		PagesHit:  5,
+		PagesRead: 1,
+		Memory:    400,
+		Spill:     spill.Stats{Files: 3, Rows: 15, Bytes: 150, Passes: 2},
+	}, op.Stats)
+}
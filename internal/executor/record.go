@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/tomarrell/lbadd/internal/executor/function"
+)
+
+// Tags of the values of an encoded record.
+const (
+	tagNull byte = iota
+	tagInteger
+	tagReal
+	tagText
+	tagBlob
+)
+
+// encodeRecord encodes the given values, which are the values of a row or of
+// a record of the catalog. Every value is a tag, followed by the value.
+// INTEGER values are varints, REAL values are 8 bytes, and TEXT and BLOB
+// values are prefixed with their length.
+func encodeRecord(values []function.Value) []byte {
+	var buf []byte
+	var scratch [binary.MaxVarintLen64]byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case int64:
+			n := binary.PutVarint(scratch[:], v)
+			buf = append(append(buf, tagInteger), scratch[:n]...)
+		case float64:
+			binary.BigEndian.PutUint64(scratch[:8], math.Float64bits(v))
+			buf = append(append(buf, tagReal), scratch[:8]...)
+		case string:
+			n := binary.PutUvarint(scratch[:], uint64(len(v)))
+			buf = append(append(append(buf, tagText), scratch[:n]...), v...)
+		case []byte:
+			n := binary.PutUvarint(scratch[:], uint64(len(v)))
+			buf = append(append(append(buf, tagBlob), scratch[:n]...), v...)
+		default:
+			buf = append(buf, tagNull)
+		}
+	}
+	return buf
+}
+
+// decodeRecord decodes a record, that was encoded by encodeRecord.
+func decodeRecord(buf []byte) ([]function.Value, error) {
+	var values []function.Value
+	for len(buf) > 0 {
+		tag := buf[0]
+		buf = buf[1:]
+		switch tag {
+		case tagNull:
+			values = append(values, nil)
+		case tagInteger:
+			v, n := binary.Varint(buf)
+			if n <= 0 {
+				return nil, ErrCorrupt
+			}
+			values = append(values, v)
+			buf = buf[n:]
+		case tagReal:
+			if len(buf) < 8 {
+				return nil, ErrCorrupt
+			}
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(buf)))
+			buf = buf[8:]
+		case tagText, tagBlob:
+			size, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < size {
+				return nil, ErrCorrupt
+			}
+			data := buf[n : n+int(size)]
+			if tag == tagText {
+				values = append(values, string(data))
+			} else {
+				values = append(values, append([]byte{}, data...))
+			}
+			buf = buf[n+int(size):]
+		default:
+			return nil, ErrCorrupt
+		}
+	}
+	return values, nil
+}
@@ -1,11 +1,76 @@
 package executor
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/executor/profile"
+)
 
 // Result describes the result of a command execution. The result is always a
 // table that has a header row. The smallest possible result table is a table
 // with one column and two rows, and is generated as a result of a single-value
-// computation, e.g. a sum().
+// computation, e.g. a sum(). Commands that do not compute values, like BEGIN,
+// have a result without columns and rows.
 type Result interface {
 	fmt.Stringer
+	// Columns returns the names of the columns, which is the header row.
+	Columns() []string
+	// Rows returns the rows of the table, without the header row.
+	Rows() [][]interface{}
+	// RowsAffected returns the number of rows, that were inserted, updated or
+	// deleted by the command.
+	RowsAffected() int64
+	// LastInsertID returns the row ID of the last row, that was inserted by
+	// the command, or 0.
+	LastInsertID() int64
+}
+
+var _ Result = table{}
+var _ Result = analyzed{}
+
+// table is a Result, that holds all rows in memory.
+type table struct {
+	columns []string
+	rows    [][]interface{}
+
+	changes      int64
+	lastInsertID int64
+}
+
+func (t table) Columns() []string     { return t.columns }
+func (t table) Rows() [][]interface{} { return t.rows }
+func (t table) RowsAffected() int64   { return t.changes }
+func (t table) LastInsertID() int64   { return t.lastInsertID }
+
+// String renders the header row and the rows of the table, one per line, with
+// the values separated by '|'.
+func (t table) String() string {
+	var b strings.Builder
+	b.WriteString(strings.Join(t.columns, "|"))
+	for _, row := range t.rows {
+		b.WriteByte('\n')
+		for i, value := range row {
+			if i > 0 {
+				b.WriteByte('|')
+			}
+			fmt.Fprint(&b, value)
+		}
+	}
+	return b.String()
+}
+
+// analyzed is the Result of EXPLAIN ANALYZE, which holds the rows of
+// profile.Explain, and the measured operators, that they describe.
+type analyzed struct {
+	table
+	profile *profile.Operator
+}
+
+// Profile returns the measured operators of the result of EXPLAIN ANALYZE,
+// which encode themselves as JSON. It returns false for the results of other
+// commands.
+func Profile(result Result) (*profile.Operator, bool) {
+	a, ok := result.(analyzed)
+	return a.profile, ok
 }
@@ -0,0 +1,516 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/alter"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/lock"
+	"github.com/tomarrell/lbadd/internal/database/stats"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/database/view"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/parser"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+)
+
+// Keys of a database. The catalog holds a record for every object of the
+// schema, like the sqlite_schema table of SQLite, under the catalog prefix
+// and the lower case name of the object. The version changes with every
+// change of the catalog, so that sessions notice that their compiled schema
+// is outdated. The rows, indexes and statistics of a table are stored under
+// the table prefix and the ID of the table.
+const (
+	catalogPrefix  = 's'
+	versionKey     = "v"
+	nextIDKey      = "i"
+	sequencePrefix = 'q'
+	tablePrefix    = 't'
+	rowsPrefix     = 'r'
+	statsPrefix    = 'a'
+)
+
+// Types of the objects of the catalog.
+const (
+	typeTable   = "table"
+	typeIndex   = "index"
+	typeTrigger = "trigger"
+	typeView    = "view"
+)
+
+// object is a record of the catalog.
+type object struct {
+	typ  string
+	name string
+	// table is the name of the table, that an index or trigger belongs to,
+	// and the name of the object itself for tables and views.
+	table string
+	id    int64
+	sql   string
+}
+
+func (o object) values() []function.Value {
+	return []function.Value{o.typ, o.name, o.table, o.id, o.sql}
+}
+
+func objectFrom(values []function.Value) (object, error) {
+	if len(values) != 5 {
+		return object{}, ErrCorrupt
+	}
+	typ, ok1 := values[0].(string)
+	name, ok2 := values[1].(string)
+	table, ok3 := values[2].(string)
+	id, ok4 := values[3].(int64)
+	sql, ok5 := values[4].(string)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 {
+		return object{}, ErrCorrupt
+	}
+	return object{typ, name, table, id, sql}, nil
+}
+
+// schema is the schema of a database, that is compiled from the records of
+// its catalog. It is compiled again when the version of the catalog changes.
+type schema struct {
+	version []byte
+	store   *binding
+	alter   *alter.Schema
+	views   *view.Catalog
+	// tables holds the tables by their lower case names.
+	tables map[string]*storedTable
+	// indexes holds the objects of the secondary indexes by their lower case
+	// names. They are compiled with their tables.
+	indexes  map[string]object
+	triggers *trigger.Catalog
+	runner   *trigger.Runner
+	// stats holds the statistics of the tables, that have been analyzed.
+	stats *stats.Catalog
+	// exec is the execution of the statement, that uses the schema, in
+	// which the programs of the triggers run.
+	exec *execution
+}
+
+// storedTable is a table of a schema.
+type storedTable struct {
+	object
+	// columns are the columns of the table, followed by the hidden row ID.
+	columns []column
+	t       *alter.Table
+	// autoincrement is set, if the table has an AUTOINCREMENT column, whose
+	// sequence is stored in the database.
+	autoincrement bool
+	// lookups holds the columns of the secondary indexes, that rows can be
+	// looked up with, by the lower case names of the indexes.
+	lookups map[string][]int
+}
+
+// binding is the store of the rows and indexes of a schema. It passes all
+// calls on to the transaction of the database, that the executed statement
+// uses, so that the compiled schema can be used by all transactions.
+type binding struct {
+	tx *transaction.Tx
+	// ctx is the context of the executed statement, until which the
+	// transaction waits for locks.
+	ctx context.Context
+}
+
+var _ index.Store = (*binding)(nil)
+
+func (b *binding) Put(key, value []byte) error { return b.tx.Put(key, value) }
+func (b *binding) Delete(key []byte) error     { return b.tx.Delete(key) }
+func (b *binding) Scan(start, end []byte, fn func(key, value []byte) (bool, error)) error {
+	return b.tx.Scan(start, end, fn)
+}
+
+// lock acquires the given lock of the table with the given ID, or of one of
+// its rows, for the transaction.
+func (b *binding) lock(table int64, row string, mode lock.Mode) error {
+	return b.tx.Lock(b.ctx, lock.Resource{Table: strconv.FormatInt(table, 10), Row: row}, mode)
+}
+
+// storedRows are the rows of a table in a database. A row is stored under
+// the prefix of the table, followed by the row ID with the sign bit flipped,
+// so that the keys are ordered like the row IDs. A row is locked exclusively,
+// before it is written, so that a concurrent transaction, that writes it,
+// waits until the transaction ends.
+type storedRows struct {
+	store  *binding
+	id     int64
+	prefix []byte
+}
+
+var _ constraint.StoredRows = storedRows{}
+
+func (r storedRows) key(id index.RowID) []byte {
+	key := make([]byte, len(r.prefix)+9)
+	copy(key, r.prefix)
+	key[len(r.prefix)] = rowsPrefix
+	binary.BigEndian.PutUint64(key[len(r.prefix)+1:], uint64(id)^(1<<63))
+	return key
+}
+
+func (r storedRows) bounds() ([]byte, []byte) {
+	start := append(append([]byte{}, r.prefix...), rowsPrefix)
+	end := append(append([]byte{}, r.prefix...), rowsPrefix+1)
+	return start, end
+}
+
+func (r storedRows) Get(id index.RowID) (constraint.Row, bool, error) {
+	value, ok, err := r.store.tx.Get(r.key(id))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	row, err := decodeRecord(value)
+	return row, err == nil, err
+}
+
+func (r storedRows) Put(id index.RowID, row constraint.Row) error {
+	if err := r.store.lock(r.id, strconv.FormatInt(int64(id), 10), lock.Exclusive); err != nil {
+		return err
+	}
+	return r.store.tx.Put(r.key(id), encodeRecord(row))
+}
+
+func (r storedRows) Delete(id index.RowID) error {
+	if err := r.store.lock(r.id, strconv.FormatInt(int64(id), 10), lock.Exclusive); err != nil {
+		return err
+	}
+	return r.store.tx.Delete(r.key(id))
+}
+
+func (r storedRows) Last() (index.RowID, bool, error) {
+	var id index.RowID
+	var found bool
+	start, end := r.bounds()
+	err := r.store.tx.ScanReverse(start, end, func(key, _ []byte) (bool, error) {
+		id, found = rowIDOf(key), true
+		return false, nil
+	})
+	return id, found, err
+}
+
+func (r storedRows) Scan(fn func(id index.RowID, row constraint.Row) error) error {
+	start, end := r.bounds()
+	return r.store.tx.Scan(start, end, func(key, value []byte) (bool, error) {
+		row, err := decodeRecord(value)
+		if err != nil {
+			return false, err
+		}
+		return true, fn(rowIDOf(key), row)
+	})
+}
+
+func (r storedRows) IndexStore() (index.Store, []byte) {
+	return r.store, r.prefix
+}
+
+func rowIDOf(key []byte) index.RowID {
+	return index.RowID(binary.BigEndian.Uint64(key[len(key)-8:]) ^ (1 << 63))
+}
+
+// tablePrefixOf returns the prefix of the keys of the rows and indexes of the
+// table with the given ID.
+func tablePrefixOf(id int64) []byte {
+	prefix := make([]byte, 5)
+	prefix[0] = tablePrefix
+	binary.BigEndian.PutUint32(prefix[1:], uint32(id))
+	return prefix
+}
+
+// prefixEnd returns the smallest key, that is greater than all keys with the
+// given prefix.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+func catalogKey(name string) []byte {
+	return append([]byte{catalogPrefix}, strings.ToLower(name)...)
+}
+
+func sequenceKey(table string) []byte {
+	return append([]byte{sequencePrefix}, strings.ToLower(table)...)
+}
+
+// loadSchema compiles the schema of a database from the records of its
+// catalog, which are read with the given transaction. The objects are
+// compiled in the order in which they were created, so that every object
+// only depends on objects before it.
+func loadSchema(tx *transaction.Tx, version []byte) (*schema, error) {
+	s := &schema{
+		version:  version,
+		store:    &binding{tx: tx, ctx: context.Background()},
+		views:    view.NewCatalog(),
+		tables:   make(map[string]*storedTable),
+		indexes:  make(map[string]object),
+		triggers: trigger.NewCatalog(),
+		stats:    stats.NewCatalog(),
+	}
+	s.alter = alter.NewSchema(s.views, s.triggers)
+	s.runner = trigger.NewRunner(s.triggers)
+
+	objects, err := catalog{tx}.objects()
+	if err != nil {
+		return nil, fmt.Errorf("read catalog: %w", err)
+	}
+	for _, o := range objects {
+		if o.typ == typeIndex {
+			s.indexes[strings.ToLower(o.name)] = o
+		}
+	}
+	for _, o := range objects {
+		if err := s.compile(o); err != nil {
+			return nil, fmt.Errorf("compile %v %v: %w", o.typ, o.name, err)
+		}
+	}
+	if err := loadStats(tx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compile adds an object of the catalog to the schema.
+func (s *schema) compile(o object) error {
+	stmt, err := parseObject(o.sql)
+	if err != nil {
+		return err
+	}
+	switch {
+	case o.typ == typeTable && stmt.CreateTableStmt != nil:
+		return s.compileTable(o, stmt.CreateTableStmt)
+	case o.typ == typeIndex && stmt.CreateIndexStmt != nil:
+		// compiled with its table, which was created before
+		if _, ok := s.table(o.table); !ok {
+			return fmt.Errorf("%w: no such table: %v", ErrCorrupt, o.table)
+		}
+		return nil
+	case o.typ == typeTrigger && stmt.CreateTriggerStmt != nil:
+		def, err := s.defineTrigger(stmt.CreateTriggerStmt)
+		if err != nil {
+			return err
+		}
+		def.SQL = o.sql
+		return s.triggers.Create(def, s.hasView(def.Table), false)
+	case o.typ == typeView && stmt.CreateViewStmt != nil:
+		v := defineView(stmt.CreateViewStmt)
+		v.SQL = o.sql
+		return s.views.Load(v)
+	}
+	return fmt.Errorf("%w: %v", ErrCorrupt, o.sql)
+}
+
+// compileTable adds a table to the schema, and opens its secondary indexes.
+func (s *schema) compileTable(o object, stmt *ast.CreateTableStmt) error {
+	def, columns, err := defineTable(stmt)
+	if err != nil {
+		return err
+	}
+	columns = append(columns, column{relation: def.Name, name: rowIDNames[0], affinity: function.Integer, typed: true, hidden: true})
+	tbl := &storedTable{object: o, columns: columns, lookups: make(map[string][]int)}
+
+	var indexes []alter.Index
+	for _, ix := range s.indexes {
+		if !strings.EqualFold(ix.table, o.name) {
+			continue
+		}
+		stmt, err := parseObject(ix.sql)
+		if err != nil {
+			return err
+		}
+		if stmt.CreateIndexStmt == nil {
+			return fmt.Errorf("%w: %v", ErrCorrupt, ix.sql)
+		}
+		def, err := defineIndex(stmt.CreateIndexStmt, tbl)
+		if err != nil {
+			return fmt.Errorf("index %v: %w", ix.name, err)
+		}
+		indexes = append(indexes, def)
+		if cols := lookupColumns(stmt.CreateIndexStmt, tbl); len(cols) > 0 {
+			tbl.lookups[strings.ToLower(ix.name)] = cols
+		}
+	}
+	// the indexes are opened in the order in which they were created
+	sort.Slice(indexes, func(i, j int) bool {
+		return s.indexes[strings.ToLower(indexes[i].Definition.Name)].id < s.indexes[strings.ToLower(indexes[j].Definition.Name)].id
+	})
+	if tbl.t, err = s.alter.Create(def, storedRows{s.store, o.id, tablePrefixOf(o.id)}, indexes...); err != nil {
+		return err
+	}
+	for _, col := range def.Columns {
+		tbl.autoincrement = tbl.autoincrement || col.Autoincrement
+	}
+	s.tables[strings.ToLower(o.name)] = tbl
+	return nil
+}
+
+// table returns the table with the given name.
+func (s *schema) table(name string) (*storedTable, bool) {
+	t, ok := s.tables[strings.ToLower(name)]
+	return t, ok
+}
+
+// hasView reports whether the schema has a view with the given name.
+func (s *schema) hasView(name string) bool {
+	_, ok := s.views.View(name)
+	return ok
+}
+
+// hasIndex reports whether the schema has a secondary index with the given
+// name.
+func (s *schema) hasIndex(name string) bool {
+	_, ok := s.indexes[strings.ToLower(name)]
+	return ok
+}
+
+// parseObject parses the SQL text of an object of the catalog, which is a
+// single statement.
+func parseObject(sql string) (*ast.SQLStmt, error) {
+	stmt, errs, ok := parser.New(sql).Next()
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: empty definition", ErrCorrupt)
+	case len(errs) > 0:
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, errs[0])
+	}
+	return stmt, nil
+}
+
+// catalog reads and writes the catalog of a database.
+type catalog struct {
+	tx *transaction.Tx
+}
+
+// version returns the version of the catalog, which is nil for a database
+// without a schema.
+func (c catalog) version() ([]byte, error) {
+	version, _, err := c.tx.Get([]byte(versionKey))
+	return version, err
+}
+
+// objects returns all objects of the catalog, in the order in which they
+// were created.
+func (c catalog) objects() ([]object, error) {
+	var objects []object
+	start := []byte{catalogPrefix}
+	err := c.tx.Scan(start, prefixEnd(start), func(_, value []byte) (bool, error) {
+		values, err := decodeRecord(value)
+		if err != nil {
+			return false, err
+		}
+		o, err := objectFrom(values)
+		objects = append(objects, o)
+		return err == nil, err
+	})
+	sort.Slice(objects, func(i, j int) bool { return objects[i].id < objects[j].id })
+	return objects, err
+}
+
+// put writes an object to the catalog, and changes the version.
+func (c catalog) put(o object) error {
+	if err := c.tx.Put(catalogKey(o.name), encodeRecord(o.values())); err != nil {
+		return err
+	}
+	return c.changed()
+}
+
+// delete deletes an object from the catalog, and changes the version.
+func (c catalog) delete(name string) error {
+	if err := c.tx.Delete(catalogKey(name)); err != nil {
+		return err
+	}
+	return c.changed()
+}
+
+// changed sets the version of the catalog to a new random value. A counter
+// could return to a version, that a session has compiled, after a change was
+// rolled back.
+func (c catalog) changed() error {
+	version := make([]byte, 8)
+	if _, err := rand.Read(version); err != nil {
+		return fmt.Errorf("schema version: %w", err)
+	}
+	return c.tx.Put([]byte(versionKey), version)
+}
+
+// nextID returns the next unused ID of an object.
+func (c catalog) nextID() (int64, error) {
+	value, ok, err := c.tx.Get([]byte(nextIDKey))
+	if err != nil {
+		return 0, err
+	}
+	id := int64(1)
+	if ok {
+		n, size := binary.Varint(value)
+		if size <= 0 {
+			return 0, ErrCorrupt
+		}
+		id = n
+	}
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], id+1)
+	return id, c.tx.Put([]byte(nextIDKey), buf[:n])
+}
+
+// deletePrefix deletes all keys with the given prefix.
+func (c catalog) deletePrefix(prefix []byte) error {
+	var keys [][]byte
+	err := c.tx.Scan(prefix, prefixEnd(prefix), func(key, _ []byte) (bool, error) {
+		keys = append(keys, key)
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := c.tx.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSequence sets the AUTOINCREMENT sequence of the given table to the
+// value, that is stored in the database.
+func (c catalog) loadSequence(seq *constraint.Sequences, table string) error {
+	value, ok, err := c.tx.Get(sequenceKey(table))
+	if err != nil || !ok {
+		seq.Drop(table)
+		return err
+	}
+	n, size := binary.Varint(value)
+	if size <= 0 {
+		return ErrCorrupt
+	}
+	seq.Set(table, index.RowID(n))
+	return nil
+}
+
+// saveSequence stores the AUTOINCREMENT sequence of the given table, if it
+// changed.
+func (c catalog) saveSequence(seq *constraint.Sequences, table string) error {
+	id, ok := seq.Get(table)
+	if !ok {
+		return nil
+	}
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], int64(id))
+	value, _, err := c.tx.Get(sequenceKey(table))
+	if err != nil || bytes.Equal(value, buf[:n]) {
+		return err
+	}
+	return c.tx.Put(sequenceKey(table), buf[:n])
+}
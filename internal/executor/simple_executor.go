@@ -1,24 +1,231 @@
 package executor
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/rs/zerolog"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
 	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/cte"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+	"github.com/tomarrell/lbadd/internal/optimizer"
+	"github.com/tomarrell/lbadd/internal/planner"
 )
 
 var _ Executor = (*simpleExecutor)(nil)
 
 type simpleExecutor struct {
 	log zerolog.Logger
+	// session is the session that commands are executed in, or nil.
+	session *attach.Session
+	// schemas holds the compiled schemas of the databases of the session.
+	schemas map[*attach.Database]*schema
+	// foreignKeys indicates whether foreign keys are enforced, as set by
+	// PRAGMA foreign_keys.
+	foreignKeys bool
+	// recursiveTriggers indicates whether triggers fire while they are
+	// running, as set by PRAGMA recursive_triggers.
+	recursiveTriggers bool
+	// recursionLimit is the maximum depth of the recursion of recursive
+	// CTEs, as set by PRAGMA recursion_limit.
+	recursionLimit int
+	// memory is the pool, that the budgets of the queries are taken from,
+	// and settings holds the budget of a query, as set by PRAGMA
+	// query_memory.
+	memory   *spill.Pool
+	settings spill.Settings
 }
 
-func newSimpleExecutor(log zerolog.Logger) *simpleExecutor {
+func newSimpleExecutor(log zerolog.Logger, session *attach.Session) *simpleExecutor {
 	return &simpleExecutor{
-		log: log,
+		log:            log,
+		session:        session,
+		schemas:        make(map[*attach.Database]*schema),
+		recursionLimit: cte.DefaultMaxDepth,
+		memory:         spill.NewPool(0, 0, ""),
 	}
 }
 
-func (e *simpleExecutor) Execute(cmd command.Command) (Result, error) {
-	return nil, fmt.Errorf("unimplemented")
+func (e *simpleExecutor) Execute(ctx context.Context, cmd command.Command) (Result, error) {
+	e.log.Debug().
+		Str("op", cmd.Op.String()).
+		Msg("execute")
+
+	if cmd.Plan != nil {
+		cmd.Plan = optimizer.Optimize(cmd.Plan)
+	}
+
+	switch cmd.Explain {
+	case command.ExplainProgram:
+		return explainProgram(cmd), nil
+	case command.ExplainQueryPlan:
+		switch cmd.Op {
+		case command.Select, command.Insert, command.Update, command.Delete:
+			if e.session != nil {
+				return e.executeStatement(ctx, cmd)
+			}
+		}
+		return explainQueryPlan(cmd), nil
+	case command.ExplainAnalyze:
+		switch cmd.Op {
+		case command.Select, command.Insert, command.Update, command.Delete:
+			if e.session == nil {
+				return nil, ErrNoSession
+			}
+			return e.executeStatement(ctx, cmd)
+		}
+		return nil, fmt.Errorf("%w: EXPLAIN ANALYZE %v", ErrUnsupported, cmd.Op)
+	}
+
+	switch cmd.Op {
+	case command.Begin, command.Commit, command.Rollback, command.RollbackTo, command.Savepoint, command.Release:
+		if e.session == nil {
+			return nil, ErrNoSession
+		}
+		if err := e.executeTx(ctx, cmd); err != nil {
+			return nil, err
+		}
+		return table{}, nil
+	case command.Attach, command.Detach:
+		if e.session == nil {
+			return nil, ErrNoSession
+		}
+		if err := e.executeAttach(cmd); err != nil {
+			return nil, err
+		}
+		return table{}, nil
+	case command.Select, command.Insert, command.Update, command.Delete, command.CreateTable, command.DropTable,
+		command.CreateIndex, command.DropIndex, command.Reindex, command.CreateTrigger, command.DropTrigger, command.CreateView,
+		command.DropView, command.Analyze, command.AlterTable:
+		if e.session == nil {
+			return nil, ErrNoSession
+		}
+		return e.executeStatement(ctx, cmd)
+	case command.Pragma:
+		if e.session == nil {
+			return nil, ErrNoSession
+		}
+		return e.executePragma(ctx, cmd)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnsupported, cmd.Op)
+}
+
+// executeTx executes a command, that begins or ends a transaction, or works
+// with a savepoint.
+func (e *simpleExecutor) executeTx(ctx context.Context, cmd command.Command) error {
+	switch cmd.Op {
+	case command.Begin:
+		_, err := e.session.Begin(ctx, transaction.Options{Mode: cmd.Mode})
+		return err
+	case command.Commit:
+		return e.commit()
+	case command.Rollback:
+		err := e.session.Rollback()
+		e.resetConstraints()
+		return err
+	case command.RollbackTo:
+		return e.session.RollbackTo(cmd.Name)
+	case command.Savepoint:
+		return e.session.Savepoint(ctx, cmd.Name)
+	case command.Release:
+		return e.session.Release(cmd.Name)
+	}
+	return fmt.Errorf("%w: %v", ErrUnsupported, cmd.Op)
+}
+
+// executeAttach executes a command, that attaches or detaches a database.
+func (e *simpleExecutor) executeAttach(cmd command.Command) error {
+	if cmd.Op == command.Attach {
+		return e.session.Attach(cmd.File, cmd.Name)
+	}
+	db, ok := e.session.Database(cmd.Name)
+	if err := e.session.Detach(cmd.Name); err != nil {
+		return err
+	}
+	if ok {
+		delete(e.schemas, db)
+	}
+	return nil
+}
+
+// explainProgram returns the result of EXPLAIN, which is the program of the
+// command.
+func explainProgram(cmd command.Command) Result {
+	result := table{columns: command.ProgramColumns}
+	for _, i := range cmd.Program() {
+		result.rows = append(result.rows, i.Values())
+	}
+	return result
+}
+
+// explainQueryPlan returns the result of EXPLAIN QUERY PLAN, which describes
+// the plan of the command. Commands without a plan have no rows.
+func explainQueryPlan(cmd command.Command) Result {
+	result := table{columns: planner.ExplainColumns}
+	if cmd.Plan == nil {
+		return result
+	}
+	for _, row := range planner.Explain(cmd.Plan) {
+		result.rows = append(result.rows, row.Values())
+	}
+	return result
+}
+
+// explainQueryPlan returns the result of EXPLAIN QUERY PLAN for a query,
+// that is compiled with the tables of the session. The joins, that the
+// cost-based planner has planned, are described by the chosen access paths,
+// join order and join algorithms, with the estimated rows and costs.
+func (x *execution) explainQueryPlan(cmd command.Command) (Result, error) {
+	n := cmd.Plan
+	if cmd.Op != command.Select {
+		if len(n.Children) == 0 {
+			return explainQueryPlan(cmd), nil
+		}
+		n = n.Children[0]
+	}
+	x.plans = make(map[*planner.Node]*planner.Node)
+	if _, err := x.node(n, x.outer); err != nil {
+		return nil, err
+	}
+	cmd.Plan = physical(cmd.Plan, x.plans)
+	return explainQueryPlan(cmd), nil
+}
+
+// physical returns a copy of the given plan, in which the nodes, that the
+// cost-based planner has planned, are replaced by the nodes, that describe
+// the chosen plans.
+func physical(n *planner.Node, plans map[*planner.Node]*planner.Node) *planner.Node {
+	if p, ok := plans[n]; ok {
+		n = p
+	}
+	c := *n
+	c.Children = make([]*planner.Node, len(n.Children))
+	for i, child := range n.Children {
+		c.Children[i] = physical(child, plans)
+	}
+	c.Predicate = physicalExpr(n.Predicate, plans)
+	c.Projections = make([]*planner.Expr, len(n.Projections))
+	for i, e := range n.Projections {
+		c.Projections[i] = physicalExpr(e, plans)
+	}
+	return &c
+}
+
+// physicalExpr returns a copy of the given expression, in which the plans of
+// the subqueries are replaced like by physical.
+func physicalExpr(e *planner.Expr, plans map[*planner.Node]*planner.Node) *planner.Expr {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	c.Args = make([]*planner.Expr, len(e.Args))
+	for i, arg := range e.Args {
+		c.Args[i] = physicalExpr(arg, plans)
+	}
+	if e.Subquery != nil {
+		c.Subquery = physical(e.Subquery, plans)
+	}
+	return &c
 }
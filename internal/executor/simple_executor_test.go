@@ -0,0 +1,283 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/database/transaction"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/parser"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestExecute_Tx(t *testing.T) {
+	ctx := context.Background()
+	s, err := attach.Open(wal.OS, filepath.Join(t.TempDir(), "main.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+	exec := NewSession(zerolog.Nop(), s)
+
+	result, err := exec.Execute(ctx, command.Command{Op: command.Begin, Mode: transaction.Immediate})
+	require.NoError(t, err)
+	assert.Empty(t, result.Columns())
+	assert.Empty(t, result.Rows())
+	require.NotNil(t, s.Tx())
+
+	for _, cmd := range []command.Command{
+		{Op: command.Savepoint, Name: "a"},
+		{Op: command.RollbackTo, Name: "a"},
+		{Op: command.Release, Name: "a"},
+	} {
+		_, err = exec.Execute(ctx, cmd)
+		require.NoError(t, err, cmd.Op)
+	}
+	_, err = exec.Execute(ctx, command.Command{Op: command.Release, Name: "a"})
+	assert.True(t, errors.Is(err, transaction.ErrNoSavepoint))
+	_, err = exec.Execute(ctx, command.Command{Op: command.Begin})
+	assert.True(t, errors.Is(err, attach.ErrTxActive))
+
+	_, err = exec.Execute(ctx, command.Command{Op: command.Commit})
+	require.NoError(t, err)
+	assert.Nil(t, s.Tx())
+	_, err = exec.Execute(ctx, command.Command{Op: command.Rollback})
+	assert.True(t, errors.Is(err, attach.ErrNoTx))
+}
+
+func TestExecute_Attach(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := attach.Open(wal.OS, filepath.Join(dir, "main.db"))
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+	exec := NewSession(zerolog.Nop(), s)
+	archive := filepath.Join(dir, "archive.db")
+
+	_, err = exec.Execute(ctx, command.Command{Op: command.Attach, File: archive, Name: "archive"})
+	require.NoError(t, err)
+	db, ok := s.Database("archive")
+	require.True(t, ok)
+	assert.Equal(t, archive, db.File)
+	_, err = exec.Execute(ctx, command.Command{Op: command.Attach, File: archive, Name: "other"})
+	assert.True(t, errors.Is(err, attach.ErrAlreadyAttached))
+
+	_, err = exec.Execute(ctx, command.Command{Op: command.Detach, Name: "archive"})
+	require.NoError(t, err)
+	_, ok = s.Database("archive")
+	assert.False(t, ok)
+	_, err = exec.Execute(ctx, command.Command{Op: command.Detach, Name: "archive"})
+	assert.True(t, errors.Is(err, attach.ErrNoSuchDatabase))
+
+	_, err = New(zerolog.Nop()).Execute(ctx, command.Command{Op: command.Detach, Name: "archive"})
+	assert.True(t, errors.Is(err, ErrNoSession))
+}
+
+func TestExecute_Errors(t *testing.T) {
+	ctx := context.Background()
+	_, err := New(zerolog.Nop()).Execute(ctx, command.Command{Op: command.Begin})
+	assert.True(t, errors.Is(err, ErrNoSession))
+	_, err = New(zerolog.Nop()).Execute(ctx, command.Command{})
+	assert.True(t, errors.Is(err, ErrUnsupported))
+}
+
+func TestExecute_Explain(t *testing.T) {
+	ctx := context.Background()
+	plan := &planner.Node{
+		Op:   planner.HashJoin,
+		Rows: 100,
+		Cost: 2000,
+		Children: []*planner.Node{
+			{Op: planner.FullScan, Relation: "big", Rows: 1000, Cost: 1000},
+			{Op: planner.FullScan, Relation: "small", Rows: 10, Cost: 10},
+		},
+	}
+	// explained commands are not executed, so they need no session
+	exec := New(zerolog.Nop())
+
+	result, err := exec.Execute(ctx, command.Command{Op: command.Begin, Explain: command.ExplainProgram})
+	require.NoError(t, err)
+	assert.Equal(t, command.ProgramColumns, result.Columns())
+	assert.Equal(t, [][]interface{}{
+		{0, "Begin", 0, 0, "", "DEFERRED"},
+		{1, "Halt", 0, 0, "", ""},
+	}, result.Rows())
+
+	result, err = exec.Execute(ctx, command.Command{Explain: command.ExplainProgram, Plan: plan})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{2, "HashJoin", 0, 1, "", "HASH JOIN"}, result.Rows()[2])
+	assert.Len(t, result.Rows(), 5)
+
+	result, err = exec.Execute(ctx, command.Command{Explain: command.ExplainQueryPlan, Plan: plan})
+	require.NoError(t, err)
+	assert.Equal(t, planner.ExplainColumns, result.Columns())
+	assert.Equal(t, [][]interface{}{
+		{1, 0, "HASH JOIN", 100.0, 2000.0},
+		{2, 1, "SCAN big", 1000.0, 1000.0},
+		{3, 1, "SCAN small", 10.0, 10.0},
+	}, result.Rows())
+	assert.Equal(t, `id|parent|detail|rows|cost
+1|0|HASH JOIN|100|2000
+2|1|SCAN big|1000|1000
+3|1|SCAN small|10|10`, result.String())
+
+	result, err = exec.Execute(ctx, command.Command{Op: command.Commit, Explain: command.ExplainQueryPlan})
+	require.NoError(t, err)
+	assert.Equal(t, planner.ExplainColumns, result.Columns())
+	assert.Empty(t, result.Rows())
+}
+
+func TestExecute_Optimize(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			"outer join",
+			"SELECT customers.name FROM customers LEFT JOIN orders ON orders.customer = customers.id WHERE orders.total > 10",
+			`id|parent|detail|rows|cost
+1|0|PROJECT customers.name|0|0
+2|1|INNER JOIN ON orders.customer = customers.id|0|0
+3|2|SCAN customers (id, name)|0|0
+4|2|FILTER orders.total > 10|0|0
+5|4|SCAN orders (customer, total)|0|0`,
+		},
+		{
+			"in list and star",
+			"SELECT * FROM customers WHERE customers.id IN (1, 2) AND 1 = 1",
+			`id|parent|detail|rows|cost
+1|0|PROJECT *|0|0
+2|1|FILTER customers.id IN (1, 2)|0|0
+3|2|SCAN customers|0|0`,
+		},
+		{
+			"subquery in from",
+			"SELECT s.total FROM (SELECT orders.total FROM orders) AS s, customers WHERE s.total > 10 LIMIT 5",
+			`id|parent|detail|rows|cost
+1|0|PROJECT s.total|0|0
+2|1|LIMIT 5|0|0
+3|2|INNER JOIN|0|0
+4|3|FILTER s.total > 10|0|0
+5|4|PROJECT * AS s|0|0
+6|5|PROJECT orders.total|0|0
+7|6|SCAN orders (total)|0|0
+8|3|SCAN customers ()|0|0`,
+		},
+		{
+			"without from",
+			"SELECT abs(1 + 2), 'a' || 'b'",
+			`id|parent|detail|rows|cost
+1|0|PROJECT abs(3), 'a' || 'b'|0|0
+2|1|SCAN CONSTANT ROW|0|0`,
+		},
+		{
+			"in subquery",
+			"SELECT customers.name FROM customers WHERE customers.id IN (SELECT orders.customer FROM orders WHERE orders.total > 100)",
+			`id|parent|detail|rows|cost
+1|0|PROJECT customers.name|0|0
+2|1|SEMI JOIN ON customers.id = orders.customer|0|0
+3|2|SCAN customers (id, name)|0|0
+4|2|FILTER orders.total > 100|0|0
+5|4|SCAN orders (customer, total)|0|0`,
+		},
+		{
+			"delete with not exists",
+			"DELETE FROM orders WHERE NOT EXISTS (SELECT 1 FROM customers WHERE customers.id = orders.customer)",
+			`id|parent|detail|rows|cost
+1|0|DELETE FROM orders|0|0
+2|1|ANTI JOIN ON customers.id = orders.customer|0|0
+3|2|SCAN orders|0|0
+4|2|SCAN customers|0|0`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := parse(t, "EXPLAIN QUERY PLAN "+tt.query)
+			before := cmd.Plan.String()
+
+			result, err := New(zerolog.Nop()).Execute(context.Background(), cmd)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.String())
+			// the plan of the command is not modified
+			assert.Equal(t, before, cmd.Plan.String())
+		})
+	}
+}
+
+func TestExecute_ExplainStatements(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			"program of select",
+			"EXPLAIN SELECT t.a FROM t WHERE t.b = 1",
+			`addr|opcode|p1|p2|p3|comment
+0|FullScan|0|0|t|SCAN t (a, b)
+1|Selection|0|0||FILTER t.b = 1
+2|Projection|1|0||PROJECT t.a
+3|ResultRow|2|0||
+4|Halt|0|0||`,
+		},
+		{
+			"program of delete",
+			"EXPLAIN DELETE FROM t WHERE t.b = 2",
+			`addr|opcode|p1|p2|p3|comment
+0|FullScan|0|0|t|SCAN t
+1|Selection|0|0||FILTER t.b = 2
+2|Delete|1|0|t|DELETE FROM t
+3|Halt|0|0||`,
+		},
+		{
+			"aggregate",
+			"EXPLAIN QUERY PLAN SELECT t.a, count(*) AS n FROM t GROUP BY t.a ORDER BY n DESC",
+			`id|parent|detail|rows|cost
+1|0|PROJECT t.a, count(*)|0|0
+2|1|ORDER BY count(*) DESC|0|0
+3|2|AGGREGATE count(*) GROUP BY t.a|0|0
+4|3|SCAN t (a)|0|0`,
+		},
+		{
+			"insert select",
+			"EXPLAIN QUERY PLAN INSERT INTO t (a) SELECT s.a FROM s WHERE s.b = 1",
+			`id|parent|detail|rows|cost
+1|0|INSERT INTO t (a)|0|0
+2|1|PROJECT s.a|0|0
+3|2|FILTER s.b = 1|0|0
+4|3|SCAN s (a, b)|0|0`,
+		},
+		{
+			"update",
+			"EXPLAIN QUERY PLAN UPDATE t SET a = t.a + 1 WHERE t.b = 2",
+			`id|parent|detail|rows|cost
+1|0|UPDATE t SET a = t.a + 1|0|0
+2|1|FILTER t.b = 2|0|0
+3|2|SCAN t|0|0`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New(zerolog.Nop()).Execute(context.Background(), parse(t, tt.query))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.String())
+		})
+	}
+}
+
+// parse parses the given statement, and converts it to a command.
+func parse(t *testing.T, query string) command.Command {
+	stmt, errs, ok := parser.New(query).Next()
+	require.True(t, ok)
+	require.Empty(t, errs)
+	cmd, err := command.From(stmt)
+	require.NoError(t, err)
+	cmd.SQL = query
+	return cmd
+}
@@ -0,0 +1,199 @@
+package spill
+
+import "github.com/tomarrell/lbadd/internal/executor/function"
+
+const (
+	// fanOut is the amount of partitions, that the rows of an aggregation
+	// or a join are written to when they spill.
+	fanOut = 8
+	// maxDepth is the maximum depth of the partitioning. Partitions at this
+	// depth are processed in memory, regardless of the budget, since their
+	// rows probably all have the same key.
+	maxDepth = 8
+	// groupSize is the estimated memory that a group of a HashAggregate
+	// uses in addition to its key, and aggregateSize the memory of every
+	// aggregate of a group.
+	groupSize     = 64
+	aggregateSize = 64
+)
+
+// HashAggregate computes the groups of GROUP BY with a hash table, and the
+// aggregates of every group. DISTINCT is a HashAggregate, whose key is the
+// whole row, and which has no aggregates.
+//
+//	a := spill.NewHashAggregate(key, aggregates, step, budget)
+//	for ... {
+//		err := a.Add(row)
+//	}
+//	err := a.Groups(emit)
+//
+// Groups are emitted in no particular order. The memory of the state of an
+// aggregate, like the string of group_concat, is not included in the
+// budget.
+type HashAggregate struct {
+	key        func(row Row) (Row, error)
+	aggregates func() []function.Aggregate
+	step       func(aggregates []function.Aggregate, row Row) error
+	budget     *Budget
+	depth      int
+
+	groups     map[string]*group
+	order      []*group
+	reserved   int64
+	partitions []*file
+	stats      Stats
+	done       bool
+}
+
+type group struct {
+	key        Row
+	aggregates []function.Aggregate
+}
+
+// NewHashAggregate creates a hash aggregate. Key computes the values of the
+// GROUP BY terms of a row. Aggregates creates the aggregates of a new group,
+// and may be nil if there are no aggregates. Step adds a row to the
+// aggregates of its group, and may be nil as well.
+func NewHashAggregate(key func(row Row) (Row, error), aggregates func() []function.Aggregate, step func(aggregates []function.Aggregate, row Row) error, budget *Budget) *HashAggregate {
+	return &HashAggregate{
+		key:        key,
+		aggregates: aggregates,
+		step:       step,
+		budget:     budget,
+		groups:     make(map[string]*group),
+	}
+}
+
+// NewDistinct creates a hash aggregate that emits every distinct row once,
+// as in SELECT DISTINCT.
+func NewDistinct(budget *Budget) *HashAggregate {
+	return NewHashAggregate(func(row Row) (Row, error) { return row, nil }, nil, nil, budget)
+}
+
+// Add adds a row to its group. If the group doesn't exist and doesn't fit
+// into the budget, the row is written to a partition, and its group is
+// computed later. After the first row was written to a partition, the rows of
+// all new groups are.
+func (a *HashAggregate) Add(row Row) error {
+	if a.done {
+		return ErrDone
+	}
+	key, err := a.key(row)
+	if err != nil {
+		return err
+	}
+	k := string(appendKey(nil, key))
+	g, ok := a.groups[k]
+	if !ok {
+		g = &group{key: key}
+		if a.aggregates != nil {
+			g.aggregates = a.aggregates()
+		}
+		if a.partitions != nil && a.depth < maxDepth {
+			// rows of the group may have been spilled already, even if it
+			// would fit into the budget now
+			return a.spill(k, row)
+		}
+		n := groupSize + Size(key) + aggregateSize*int64(len(g.aggregates))
+		if !a.budget.Grow(n) {
+			if len(a.groups) != 0 && a.depth < maxDepth {
+				return a.spill(k, row)
+			}
+			a.budget.force(n)
+		}
+		a.reserved += n
+		a.stats.reserved(a.reserved)
+		a.groups[k] = g
+		a.order = append(a.order, g)
+	}
+	if a.step == nil {
+		return nil
+	}
+	return a.step(g.aggregates, row)
+}
+
+// spill writes the given row to its partition.
+func (a *HashAggregate) spill(key string, row Row) error {
+	if a.partitions == nil {
+		a.partitions = make([]*file, fanOut)
+		a.stats.Passes = a.depth + 1
+	}
+	p := partition(key, a.depth)
+	if a.partitions[p] == nil {
+		f, err := create(a.budget.dir(), &a.stats)
+		if err != nil {
+			return err
+		}
+		a.partitions[p] = f
+	}
+	return a.partitions[p].write(row)
+}
+
+// Groups emits the key and the aggregates of every group. After Groups, the
+// aggregate releases its memory and temporary files, and cannot be used
+// anymore.
+func (a *HashAggregate) Groups(emit func(key Row, aggregates []function.Aggregate) error) (err error) {
+	if a.done {
+		return ErrDone
+	}
+	defer func() {
+		if closeErr := a.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, g := range a.order {
+		if err := emit(g.key, g.aggregates); err != nil {
+			return err
+		}
+	}
+	a.release()
+
+	for _, p := range a.partitions {
+		if p == nil {
+			continue
+		}
+		child := NewHashAggregate(a.key, a.aggregates, a.step, a.budget)
+		child.depth = a.depth + 1
+		err := feed(p, child.Add)
+		if err == nil {
+			err = child.Groups(emit)
+		}
+		a.stats.Add(child.stats)
+		if err != nil {
+			_ = child.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// release releases the groups in memory.
+func (a *HashAggregate) release() {
+	a.groups, a.order = nil, nil
+	a.budget.Shrink(a.reserved)
+	a.reserved = 0
+}
+
+// Stats returns how much the aggregate spilled.
+func (a *HashAggregate) Stats() Stats {
+	return a.stats
+}
+
+// Close releases the memory and the temporary files of the aggregate. It is
+// not necessary to call Close after Groups.
+func (a *HashAggregate) Close() error {
+	if a.done {
+		return nil
+	}
+	a.done = true
+	a.release()
+	var files []*file
+	for _, p := range a.partitions {
+		if p != nil {
+			files = append(files, p)
+		}
+	}
+	a.partitions = nil
+	return removeAll(files)
+}
@@ -0,0 +1,135 @@
+package spill
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+)
+
+func TestHashAggregate(t *testing.T) {
+	registry := function.NewRegistry()
+	count, err := registry.Aggregate("count", 0)
+	require.NoError(t, err)
+	sum, err := registry.Aggregate("sum", 1)
+	require.NoError(t, err)
+
+	// SELECT g, count(*), sum(v) FROM rows GROUP BY g, where integers and
+	// integral reals are the same group
+	var rows []Row
+	for i := 0; i < 3000; i++ {
+		var g function.Value = int64(i % 500)
+		if i%3 == 0 {
+			g = float64(i % 500)
+		}
+		rows = append(rows, Row{g, int64(i)})
+	}
+	want := make(map[int64][2]int64)
+	for i := int64(0); i < 3000; i++ {
+		w := want[i%500]
+		want[i%500] = [2]int64{w[0] + 1, w[1] + i}
+	}
+
+	tests := []struct {
+		name  string
+		limit int64
+	}{
+		{"in memory", 0},
+		{"spilled", 8 << 10},
+		{"spilled recursively", 1 << 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewHashAggregate(
+				func(row Row) (Row, error) { return row[:1], nil },
+				func() []function.Aggregate { return []function.Aggregate{count.New(), sum.New()} },
+				func(aggregates []function.Aggregate, row Row) error {
+					if err := aggregates[0].Step(); err != nil {
+						return err
+					}
+					return aggregates[1].Step(row[1])
+				},
+				newBudget(t, tt.limit),
+			)
+			for _, row := range rows {
+				require.NoError(t, a.Add(row))
+			}
+
+			got := make(map[int64][2]int64)
+			require.NoError(t, a.Groups(func(key Row, aggregates []function.Aggregate) error {
+				g := key[0]
+				if f, ok := g.(float64); ok {
+					g = int64(f)
+				}
+				_, dup := got[g.(int64)]
+				assert.False(t, dup, "group %v is emitted twice", g)
+				c, _ := aggregates[0].Final()
+				s, _ := aggregates[1].Final()
+				got[g.(int64)] = [2]int64{c.(int64), s.(int64)}
+				return nil
+			}))
+			assert.Equal(t, want, got)
+
+			stats := a.Stats()
+			assert.Equal(t, tt.limit != 0, stats.Spilled())
+			if tt.name == "spilled recursively" {
+				assert.Greater(t, stats.Passes, 1)
+			}
+		})
+	}
+}
+
+func TestHashAggregate_Released(t *testing.T) {
+	registry := function.NewRegistry()
+	count, err := registry.Aggregate("count", 0)
+	require.NoError(t, err)
+
+	// another operator of the query releases its memory, after the groups
+	// spilled
+	budget := newBudget(t, 1<<10)
+	require.True(t, budget.Grow(512))
+	a := NewHashAggregate(
+		func(row Row) (Row, error) { return row, nil },
+		func() []function.Aggregate { return []function.Aggregate{count.New()} },
+		func(aggregates []function.Aggregate, row Row) error { return aggregates[0].Step() },
+		budget,
+	)
+	for i := 0; i < 50; i++ {
+		require.NoError(t, a.Add(Row{int64(i)}))
+	}
+	budget.Shrink(512)
+	for i := 0; i < 50; i++ {
+		require.NoError(t, a.Add(Row{int64(i)}))
+	}
+
+	got := make(map[int64]int64)
+	require.NoError(t, a.Groups(func(key Row, aggregates []function.Aggregate) error {
+		_, dup := got[key[0].(int64)]
+		assert.False(t, dup, "group %v is emitted twice", key[0])
+		c, _ := aggregates[0].Final()
+		got[key[0].(int64)] = c.(int64)
+		return nil
+	}))
+	assert.Len(t, got, 50)
+	for g, c := range got {
+		assert.Equal(t, int64(2), c, "group %v", g)
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	d := NewDistinct(newBudget(t, 512))
+	for i := 0; i < 200; i++ {
+		require.NoError(t, d.Add(Row{int64(i % 20), nil}))
+		require.NoError(t, d.Add(Row{"x", []byte{byte(i % 3)}}))
+	}
+	var got []Row
+	require.NoError(t, d.Groups(func(key Row, aggregates []function.Aggregate) error {
+		assert.Nil(t, aggregates)
+		got = append(got, key)
+		return nil
+	}))
+	assert.Len(t, got, 23)
+	assert.True(t, d.Stats().Spilled())
+	assert.Equal(t, ErrDone, d.Add(Row{nil}))
+}
@@ -0,0 +1,175 @@
+package spill
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Pool is the memory that all queries of a server can use together. A Pool
+// is safe for concurrent use.
+type Pool struct {
+	// queryLimit is the default limit of the budgets of queries.
+	queryLimit int64
+	// dir is the directory of the temporary files, or empty for the default
+	// temporary directory of the operating system.
+	dir string
+
+	mu    sync.Mutex
+	limit int64
+	used  int64
+}
+
+// NewPool creates a pool with the given limit in bytes, whose queries can
+// use at most queryLimit bytes by default, and write temporary files to the
+// given directory. A limit of 0 means that the pool or the queries are
+// unlimited, and an empty directory means the temporary directory of the
+// operating system.
+func NewPool(limit, queryLimit int64, dir string) *Pool {
+	return &Pool{limit: limit, queryLimit: queryLimit, dir: dir}
+}
+
+// Used returns the amount of bytes that are used by all queries.
+func (p *Pool) Used() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.used
+}
+
+// Budget creates the budget of a query, that can use at most the given amount
+// of bytes, as long as the pool has enough memory left. A limit of 0 means
+// the default limit of the queries of the pool. The budget must be closed
+// after the query.
+func (p *Pool) Budget(limit int64) *Budget {
+	if limit == 0 {
+		limit = p.queryLimit
+	}
+	return &Budget{pool: p, limit: limit}
+}
+
+// grow reserves n bytes, if force is set or the pool has enough memory left.
+func (p *Pool) grow(n int64, force bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !force && p.limit > 0 && p.used+n > p.limit {
+		return false
+	}
+	p.used += n
+	return true
+}
+
+func (p *Pool) shrink(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.used -= n
+}
+
+// Budget is the memory that a single query can use. A nil Budget is
+// unlimited, and never causes operators to spill. A Budget is safe for
+// concurrent use.
+type Budget struct {
+	pool *Pool
+
+	mu    sync.Mutex
+	limit int64
+	used  int64
+}
+
+// Grow reserves n more bytes, and reports whether the budget of the query and
+// the pool allowed it.
+func (b *Budget) Grow(n int64) bool {
+	return b.grow(n, false)
+}
+
+// force reserves n more bytes, even if that exceeds the budget. Operators
+// use it to make progress, when not even a single row fits into the budget.
+func (b *Budget) force(n int64) {
+	b.grow(n, true)
+}
+
+func (b *Budget) grow(n int64, force bool) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !force && b.limit > 0 && b.used+n > b.limit {
+		return false
+	}
+	if !b.pool.grow(n, force) {
+		return false
+	}
+	b.used += n
+	return true
+}
+
+// Shrink releases n bytes, that were reserved with Grow.
+func (b *Budget) Shrink(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used -= n
+	b.pool.shrink(n)
+}
+
+// Used returns the amount of bytes that are reserved by the query.
+func (b *Budget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Close releases all memory of the query.
+func (b *Budget) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pool.shrink(b.used)
+	b.used = 0
+}
+
+// dir returns the directory of the temporary files.
+func (b *Budget) dir() string {
+	if b == nil {
+		return ""
+	}
+	return b.pool.dir
+}
+
+// sizeUnits are the units that ParseSize accepts, from the longest to the
+// shortest suffix.
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses an amount of bytes, which is a non-negative integer with
+// an optional unit, like 512, 64KB, 256M or 1GiB. The units are powers of
+// 1024.
+func ParseSize(s string) (int64, error) {
+	number, factor := strings.ToUpper(strings.TrimSpace(s)), int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, factor = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix)), unit.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 || n > (1<<63-1)/factor {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidSize, s)
+	}
+	return n * factor, nil
+}
@@ -0,0 +1,81 @@
+package spill
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBudget creates a budget with the given limit, in a pool whose temporary
+// files are written to a new directory. The test fails if any temporary file
+// is left, or any memory is still reserved, at the end of the test.
+func newBudget(t *testing.T, limit int64) *Budget {
+	dir := t.TempDir()
+	pool := NewPool(0, 0, dir)
+	b := pool.Budget(limit)
+	t.Cleanup(func() {
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "temporary files are left")
+		assert.Zero(t, pool.Used(), "memory is still reserved")
+	})
+	return b
+}
+
+func TestBudget(t *testing.T) {
+	pool := NewPool(100, 60, "")
+	a, b := pool.Budget(0), pool.Budget(200)
+
+	assert.True(t, a.Grow(50))
+	assert.False(t, a.Grow(20), "exceeds the query limit")
+	assert.True(t, b.Grow(40))
+	assert.False(t, b.Grow(20), "exceeds the pool limit")
+	assert.Equal(t, int64(90), pool.Used())
+
+	a.Shrink(30)
+	assert.True(t, b.Grow(20))
+	a.force(100)
+	assert.Equal(t, int64(120), a.Used())
+	a.Close()
+	assert.Zero(t, a.Used())
+	assert.Equal(t, int64(60), pool.Used())
+
+	var unlimited *Budget
+	assert.True(t, unlimited.Grow(1<<40))
+	unlimited.Close()
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+		err  bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"64KB", 64 << 10, false},
+		{"64 kib", 64 << 10, false},
+		{"256M", 256 << 20, false},
+		{"1GiB", 1 << 30, false},
+		{"", 0, true},
+		{"-1", 0, true},
+		{"1.5GB", 0, true},
+		{"12TB", 0, true},
+		{"9223372036854775807G", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			n, err := ParseSize(tt.s)
+			if tt.err {
+				assert.True(t, errors.Is(err, ErrInvalidSize), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, n)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Package spill implements the operators of the executor, that may need more
+// memory than a query is allowed to use: sorts for ORDER BY, hash
+// aggregations for GROUP BY and DISTINCT, and hash joins. When an operator
+// exceeds the memory budget of its query, it writes rows to temporary files,
+// and continues with less memory.
+//
+// A Sorter sorts as many rows as fit into the budget in memory, and writes
+// them to a file as a sorted run. After all rows were added, the runs are
+// combined with a k-way merge. If there are more runs than can be merged at
+// once, groups of runs are merged into longer runs first.
+//
+// A HashAggregate keeps the groups that fit into the budget in memory. Rows
+// of groups that don't fit are written to one of several partition files,
+// depending on the hash of their group key. Every partition file is
+// aggregated on its own after all rows were added, and is partitioned again
+// if it still doesn't fit.
+//
+// A HashJoin is a grace hash join. If the rows of its build side don't fit
+// into the budget, the rows of both sides are partitioned by the hash of
+// their join key, and each pair of partitions is joined on its own.
+//
+// The budget of a query is limited by the Pool of the server, which is
+// shared by all queries. Every operator reports how much it spilled with its
+// Stats.
+package spill
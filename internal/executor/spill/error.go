@@ -0,0 +1,13 @@
+package spill
+
+// Error provides constant errors to the spill package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrInvalidSize = Error("invalid size")
+	ErrCorrupt     = Error("temporary file is corrupt")
+	ErrDone        = Error("operator has already finished")
+)
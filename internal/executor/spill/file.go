@@ -0,0 +1,307 @@
+package spill
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/tomarrell/lbadd/internal/executor/function"
+)
+
+// Row is a row that an operator processes.
+type Row = []function.Value
+
+// Size estimates the amount of memory that the given row uses.
+func Size(row Row) int64 {
+	// the slice header, and the interface value of every column
+	n := int64(24 + 16*len(row))
+	for _, v := range row {
+		switch v := v.(type) {
+		case string:
+			n += int64(len(v))
+		case []byte:
+			n += int64(len(v))
+		case int64, float64:
+			n += 8
+		}
+	}
+	return n
+}
+
+// Stats describes how much an operator spilled to temporary files, and how
+// much memory it used.
+type Stats struct {
+	// Files is the amount of temporary files that were written, which are
+	// the sorted runs of a sort, or the partitions of an aggregation or a
+	// join.
+	Files int
+	// Rows and Bytes are the amount of rows and bytes that were written to
+	// temporary files.
+	Rows, Bytes int64
+	// Passes is the amount of merges of a sort, or the maximum depth of the
+	// partitioning of an aggregation or a join.
+	Passes int
+	// Memory is the peak amount of bytes, that the operator reserved in its
+	// budget.
+	Memory int64
+}
+
+// Spilled reports whether the operator wrote any temporary files.
+func (s Stats) Spilled() bool {
+	return s.Files > 0
+}
+
+// String describes the stats, like "spilled 12 files, 10000 rows, 1.2 MB,
+// 2 passes".
+func (s Stats) String() string {
+	if !s.Spilled() {
+		return "in memory"
+	}
+	return fmt.Sprintf("spilled %d files, %d rows, %v, %d passes", s.Files, s.Rows, FormatBytes(s.Bytes), s.Passes)
+}
+
+// FormatBytes formats an amount of bytes with a binary unit, like 1.2 MB.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Add adds the given stats to these stats. The passes and the memory are the
+// maximum of both.
+func (s *Stats) Add(other Stats) {
+	s.Files += other.Files
+	s.Rows += other.Rows
+	s.Bytes += other.Bytes
+	if other.Passes > s.Passes {
+		s.Passes = other.Passes
+	}
+	s.reserved(other.Memory)
+}
+
+// reserved records, that the operator reserved the given amount of bytes.
+func (s *Stats) reserved(n int64) {
+	if n > s.Memory {
+		s.Memory = n
+	}
+}
+
+// Tags of the encoded values in temporary files.
+const (
+	tagNull byte = iota
+	tagInteger
+	tagReal
+	tagText
+	tagBlob
+)
+
+// file is a temporary file, that holds a sequence of rows. Rows are written
+// to it, until it is rewound, after which they can be read. The file is
+// deleted by remove.
+type file struct {
+	f     *os.File
+	w     *bufio.Writer
+	r     *bufio.Reader
+	buf   []byte
+	rows  int64
+	stats *Stats
+}
+
+// create creates a new temporary file in the given directory, whose writes
+// are counted in the given stats.
+func create(dir string, stats *Stats) (*file, error) {
+	f, err := os.CreateTemp(dir, "lbadd-spill-")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary file: %w", err)
+	}
+	stats.Files++
+	return &file{f: f, w: bufio.NewWriter(f), stats: stats}, nil
+}
+
+// write appends the given row to the file.
+func (f *file) write(row Row) error {
+	f.buf = appendRow(f.buf[:0], row)
+	if _, err := f.w.Write(f.buf); err != nil {
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	f.rows++
+	f.stats.Rows++
+	f.stats.Bytes += int64(len(f.buf))
+	return nil
+}
+
+// rewind finishes writing, and prepares the file to read the rows from the
+// beginning.
+func (f *file) rewind() error {
+	if err := f.w.Flush(); err != nil {
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if _, err := f.f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind temporary file: %w", err)
+	}
+	f.r = bufio.NewReader(f.f)
+	return nil
+}
+
+// read reads the next row. It returns io.EOF after the last row.
+func (f *file) read() (Row, error) {
+	n, err := binary.ReadUvarint(f.r)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read temporary file: %w", err)
+	}
+	row := make(Row, n)
+	for i := range row {
+		if row[i], err = readValue(f.r); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// remove closes and deletes the file.
+func (f *file) remove() error {
+	err := f.f.Close()
+	if removeErr := os.Remove(f.f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// removeAll removes all given files, and returns the first error.
+func removeAll(files []*file) error {
+	var firstErr error
+	for _, f := range files {
+		if err := f.remove(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// appendRow appends the encoding of the given row, which is the amount of
+// values, followed by every value with its tag.
+func appendRow(buf []byte, row Row) []byte {
+	buf = appendUvarint(buf, uint64(len(row)))
+	for _, v := range row {
+		switch v := v.(type) {
+		case nil:
+			buf = append(buf, tagNull)
+		case int64:
+			buf = append(buf, tagInteger)
+			buf = appendVarint(buf, v)
+		case float64:
+			buf = append(buf, tagReal)
+			buf = appendUint64(buf, math.Float64bits(v))
+		case string:
+			buf = append(buf, tagText)
+			buf = appendUvarint(buf, uint64(len(v)))
+			buf = append(buf, v...)
+		case []byte:
+			buf = append(buf, tagBlob)
+			buf = appendUvarint(buf, uint64(len(v)))
+			buf = append(buf, v...)
+		}
+	}
+	return buf
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutVarint(b[:], v)]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func readValue(r *bufio.Reader) (function.Value, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	switch tag {
+	case tagNull:
+		return nil, nil
+	case tagInteger:
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		return v, nil
+	case tagReal:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case tagText, tagBlob:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		if tag == tagText {
+			return string(b), nil
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: unknown tag %d", ErrCorrupt, tag)
+}
+
+// appendKey appends an encoding of the given row, that is equal for two
+// rows, if and only if function.Compare returns 0 for all of their values.
+func appendKey(buf []byte, row Row) []byte {
+	for _, v := range row {
+		if f, ok := v.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+			// integral reals are equal to integers
+			v = int64(f)
+		}
+		buf = appendRow(buf, Row{v})
+	}
+	return buf
+}
+
+// hasNull reports whether any value of the given row is NULL.
+func hasNull(row Row) bool {
+	for _, v := range row {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// partition returns the partition of the given key at the given depth of
+// the partitioning. Every depth uses a different hash function, so that the
+// rows of a partition are spread over all partitions of the next depth.
+func partition(key string, depth int) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte{byte(depth)})
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum64() % fanOut)
+}
@@ -0,0 +1,241 @@
+package spill
+
+import "io"
+
+// entrySize is the estimated memory, that an entry of the hash table of a
+// HashJoin uses in addition to its row and key.
+const entrySize = 32
+
+// HashJoin joins the rows of two inputs, whose join keys are equal, with a
+// hash table of the rows of the build side. If they don't fit into the
+// budget, it becomes a grace hash join, which partitions the rows of both
+// sides, and joins every pair of partitions on its own.
+//
+//	j := spill.NewHashJoin(buildKey, probeKey, budget)
+//	for ... {
+//		err := j.Build(row)
+//	}
+//	for ... {
+//		err := j.Probe(row, emit)
+//	}
+//	err := j.Finish(emit)
+//
+// Rows are joined, if all values of their keys are equal. Keys with a NULL
+// value never match.
+type HashJoin struct {
+	buildKey, probeKey func(row Row) (Row, error)
+	budget             *Budget
+	// Left indicates a LEFT JOIN, for which the rows of the probe side are
+	// emitted with a nil row, if they match no row of the build side.
+	Left  bool
+	depth int
+
+	table    map[string][]Row
+	reserved int64
+	// build and probe hold the partitions of both sides, after the build
+	// side spilled.
+	build, probe []*file
+	probing      bool
+	stats        Stats
+	done         bool
+}
+
+// NewHashJoin creates a hash join, that computes the join key of the rows of
+// the build side with buildKey, and of the rows of the probe side with
+// probeKey.
+func NewHashJoin(buildKey, probeKey func(row Row) (Row, error), budget *Budget) *HashJoin {
+	return &HashJoin{
+		buildKey: buildKey,
+		probeKey: probeKey,
+		budget:   budget,
+		table:    make(map[string][]Row),
+	}
+}
+
+// Build adds a row of the build side. All rows of the build side must be
+// added before the first row is probed.
+func (j *HashJoin) Build(row Row) error {
+	if j.done || j.probing {
+		return ErrDone
+	}
+	key, err := j.buildKey(row)
+	if err != nil || hasNull(key) {
+		return err
+	}
+	k := string(appendKey(nil, key))
+	if j.build != nil {
+		return j.write(j.build, k, row)
+	}
+
+	n := entrySize + int64(len(k)) + Size(row)
+	if !j.budget.Grow(n) {
+		if len(j.table) != 0 && j.depth < maxDepth {
+			if err := j.spill(); err != nil {
+				return err
+			}
+			return j.write(j.build, k, row)
+		}
+		j.budget.force(n)
+	}
+	j.reserved += n
+	j.stats.reserved(j.reserved)
+	j.table[k] = append(j.table[k], row)
+	return nil
+}
+
+// spill writes the hash table to the partitions of the build side.
+func (j *HashJoin) spill() error {
+	j.build = make([]*file, fanOut)
+	j.probe = make([]*file, fanOut)
+	j.stats.Passes = j.depth + 1
+	for k, rows := range j.table {
+		for _, row := range rows {
+			if err := j.write(j.build, k, row); err != nil {
+				return err
+			}
+		}
+	}
+	j.release()
+	return nil
+}
+
+// write writes a row with the given key to its partition.
+func (j *HashJoin) write(partitions []*file, key string, row Row) error {
+	p := partition(key, j.depth)
+	if partitions[p] == nil {
+		f, err := create(j.budget.dir(), &j.stats)
+		if err != nil {
+			return err
+		}
+		partitions[p] = f
+	}
+	return partitions[p].write(row)
+}
+
+// Probe joins a row of the probe side with the matching rows of the build
+// side, and emits every pair. If the build side spilled, the row is written
+// to a partition, and joined by Finish.
+func (j *HashJoin) Probe(row Row, emit func(probe, build Row) error) error {
+	if j.done {
+		return ErrDone
+	}
+	j.probing = true
+	key, err := j.probeKey(row)
+	if err != nil {
+		return err
+	}
+	if hasNull(key) {
+		if j.Left {
+			return emit(row, nil)
+		}
+		return nil
+	}
+	k := string(appendKey(nil, key))
+	if j.probe != nil {
+		return j.write(j.probe, k, row)
+	}
+
+	matches := j.table[k]
+	if len(matches) == 0 && j.Left {
+		return emit(row, nil)
+	}
+	for _, match := range matches {
+		if err := emit(row, match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finish joins the partitions, if the build side spilled, and emits the
+// joined rows. After Finish, the join releases its memory and temporary
+// files, and cannot be used anymore.
+func (j *HashJoin) Finish(emit func(probe, build Row) error) (err error) {
+	if j.done {
+		return ErrDone
+	}
+	defer func() {
+		if closeErr := j.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	j.release()
+
+	for i := range j.probe {
+		if j.probe[i] == nil || j.build[i] == nil && !j.Left {
+			continue
+		}
+		child := NewHashJoin(j.buildKey, j.probeKey, j.budget)
+		child.Left = j.Left
+		child.depth = j.depth + 1
+		err := j.join(child, j.build[i], j.probe[i], emit)
+		j.stats.Add(child.stats)
+		if err != nil {
+			_ = child.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// join joins the given partitions with the given join.
+func (j *HashJoin) join(child *HashJoin, build, probe *file, emit func(probe, build Row) error) error {
+	if build != nil {
+		if err := feed(build, child.Build); err != nil {
+			return err
+		}
+	}
+	if err := feed(probe, func(row Row) error { return child.Probe(row, emit) }); err != nil {
+		return err
+	}
+	return child.Finish(emit)
+}
+
+// feed rewinds the given file, and calls fn for all of its rows.
+func feed(f *file, fn func(row Row) error) error {
+	if err := f.rewind(); err != nil {
+		return err
+	}
+	for {
+		row, err := f.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// release releases the hash table.
+func (j *HashJoin) release() {
+	j.table = nil
+	j.budget.Shrink(j.reserved)
+	j.reserved = 0
+}
+
+// Stats returns how much the join spilled.
+func (j *HashJoin) Stats() Stats {
+	return j.stats
+}
+
+// Close releases the memory and the temporary files of the join. It is not
+// necessary to call Close after Finish.
+func (j *HashJoin) Close() error {
+	if j.done {
+		return nil
+	}
+	j.done = true
+	j.release()
+	var files []*file
+	for _, p := range append(j.build, j.probe...) {
+		if p != nil {
+			files = append(files, p)
+		}
+	}
+	j.build, j.probe = nil, nil
+	return removeAll(files)
+}
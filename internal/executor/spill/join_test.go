@@ -0,0 +1,92 @@
+package spill
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func first(row Row) (Row, error) {
+	return row[:1], nil
+}
+
+func TestHashJoin(t *testing.T) {
+	// the build side has 3 rows for every even key, the probe side has a
+	// row for every key, and a row with a NULL key
+	var build, probe []Row
+	for i := 0; i < 2000; i++ {
+		if k := i % 1000; k%2 == 0 || i < 1000 {
+			build = append(build, Row{int64(k), fmt.Sprintf("build %d", i)})
+		}
+	}
+	build = append(build, Row{nil, "build null"})
+	for i := 0; i < 1000; i++ {
+		probe = append(probe, Row{float64(i), fmt.Sprintf("probe %d", i)})
+	}
+	probe = append(probe, Row{nil, "probe null"})
+
+	// join with nested loops
+	expected := func(left bool) []string {
+		var want []string
+		for _, p := range probe {
+			matched := false
+			for _, b := range build {
+				if p[0] != nil && b[0] != nil && int64(p[0].(float64)) == b[0].(int64) {
+					want = append(want, p[1].(string)+"|"+b[1].(string))
+					matched = true
+				}
+			}
+			if !matched && left {
+				want = append(want, p[1].(string)+"|")
+			}
+		}
+		sort.Strings(want)
+		return want
+	}
+
+	tests := []struct {
+		name  string
+		limit int64
+		left  bool
+	}{
+		{"in memory", 0, false},
+		{"in memory left", 0, true},
+		{"grace", 32 << 10, false},
+		{"grace left", 32 << 10, true},
+		{"grace recursive", 2 << 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := NewHashJoin(first, first, newBudget(t, tt.limit))
+			j.Left = tt.left
+			for _, row := range build {
+				require.NoError(t, j.Build(row))
+			}
+			var got []string
+			emit := func(p, b Row) error {
+				s := p[1].(string) + "|"
+				if b != nil {
+					s += b[1].(string)
+				}
+				got = append(got, s)
+				return nil
+			}
+			for _, row := range probe {
+				require.NoError(t, j.Probe(row, emit))
+			}
+			assert.Equal(t, ErrDone, j.Build(build[0]))
+			require.NoError(t, j.Finish(emit))
+
+			sort.Strings(got)
+			assert.Equal(t, expected(tt.left), got)
+			stats := j.Stats()
+			assert.Equal(t, tt.limit != 0, stats.Spilled())
+			if tt.name == "grace recursive" {
+				assert.Greater(t, stats.Passes, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package spill
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// PragmaQueryMemory is the name of the pragma, that sets the memory budget of
+// the queries of a session, like PRAGMA query_memory = '64MB'. A value of 0
+// restores the default budget of the server.
+const PragmaQueryMemory = "query_memory"
+
+// Settings are the memory settings of a session.
+type Settings struct {
+	// QueryMemory is the budget of every query of the session in bytes, or 0
+	// for the default budget of the server.
+	QueryMemory int64
+}
+
+// Budget creates the budget of a query of the session from the given pool.
+func (s *Settings) Budget(pool *Pool) *Budget {
+	return pool.Budget(s.QueryMemory)
+}
+
+// ExecPragma executes the given PRAGMA statement, if it is a memory pragma,
+// and returns the value of the pragma after the statement. If it is no memory
+// pragma, ok is false.
+func (s *Settings) ExecPragma(stmt *ast.PragmaStmt) (value int64, ok bool, err error) {
+	if stmt == nil || stmt.PragmaName == nil || !strings.EqualFold(stmt.PragmaName.Value(), PragmaQueryMemory) {
+		return 0, false, nil
+	}
+	if stmt.PragmaValue == nil {
+		return s.QueryMemory, true, nil
+	}
+
+	size, err := ParseSize(pragmaValue(stmt.PragmaValue))
+	if err != nil {
+		return 0, true, fmt.Errorf("pragma %s: %w", PragmaQueryMemory, err)
+	}
+	s.QueryMemory = size
+	return size, true, nil
+}
+
+// pragmaValue returns the value of a pragma as a string, without quotes.
+func pragmaValue(value *ast.PragmaValue) string {
+	if num := value.SignedNumber; num != nil {
+		return tokenValue(num.Sign) + tokenValue(num.NumericLiteral)
+	}
+	v := tokenValue(value.Name)
+	if len(v) >= 2 && (v[0] == '\'' || v[0] == '"') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+func tokenValue(tk token.Token) string {
+	if tk == nil {
+		return ""
+	}
+	return tk.Value()
+}
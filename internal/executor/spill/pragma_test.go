@@ -0,0 +1,63 @@
+package spill
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/parser"
+)
+
+func TestSettings_ExecPragma(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		value   int64
+		ok      bool
+		wantErr error
+	}{
+		{"read default", "PRAGMA query_memory", 0, true, nil},
+		{"set string", "PRAGMA query_memory = '64MB'", 64 << 20, true, nil},
+		{"set number", "PRAGMA main.query_memory(4096)", 4096, true, nil},
+		{"reset", "PRAGMA query_memory = 0", 0, true, nil},
+		{"negative", "PRAGMA query_memory = -1", 0, true, ErrInvalidSize},
+		{"other pragma", "PRAGMA cache_size = 10", 0, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			stmt, errs, ok := parser.New(tt.query).Next()
+			assert.True(ok)
+			assert.Empty(errs)
+
+			var settings Settings
+			value, ok, err := settings.ExecPragma(stmt.PragmaStmt)
+			assert.Equal(tt.ok, ok)
+			if tt.wantErr != nil {
+				assert.True(errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.value, value)
+			assert.Equal(tt.value, settings.QueryMemory)
+		})
+	}
+}
+
+func TestSettings_Budget(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := NewPool(0, 1024, t.TempDir())
+	var settings Settings
+	budget := settings.Budget(pool)
+	assert.True(budget.Grow(1024))
+	assert.False(budget.Grow(1))
+	budget.Close()
+
+	settings.QueryMemory = 2048
+	budget = settings.Budget(pool)
+	assert.True(budget.Grow(2048))
+	budget.Close()
+	assert.Zero(pool.Used())
+}
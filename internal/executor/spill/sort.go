@@ -0,0 +1,238 @@
+package spill
+
+import (
+	"container/heap"
+	"io"
+	"sort"
+)
+
+// DefaultFanIn is the default maximum amount of runs, that a Sorter merges
+// at once.
+const DefaultFanIn = 64
+
+// Sorter sorts rows for ORDER BY. The sort is stable, so rows that compare
+// equal are emitted in the order in which they were added.
+//
+//	s := spill.NewSorter(compare, budget)
+//	for ... {
+//		err := s.Add(row)
+//	}
+//	err := s.Sort(emit)
+type Sorter struct {
+	compare func(a, b Row) int
+	budget  *Budget
+	// FanIn is the maximum amount of runs that are merged at once. It must
+	// be at least 2.
+	FanIn int
+
+	rows []Row
+	// reserved is the amount of bytes of rows, that is reserved in the
+	// budget.
+	reserved int64
+	runs     []*file
+	stats    Stats
+	done     bool
+}
+
+// NewSorter creates a sorter, that orders rows with the given comparison
+// function, and spills when it exceeds the given budget.
+func NewSorter(compare func(a, b Row) int, budget *Budget) *Sorter {
+	return &Sorter{
+		compare: compare,
+		budget:  budget,
+		FanIn:   DefaultFanIn,
+	}
+}
+
+// Add adds a row to the sorter. If the row doesn't fit into the budget, the
+// rows in memory are sorted and written to a run first.
+func (s *Sorter) Add(row Row) error {
+	if s.done {
+		return ErrDone
+	}
+	n := Size(row)
+	if !s.budget.Grow(n) {
+		if err := s.spill(); err != nil {
+			return err
+		}
+		if !s.budget.Grow(n) {
+			s.budget.force(n)
+		}
+	}
+	s.reserved += n
+	s.stats.reserved(s.reserved)
+	s.rows = append(s.rows, row)
+	return nil
+}
+
+// spill sorts the rows in memory, and writes them to a new run.
+func (s *Sorter) spill() error {
+	if len(s.rows) == 0 {
+		return nil
+	}
+	s.sortRows()
+	run, err := create(s.budget.dir(), &s.stats)
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	for _, row := range s.rows {
+		if err := run.write(row); err != nil {
+			return err
+		}
+	}
+	s.release()
+	return nil
+}
+
+func (s *Sorter) sortRows() {
+	sort.SliceStable(s.rows, func(i, j int) bool { return s.compare(s.rows[i], s.rows[j]) < 0 })
+}
+
+// release releases the rows in memory.
+func (s *Sorter) release() {
+	s.rows = nil
+	s.budget.Shrink(s.reserved)
+	s.reserved = 0
+}
+
+// Sort emits all rows in sorted order. After Sort, the sorter releases its
+// memory and temporary files, and cannot be used anymore.
+func (s *Sorter) Sort(emit func(Row) error) (err error) {
+	if s.done {
+		return ErrDone
+	}
+	defer func() {
+		if closeErr := s.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if len(s.runs) == 0 {
+		s.sortRows()
+		for _, row := range s.rows {
+			if err := emit(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.spill(); err != nil {
+		return err
+	}
+	fanIn := s.FanIn
+	if fanIn < 2 {
+		fanIn = 2
+	}
+	// Merge the first runs into a single run, until all runs can be merged
+	// at once. The merged run replaces the first runs, which keeps the sort
+	// stable.
+	for len(s.runs) > fanIn {
+		merged, err := create(s.budget.dir(), &s.stats)
+		if err != nil {
+			return err
+		}
+		first := s.runs[:fanIn]
+		s.runs = append([]*file{merged}, s.runs[fanIn:]...)
+		if err := s.merge(first, merged.write); err != nil {
+			_ = removeAll(first)
+			return err
+		}
+		if err := removeAll(first); err != nil {
+			return err
+		}
+	}
+	return s.merge(s.runs, emit)
+}
+
+// merge merges the given runs, and emits their rows in sorted order.
+func (s *Sorter) merge(runs []*file, emit func(Row) error) error {
+	s.stats.Passes++
+	h := &mergeHeap{compare: s.compare}
+	for i, run := range runs {
+		if err := run.rewind(); err != nil {
+			return err
+		}
+		if err := h.next(run, i); err != nil {
+			return err
+		}
+	}
+	for h.Len() > 0 {
+		top := h.items[0]
+		if err := emit(top.row); err != nil {
+			return err
+		}
+		heap.Pop(h)
+		if err := h.next(top.run, top.index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats returns how much the sorter spilled.
+func (s *Sorter) Stats() Stats {
+	return s.stats
+}
+
+// Close releases the memory and the temporary files of the sorter, without
+// sorting. It is not necessary to call Close after Sort.
+func (s *Sorter) Close() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+	s.release()
+	err := removeAll(s.runs)
+	s.runs = nil
+	return err
+}
+
+// mergeItem is the next row of a run, that is being merged.
+type mergeItem struct {
+	row Row
+	run *file
+	// index is the position of the run, which orders rows that compare
+	// equal.
+	index int
+}
+
+// mergeHeap holds the next row of every run, that is being merged, and
+// implements heap.Interface.
+type mergeHeap struct {
+	compare func(a, b Row) int
+	items   []mergeItem
+}
+
+// next pushes the next row of the given run, if there is one.
+func (h *mergeHeap) next(run *file, index int) error {
+	row, err := run.read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	heap.Push(h, mergeItem{row, run, index})
+	return nil
+}
+
+func (h *mergeHeap) Len() int { return len(h.items) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	if c := h.compare(h.items[i].row, h.items[j].row); c != 0 {
+		return c < 0
+	}
+	return h.items[i].index < h.items[j].index
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
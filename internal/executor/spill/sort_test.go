@@ -0,0 +1,83 @@
+package spill
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+)
+
+// byFirst compares rows by their first value.
+func byFirst(a, b Row) int {
+	return function.Compare(a[0], b[0])
+}
+
+func TestSorter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var rows []Row
+	for i := 0; i < 5000; i++ {
+		// the second value is the position, which checks that the sort is
+		// stable
+		var v function.Value
+		switch rnd.Intn(4) {
+		case 0:
+			v = nil
+		case 1:
+			v = int64(rnd.Intn(100))
+		case 2:
+			v = float64(rnd.Intn(100)) + 0.5
+		case 3:
+			v = string(rune('a' + rnd.Intn(26)))
+		}
+		rows = append(rows, Row{v, int64(i)})
+	}
+	want := append([]Row{}, rows...)
+	sort.SliceStable(want, func(i, j int) bool { return byFirst(want[i], want[j]) < 0 })
+
+	tests := []struct {
+		name   string
+		limit  int64
+		fanIn  int
+		files  int
+		passes int
+	}{
+		{"in memory", 0, DefaultFanIn, 0, 0},
+		{"single merge", 64 << 10, DefaultFanIn, 6, 1},
+		{"multiple merges", 16 << 10, 4, 27, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSorter(byFirst, newBudget(t, tt.limit))
+			s.FanIn = tt.fanIn
+			for _, row := range rows {
+				require.NoError(t, s.Add(row))
+			}
+			var got []Row
+			require.NoError(t, s.Sort(func(row Row) error {
+				got = append(got, row)
+				return nil
+			}))
+			assert.Equal(t, want, got)
+
+			stats := s.Stats()
+			assert.Equal(t, tt.files, stats.Files)
+			assert.Equal(t, tt.passes, stats.Passes)
+			assert.Equal(t, tt.files > 0, stats.Spilled())
+			assert.Greater(t, stats.Memory, int64(0))
+			assert.Equal(t, ErrDone, s.Add(Row{nil}))
+		})
+	}
+}
+
+func TestSorterClose(t *testing.T) {
+	s := NewSorter(byFirst, newBudget(t, 1))
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Add(Row{int64(i), "value", []byte{1, 2}, 1.5}))
+	}
+	assert.Equal(t, 9, s.Stats().Files)
+	require.NoError(t, s.Close())
+	assert.Equal(t, ErrDone, s.Sort(func(Row) error { return nil }))
+}
@@ -0,0 +1,879 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tomarrell/lbadd/internal/database/alter"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/index"
+	"github.com/tomarrell/lbadd/internal/database/storage/wal"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/database/view"
+	"github.com/tomarrell/lbadd/internal/executor/cte"
+	"github.com/tomarrell/lbadd/internal/executor/profile"
+	"github.com/tomarrell/lbadd/internal/executor/spill"
+)
+
+// session opens a session on a new database, and returns an executor for it.
+func session(t *testing.T, opts ...Option) Executor {
+	s, err := attach.Open(wal.OS, filepath.Join(t.TempDir(), "main.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return NewSession(zerolog.Nop(), s, opts...)
+}
+
+// execute executes the given statements, and returns the result of the last
+// one, or the first error.
+func execute(t *testing.T, exec Executor, queries ...string) (Result, error) {
+	var result Result
+	for _, query := range queries {
+		var err error
+		if result, err = exec.Execute(context.Background(), parse(t, query)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func TestExecute_Statements(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []string
+		want    string
+		wantErr error
+	}{
+		{
+			"insert and select",
+			[]string{
+				"CREATE TABLE t (a INTEGER PRIMARY KEY, b TEXT)",
+				"INSERT INTO t (b) VALUES ('one'), ('two')",
+				"SELECT t.a, t.b FROM t ORDER BY t.a DESC",
+			},
+			"a|b\n2|two\n1|one",
+			nil,
+		},
+		{
+			"affinity and rowid",
+			[]string{
+				"CREATE TABLE t (a INTEGER, b TEXT, c)",
+				"INSERT INTO t VALUES ('12', 34, '5')",
+				"SELECT t.rowid, typeof(t.a), typeof(t.b), typeof(t.c) FROM t",
+			},
+			"rowid|typeof(t.a)|typeof(t.b)|typeof(t.c)\n1|integer|text|text",
+			nil,
+		},
+		{
+			"update and delete",
+			[]string{
+				"CREATE TABLE t (a INTEGER, b INTEGER)",
+				"INSERT INTO t VALUES (1, 10), (2, 20), (3, 30)",
+				"UPDATE t SET b = t.b + 1 WHERE t.a > 1",
+				"DELETE FROM t WHERE t.a = 3",
+				"SELECT * FROM t",
+			},
+			"a|b\n1|10\n2|21",
+			nil,
+		},
+		{
+			"default values",
+			[]string{
+				"CREATE TABLE t (a INTEGER DEFAULT 7, b TEXT DEFAULT 'xy', c DEFAULT (1 + 2))",
+				"INSERT INTO t DEFAULT VALUES",
+				"INSERT INTO t (c) VALUES (0)",
+				"SELECT * FROM t",
+			},
+			"a|b|c\n7|xy|3\n7|xy|0",
+			nil,
+		},
+		{
+			"aggregate join and subquery",
+			[]string{
+				"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT)",
+				"CREATE TABLE orders (customer INTEGER, total INTEGER)",
+				"INSERT INTO customers (name) VALUES ('ann'), ('bob'), ('cid')",
+				"INSERT INTO orders VALUES (1, 10), (1, 20), (2, 5)",
+				"SELECT c.name, count(*), sum(o.total) FROM customers AS c JOIN orders AS o ON o.customer = c.id GROUP BY c.name ORDER BY c.name",
+			},
+			"name|count(*)|sum(o.total)\nann|2|30\nbob|1|5",
+			nil,
+		},
+		{
+			"not exists",
+			[]string{
+				"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT)",
+				"CREATE TABLE orders (customer INTEGER, total INTEGER)",
+				"INSERT INTO customers (name) VALUES ('ann'), ('bob')",
+				"INSERT INTO orders VALUES (1, 10)",
+				"SELECT customers.name FROM customers WHERE NOT EXISTS (SELECT 1 FROM orders WHERE orders.customer = customers.id)",
+			},
+			"name\nbob",
+			nil,
+		},
+		{
+			"create table as select",
+			[]string{
+				"CREATE TABLE t (a INTEGER, b TEXT)",
+				"INSERT INTO t VALUES (1, 'one'), (2, 'two')",
+				"CREATE TABLE s AS SELECT t.b AS name FROM t WHERE t.a > 1",
+				"SELECT * FROM s",
+			},
+			"name\ntwo",
+			nil,
+		},
+		{
+			"drop table",
+			[]string{
+				"CREATE TABLE t (a)",
+				"DROP TABLE t",
+				"DROP TABLE IF EXISTS t",
+				"SELECT * FROM t",
+			},
+			"",
+			attach.ErrNoSuchTable,
+		},
+		{
+			"table exists",
+			[]string{
+				"CREATE TABLE t (a)",
+				"CREATE TABLE IF NOT EXISTS t (b)",
+				"CREATE TABLE t (c)",
+			},
+			"",
+			alter.ErrTableExists,
+		},
+		{
+			"not null",
+			[]string{
+				"CREATE TABLE t (a INTEGER NOT NULL)",
+				"INSERT INTO t VALUES (NULL)",
+			},
+			"",
+			constraint.ErrConstraint,
+		},
+		{
+			"check",
+			[]string{
+				"CREATE TABLE t (a INTEGER CHECK (a > 0))",
+				"INSERT INTO t VALUES (1)",
+				"UPDATE t SET a = 0",
+			},
+			"",
+			constraint.ErrConstraint,
+		},
+		{
+			"unique",
+			[]string{
+				"CREATE TABLE t (a INTEGER UNIQUE, b TEXT)",
+				"INSERT INTO t VALUES (1, 'one')",
+				"INSERT INTO t VALUES (1, 'uno')",
+			},
+			"",
+			constraint.ErrConstraint,
+		},
+		{
+			"or ignore and or replace",
+			[]string{
+				"CREATE TABLE t (a INTEGER UNIQUE, b TEXT)",
+				"INSERT INTO t VALUES (1, 'one'), (2, 'two')",
+				"INSERT OR IGNORE INTO t VALUES (1, 'uno'), (3, 'three')",
+				"INSERT OR REPLACE INTO t VALUES (2, 'dos')",
+				"SELECT t.a, t.b FROM t ORDER BY t.a",
+			},
+			"a|b\n1|one\n2|dos\n3|three",
+			nil,
+		},
+		{
+			"abort reverts the statement",
+			[]string{
+				"CREATE TABLE t (a INTEGER PRIMARY KEY)",
+				"INSERT INTO t VALUES (1)",
+				"INSERT INTO t VALUES (2), (1)",
+			},
+			"",
+			constraint.ErrConstraint,
+		},
+		{
+			"autoincrement",
+			[]string{
+				"CREATE TABLE t (a INTEGER PRIMARY KEY AUTOINCREMENT, b TEXT)",
+				"INSERT INTO t (b) VALUES ('one'), ('two')",
+				"DELETE FROM t WHERE t.a = 2",
+				"INSERT INTO t (b) VALUES ('three')",
+				"SELECT * FROM t",
+			},
+			"a|b\n1|one\n3|three",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := execute(t, session(t), tt.queries...)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.String())
+		})
+	}
+}
+
+func TestExecute_Conflict(t *testing.T) {
+	exec := session(t)
+	_, err := execute(t, exec,
+		"CREATE TABLE t (a INTEGER PRIMARY KEY, b TEXT NOT NULL)",
+		"BEGIN",
+		"INSERT INTO t VALUES (1, 'one')",
+	)
+	require.NoError(t, err)
+
+	// ABORT reverts the statement, but not the transaction
+	_, err = execute(t, exec, "INSERT INTO t VALUES (2, 'two'), (3, NULL)")
+	var v *constraint.Violation
+	require.True(t, errors.As(err, &v), err)
+	assert.Equal(t, constraint.NotNull, v.Kind)
+	assert.Equal(t, "t.b", v.Constraint)
+	assert.Equal(t, constraint.Abort, v.Conflict)
+
+	// FAIL keeps the rows, that were inserted before the violation
+	_, err = execute(t, exec, "INSERT OR FAIL INTO t VALUES (4, 'four'), (5, NULL)")
+	require.True(t, errors.As(err, &v), err)
+	assert.Equal(t, constraint.Fail, v.Conflict)
+
+	result, err := execute(t, exec, "COMMIT", "SELECT t.a FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, "a\n1\n4", result.String())
+
+	// ROLLBACK rolls back the transaction
+	_, err = execute(t, exec, "BEGIN", "INSERT INTO t VALUES (6, 'six')")
+	require.NoError(t, err)
+	_, err = execute(t, exec, "INSERT OR ROLLBACK INTO t VALUES (1, 'uno')")
+	require.True(t, errors.As(err, &v), err)
+	assert.Equal(t, constraint.PrimaryKey, v.Kind)
+	_, err = execute(t, exec, "COMMIT")
+	assert.True(t, errors.Is(err, attach.ErrNoTx), err)
+
+	result, err = execute(t, exec, "UPDATE t SET b = 'x' || t.b WHERE t.a > 1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.RowsAffected())
+	result, err = execute(t, exec, "INSERT INTO t (b) VALUES ('next')")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.LastInsertID())
+	result, err = execute(t, exec, "SELECT * FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, "a|b\n1|one\n4|xfour\n5|next", result.String())
+}
+
+func TestExecute_Index(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.db")
+	open := func() Executor {
+		s, err := attach.Open(wal.OS, file)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Close() })
+		return NewSession(zerolog.Nop(), s)
+	}
+	exec := open()
+	_, err := execute(t, exec,
+		"CREATE TABLE t (a TEXT, b INTEGER)",
+		"INSERT INTO t VALUES ('x', 1), ('X', 0), ('y', 0)",
+		"CREATE INDEX ix ON t (b DESC, a)",
+		"CREATE INDEX IF NOT EXISTS ix ON t (a)",
+		"CREATE UNIQUE INDEX lower ON t (a COLLATE NOCASE) WHERE b > 0",
+	)
+	require.NoError(t, err)
+
+	_, err = execute(t, exec, "CREATE INDEX ix ON t (a)")
+	assert.True(t, errors.Is(err, index.ErrExists), err)
+	_, err = execute(t, exec, "CREATE INDEX t ON t (a)")
+	assert.True(t, errors.Is(err, alter.ErrTableExists), err)
+	_, err = execute(t, exec, "CREATE UNIQUE INDEX upper ON t (a COLLATE NOCASE)")
+	var v *constraint.Violation
+	require.True(t, errors.As(err, &v), err)
+	assert.Equal(t, "index 'upper'", v.Constraint)
+
+	// the partial index only holds the rows, for which its predicate is true
+	_, err = execute(t, exec, "INSERT INTO t VALUES ('Y', 0)")
+	require.NoError(t, err)
+	_, err = execute(t, exec, "INSERT INTO t VALUES ('X', 1)")
+	assert.True(t, errors.Is(err, constraint.ErrConstraint), err)
+
+	// the indexes are stored with the table, and enforced by other sessions
+	other := open()
+	_, err = execute(t, other, "UPDATE t SET b = 2 WHERE t.a = 'X'")
+	require.True(t, errors.As(err, &v), err)
+	assert.Equal(t, "index 'lower'", v.Constraint)
+	for _, query := range []string{"REINDEX", "REINDEX t", "REINDEX main.ix", "REINDEX nocase"} {
+		_, err = execute(t, other, query)
+		assert.NoError(t, err, query)
+	}
+	_, err = execute(t, other, "REINDEX nothing")
+	assert.True(t, errors.Is(err, ErrReindexObject), err)
+
+	_, err = execute(t, exec, "DROP INDEX lower", "DROP INDEX IF EXISTS lower", "UPDATE t SET b = 2 WHERE t.a = 'X'")
+	require.NoError(t, err)
+	_, err = execute(t, exec, "DROP INDEX lower")
+	assert.True(t, errors.Is(err, index.ErrNotExist), err)
+
+	// dropping a table drops its indexes
+	result, err := execute(t, other, "DROP TABLE t", "CREATE TABLE t (a)", "CREATE INDEX ix ON t (a)", "SELECT * FROM t")
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows())
+}
+
+func TestExecute_ForeignKeys(t *testing.T) {
+	exec := session(t)
+	result, err := execute(t, exec,
+		"CREATE TABLE parent (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE child (id INTEGER PRIMARY KEY, pid INTEGER REFERENCES parent ON DELETE CASCADE)",
+		"CREATE TABLE later (pid INTEGER REFERENCES parent (id) DEFERRABLE INITIALLY DEFERRED)",
+		"INSERT INTO parent VALUES (1, 'one'), (2, 'two')",
+		"INSERT INTO child VALUES (1, 1), (2, 2), (3, 3)",
+		"PRAGMA foreign_keys",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "foreign_keys\n0", result.String())
+
+	// foreign keys are checked, even if they are not enforced
+	result, err = execute(t, exec, "PRAGMA foreign_key_check")
+	require.NoError(t, err)
+	assert.Equal(t, "table|rowid|parent|fkid\nchild|3|parent|0", result.String())
+	result, err = execute(t, exec, "PRAGMA main.foreign_key_check(parent)")
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows())
+
+	result, err = execute(t, exec, "DELETE FROM child WHERE child.id = 3", "PRAGMA foreign_keys = ON", "PRAGMA foreign_keys")
+	require.NoError(t, err)
+	assert.Equal(t, "foreign_keys\n1", result.String())
+
+	// immediate foreign keys are violated by the statement
+	_, err = execute(t, exec, "INSERT INTO child VALUES (4, 4)")
+	var v *constraint.Violation
+	require.True(t, errors.As(err, &v), err)
+	assert.Equal(t, constraint.ForeignKey, v.Kind)
+
+	// deleting a parent deletes its children
+	result, err = execute(t, exec, "DELETE FROM parent WHERE parent.id = 1", "SELECT * FROM child")
+	require.NoError(t, err)
+	assert.Equal(t, "id|pid\n2|2", result.String())
+
+	// deferred foreign keys are violated by COMMIT, and the transaction
+	// remains active until the violation is resolved
+	_, err = execute(t, exec, "BEGIN", "INSERT INTO later VALUES (3)", "PRAGMA foreign_keys = OFF")
+	require.NoError(t, err)
+	_, err = execute(t, exec, "COMMIT")
+	require.True(t, errors.As(err, &v), err)
+	assert.Equal(t, constraint.ForeignKey, v.Kind)
+	result, err = execute(t, exec, "INSERT INTO parent VALUES (3, 'three')", "COMMIT", "SELECT * FROM later")
+	require.NoError(t, err)
+	assert.Equal(t, "pid\n3", result.String())
+
+	// a deferred violation outside of a transaction rolls back the statement
+	_, err = execute(t, exec, "INSERT INTO later VALUES (5)")
+	assert.True(t, errors.Is(err, constraint.ErrConstraint), err)
+	result, err = execute(t, exec, "SELECT * FROM later")
+	require.NoError(t, err)
+	assert.Equal(t, "pid\n3", result.String())
+}
+
+func TestExecute_Triggers(t *testing.T) {
+	exec := session(t)
+	_, err := execute(t, exec,
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, a INTEGER, b TEXT DEFAULT 'none')",
+		"CREATE TABLE log (event TEXT, old, new)",
+		"CREATE TRIGGER ins AFTER INSERT ON t BEGIN INSERT INTO log VALUES ('insert', NULL, NEW.id); END",
+		"CREATE TRIGGER upd AFTER UPDATE OF a ON t FOR EACH ROW WHEN NEW.a != OLD.a BEGIN INSERT INTO log VALUES ('update', OLD.a, NEW.a); END",
+		"CREATE TRIGGER del BEFORE DELETE ON t BEGIN INSERT INTO log VALUES ('delete', OLD.b, NULL); END",
+		"CREATE TRIGGER neg BEFORE INSERT ON t WHEN NEW.a < 0 BEGIN SELECT RAISE(ABORT, 'negative'); END",
+		"CREATE TRIGGER skip BEFORE INSERT ON t WHEN NEW.b = 'skip' BEGIN SELECT RAISE(IGNORE); END",
+		"INSERT INTO t (a) VALUES (1), (2)",
+		"INSERT INTO t (a, b) VALUES (3, 'skip')",
+		"UPDATE t SET a = t.a * 10 WHERE t.id = 1",
+		"UPDATE t SET a = 2 WHERE t.id = 2",
+		"UPDATE t SET b = 'b'",
+		"DELETE FROM t WHERE t.id = 2",
+	)
+	require.NoError(t, err)
+	result, err := execute(t, exec, "SELECT * FROM log")
+	require.NoError(t, err)
+	assert.Equal(t, "event|old|new\ninsert|<nil>|1\ninsert|<nil>|2\nupdate|1|10\ndelete|b|<nil>", result.String())
+
+	// RAISE(ABORT) fails the statement like a violation of a constraint
+	_, err = execute(t, exec, "INSERT INTO t (a) VALUES (5), (-1)")
+	assert.True(t, errors.Is(err, constraint.ErrConstraint), err)
+	assert.EqualError(t, err, "negative")
+	result, err = execute(t, exec, "SELECT t.id, t.a FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, "id|a\n1|10", result.String())
+
+	_, err = execute(t, exec, "SELECT RAISE(FAIL, 'outside')")
+	assert.True(t, errors.Is(err, ErrRaiseOutsideTrigger), err)
+	_, err = execute(t, exec, "CREATE TRIGGER ins AFTER INSERT ON t BEGIN SELECT 1; END")
+	assert.True(t, errors.Is(err, trigger.ErrExists), err)
+	_, err = execute(t, exec, "CREATE TRIGGER view INSTEAD OF INSERT ON t BEGIN SELECT 1; END")
+	assert.True(t, errors.Is(err, trigger.ErrInsteadOfTable), err)
+
+	// triggers only fire recursively, if recursive triggers are enabled, and
+	// the depth of recursion is limited
+	result, err = execute(t, exec,
+		"DROP TRIGGER ins",
+		"DROP TRIGGER IF EXISTS ins",
+		"DROP TABLE log",
+		"CREATE TABLE n (i INTEGER)",
+		"CREATE TRIGGER next AFTER INSERT ON n WHEN NEW.i < 5 BEGIN INSERT INTO n VALUES (NEW.i + 1); END",
+		"INSERT INTO n VALUES (1)",
+		"PRAGMA recursive_triggers = ON",
+		"INSERT INTO n VALUES (3)",
+		"PRAGMA recursive_triggers",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "recursive_triggers\n1", result.String())
+	result, err = execute(t, exec, "SELECT n.i FROM n ORDER BY n.i")
+	require.NoError(t, err)
+	assert.Equal(t, "i\n1\n2\n3\n4\n5", result.String())
+
+	_, err = execute(t, exec,
+		"CREATE TRIGGER forever AFTER INSERT ON n BEGIN INSERT INTO n VALUES (NEW.i); END",
+		"INSERT INTO n VALUES (0)",
+	)
+	assert.True(t, errors.Is(err, trigger.ErrTooDeep), err)
+	result, err = execute(t, exec, "SELECT count(*) FROM n")
+	require.NoError(t, err)
+	assert.Equal(t, "count(*)\n5", result.String())
+
+	_, err = execute(t, exec, "DROP TRIGGER forever")
+	require.NoError(t, err)
+	_, err = execute(t, exec, "DROP TRIGGER forever")
+	assert.True(t, errors.Is(err, trigger.ErrNotExist), err)
+}
+
+func TestExecute_Views(t *testing.T) {
+	exec := session(t)
+	result, err := execute(t, exec,
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, a INTEGER, b TEXT)",
+		"CREATE TABLE u (id INTEGER, c TEXT)",
+		"INSERT INTO t (a, b) VALUES (1, 'one'), (2, 'two'), (3, 'three')",
+		"INSERT INTO u VALUES (1, 'first'), (3, 'third')",
+		"CREATE VIEW big (key, val) AS SELECT t.id, t.b FROM t WHERE t.a > 1",
+		"CREATE VIEW joined AS SELECT big.val, u.c FROM big JOIN u ON big.key = u.id",
+		"SELECT * FROM big WHERE big.key < 3",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "key|val\n2|two", result.String())
+	result, err = execute(t, exec, "SELECT * FROM joined")
+	require.NoError(t, err)
+	assert.Equal(t, "val|c\nthree|third", result.String())
+	result, err = execute(t, exec, "SELECT count(*) FROM big AS v JOIN t ON v.key = t.id")
+	require.NoError(t, err)
+	assert.Equal(t, "count(*)\n2", result.String())
+
+	_, err = execute(t, exec, "CREATE VIEW big AS SELECT 1")
+	assert.True(t, errors.Is(err, view.ErrExists), err)
+	_, err = execute(t, exec, "CREATE VIEW IF NOT EXISTS big AS SELECT 1")
+	assert.NoError(t, err)
+	_, err = execute(t, exec, "CREATE VIEW t AS SELECT 1")
+	assert.True(t, errors.Is(err, alter.ErrTableExists), err)
+	_, err = execute(t, exec, "CREATE VIEW bad (a, b) AS SELECT 1")
+	assert.True(t, errors.Is(err, view.ErrColumnCount), err)
+	_, err = execute(t, exec, "CREATE VIEW bad AS SELECT * FROM missing")
+	assert.True(t, errors.Is(err, view.ErrNoSuchTable), err)
+
+	// a view, whose table was dropped, fails when it is used, and cannot be
+	// defined circularly
+	_, err = execute(t, exec,
+		"CREATE TABLE w (a)",
+		"CREATE VIEW vw AS SELECT * FROM w",
+		"DROP TABLE w",
+	)
+	require.NoError(t, err)
+	_, err = execute(t, exec, "SELECT * FROM vw")
+	assert.True(t, errors.Is(err, view.ErrNoSuchTable), err)
+	_, err = execute(t, exec, "CREATE VIEW w AS SELECT * FROM vw")
+	assert.True(t, errors.Is(err, view.ErrCircular), err)
+
+	// a view can only be changed by INSTEAD OF triggers
+	_, err = execute(t, exec, "INSERT INTO big VALUES (4, 'four')")
+	assert.True(t, errors.Is(err, view.ErrReadOnly), err)
+	result, err = execute(t, exec,
+		"CREATE TRIGGER ins INSTEAD OF INSERT ON big BEGIN INSERT INTO t VALUES (NEW.key, NEW.key, NEW.val); END",
+		"CREATE TRIGGER upd INSTEAD OF UPDATE OF val ON big BEGIN UPDATE t SET b = NEW.val WHERE t.id = OLD.key; END",
+		"CREATE TRIGGER del INSTEAD OF DELETE ON big BEGIN DELETE FROM t WHERE t.id = OLD.key; END",
+		"INSERT INTO big VALUES (4, 'four')",
+		"UPDATE big SET val = 'deux' WHERE big.key = 2",
+		"DELETE FROM big WHERE big.key = 3",
+		"SELECT * FROM t",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "id|a|b\n1|1|one\n2|2|deux\n4|4|four", result.String())
+	_, err = execute(t, exec, "UPDATE big SET key = 5")
+	assert.True(t, errors.Is(err, view.ErrReadOnly), err)
+
+	// the triggers of a view are dropped with it
+	result, err = execute(t, exec,
+		"DROP VIEW joined",
+		"DROP VIEW big",
+		"DROP VIEW IF EXISTS big",
+		"SELECT count(*) FROM t",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "count(*)\n3", result.String())
+	_, err = execute(t, exec, "DROP VIEW big")
+	assert.True(t, errors.Is(err, view.ErrNotExist), err)
+	_, err = execute(t, exec, "DROP TRIGGER ins")
+	assert.True(t, errors.Is(err, trigger.ErrNotExist), err)
+}
+
+func TestExecute_CTE(t *testing.T) {
+	exec := session(t)
+	result, err := execute(t, exec,
+		"WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 5) SELECT * FROM cnt",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "n\n1\n2\n3\n4\n5", result.String())
+
+	// UNION discards the rows, that were already found, which ends the
+	// recursion of a cyclic graph
+	result, err = execute(t, exec,
+		"CREATE TABLE edge (a INTEGER, b INTEGER)",
+		"INSERT INTO edge VALUES (1, 2), (2, 3), (3, 1), (4, 5)",
+		"WITH RECURSIVE reach (node) AS (SELECT 1 UNION SELECT edge.b FROM edge JOIN reach ON edge.a = reach.node) SELECT node FROM reach ORDER BY node",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "node\n1\n2\n3", result.String())
+
+	// ORDER BY and LIMIT of a recursive CTE apply to the recursion
+	result, err = execute(t, exec,
+		"WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt LIMIT 3) SELECT * FROM cnt",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "n\n1\n2\n3", result.String())
+
+	// a CTE, that is referenced twice, is materialized, and one, that is
+	// referenced once, is inlined
+	result, err = execute(t, exec,
+		"WITH src AS (SELECT edge.a FROM edge WHERE edge.a < 3) SELECT s1.a, s2.a FROM src AS s1 JOIN src AS s2 ON s1.a < s2.a",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "a|a\n1|2", result.String())
+	result, err = execute(t, exec,
+		"WITH src (val) AS (SELECT edge.b FROM edge WHERE edge.a = 4) SELECT src.val FROM src",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "val\n5", result.String())
+
+	_, err = execute(t, exec, "WITH bad (a, b) AS (SELECT 1) SELECT * FROM bad")
+	assert.True(t, errors.Is(err, ErrValueCount), err)
+
+	// the depth of the recursion is limited by PRAGMA recursion_limit
+	result, err = execute(t, exec,
+		"PRAGMA recursion_limit = 3",
+		"PRAGMA recursion_limit",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "recursion_limit\n3", result.String())
+	_, err = execute(t, exec,
+		"WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 5) SELECT * FROM cnt",
+	)
+	assert.True(t, errors.Is(err, cte.ErrRecursionLimit), err)
+}
+
+func TestExecute_Analyze(t *testing.T) {
+	exec := session(t)
+	report := "SELECT c.name, o.total, p.title FROM customers AS c JOIN orders AS o ON o.customer = c.id JOIN products AS p ON p.id = o.product WHERE c.region = 'r7' AND o.total < 300 ORDER BY o.total"
+	_, err := execute(t, exec,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT, region TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, customer INTEGER, product INTEGER, total INTEGER)",
+		"CREATE TABLE products (id INTEGER PRIMARY KEY, title TEXT)",
+		"CREATE INDEX orders_customer ON orders (customer)",
+		"CREATE INDEX customers_region ON customers (region)",
+		"INSERT INTO customers WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 200) SELECT cnt.n, 'c' || cnt.n, 'r' || (cnt.n % 20) FROM cnt",
+		"INSERT INTO products WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 50) SELECT cnt.n, 'p' || cnt.n FROM cnt",
+		"INSERT INTO orders WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 1000) SELECT cnt.n, cnt.n % 200 + 1, cnt.n % 50 + 1, cnt.n FROM cnt",
+	)
+	require.NoError(t, err)
+	const rows = "name|total|title\nc7|6|p7\nc27|26|p27\nc47|46|p47\nc67|66|p17\nc87|86|p37\nc107|106|p7\nc127|126|p27\nc147|146|p47\nc167|166|p17\nc187|186|p37\nc7|206|p7\nc27|226|p27\nc47|246|p47\nc67|266|p17\nc87|286|p37"
+
+	// without statistics, the joins are run in the order of the query
+	result, err := execute(t, exec, report)
+	require.NoError(t, err)
+	assert.Equal(t, rows, result.String())
+	result, err = execute(t, exec, "EXPLAIN QUERY PLAN "+report)
+	require.NoError(t, err)
+	assert.Contains(t, result.String(), "INNER JOIN ON o.customer = c.id")
+
+	// with statistics, the planner searches the indexes, and chooses the
+	// join order and join algorithms
+	result, err = execute(t, exec, "ANALYZE", "EXPLAIN QUERY PLAN "+report)
+	require.NoError(t, err)
+	plan := result.String()
+	assert.Contains(t, plan, "SEARCH customers AS c USING INDEX customers_region")
+	assert.Contains(t, plan, "INDEX NESTED LOOP JOIN")
+	assert.Contains(t, plan, "SEARCH orders AS o USING INDEX orders_customer")
+	assert.Contains(t, plan, "HASH JOIN")
+	result, err = execute(t, exec, report)
+	require.NoError(t, err)
+	assert.Equal(t, rows, result.String())
+
+	// the columns of SELECT * are in the order of the query
+	result, err = execute(t, exec, "SELECT * FROM orders AS o, customers AS c WHERE o.customer = c.id AND c.id = 7 AND o.total < 300")
+	require.NoError(t, err)
+	assert.Equal(t, "id|customer|product|total|id|name|region\n6|7|7|6|7|c7|r7\n206|7|7|206|7|c7|r7", result.String())
+
+	// comparisons, that convert the indexed column or use another collation,
+	// cannot search the index
+	result, err = execute(t, exec,
+		"CREATE INDEX products_title ON products (title COLLATE NOCASE)",
+		"ANALYZE products",
+		"EXPLAIN QUERY PLAN SELECT p.id FROM products AS p WHERE p.title = 'P7'",
+	)
+	require.NoError(t, err)
+	assert.Contains(t, result.String(), "SCAN products AS p")
+	result, err = execute(t, exec, "SELECT p.id FROM products AS p WHERE p.title = 'P7'")
+	require.NoError(t, err)
+	assert.Equal(t, "id", result.String())
+
+	// rows, that are found with an index, can be changed
+	result, err = execute(t, exec,
+		"DELETE FROM customers WHERE customers.region = 'r7'",
+		"SELECT count(*) FROM customers",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "count(*)\n190", result.String())
+
+	// ANALYZE accepts the name of a schema, a table or an index
+	for _, stmt := range []string{"ANALYZE main", "ANALYZE orders", "ANALYZE main.orders_customer"} {
+		_, err = execute(t, exec, stmt)
+		assert.NoError(t, err, stmt)
+	}
+	_, err = execute(t, exec, "ANALYZE nosuch")
+	assert.True(t, errors.Is(err, ErrAnalyzeObject), err)
+}
+
+func TestExecute_AlterTable(t *testing.T) {
+	exec := session(t)
+	_, err := execute(t, exec,
+		"CREATE TABLE t (a INTEGER PRIMARY KEY AUTOINCREMENT, b TEXT, c INTEGER CHECK (c >= 0), UNIQUE (b))",
+		"CREATE TABLE child (id INTEGER, tb TEXT REFERENCES t (b))",
+		"CREATE INDEX t_c ON t (c)",
+		"CREATE TABLE log (msg)",
+		"CREATE TRIGGER tr AFTER UPDATE OF c ON t BEGIN INSERT INTO log VALUES (NEW.c); END",
+		"CREATE VIEW v AS SELECT t.b, t.c FROM t",
+		"INSERT INTO t (b, c) VALUES ('one', 1), ('two', 2)",
+	)
+	require.NoError(t, err)
+
+	// renames change the index, the trigger, the view and the foreign key
+	result, err := execute(t, exec,
+		"ALTER TABLE t RENAME COLUMN c TO cnt",
+		"ALTER TABLE t RENAME TO u",
+		"UPDATE u SET cnt = 5 WHERE u.b = 'one'",
+		"SELECT * FROM log",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "msg\n5", result.String())
+	result, err = execute(t, exec, "SELECT * FROM v")
+	require.NoError(t, err)
+	assert.Equal(t, "b|cnt\none|5\ntwo|2", result.String())
+	result, err = execute(t, exec, "EXPLAIN QUERY PLAN SELECT u.a FROM u WHERE u.cnt = 2")
+	require.NoError(t, err)
+	assert.Contains(t, result.String(), "SCAN u")
+	_, err = execute(t, exec, "SELECT * FROM t")
+	assert.True(t, errors.Is(err, attach.ErrNoSuchTable), err)
+
+	// the rows get the default of an added column without being rewritten
+	result, err = execute(t, exec,
+		"ALTER TABLE u ADD COLUMN d TEXT DEFAULT 'none' NOT NULL",
+		"INSERT INTO u (b, cnt, d) VALUES ('three', 3, 'x')",
+		"SELECT * FROM u",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "a|b|cnt|d\n1|one|5|none\n2|two|2|none\n3|three|3|x", result.String())
+
+	// a dropped column is removed from the rows
+	result, err = execute(t, exec,
+		"ALTER TABLE u DROP COLUMN d",
+		"SELECT * FROM u",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "a|b|cnt\n1|one|5\n2|two|2\n3|three|3", result.String())
+
+	// the AUTOINCREMENT sequence is kept
+	result, err = execute(t, exec,
+		"DELETE FROM u WHERE u.a = 3",
+		"INSERT INTO u (b, cnt) VALUES ('four', 4)",
+		"SELECT u.a FROM u WHERE u.b = 'four'",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "a\n4", result.String())
+
+	// like in SQLite, a column cannot be dropped, if a definition still
+	// refers to it, and the table is not changed
+	_, err = execute(t, exec, "ALTER TABLE u DROP COLUMN b")
+	assert.True(t, errors.Is(err, alter.ErrCannotDrop), err)
+	_, err = execute(t, exec,
+		"ALTER TABLE u ADD COLUMN e INTEGER",
+		"CREATE TRIGGER tr2 AFTER INSERT ON u BEGIN INSERT INTO log VALUES (NEW.e); END",
+		"ALTER TABLE u DROP COLUMN e",
+	)
+	assert.True(t, errors.Is(err, alter.ErrCannotDrop), err)
+	result, err = execute(t, exec, "SELECT u.e FROM u WHERE u.a = 1")
+	require.NoError(t, err)
+	assert.Equal(t, "e\n<nil>", result.String())
+
+	_, err = execute(t, exec, "ALTER TABLE u RENAME TO v")
+	assert.True(t, errors.Is(err, alter.ErrTableExists), err)
+	_, err = execute(t, exec, "ALTER TABLE u RENAME COLUMN nosuch TO z")
+	assert.True(t, errors.Is(err, alter.ErrNoSuchColumn), err)
+	_, err = execute(t, exec, "ALTER TABLE nosuch ADD COLUMN z")
+	assert.True(t, errors.Is(err, attach.ErrNoSuchTable), err)
+}
+
+func TestExecute_Spill(t *testing.T) {
+	setup := []string{
+		"PRAGMA recursion_limit = 2000",
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, grp INTEGER, name TEXT)",
+		"CREATE TABLE u (id INTEGER PRIMARY KEY, grp INTEGER, label TEXT)",
+		"INSERT INTO t WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 2000) SELECT cnt.n, cnt.n % 500, 'n' || (cnt.n % 700) FROM cnt",
+		"INSERT INTO u WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 600) SELECT cnt.n, cnt.n % 500, 'l' || cnt.n FROM cnt",
+		"ANALYZE",
+	}
+	join := "SELECT a.id, b.label FROM t AS a JOIN u AS b ON b.grp = a.grp ORDER BY a.id, b.id"
+	queries := []string{
+		"SELECT t.id, t.name FROM t ORDER BY t.name DESC, t.id",
+		"SELECT t.grp, count(*), sum(t.id), max(t.name) FROM t GROUP BY t.grp",
+		"SELECT DISTINCT t.name FROM t ORDER BY t.name",
+		join,
+	}
+
+	pool := spill.NewPool(0, 0, t.TempDir())
+	exec := session(t, WithMemory(pool))
+	_, err := execute(t, exec, setup...)
+	require.NoError(t, err)
+	result, err := execute(t, exec, "EXPLAIN QUERY PLAN "+join)
+	require.NoError(t, err)
+	assert.Contains(t, result.String(), "HASH JOIN")
+
+	// without a limit, the queries run in memory
+	var expected []string
+	for _, query := range queries {
+		result, err := execute(t, exec, query)
+		require.NoError(t, err, query)
+		expected = append(expected, result.String())
+	}
+
+	// with a budget of a few KB, the queries spill, and return the same rows
+	result, err = execute(t, exec, "PRAGMA query_memory = '4KB'", "PRAGMA query_memory")
+	require.NoError(t, err)
+	assert.Equal(t, "query_memory\n4096", result.String())
+	for i, query := range queries {
+		result, err := execute(t, exec, query)
+		require.NoError(t, err, query)
+		assert.Equal(t, expected[i], result.String(), query)
+	}
+	assert.Zero(t, pool.Used())
+
+	// the queries need their temporary files
+	missing := session(t, WithMemory(spill.NewPool(0, 4096, filepath.Join(t.TempDir(), "missing"))))
+	_, err = execute(t, missing, setup...)
+	require.NoError(t, err)
+	for _, query := range queries {
+		_, err := execute(t, missing, query)
+		assert.True(t, errors.Is(err, fs.ErrNotExist), query)
+	}
+	_, err = execute(t, missing, "PRAGMA query_memory = 0", queries[0])
+	assert.True(t, errors.Is(err, fs.ErrNotExist), err)
+	_, err = execute(t, missing, "PRAGMA query_memory = '1MB'", queries[0])
+	assert.NoError(t, err)
+
+	_, err = execute(t, exec, "PRAGMA query_memory = 'lots'")
+	assert.True(t, errors.Is(err, spill.ErrInvalidSize), err)
+}
+
+func TestExecute_ExplainAnalyze(t *testing.T) {
+	exec := session(t)
+	_, err := execute(t, exec,
+		"CREATE TABLE t (id INTEGER PRIMARY KEY, grp INTEGER, name TEXT)",
+		"CREATE TABLE u (id INTEGER PRIMARY KEY, grp INTEGER, label TEXT)",
+		"CREATE INDEX u_grp ON u (grp)",
+		"INSERT INTO t WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 500) SELECT cnt.n, cnt.n % 50, 'n' || cnt.n FROM cnt",
+		"INSERT INTO u WITH RECURSIVE cnt (n) AS (SELECT 1 UNION ALL SELECT cnt.n + 1 FROM cnt WHERE cnt.n < 200) SELECT cnt.n, cnt.n % 100, 'l' || cnt.n FROM cnt",
+		"ANALYZE",
+	)
+	require.NoError(t, err)
+
+	// rows returns the detail, the actual rows and the loops of every
+	// operator of the result of EXPLAIN ANALYZE
+	type operator struct {
+		id, parent  int
+		detail      string
+		rows, loops int64
+		spill       string
+	}
+	rows := func(result Result) []operator {
+		assert.Equal(t, profile.ExplainColumns, result.Columns())
+		var ops []operator
+		for _, row := range result.Rows() {
+			ops = append(ops, operator{row[0].(int), row[1].(int), row[2].(string), row[5].(int64), row[6].(int64), row[11].(string)})
+		}
+		return ops
+	}
+
+	result, err := execute(t, exec, "EXPLAIN ANALYZE SELECT a.id, b.label FROM t AS a JOIN u AS b ON b.grp = a.grp WHERE a.id <= 20")
+	require.NoError(t, err)
+	assert.Equal(t, []operator{
+		{1, 0, "PROJECT a.id, b.label", 40, 1, "in memory"},
+		{2, 1, "HASH JOIN", 40, 1, "in memory"},
+		{3, 2, "SCAN u AS b (grp, label)", 200, 1, "in memory"},
+		{4, 2, "FILTER a.id <= 20", 20, 1, "in memory"},
+		{5, 4, "SCAN t AS a (grp, id)", 500, 1, "in memory"},
+	}, rows(result))
+	operators, ok := Profile(result)
+	require.True(t, ok)
+	data, err := json.Marshal(operators)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"detail":"HASH JOIN","rows":48`)
+
+	// a correlated subquery runs once for every row, and searches the index
+	// of u every time
+	result, err = execute(t, exec, "EXPLAIN ANALYZE SELECT a.id, (SELECT count(*) FROM u WHERE u.grp = a.grp) FROM t AS a WHERE a.id <= 3")
+	require.NoError(t, err)
+	assert.Equal(t, []operator{
+		{1, 0, "PROJECT a.id, (SUBQUERY)", 3, 1, "in memory"},
+		{2, 1, "FILTER a.id <= 3", 3, 1, "in memory"},
+		{3, 2, "SCAN t AS a (grp, id)", 500, 1, "in memory"},
+		{4, 1, "PROJECT count(*)", 3, 3, "in memory"},
+		{5, 4, "AGGREGATE count(*)", 3, 3, "in memory"},
+		{6, 5, "FILTER u.grp = a.grp", 6, 3, "in memory"},
+		{7, 6, "SEARCH u USING INDEX u_grp", 6, 3, "in memory"},
+	}, rows(result))
+
+	// the sort spills, if the rows don't fit into the budget of the query
+	result, err = execute(t, exec, "PRAGMA query_memory = '4KB'", "EXPLAIN ANALYZE SELECT t.name FROM t ORDER BY t.name")
+	require.NoError(t, err)
+	ops := rows(result)
+	assert.Equal(t, "ORDER BY t.name", ops[1].detail)
+	assert.Equal(t, int64(500), ops[1].rows)
+	assert.Contains(t, ops[1].spill, "spilled")
+	assert.Greater(t, result.Rows()[1][10].(int64), int64(0), "memory")
+
+	// the statement is executed, and the rows of a change are the changed
+	// rows
+	result, err = execute(t, exec, "EXPLAIN ANALYZE DELETE FROM t WHERE t.grp = 7")
+	require.NoError(t, err)
+	ops = rows(result)
+	assert.Equal(t, operator{1, 0, "DELETE FROM t", 10, 1, "in memory"}, ops[0])
+	result, err = execute(t, exec, "SELECT count(*) FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, "count(*)\n490", result.String())
+
+	_, err = execute(t, exec, "EXPLAIN ANALYZE CREATE TABLE v (a)")
+	assert.True(t, errors.Is(err, ErrUnsupported), err)
+}
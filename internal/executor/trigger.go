@@ -0,0 +1,301 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/constraint"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/optimizer"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// Relations of the OLD and NEW row in the program of a trigger.
+const (
+	oldRelation = "old"
+	newRelation = "new"
+)
+
+// defineTrigger converts a CREATE TRIGGER statement to a trigger of the
+// schema. The statements of the program are compiled when the trigger fires,
+// in the execution of the statement, that fired it.
+func (s *schema) defineTrigger(stmt *ast.CreateTriggerStmt) (*trigger.Trigger, error) {
+	t := &trigger.Trigger{
+		Name:  token.Unquote(stmt.TriggerName),
+		Table: token.Unquote(stmt.TableName),
+	}
+	switch {
+	case stmt.After != nil:
+		t.Time = trigger.After
+	case stmt.Instead != nil:
+		t.Time = trigger.InsteadOf
+	}
+	switch {
+	case stmt.Update != nil:
+		t.Event = trigger.Update
+	case stmt.Delete != nil:
+		t.Event = trigger.Delete
+	}
+	for _, name := range stmt.ColumnName {
+		t.Columns = append(t.Columns, token.Unquote(name))
+	}
+
+	cmds := make([]command.Command, len(stmt.TriggerStmt))
+	for i, ts := range stmt.TriggerStmt {
+		var err error
+		cmds[i], err = command.From(&ast.SQLStmt{
+			SelectStmt: ts.SelectStmt,
+			InsertStmt: ts.InsertStmt,
+			UpdateStmt: ts.UpdateStmt,
+			DeleteStmt: ts.DeleteStmt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		cmds[i].Plan = optimizer.Optimize(cmds[i].Plan)
+	}
+	t.Program = func(_ *trigger.Execution, old, new trigger.Row) error {
+		x, err := s.exec.program(s, t.Table, old, new)
+		if err != nil {
+			return err
+		}
+		for _, cmd := range cmds {
+			if _, err := x.execute(cmd); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if stmt.Expr != nil {
+		when, err := command.Expr(stmt.Expr)
+		if err != nil {
+			return nil, err
+		}
+		t.When = func(old, new trigger.Row) (bool, error) {
+			x, err := s.exec.program(s, t.Table, old, new)
+			if err != nil {
+				return false, err
+			}
+			e, err := x.expr(when, x.outer)
+			if err != nil {
+				return false, err
+			}
+			v, err := e.eval(x.outerFrame)
+			if err != nil {
+				return false, err
+			}
+			truth, _ := function.Truth(v)
+			return truth, nil
+		}
+	}
+	return t, nil
+}
+
+// program returns the execution of the program of a trigger on the given
+// table or view of the given schema, whose statements can refer to the given
+// OLD and NEW row.
+func (x *execution) program(s *schema, table string, old, new trigger.Row) (*execution, error) {
+	var columns []column
+	if t, ok := s.table(table); ok {
+		columns = t.columns
+	} else if s.hasView(table) {
+		var err error
+		if columns, err = x.viewColumns(s, table); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("%w: %v", attach.ErrNoSuchTable, table)
+	}
+	sc := &scope{}
+	for _, relation := range []string{oldRelation, newRelation} {
+		for _, col := range columns {
+			col.relation = relation
+			sc.columns = append(sc.columns, col)
+		}
+	}
+	row := make([]function.Value, len(sc.columns))
+	copy(row, old)
+	copy(row[len(columns):], new)
+	return &execution{
+		e:          x.e,
+		ctx:        x.ctx,
+		tx:         x.tx,
+		budget:     x.budget,
+		loaded:     x.loaded,
+		outer:      sc,
+		outerFrame: &frame{row: row},
+		firing:     x.firing,
+	}, nil
+}
+
+// raise compiles RAISE, which is an error of type *trigger.Raise.
+func (x *execution) raise(e *planner.Expr) (expression, error) {
+	if x.outer == nil {
+		return expression{}, ErrRaiseOutsideTrigger
+	}
+	raise := &trigger.Raise{Conflict: constraint.Ignore}
+	switch e.Name {
+	case "ROLLBACK":
+		raise.Conflict = constraint.Rollback
+	case "ABORT":
+		raise.Conflict = constraint.Abort
+	case "FAIL":
+		raise.Conflict = constraint.Fail
+	}
+	raise.Message, _ = e.Value.(string)
+	return expression{eval: func(*frame) (function.Value, error) {
+		return nil, raise
+	}}, nil
+}
+
+// fire fires the triggers of the given table of the given schema for the
+// change of a row, like trigger.Execution.Fire.
+func (x *execution) fire(s *schema, t *storedTable, time trigger.Time, event trigger.Event, updated []string, old, new trigger.Row) (bool, error) {
+	return x.triggers(s).Fire(t.name, time, event, updated, old, new)
+}
+
+// triggers returns the running triggers of the given schema.
+func (x *execution) triggers(s *schema) *trigger.Execution {
+	run, ok := x.firing[s]
+	if !ok {
+		run = s.runner.Begin()
+		x.firing[s] = run
+	}
+	return run
+}
+
+// triggerRow returns the given row of the table with the given row ID, as the
+// OLD or NEW row of a trigger, which has the row ID as last value. If the
+// table has an INTEGER PRIMARY KEY, its value is the row ID.
+func (t *storedTable) triggerRow(row constraint.Row, id function.Value) trigger.Row {
+	r := make(trigger.Row, len(t.columns))
+	copy(r, row)
+	r[len(r)-1] = id
+	for i, col := range t.t.Definition().Columns {
+		if col.RowID {
+			r[len(r)-1] = row[i]
+		}
+	}
+	return r
+}
+
+// newRow returns the row, that an INSERT of the given values of the given
+// columns inserts, before its constraints are enforced. Like in SQLite, the
+// row ID of the NEW row of a BEFORE INSERT trigger is NULL, unless it is
+// given.
+func (t *storedTable) newRow(columns []int, values constraint.Row) (trigger.Row, error) {
+	row := make(constraint.Row, t.width())
+	given := make([]bool, t.width())
+	for i, v := range values {
+		c := i
+		if columns != nil {
+			c = columns[i]
+		}
+		row[c], given[c] = v, true
+	}
+	for i, col := range t.t.Definition().Columns {
+		if given[i] || col.Default == nil {
+			continue
+		}
+		v, err := col.Default()
+		if err != nil {
+			return nil, err
+		}
+		row[i] = v
+	}
+	return t.triggerRow(row, nil), nil
+}
+
+// triggerError ends the given statement after a trigger failed with the given
+// error. Like a violation, RAISE(FAIL) keeps the changes, that the statement
+// made before.
+func triggerError(stmt *constraint.Statement, err error) error {
+	if conflict, ok := resolution(err); ok && conflict == constraint.Fail {
+		_ = stmt.Commit()
+	} else {
+		_ = stmt.Rollback()
+	}
+	return err
+}
+
+// createTrigger executes CREATE TRIGGER. The trigger is recorded in the
+// catalog of the database of its table.
+func (x *execution) createTrigger(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.CreateTriggerStmt
+	name := cmd.Name
+	db, s, err := x.resolve(token.Unquote(stmt.SchemaName), token.Unquote(stmt.TableName), hasRelation)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := s.triggers.Trigger(name); exists {
+		if stmt.If != nil {
+			return table{}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", trigger.ErrExists, name)
+	}
+	if strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+		return nil, fmt.Errorf("%w: %v", ErrReservedName, name)
+	}
+
+	def, err := s.defineTrigger(stmt)
+	if err != nil {
+		return nil, err
+	}
+	// the trigger is checked in a catalog of its own, since the catalog of
+	// the schema only changes when it is compiled again
+	if err := trigger.NewCatalog().Create(def, s.hasView(def.Table), false); err != nil {
+		return nil, err
+	}
+	// like SQLite, the definition is stored without IF NOT EXISTS and the
+	// schema name
+	var from token.Token
+	for _, t := range []token.Token{stmt.Before, stmt.After, stmt.Instead, stmt.Delete, stmt.Insert, stmt.Update} {
+		if t != nil {
+			from = t
+			break
+		}
+	}
+	sql := "CREATE TRIGGER " + stmt.TriggerName.Value() + " " + cmd.Text(from)
+
+	c := catalog{s.store.tx}
+	id, err := c.nextID()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.put(object{typ: typeTrigger, name: name, table: def.Table, id: id, sql: sql}); err != nil {
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
+
+// dropTrigger executes DROP TRIGGER.
+func (x *execution) dropTrigger(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.DropTriggerStmt
+	db, s, err := x.resolve(token.Unquote(stmt.SchemaName), cmd.Name, func(s *schema, name string) bool {
+		_, ok := s.triggers.Trigger(name)
+		return ok
+	})
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		if stmt.If != nil {
+			return table{}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", trigger.ErrNotExist, cmd.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, _ := s.triggers.Trigger(cmd.Name)
+	if err := (catalog{s.store.tx}).delete(t.Name); err != nil {
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
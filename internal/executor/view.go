@@ -0,0 +1,331 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/database/alter"
+	"github.com/tomarrell/lbadd/internal/database/attach"
+	"github.com/tomarrell/lbadd/internal/database/trigger"
+	"github.com/tomarrell/lbadd/internal/database/view"
+	"github.com/tomarrell/lbadd/internal/executor/command"
+	"github.com/tomarrell/lbadd/internal/executor/function"
+	"github.com/tomarrell/lbadd/internal/optimizer"
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// defineView converts a CREATE VIEW statement to a view.
+func defineView(stmt *ast.CreateViewStmt) *view.View {
+	v := view.From(stmt)
+	v.Name = token.Unquote(stmt.ViewName)
+	for i, name := range stmt.ColumnName {
+		v.Columns[i] = token.Unquote(name)
+	}
+	return v
+}
+
+// hasRelation reports whether the given schema has a table or view with the
+// given name.
+func hasRelation(s *schema, name string) bool {
+	_, ok := s.table(name)
+	return ok || s.hasView(name)
+}
+
+// tableColumns returns the names of the columns of the table with the given
+// name, in which the tables of views are looked up.
+func (x *execution) tableColumns(name string) ([]string, bool) {
+	_, _, t, err := x.table("", name)
+	if err != nil {
+		return nil, false
+	}
+	var names []string
+	for _, col := range visible(t.columns) {
+		names = append(names, col.name)
+	}
+	return names, true
+}
+
+// viewScan reads the rows of a view, which are the rows of its SELECT
+// statement, whose columns are renamed to the columns of the view.
+type viewScan struct {
+	child operator
+	// positions are the positions of the columns of the view in the rows of
+	// the child.
+	positions []int
+	cols      []column
+}
+
+// viewScan compiles the SELECT statement of the view with the given name of
+// the given schema, whose columns are qualified with the relation of the
+// given node.
+func (x *execution) viewScan(s *schema, name string, n *planner.Node) (operator, error) {
+	exp, err := s.views.Expand(name, x.tableColumns)
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := command.From(&ast.SQLStmt{SelectStmt: exp.View.Select})
+	if err != nil {
+		return nil, fmt.Errorf("view %v: %w", exp.View.Name, err)
+	}
+	// a view cannot refer to the query, that selects from it, nor to its
+	// CTEs
+	ctes := x.ctes
+	x.ctes = nil
+	child, err := x.node(optimizer.Optimize(cmd.Plan), nil)
+	x.ctes = ctes
+	if err != nil {
+		return nil, fmt.Errorf("view %v: %w", exp.View.Name, err)
+	}
+
+	v := &viewScan{child: child}
+	for i, col := range child.columns() {
+		if !col.hidden && len(v.positions) < len(exp.Columns) {
+			v.positions = append(v.positions, i)
+		}
+	}
+	if len(v.positions) != len(exp.Columns) {
+		return nil, fmt.Errorf("%w: %v", view.ErrColumnCount, exp.View.Name)
+	}
+	for i, name := range exp.Columns {
+		col := child.columns()[v.positions[i]]
+		col.relation, col.name, col.aggregate = n.Relation, name, ""
+		v.cols = append(v.cols, col)
+	}
+	return v, nil
+}
+
+func (v *viewScan) columns() []column { return v.cols }
+
+func (v *viewScan) run(_ *frame, emit func(row []function.Value) error) error {
+	return v.child.run(nil, func(row []function.Value) error {
+		values := make([]function.Value, len(v.positions))
+		for i, p := range v.positions {
+			values[i] = row[p]
+		}
+		return emit(values)
+	})
+}
+
+// viewColumns returns the columns of the view with the given name of the
+// given schema, which are the columns of the OLD and NEW row of its
+// triggers.
+func (x *execution) viewColumns(s *schema, name string) ([]column, error) {
+	exp, err := s.views.Expand(name, x.tableColumns)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]column, len(exp.Columns))
+	for i, name := range exp.Columns {
+		columns[i] = column{relation: exp.View.Name, name: name}
+	}
+	return columns, nil
+}
+
+// changeView executes INSERT, UPDATE or DELETE on a view, which fires the
+// INSTEAD OF triggers of the view for every row, instead of changing it. If
+// the relation of the command is not a view, the given error of looking up
+// the table is returned.
+func (x *execution) changeView(cmd command.Command, notTable error) (Result, error) {
+	n := cmd.Plan
+	_, s, err := x.resolve(n.Schema, n.Relation, func(s *schema, name string) bool {
+		return s.hasView(name)
+	})
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		return nil, notTable
+	}
+	if err != nil {
+		return nil, err
+	}
+	v, _ := s.views.View(n.Relation)
+	event := trigger.Insert
+	switch cmd.Op {
+	case command.Update:
+		event = trigger.Update
+	case command.Delete:
+		event = trigger.Delete
+	}
+	run := x.triggers(s)
+	if err := view.Writable(run, v.Name, event, n.Columns); err != nil {
+		return nil, err
+	}
+	columns, err := x.viewColumns(s, v.Name)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]int, len(n.Columns))
+	for i, name := range n.Columns {
+		if positions[i] = columnPosition(columns, name); positions[i] < 0 {
+			return nil, fmt.Errorf("%w: %v.%v", ErrNoSuchColumn, v.Name, name)
+		}
+	}
+	op, err := x.node(n.Children[0], x.outer)
+	if err != nil {
+		return nil, err
+	}
+
+	// all rows are computed before the first trigger fires
+	var olds, news []trigger.Row
+	switch cmd.Op {
+	case command.Insert:
+		rows, err := collect(op, x.outerFrame)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if n.Columns == nil && len(row) != len(columns) {
+				return nil, fmt.Errorf("%w: view %v has %d columns but %d values were supplied", ErrValueCount, v.Name, len(columns), len(row))
+			}
+			if n.Columns != nil && len(row) != len(n.Columns) {
+				return nil, fmt.Errorf("%w: %d values for %d columns", ErrValueCount, len(row), len(n.Columns))
+			}
+			new := make(trigger.Row, len(columns))
+			for i, value := range row {
+				if n.Columns == nil {
+					new[i] = value
+				} else {
+					new[positions[i]] = value
+				}
+			}
+			olds, news = append(olds, nil), append(news, new)
+		}
+	case command.Update:
+		exprs, err := x.exprs(n.Projections, &scope{columns: op.columns(), outer: x.outer})
+		if err != nil {
+			return nil, err
+		}
+		err = op.run(x.outerFrame, func(row []function.Value) error {
+			f := &frame{row: row, outer: x.outerFrame}
+			new := append(trigger.Row{}, row...)
+			for i, e := range exprs {
+				value, err := e.eval(f)
+				if err != nil {
+					return err
+				}
+				new[positions[i]] = value
+			}
+			olds, news = append(olds, row), append(news, new)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	case command.Delete:
+		rows, err := collect(op, x.outerFrame)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			olds, news = append(olds, row), append(news, nil)
+		}
+	}
+
+	for i := range olds {
+		if _, err := run.Fire(v.Name, trigger.InsteadOf, event, n.Columns, olds[i], news[i]); err != nil {
+			return nil, err
+		}
+	}
+	return table{}, nil
+}
+
+// columnPosition returns the position of the column with the given name, or
+// -1.
+func columnPosition(columns []column, name string) int {
+	for i, col := range columns {
+		if strings.EqualFold(col.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// createView executes CREATE VIEW. The view is recorded in the catalog of its
+// database.
+func (x *execution) createView(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.CreateViewStmt
+	db, err := x.e.session.CreateView(stmt)
+	if err != nil {
+		return nil, err
+	}
+	s, err := x.schema(db)
+	if err != nil {
+		return nil, err
+	}
+	name := cmd.Name
+	if _, exists := s.table(name); exists || s.hasView(name) || s.hasIndex(name) {
+		switch {
+		case stmt.If != nil:
+			return table{}, nil
+		case s.hasView(name):
+			return nil, fmt.Errorf("%w: %v", view.ErrExists, name)
+		}
+		return nil, fmt.Errorf("%w: %v", alter.ErrTableExists, name)
+	}
+	if strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+		return nil, fmt.Errorf("%w: %v", ErrReservedName, name)
+	}
+
+	// the view is checked in a catalog of its own, since the catalog of the
+	// schema only changes when it is compiled again
+	check := view.NewCatalog()
+	for _, v := range s.views.Views() {
+		if err := check.Load(v); err != nil {
+			return nil, err
+		}
+	}
+	if err := check.Create(defineView(stmt), false, x.tableColumns); err != nil {
+		return nil, err
+	}
+	// like SQLite, the definition is stored without TEMP, IF NOT EXISTS and
+	// the schema name
+	from := stmt.As
+	if stmt.LeftParen != nil {
+		from = stmt.LeftParen
+	}
+	sql := "CREATE VIEW " + stmt.ViewName.Value() + " " + cmd.Text(from)
+
+	c := catalog{s.store.tx}
+	id, err := c.nextID()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.put(object{typ: typeView, name: name, table: name, id: id, sql: sql}); err != nil {
+		return nil, err
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
+
+// dropView executes DROP VIEW. The triggers of the view are dropped with it.
+func (x *execution) dropView(cmd command.Command) (Result, error) {
+	stmt := cmd.Stmt.DropViewStmt
+	db, s, err := x.resolve(token.Unquote(stmt.SchemaName), cmd.Name, func(s *schema, name string) bool {
+		return s.hasView(name)
+	})
+	if errors.Is(err, attach.ErrNoSuchTable) {
+		if stmt.If != nil {
+			return table{}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", view.ErrNotExist, cmd.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	v, _ := s.views.View(cmd.Name)
+	c := catalog{s.store.tx}
+	if err := c.delete(v.Name); err != nil {
+		return nil, err
+	}
+	for _, tr := range s.triggers.Triggers() {
+		if !strings.EqualFold(tr.Table, v.Name) {
+			continue
+		}
+		if err := c.delete(tr.Name); err != nil {
+			return nil, err
+		}
+	}
+	_, err = x.reload(db)
+	return table{}, err
+}
@@ -0,0 +1,103 @@
+package optimizer
+
+import "github.com/tomarrell/lbadd/internal/planner"
+
+// Decorrelation turns subqueries in the predicate of a Selection into joins,
+// so that they are not evaluated once for every row. EXISTS becomes a
+// SemiJoin, NOT EXISTS an AntiJoin, and IN a SemiJoin on the equality of the
+// value and the projection of the subquery. The predicate of a Selection at
+// the root of the subquery becomes the condition of the join, so a subquery
+// can only be decorrelated, if no other part of it references the outer
+// query.
+//
+// NOT IN is not decorrelated, since it is not true if the subquery returns
+// NULL, which an AntiJoin cannot express.
+type Decorrelation struct{}
+
+// Name returns the name of the rule.
+func (Decorrelation) Name() string { return "subquery decorrelation" }
+
+// Apply decorrelates the subqueries in the predicate of the given Selection.
+func (Decorrelation) Apply(n *planner.Node) (*planner.Node, bool) {
+	if n.Op != planner.Selection {
+		return n, false
+	}
+
+	input := n.Children[0]
+	var remaining []*planner.Expr
+	changed := false
+	for _, c := range conjuncts(n.Predicate) {
+		join, ok := decorrelate(c)
+		if !ok {
+			remaining = append(remaining, c)
+			continue
+		}
+		join.Children = []*planner.Node{input, join.Children[0]}
+		input, changed = join, true
+	}
+	if !changed {
+		return n, false
+	}
+	return filter(input, remaining), true
+}
+
+// decorrelate returns a join with the subquery of the given predicate as its
+// only child, which must be prepended by the input of the Selection.
+func decorrelate(e *planner.Expr) (*planner.Node, bool) {
+	joinType := planner.SemiJoin
+	if e.Op == planner.NotExpr && e.Args[0].Op == planner.ExistsExpr {
+		joinType, e = planner.AntiJoin, e.Args[0]
+	}
+
+	switch e.Op {
+	case planner.ExistsExpr:
+		// the projection does not matter for EXISTS
+		subquery := e.Subquery
+		if subquery.Op == planner.Projection {
+			subquery = subquery.Children[0]
+		}
+		input, condition, ok := lift(subquery)
+		if !ok {
+			return nil, false
+		}
+		return &planner.Node{
+			Op:        planner.Join,
+			JoinType:  joinType,
+			Predicate: condition,
+			Children:  []*planner.Node{input},
+		}, true
+	case planner.InExpr:
+		projection := e.Subquery
+		if projection == nil {
+			// a list of values
+			return nil, false
+		}
+		if projection.Op != planner.Projection || len(projection.Projections) != 1 {
+			return nil, false
+		}
+		input, condition, ok := lift(projection.Children[0])
+		if !ok || !relations(input).contains(references(projection.Projections[0])) {
+			return nil, false
+		}
+		equal := planner.NewExpr(planner.EqExpr, e.Args[0], projection.Projections[0])
+		return &planner.Node{
+			Op:        planner.Join,
+			JoinType:  planner.SemiJoin,
+			Predicate: conjoin(equal, condition),
+			Children:  []*planner.Node{input},
+		}, true
+	}
+	return nil, false
+}
+
+// lift removes the Selections at the root of the given subquery, and returns
+// the rest of the subquery and the conjunction of their predicates. It
+// reports false, if the rest of the subquery references an outer query.
+func lift(subquery *planner.Node) (*planner.Node, *planner.Expr, bool) {
+	var condition *planner.Expr
+	for subquery.Op == planner.Selection {
+		condition = conjoin(condition, subquery.Predicate)
+		subquery = subquery.Children[0]
+	}
+	return subquery, condition, len(correlated(subquery)) == 0
+}
@@ -0,0 +1,146 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestDecorrelation(t *testing.T) {
+	// SELECT * FROM orders WHERE orders.customer = customers.id AND orders.total > 10
+	correlated := func() *planner.Node {
+		return selection(and(eq(orderCustomer, customerID), gt(orderTotal, integer(10))), scan("orders"))
+	}
+
+	tests := []golden{
+		{
+			name: "exists",
+			plan: selection(planner.Exists(projection(correlated(), integer(1))), scan("customers")),
+			before: "`" + `--FILTER EXISTS (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--PROJECT 1
+         ` + "`" + `--FILTER orders.customer = customers.id AND orders.total > 10
+            ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--SEMI JOIN ON orders.customer = customers.id AND orders.total > 10
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "not exists",
+			plan: selection(
+				and(eq(customerCountry, planner.Const("NZ")), planner.NewExpr(planner.NotExpr, planner.Exists(correlated()))),
+				scan("customers"),
+			),
+			before: "`" + `--FILTER customers.country = 'NZ' AND NOT EXISTS (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--FILTER orders.customer = customers.id AND orders.total > 10
+         ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER customers.country = 'NZ'
+   ` + "`" + `--ANTI JOIN ON orders.customer = customers.id AND orders.total > 10
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "in",
+			plan: selection(planner.In(customerID, projection(selection(gt(orderTotal, integer(10)), scan("orders")), orderCustomer)), scan("customers")),
+			before: "`" + `--FILTER customers.id IN (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--PROJECT orders.customer
+         ` + "`" + `--FILTER orders.total > 10
+            ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--SEMI JOIN ON customers.id = orders.customer AND orders.total > 10
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "correlated in",
+			plan: selection(planner.In(customerID, projection(correlated(), orderCustomer)), scan("customers")),
+			before: "`" + `--FILTER customers.id IN (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--PROJECT orders.customer
+         ` + "`" + `--FILTER orders.customer = customers.id AND orders.total > 10
+            ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--SEMI JOIN ON customers.id = orders.customer AND orders.customer = customers.id AND orders.total > 10
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "correlated below join",
+			plan: selection(
+				planner.Exists(join(planner.InnerJoin, eq(orderCustomer, customerID), scan("orders"), scan("products"))),
+				scan("customers"),
+			),
+			before: "`" + `--FILTER EXISTS (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+         |--SCAN orders
+         ` + "`" + `--SCAN products
+`,
+			after: "`" + `--FILTER EXISTS (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+         |--SCAN orders
+         ` + "`" + `--SCAN products
+`,
+		},
+		{
+			name: "correlated projection",
+			plan: selection(planner.In(integer(1), projection(scan("orders"), customerID)), scan("customers")),
+			before: "`" + `--FILTER 1 IN (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--PROJECT customers.id
+         ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER 1 IN (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--PROJECT customers.id
+         ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "not in",
+			plan: selection(planner.NewExpr(planner.NotExpr, planner.In(customerID, projection(scan("orders"), orderCustomer))), scan("customers")),
+			before: "`" + `--FILTER NOT customers.id IN (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--PROJECT orders.customer
+         ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER NOT customers.id IN (SUBQUERY)
+   |--SCAN customers
+   ` + "`" + `--SUBQUERY
+      ` + "`" + `--PROJECT orders.customer
+         ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "in list",
+			plan: selection(planner.NewExpr(planner.InExpr, customerID, integer(1), integer(2)), scan("customers")),
+			before: "`" + `--FILTER customers.id IN (1, 2)
+   ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--FILTER customers.id IN (1, 2)
+   ` + "`" + `--SCAN customers
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt.run(t, apply(Decorrelation{}))
+	}
+}
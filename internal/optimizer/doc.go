@@ -0,0 +1,22 @@
+// Package optimizer rewrites logical plans into equivalent plans, that are
+// cheaper to execute, before the planner chooses the physical operations.
+//
+// A Rule rewrites a single node of a plan. Optimize applies a set of rules
+// to every node of a plan, from the root to the leaves, and repeats that
+// until no rule changes the plan anymore. The default Rules are
+//
+//   - ConstantFolding, which evaluates constant expressions,
+//   - Decorrelation, which turns EXISTS and IN subqueries into semi-joins and
+//     NOT EXISTS subqueries into anti-joins,
+//   - OuterJoinSimplification, which turns a left join into an inner join, if
+//     a filter above it rejects the rows without a match,
+//   - PredicatePushdown, which moves filters and join conditions as close to
+//     the scans as possible,
+//   - ProjectionPruning, which lets scans read only the columns, that a
+//     projection needs, and
+//   - LimitPushdown, which moves limits below projections, and into the
+//     first child of left joins.
+//
+// Rules never modify a plan, but create new nodes for the parts that they
+// rewrite, so plans can share nodes.
+package optimizer
@@ -0,0 +1,176 @@
+package optimizer
+
+import "github.com/tomarrell/lbadd/internal/planner"
+
+// set is a set of relation names.
+type set map[string]bool
+
+func (s set) contains(other set) bool {
+	for name := range other {
+		if !s[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func union(sets ...set) set {
+	u := make(set)
+	for _, s := range sets {
+		for name := range s {
+			u[name] = true
+		}
+	}
+	return u
+}
+
+// conjuncts returns the arguments of the given conjunction, or the given
+// expression, if it is not a conjunction.
+func conjuncts(e *planner.Expr) []*planner.Expr {
+	if e == nil {
+		return nil
+	}
+	if e.Op != planner.AndExpr {
+		return []*planner.Expr{e}
+	}
+	var result []*planner.Expr
+	for _, arg := range e.Args {
+		result = append(result, conjuncts(arg)...)
+	}
+	return result
+}
+
+// conjoin returns the conjunction of the given expressions, or nil if there
+// are none.
+func conjoin(exprs ...*planner.Expr) *planner.Expr {
+	var args []*planner.Expr
+	for _, e := range exprs {
+		args = append(args, conjuncts(e)...)
+	}
+	switch len(args) {
+	case 0:
+		return nil
+	case 1:
+		return args[0]
+	}
+	return planner.NewExpr(planner.AndExpr, args...)
+}
+
+// filter returns a Selection of the given node with the conjunction of the
+// given predicates, or the node, if there are no predicates.
+func filter(n *planner.Node, predicates []*planner.Expr) *planner.Node {
+	if len(predicates) == 0 {
+		return n
+	}
+	return &planner.Node{
+		Op:        planner.Selection,
+		Predicate: conjoin(predicates...),
+		Children:  []*planner.Node{n},
+	}
+}
+
+// columns calls the given function for every column, that the given
+// expression references, also in its subqueries.
+func columns(e *planner.Expr, fn func(planner.Column)) {
+	if e == nil {
+		return
+	}
+	if e.Op == planner.ColumnExpr {
+		fn(e.Column)
+	}
+	for _, arg := range e.Args {
+		columns(arg, fn)
+	}
+	if e.Subquery != nil {
+		nodeColumns(e.Subquery, fn)
+	}
+}
+
+// nodeColumns calls the given function for every column, that the
+// expressions of the given node and the nodes below it reference.
+func nodeColumns(n *planner.Node, fn func(planner.Column)) {
+	for _, e := range n.Exprs() {
+		columns(e, fn)
+	}
+	for _, child := range n.Children {
+		nodeColumns(child, fn)
+	}
+}
+
+// references returns the relations, that the given expression references.
+// Columns of relations, that a subquery reads itself, are not included.
+func references(e *planner.Expr) set {
+	refs := make(set)
+	if e == nil {
+		return refs
+	}
+	if e.Op == planner.ColumnExpr {
+		refs[e.Column.Relation] = true
+	}
+	for _, arg := range e.Args {
+		refs = union(refs, references(arg))
+	}
+	if e.Subquery != nil {
+		refs = union(refs, correlated(e.Subquery))
+	}
+	return refs
+}
+
+// correlated returns the relations, that the given plan references, but does
+// not read itself. These are the relations of an outer query, that a
+// correlated subquery references.
+func correlated(n *planner.Node) set {
+	refs := make(set)
+	var visit func(n *planner.Node)
+	visit = func(n *planner.Node) {
+		for _, e := range n.Exprs() {
+			refs = union(refs, references(e))
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	visit(n)
+	for name := range scans(n) {
+		delete(refs, name)
+	}
+	return refs
+}
+
+// scans returns the relations, that the given plan scans, without the
+// relations of its subqueries. A subquery in a FROM clause is scanned as the
+// relation of its alias.
+func scans(n *planner.Node) set {
+	if n.Op == planner.FullScan || n.Op == planner.IndexScan || derived(n) {
+		return set{n.Relation: true}
+	}
+	var sets []set
+	for _, child := range n.Children {
+		sets = append(sets, scans(child))
+	}
+	return union(sets...)
+}
+
+// relations returns the relations, whose columns the rows of the given node
+// have.
+func relations(n *planner.Node) set {
+	switch {
+	case n.Op == planner.FullScan, n.Op == planner.IndexScan, derived(n):
+		return set{n.Relation: true}
+	case n.Op == planner.Join:
+		if n.JoinType == planner.SemiJoin || n.JoinType == planner.AntiJoin {
+			return relations(n.Children[0])
+		}
+	}
+	var sets []set
+	for _, child := range n.Children {
+		sets = append(sets, relations(child))
+	}
+	return union(sets...)
+}
+
+// derived reports whether the given node is the Projection of a subquery in
+// a FROM clause, whose columns belong to the relation of its alias.
+func derived(n *planner.Node) bool {
+	return n.Op == planner.Projection && n.Relation != ""
+}
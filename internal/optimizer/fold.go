@@ -0,0 +1,319 @@
+package optimizer
+
+import (
+	"math"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// ConstantFolding evaluates the parts of predicates and projections, that
+// only depend on constants. AND and OR are simplified, if one of their
+// arguments is a constant. A Selection with a predicate, that is always
+// true, is removed, and so is the predicate of a Join, that is always true.
+type ConstantFolding struct{}
+
+// Name returns the name of the rule.
+func (ConstantFolding) Name() string { return "constant folding" }
+
+// Apply folds the expressions of the given node.
+func (ConstantFolding) Apply(n *planner.Node) (*planner.Node, bool) {
+	switch n.Op {
+	case planner.Selection:
+		predicate, changed := fold(n.Predicate)
+		if predicate.IsTrue() {
+			return n.Children[0], true
+		}
+		if changed {
+			n = clone(n)
+			n.Predicate = predicate
+		}
+		return n, changed
+	case planner.Join:
+		if n.Predicate == nil {
+			return n, false
+		}
+		predicate, changed := fold(n.Predicate)
+		if predicate.IsTrue() {
+			predicate, changed = nil, true
+		}
+		if changed {
+			n = clone(n)
+			n.Predicate = predicate
+		}
+		return n, changed
+	case planner.Projection:
+		var projections []*planner.Expr
+		for i, e := range n.Projections {
+			folded, changed := fold(e)
+			if !changed {
+				continue
+			}
+			if projections == nil {
+				projections = append([]*planner.Expr(nil), n.Projections...)
+			}
+			projections[i] = folded
+		}
+		if projections == nil {
+			return n, false
+		}
+		n = clone(n)
+		n.Projections = projections
+		return n, true
+	}
+	return n, false
+}
+
+// fold evaluates the constant parts of the given expression.
+func fold(e *planner.Expr) (*planner.Expr, bool) {
+	if e == nil || len(e.Args) == 0 {
+		return e, false
+	}
+	changed := false
+	args := make([]*planner.Expr, len(e.Args))
+	for i, arg := range e.Args {
+		var ok bool
+		if args[i], ok = fold(arg); ok {
+			changed = true
+		}
+	}
+	// the copy keeps the name of a function, and the other fields, that
+	// are not folded
+	copied := *e
+	copied.Args = args
+	folded := &copied
+
+	switch e.Op {
+	case planner.AndExpr, planner.OrExpr:
+		if result, ok := foldLogic(folded); ok {
+			return result, true
+		}
+	case planner.NotExpr:
+		if v, ok := constant(args[0]); ok {
+			if b, ok := v.(bool); ok {
+				return planner.Const(!b), true
+			}
+			if v == nil {
+				return planner.Const(nil), true
+			}
+		}
+	case planner.IsNullExpr:
+		if v, ok := constant(args[0]); ok {
+			return planner.Const(v == nil), true
+		}
+	case planner.EqExpr, planner.NeExpr, planner.LtExpr, planner.LeExpr, planner.GtExpr, planner.GeExpr:
+		if result, ok := foldComparison(e.Op, args[0], args[1]); ok {
+			return result, true
+		}
+	case planner.AddExpr, planner.SubExpr, planner.MulExpr, planner.DivExpr:
+		if result, ok := foldArithmetic(e.Op, args[0], args[1]); ok {
+			return result, true
+		}
+	}
+	if !changed {
+		return e, false
+	}
+	return folded, true
+}
+
+// foldLogic simplifies a conjunction or a disjunction. The dominant constant,
+// which is FALSE for AND and TRUE for OR, determines the result, and the
+// other boolean constant is dropped.
+func foldLogic(e *planner.Expr) (*planner.Expr, bool) {
+	dominant := e.Op == planner.OrExpr
+	var args []*planner.Expr
+	for _, arg := range e.Args {
+		if v, ok := constant(arg); ok {
+			if b, ok := v.(bool); ok {
+				if b == dominant {
+					return planner.Const(dominant), true
+				}
+				continue
+			}
+		}
+		args = append(args, arg)
+	}
+	switch {
+	case len(args) == len(e.Args):
+		return nil, false
+	case len(args) == 0:
+		return planner.Const(!dominant), true
+	case len(args) == 1:
+		return args[0], true
+	}
+	return planner.NewExpr(e.Op, args...), true
+}
+
+// foldComparison compares two constants. Like in SQLite, the result is NULL
+// if any of them is NULL, and numbers are less than strings. Blobs are not
+// compared.
+func foldComparison(op planner.ExprOp, left, right *planner.Expr) (*planner.Expr, bool) {
+	l, ok := constant(left)
+	if !ok || isBlob(l) {
+		return nil, false
+	}
+	r, ok := constant(right)
+	if !ok || isBlob(r) {
+		return nil, false
+	}
+	if l == nil || r == nil {
+		return planner.Const(nil), true
+	}
+	c := compare(l, r)
+	var result bool
+	switch op {
+	case planner.EqExpr:
+		result = c == 0
+	case planner.NeExpr:
+		result = c != 0
+	case planner.LtExpr:
+		result = c < 0
+	case planner.LeExpr:
+		result = c <= 0
+	case planner.GtExpr:
+		result = c > 0
+	case planner.GeExpr:
+		result = c >= 0
+	}
+	return planner.Const(result), true
+}
+
+func compare(l, r interface{}) int {
+	ls, lString := l.(string)
+	rs, rString := r.(string)
+	switch {
+	case lString && rString:
+		return strings.Compare(ls, rs)
+	case lString:
+		return 1
+	case rString:
+		return -1
+	}
+	if li, ok := l.(int64); ok {
+		if ri, ok := r.(int64); ok {
+			switch {
+			case li < ri:
+				return -1
+			case li > ri:
+				return 1
+			}
+			return 0
+		}
+	}
+	lf, rf := number(l), number(r)
+	switch {
+	case lf < rf:
+		return -1
+	case lf > rf:
+		return 1
+	}
+	return 0
+}
+
+// foldArithmetic computes two numeric constants. The result is NULL if any
+// of them is NULL, or for a division by zero. Integers are computed as
+// integers, unless the result overflows.
+func foldArithmetic(op planner.ExprOp, left, right *planner.Expr) (*planner.Expr, bool) {
+	l, ok := constant(left)
+	if !ok || !numeric(l) {
+		return nil, false
+	}
+	r, ok := constant(right)
+	if !ok || !numeric(r) {
+		return nil, false
+	}
+	if l == nil || r == nil {
+		return planner.Const(nil), true
+	}
+
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		if result, ok := integerArithmetic(op, li, ri); ok {
+			return result, true
+		}
+	}
+	lf, rf := number(l), number(r)
+	switch op {
+	case planner.AddExpr:
+		return planner.Const(lf + rf), true
+	case planner.SubExpr:
+		return planner.Const(lf - rf), true
+	case planner.MulExpr:
+		return planner.Const(lf * rf), true
+	}
+	if rf == 0 {
+		return planner.Const(nil), true
+	}
+	return planner.Const(lf / rf), true
+}
+
+// integerArithmetic computes two integers, and reports false if the result
+// overflows.
+func integerArithmetic(op planner.ExprOp, l, r int64) (*planner.Expr, bool) {
+	switch op {
+	case planner.AddExpr:
+		if (r > 0 && l > math.MaxInt64-r) || (r < 0 && l < math.MinInt64-r) {
+			return nil, false
+		}
+		return planner.Const(l + r), true
+	case planner.SubExpr:
+		if (r < 0 && l > math.MaxInt64+r) || (r > 0 && l < math.MinInt64+r) {
+			return nil, false
+		}
+		return planner.Const(l - r), true
+	case planner.MulExpr:
+		if l != 0 && r != 0 {
+			p := l * r
+			if p/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+				return nil, false
+			}
+		}
+		return planner.Const(l * r), true
+	}
+	if r == 0 {
+		return planner.Const(nil), true
+	}
+	if l == math.MinInt64 && r == -1 {
+		return nil, false
+	}
+	return planner.Const(l / r), true
+}
+
+// constant returns the value of the given expression, if it is a constant.
+func constant(e *planner.Expr) (interface{}, bool) {
+	if e.Op != planner.ConstExpr {
+		return nil, false
+	}
+	return e.Value, true
+}
+
+func isBlob(v interface{}) bool {
+	_, ok := v.([]byte)
+	return ok
+}
+
+// numeric reports whether the given constant is a number or NULL.
+func numeric(v interface{}) bool {
+	switch v.(type) {
+	case nil, int64, float64:
+		return true
+	}
+	return false
+}
+
+// number converts a number or a boolean to a float64. Booleans are 1 and 0,
+// like in SQLite.
+func number(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+	}
+	return 0
+}
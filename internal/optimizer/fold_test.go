@@ -0,0 +1,112 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestConstantFolding(t *testing.T) {
+	tests := []golden{
+		{
+			name: "arithmetic",
+			plan: projection(scan("orders"),
+				planner.NewExpr(planner.AddExpr, orderTotal, planner.NewExpr(planner.MulExpr, integer(2), integer(3))),
+				planner.NewExpr(planner.DivExpr, integer(7), planner.Const(2.0)),
+				planner.NewExpr(planner.DivExpr, integer(1), integer(0)),
+				planner.NewExpr(planner.AddExpr, integer(9223372036854775807), integer(1)),
+			),
+			before: "`" + `--PROJECT orders.total + 2 * 3, 7 / 2, 1 / 0, 9223372036854775807 + 1
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--PROJECT orders.total + 6, 3.5, NULL, 9.223372036854776e+18
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "true selection",
+			plan: selection(and(eq(integer(1), integer(1)), planner.NewExpr(planner.LtExpr, planner.Const("a"), planner.Const("b"))), scan("orders")),
+			before: "`" + `--FILTER 1 = 1 AND 'a' < 'b'
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "and",
+			plan: selection(and(gt(orderTotal, integer(10)), eq(integer(1), integer(1)), gt(integer(2), integer(1))), scan("orders")),
+			before: "`" + `--FILTER orders.total > 10 AND 1 = 1 AND 2 > 1
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "false and",
+			plan: selection(and(gt(orderTotal, integer(10)), eq(integer(1), integer(2))), scan("orders")),
+			before: "`" + `--FILTER orders.total > 10 AND 1 = 2
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER FALSE
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "or",
+			plan: selection(planner.NewExpr(planner.OrExpr, gt(orderTotal, integer(10)), planner.NewExpr(planner.NotExpr, planner.Const(false))), scan("orders")),
+			before: "`" + `--FILTER orders.total > 10 OR NOT FALSE
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "null",
+			plan: selection(planner.NewExpr(planner.OrExpr, eq(orderTotal, planner.Const(nil)), planner.NewExpr(planner.IsNullExpr, planner.Const(nil))), scan("orders")),
+			before: "`" + `--FILTER orders.total = NULL OR NULL IS NULL
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "join",
+			plan: join(planner.InnerJoin, and(eq(integer(1), integer(1)), planner.Const(true)), scan("customers"), scan("orders")),
+			before: "`" + `--INNER JOIN ON 1 = 1 AND TRUE
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--INNER JOIN
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "nothing to fold",
+			plan: selection(gt(orderTotal, integer(10)), scan("orders")),
+			before: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "functions and blobs",
+			plan: projection(scan("orders"),
+				&planner.Expr{Op: planner.FuncExpr, Name: "count", Distinct: true, Args: []*planner.Expr{planner.NewExpr(planner.AddExpr, orderTotal, planner.NewExpr(planner.MulExpr, integer(2), integer(3)))}},
+				eq(planner.Const([]byte{1}), planner.Const([]byte{2})),
+			),
+			before: "`" + `--PROJECT count(DISTINCT orders.total + 2 * 3), X'01' = X'02'
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--PROJECT count(DISTINCT orders.total + 6), X'01' = X'02'
+   ` + "`" + `--SCAN orders
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt.run(t, apply(ConstantFolding{}))
+	}
+}
@@ -0,0 +1,72 @@
+package optimizer
+
+import "github.com/tomarrell/lbadd/internal/planner"
+
+// LimitPushdown moves a Limit below Projections, merges consecutive Limits,
+// and adds a Limit to the first child of a LeftJoin below a Limit, since
+// every row of the first child produces at least one row of the join. A
+// Limit without a maximum and without an offset is removed.
+type LimitPushdown struct{}
+
+// Name returns the name of the rule.
+func (LimitPushdown) Name() string { return "limit pushdown" }
+
+// Apply pushes the given Limit down.
+func (LimitPushdown) Apply(n *planner.Node) (*planner.Node, bool) {
+	if n.Op != planner.Limit {
+		return n, false
+	}
+	if n.Count < 0 && n.Offset == 0 {
+		return n.Children[0], true
+	}
+
+	child := n.Children[0]
+	switch child.Op {
+	case planner.Projection:
+		limit := clone(n)
+		limit.Children[0] = child.Children[0]
+		child = clone(child)
+		child.Children[0] = limit
+		return child, true
+	case planner.Limit:
+		// the outer limit skips n.Offset of the rows, that the inner limit
+		// passes on
+		count := child.Count
+		if count >= 0 {
+			count -= n.Offset
+			if count < 0 {
+				count = 0
+			}
+			if n.Count >= 0 && n.Count < count {
+				count = n.Count
+			}
+		} else {
+			count = n.Count
+		}
+		return &planner.Node{
+			Op:       planner.Limit,
+			Count:    count,
+			Offset:   child.Offset + n.Offset,
+			Children: child.Children,
+		}, true
+	case planner.Join:
+		if child.JoinType != planner.LeftJoin || n.Count < 0 {
+			return n, false
+		}
+		count := n.Count + n.Offset
+		left := child.Children[0]
+		if left.Op == planner.Limit && left.Offset == 0 && left.Count >= 0 && left.Count <= count {
+			return n, false
+		}
+		child = clone(child)
+		child.Children[0] = &planner.Node{
+			Op:       planner.Limit,
+			Count:    count,
+			Children: []*planner.Node{left},
+		}
+		n = clone(n)
+		n.Children[0] = child
+		return n, true
+	}
+	return n, false
+}
@@ -0,0 +1,114 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestLimitPushdown(t *testing.T) {
+	tests := []golden{
+		{
+			name: "below projection",
+			plan: limit(10, 5, projection(scan("customers"), customerName)),
+			before: "`" + `--LIMIT 10 OFFSET 5
+   ` + "`" + `--PROJECT customers.name
+      ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--PROJECT customers.name
+   ` + "`" + `--LIMIT 10 OFFSET 5
+      ` + "`" + `--SCAN customers
+`,
+		},
+		{
+			name: "merge limits",
+			plan: limit(10, 5, limit(12, 2, scan("customers"))),
+			before: "`" + `--LIMIT 10 OFFSET 5
+   ` + "`" + `--LIMIT 12 OFFSET 2
+      ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--LIMIT 7 OFFSET 7
+   ` + "`" + `--SCAN customers
+`,
+		},
+		{
+			name: "merge unlimited",
+			plan: limit(-1, 5, limit(-1, 2, scan("customers"))),
+			before: "`" + `--LIMIT -1 OFFSET 5
+   ` + "`" + `--LIMIT -1 OFFSET 2
+      ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--LIMIT -1 OFFSET 7
+   ` + "`" + `--SCAN customers
+`,
+		},
+		{
+			name: "merge past inner limit",
+			plan: limit(10, 5, limit(3, 0, scan("customers"))),
+			before: "`" + `--LIMIT 10 OFFSET 5
+   ` + "`" + `--LIMIT 3
+      ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--LIMIT 0 OFFSET 5
+   ` + "`" + `--SCAN customers
+`,
+		},
+		{
+			name: "into left join",
+			plan: limit(10, 5, join(planner.LeftJoin, eq(orderCustomer, customerID), scan("customers"), scan("orders"))),
+			before: "`" + `--LIMIT 10 OFFSET 5
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--LIMIT 10 OFFSET 5
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--LIMIT 15
+      |  ` + "`" + `--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "into left join with limit",
+			plan: limit(10, 0, join(planner.LeftJoin, eq(orderCustomer, customerID), limit(20, 0, scan("customers")), scan("orders"))),
+			before: "`" + `--LIMIT 10
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--LIMIT 20
+      |  ` + "`" + `--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--LIMIT 10
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--LIMIT 10
+      |  ` + "`" + `--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "inner join",
+			plan: limit(10, 0, join(planner.InnerJoin, eq(orderCustomer, customerID), scan("customers"), scan("orders"))),
+			before: "`" + `--LIMIT 10
+   ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--LIMIT 10
+   ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "no limit",
+			plan: limit(-1, 0, scan("customers")),
+			before: "`" + `--LIMIT -1
+   ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--SCAN customers
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt.run(t, apply(LimitPushdown{}))
+	}
+}
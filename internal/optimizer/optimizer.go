@@ -0,0 +1,78 @@
+package optimizer
+
+import "github.com/tomarrell/lbadd/internal/planner"
+
+// Rule is a rewrite rule of logical plans.
+type Rule interface {
+	// Name returns the name of the rule.
+	Name() string
+	// Apply rewrites the given node into an equivalent node, and reports
+	// whether it changed anything. Apply must not modify the given node or
+	// any node below it.
+	Apply(n *planner.Node) (*planner.Node, bool)
+}
+
+// MaxPasses is the maximum amount of passes, that Optimize makes over a plan.
+// It guards against rules, that keep undoing each other.
+const MaxPasses = 100
+
+// Rules are the rules that Optimize applies, if no rules are given, in the
+// order in which they are applied to a node.
+var Rules = []Rule{
+	ConstantFolding{},
+	Decorrelation{},
+	OuterJoinSimplification{},
+	PredicatePushdown{},
+	ProjectionPruning{},
+	LimitPushdown{},
+}
+
+// Optimize rewrites the given plan with the given rules, or with Rules if no
+// rules are given. In every pass, the rules are applied to every node, from
+// the root to the leaves. Passes are repeated, until no rule changes the plan
+// anymore, which is the fixed point of the rules, or MaxPasses were made.
+func Optimize(n *planner.Node, rules ...Rule) *planner.Node {
+	if len(rules) == 0 {
+		rules = Rules
+	}
+	for pass := 0; pass < MaxPasses; pass++ {
+		var changed bool
+		if n, changed = rewrite(n, rules); !changed {
+			break
+		}
+	}
+	return n
+}
+
+// rewrite applies the given rules to the given node, and then to its
+// children.
+func rewrite(n *planner.Node, rules []Rule) (*planner.Node, bool) {
+	changed := false
+	for _, rule := range rules {
+		if rewritten, ok := rule.Apply(n); ok {
+			n, changed = rewritten, true
+		}
+	}
+	// the node may be shared with the given plan, even if a rule rewrote
+	// it, so its children are replaced in a copy
+	cloned := false
+	for i, child := range n.Children {
+		rewritten, ok := rewrite(child, rules)
+		if !ok {
+			continue
+		}
+		if !cloned {
+			n, cloned = clone(n), true
+		}
+		n.Children[i] = rewritten
+		changed = true
+	}
+	return n, changed
+}
+
+// clone returns a copy of the given node, with its own slice of children.
+func clone(n *planner.Node) *planner.Node {
+	c := *n
+	c.Children = append([]*planner.Node(nil), n.Children...)
+	return &c
+}
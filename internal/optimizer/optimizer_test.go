@@ -0,0 +1,168 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// golden is a test of a rewrite. Before and after are the plan before and
+// after the rewrite, as rendered by (*planner.Node).String, without the
+// header.
+type golden struct {
+	name   string
+	plan   *planner.Node
+	before string
+	after  string
+}
+
+// run checks, that the given plan is rendered as before, and rewritten by
+// the given function into a plan, that is rendered as after. It also checks,
+// that the given plan was not modified.
+func (g golden) run(t *testing.T, rewrite func(*planner.Node) *planner.Node) {
+	t.Run(g.name, func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal("QUERY PLAN\n"+g.before, g.plan.String())
+		assert.Equal("QUERY PLAN\n"+g.after, rewrite(g.plan).String())
+		assert.Equal("QUERY PLAN\n"+g.before, g.plan.String(), "the rewritten plan was modified")
+	})
+}
+
+// apply returns a function, that applies the given rule with Optimize.
+func apply(rule Rule) func(*planner.Node) *planner.Node {
+	return func(n *planner.Node) *planner.Node {
+		return Optimize(n, rule)
+	}
+}
+
+func scan(relation string) *planner.Node {
+	return &planner.Node{Op: planner.FullScan, Relation: relation}
+}
+
+func selection(predicate *planner.Expr, child *planner.Node) *planner.Node {
+	return &planner.Node{Op: planner.Selection, Predicate: predicate, Children: []*planner.Node{child}}
+}
+
+func projection(child *planner.Node, exprs ...*planner.Expr) *planner.Node {
+	return &planner.Node{Op: planner.Projection, Projections: exprs, Children: []*planner.Node{child}}
+}
+
+func join(joinType planner.JoinType, predicate *planner.Expr, left, right *planner.Node) *planner.Node {
+	return &planner.Node{Op: planner.Join, JoinType: joinType, Predicate: predicate, Children: []*planner.Node{left, right}}
+}
+
+func limit(count, offset int64, child *planner.Node) *planner.Node {
+	return &planner.Node{Op: planner.Limit, Count: count, Offset: offset, Children: []*planner.Node{child}}
+}
+
+func eq(left, right *planner.Expr) *planner.Expr {
+	return planner.NewExpr(planner.EqExpr, left, right)
+}
+
+func gt(left, right *planner.Expr) *planner.Expr {
+	return planner.NewExpr(planner.GtExpr, left, right)
+}
+
+func and(args ...*planner.Expr) *planner.Expr {
+	return planner.NewExpr(planner.AndExpr, args...)
+}
+
+func integer(v int64) *planner.Expr {
+	return planner.Const(v)
+}
+
+var (
+	customerID      = planner.Ref("customers", "id")
+	customerName    = planner.Ref("customers", "name")
+	customerCountry = planner.Ref("customers", "country")
+	orderCustomer   = planner.Ref("orders", "customer")
+	orderTotal      = planner.Ref("orders", "total")
+)
+
+// countingRule counts how often it is applied, and never changes anything.
+type countingRule struct {
+	applied *int
+}
+
+func (countingRule) Name() string { return "counting" }
+
+func (r countingRule) Apply(n *planner.Node) (*planner.Node, bool) {
+	*r.applied++
+	return n, false
+}
+
+// flipRule swaps the children of every join, so it never reaches a fixed
+// point.
+type flipRule struct{}
+
+func (flipRule) Name() string { return "flip" }
+
+func (flipRule) Apply(n *planner.Node) (*planner.Node, bool) {
+	if n.Op != planner.Join {
+		return n, false
+	}
+	n = clone(n)
+	n.Children[0], n.Children[1] = n.Children[1], n.Children[0]
+	return n, true
+}
+
+func TestOptimize_FixedPoint(t *testing.T) {
+	assert := assert.New(t)
+
+	// a plan, that no rule changes, is visited once
+	applied := 0
+	plan := join(planner.InnerJoin, nil, scan("customers"), scan("orders"))
+	assert.Same(plan, Optimize(plan, countingRule{&applied}))
+	assert.Equal(3, applied)
+
+	// rules, that keep undoing each other, stop after MaxPasses
+	applied = 0
+	result := Optimize(plan, flipRule{}, countingRule{&applied})
+	assert.Equal(3*MaxPasses, applied)
+	assert.Equal("customers", result.Children[0].Relation)
+	assert.Equal("customers", plan.Children[0].Relation)
+}
+
+func TestOptimize(t *testing.T) {
+	// SELECT customers.name
+	// FROM customers LEFT JOIN orders ON orders.customer = customers.id
+	// WHERE orders.total > 10 * 10 AND 1 = 1
+	//   AND EXISTS (SELECT * FROM countries WHERE countries.code = customers.country)
+	// LIMIT 5
+	countries := selection(eq(planner.Ref("countries", "code"), customerCountry), scan("countries"))
+	golden{
+		name: "all rules",
+		plan: limit(5, 0, projection(
+			selection(
+				and(
+					gt(orderTotal, planner.NewExpr(planner.MulExpr, integer(10), integer(10))),
+					eq(integer(1), integer(1)),
+					planner.Exists(projection(countries, planner.Const(int64(1)))),
+				),
+				join(planner.LeftJoin, eq(orderCustomer, customerID), scan("customers"), scan("orders")),
+			),
+			customerName,
+		)),
+		before: "`" + `--LIMIT 5
+   ` + "`" + `--PROJECT customers.name
+      ` + "`" + `--FILTER orders.total > 10 * 10 AND 1 = 1 AND EXISTS (SUBQUERY)
+         |--LEFT JOIN ON orders.customer = customers.id
+         |  |--SCAN customers
+         |  ` + "`" + `--SCAN orders
+         ` + "`" + `--SUBQUERY
+            ` + "`" + `--PROJECT 1
+               ` + "`" + `--FILTER countries.code = customers.country
+                  ` + "`" + `--SCAN countries
+`,
+		after: "`" + `--PROJECT customers.name
+   ` + "`" + `--LIMIT 5
+      ` + "`" + `--SEMI JOIN ON countries.code = customers.country
+         |--INNER JOIN ON orders.customer = customers.id
+         |  |--SCAN customers (country, id, name)
+         |  ` + "`" + `--FILTER orders.total > 100
+         |     ` + "`" + `--SCAN orders (customer, total)
+         ` + "`" + `--SCAN countries (code)
+`,
+	}.run(t, func(n *planner.Node) *planner.Node { return Optimize(n) })
+}
@@ -0,0 +1,74 @@
+package optimizer
+
+import "github.com/tomarrell/lbadd/internal/planner"
+
+// OuterJoinSimplification turns a LeftJoin into an InnerJoin, if the
+// predicate of a Selection above it is not true for the rows of the first
+// child without a match. In these rows, all columns of the second child are
+// NULL, so a predicate rejects them, if it cannot be true when a column of
+// the second child is NULL, like a comparison of such a column.
+type OuterJoinSimplification struct{}
+
+// Name returns the name of the rule.
+func (OuterJoinSimplification) Name() string { return "outer join simplification" }
+
+// Apply simplifies the LeftJoin below the given Selection.
+func (OuterJoinSimplification) Apply(n *planner.Node) (*planner.Node, bool) {
+	if n.Op != planner.Selection {
+		return n, false
+	}
+	join := n.Children[0]
+	if join.Op != planner.Join || join.JoinType != planner.LeftJoin {
+		return n, false
+	}
+	if !rejectsNull(n.Predicate, relations(join.Children[1])) {
+		return n, false
+	}
+
+	join = clone(join)
+	join.JoinType = planner.InnerJoin
+	n = clone(n)
+	n.Children[0] = join
+	return n, true
+}
+
+// rejectsNull reports whether the given predicate is not true, if all columns
+// of the given relations are NULL.
+func rejectsNull(e *planner.Expr, rels set) bool {
+	switch e.Op {
+	case planner.EqExpr, planner.NeExpr, planner.LtExpr, planner.LeExpr, planner.GtExpr, planner.GeExpr:
+		return strict(e.Args[0], rels) || strict(e.Args[1], rels)
+	case planner.AndExpr:
+		for _, arg := range e.Args {
+			if rejectsNull(arg, rels) {
+				return true
+			}
+		}
+		return false
+	case planner.OrExpr:
+		for _, arg := range e.Args {
+			if !rejectsNull(arg, rels) {
+				return false
+			}
+		}
+		return true
+	case planner.NotExpr:
+		// NOT x IS NULL
+		arg := e.Args[0]
+		return arg.Op == planner.IsNullExpr && strict(arg.Args[0], rels)
+	}
+	return strict(e, rels)
+}
+
+// strict reports whether the given expression is NULL, if all columns of the
+// given relations are NULL.
+func strict(e *planner.Expr, rels set) bool {
+	switch e.Op {
+	case planner.ColumnExpr:
+		return rels[e.Column.Relation]
+	case planner.AddExpr, planner.SubExpr, planner.MulExpr, planner.DivExpr,
+		planner.EqExpr, planner.NeExpr, planner.LtExpr, planner.LeExpr, planner.GtExpr, planner.GeExpr:
+		return strict(e.Args[0], rels) || strict(e.Args[1], rels)
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestOuterJoinSimplification(t *testing.T) {
+	leftJoin := func() *planner.Node {
+		return join(planner.LeftJoin, eq(orderCustomer, customerID), scan("customers"), scan("orders"))
+	}
+	isNull := func(e *planner.Expr) *planner.Expr {
+		return planner.NewExpr(planner.IsNullExpr, e)
+	}
+
+	tests := []golden{
+		{
+			name: "comparison",
+			plan: selection(gt(planner.NewExpr(planner.AddExpr, orderTotal, integer(1)), integer(10)), leftJoin()),
+			before: "`" + `--FILTER orders.total + 1 > 10
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total + 1 > 10
+   ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "not null",
+			plan: selection(planner.NewExpr(planner.NotExpr, isNull(orderTotal)), leftJoin()),
+			before: "`" + `--FILTER NOT orders.total IS NULL
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER NOT orders.total IS NULL
+   ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "or",
+			plan: selection(planner.NewExpr(planner.OrExpr, gt(orderTotal, integer(10)), eq(orderCustomer, integer(1))), leftJoin()),
+			before: "`" + `--FILTER orders.total > 10 OR orders.customer = 1
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total > 10 OR orders.customer = 1
+   ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "is null",
+			plan: selection(isNull(orderTotal), leftJoin()),
+			before: "`" + `--FILTER orders.total IS NULL
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total IS NULL
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "or with first child",
+			plan: selection(planner.NewExpr(planner.OrExpr, gt(orderTotal, integer(10)), eq(customerCountry, planner.Const("NZ"))), leftJoin()),
+			before: "`" + `--FILTER orders.total > 10 OR customers.country = 'NZ'
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total > 10 OR customers.country = 'NZ'
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "first child",
+			plan: selection(eq(customerCountry, planner.Const("NZ")), leftJoin()),
+			before: "`" + `--FILTER customers.country = 'NZ'
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER customers.country = 'NZ'
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt.run(t, apply(OuterJoinSimplification{}))
+	}
+}
@@ -0,0 +1,117 @@
+package optimizer
+
+import (
+	"sort"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+// ProjectionPruning restricts the scans below a Projection to the columns,
+// that the Projection and the predicates between it and the scans need.
+// Scans, that read all columns, are given the sorted list of the needed
+// columns of their relation. Projections of all columns with * are not
+// pruned, and neither are scans, as long as a needed column is not qualified
+// with its relation.
+type ProjectionPruning struct{}
+
+// Name returns the name of the rule.
+func (ProjectionPruning) Name() string { return "projection pruning" }
+
+// Apply prunes the columns of the scans below the given Projection.
+func (ProjectionPruning) Apply(n *planner.Node) (*planner.Node, bool) {
+	if n.Op != planner.Projection || len(n.Children) == 0 {
+		return n, false
+	}
+	required := make(map[planner.Column]bool)
+	for _, e := range n.Projections {
+		if e.Op == planner.StarExpr {
+			return n, false
+		}
+		columns(e, func(c planner.Column) { required[c] = true })
+	}
+
+	child, changed := prune(n.Children[0], required)
+	if !changed {
+		return n, false
+	}
+	n = clone(n)
+	n.Children[0] = child
+	return n, true
+}
+
+// prune restricts the scans below the given node to the given columns, and
+// the columns that the predicates below the node need.
+func prune(n *planner.Node, required map[planner.Column]bool) (*planner.Node, bool) {
+	switch n.Op {
+	case planner.FullScan, planner.IndexScan:
+		return pruneScan(n, required)
+	case planner.Selection, planner.Join, planner.Sort, planner.Aggregate:
+		// the columns of the rows, that an Aggregate returns for a group,
+		// are the ones of the last row of the group, so the columns above
+		// it are still needed
+		exprs := n.Exprs()
+		if len(exprs) > 0 {
+			needed := make(map[planner.Column]bool, len(required))
+			for c := range required {
+				needed[c] = true
+			}
+			for _, e := range exprs {
+				columns(e, func(c planner.Column) { needed[c] = true })
+			}
+			required = needed
+		}
+	case planner.Limit, planner.Distinct:
+	default:
+		// the columns of a nested Projection are pruned, when the rule is
+		// applied to it, and physical operations are not pruned at all
+		return n, false
+	}
+
+	cloned := false
+	for i, child := range n.Children {
+		pruned, ok := prune(child, required)
+		if !ok {
+			continue
+		}
+		if !cloned {
+			n, cloned = clone(n), true
+		}
+		n.Children[i] = pruned
+	}
+	return n, cloned
+}
+
+// pruneScan restricts the given scan to the given columns of its relation.
+func pruneScan(n *planner.Node, required map[planner.Column]bool) (*planner.Node, bool) {
+	for c := range required {
+		if c.Relation == "" {
+			// the relation of the column is not known
+			return n, false
+		}
+	}
+	var pruned []string
+	if n.Columns == nil {
+		pruned = []string{}
+		for c := range required {
+			if c.Relation == n.Relation {
+				pruned = append(pruned, c.Name)
+			}
+		}
+		sort.Strings(pruned)
+	} else {
+		for _, name := range n.Columns {
+			if required[planner.Column{Relation: n.Relation, Name: name}] {
+				pruned = append(pruned, name)
+			}
+		}
+		if len(pruned) == len(n.Columns) {
+			return n, false
+		}
+		if pruned == nil {
+			pruned = []string{}
+		}
+	}
+	n = clone(n)
+	n.Columns = pruned
+	return n, true
+}
@@ -0,0 +1,131 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestProjectionPruning(t *testing.T) {
+	tests := []golden{
+		{
+			name: "scans",
+			plan: projection(
+				selection(gt(orderTotal, integer(10)), join(planner.InnerJoin, eq(orderCustomer, customerID), scan("customers"), scan("orders"))),
+				customerName,
+			),
+			before: "`" + `--PROJECT customers.name
+   ` + "`" + `--FILTER orders.total > 10
+      ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+         |--SCAN customers
+         ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--PROJECT customers.name
+   ` + "`" + `--FILTER orders.total > 10
+      ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+         |--SCAN customers (id, name)
+         ` + "`" + `--SCAN orders (customer, total)
+`,
+		},
+		{
+			name: "pruned scan",
+			plan: projection(
+				limit(10, 0, &planner.Node{Op: planner.IndexScan, Relation: "customers", Index: "customers_country", Columns: []string{"name", "id", "country"}}),
+				customerName, customerID,
+			),
+			before: "`" + `--PROJECT customers.name, customers.id
+   ` + "`" + `--LIMIT 10
+      ` + "`" + `--SEARCH customers USING INDEX customers_country (name, id, country)
+`,
+			after: "`" + `--PROJECT customers.name, customers.id
+   ` + "`" + `--LIMIT 10
+      ` + "`" + `--SEARCH customers USING INDEX customers_country (name, id)
+`,
+		},
+		{
+			name: "no columns",
+			plan: projection(scan("customers"), integer(1)),
+			before: "`" + `--PROJECT 1
+   ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--PROJECT 1
+   ` + "`" + `--SCAN customers ()
+`,
+		},
+		{
+			name: "nested projection",
+			plan: projection(projection(scan("customers"), customerName, customerID), customerName),
+			before: "`" + `--PROJECT customers.name
+   ` + "`" + `--PROJECT customers.name, customers.id
+      ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--PROJECT customers.name
+   ` + "`" + `--PROJECT customers.name, customers.id
+      ` + "`" + `--SCAN customers (id, name)
+`,
+		},
+		{
+			name: "correlated subquery",
+			plan: projection(
+				selection(planner.Exists(selection(eq(orderCustomer, customerID), scan("orders"))), scan("customers")),
+				customerName,
+			),
+			before: "`" + `--PROJECT customers.name
+   ` + "`" + `--FILTER EXISTS (SUBQUERY)
+      |--SCAN customers
+      ` + "`" + `--SUBQUERY
+         ` + "`" + `--FILTER orders.customer = customers.id
+            ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--PROJECT customers.name
+   ` + "`" + `--FILTER EXISTS (SUBQUERY)
+      |--SCAN customers (id, name)
+      ` + "`" + `--SUBQUERY
+         ` + "`" + `--FILTER orders.customer = customers.id
+            ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "star",
+			plan: projection(scan("customers"), &planner.Expr{Op: planner.StarExpr}),
+			before: "`" + `--PROJECT *
+   ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--PROJECT *
+   ` + "`" + `--SCAN customers
+`,
+		},
+		{
+			name: "unqualified column",
+			plan: projection(scan("customers"), planner.Ref("", "name")),
+			before: "`" + `--PROJECT name
+   ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--PROJECT name
+   ` + "`" + `--SCAN customers
+`,
+		},
+		{
+			name: "aggregate and sort",
+			plan: projection(
+				&planner.Node{Op: planner.Sort, Order: []planner.OrderTerm{{Expr: customerName, NullsFirst: true}}, Children: []*planner.Node{
+					{Op: planner.Aggregate, GroupBy: []*planner.Expr{customerCountry}, Projections: []*planner.Expr{planner.Func("max", customerID)}, Children: []*planner.Node{scan("customers")}},
+				}},
+				customerCountry, planner.Func("max", customerID),
+			),
+			before: "`" + `--PROJECT customers.country, max(customers.id)
+   ` + "`" + `--ORDER BY customers.name
+      ` + "`" + `--AGGREGATE max(customers.id) GROUP BY customers.country
+         ` + "`" + `--SCAN customers
+`,
+			after: "`" + `--PROJECT customers.country, max(customers.id)
+   ` + "`" + `--ORDER BY customers.name
+      ` + "`" + `--AGGREGATE max(customers.id) GROUP BY customers.country
+         ` + "`" + `--SCAN customers (country, id, name)
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt.run(t, apply(ProjectionPruning{}))
+	}
+}
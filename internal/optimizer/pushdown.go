@@ -0,0 +1,128 @@
+package optimizer
+
+import "github.com/tomarrell/lbadd/internal/planner"
+
+// PredicatePushdown moves predicates as close to the scans as possible, so
+// that rows are filtered before they are joined or projected. Selections are
+// merged, moved below Projections, and the conjuncts of their predicates are
+// moved into the children of a Join, or into its predicate. The conjuncts of
+// the predicate of a Join, that only reference one of its children, are
+// moved into that child, if that does not change the result of the join.
+type PredicatePushdown struct{}
+
+// Name returns the name of the rule.
+func (PredicatePushdown) Name() string { return "predicate pushdown" }
+
+// Apply pushes the predicate of the given Selection or Join down.
+func (PredicatePushdown) Apply(n *planner.Node) (*planner.Node, bool) {
+	switch n.Op {
+	case planner.Selection:
+		child := n.Children[0]
+		switch child.Op {
+		case planner.Selection:
+			return filter(child.Children[0], []*planner.Expr{child.Predicate, n.Predicate}), true
+		case planner.Projection:
+			if !passes(child, n.Predicate) {
+				return n, false
+			}
+			child = clone(child)
+			child.Children[0] = filter(child.Children[0], []*planner.Expr{n.Predicate})
+			return child, true
+		case planner.Join:
+			return pushIntoJoin(n, child)
+		}
+	case planner.Join:
+		return pushFromJoin(n)
+	}
+	return n, false
+}
+
+// passes reports whether all columns, that the given predicate references,
+// are passed on unchanged by the given Projection.
+func passes(projection *planner.Node, predicate *planner.Expr) bool {
+	passed := make(map[planner.Column]bool)
+	for _, e := range projection.Projections {
+		if e.Op == planner.ColumnExpr {
+			passed[e.Column] = true
+		}
+	}
+	ok := true
+	columns(predicate, func(c planner.Column) {
+		ok = ok && passed[c]
+	})
+	return ok
+}
+
+// pushIntoJoin moves the conjuncts of the predicate of the given Selection
+// into the children of the given Join, which is the child of the Selection.
+// Conjuncts, that reference both children, are moved into the predicate of
+// an InnerJoin.
+func pushIntoJoin(n, join *planner.Node) (*planner.Node, bool) {
+	left, right := relations(join.Children[0]), relations(join.Children[1])
+	both := union(left, right)
+	inner := join.JoinType == planner.InnerJoin
+
+	var toLeft, toRight, toJoin, remaining []*planner.Expr
+	for _, c := range conjuncts(n.Predicate) {
+		refs := references(c)
+		switch {
+		case len(refs) == 0:
+			remaining = append(remaining, c)
+		case left.contains(refs):
+			toLeft = append(toLeft, c)
+		case inner && right.contains(refs):
+			toRight = append(toRight, c)
+		case inner && both.contains(refs):
+			toJoin = append(toJoin, c)
+		default:
+			remaining = append(remaining, c)
+		}
+	}
+	if len(remaining) == len(conjuncts(n.Predicate)) {
+		return n, false
+	}
+
+	join = clone(join)
+	join.Children[0] = filter(join.Children[0], toLeft)
+	join.Children[1] = filter(join.Children[1], toRight)
+	if len(toJoin) > 0 {
+		join.Predicate = conjoin(append([]*planner.Expr{join.Predicate}, toJoin...)...)
+	}
+	return filter(join, remaining), true
+}
+
+// pushFromJoin moves the conjuncts of the predicate of the given Join, that
+// only reference one of its children, into that child. Conjuncts, that only
+// reference the first child, are kept for outer and anti joins, since rows
+// of the first child, for which they are false, are still returned.
+func pushFromJoin(join *planner.Node) (*planner.Node, bool) {
+	if join.Predicate == nil {
+		return join, false
+	}
+	left, right := relations(join.Children[0]), relations(join.Children[1])
+	pushLeft := join.JoinType == planner.InnerJoin || join.JoinType == planner.SemiJoin
+
+	var toLeft, toRight, remaining []*planner.Expr
+	for _, c := range conjuncts(join.Predicate) {
+		refs := references(c)
+		switch {
+		case len(refs) == 0:
+			remaining = append(remaining, c)
+		case pushLeft && left.contains(refs):
+			toLeft = append(toLeft, c)
+		case right.contains(refs):
+			toRight = append(toRight, c)
+		default:
+			remaining = append(remaining, c)
+		}
+	}
+	if len(toLeft) == 0 && len(toRight) == 0 {
+		return join, false
+	}
+
+	join = clone(join)
+	join.Predicate = conjoin(remaining...)
+	join.Children[0] = filter(join.Children[0], toLeft)
+	join.Children[1] = filter(join.Children[1], toRight)
+	return join, true
+}
@@ -0,0 +1,168 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/tomarrell/lbadd/internal/planner"
+)
+
+func TestPredicatePushdown(t *testing.T) {
+	customersOrders := func(joinType planner.JoinType, predicate *planner.Expr) *planner.Node {
+		return join(joinType, predicate, scan("customers"), scan("orders"))
+	}
+
+	tests := []golden{
+		{
+			name: "into inner join",
+			plan: selection(
+				and(eq(orderCustomer, customerID), gt(orderTotal, integer(10)), eq(customerCountry, planner.Const("NZ"))),
+				customersOrders(planner.InnerJoin, nil),
+			),
+			before: "`" + `--FILTER orders.customer = customers.id AND orders.total > 10 AND customers.country = 'NZ'
+   ` + "`" + `--INNER JOIN
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--INNER JOIN ON orders.customer = customers.id
+   |--FILTER customers.country = 'NZ'
+   |  ` + "`" + `--SCAN customers
+   ` + "`" + `--FILTER orders.total > 10
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "into left join",
+			plan: selection(
+				and(gt(orderTotal, integer(10)), eq(customerCountry, planner.Const("NZ"))),
+				customersOrders(planner.LeftJoin, eq(orderCustomer, customerID)),
+			),
+			before: "`" + `--FILTER orders.total > 10 AND customers.country = 'NZ'
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--LEFT JOIN ON orders.customer = customers.id
+      |--FILTER customers.country = 'NZ'
+      |  ` + "`" + `--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "from inner join",
+			plan: customersOrders(planner.InnerJoin, and(eq(orderCustomer, customerID), gt(orderTotal, integer(10)), eq(customerCountry, planner.Const("NZ")))),
+			before: "`" + `--INNER JOIN ON orders.customer = customers.id AND orders.total > 10 AND customers.country = 'NZ'
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--INNER JOIN ON orders.customer = customers.id
+   |--FILTER customers.country = 'NZ'
+   |  ` + "`" + `--SCAN customers
+   ` + "`" + `--FILTER orders.total > 10
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "from left join",
+			plan: customersOrders(planner.LeftJoin, and(eq(orderCustomer, customerID), gt(orderTotal, integer(10)), eq(customerCountry, planner.Const("NZ")))),
+			before: "`" + `--LEFT JOIN ON orders.customer = customers.id AND orders.total > 10 AND customers.country = 'NZ'
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--LEFT JOIN ON orders.customer = customers.id AND customers.country = 'NZ'
+   |--SCAN customers
+   ` + "`" + `--FILTER orders.total > 10
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "from anti join",
+			plan: customersOrders(planner.AntiJoin, and(eq(orderCustomer, customerID), gt(orderTotal, integer(10)), eq(customerCountry, planner.Const("NZ")))),
+			before: "`" + `--ANTI JOIN ON orders.customer = customers.id AND orders.total > 10 AND customers.country = 'NZ'
+   |--SCAN customers
+   ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--ANTI JOIN ON orders.customer = customers.id AND customers.country = 'NZ'
+   |--SCAN customers
+   ` + "`" + `--FILTER orders.total > 10
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "merge selections",
+			plan: selection(gt(orderTotal, integer(10)), selection(eq(orderCustomer, integer(1)), scan("orders"))),
+			before: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--FILTER orders.customer = 1
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.customer = 1 AND orders.total > 10
+   ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "below projection",
+			plan: selection(gt(orderTotal, integer(10)), projection(scan("orders"), orderCustomer, orderTotal)),
+			before: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--PROJECT orders.customer, orders.total
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--PROJECT orders.customer, orders.total
+   ` + "`" + `--FILTER orders.total > 10
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "computed column",
+			plan: selection(gt(orderTotal, integer(10)), projection(scan("orders"), planner.NewExpr(planner.MulExpr, orderTotal, integer(2)))),
+			before: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--PROJECT orders.total * 2
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER orders.total > 10
+   ` + "`" + `--PROJECT orders.total * 2
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "constant",
+			plan: selection(and(planner.Const(false), eq(orderCustomer, customerID)), customersOrders(planner.InnerJoin, nil)),
+			before: "`" + `--FILTER FALSE AND orders.customer = customers.id
+   ` + "`" + `--INNER JOIN
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--FILTER FALSE
+   ` + "`" + `--INNER JOIN ON orders.customer = customers.id
+      |--SCAN customers
+      ` + "`" + `--SCAN orders
+`,
+		},
+		{
+			name: "into subquery in from",
+			plan: selection(
+				and(eq(planner.Ref("big", "customer"), customerID), gt(planner.Ref("big", "total"), integer(10))),
+				join(planner.InnerJoin, nil, scan("customers"), &planner.Node{
+					Op:          planner.Projection,
+					Relation:    "big",
+					Projections: []*planner.Expr{{Op: planner.StarExpr}},
+					Children:    []*planner.Node{scan("orders")},
+				}),
+			),
+			before: "`" + `--FILTER big.customer = customers.id AND big.total > 10
+   ` + "`" + `--INNER JOIN
+      |--SCAN customers
+      ` + "`" + `--PROJECT * AS big
+         ` + "`" + `--SCAN orders
+`,
+			after: "`" + `--INNER JOIN ON big.customer = customers.id
+   |--SCAN customers
+   ` + "`" + `--FILTER big.total > 10
+      ` + "`" + `--PROJECT * AS big
+         ` + "`" + `--SCAN orders
+`,
+		},
+	}
+	for _, tt := range tests {
+		tt.run(t, apply(PredicatePushdown{}))
+	}
+}
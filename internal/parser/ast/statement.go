@@ -11,6 +11,7 @@ type (
 		Explain token.Token
 		Query   token.Token
 		Plan    token.Token
+		Analyze token.Token
 
 		AlterTableStmt         *AlterTableStmt
 		AnalyzeStmt            *AnalyzeStmt
@@ -30,6 +31,7 @@ type (
 		DropTriggerStmt        *DropTriggerStmt
 		DropViewStmt           *DropViewStmt
 		InsertStmt             *InsertStmt
+		PragmaStmt             *PragmaStmt
 		ReindexStmt            *ReindexStmt
 		ReleaseStmt            *ReleaseStmt
 		RollbackStmt           *RollbackStmt
@@ -55,6 +57,7 @@ type (
 		NewColumnName token.Token
 		Add           token.Token
 		ColumnDef     *ColumnDef
+		Drop          token.Token
 	}
 
 	// AnalyzeStmt as in the SQLite grammar.
@@ -161,13 +164,19 @@ type (
 		When        token.Token
 		Expr        *Expr
 		Begin       token.Token
-		UpdateStmt  []*UpdateStmt
-		InsertStmt  []*InsertStmt
-		DeleteStmt  []*DeleteStmt
-		SelectStmt  []*SelectStmt
+		TriggerStmt []*TriggerStmt
 		End         token.Token
 	}
 
+	// TriggerStmt is a statement of the body of a trigger, which is exactly
+	// one of an UPDATE, INSERT, DELETE or SELECT statement.
+	TriggerStmt struct {
+		UpdateStmt *UpdateStmt
+		InsertStmt *InsertStmt
+		DeleteStmt *DeleteStmt
+		SelectStmt *SelectStmt
+	}
+
 	// CreateViewStmt as in the SQLite grammar.
 	CreateViewStmt struct {
 		Create     token.Token
@@ -392,7 +401,9 @@ type (
 		NamedWindow              []*NamedWindow
 		Values                   token.Token
 		ParenthesizedExpressions []*ParenthesizedExpressions
-		CompoundOperator         *CompoundOperator
+		// CompoundOperator combines this SELECT with the SELECTs before it.
+		// It is nil for the first SELECT of a statement.
+		CompoundOperator *CompoundOperator
 	}
 
 	// UpdateStmt as in the SQLite grammar.
@@ -451,6 +462,24 @@ type (
 		Expr2         *Expr
 	}
 
+	// PragmaStmt as in the SQLite grammar.
+	PragmaStmt struct {
+		Pragma      token.Token
+		SchemaName  token.Token
+		Period      token.Token
+		PragmaName  token.Token
+		Equal       token.Token
+		LeftParen   token.Token
+		PragmaValue *PragmaValue
+		RightParen  token.Token
+	}
+
+	// PragmaValue as in the SQLite grammar. A string literal is a Name.
+	PragmaValue struct {
+		SignedNumber *SignedNumber
+		Name         token.Token
+	}
+
 	// VacuumStmt as in the SQLite grammar.
 	VacuumStmt struct {
 		Vacuum     token.Token
@@ -506,6 +535,7 @@ type (
 		OverClause     *OverClause
 		Cast           token.Token
 		As             token.Token
+		TypeName       *TypeName
 		Collate        token.Token
 		CollationName  token.Token
 		Not            token.Token
@@ -520,19 +550,27 @@ type (
 		Null           token.Token
 		Is             token.Token
 		Between        token.Token
+		And            token.Token
 		In             token.Token
 		SelectStmt     *SelectStmt
 		TableFunction  token.Token
 		Exists         token.Token
 		Case           token.Token
-		When           token.Token
-		Then           token.Token
+		WhenClause     []*WhenClause
 		Else           token.Token
 		Expr4          *Expr
 		End            token.Token
 		RaiseFunction  *RaiseFunction
 	}
 
+	// WhenClause is a WHEN ... THEN ... clause of a CASE expression.
+	WhenClause struct {
+		When  token.Token
+		Expr1 *Expr
+		Then  token.Token
+		Expr2 *Expr
+	}
+
 	// FilterClause as in the SQLite grammar.
 	FilterClause struct {
 		Filter     token.Token
@@ -665,23 +703,31 @@ type (
 		LeftParen    token.Token
 		ColumnName   []token.Token
 		RightParen   token.Token
-		On           token.Token
-		Delete       token.Token
-		Update       token.Token
-		Set          token.Token
-		Null         token.Token
-		Default      token.Token
-		Cascade      token.Token
-		Restrict     token.Token
-		No           token.Token
-		Action       token.Token
-		Match        token.Token
-		Name         token.Token
-		Not          token.Token
-		Deferrable   token.Token
-		Initially    token.Token
-		Deferred     token.Token
-		Immediate    token.Token
+		// ForeignKeyClauseCore holds the ON DELETE, ON UPDATE and MATCH
+		// clauses, in the order in which they appear.
+		ForeignKeyClauseCore []*ForeignKeyClauseCore
+		Not                  token.Token
+		Deferrable           token.Token
+		Initially            token.Token
+		Deferred             token.Token
+		Immediate            token.Token
+	}
+
+	// ForeignKeyClauseCore is an ON DELETE, ON UPDATE or MATCH clause of a
+	// foreign key clause.
+	ForeignKeyClauseCore struct {
+		On       token.Token
+		Delete   token.Token
+		Update   token.Token
+		Set      token.Token
+		Null     token.Token
+		Default  token.Token
+		Cascade  token.Token
+		Restrict token.Token
+		No       token.Token
+		Action   token.Token
+		Match    token.Token
+		Name     token.Token
 	}
 
 	// CommonTableExpression as in the SQLite grammar.
@@ -724,7 +770,9 @@ type (
 		SelectStmt        *SelectStmt
 	}
 
-	// JoinClause as in the SQLite grammar.
+	// JoinClause as in the SQLite grammar. A join of more than two tables
+	// is nested to the left, so that the TableOrSubquery of the outermost
+	// clause holds the join of all tables but the last one.
 	JoinClause struct {
 		TableOrSubquery *TableOrSubquery
 		JoinClausePart  *JoinClausePart
@@ -783,7 +831,7 @@ type (
 		Expr             *Expr
 		RightParen       token.Token
 		Default          token.Token
-		SignedNumber     token.Token
+		SignedNumber     *SignedNumber
 		LiteralValue     token.Token
 		Collate          token.Token
 		CollationName    token.Token
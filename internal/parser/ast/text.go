@@ -0,0 +1,68 @@
+package ast
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+var tokenType = reflect.TypeOf((*token.Token)(nil)).Elem()
+
+// Tokens returns the tokens of the given node of an AST, like an *Expr or a
+// *SelectStmt, in the order in which they appear in the input.
+func Tokens(node interface{}) []token.Token {
+	var tokens []token.Token
+	collect(reflect.ValueOf(node), &tokens)
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return tokens[i].Offset() < tokens[j].Offset()
+	})
+	return tokens
+}
+
+func collect(v reflect.Value, tokens *[]token.Token) {
+	if !v.IsValid() {
+		return
+	}
+	if v.Type() == tokenType {
+		if !v.IsNil() {
+			*tokens = append(*tokens, v.Interface().(token.Token))
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collect(v.Elem(), tokens)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			collect(v.Field(i), tokens)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			collect(v.Index(i), tokens)
+		}
+	}
+}
+
+// Text renders the SQL text of the given node of an AST from its tokens.
+// Tokens, that were separated in the input, are separated by a single space.
+// A token, that appears in more than one field of the node, is only rendered
+// once.
+func Text(node interface{}) string {
+	var b strings.Builder
+	end := -1
+	for _, t := range Tokens(node) {
+		if t.Offset() < end {
+			continue
+		}
+		if end >= 0 && t.Offset() > end {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.Value())
+		end = t.Offset() + t.Length()
+	}
+	return b.String()
+}
@@ -141,6 +141,129 @@ func TestSingleStatementParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"alter add column with default",
+			"ALTER TABLE users ADD age INTEGER DEFAULT -1",
+			&ast.SQLStmt{
+				AlterTableStmt: &ast.AlterTableStmt{
+					Alter:     token.New(1, 1, 0, 5, token.KeywordAlter, "ALTER"),
+					Table:     token.New(1, 7, 6, 5, token.KeywordTable, "TABLE"),
+					TableName: token.New(1, 13, 12, 5, token.Literal, "users"),
+					Add:       token.New(1, 19, 18, 3, token.KeywordAdd, "ADD"),
+					ColumnDef: &ast.ColumnDef{
+						ColumnName: token.New(1, 23, 22, 3, token.Literal, "age"),
+						TypeName: &ast.TypeName{
+							Name: []token.Token{
+								token.New(1, 27, 26, 7, token.Literal, "INTEGER"),
+							},
+						},
+						ColumnConstraint: []*ast.ColumnConstraint{
+							{
+								Default: token.New(1, 35, 34, 7, token.KeywordDefault, "DEFAULT"),
+								SignedNumber: &ast.SignedNumber{
+									Sign:           token.New(1, 43, 42, 1, token.UnaryOperator, "-"),
+									NumericLiteral: token.New(1, 44, 43, 1, token.Literal, "1"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"alter add column with positive default",
+			"ALTER TABLE users ADD price REAL DEFAULT +1.5",
+			&ast.SQLStmt{
+				AlterTableStmt: &ast.AlterTableStmt{
+					Alter:     token.New(1, 1, 0, 5, token.KeywordAlter, "ALTER"),
+					Table:     token.New(1, 7, 6, 5, token.KeywordTable, "TABLE"),
+					TableName: token.New(1, 13, 12, 5, token.Literal, "users"),
+					Add:       token.New(1, 19, 18, 3, token.KeywordAdd, "ADD"),
+					ColumnDef: &ast.ColumnDef{
+						ColumnName: token.New(1, 23, 22, 5, token.Literal, "price"),
+						TypeName: &ast.TypeName{
+							Name: []token.Token{
+								token.New(1, 29, 28, 4, token.Literal, "REAL"),
+							},
+						},
+						ColumnConstraint: []*ast.ColumnConstraint{
+							{
+								Default: token.New(1, 34, 33, 7, token.KeywordDefault, "DEFAULT"),
+								SignedNumber: &ast.SignedNumber{
+									Sign:           token.New(1, 42, 41, 1, token.UnaryOperator, "+"),
+									NumericLiteral: token.New(1, 43, 42, 3, token.Literal, "1.5"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"alter add column with type name",
+			"ALTER TABLE users ADD COLUMN age INTEGER",
+			&ast.SQLStmt{
+				AlterTableStmt: &ast.AlterTableStmt{
+					Alter:     token.New(1, 1, 0, 5, token.KeywordAlter, "ALTER"),
+					Table:     token.New(1, 7, 6, 5, token.KeywordTable, "TABLE"),
+					TableName: token.New(1, 13, 12, 5, token.Literal, "users"),
+					Add:       token.New(1, 19, 18, 3, token.KeywordAdd, "ADD"),
+					Column:    token.New(1, 23, 22, 6, token.KeywordColumn, "COLUMN"),
+					ColumnDef: &ast.ColumnDef{
+						ColumnName: token.New(1, 30, 29, 3, token.Literal, "age"),
+						TypeName: &ast.TypeName{
+							Name: []token.Token{
+								token.New(1, 34, 33, 7, token.Literal, "INTEGER"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"alter add column without type",
+			"ALTER TABLE users ADD age",
+			&ast.SQLStmt{
+				AlterTableStmt: &ast.AlterTableStmt{
+					Alter:     token.New(1, 1, 0, 5, token.KeywordAlter, "ALTER"),
+					Table:     token.New(1, 7, 6, 5, token.KeywordTable, "TABLE"),
+					TableName: token.New(1, 13, 12, 5, token.Literal, "users"),
+					Add:       token.New(1, 19, 18, 3, token.KeywordAdd, "ADD"),
+					ColumnDef: &ast.ColumnDef{
+						ColumnName: token.New(1, 23, 22, 3, token.Literal, "age"),
+					},
+				},
+			},
+		},
+		{
+			"alter drop column",
+			"ALTER TABLE main.users DROP COLUMN name",
+			&ast.SQLStmt{
+				AlterTableStmt: &ast.AlterTableStmt{
+					Alter:      token.New(1, 1, 0, 5, token.KeywordAlter, "ALTER"),
+					Table:      token.New(1, 7, 6, 5, token.KeywordTable, "TABLE"),
+					SchemaName: token.New(1, 13, 12, 4, token.Literal, "main"),
+					Period:     token.New(1, 17, 16, 1, token.Literal, "."),
+					TableName:  token.New(1, 18, 17, 5, token.Literal, "users"),
+					Drop:       token.New(1, 24, 23, 4, token.KeywordDrop, "DROP"),
+					Column:     token.New(1, 29, 28, 6, token.KeywordColumn, "COLUMN"),
+					ColumnName: token.New(1, 36, 35, 4, token.Literal, "name"),
+				},
+			},
+		},
+		{
+			"alter drop column implicit",
+			"ALTER TABLE users DROP name",
+			&ast.SQLStmt{
+				AlterTableStmt: &ast.AlterTableStmt{
+					Alter:      token.New(1, 1, 0, 5, token.KeywordAlter, "ALTER"),
+					Table:      token.New(1, 7, 6, 5, token.KeywordTable, "TABLE"),
+					TableName:  token.New(1, 13, 12, 5, token.Literal, "users"),
+					Drop:       token.New(1, 19, 18, 4, token.KeywordDrop, "DROP"),
+					ColumnName: token.New(1, 24, 23, 4, token.Literal, "name"),
+				},
+			},
+		},
 		{
 			"attach database",
 			"ATTACH DATABASE myDb AS newDb",
@@ -149,7 +272,7 @@ func TestSingleStatementParse(t *testing.T) {
 					Attach:   token.New(1, 1, 0, 6, token.KeywordAttach, "ATTACH"),
 					Database: token.New(1, 8, 7, 8, token.KeywordDatabase, "DATABASE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 17, 16, 4, token.Literal, "myDb"),
+						ColumnName: token.New(1, 17, 16, 4, token.Literal, "myDb"),
 					},
 					As:         token.New(1, 22, 21, 2, token.KeywordAs, "AS"),
 					SchemaName: token.New(1, 25, 24, 5, token.Literal, "newDb"),
@@ -163,7 +286,7 @@ func TestSingleStatementParse(t *testing.T) {
 				AttachStmt: &ast.AttachStmt{
 					Attach: token.New(1, 1, 0, 6, token.KeywordAttach, "ATTACH"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 8, 7, 8, token.Literal, "mySchema"),
+						ColumnName: token.New(1, 8, 7, 8, token.Literal, "mySchema"),
 					},
 					As:         token.New(1, 17, 16, 2, token.KeywordAs, "AS"),
 					SchemaName: token.New(1, 20, 19, 9, token.Literal, "newSchema"),
@@ -191,6 +314,355 @@ func TestSingleStatementParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"drop view",
+			"DROP VIEW myView",
+			&ast.SQLStmt{
+				DropViewStmt: &ast.DropViewStmt{
+					Drop:     token.New(1, 1, 0, 4, token.KeywordDrop, "DROP"),
+					View:     token.New(1, 6, 5, 4, token.KeywordView, "VIEW"),
+					ViewName: token.New(1, 11, 10, 6, token.Literal, "myView"),
+				},
+			},
+		},
+		{
+			"drop view with schema and IF EXISTS",
+			"DROP VIEW IF EXISTS mySchema.myView",
+			&ast.SQLStmt{
+				DropViewStmt: &ast.DropViewStmt{
+					Drop:       token.New(1, 1, 0, 4, token.KeywordDrop, "DROP"),
+					View:       token.New(1, 6, 5, 4, token.KeywordView, "VIEW"),
+					If:         token.New(1, 11, 10, 2, token.KeywordIf, "IF"),
+					Exists:     token.New(1, 14, 13, 6, token.KeywordExists, "EXISTS"),
+					SchemaName: token.New(1, 21, 20, 8, token.Literal, "mySchema"),
+					Period:     token.New(1, 29, 28, 1, token.Literal, "."),
+					ViewName:   token.New(1, 30, 29, 6, token.Literal, "myView"),
+				},
+			},
+		},
+		{
+			"create table",
+			"CREATE TABLE IF NOT EXISTS main.t (a INTEGER PRIMARY KEY, b TEXT NOT NULL, UNIQUE (b) ON CONFLICT REPLACE, CHECK (a > 0))",
+			&ast.SQLStmt{
+				CreateTableStmt: &ast.CreateTableStmt{
+					Create:     token.New(1, 1, 0, 6, token.KeywordCreate, "CREATE"),
+					Table:      token.New(1, 8, 7, 5, token.KeywordTable, "TABLE"),
+					If:         token.New(1, 14, 13, 2, token.KeywordIf, "IF"),
+					Not:        token.New(1, 17, 16, 3, token.KeywordNot, "NOT"),
+					Exists:     token.New(1, 21, 20, 6, token.KeywordExists, "EXISTS"),
+					SchemaName: token.New(1, 28, 27, 4, token.Literal, "main"),
+					Period:     token.New(1, 32, 31, 1, token.Literal, "."),
+					TableName:  token.New(1, 33, 32, 1, token.Literal, "t"),
+					LeftParen:  token.New(1, 35, 34, 1, token.Delimiter, "("),
+					ColumnDef: []*ast.ColumnDef{
+						{
+							ColumnName: token.New(1, 36, 35, 1, token.Literal, "a"),
+							TypeName: &ast.TypeName{
+								Name: []token.Token{
+									token.New(1, 38, 37, 7, token.Literal, "INTEGER"),
+								},
+							},
+							ColumnConstraint: []*ast.ColumnConstraint{
+								{
+									Primary: token.New(1, 46, 45, 7, token.KeywordPrimary, "PRIMARY"),
+									Key:     token.New(1, 54, 53, 3, token.KeywordKey, "KEY"),
+								},
+							},
+						},
+						{
+							ColumnName: token.New(1, 59, 58, 1, token.Literal, "b"),
+							TypeName: &ast.TypeName{
+								Name: []token.Token{
+									token.New(1, 61, 60, 4, token.Literal, "TEXT"),
+								},
+							},
+							ColumnConstraint: []*ast.ColumnConstraint{
+								{
+									Not:  token.New(1, 66, 65, 3, token.KeywordNot, "NOT"),
+									Null: token.New(1, 70, 69, 4, token.KeywordNull, "NULL"),
+								},
+							},
+						},
+					},
+					TableConstraint: []*ast.TableConstraint{
+						{
+							Unique:     token.New(1, 76, 75, 6, token.KeywordUnique, "UNIQUE"),
+							LeftParen:  token.New(1, 83, 82, 1, token.Delimiter, "("),
+							RightParen: token.New(1, 85, 84, 1, token.Delimiter, ")"),
+							IndexedColumn: []*ast.IndexedColumn{
+								{
+									ColumnName: token.New(1, 84, 83, 1, token.Literal, "b"),
+								},
+							},
+							ConflictClause: &ast.ConflictClause{
+								On:       token.New(1, 87, 86, 2, token.KeywordOn, "ON"),
+								Conflict: token.New(1, 90, 89, 8, token.KeywordConflict, "CONFLICT"),
+								Replace:  token.New(1, 99, 98, 7, token.KeywordReplace, "REPLACE"),
+							},
+						},
+						{
+							LeftParen:  token.New(1, 114, 113, 1, token.Delimiter, "("),
+							RightParen: token.New(1, 120, 119, 1, token.Delimiter, ")"),
+							Check:      token.New(1, 108, 107, 5, token.KeywordCheck, "CHECK"),
+							Expr: &ast.Expr{
+								Expr1: &ast.Expr{
+									ColumnName: token.New(1, 115, 114, 1, token.Literal, "a"),
+								},
+								BinaryOperator: token.New(1, 117, 116, 1, token.BinaryOperator, ">"),
+								Expr2: &ast.Expr{
+									LiteralValue: token.New(1, 119, 118, 1, token.Literal, "0"),
+								},
+							},
+						},
+					},
+					RightParen: token.New(1, 121, 120, 1, token.Delimiter, ")"),
+				},
+			},
+		},
+		{
+			"create table with foreign key",
+			"CREATE TEMP TABLE c (p INTEGER REFERENCES t (a) ON DELETE CASCADE, FOREIGN KEY (p) REFERENCES t ON UPDATE SET NULL DEFERRABLE INITIALLY DEFERRED) WITHOUT ROWID",
+			&ast.SQLStmt{
+				CreateTableStmt: &ast.CreateTableStmt{
+					Create:    token.New(1, 1, 0, 6, token.KeywordCreate, "CREATE"),
+					Temp:      token.New(1, 8, 7, 4, token.KeywordTemp, "TEMP"),
+					Table:     token.New(1, 13, 12, 5, token.KeywordTable, "TABLE"),
+					TableName: token.New(1, 19, 18, 1, token.Literal, "c"),
+					LeftParen: token.New(1, 21, 20, 1, token.Delimiter, "("),
+					ColumnDef: []*ast.ColumnDef{
+						{
+							ColumnName: token.New(1, 22, 21, 1, token.Literal, "p"),
+							TypeName: &ast.TypeName{
+								Name: []token.Token{
+									token.New(1, 24, 23, 7, token.Literal, "INTEGER"),
+								},
+							},
+							ColumnConstraint: []*ast.ColumnConstraint{
+								{
+									ForeignKeyClause: &ast.ForeignKeyClause{
+										References:   token.New(1, 32, 31, 10, token.KeywordReferences, "REFERENCES"),
+										ForeignTable: token.New(1, 43, 42, 1, token.Literal, "t"),
+										LeftParen:    token.New(1, 45, 44, 1, token.Delimiter, "("),
+										ColumnName: []token.Token{
+											token.New(1, 46, 45, 1, token.Literal, "a"),
+										},
+										RightParen: token.New(1, 47, 46, 1, token.Delimiter, ")"),
+										ForeignKeyClauseCore: []*ast.ForeignKeyClauseCore{
+											{
+												On:      token.New(1, 49, 48, 2, token.KeywordOn, "ON"),
+												Delete:  token.New(1, 52, 51, 6, token.KeywordDelete, "DELETE"),
+												Cascade: token.New(1, 59, 58, 7, token.KeywordCascade, "CASCADE"),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					TableConstraint: []*ast.TableConstraint{
+						{
+							Key:        token.New(1, 76, 75, 3, token.KeywordKey, "KEY"),
+							LeftParen:  token.New(1, 80, 79, 1, token.Delimiter, "("),
+							RightParen: token.New(1, 82, 81, 1, token.Delimiter, ")"),
+							Foreign:    token.New(1, 68, 67, 7, token.KeywordForeign, "FOREIGN"),
+							ColumnName: []token.Token{
+								token.New(1, 81, 80, 1, token.Literal, "p"),
+							},
+							ForeignKeyClause: &ast.ForeignKeyClause{
+								References:   token.New(1, 84, 83, 10, token.KeywordReferences, "REFERENCES"),
+								ForeignTable: token.New(1, 95, 94, 1, token.Literal, "t"),
+								ForeignKeyClauseCore: []*ast.ForeignKeyClauseCore{
+									{
+										On:     token.New(1, 97, 96, 2, token.KeywordOn, "ON"),
+										Update: token.New(1, 100, 99, 6, token.KeywordUpdate, "UPDATE"),
+										Set:    token.New(1, 107, 106, 3, token.KeywordSet, "SET"),
+										Null:   token.New(1, 111, 110, 4, token.KeywordNull, "NULL"),
+									},
+								},
+								Deferrable: token.New(1, 116, 115, 10, token.KeywordDeferrable, "DEFERRABLE"),
+								Initially:  token.New(1, 127, 126, 9, token.KeywordInitially, "INITIALLY"),
+								Deferred:   token.New(1, 137, 136, 8, token.KeywordDeferred, "DEFERRED"),
+							},
+						},
+					},
+					RightParen: token.New(1, 145, 144, 1, token.Delimiter, ")"),
+					Without:    token.New(1, 147, 146, 7, token.KeywordWithout, "WITHOUT"),
+					Rowid:      token.New(1, 155, 154, 5, token.Literal, "ROWID"),
+				},
+			},
+		},
+		{
+			"create table as select",
+			"CREATE TABLE t AS SELECT a FROM u",
+			&ast.SQLStmt{
+				CreateTableStmt: &ast.CreateTableStmt{
+					Create:    token.New(1, 1, 0, 6, token.KeywordCreate, "CREATE"),
+					Table:     token.New(1, 8, 7, 5, token.KeywordTable, "TABLE"),
+					TableName: token.New(1, 14, 13, 1, token.Literal, "t"),
+					As:        token.New(1, 16, 15, 2, token.KeywordAs, "AS"),
+					SelectStmt: &ast.SelectStmt{
+						SelectCore: []*ast.SelectCore{
+							{
+								Select: token.New(1, 19, 18, 6, token.KeywordSelect, "SELECT"),
+								ResultColumn: []*ast.ResultColumn{
+									{
+										Expr: &ast.Expr{
+											ColumnName: token.New(1, 26, 25, 1, token.Literal, "a"),
+										},
+									},
+								},
+								From: token.New(1, 28, 27, 4, token.KeywordFrom, "FROM"),
+								JoinClause: &ast.JoinClause{
+									TableOrSubquery: &ast.TableOrSubquery{
+										TableName: token.New(1, 33, 32, 1, token.Literal, "u"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"create view",
+			"CREATE VIEW v (b) AS SELECT a FROM u",
+			&ast.SQLStmt{
+				CreateViewStmt: &ast.CreateViewStmt{
+					Create:     token.New(1, 1, 0, 6, token.KeywordCreate, "CREATE"),
+					View:       token.New(1, 8, 7, 4, token.KeywordView, "VIEW"),
+					ViewName:   token.New(1, 13, 12, 1, token.Literal, "v"),
+					LeftParen:  token.New(1, 15, 14, 1, token.Delimiter, "("),
+					ColumnName: []token.Token{token.New(1, 16, 15, 1, token.Literal, "b")},
+					RightParen: token.New(1, 17, 16, 1, token.Delimiter, ")"),
+					As:         token.New(1, 19, 18, 2, token.KeywordAs, "AS"),
+					SelectStmt: &ast.SelectStmt{
+						SelectCore: []*ast.SelectCore{
+							{
+								Select: token.New(1, 22, 21, 6, token.KeywordSelect, "SELECT"),
+								ResultColumn: []*ast.ResultColumn{
+									{
+										Expr: &ast.Expr{
+											ColumnName: token.New(1, 29, 28, 1, token.Literal, "a"),
+										},
+									},
+								},
+								From: token.New(1, 31, 30, 4, token.KeywordFrom, "FROM"),
+								JoinClause: &ast.JoinClause{
+									TableOrSubquery: &ast.TableOrSubquery{
+										TableName: token.New(1, 36, 35, 1, token.Literal, "u"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"drop table",
+			"DROP TABLE IF EXISTS main.t",
+			&ast.SQLStmt{
+				DropTableStmt: &ast.DropTableStmt{
+					Drop:       token.New(1, 1, 0, 4, token.KeywordDrop, "DROP"),
+					Table:      token.New(1, 6, 5, 5, token.KeywordTable, "TABLE"),
+					If:         token.New(1, 12, 11, 2, token.KeywordIf, "IF"),
+					Exists:     token.New(1, 15, 14, 6, token.KeywordExists, "EXISTS"),
+					SchemaName: token.New(1, 22, 21, 4, token.Literal, "main"),
+					Period:     token.New(1, 26, 25, 1, token.Literal, "."),
+					TableName:  token.New(1, 27, 26, 1, token.Literal, "t"),
+				},
+			},
+		},
+		{
+			"drop index",
+			"DROP INDEX IF EXISTS main.ix",
+			&ast.SQLStmt{
+				DropIndexStmt: &ast.DropIndexStmt{
+					Drop:       token.New(1, 1, 0, 4, token.KeywordDrop, "DROP"),
+					Index:      token.New(1, 6, 5, 5, token.KeywordIndex, "INDEX"),
+					If:         token.New(1, 12, 11, 2, token.KeywordIf, "IF"),
+					Exists:     token.New(1, 15, 14, 6, token.KeywordExists, "EXISTS"),
+					SchemaName: token.New(1, 22, 21, 4, token.Literal, "main"),
+					Period:     token.New(1, 26, 25, 1, token.Literal, "."),
+					IndexName:  token.New(1, 27, 26, 2, token.Literal, "ix"),
+				},
+			},
+		},
+		{
+			"create trigger",
+			"CREATE TRIGGER tr AFTER DELETE ON t BEGIN DELETE FROM u; END",
+			&ast.SQLStmt{
+				CreateTriggerStmt: &ast.CreateTriggerStmt{
+					Create:      token.New(1, 1, 0, 6, token.KeywordCreate, "CREATE"),
+					Trigger:     token.New(1, 8, 7, 7, token.KeywordTrigger, "TRIGGER"),
+					TriggerName: token.New(1, 16, 15, 2, token.Literal, "tr"),
+					After:       token.New(1, 19, 18, 5, token.KeywordAfter, "AFTER"),
+					Delete:      token.New(1, 25, 24, 6, token.KeywordDelete, "DELETE"),
+					On:          token.New(1, 32, 31, 2, token.KeywordOn, "ON"),
+					TableName:   token.New(1, 35, 34, 1, token.Literal, "t"),
+					Begin:       token.New(1, 37, 36, 5, token.KeywordBegin, "BEGIN"),
+					TriggerStmt: []*ast.TriggerStmt{
+						{
+							DeleteStmt: &ast.DeleteStmt{
+								Delete: token.New(1, 43, 42, 6, token.KeywordDelete, "DELETE"),
+								From:   token.New(1, 50, 49, 4, token.KeywordFrom, "FROM"),
+								QualifiedTableName: &ast.QualifiedTableName{
+									TableName: token.New(1, 55, 54, 1, token.Literal, "u"),
+								},
+							},
+						},
+					},
+					End: token.New(1, 58, 57, 3, token.KeywordEnd, "END"),
+				},
+			},
+		},
+		{
+			"drop trigger",
+			"DROP TRIGGER IF EXISTS main.tr",
+			&ast.SQLStmt{
+				DropTriggerStmt: &ast.DropTriggerStmt{
+					Drop:        token.New(1, 1, 0, 4, token.KeywordDrop, "DROP"),
+					Trigger:     token.New(1, 6, 5, 7, token.KeywordTrigger, "TRIGGER"),
+					If:          token.New(1, 14, 13, 2, token.KeywordIf, "IF"),
+					Exists:      token.New(1, 17, 16, 6, token.KeywordExists, "EXISTS"),
+					SchemaName:  token.New(1, 24, 23, 4, token.Literal, "main"),
+					Period:      token.New(1, 28, 27, 1, token.Literal, "."),
+					TriggerName: token.New(1, 29, 28, 2, token.Literal, "tr"),
+				},
+			},
+		},
+		{
+			"reindex",
+			"REINDEX",
+			&ast.SQLStmt{
+				ReindexStmt: &ast.ReindexStmt{
+					Reindex: token.New(1, 1, 0, 7, token.KeywordReindex, "REINDEX"),
+				},
+			},
+		},
+		{
+			"reindex collation or table",
+			"REINDEX nocase",
+			&ast.SQLStmt{
+				ReindexStmt: &ast.ReindexStmt{
+					Reindex:          token.New(1, 1, 0, 7, token.KeywordReindex, "REINDEX"),
+					CollationName:    token.New(1, 9, 8, 6, token.Literal, "nocase"),
+					TableOrIndexName: token.New(1, 9, 8, 6, token.Literal, "nocase"),
+				},
+			},
+		},
+		{
+			"reindex qualified",
+			"REINDEX main.t",
+			&ast.SQLStmt{
+				ReindexStmt: &ast.ReindexStmt{
+					Reindex:          token.New(1, 1, 0, 7, token.KeywordReindex, "REINDEX"),
+					SchemaName:       token.New(1, 9, 8, 4, token.Literal, "main"),
+					Period:           token.New(1, 13, 12, 1, token.Literal, "."),
+					TableOrIndexName: token.New(1, 14, 13, 1, token.Literal, "t"),
+				},
+			},
+		},
 		{
 			"vacuum",
 			"VACUUM",
@@ -233,6 +705,83 @@ func TestSingleStatementParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"PRAGMA",
+			"PRAGMA query_memory",
+			&ast.SQLStmt{
+				PragmaStmt: &ast.PragmaStmt{
+					Pragma:     token.New(1, 1, 0, 6, token.KeywordPragma, "PRAGMA"),
+					PragmaName: token.New(1, 8, 7, 12, token.Literal, "query_memory"),
+				},
+			},
+		},
+		{
+			"PRAGMA with schema-name and value",
+			"PRAGMA main.query_memory = '64MB'",
+			&ast.SQLStmt{
+				PragmaStmt: &ast.PragmaStmt{
+					Pragma:     token.New(1, 1, 0, 6, token.KeywordPragma, "PRAGMA"),
+					SchemaName: token.New(1, 8, 7, 4, token.Literal, "main"),
+					Period:     token.New(1, 12, 11, 1, token.Literal, "."),
+					PragmaName: token.New(1, 13, 12, 12, token.Literal, "query_memory"),
+					Equal:      token.New(1, 26, 25, 1, token.BinaryOperator, "="),
+					PragmaValue: &ast.PragmaValue{
+						Name: token.New(1, 28, 27, 6, token.Literal, "'64MB'"),
+					},
+				},
+			},
+		},
+		{
+			"PRAGMA with signed number",
+			"PRAGMA cache_size(-2000)",
+			&ast.SQLStmt{
+				PragmaStmt: &ast.PragmaStmt{
+					Pragma:     token.New(1, 1, 0, 6, token.KeywordPragma, "PRAGMA"),
+					PragmaName: token.New(1, 8, 7, 10, token.Literal, "cache_size"),
+					LeftParen:  token.New(1, 18, 17, 1, token.Delimiter, "("),
+					PragmaValue: &ast.PragmaValue{
+						SignedNumber: &ast.SignedNumber{
+							Sign:           token.New(1, 19, 18, 1, token.UnaryOperator, "-"),
+							NumericLiteral: token.New(1, 20, 19, 4, token.Literal, "2000"),
+						},
+					},
+					RightParen: token.New(1, 24, 23, 1, token.Delimiter, ")"),
+				},
+			},
+		},
+		{
+			"EXPLAIN ANALYZE",
+			"EXPLAIN ANALYZE VACUUM",
+			&ast.SQLStmt{
+				Explain: token.New(1, 1, 0, 7, token.KeywordExplain, "EXPLAIN"),
+				Analyze: token.New(1, 9, 8, 7, token.KeywordAnalyze, "ANALYZE"),
+				VacuumStmt: &ast.VacuumStmt{
+					Vacuum: token.New(1, 17, 16, 6, token.KeywordVacuum, "VACUUM"),
+				},
+			},
+		},
+		{
+			"EXPLAIN of ANALYZE",
+			"EXPLAIN ANALYZE",
+			&ast.SQLStmt{
+				Explain: token.New(1, 1, 0, 7, token.KeywordExplain, "EXPLAIN"),
+				AnalyzeStmt: &ast.AnalyzeStmt{
+					Analyze: token.New(1, 9, 8, 7, token.KeywordAnalyze, "ANALYZE"),
+				},
+			},
+		},
+		{
+			"EXPLAIN of ANALYZE with table-or-index-name",
+			"EXPLAIN ANALYZE myTable",
+			&ast.SQLStmt{
+				Explain: token.New(1, 1, 0, 7, token.KeywordExplain, "EXPLAIN"),
+				AnalyzeStmt: &ast.AnalyzeStmt{
+					Analyze:          token.New(1, 9, 8, 7, token.KeywordAnalyze, "ANALYZE"),
+					SchemaName:       token.New(1, 17, 16, 7, token.Literal, "myTable"),
+					TableOrIndexName: token.New(1, 17, 16, 7, token.Literal, "myTable"),
+				},
+			},
+		},
 		{
 			"analyze",
 			"ANALYZE",
@@ -385,6 +934,37 @@ func TestSingleStatementParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"SAVEPOINT",
+			"SAVEPOINT mySavePoint",
+			&ast.SQLStmt{
+				SavepointStmt: &ast.SavepointStmt{
+					Savepoint:     token.New(1, 1, 0, 9, token.KeywordSavepoint, "SAVEPOINT"),
+					SavepointName: token.New(1, 11, 10, 11, token.Literal, "mySavePoint"),
+				},
+			},
+		},
+		{
+			"RELEASE",
+			"RELEASE mySavePoint",
+			&ast.SQLStmt{
+				ReleaseStmt: &ast.ReleaseStmt{
+					Release:       token.New(1, 1, 0, 7, token.KeywordRelease, "RELEASE"),
+					SavepointName: token.New(1, 9, 8, 11, token.Literal, "mySavePoint"),
+				},
+			},
+		},
+		{
+			"RELEASE with SAVEPOINT",
+			"RELEASE SAVEPOINT mySavePoint",
+			&ast.SQLStmt{
+				ReleaseStmt: &ast.ReleaseStmt{
+					Release:       token.New(1, 1, 0, 7, token.KeywordRelease, "RELEASE"),
+					Savepoint:     token.New(1, 9, 8, 9, token.KeywordSavepoint, "SAVEPOINT"),
+					SavepointName: token.New(1, 19, 18, 11, token.Literal, "mySavePoint"),
+				},
+			},
+		},
 		{
 			"rollback",
 			"ROLLBACK",
@@ -655,7 +1235,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 45, 44, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 47, 46, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 53, 52, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 53, 52, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -680,7 +1260,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 52, 51, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 54, 53, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 60, 59, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 60, 59, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -707,7 +1287,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 59, 58, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 61, 60, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 67, 66, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 67, 66, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -735,7 +1315,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 66, 65, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 68, 67, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 74, 73, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 74, 73, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -761,7 +1341,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 54, 53, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 56, 55, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 62, 61, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 62, 61, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -788,7 +1368,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 61, 60, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 63, 62, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 69, 68, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 69, 68, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -817,7 +1397,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 68, 67, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 70, 69, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 76, 75, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 76, 75, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -847,7 +1427,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 75, 74, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 77, 76, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 83, 82, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 83, 82, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -883,7 +1463,7 @@ func TestSingleStatementParse(t *testing.T) {
 					RightParen: token.New(1, 102, 101, 1, token.Delimiter, ")"),
 					Where:      token.New(1, 104, 103, 5, token.KeywordWhere, "WHERE"),
 					Expr: &ast.Expr{
-						LiteralValue: token.New(1, 110, 109, 11, token.Literal, "exprLiteral"),
+						ColumnName: token.New(1, 110, 109, 11, token.Literal, "exprLiteral"),
 					},
 				},
 			},
@@ -932,6 +1512,406 @@ func TestSingleStatementParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"SELECT with WHERE and operator precedence",
+			"SELECT a, t.* FROM t WHERE b = 1 + 2 * 3",
+			&ast.SQLStmt{
+				SelectStmt: &ast.SelectStmt{
+					SelectCore: []*ast.SelectCore{
+						{
+							Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+							ResultColumn: []*ast.ResultColumn{
+								{
+									Expr: &ast.Expr{
+										ColumnName: token.New(1, 8, 7, 1, token.Literal, "a"),
+									},
+								},
+								{
+									Asterisk:  token.New(1, 13, 12, 1, token.BinaryOperator, "*"),
+									TableName: token.New(1, 11, 10, 1, token.Literal, "t"),
+									Period:    token.New(1, 12, 11, 1, token.Literal, "."),
+								},
+							},
+							From: token.New(1, 15, 14, 4, token.KeywordFrom, "FROM"),
+							JoinClause: &ast.JoinClause{
+								TableOrSubquery: &ast.TableOrSubquery{
+									TableName: token.New(1, 20, 19, 1, token.Literal, "t"),
+								},
+							},
+							Where: token.New(1, 22, 21, 5, token.KeywordWhere, "WHERE"),
+							Expr1: &ast.Expr{
+								Expr1: &ast.Expr{
+									ColumnName: token.New(1, 28, 27, 1, token.Literal, "b"),
+								},
+								BinaryOperator: token.New(1, 30, 29, 1, token.BinaryOperator, "="),
+								Expr2: &ast.Expr{
+									Expr1: &ast.Expr{
+										LiteralValue: token.New(1, 32, 31, 1, token.Literal, "1"),
+									},
+									BinaryOperator: token.New(1, 34, 33, 1, token.UnaryOperator, "+"),
+									Expr2: &ast.Expr{
+										Expr1: &ast.Expr{
+											LiteralValue: token.New(1, 36, 35, 1, token.Literal, "2"),
+										},
+										BinaryOperator: token.New(1, 38, 37, 1, token.BinaryOperator, "*"),
+										Expr2: &ast.Expr{
+											LiteralValue: token.New(1, 40, 39, 1, token.Literal, "3"),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"SELECT DISTINCT with alias, LEFT JOIN, ORDER BY and LIMIT",
+			"SELECT DISTINCT a AS x FROM s.t AS u LEFT JOIN v ON u.a = v.a ORDER BY a DESC LIMIT 10",
+			&ast.SQLStmt{
+				SelectStmt: &ast.SelectStmt{
+					SelectCore: []*ast.SelectCore{
+						{
+							Select:   token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+							Distinct: token.New(1, 8, 7, 8, token.KeywordDistinct, "DISTINCT"),
+							ResultColumn: []*ast.ResultColumn{
+								{
+									Expr: &ast.Expr{
+										ColumnName: token.New(1, 17, 16, 1, token.Literal, "a"),
+									},
+									As:          token.New(1, 19, 18, 2, token.KeywordAs, "AS"),
+									ColumnAlias: token.New(1, 22, 21, 1, token.Literal, "x"),
+								},
+							},
+							From: token.New(1, 24, 23, 4, token.KeywordFrom, "FROM"),
+							JoinClause: &ast.JoinClause{
+								TableOrSubquery: &ast.TableOrSubquery{
+									SchemaName: token.New(1, 29, 28, 1, token.Literal, "s"),
+									Period:     token.New(1, 30, 29, 1, token.Literal, "."),
+									TableName:  token.New(1, 31, 30, 1, token.Literal, "t"),
+									As:         token.New(1, 33, 32, 2, token.KeywordAs, "AS"),
+									TableAlias: token.New(1, 36, 35, 1, token.Literal, "u"),
+								},
+								JoinClausePart: &ast.JoinClausePart{
+									JoinOperator: &ast.JoinOperator{
+										Left: token.New(1, 38, 37, 4, token.KeywordLeft, "LEFT"),
+										Join: token.New(1, 43, 42, 4, token.KeywordJoin, "JOIN"),
+									},
+									TableOrSubquery: &ast.TableOrSubquery{
+										TableName: token.New(1, 48, 47, 1, token.Literal, "v"),
+									},
+									JoinConstraint: &ast.JoinConstraint{
+										On: token.New(1, 50, 49, 2, token.KeywordOn, "ON"),
+										Expr: &ast.Expr{
+											Expr1: &ast.Expr{
+												TableName:  token.New(1, 53, 52, 1, token.Literal, "u"),
+												Period2:    token.New(1, 54, 53, 1, token.Literal, "."),
+												ColumnName: token.New(1, 55, 54, 1, token.Literal, "a"),
+											},
+											BinaryOperator: token.New(1, 57, 56, 1, token.BinaryOperator, "="),
+											Expr2: &ast.Expr{
+												TableName:  token.New(1, 59, 58, 1, token.Literal, "v"),
+												Period2:    token.New(1, 60, 59, 1, token.Literal, "."),
+												ColumnName: token.New(1, 61, 60, 1, token.Literal, "a"),
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					Order: token.New(1, 63, 62, 5, token.KeywordOrder, "ORDER"),
+					By:    token.New(1, 69, 68, 2, token.KeywordBy, "BY"),
+					OrderingTerm: []*ast.OrderingTerm{
+						{
+							Expr: &ast.Expr{
+								ColumnName: token.New(1, 72, 71, 1, token.Literal, "a"),
+							},
+							Desc: token.New(1, 74, 73, 4, token.KeywordDesc, "DESC"),
+						},
+					},
+					Limit: token.New(1, 79, 78, 5, token.KeywordLimit, "LIMIT"),
+					Expr1: &ast.Expr{
+						LiteralValue: token.New(1, 85, 84, 2, token.Literal, "10"),
+					},
+				},
+			},
+		},
+		{
+			"SELECT with GROUP BY, HAVING, IN and compound VALUES",
+			"SELECT count(*) FROM t GROUP BY a HAVING a IN (1, 2) UNION ALL VALUES (1)",
+			&ast.SQLStmt{
+				SelectStmt: &ast.SelectStmt{
+					SelectCore: []*ast.SelectCore{
+						{
+							Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+							ResultColumn: []*ast.ResultColumn{
+								{
+									Expr: &ast.Expr{
+										FunctionName: token.New(1, 8, 7, 5, token.Literal, "count"),
+										LeftParen:    token.New(1, 13, 12, 1, token.Delimiter, "("),
+										Asterisk:     token.New(1, 14, 13, 1, token.BinaryOperator, "*"),
+										RightParen:   token.New(1, 15, 14, 1, token.Delimiter, ")"),
+									},
+								},
+							},
+							From: token.New(1, 17, 16, 4, token.KeywordFrom, "FROM"),
+							JoinClause: &ast.JoinClause{
+								TableOrSubquery: &ast.TableOrSubquery{
+									TableName: token.New(1, 22, 21, 1, token.Literal, "t"),
+								},
+							},
+							Group: token.New(1, 24, 23, 5, token.KeywordGroup, "GROUP"),
+							By:    token.New(1, 30, 29, 2, token.KeywordBy, "BY"),
+							Expr2: []*ast.Expr{
+								{
+									ColumnName: token.New(1, 33, 32, 1, token.Literal, "a"),
+								},
+							},
+							Having: token.New(1, 35, 34, 6, token.KeywordHaving, "HAVING"),
+							Expr3: &ast.Expr{
+								Expr1: &ast.Expr{
+									ColumnName: token.New(1, 42, 41, 1, token.Literal, "a"),
+								},
+								LeftParen: token.New(1, 47, 46, 1, token.Delimiter, "("),
+								Expr: []*ast.Expr{
+									{
+										LiteralValue: token.New(1, 48, 47, 1, token.Literal, "1"),
+									},
+									{
+										LiteralValue: token.New(1, 51, 50, 1, token.Literal, "2"),
+									},
+								},
+								RightParen: token.New(1, 52, 51, 1, token.Delimiter, ")"),
+								In:         token.New(1, 44, 43, 2, token.KeywordIn, "IN"),
+							},
+						},
+						{
+							Values: token.New(1, 64, 63, 6, token.KeywordValues, "VALUES"),
+							ParenthesizedExpressions: []*ast.ParenthesizedExpressions{
+								{
+									LeftParen: token.New(1, 71, 70, 1, token.Delimiter, "("),
+									Exprs: []*ast.Expr{
+										{
+											LiteralValue: token.New(1, 72, 71, 1, token.Literal, "1"),
+										},
+									},
+									RightParen: token.New(1, 73, 72, 1, token.Delimiter, ")"),
+								},
+							},
+							CompoundOperator: &ast.CompoundOperator{
+								Union: token.New(1, 54, 53, 5, token.KeywordUnion, "UNION"),
+								All:   token.New(1, 60, 59, 3, token.KeywordAll, "ALL"),
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"INSERT with column names and multiple rows",
+			"INSERT INTO t (a, b) VALUES (1, 'a'), (2, NULL)",
+			&ast.SQLStmt{
+				InsertStmt: &ast.InsertStmt{
+					Insert:     token.New(1, 1, 0, 6, token.KeywordInsert, "INSERT"),
+					Into:       token.New(1, 8, 7, 4, token.KeywordInto, "INTO"),
+					TableName:  token.New(1, 13, 12, 1, token.Literal, "t"),
+					LeftParen1: token.New(1, 15, 14, 1, token.Delimiter, "("),
+					ColumnName: []token.Token{
+						token.New(1, 16, 15, 1, token.Literal, "a"),
+						token.New(1, 19, 18, 1, token.Literal, "b"),
+					},
+					RightParen2: token.New(1, 20, 19, 1, token.Delimiter, ")"),
+					Values:      token.New(1, 22, 21, 6, token.KeywordValues, "VALUES"),
+					ParenthesizedExpressions: &[]ast.ParenthesizedExpressions{
+						{
+							LeftParen: token.New(1, 29, 28, 1, token.Delimiter, "("),
+							Exprs: []*ast.Expr{
+								{
+									LiteralValue: token.New(1, 30, 29, 1, token.Literal, "1"),
+								},
+								{
+									LiteralValue: token.New(1, 33, 32, 3, token.Literal, "'a'"),
+								},
+							},
+							RightParen: token.New(1, 36, 35, 1, token.Delimiter, ")"),
+						},
+						{
+							LeftParen: token.New(1, 39, 38, 1, token.Delimiter, "("),
+							Exprs: []*ast.Expr{
+								{
+									LiteralValue: token.New(1, 40, 39, 1, token.Literal, "2"),
+								},
+								{
+									LiteralValue: token.New(1, 43, 42, 4, token.KeywordNull, "NULL"),
+								},
+							},
+							RightParen: token.New(1, 47, 46, 1, token.Delimiter, ")"),
+						},
+					},
+				},
+			},
+		},
+		{
+			"INSERT OR IGNORE with SELECT",
+			"INSERT OR IGNORE INTO t SELECT a FROM s",
+			&ast.SQLStmt{
+				InsertStmt: &ast.InsertStmt{
+					Insert:    token.New(1, 1, 0, 6, token.KeywordInsert, "INSERT"),
+					Or:        token.New(1, 8, 7, 2, token.KeywordOr, "OR"),
+					Ignore:    token.New(1, 11, 10, 6, token.KeywordIgnore, "IGNORE"),
+					Into:      token.New(1, 18, 17, 4, token.KeywordInto, "INTO"),
+					TableName: token.New(1, 23, 22, 1, token.Literal, "t"),
+					SelectStmt: &ast.SelectStmt{
+						SelectCore: []*ast.SelectCore{
+							{
+								Select: token.New(1, 25, 24, 6, token.KeywordSelect, "SELECT"),
+								ResultColumn: []*ast.ResultColumn{
+									{
+										Expr: &ast.Expr{
+											ColumnName: token.New(1, 32, 31, 1, token.Literal, "a"),
+										},
+									},
+								},
+								From: token.New(1, 34, 33, 4, token.KeywordFrom, "FROM"),
+								JoinClause: &ast.JoinClause{
+									TableOrSubquery: &ast.TableOrSubquery{
+										TableName: token.New(1, 39, 38, 1, token.Literal, "s"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			"UPDATE with WHERE",
+			"UPDATE t SET a = a + 1 WHERE b IS NOT NULL",
+			&ast.SQLStmt{
+				UpdateStmt: &ast.UpdateStmt{
+					Update: token.New(1, 1, 0, 6, token.KeywordUpdate, "UPDATE"),
+					QualifiedTableName: &ast.QualifiedTableName{
+						TableName: token.New(1, 8, 7, 1, token.Literal, "t"),
+					},
+					Set: token.New(1, 10, 9, 3, token.KeywordSet, "SET"),
+					UpdateSetter: []*ast.UpdateSetter{
+						{
+							ColumnName: token.New(1, 14, 13, 1, token.Literal, "a"),
+							Assign:     token.New(1, 16, 15, 1, token.BinaryOperator, "="),
+							Expr: &ast.Expr{
+								Expr1: &ast.Expr{
+									ColumnName: token.New(1, 18, 17, 1, token.Literal, "a"),
+								},
+								BinaryOperator: token.New(1, 20, 19, 1, token.UnaryOperator, "+"),
+								Expr2: &ast.Expr{
+									LiteralValue: token.New(1, 22, 21, 1, token.Literal, "1"),
+								},
+							},
+						},
+					},
+					Where: token.New(1, 24, 23, 5, token.KeywordWhere, "WHERE"),
+					Expr: &ast.Expr{
+						Expr1: &ast.Expr{
+							ColumnName: token.New(1, 30, 29, 1, token.Literal, "b"),
+						},
+						Expr2: &ast.Expr{
+							LiteralValue: token.New(1, 39, 38, 4, token.KeywordNull, "NULL"),
+						},
+						Not: token.New(1, 35, 34, 3, token.KeywordNot, "NOT"),
+						Is:  token.New(1, 32, 31, 2, token.KeywordIs, "IS"),
+					},
+				},
+			},
+		},
+		{
+			"DELETE with NOT BETWEEN and LIKE",
+			"DELETE FROM t WHERE NOT a BETWEEN 1 AND 2 OR b LIKE 'c%'",
+			&ast.SQLStmt{
+				DeleteStmt: &ast.DeleteStmt{
+					Delete: token.New(1, 1, 0, 6, token.KeywordDelete, "DELETE"),
+					From:   token.New(1, 8, 7, 4, token.KeywordFrom, "FROM"),
+					QualifiedTableName: &ast.QualifiedTableName{
+						TableName: token.New(1, 13, 12, 1, token.Literal, "t"),
+					},
+					Where: token.New(1, 15, 14, 5, token.KeywordWhere, "WHERE"),
+					Expr: &ast.Expr{
+						Expr1: &ast.Expr{
+							Expr1: &ast.Expr{
+								Expr1: &ast.Expr{
+									ColumnName: token.New(1, 25, 24, 1, token.Literal, "a"),
+								},
+								Expr2: &ast.Expr{
+									LiteralValue: token.New(1, 35, 34, 1, token.Literal, "1"),
+								},
+								Expr3: &ast.Expr{
+									LiteralValue: token.New(1, 41, 40, 1, token.Literal, "2"),
+								},
+								Between: token.New(1, 27, 26, 7, token.KeywordBetween, "BETWEEN"),
+								And:     token.New(1, 37, 36, 3, token.KeywordAnd, "AND"),
+							},
+							Not: token.New(1, 21, 20, 3, token.KeywordNot, "NOT"),
+						},
+						BinaryOperator: token.New(1, 43, 42, 2, token.KeywordOr, "OR"),
+						Expr2: &ast.Expr{
+							Expr1: &ast.Expr{
+								ColumnName: token.New(1, 46, 45, 1, token.Literal, "b"),
+							},
+							Expr2: &ast.Expr{
+								LiteralValue: token.New(1, 53, 52, 4, token.Literal, "'c%'"),
+							},
+							Like: token.New(1, 48, 47, 4, token.KeywordLike, "LIKE"),
+						},
+					},
+				},
+			},
+		},
+		{
+			"SELECT with CASE",
+			"SELECT CASE a WHEN 1 THEN 'one' ELSE 'other' END FROM t",
+			&ast.SQLStmt{
+				SelectStmt: &ast.SelectStmt{
+					SelectCore: []*ast.SelectCore{
+						{
+							Select: token.New(1, 1, 0, 6, token.KeywordSelect, "SELECT"),
+							ResultColumn: []*ast.ResultColumn{
+								{
+									Expr: &ast.Expr{
+										Expr1: &ast.Expr{
+											ColumnName: token.New(1, 13, 12, 1, token.Literal, "a"),
+										},
+										Case: token.New(1, 8, 7, 4, token.KeywordCase, "CASE"),
+										WhenClause: []*ast.WhenClause{
+											{
+												When: token.New(1, 15, 14, 4, token.KeywordWhen, "WHEN"),
+												Expr1: &ast.Expr{
+													LiteralValue: token.New(1, 20, 19, 1, token.Literal, "1"),
+												},
+												Then: token.New(1, 22, 21, 4, token.KeywordThen, "THEN"),
+												Expr2: &ast.Expr{
+													LiteralValue: token.New(1, 27, 26, 5, token.Literal, "'one'"),
+												},
+											},
+										},
+										Else: token.New(1, 33, 32, 4, token.KeywordElse, "ELSE"),
+										Expr4: &ast.Expr{
+											LiteralValue: token.New(1, 38, 37, 7, token.Literal, "'other'"),
+										},
+										End: token.New(1, 46, 45, 3, token.KeywordEnd, "END"),
+									},
+								},
+							},
+							From: token.New(1, 50, 49, 4, token.KeywordFrom, "FROM"),
+							JoinClause: &ast.JoinClause{
+								TableOrSubquery: &ast.TableOrSubquery{
+									TableName: token.New(1, 55, 54, 1, token.Literal, "t"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 	for _, input := range inputs {
 		t.Run(input.Name, func(t *testing.T) {
@@ -206,12 +206,100 @@ func TestRuleBasedScanner(t *testing.T) {
 				token.New(1, 9, 8, 0, token.EOF, ""),
 			},
 		},
+		{
+			"CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP CURRENT_DATEX",
+			ruleset.Default,
+			[]token.Token{
+				token.New(1, 1, 0, 12, token.KeywordCurrentDate, "CURRENT_DATE"),
+				token.New(1, 14, 13, 12, token.KeywordCurrentTime, "CURRENT_TIME"),
+				token.New(1, 27, 26, 17, token.KeywordCurrentTimestamp, "CURRENT_TIMESTAMP"),
+				token.New(1, 45, 44, 13, token.Literal, "CURRENT_DATEX"),
+				token.New(1, 58, 57, 0, token.EOF, ""),
+			},
+		},
+		{
+			"my_table _x a__b",
+			ruleset.Default,
+			[]token.Token{
+				token.New(1, 1, 0, 8, token.Literal, "my_table"),
+				token.New(1, 10, 9, 2, token.Literal, "_x"),
+				token.New(1, 13, 12, 4, token.Literal, "a__b"),
+				token.New(1, 17, 16, 0, token.EOF, ""),
+			},
+		},
+		{
+			"1.5 0x1F 1E10 .5 -7",
+			ruleset.Default,
+			[]token.Token{
+				token.New(1, 1, 0, 3, token.Literal, "1.5"),
+				token.New(1, 5, 4, 4, token.Literal, "0x1F"),
+				token.New(1, 10, 9, 4, token.Literal, "1E10"),
+				token.New(1, 15, 14, 2, token.Literal, ".5"),
+				token.New(1, 18, 17, 1, token.UnaryOperator, "-"),
+				token.New(1, 19, 18, 1, token.Literal, "7"),
+				token.New(1, 20, 19, 0, token.EOF, ""),
+			},
+		},
+		{
+			"PRAGMA query_memory",
+			ruleset.Default,
+			[]token.Token{
+				token.New(1, 1, 0, 6, token.KeywordPragma, "PRAGMA"),
+				token.New(1, 8, 7, 12, token.Literal, "query_memory"),
+				token.New(1, 20, 19, 0, token.EOF, ""),
+			},
+		},
 	}
 	for _, input := range inputs {
 		t.Run("ruleset=default/"+input.query, _TestRuleBasedScannerWithRuleset(input.query, input.ruleset, input.want))
 	}
 }
 
+func TestRuleBasedScannerUnderscoreDelimited(t *testing.T) {
+	// underscores are part of a literal, but don't swallow any of the tokens
+	// that can follow it
+	tokens := []struct {
+		value string
+		typ   token.Type
+	}{
+		{";", token.StatementSeparator},
+		{"(", token.Delimiter},
+		{")", token.Delimiter},
+		{",", token.Delimiter},
+		{"+", token.UnaryOperator},
+		{"-", token.UnaryOperator},
+		{"~", token.UnaryOperator},
+		{"||", token.BinaryOperator},
+		{"*", token.BinaryOperator},
+		{"/", token.BinaryOperator},
+		{"%", token.BinaryOperator},
+		{"<<", token.BinaryOperator},
+		{">>", token.BinaryOperator},
+		{"&", token.BinaryOperator},
+		{"|", token.BinaryOperator},
+		{"<", token.BinaryOperator},
+		{"<=", token.BinaryOperator},
+		{">", token.BinaryOperator},
+		{">=", token.BinaryOperator},
+		{"=", token.BinaryOperator},
+		{"==", token.BinaryOperator},
+		{"!=", token.BinaryOperator},
+		{"<>", token.BinaryOperator},
+		{"'a_b'", token.Literal},
+		{"\"a_b\"", token.Literal},
+	}
+	for _, tok := range tokens {
+		query := "a_" + tok.value + "_b"
+		n := len(tok.value)
+		t.Run(query, _TestRuleBasedScannerWithRuleset(query, ruleset.Default, []token.Token{
+			token.New(1, 1, 0, 2, token.Literal, "a_"),
+			token.New(1, 3, 2, n, tok.typ, tok.value),
+			token.New(1, 3+n, 2+n, 2, token.Literal, "_b"),
+			token.New(1, 5+n, 4+n, 0, token.EOF, ""),
+		}))
+	}
+}
+
 func _TestRuleBasedScannerWithRuleset(input string, ruleset ruleset.Ruleset, want []token.Token) func(*testing.T) {
 	return func(t *testing.T) {
 		assert := assert.New(t)
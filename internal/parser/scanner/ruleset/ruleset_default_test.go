@@ -0,0 +1,53 @@
+package ruleset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// runeScanner is a RuneScanner over a fixed input.
+type runeScanner struct {
+	input []rune
+	pos   int
+}
+
+func (s *runeScanner) Lookahead() (rune, bool) {
+	if s.pos >= len(s.input) {
+		return 0, false
+	}
+	return s.input[s.pos], true
+}
+
+func (s *runeScanner) ConsumeRune() { s.pos++ }
+
+// apply applies the rules of the default ruleset to the input like the rule
+// based scanner does, and returns the type of the first token and the amount
+// of runes that it consumed.
+func apply(input string) (token.Type, int) {
+	s := &runeScanner{input: []rune(input)}
+	for _, rule := range Default.Rules {
+		s.pos = 0
+		if typ, ok := rule.Apply(s); ok {
+			return typ, s.pos
+		}
+	}
+	return token.Error, 0
+}
+
+func TestDefaultKeywords(t *testing.T) {
+	for keyword, typ := range defaultKeywords {
+		// every keyword is scanned as a whole, also if it is followed by a
+		// delimiter
+		for _, input := range []string{keyword, keyword + "(", keyword + ";"} {
+			gotType, gotLength := apply(input)
+			assert.Equal(t, typ, gotType, input)
+			assert.Equal(t, len(keyword), gotLength, input)
+		}
+		// with an underscore, a keyword is a literal
+		gotType, gotLength := apply("_" + keyword)
+		assert.Equal(t, token.Literal, gotType, keyword)
+		assert.Equal(t, len(keyword)+1, gotLength, keyword)
+	}
+}
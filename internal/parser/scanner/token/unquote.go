@@ -0,0 +1,26 @@
+package token
+
+import "strings"
+
+// Unquote returns the value of the given identifier or string literal token
+// without its quotes. Doubled quotes inside a quoted value are replaced by a
+// single quote. Unquoted values are returned as they are, and a nil token
+// yields an empty string.
+func Unquote(tk Token) string {
+	if tk == nil {
+		return ""
+	}
+	v := tk.Value()
+	if len(v) < 2 {
+		return v
+	}
+	switch first, last := v[0], v[len(v)-1]; {
+	case first == '\'' && last == '\'':
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+	case first == '"' && last == '"':
+		return strings.ReplaceAll(v[1:len(v)-1], `""`, `"`)
+	case first == '`' && last == '`', first == '[' && last == ']':
+		return v[1 : len(v)-1]
+	}
+	return v
+}
@@ -0,0 +1,27 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"x", "x"},
+		{"", ""},
+		{`'it''s'`, "it's"},
+		{`"a""b"`, `a"b`},
+		{"`a b`", "a b"},
+		{"[a b]", "a b"},
+		{`'unterminated`, `'unterminated`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Unquote(New(1, 1, 0, len(tt.value), Literal, tt.value)), tt.value)
+	}
+	assert.Equal(t, "", Unquote(nil))
+}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/tomarrell/lbadd/internal/parser/ast"
 	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
 )
@@ -26,11 +28,23 @@ func (p *simpleParser) parseSQLStatement(r reporter) (stmt *ast.SQLStmt) {
 				// assume that the user meant to input 'EXPLAIN <statement>'
 				// instead of 'EXPLAIN QUERY PLAN <statement>'.
 			}
+		} else if ok && next.Type() == token.KeywordAnalyze {
+			p.consumeToken()
+
+			// 'EXPLAIN ANALYZE <statement>' runs the statement, but
+			// 'EXPLAIN ANALYZE [schema-name.]table-or-index-name' explains
+			// an ANALYZE statement.
+			if !p.startsStatement(r) {
+				stmt.AnalyzeStmt = p.parseAnalyzeStmtArguments(r, next)
+				p.parseStatementEnd(r)
+				return
+			}
+			stmt.Analyze = next
 		}
 	}
 
 	// according to the grammar, these are the tokens that initiate a statement
-	p.searchNext(r, token.StatementSeparator, token.EOF, token.KeywordAlter, token.KeywordAnalyze, token.KeywordAttach, token.KeywordBegin, token.KeywordCommit, token.KeywordCreate, token.KeywordDelete, token.KeywordDetach, token.KeywordDrop, token.KeywordEnd, token.KeywordInsert, token.KeywordPragma, token.KeywordReindex, token.KeywordRelease, token.KeywordRollback, token.KeywordSavepoint, token.KeywordSelect, token.KeywordUpdate, token.KeywordVacuum)
+	p.searchNext(r, token.StatementSeparator, token.EOF, token.KeywordAlter, token.KeywordAnalyze, token.KeywordAttach, token.KeywordBegin, token.KeywordCommit, token.KeywordCreate, token.KeywordDelete, token.KeywordDetach, token.KeywordDrop, token.KeywordEnd, token.KeywordInsert, token.KeywordPragma, token.KeywordReindex, token.KeywordRelease, token.KeywordRollback, token.KeywordSavepoint, token.KeywordSelect, token.KeywordUpdate, token.KeywordVacuum, token.KeywordWith, token.KeywordValues, token.KeywordReplace)
 
 	next, ok := p.unsafeLowLevelLookahead()
 	if !ok {
@@ -52,28 +66,67 @@ func (p *simpleParser) parseSQLStatement(r reporter) (stmt *ast.SQLStmt) {
 		stmt.CommitStmt = p.parseCommitStmt(r)
 	case token.KeywordCreate:
 		p.parseCreateStmt(stmt, r)
+	case token.KeywordDelete:
+		stmt.DeleteStmt = p.parseDeleteStmt(r)
 	case token.KeywordDetach:
 		stmt.DetachStmt = p.parseDetachDatabaseStmt(r)
+	case token.KeywordDrop:
+		p.parseDropStmt(stmt, r)
 	case token.KeywordEnd:
 		stmt.CommitStmt = p.parseCommitStmt(r)
+	case token.KeywordInsert, token.KeywordReplace:
+		stmt.InsertStmt = p.parseInsertStmt(r)
+	case token.KeywordReindex:
+		stmt.ReindexStmt = p.parseReindexStmt(r)
+	case token.KeywordRelease:
+		stmt.ReleaseStmt = p.parseReleaseStmt(r)
 	case token.KeywordRollback:
 		stmt.RollbackStmt = p.parseRollbackStmt(r)
+	case token.KeywordSavepoint:
+		stmt.SavepointStmt = p.parseSavepointStmt(r)
+	case token.KeywordSelect, token.KeywordValues, token.KeywordWith:
+		stmt.SelectStmt = p.parseSelectStmt(r)
+	case token.KeywordUpdate:
+		stmt.UpdateStmt = p.parseUpdateStmt(r)
 	case token.KeywordVacuum:
 		stmt.VacuumStmt = p.parseVacuumStmt(r)
 	case token.StatementSeparator:
 		r.incompleteStatement()
 		p.consumeToken()
 	case token.KeywordPragma:
-		// we don't support pragmas, as we don't need them yet
-		r.unsupportedConstruct(next)
-		p.skipUntil(token.StatementSeparator, token.EOF)
+		stmt.PragmaStmt = p.parsePragmaStmt(r)
 	default:
 		r.unsupportedConstruct(next)
 		p.skipUntil(token.StatementSeparator, token.EOF)
 	}
 
+	p.parseStatementEnd(r)
+	return
+}
+
+// statementKeywords are the keywords, that a statement can start with.
+var statementKeywords = []token.Type{token.KeywordAlter, token.KeywordAnalyze, token.KeywordAttach, token.KeywordBegin, token.KeywordCommit, token.KeywordCreate, token.KeywordDelete, token.KeywordDetach, token.KeywordDrop, token.KeywordEnd, token.KeywordInsert, token.KeywordPragma, token.KeywordReindex, token.KeywordRelease, token.KeywordRollback, token.KeywordSavepoint, token.KeywordSelect, token.KeywordUpdate, token.KeywordVacuum, token.KeywordWith, token.KeywordValues, token.KeywordReplace}
+
+// startsStatement reports whether the next token is a keyword, that a
+// statement can start with. The token is not consumed.
+func (p *simpleParser) startsStatement(r reporter) bool {
+	next, ok := p.optionalLookahead(r)
+	if !ok {
+		return false
+	}
+	for _, typ := range statementKeywords {
+		if next.Type() == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatementEnd consumes the statement separator after a statement, and
+// the EOF if it follows.
+func (p *simpleParser) parseStatementEnd(r reporter) {
 	p.searchNext(r, token.StatementSeparator, token.EOF)
-	next, ok = p.unsafeLowLevelLookahead()
+	next, ok := p.unsafeLowLevelLookahead()
 	if !ok {
 		return
 	}
@@ -89,9 +142,7 @@ func (p *simpleParser) parseSQLStatement(r reporter) (stmt *ast.SQLStmt) {
 	}
 	if next.Type() == token.EOF {
 		p.consumeToken()
-		return
 	}
-	return
 }
 
 func (p *simpleParser) parseAlterTableStmt(r reporter) (stmt *ast.AlterTableStmt) {
@@ -146,6 +197,11 @@ func (p *simpleParser) parseAlterTableStmt(r reporter) (stmt *ast.AlterTableStmt
 			stmt.TableName = tableName
 			p.consumeToken()
 		}
+
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
 	} else {
 		stmt.TableName = schemaOrTableName
 	}
@@ -249,6 +305,32 @@ func (p *simpleParser) parseAlterTableStmt(r reporter) (stmt *ast.AlterTableStmt
 		default:
 			r.unexpectedToken(token.KeywordColumn, token.Literal)
 		}
+	case token.KeywordDrop:
+		stmt.Drop = next
+		p.consumeToken()
+
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Type() == token.KeywordColumn {
+			stmt.Column = next
+			p.consumeToken()
+
+			next, ok = p.lookahead(r)
+			if !ok {
+				return
+			}
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			p.consumeToken()
+			return
+		}
+		stmt.ColumnName = next
+		p.consumeToken()
+	default:
+		r.unexpectedToken(token.KeywordRename, token.KeywordAdd, token.KeywordDrop)
 	}
 
 	return
@@ -261,7 +343,9 @@ func (p *simpleParser) parseColumnDef(r reporter) (def *ast.ColumnDef) {
 		def.ColumnName = next
 		p.consumeToken()
 
-		if next, ok = p.lookahead(r); ok && next.Type() == token.Literal {
+		// the column definition of ALTER TABLE ... ADD COLUMN may end the
+		// statement
+		if next, ok = p.optionalLookahead(r); ok && next.Type() == token.Literal {
 			def.TypeName = p.parseTypeName(r)
 		}
 
@@ -299,7 +383,7 @@ func (p *simpleParser) parseTypeName(r reporter) (name *ast.TypeName) {
 		r.unexpectedToken(token.Literal)
 	}
 	for {
-		if next, ok := p.lookahead(r); ok && next.Type() == token.Literal {
+		if next, ok := p.optionalLookahead(r); ok && next.Type() == token.Literal {
 			name.Name = append(name.Name, next)
 			p.consumeToken()
 		} else {
@@ -307,15 +391,13 @@ func (p *simpleParser) parseTypeName(r reporter) (name *ast.TypeName) {
 		}
 	}
 
-	if next, ok := p.lookahead(r); ok && next.Type() == token.Delimiter {
-		if next.Value() == "(" {
-			name.LeftParen = next
-			p.consumeToken()
+	// the type name may be followed by a closing paren or a comma, like in
+	// CAST(a AS INTEGER) or a column definition
+	if next, ok := p.optionalLookahead(r); ok && isDelimiter(next, "(") {
+		name.LeftParen = next
+		p.consumeToken()
 
-			name.SignedNumber1 = p.parseSignedNumber(r)
-		} else {
-			r.unexpectedToken(token.Delimiter)
-		}
+		name.SignedNumber1 = p.parseSignedNumber(r)
 	} else {
 		return
 	}
@@ -425,7 +507,7 @@ func (p *simpleParser) parseColumnConstraint(r reporter) (constr *ast.ColumnCons
 		}
 
 		// ASC, DESC
-		next, ok = p.lookahead(r)
+		next, ok = p.optionalLookahead(r)
 		if !ok {
 			return
 		}
@@ -536,10 +618,51 @@ func (p *simpleParser) parseColumnConstraint(r reporter) (constr *ast.ColumnCons
 		constr.Default = next
 		p.consumeToken()
 
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		switch {
+		case next.Type() == token.Delimiter && next.Value() == "(":
+			constr.LeftParen = next
+			p.consumeToken()
+			constr.Expr = p.parseExpression(r)
+
+			next, ok = p.lookahead(r)
+			if !ok {
+				return
+			}
+			if next.Value() == ")" {
+				constr.RightParen = next
+				p.consumeToken()
+			} else {
+				r.unexpectedSingleRuneToken(token.Delimiter, ')')
+			}
+		case next.Type() == token.UnaryOperator:
+			constr.SignedNumber = p.parseSignedNumber(r)
+		case next.Type() == token.Literal, next.Type() == token.KeywordNull,
+			next.Type() == token.KeywordCurrentTime, next.Type() == token.KeywordCurrentDate, next.Type() == token.KeywordCurrentTimestamp:
+			constr.LiteralValue = next
+			p.consumeToken()
+		default:
+			r.unexpectedToken(token.Literal, token.UnaryOperator, token.KeywordNull)
+		}
+
 	case token.KeywordCollate:
 		constr.Collate = next
 		p.consumeToken()
 
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Type() == token.Literal {
+			constr.CollationName = next
+			p.consumeToken()
+		} else {
+			r.unexpectedToken(token.Literal)
+		}
+
 	case token.KeywordGenerated:
 		constr.Generated = next
 		p.consumeToken()
@@ -553,17 +676,171 @@ func (p *simpleParser) parseColumnConstraint(r reporter) (constr *ast.ColumnCons
 	return
 }
 
-// parseForeignKeyClause is not implemented yet and will always result in an
-// unsupported construct error.
+// parseForeignKeyClause parses a foreign key clause as defined in the spec:
+// https://sqlite.org/syntax/foreign-key-clause.html
 func (p *simpleParser) parseForeignKeyClause(r reporter) (clause *ast.ForeignKeyClause) {
 	clause = &ast.ForeignKeyClause{}
 
+	if clause.References = p.parseKeyword(r, token.KeywordReferences); clause.References == nil {
+		return
+	}
 	next, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	r.unsupportedConstruct(next)
-	p.searchNext(r, token.StatementSeparator, token.EOF)
+	if next.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	clause.ForeignTable = next
+	p.consumeToken()
+
+	// the clause may be the last part of a column definition, which may be
+	// the last token of an ALTER TABLE statement
+	next, ok = p.optionalLookahead(r)
+	if !ok {
+		return
+	}
+	if isDelimiter(next, "(") {
+		clause.LeftParen = next
+		p.consumeToken()
+		clause.ColumnName = p.parseNameList(r)
+		if clause.RightParen = p.parseDelimiter(r, ')'); clause.RightParen == nil {
+			return
+		}
+		if next, ok = p.optionalLookahead(r); !ok {
+			return
+		}
+	}
+
+	for next.Type() == token.KeywordOn || next.Type() == token.KeywordMatch {
+		core := p.parseForeignKeyClauseCore(r)
+		clause.ForeignKeyClauseCore = append(clause.ForeignKeyClauseCore, core)
+		if core.Name == nil && core.Action == nil && core.Null == nil && core.Default == nil && core.Cascade == nil && core.Restrict == nil {
+			return
+		}
+		if next, ok = p.optionalLookahead(r); !ok {
+			return
+		}
+	}
+
+	if next.Type() == token.KeywordNot {
+		clause.Not = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.KeywordDeferrable {
+			r.unexpectedToken(token.KeywordDeferrable)
+			return
+		}
+	}
+	if next.Type() != token.KeywordDeferrable {
+		return
+	}
+	clause.Deferrable = next
+	p.consumeToken()
+
+	if next, ok = p.optionalLookahead(r); !ok || next.Type() != token.KeywordInitially {
+		return
+	}
+	clause.Initially = next
+	p.consumeToken()
+	if next, ok = p.lookahead(r); !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordDeferred:
+		clause.Deferred = next
+	case token.KeywordImmediate:
+		clause.Immediate = next
+	default:
+		r.unexpectedToken(token.KeywordDeferred, token.KeywordImmediate)
+		return
+	}
+	p.consumeToken()
+	return
+}
+
+// parseForeignKeyClauseCore parses a single ON DELETE, ON UPDATE or MATCH
+// clause of a foreign key clause.
+func (p *simpleParser) parseForeignKeyClauseCore(r reporter) (core *ast.ForeignKeyClauseCore) {
+	core = &ast.ForeignKeyClauseCore{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordMatch {
+		core.Match = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+		core.Name = next
+		p.consumeToken()
+		return
+	}
+
+	core.On = next
+	p.consumeToken()
+	if next, ok = p.lookahead(r); !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordDelete:
+		core.Delete = next
+	case token.KeywordUpdate:
+		core.Update = next
+	default:
+		r.unexpectedToken(token.KeywordDelete, token.KeywordUpdate)
+		return
+	}
+	p.consumeToken()
+
+	if next, ok = p.lookahead(r); !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordSet:
+		core.Set = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		switch next.Type() {
+		case token.KeywordNull:
+			core.Null = next
+		case token.KeywordDefault:
+			core.Default = next
+		default:
+			r.unexpectedToken(token.KeywordNull, token.KeywordDefault)
+			return
+		}
+	case token.KeywordCascade:
+		core.Cascade = next
+	case token.KeywordRestrict:
+		core.Restrict = next
+	case token.KeywordNo:
+		core.No = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.KeywordAction {
+			r.unexpectedToken(token.KeywordAction)
+			return
+		}
+		core.Action = next
+	default:
+		r.unexpectedToken(token.KeywordSet, token.KeywordCascade, token.KeywordRestrict, token.KeywordNo)
+		return
+	}
+	p.consumeToken()
 	return
 }
 
@@ -625,25 +902,6 @@ func (p *simpleParser) parseConflictClause(r reporter) (clause *ast.ConflictClau
 	return
 }
 
-// parseExpression is not implemented yet and will always result in an
-// unsupported construct error.
-func (p *simpleParser) parseExpression(r reporter) (expr *ast.Expr) {
-	expr = &ast.Expr{}
-
-	next, ok := p.lookahead(r)
-	if !ok {
-		return
-	}
-	if next.Type() == token.Literal {
-		expr.LiteralValue = next
-		p.consumeToken()
-	} else {
-		r.unsupportedConstruct(next)
-		p.searchNext(r, token.StatementSeparator, token.EOF)
-	}
-	return
-}
-
 // parseAttachDatabaseStmt parses a single ATTACH statement as defined in the spec:
 // https://sqlite.org/lang_attach.html
 func (p *simpleParser) parseAttachDatabaseStmt(r reporter) (stmt *ast.AttachStmt) {
@@ -725,71 +983,176 @@ func (p *simpleParser) parseDetachDatabaseStmt(r reporter) (stmt *ast.DetachStmt
 	return
 }
 
-// parseVacuumStmt parses a single VACUUM statement as defined in the spec:
-// https://sqlite.org/lang_vacuum.html
-func (p *simpleParser) parseVacuumStmt(r reporter) (stmt *ast.VacuumStmt) {
-	stmt = &ast.VacuumStmt{}
-	p.searchNext(r, token.KeywordVacuum)
+// parsePragmaStmt parses a single PRAGMA statement as defined in the spec:
+// https://sqlite.org/pragma.html#syntax
+func (p *simpleParser) parsePragmaStmt(r reporter) (stmt *ast.PragmaStmt) {
+	stmt = &ast.PragmaStmt{}
+	p.searchNext(r, token.KeywordPragma)
 	next, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	stmt.Vacuum = next
+	stmt.Pragma = next
 	p.consumeToken()
 
-	// optionalLookahead is used because, the lookahead function
-	// always looks for the next "real" token and not EOF.
-	// Since Just "VACUUM" is a valid statement, we have to accept
-	// the fact that there can be no tokens after the first keyword.
-	// Same logic is applied for the next INTO keyword check too.
-	next, ok = p.optionalLookahead(r)
+	schemaOrPragmaName, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	if next.Type() == token.Literal {
-		stmt.SchemaName = next
-		p.consumeToken()
+	if schemaOrPragmaName.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
 	}
+	p.consumeToken()
 
 	next, ok = p.optionalLookahead(r)
-	if !ok {
+	if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
+		stmt.PragmaName = schemaOrPragmaName
 		return
 	}
-	if next.Type() == token.KeywordInto {
-		stmt.Into = next
+	if next.Value() == "." {
+		stmt.SchemaName = schemaOrPragmaName
+		stmt.Period = next
 		p.consumeToken()
-		fileName, ok := p.lookahead(r)
+
+		pragmaName, ok := p.lookahead(r)
 		if !ok {
 			return
 		}
-		if fileName.Type() != token.Literal {
+		if pragmaName.Type() != token.Literal {
 			r.unexpectedToken(token.Literal)
 			return
 		}
-		stmt.Filename = fileName
+		stmt.PragmaName = pragmaName
+		p.consumeToken()
+
+		next, ok = p.optionalLookahead(r)
+		if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
+			return
+		}
+	} else {
+		stmt.PragmaName = schemaOrPragmaName
+	}
+
+	switch {
+	case next.Type() == token.BinaryOperator && next.Value() == "=":
+		stmt.Equal = next
+		p.consumeToken()
+		stmt.PragmaValue = p.parsePragmaValue(r)
+	case next.Type() == token.Delimiter && next.Value() == "(":
+		stmt.LeftParen = next
+		p.consumeToken()
+		stmt.PragmaValue = p.parsePragmaValue(r)
+
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Value() != ")" {
+			r.unexpectedSingleRuneToken(token.Delimiter, ')')
+			return
+		}
+		stmt.RightParen = next
 		p.consumeToken()
+	default:
+		r.unexpectedToken(token.BinaryOperator, token.Delimiter)
 	}
 	return
 }
 
-// parseAnalyzeStmt parses a single ANALYZE statement as defined in the spec:
-// https://sqlite.org/lang_analyze.html
-func (p *simpleParser) parseAnalyzeStmt(r reporter) (stmt *ast.AnalyzeStmt) {
-	stmt = &ast.AnalyzeStmt{}
-	p.searchNext(r, token.KeywordAnalyze)
+// parsePragmaValue parses the value of a PRAGMA statement, which is a signed
+// number, a name or a string literal. Keywords like ON, that are commonly
+// used as values, are accepted as names.
+func (p *simpleParser) parsePragmaValue(r reporter) (value *ast.PragmaValue) {
+	value = &ast.PragmaValue{}
 	next, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	stmt.Analyze = next
-	p.consumeToken()
-
-	// optionalLookahead is used, because ANALYZE alone is a valid statement
-	next, ok = p.optionalLookahead(r)
-	if !ok || next.Type() == token.EOF {
-		return
+	switch next.Type() {
+	case token.UnaryOperator:
+		value.SignedNumber = p.parseSignedNumber(r)
+	case token.BinaryOperator, token.Delimiter, token.StatementSeparator, token.EOF:
+		r.unexpectedToken(token.Literal)
+	default:
+		value.Name = next
+		p.consumeToken()
 	}
-	if next.Type() == token.Literal {
+	return
+}
+
+// parseVacuumStmt parses a single VACUUM statement as defined in the spec:
+// https://sqlite.org/lang_vacuum.html
+func (p *simpleParser) parseVacuumStmt(r reporter) (stmt *ast.VacuumStmt) {
+	stmt = &ast.VacuumStmt{}
+	p.searchNext(r, token.KeywordVacuum)
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	stmt.Vacuum = next
+	p.consumeToken()
+
+	// optionalLookahead is used because, the lookahead function
+	// always looks for the next "real" token and not EOF.
+	// Since Just "VACUUM" is a valid statement, we have to accept
+	// the fact that there can be no tokens after the first keyword.
+	// Same logic is applied for the next INTO keyword check too.
+	next, ok = p.optionalLookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.Literal {
+		stmt.SchemaName = next
+		p.consumeToken()
+	}
+
+	next, ok = p.optionalLookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordInto {
+		stmt.Into = next
+		p.consumeToken()
+		fileName, ok := p.lookahead(r)
+		if !ok {
+			return
+		}
+		if fileName.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+		stmt.Filename = fileName
+		p.consumeToken()
+	}
+	return
+}
+
+// parseAnalyzeStmt parses a single ANALYZE statement as defined in the spec:
+// https://sqlite.org/lang_analyze.html
+func (p *simpleParser) parseAnalyzeStmt(r reporter) (stmt *ast.AnalyzeStmt) {
+	stmt = &ast.AnalyzeStmt{}
+	p.searchNext(r, token.KeywordAnalyze)
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	p.consumeToken()
+	return p.parseAnalyzeStmtArguments(r, next)
+}
+
+// parseAnalyzeStmtArguments parses the rest of an ANALYZE statement, whose
+// ANALYZE keyword has already been consumed.
+func (p *simpleParser) parseAnalyzeStmtArguments(r reporter, analyze token.Token) (stmt *ast.AnalyzeStmt) {
+	stmt = &ast.AnalyzeStmt{}
+	stmt.Analyze = analyze
+
+	// optionalLookahead is used, because ANALYZE alone is a valid statement
+	next, ok := p.optionalLookahead(r)
+	if !ok || next.Type() == token.EOF {
+		return
+	}
+	if next.Type() == token.Literal {
 		stmt.SchemaName = next
 		stmt.TableOrIndexName = next
 		p.consumeToken()
@@ -941,6 +1304,65 @@ func (p *simpleParser) parseRollbackStmt(r reporter) (stmt *ast.RollbackStmt) {
 	return
 }
 
+// parseSavepointStmt parses a single SAVEPOINT statement as defined in the
+// spec: https://sqlite.org/lang_savepoint.html
+func (p *simpleParser) parseSavepointStmt(r reporter) (stmt *ast.SavepointStmt) {
+	stmt = &ast.SavepointStmt{}
+	p.searchNext(r, token.KeywordSavepoint)
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	stmt.Savepoint = next
+	p.consumeToken()
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	stmt.SavepointName = next
+	p.consumeToken()
+	return
+}
+
+// parseReleaseStmt parses a single RELEASE statement as defined in the spec:
+// https://sqlite.org/lang_savepoint.html
+func (p *simpleParser) parseReleaseStmt(r reporter) (stmt *ast.ReleaseStmt) {
+	stmt = &ast.ReleaseStmt{}
+	p.searchNext(r, token.KeywordRelease)
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	stmt.Release = next
+	p.consumeToken()
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordSavepoint {
+		stmt.Savepoint = next
+		p.consumeToken()
+
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+	}
+	if next.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	stmt.SavepointName = next
+	p.consumeToken()
+	return
+}
+
 // parseCreateStmt looks ahead for the tokens and decides which function gets to parse the statement
 func (p *simpleParser) parseCreateStmt(stmt *ast.SQLStmt, r reporter) {
 	p.searchNext(r, token.KeywordCreate)
@@ -999,6 +1421,187 @@ func (p *simpleParser) parseCreateStmt(stmt *ast.SQLStmt, r reporter) {
 	}
 }
 
+func (p *simpleParser) parseDropStmt(stmt *ast.SQLStmt, r reporter) {
+	p.searchNext(r, token.KeywordDrop)
+	dropToken, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	p.consumeToken()
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordTable:
+		stmt.DropTableStmt = p.parseDropTableStmt(dropToken, r)
+	case token.KeywordIndex:
+		stmt.DropIndexStmt = p.parseDropIndexStmt(dropToken, r)
+	case token.KeywordTrigger:
+		stmt.DropTriggerStmt = p.parseDropTriggerStmt(dropToken, r)
+	case token.KeywordView:
+		stmt.DropViewStmt = p.parseDropViewStmt(dropToken, r)
+	default:
+		r.unsupportedConstruct(next)
+		p.searchNext(r, token.StatementSeparator, token.EOF)
+	}
+}
+
+// parseDropTableStmt parses a single DROP TABLE statement as defined in the
+// spec:
+// https://sqlite.org/lang_droptable.html
+func (p *simpleParser) parseDropTableStmt(dropToken token.Token, r reporter) (stmt *ast.DropTableStmt) {
+	stmt = &ast.DropTableStmt{
+		Drop: dropToken,
+	}
+	if stmt.Table = p.parseKeyword(r, token.KeywordTable); stmt.Table == nil {
+		return
+	}
+	if !p.parseIfExists(r, &stmt.If, &stmt.Exists) {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.TableName)
+	return
+}
+
+// parseDropIndexStmt parses a single DROP INDEX statement as defined in the
+// spec:
+// https://sqlite.org/lang_dropindex.html
+func (p *simpleParser) parseDropIndexStmt(dropToken token.Token, r reporter) (stmt *ast.DropIndexStmt) {
+	stmt = &ast.DropIndexStmt{
+		Drop: dropToken,
+	}
+	if stmt.Index = p.parseKeyword(r, token.KeywordIndex); stmt.Index == nil {
+		return
+	}
+	if !p.parseIfExists(r, &stmt.If, &stmt.Exists) {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.IndexName)
+	return
+}
+
+// parseDropTriggerStmt parses a single DROP TRIGGER statement as defined in
+// the spec:
+// https://sqlite.org/lang_droptrigger.html
+func (p *simpleParser) parseDropTriggerStmt(dropToken token.Token, r reporter) (stmt *ast.DropTriggerStmt) {
+	stmt = &ast.DropTriggerStmt{
+		Drop: dropToken,
+	}
+	if stmt.Trigger = p.parseKeyword(r, token.KeywordTrigger); stmt.Trigger == nil {
+		return
+	}
+	if !p.parseIfExists(r, &stmt.If, &stmt.Exists) {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.TriggerName)
+	return
+}
+
+// parseReindexStmt parses a single REINDEX statement as defined in the spec:
+// https://sqlite.org/lang_reindex.html
+// An unqualified name may be the name of a collation or of a table or index,
+// like for ANALYZE, so it is set as both.
+func (p *simpleParser) parseReindexStmt(r reporter) (stmt *ast.ReindexStmt) {
+	stmt = &ast.ReindexStmt{}
+	p.searchNext(r, token.KeywordReindex)
+	if stmt.Reindex = p.parseKeyword(r, token.KeywordReindex); stmt.Reindex == nil {
+		return
+	}
+	// optionalLookahead is used, because REINDEX alone is a valid statement
+	next, ok := p.optionalLookahead(r)
+	if !ok || next.Type() == token.EOF || next.Type() == token.StatementSeparator {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.TableOrIndexName)
+	if stmt.SchemaName == nil {
+		stmt.CollationName = stmt.TableOrIndexName
+	}
+	return
+}
+
+// parseIfExists parses IF EXISTS, if the next token is IF. It returns false,
+// if the clause is incomplete.
+func (p *simpleParser) parseIfExists(r reporter, ifToken, existsToken *token.Token) bool {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return false
+	}
+	if next.Type() != token.KeywordIf {
+		return true
+	}
+	*ifToken = next
+	p.consumeToken()
+	*existsToken = p.parseKeyword(r, token.KeywordExists)
+	return *existsToken != nil
+}
+
+// parseDropViewStmt parses a single DROP VIEW statement as defined in the spec:
+// https://sqlite.org/lang_dropview.html
+func (p *simpleParser) parseDropViewStmt(dropToken token.Token, r reporter) (stmt *ast.DropViewStmt) {
+	stmt = &ast.DropViewStmt{}
+	stmt.Drop = dropToken
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	stmt.View = next
+	p.consumeToken()
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordIf {
+		stmt.If = next
+		p.consumeToken()
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Type() == token.KeywordExists {
+			stmt.Exists = next
+			p.consumeToken()
+		} else {
+			r.unexpectedToken(token.KeywordExists)
+		}
+	}
+
+	schemaNameOrViewName, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if schemaNameOrViewName.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	// assume that there is no schema name, until a period follows
+	stmt.ViewName = schemaNameOrViewName
+	p.consumeToken()
+
+	// the view name may be the last token of the input, so an EOF is not an
+	// error here
+	next, ok = p.optionalLookahead(r)
+	if !ok || next.Value() != "." {
+		return
+	}
+	stmt.SchemaName = schemaNameOrViewName
+	stmt.Period = next
+	p.consumeToken()
+	viewName, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if viewName.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	stmt.ViewName = viewName
+	p.consumeToken()
+	return
+}
+
 // parseCreateIndexStmt parses a single CREATE INDEX statement as defined in the spec:
 // https://sqlite.org/lang_createindex.html
 func (p *simpleParser) parseCreateIndexStmt(createToken token.Token, r reporter) (stmt *ast.CreateIndexStmt) {
@@ -1142,80 +1745,418 @@ func (p *simpleParser) parseCreateIndexStmt(createToken token.Token, r reporter)
 	return
 }
 
+// parseIndexedColumn parses an indexed column as defined in the spec:
+// https://sqlite.org/syntax/indexed-column.html
+// A trailing COLLATE clause is part of the indexed column, not of the
+// expression, and a bare column name is parsed into ColumnName.
 func (p *simpleParser) parseIndexedColumn(r reporter) (stmt *ast.IndexedColumn) {
 	stmt = &ast.IndexedColumn{}
+
+	expr := p.parseExpression(r)
+	if expr.Collate != nil {
+		stmt.Collate = expr.Collate
+		stmt.CollationName = expr.CollationName
+		expr = expr.Expr1
+	}
+	if isColumnName(expr) {
+		stmt.ColumnName = expr.ColumnName
+	} else {
+		stmt.Expr = expr
+	}
+
+	next, ok := p.optionalLookahead(r)
+	if !ok || next.Type() == token.EOF {
+		return
+	}
+	if next.Type() == token.KeywordAsc {
+		stmt.Asc = next
+		p.consumeToken()
+	}
+	if next.Type() == token.KeywordDesc {
+		stmt.Desc = next
+		p.consumeToken()
+	}
+	return
+}
+
+// parseCreateTableStmt parses a single CREATE TABLE statement as defined in
+// the spec:
+// https://sqlite.org/lang_createtable.html
+func (p *simpleParser) parseCreateTableStmt(createToken, tempToken, temporaryToken token.Token, r reporter) (stmt *ast.CreateTableStmt) {
+	stmt = &ast.CreateTableStmt{
+		Create:    createToken,
+		Temp:      tempToken,
+		Temporary: temporaryToken,
+	}
+	if stmt.Table = p.parseKeyword(r, token.KeywordTable); stmt.Table == nil {
+		return
+	}
+	if !p.parseIfNotExists(r, &stmt.If, &stmt.Not, &stmt.Exists) {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.TableName)
+	if stmt.TableName == nil {
+		return
+	}
+
 	next, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	if next.Type() == token.Literal {
-		stmt.ColumnName = next
+	if next.Type() == token.KeywordAs {
+		stmt.As = next
 		p.consumeToken()
-	} else {
-		stmt.Expr = p.parseExpression(r)
+		stmt.SelectStmt = p.parseSelectStmt(r)
+		return
 	}
-
-	next, ok = p.optionalLookahead(r)
-	if !ok || next.Type() == token.EOF {
+	if stmt.LeftParen = p.parseDelimiter(r, '('); stmt.LeftParen == nil {
 		return
 	}
-	if next.Type() == token.KeywordCollate {
-		stmt.Collate = next
+
+	// column definitions come first, followed by the table constraints
+	for {
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if startsTableConstraint(next) {
+			break
+		}
+		stmt.ColumnDef = append(stmt.ColumnDef, p.parseColumnDef(r))
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if !isDelimiter(next, ",") {
+			break
+		}
 		p.consumeToken()
-		next, ok = p.lookahead(r)
-		if !ok {
+	}
+	for startsTableConstraint(next) {
+		stmt.TableConstraint = append(stmt.TableConstraint, p.parseTableConstraint(r))
+		if next, ok = p.lookahead(r); !ok {
 			return
 		}
-		if next.Type() == token.Literal {
-			stmt.CollationName = next
-			p.consumeToken()
-		} else {
-			r.unexpectedToken(token.Literal)
+		if !isDelimiter(next, ",") {
+			break
+		}
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
 		}
 	}
+	if stmt.RightParen = p.parseDelimiter(r, ')'); stmt.RightParen == nil {
+		return
+	}
 
-	next, ok = p.optionalLookahead(r)
-	if !ok || next.Type() == token.EOF {
+	if next, ok = p.optionalLookahead(r); !ok || next.Type() != token.KeywordWithout {
 		return
 	}
-	if next.Type() == token.KeywordAsc {
-		stmt.Asc = next
-		p.consumeToken()
+	stmt.Without = next
+	p.consumeToken()
+	if next, ok = p.lookahead(r); !ok {
+		return
 	}
-	if next.Type() == token.KeywordDesc {
-		stmt.Desc = next
-		p.consumeToken()
+	if next.Type() != token.Literal || !strings.EqualFold(next.Value(), "ROWID") {
+		r.unexpectedToken(token.Literal)
+		return
 	}
+	stmt.Rowid = next
+	p.consumeToken()
 	return
 }
 
-func (p *simpleParser) parseCreateTableStmt(createToken, tempToken, temporaryToken token.Token, r reporter) (stmt *ast.CreateTableStmt) {
+// startsTableConstraint reports whether the given token starts a table
+// constraint, rather than a column definition.
+func startsTableConstraint(tk token.Token) bool {
+	switch tk.Type() {
+	case token.KeywordConstraint, token.KeywordPrimary, token.KeywordUnique, token.KeywordCheck, token.KeywordForeign:
+		return true
+	}
+	return false
+}
+
+// parseTableConstraint parses a table constraint as defined in the spec:
+// https://sqlite.org/syntax/table-constraint.html
+func (p *simpleParser) parseTableConstraint(r reporter) (constr *ast.TableConstraint) {
+	constr = &ast.TableConstraint{}
+
 	next, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	r.unsupportedConstruct(next)
-	p.searchNext(r, token.StatementSeparator, token.EOF)
+	if next.Type() == token.KeywordConstraint {
+		constr.Constraint = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+		constr.Name = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+	}
+
+	switch next.Type() {
+	case token.KeywordPrimary, token.KeywordUnique:
+		if next.Type() == token.KeywordPrimary {
+			constr.Primary = next
+			p.consumeToken()
+			if constr.Key = p.parseKeyword(r, token.KeywordKey); constr.Key == nil {
+				return
+			}
+		} else {
+			constr.Unique = next
+			p.consumeToken()
+		}
+		if constr.LeftParen = p.parseDelimiter(r, '('); constr.LeftParen == nil {
+			return
+		}
+		for {
+			constr.IndexedColumn = append(constr.IndexedColumn, p.parseIndexedColumn(r))
+			if next, ok = p.lookahead(r); !ok {
+				return
+			}
+			if !isDelimiter(next, ",") {
+				break
+			}
+			p.consumeToken()
+		}
+		if constr.RightParen = p.parseDelimiter(r, ')'); constr.RightParen == nil {
+			return
+		}
+		if next, ok = p.optionalLookahead(r); ok && next.Type() == token.KeywordOn {
+			constr.ConflictClause = p.parseConflictClause(r)
+		}
+	case token.KeywordCheck:
+		constr.Check = next
+		p.consumeToken()
+		if constr.LeftParen = p.parseDelimiter(r, '('); constr.LeftParen == nil {
+			return
+		}
+		constr.Expr = p.parseExpression(r)
+		constr.RightParen = p.parseDelimiter(r, ')')
+	case token.KeywordForeign:
+		constr.Foreign = next
+		p.consumeToken()
+		if constr.Key = p.parseKeyword(r, token.KeywordKey); constr.Key == nil {
+			return
+		}
+		if constr.LeftParen = p.parseDelimiter(r, '('); constr.LeftParen == nil {
+			return
+		}
+		constr.ColumnName = p.parseNameList(r)
+		if constr.RightParen = p.parseDelimiter(r, ')'); constr.RightParen == nil {
+			return
+		}
+		constr.ForeignKeyClause = p.parseForeignKeyClause(r)
+	default:
+		r.unexpectedToken(token.KeywordPrimary, token.KeywordUnique, token.KeywordCheck, token.KeywordForeign)
+	}
 	return
 }
 
+// parseIfNotExists parses IF NOT EXISTS, if the next token is IF. It returns
+// false, if the clause is incomplete.
+func (p *simpleParser) parseIfNotExists(r reporter, ifToken, notToken, existsToken *token.Token) bool {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return false
+	}
+	if next.Type() != token.KeywordIf {
+		return true
+	}
+	*ifToken = next
+	p.consumeToken()
+	if *notToken = p.parseKeyword(r, token.KeywordNot); *notToken == nil {
+		return false
+	}
+	*existsToken = p.parseKeyword(r, token.KeywordExists)
+	return *existsToken != nil
+}
+
+// parseCreateTriggerStmt parses a single CREATE TRIGGER statement as defined
+// in the spec:
+// https://sqlite.org/lang_createtrigger.html
 func (p *simpleParser) parseCreateTriggerStmt(createToken, tempToken, temporaryToken token.Token, r reporter) (stmt *ast.CreateTriggerStmt) {
+	stmt = &ast.CreateTriggerStmt{
+		Create:    createToken,
+		Temp:      tempToken,
+		Temporary: temporaryToken,
+	}
+	if stmt.Trigger = p.parseKeyword(r, token.KeywordTrigger); stmt.Trigger == nil {
+		return
+	}
+	if !p.parseIfNotExists(r, &stmt.If, &stmt.Not, &stmt.Exists) {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.TriggerName)
+	if stmt.TriggerName == nil {
+		return
+	}
+
 	next, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	r.unsupportedConstruct(next)
-	p.searchNext(r, token.StatementSeparator, token.EOF)
-	return
+	switch next.Type() {
+	case token.KeywordBefore:
+		stmt.Before = next
+		p.consumeToken()
+	case token.KeywordAfter:
+		stmt.After = next
+		p.consumeToken()
+	case token.KeywordInstead:
+		stmt.Instead = next
+		p.consumeToken()
+		if stmt.Of1 = p.parseKeyword(r, token.KeywordOf); stmt.Of1 == nil {
+			return
+		}
+	}
+
+	if next, ok = p.lookahead(r); !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordDelete:
+		stmt.Delete = next
+		p.consumeToken()
+	case token.KeywordInsert:
+		stmt.Insert = next
+		p.consumeToken()
+	case token.KeywordUpdate:
+		stmt.Update = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); ok && next.Type() == token.KeywordOf {
+			stmt.Of2 = next
+			p.consumeToken()
+			if stmt.ColumnName = p.parseNameList(r); stmt.ColumnName == nil {
+				return
+			}
+		}
+	default:
+		r.unexpectedToken(token.KeywordDelete, token.KeywordInsert, token.KeywordUpdate)
+		return
+	}
+
+	if stmt.On = p.parseKeyword(r, token.KeywordOn); stmt.On == nil {
+		return
+	}
+	if next, ok = p.lookahead(r); !ok {
+		return
+	}
+	if next.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	stmt.TableName = next
+	p.consumeToken()
+
+	if next, ok = p.lookahead(r); !ok {
+		return
+	}
+	if next.Type() == token.KeywordFor {
+		stmt.For = next
+		p.consumeToken()
+		if stmt.Each = p.parseKeyword(r, token.KeywordEach); stmt.Each == nil {
+			return
+		}
+		if stmt.Row = p.parseKeyword(r, token.KeywordRow); stmt.Row == nil {
+			return
+		}
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+	}
+	if next.Type() == token.KeywordWhen {
+		stmt.When = next
+		p.consumeToken()
+		if stmt.Expr = p.parseExpression(r); stmt.Expr == nil {
+			return
+		}
+	}
+
+	if stmt.Begin = p.parseKeyword(r, token.KeywordBegin); stmt.Begin == nil {
+		return
+	}
+	for {
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() == token.KeywordEnd && len(stmt.TriggerStmt) > 0 {
+			stmt.End = next
+			p.consumeToken()
+			return
+		}
+		triggerStmt := &ast.TriggerStmt{}
+		switch next.Type() {
+		case token.KeywordUpdate:
+			triggerStmt.UpdateStmt = p.parseUpdateStmt(r)
+		case token.KeywordInsert, token.KeywordReplace:
+			triggerStmt.InsertStmt = p.parseInsertStmt(r)
+		case token.KeywordDelete:
+			triggerStmt.DeleteStmt = p.parseDeleteStmt(r)
+		case token.KeywordSelect, token.KeywordValues, token.KeywordWith:
+			triggerStmt.SelectStmt = p.parseSelectStmt(r)
+		default:
+			r.unexpectedToken(token.KeywordUpdate, token.KeywordInsert, token.KeywordReplace, token.KeywordDelete, token.KeywordSelect)
+			return
+		}
+		stmt.TriggerStmt = append(stmt.TriggerStmt, triggerStmt)
+		// the statements of the trigger are separated by statement
+		// separators, which lookahead reports as incomplete
+		if next, ok = p.optionalLookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.StatementSeparator {
+			r.unexpectedToken(token.StatementSeparator)
+			return
+		}
+		p.consumeToken()
+	}
 }
 
+// parseCreateViewStmt parses a single CREATE VIEW statement as defined in the
+// spec:
+// https://sqlite.org/lang_createview.html
 func (p *simpleParser) parseCreateViewStmt(createToken, tempToken, temporaryToken token.Token, r reporter) (stmt *ast.CreateViewStmt) {
+	stmt = &ast.CreateViewStmt{
+		Create:    createToken,
+		Temp:      tempToken,
+		Temporary: temporaryToken,
+	}
+	if stmt.View = p.parseKeyword(r, token.KeywordView); stmt.View == nil {
+		return
+	}
+	if !p.parseIfNotExists(r, &stmt.If, &stmt.Not, &stmt.Exists) {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.ViewName)
+	if stmt.ViewName == nil {
+		return
+	}
+
 	next, ok := p.lookahead(r)
 	if !ok {
 		return
 	}
-	r.unsupportedConstruct(next)
-	p.searchNext(r, token.StatementSeparator, token.EOF)
+	if isDelimiter(next, "(") {
+		stmt.LeftParen = next
+		p.consumeToken()
+		if stmt.ColumnName = p.parseNameList(r); stmt.ColumnName == nil {
+			return
+		}
+		if stmt.RightParen = p.parseDelimiter(r, ')'); stmt.RightParen == nil {
+			return
+		}
+	}
+	if stmt.As = p.parseKeyword(r, token.KeywordAs); stmt.As == nil {
+		return
+	}
+	stmt.SelectStmt = p.parseSelectStmt(r)
 	return
 }
 
@@ -0,0 +1,820 @@
+package parser
+
+import (
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// parseSelectStmt parses a SELECT statement as defined in the spec:
+// https://sqlite.org/lang_select.html
+// A VALUES clause is parsed as a SELECT statement, too. Window definitions
+// are not supported yet.
+func (p *simpleParser) parseSelectStmt(r reporter) (stmt *ast.SelectStmt) {
+	stmt = &ast.SelectStmt{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordWith {
+		stmt.With = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); ok && next.Type() == token.KeywordRecursive {
+			stmt.Recursive = next
+			p.consumeToken()
+		}
+		for {
+			stmt.CommonTableExpression = append(stmt.CommonTableExpression, p.parseCommonTableExpression(r))
+			next, ok = p.lookahead(r)
+			if !ok {
+				return
+			}
+			if !isDelimiter(next, ",") {
+				break
+			}
+			p.consumeToken()
+		}
+	}
+
+	stmt.SelectCore = append(stmt.SelectCore, p.parseSelectCore(r))
+	for {
+		next, ok = p.optionalLookahead(r)
+		if !ok {
+			return
+		}
+		op := &ast.CompoundOperator{}
+		switch next.Type() {
+		case token.KeywordUnion:
+			op.Union = next
+			p.consumeToken()
+			if all, ok := p.lookahead(r); ok && all.Type() == token.KeywordAll {
+				op.All = all
+				p.consumeToken()
+			}
+		case token.KeywordIntersect:
+			op.Intersect = next
+			p.consumeToken()
+		case token.KeywordExcept:
+			op.Except = next
+			p.consumeToken()
+		default:
+			op = nil
+		}
+		if op == nil {
+			break
+		}
+		core := p.parseSelectCore(r)
+		core.CompoundOperator = op
+		stmt.SelectCore = append(stmt.SelectCore, core)
+	}
+
+	if next.Type() == token.KeywordOrder {
+		stmt.Order = next
+		p.consumeToken()
+		stmt.By = p.parseKeyword(r, token.KeywordBy)
+		if stmt.By == nil {
+			return
+		}
+		stmt.OrderingTerm = p.parseOrderingTerms(r)
+		if next, ok = p.optionalLookahead(r); !ok {
+			return
+		}
+	}
+	if next.Type() == token.KeywordLimit {
+		stmt.Limit = next
+		p.consumeToken()
+		stmt.Expr1 = p.parseExpression(r)
+		next, ok = p.optionalLookahead(r)
+		if !ok {
+			return
+		}
+		switch {
+		case next.Type() == token.KeywordOffset:
+			stmt.Offset = next
+		case isDelimiter(next, ","):
+			stmt.Comma = next
+		default:
+			return
+		}
+		p.consumeToken()
+		stmt.Expr2 = p.parseExpression(r)
+	}
+	return
+}
+
+// parseCommonTableExpression parses a common table expression of a WITH
+// clause, as defined in the spec:
+// https://sqlite.org/syntax/common-table-expression.html
+func (p *simpleParser) parseCommonTableExpression(r reporter) (cte *ast.CommonTableExpression) {
+	cte = &ast.CommonTableExpression{}
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	cte.TableName = next
+	p.consumeToken()
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	if isDelimiter(next, "(") {
+		cte.LeftParen1 = next
+		p.consumeToken()
+		cte.ColumnName = p.parseNameList(r)
+		if cte.RightParen1 = p.parseDelimiter(r, ')'); cte.RightParen1 == nil {
+			return
+		}
+	}
+	if cte.As = p.parseKeyword(r, token.KeywordAs); cte.As == nil {
+		return
+	}
+	if cte.LeftParen2 = p.parseDelimiter(r, '('); cte.LeftParen2 == nil {
+		return
+	}
+	cte.SelectStmt = p.parseSelectStmt(r)
+	cte.RightParen2 = p.parseDelimiter(r, ')')
+	return
+}
+
+// parseSelectCore parses a single SELECT or VALUES clause of a SELECT
+// statement.
+func (p *simpleParser) parseSelectCore(r reporter) (core *ast.SelectCore) {
+	core = &ast.SelectCore{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordValues:
+		core.Values = next
+		p.consumeToken()
+		for {
+			core.ParenthesizedExpressions = append(core.ParenthesizedExpressions, p.parseParenthesizedExpressions(r))
+			next, ok = p.optionalLookahead(r)
+			if !ok || !isDelimiter(next, ",") {
+				return
+			}
+			p.consumeToken()
+		}
+	case token.KeywordSelect:
+		core.Select = next
+		p.consumeToken()
+	default:
+		r.unexpectedToken(token.KeywordSelect, token.KeywordValues)
+		return
+	}
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordDistinct:
+		core.Distinct = next
+		p.consumeToken()
+	case token.KeywordAll:
+		core.All = next
+		p.consumeToken()
+	}
+
+	for {
+		core.ResultColumn = append(core.ResultColumn, p.parseResultColumn(r))
+		next, ok = p.optionalLookahead(r)
+		if !ok || !isDelimiter(next, ",") {
+			break
+		}
+		p.consumeToken()
+	}
+	if !ok {
+		return
+	}
+
+	if next.Type() == token.KeywordFrom {
+		core.From = next
+		p.consumeToken()
+		core.JoinClause = p.parseJoinClause(r)
+		if next, ok = p.optionalLookahead(r); !ok {
+			return
+		}
+	}
+	if next.Type() == token.KeywordWhere {
+		core.Where = next
+		p.consumeToken()
+		core.Expr1 = p.parseExpression(r)
+		if next, ok = p.optionalLookahead(r); !ok {
+			return
+		}
+	}
+	if next.Type() == token.KeywordGroup {
+		core.Group = next
+		p.consumeToken()
+		if core.By = p.parseKeyword(r, token.KeywordBy); core.By == nil {
+			return
+		}
+		core.Expr2 = p.parseExpressionList(r)
+		if next, ok = p.optionalLookahead(r); !ok {
+			return
+		}
+		if next.Type() == token.KeywordHaving {
+			core.Having = next
+			p.consumeToken()
+			core.Expr3 = p.parseExpression(r)
+			if next, ok = p.optionalLookahead(r); !ok {
+				return
+			}
+		}
+	}
+	if next.Type() == token.KeywordWindow {
+		r.unsupportedConstruct(next)
+		p.skipUntil(token.StatementSeparator, token.EOF)
+	}
+	return
+}
+
+// parseResultColumn parses a result column as defined in the spec:
+// https://sqlite.org/syntax/result-column.html
+func (p *simpleParser) parseResultColumn(r reporter) (col *ast.ResultColumn) {
+	col = &ast.ResultColumn{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if isOperator(next, "*") {
+		col.Asterisk = next
+		p.consumeToken()
+		return
+	}
+
+	col.Expr = p.parseExpression(r)
+	if e := col.Expr; e.TableName != nil && e.Asterisk != nil {
+		// table.* is parsed as an expression
+		return &ast.ResultColumn{TableName: e.TableName, Period: e.Period2, Asterisk: e.Asterisk}
+	}
+	next, ok = p.optionalLookahead(r)
+	if !ok {
+		return
+	}
+
+	if next.Type() == token.KeywordAs {
+		col.As = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+	}
+	if next.Type() == token.Literal {
+		col.ColumnAlias = next
+		p.consumeToken()
+	}
+	return
+}
+
+// parseJoinClause parses a join clause as defined in the spec:
+// https://sqlite.org/syntax/join-clause.html
+// A single table is a join clause without a JoinClausePart.
+func (p *simpleParser) parseJoinClause(r reporter) (join *ast.JoinClause) {
+	join = &ast.JoinClause{TableOrSubquery: p.parseTableOrSubquery(r)}
+	for {
+		next, ok := p.optionalLookahead(r)
+		if !ok {
+			return
+		}
+		op, ok := p.parseJoinOperator(r, next)
+		if !ok {
+			return
+		}
+		part := &ast.JoinClausePart{JoinOperator: op, TableOrSubquery: p.parseTableOrSubquery(r)}
+		if next, ok = p.optionalLookahead(r); ok && (next.Type() == token.KeywordOn || next.Type() == token.KeywordUsing) {
+			part.JoinConstraint = p.parseJoinConstraint(r)
+		}
+		if join.JoinClausePart != nil {
+			join = &ast.JoinClause{TableOrSubquery: &ast.TableOrSubquery{JoinClause: join}}
+		}
+		join.JoinClausePart = part
+	}
+}
+
+// parseJoinOperator parses a join operator as defined in the spec:
+// https://sqlite.org/syntax/join-operator.html
+// If the given next token does not start a join operator, false is returned
+// and nothing is consumed. RIGHT and FULL joins are not supported.
+func (p *simpleParser) parseJoinOperator(r reporter, next token.Token) (*ast.JoinOperator, bool) {
+	op := &ast.JoinOperator{}
+	switch next.Type() {
+	case token.Delimiter:
+		if !isDelimiter(next, ",") {
+			return nil, false
+		}
+		op.Comma = next
+		p.consumeToken()
+		return op, true
+	case token.KeywordNatural, token.KeywordLeft, token.KeywordInner, token.KeywordCross, token.KeywordJoin:
+	case token.KeywordRight, token.KeywordFull:
+		r.unsupportedConstruct(next)
+		p.skipUntil(token.StatementSeparator, token.EOF)
+		return nil, false
+	default:
+		return nil, false
+	}
+
+	if next.Type() == token.KeywordNatural {
+		op.Natural = next
+		p.consumeToken()
+		var ok bool
+		if next, ok = p.lookahead(r); !ok {
+			return nil, false
+		}
+	}
+	switch next.Type() {
+	case token.KeywordLeft:
+		op.Left = next
+		p.consumeToken()
+		if outer, ok := p.lookahead(r); ok && outer.Type() == token.KeywordOuter {
+			op.Outer = outer
+			p.consumeToken()
+		}
+	case token.KeywordInner:
+		op.Inner = next
+		p.consumeToken()
+	case token.KeywordCross:
+		op.Cross = next
+		p.consumeToken()
+	}
+	if op.Join = p.parseKeyword(r, token.KeywordJoin); op.Join == nil {
+		return nil, false
+	}
+	return op, true
+}
+
+// parseJoinConstraint parses a join constraint as defined in the spec:
+// https://sqlite.org/syntax/join-constraint.html
+func (p *simpleParser) parseJoinConstraint(r reporter) (c *ast.JoinConstraint) {
+	c = &ast.JoinConstraint{}
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordOn {
+		c.On = next
+		p.consumeToken()
+		c.Expr = p.parseExpression(r)
+		return
+	}
+	c.Using = next
+	p.consumeToken()
+	if c.LeftParen = p.parseDelimiter(r, '('); c.LeftParen == nil {
+		return
+	}
+	c.ColumnName = p.parseNameList(r)
+	c.RightParen = p.parseDelimiter(r, ')')
+	return
+}
+
+// parseTableOrSubquery parses a table or subquery as defined in the spec:
+// https://sqlite.org/syntax/table-or-subquery.html
+func (p *simpleParser) parseTableOrSubquery(r reporter) (table *ast.TableOrSubquery) {
+	table = &ast.TableOrSubquery{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch {
+	case isDelimiter(next, "("):
+		table.LeftParen = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if startsSelect(next) {
+			table.SelectStmt = p.parseSelectStmt(r)
+		} else {
+			table.JoinClause = p.parseJoinClause(r)
+		}
+		if table.RightParen = p.parseDelimiter(r, ')'); table.RightParen == nil {
+			return
+		}
+		if table.JoinClause != nil {
+			return
+		}
+	case next.Type() == token.Literal:
+		p.parseQualifiedName(r, &table.SchemaName, &table.Period, &table.TableName)
+		if next, ok = p.optionalLookahead(r); ok && isDelimiter(next, "(") {
+			table.TableFunctionName, table.TableName = table.TableName, nil
+			table.LeftParen = next
+			p.consumeToken()
+			if next, ok = p.lookahead(r); ok && !isDelimiter(next, ")") {
+				table.Expr = p.parseExpressionList(r)
+			}
+			if table.RightParen = p.parseDelimiter(r, ')'); table.RightParen == nil {
+				return
+			}
+		}
+	default:
+		r.unexpectedToken(token.Literal, token.Delimiter)
+		return
+	}
+
+	p.parseAlias(r, &table.As, &table.TableAlias)
+	if table.TableName != nil {
+		p.parseIndexedBy(r, &table.Not, &table.Indexed, &table.By, &table.IndexName)
+	}
+	return
+}
+
+// parseAlias parses an optional alias, like [AS] alias.
+func (p *simpleParser) parseAlias(r reporter, as, alias *token.Token) {
+	next, ok := p.optionalLookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordAs {
+		*as = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+	}
+	if next.Type() == token.Literal && !isPeriod(next) {
+		*alias = next
+		p.consumeToken()
+	}
+}
+
+// parseIndexedBy parses an optional INDEXED BY index-name or NOT INDEXED.
+func (p *simpleParser) parseIndexedBy(r reporter, not, indexed, by, name *token.Token) {
+	next, ok := p.optionalLookahead(r)
+	if !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordNot:
+		*not = next
+		p.consumeToken()
+		*indexed = p.parseKeyword(r, token.KeywordIndexed)
+	case token.KeywordIndexed:
+		*indexed = next
+		p.consumeToken()
+		if *by = p.parseKeyword(r, token.KeywordBy); *by == nil {
+			return
+		}
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+		*name = next
+		p.consumeToken()
+	}
+}
+
+// parseOrderingTerms parses the ordering terms of an ORDER BY clause, as
+// defined in the spec:
+// https://sqlite.org/syntax/ordering-term.html
+// A COLLATE clause is parsed as part of the expression of the term.
+func (p *simpleParser) parseOrderingTerms(r reporter) (terms []*ast.OrderingTerm) {
+	for {
+		term := &ast.OrderingTerm{Expr: p.parseExpression(r)}
+		terms = append(terms, term)
+
+		next, ok := p.optionalLookahead(r)
+		if !ok {
+			return
+		}
+		switch next.Type() {
+		case token.KeywordAsc:
+			term.Asc = next
+			p.consumeToken()
+		case token.KeywordDesc:
+			term.Desc = next
+			p.consumeToken()
+		}
+		if next, ok = p.optionalLookahead(r); ok && next.Type() == token.KeywordNulls {
+			term.Nulls = next
+			p.consumeToken()
+			if next, ok = p.lookahead(r); !ok {
+				return
+			}
+			switch next.Type() {
+			case token.KeywordFirst:
+				term.First = next
+			case token.KeywordLast:
+				term.Last = next
+			default:
+				r.unexpectedToken(token.KeywordFirst, token.KeywordLast)
+				return
+			}
+			p.consumeToken()
+		}
+		if next, ok = p.optionalLookahead(r); !ok || !isDelimiter(next, ",") {
+			return
+		}
+		p.consumeToken()
+	}
+}
+
+// parseParenthesizedExpressions parses a parenthesized list of expressions,
+// like a row of a VALUES clause.
+func (p *simpleParser) parseParenthesizedExpressions(r reporter) (exprs *ast.ParenthesizedExpressions) {
+	exprs = &ast.ParenthesizedExpressions{}
+	if exprs.LeftParen = p.parseDelimiter(r, '('); exprs.LeftParen == nil {
+		return
+	}
+	exprs.Exprs = p.parseExpressionList(r)
+	exprs.RightParen = p.parseDelimiter(r, ')')
+	return
+}
+
+// parseInsertStmt parses an INSERT statement as defined in the spec:
+// https://sqlite.org/lang_insert.html
+// Upsert clauses and RETURNING are not supported yet.
+func (p *simpleParser) parseInsertStmt(r reporter) (stmt *ast.InsertStmt) {
+	stmt = &ast.InsertStmt{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordReplace {
+		stmt.Replace = next
+		p.consumeToken()
+	} else {
+		stmt.Insert = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() == token.KeywordOr {
+			stmt.Or = next
+			p.consumeToken()
+			if !p.parseConflictAlgorithm(r, &stmt.Rollback, &stmt.Abort, &stmt.Replace, &stmt.Fail, &stmt.Ignore) {
+				return
+			}
+		}
+	}
+	if stmt.Into = p.parseKeyword(r, token.KeywordInto); stmt.Into == nil {
+		return
+	}
+	p.parseQualifiedName(r, &stmt.SchemaName, &stmt.Period, &stmt.TableName)
+	if stmt.TableName == nil {
+		return
+	}
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.KeywordAs {
+		stmt.As = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+		stmt.Alias = next
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+	}
+	if isDelimiter(next, "(") {
+		stmt.LeftParen1 = next
+		p.consumeToken()
+		stmt.ColumnName = p.parseNameList(r)
+		if stmt.RightParen2 = p.parseDelimiter(r, ')'); stmt.RightParen2 == nil {
+			return
+		}
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+	}
+
+	switch next.Type() {
+	case token.KeywordDefault:
+		stmt.Default = next
+		p.consumeToken()
+		stmt.Values = p.parseKeyword(r, token.KeywordValues)
+	case token.KeywordValues:
+		stmt.Values = next
+		p.consumeToken()
+		var rows []ast.ParenthesizedExpressions
+		for {
+			rows = append(rows, *p.parseParenthesizedExpressions(r))
+			next, ok = p.optionalLookahead(r)
+			if !ok || !isDelimiter(next, ",") {
+				break
+			}
+			p.consumeToken()
+		}
+		stmt.ParenthesizedExpressions = &rows
+	case token.KeywordSelect, token.KeywordWith:
+		stmt.SelectStmt = p.parseSelectStmt(r)
+	default:
+		r.unexpectedToken(token.KeywordDefault, token.KeywordValues, token.KeywordSelect)
+		return
+	}
+
+	if next, ok = p.optionalLookahead(r); ok && next.Type() == token.KeywordOn {
+		r.unsupportedConstruct(next)
+		p.skipUntil(token.StatementSeparator, token.EOF)
+	}
+	return
+}
+
+// parseUpdateStmt parses an UPDATE statement as defined in the spec:
+// https://sqlite.org/lang_update.html
+// UPDATE FROM and RETURNING are not supported yet.
+func (p *simpleParser) parseUpdateStmt(r reporter) (stmt *ast.UpdateStmt) {
+	stmt = &ast.UpdateStmt{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	stmt.Update = next
+	p.consumeToken()
+
+	if next, ok = p.lookahead(r); !ok {
+		return
+	}
+	if next.Type() == token.KeywordOr {
+		stmt.Or = next
+		p.consumeToken()
+		if !p.parseConflictAlgorithm(r, &stmt.Rollback, &stmt.Abort, &stmt.Replace, &stmt.Fail, &stmt.Ignore) {
+			return
+		}
+	}
+	stmt.QualifiedTableName = p.parseQualifiedTableName(r)
+	if stmt.Set = p.parseKeyword(r, token.KeywordSet); stmt.Set == nil {
+		return
+	}
+
+	for {
+		setter := &ast.UpdateSetter{}
+		stmt.UpdateSetter = append(stmt.UpdateSetter, setter)
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		switch {
+		case isDelimiter(next, "("):
+			setter.ColumnNameList = &ast.ColumnNameList{LeftParen: next}
+			p.consumeToken()
+			setter.ColumnNameList.ColumnName = p.parseNameList(r)
+			if setter.ColumnNameList.RightParen = p.parseDelimiter(r, ')'); setter.ColumnNameList.RightParen == nil {
+				return
+			}
+		case next.Type() == token.Literal:
+			setter.ColumnName = next
+			p.consumeToken()
+		default:
+			r.unexpectedToken(token.Literal, token.Delimiter)
+			return
+		}
+
+		if next, ok = p.lookahead(r); !ok {
+			return
+		}
+		if !isOperator(next, "=") {
+			r.unexpectedSingleRuneToken(token.BinaryOperator, '=')
+			return
+		}
+		setter.Assign = next
+		p.consumeToken()
+		setter.Expr = p.parseExpression(r)
+
+		if next, ok = p.optionalLookahead(r); !ok || !isDelimiter(next, ",") {
+			break
+		}
+		p.consumeToken()
+	}
+
+	if ok && next.Type() == token.KeywordWhere {
+		stmt.Where = next
+		p.consumeToken()
+		stmt.Expr = p.parseExpression(r)
+	}
+	return
+}
+
+// parseDeleteStmt parses a DELETE statement as defined in the spec:
+// https://sqlite.org/lang_delete.html
+// RETURNING is not supported yet.
+func (p *simpleParser) parseDeleteStmt(r reporter) (stmt *ast.DeleteStmt) {
+	stmt = &ast.DeleteStmt{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	stmt.Delete = next
+	p.consumeToken()
+	if stmt.From = p.parseKeyword(r, token.KeywordFrom); stmt.From == nil {
+		return
+	}
+	stmt.QualifiedTableName = p.parseQualifiedTableName(r)
+
+	if next, ok = p.optionalLookahead(r); ok && next.Type() == token.KeywordWhere {
+		stmt.Where = next
+		p.consumeToken()
+		stmt.Expr = p.parseExpression(r)
+	}
+	return
+}
+
+// parseQualifiedTableName parses a qualified table name as defined in the
+// spec:
+// https://sqlite.org/syntax/qualified-table-name.html
+func (p *simpleParser) parseQualifiedTableName(r reporter) (name *ast.QualifiedTableName) {
+	name = &ast.QualifiedTableName{}
+	p.parseQualifiedName(r, &name.SchemaName, &name.Period, &name.TableName)
+	if name.TableName == nil {
+		return
+	}
+	p.parseAlias(r, &name.As, &name.Alias)
+	p.parseIndexedBy(r, &name.Not, &name.Indexed, &name.By, &name.IndexName)
+	return
+}
+
+// parseConflictAlgorithm parses the conflict algorithm after OR, as in INSERT
+// OR REPLACE, into the token of the algorithm. It returns false, if the next
+// token is not a conflict algorithm.
+func (p *simpleParser) parseConflictAlgorithm(r reporter, rollback, abort, replace, fail, ignore *token.Token) bool {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return false
+	}
+	switch next.Type() {
+	case token.KeywordRollback:
+		*rollback = next
+	case token.KeywordAbort:
+		*abort = next
+	case token.KeywordReplace:
+		*replace = next
+	case token.KeywordFail:
+		*fail = next
+	case token.KeywordIgnore:
+		*ignore = next
+	default:
+		r.unexpectedToken(token.KeywordRollback, token.KeywordAbort, token.KeywordReplace, token.KeywordFail, token.KeywordIgnore)
+		return false
+	}
+	p.consumeToken()
+	return true
+}
+
+// parseNameList parses one or more names, that are separated by commas, like
+// the column names of an INSERT statement.
+func (p *simpleParser) parseNameList(r reporter) (names []token.Token) {
+	for {
+		next, ok := p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+		names = append(names, next)
+		p.consumeToken()
+		if next, ok = p.lookahead(r); !ok || !isDelimiter(next, ",") {
+			return
+		}
+		p.consumeToken()
+	}
+}
+
+// parseKeyword consumes the given keyword. If the next token is not the
+// keyword, an error is reported and nil is returned.
+func (p *simpleParser) parseKeyword(r reporter, keyword token.Type) token.Token {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return nil
+	}
+	if next.Type() != keyword {
+		r.unexpectedToken(keyword)
+		return nil
+	}
+	p.consumeToken()
+	return next
+}
@@ -0,0 +1,675 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/tomarrell/lbadd/internal/parser/ast"
+	"github.com/tomarrell/lbadd/internal/parser/scanner/token"
+)
+
+// binaryLevels are the binary operators, that are parsed by parseBinary,
+// from the lowest to the highest precedence. Operators of the same level are
+// left associative. The comparison operators of the equality level, like IS
+// and IN, are parsed by parseEquality, and NOT by parseNot.
+var binaryLevels = [][]string{
+	{"<", "<=", ">", ">="},
+	{"&", "|", "<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+	{"||"},
+}
+
+// parseExpression parses an expression as defined in the spec:
+// https://sqlite.org/lang_expr.html
+// The precedence of the operators is the one of SQLite. Window functions and
+// the FILTER clause are not supported yet.
+func (p *simpleParser) parseExpression(r reporter) (expr *ast.Expr) {
+	return p.parseOr(r)
+}
+
+func (p *simpleParser) parseOr(r reporter) *ast.Expr {
+	left := p.parseAnd(r)
+	for {
+		next, ok := p.optionalLookahead(r)
+		if !ok || next.Type() != token.KeywordOr {
+			return left
+		}
+		p.consumeToken()
+		left = &ast.Expr{Expr1: left, BinaryOperator: next, Expr2: p.parseAnd(r)}
+	}
+}
+
+func (p *simpleParser) parseAnd(r reporter) *ast.Expr {
+	left := p.parseNot(r)
+	for {
+		next, ok := p.optionalLookahead(r)
+		if !ok || next.Type() != token.KeywordAnd {
+			return left
+		}
+		p.consumeToken()
+		left = &ast.Expr{Expr1: left, BinaryOperator: next, Expr2: p.parseNot(r)}
+	}
+}
+
+func (p *simpleParser) parseNot(r reporter) *ast.Expr {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return &ast.Expr{}
+	}
+	if next.Type() == token.KeywordNot {
+		p.consumeToken()
+		return &ast.Expr{Not: next, Expr1: p.parseNot(r)}
+	}
+	return p.parseEquality(r)
+}
+
+// parseEquality parses the operators with the precedence of =, which are
+// ==, !=, <>, IS [NOT], [NOT] IN, [NOT] LIKE, [NOT] GLOB, [NOT] REGEXP,
+// [NOT] MATCH, [NOT] BETWEEN, ISNULL, NOTNULL and NOT NULL.
+func (p *simpleParser) parseEquality(r reporter) *ast.Expr {
+	left := p.parseBinary(r, 0)
+	for {
+		next, ok := p.optionalLookahead(r)
+		if !ok {
+			return left
+		}
+		switch {
+		case isOperator(next, "=", "==", "!=", "<>"):
+			p.consumeToken()
+			left = &ast.Expr{Expr1: left, BinaryOperator: next, Expr2: p.parseBinary(r, 0)}
+		case next.Type() == token.KeywordIs:
+			p.consumeToken()
+			expr := &ast.Expr{Expr1: left, Is: next}
+			if not, ok := p.optionalLookahead(r); ok && not.Type() == token.KeywordNot {
+				expr.Not = not
+				p.consumeToken()
+			}
+			expr.Expr2 = p.parseBinary(r, 0)
+			left = expr
+		case next.Type() == token.KeywordIsnull:
+			p.consumeToken()
+			left = &ast.Expr{Expr1: left, Isnull: next}
+		case next.Type() == token.KeywordNotnull:
+			p.consumeToken()
+			left = &ast.Expr{Expr1: left, Notnull: next}
+		case next.Type() == token.KeywordNot:
+			// NOT in this position can only be followed by NULL or one of
+			// the operators that it negates
+			p.consumeToken()
+			op, ok := p.lookahead(r)
+			if !ok {
+				return left
+			}
+			if op.Type() == token.KeywordNull {
+				p.consumeToken()
+				left = &ast.Expr{Expr1: left, Not: next, Null: op}
+				continue
+			}
+			expr := &ast.Expr{Expr1: left, Not: next}
+			if !p.parseNegatable(r, expr) {
+				r.unexpectedToken(token.KeywordNull, token.KeywordIn, token.KeywordLike, token.KeywordGlob, token.KeywordRegexp, token.KeywordMatch, token.KeywordBetween)
+				return left
+			}
+			left = expr
+		default:
+			expr := &ast.Expr{Expr1: left}
+			if !p.parseNegatable(r, expr) {
+				return left
+			}
+			left = expr
+		}
+	}
+}
+
+// parseNegatable parses the operators, that can be negated with NOT, which
+// are IN, LIKE, GLOB, REGEXP, MATCH and BETWEEN, and their right operands,
+// into the given expression. If the next token is not such an operator,
+// false is returned and nothing is consumed.
+func (p *simpleParser) parseNegatable(r reporter, expr *ast.Expr) bool {
+	next, ok := p.optionalLookahead(r)
+	if !ok {
+		return false
+	}
+	switch next.Type() {
+	case token.KeywordIn:
+		expr.In = next
+		p.consumeToken()
+		p.parseInOperand(r, expr)
+	case token.KeywordLike, token.KeywordGlob, token.KeywordRegexp, token.KeywordMatch:
+		switch next.Type() {
+		case token.KeywordLike:
+			expr.Like = next
+		case token.KeywordGlob:
+			expr.Glob = next
+		case token.KeywordRegexp:
+			expr.Regexp = next
+		default:
+			expr.Match = next
+		}
+		p.consumeToken()
+		expr.Expr2 = p.parseBinary(r, 0)
+		if escape, ok := p.optionalLookahead(r); ok && escape.Type() == token.KeywordEscape {
+			expr.Escape = escape
+			p.consumeToken()
+			expr.Expr3 = p.parseBinary(r, 0)
+		}
+	case token.KeywordBetween:
+		expr.Between = next
+		p.consumeToken()
+		// the bounds bind tighter than AND, so that the AND of BETWEEN is
+		// not parsed as a logical operator
+		expr.Expr2 = p.parseBinary(r, 0)
+		and, ok := p.lookahead(r)
+		if !ok {
+			return true
+		}
+		if and.Type() != token.KeywordAnd {
+			r.unexpectedToken(token.KeywordAnd)
+			return true
+		}
+		expr.And = and
+		p.consumeToken()
+		expr.Expr3 = p.parseBinary(r, 0)
+	default:
+		return false
+	}
+	return true
+}
+
+// parseInOperand parses the right operand of IN, which is a parenthesized
+// SELECT statement, a parenthesized list of expressions, which may be empty,
+// or the name of a table.
+func (p *simpleParser) parseInOperand(r reporter, expr *ast.Expr) {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() == token.Literal {
+		p.parseQualifiedName(r, &expr.SchemaName, &expr.Period1, &expr.TableName)
+		return
+	}
+	if !isDelimiter(next, "(") {
+		r.unexpectedSingleRuneToken(token.Delimiter, '(')
+		return
+	}
+	expr.LeftParen = next
+	p.consumeToken()
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch {
+	case startsSelect(next):
+		expr.SelectStmt = p.parseSelectStmt(r)
+	case !isDelimiter(next, ")"):
+		expr.Expr = p.parseExpressionList(r)
+	}
+	expr.RightParen = p.parseDelimiter(r, ')')
+}
+
+// parseBinary parses the binary operators of the given level of binaryLevels
+// and all levels above it.
+func (p *simpleParser) parseBinary(r reporter, level int) *ast.Expr {
+	if level == len(binaryLevels) {
+		return p.parseUnary(r)
+	}
+	left := p.parseBinary(r, level+1)
+	for {
+		next, ok := p.optionalLookahead(r)
+		if !ok || !isOperator(next, binaryLevels[level]...) {
+			return left
+		}
+		p.consumeToken()
+		left = &ast.Expr{Expr1: left, BinaryOperator: next, Expr2: p.parseBinary(r, level+1)}
+	}
+}
+
+func (p *simpleParser) parseUnary(r reporter) *ast.Expr {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return &ast.Expr{}
+	}
+	if next.Type() == token.UnaryOperator {
+		p.consumeToken()
+		return &ast.Expr{UnaryOperator: next, Expr1: p.parseUnary(r)}
+	}
+	return p.parseCollate(r)
+}
+
+// parseCollate parses a primary expression, that may be followed by one or
+// more COLLATE operators.
+func (p *simpleParser) parseCollate(r reporter) *ast.Expr {
+	expr := p.parsePrimary(r)
+	for {
+		next, ok := p.optionalLookahead(r)
+		if !ok || next.Type() != token.KeywordCollate {
+			return expr
+		}
+		p.consumeToken()
+		expr = &ast.Expr{Expr1: expr, Collate: next}
+		name, ok := p.lookahead(r)
+		if !ok {
+			return expr
+		}
+		if name.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return expr
+		}
+		expr.CollationName = name
+		p.consumeToken()
+	}
+}
+
+// parsePrimary parses an expression without operators, which is a literal,
+// a column, a function call, a parenthesized expression or list of
+// expressions, a subquery, or a CAST, CASE, EXISTS or RAISE expression.
+func (p *simpleParser) parsePrimary(r reporter) (expr *ast.Expr) {
+	expr = &ast.Expr{}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordNull, token.KeywordCurrentTime, token.KeywordCurrentDate, token.KeywordCurrentTimestamp:
+		expr.LiteralValue = next
+		p.consumeToken()
+	case token.KeywordCast:
+		p.parseCast(r, expr)
+	case token.KeywordCase:
+		p.parseCase(r, expr)
+	case token.KeywordExists:
+		expr.Exists = next
+		p.consumeToken()
+		expr.LeftParen = p.parseDelimiter(r, '(')
+		if expr.LeftParen == nil {
+			return
+		}
+		expr.SelectStmt = p.parseSelectStmt(r)
+		expr.RightParen = p.parseDelimiter(r, ')')
+	case token.KeywordRaise:
+		expr.RaiseFunction = p.parseRaiseFunction(r)
+	case token.Delimiter:
+		if !isDelimiter(next, "(") {
+			r.unexpectedSingleRuneToken(token.Delimiter, '(')
+			return
+		}
+		expr.LeftParen = next
+		p.consumeToken()
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		if startsSelect(next) {
+			expr.SelectStmt = p.parseSelectStmt(r)
+		} else {
+			expr.Expr = p.parseExpressionList(r)
+		}
+		expr.RightParen = p.parseDelimiter(r, ')')
+	case token.Literal:
+		p.parseLiteralOrName(r, expr)
+	default:
+		r.unexpectedToken(token.Literal, token.KeywordNull, token.KeywordCast, token.KeywordCase, token.KeywordExists, token.KeywordRaise, token.Delimiter)
+	}
+	return
+}
+
+// parseLiteralOrName parses a literal value, a column name, which may be
+// qualified with a table and a schema name, or a function call.
+func (p *simpleParser) parseLiteralOrName(r reporter, expr *ast.Expr) {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	p.consumeToken()
+	if isValueLiteral(next) {
+		expr.LiteralValue = next
+		return
+	}
+	if blob, ok := p.parseBlobLiteral(r, next); ok {
+		expr.LiteralValue = blob
+		return
+	}
+
+	after, ok := p.optionalLookahead(r)
+	if !ok {
+		expr.ColumnName = next
+		return
+	}
+	switch {
+	case isDelimiter(after, "("):
+		expr.FunctionName = next
+		p.parseFunctionArguments(r, expr)
+	case isPeriod(after):
+		// schema.table.column or table.column
+		names := []token.Token{next}
+		var periods []token.Token
+		for len(names) < 3 {
+			period, ok := p.optionalLookahead(r)
+			if !ok || !isPeriod(period) {
+				break
+			}
+			p.consumeToken()
+			name, ok := p.lookahead(r)
+			if !ok {
+				return
+			}
+			if len(names) == 1 && isOperator(name, "*") {
+				// table.* of a result column
+				p.consumeToken()
+				expr.TableName, expr.Period2, expr.Asterisk = next, period, name
+				return
+			}
+			if name.Type() != token.Literal {
+				r.unexpectedToken(token.Literal)
+				return
+			}
+			p.consumeToken()
+			periods = append(periods, period)
+			names = append(names, name)
+		}
+		if len(names) == 3 {
+			expr.SchemaName, expr.Period1 = names[0], periods[0]
+			names, periods = names[1:], periods[1:]
+		}
+		expr.TableName, expr.Period2, expr.ColumnName = names[0], periods[0], names[1]
+	default:
+		expr.ColumnName = next
+	}
+}
+
+// parseBlobLiteral combines a blob literal like x'0a', which the scanner
+// splits into the literal x and the quoted literal '0a', into a single token.
+// The x must already be consumed. If the given token does not start a blob
+// literal, false is returned and nothing is consumed.
+func (p *simpleParser) parseBlobLiteral(r reporter, x token.Token) (token.Token, bool) {
+	if !strings.EqualFold(x.Value(), "x") {
+		return nil, false
+	}
+	next, ok := p.optionalLookahead(r)
+	if !ok || next.Type() != token.Literal || next.Offset() != x.Offset()+x.Length() || !strings.HasPrefix(next.Value(), "'") {
+		return nil, false
+	}
+	p.consumeToken()
+	return token.New(x.Line(), x.Col(), x.Offset(), x.Length()+next.Length(), token.Literal, x.Value()+next.Value()), true
+}
+
+// parseFunctionArguments parses the parenthesized arguments of a function
+// call, whose name has already been consumed, into the given expression.
+func (p *simpleParser) parseFunctionArguments(r reporter, expr *ast.Expr) {
+	expr.LeftParen = p.parseDelimiter(r, '(')
+	if expr.LeftParen == nil {
+		return
+	}
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch {
+	case isOperator(next, "*"):
+		expr.Asterisk = next
+		p.consumeToken()
+	case next.Type() == token.KeywordDistinct:
+		expr.Distinct = next
+		p.consumeToken()
+		expr.Expr = p.parseExpressionList(r)
+	case !isDelimiter(next, ")"):
+		expr.Expr = p.parseExpressionList(r)
+	}
+	expr.RightParen = p.parseDelimiter(r, ')')
+
+	if next, ok := p.optionalLookahead(r); ok && (next.Type() == token.KeywordFilter || next.Type() == token.KeywordOver) {
+		r.unsupportedConstruct(next)
+		p.skipUntil(token.StatementSeparator, token.EOF)
+	}
+}
+
+// parseCast parses a CAST expression into the given expression.
+func (p *simpleParser) parseCast(r reporter, expr *ast.Expr) {
+	expr.Cast, _ = p.lookahead(r)
+	p.consumeToken()
+	expr.LeftParen = p.parseDelimiter(r, '(')
+	if expr.LeftParen == nil {
+		return
+	}
+	expr.Expr1 = p.parseExpression(r)
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() != token.KeywordAs {
+		r.unexpectedToken(token.KeywordAs)
+		return
+	}
+	expr.As = next
+	p.consumeToken()
+	expr.TypeName = p.parseTypeName(r)
+	expr.RightParen = p.parseDelimiter(r, ')')
+}
+
+// parseCase parses a CASE expression into the given expression.
+func (p *simpleParser) parseCase(r reporter, expr *ast.Expr) {
+	expr.Case, _ = p.lookahead(r)
+	p.consumeToken()
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() != token.KeywordWhen {
+		expr.Expr1 = p.parseExpression(r)
+	}
+	for {
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+		if next.Type() != token.KeywordWhen {
+			break
+		}
+		p.consumeToken()
+		when := &ast.WhenClause{When: next, Expr1: p.parseExpression(r)}
+		expr.WhenClause = append(expr.WhenClause, when)
+
+		then, ok := p.lookahead(r)
+		if !ok {
+			return
+		}
+		if then.Type() != token.KeywordThen {
+			r.unexpectedToken(token.KeywordThen)
+			return
+		}
+		when.Then = then
+		p.consumeToken()
+		when.Expr2 = p.parseExpression(r)
+	}
+	if len(expr.WhenClause) == 0 {
+		r.unexpectedToken(token.KeywordWhen)
+		return
+	}
+	if next.Type() == token.KeywordElse {
+		expr.Else = next
+		p.consumeToken()
+		expr.Expr4 = p.parseExpression(r)
+		next, ok = p.lookahead(r)
+		if !ok {
+			return
+		}
+	}
+	if next.Type() != token.KeywordEnd {
+		r.unexpectedToken(token.KeywordEnd)
+		return
+	}
+	expr.End = next
+	p.consumeToken()
+}
+
+// parseRaiseFunction parses a RAISE function, as defined in the spec:
+// https://sqlite.org/syntax/raise-function.html
+func (p *simpleParser) parseRaiseFunction(r reporter) (raise *ast.RaiseFunction) {
+	raise = &ast.RaiseFunction{}
+	raise.Raise, _ = p.lookahead(r)
+	p.consumeToken()
+	raise.LeftParen = p.parseDelimiter(r, '(')
+	if raise.LeftParen == nil {
+		return
+	}
+
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	switch next.Type() {
+	case token.KeywordIgnore:
+		raise.Ignore = next
+	case token.KeywordRollback:
+		raise.Rollback = next
+	case token.KeywordAbort:
+		raise.Abort = next
+	case token.KeywordFail:
+		raise.Fail = next
+	default:
+		r.unexpectedToken(token.KeywordIgnore, token.KeywordRollback, token.KeywordAbort, token.KeywordFail)
+		return
+	}
+	p.consumeToken()
+
+	if raise.Ignore == nil {
+		raise.Comma = p.parseDelimiter(r, ',')
+		if raise.Comma == nil {
+			return
+		}
+		message, ok := p.lookahead(r)
+		if !ok {
+			return
+		}
+		if message.Type() != token.Literal {
+			r.unexpectedToken(token.Literal)
+			return
+		}
+		raise.ErrorMessage = message
+		p.consumeToken()
+	}
+	raise.RightParen = p.parseDelimiter(r, ')')
+	return
+}
+
+// parseExpressionList parses one or more expressions, that are separated by
+// commas.
+func (p *simpleParser) parseExpressionList(r reporter) (exprs []*ast.Expr) {
+	for {
+		exprs = append(exprs, p.parseExpression(r))
+		next, ok := p.optionalLookahead(r)
+		if !ok || !isDelimiter(next, ",") {
+			return
+		}
+		p.consumeToken()
+	}
+}
+
+// parseDelimiter consumes the given delimiter. If the next token is not the
+// delimiter, an error is reported and nil is returned.
+func (p *simpleParser) parseDelimiter(r reporter, delimiter rune) token.Token {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return nil
+	}
+	if !isDelimiter(next, string(delimiter)) {
+		r.unexpectedSingleRuneToken(token.Delimiter, delimiter)
+		return nil
+	}
+	p.consumeToken()
+	return next
+}
+
+// parseQualifiedName parses a name, that may be qualified with a schema name,
+// like [schema-name.]name. If there is no schema name, schema and period are
+// left nil.
+func (p *simpleParser) parseQualifiedName(r reporter, schema, period, name *token.Token) {
+	next, ok := p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	*name = next
+	p.consumeToken()
+
+	next, ok = p.optionalLookahead(r)
+	if !ok || !isPeriod(next) {
+		return
+	}
+	*schema, *period = *name, next
+	*name = nil
+	p.consumeToken()
+
+	next, ok = p.lookahead(r)
+	if !ok {
+		return
+	}
+	if next.Type() != token.Literal {
+		r.unexpectedToken(token.Literal)
+		return
+	}
+	*name = next
+	p.consumeToken()
+}
+
+// isDelimiter reports whether the given token is the given delimiter.
+func isDelimiter(tk token.Token, delimiter string) bool {
+	return tk.Type() == token.Delimiter && tk.Value() == delimiter
+}
+
+// isPeriod reports whether the given token is the period, that separates the
+// parts of a qualified name.
+func isPeriod(tk token.Token) bool {
+	return tk.Type() == token.Literal && tk.Value() == "."
+}
+
+// isOperator reports whether the given token is one of the given operators.
+// Since the scanner always scans + and - as unary operators, they are
+// accepted as binary operators, too.
+func isOperator(tk token.Token, operators ...string) bool {
+	if tk.Type() != token.BinaryOperator && tk.Type() != token.UnaryOperator {
+		return false
+	}
+	for _, op := range operators {
+		if tk.Value() == op {
+			return true
+		}
+	}
+	return false
+}
+
+// isValueLiteral reports whether the given literal token is a string or a
+// numeric literal, rather than a name.
+func isValueLiteral(tk token.Token) bool {
+	v := tk.Value()
+	switch {
+	case v == "" || v == ".":
+		return false
+	case v[0] == '\'':
+		return true
+	case v[0] >= '0' && v[0] <= '9', v[0] == '.':
+		return true
+	}
+	return false
+}
+
+// isColumnName reports whether the given expression is a bare column name,
+// that is not qualified by a table name.
+func isColumnName(expr *ast.Expr) bool {
+	return expr != nil && expr.ColumnName != nil && expr.TableName == nil
+}
+
+// startsSelect reports whether the given token starts a SELECT statement.
+func startsSelect(tk token.Token) bool {
+	switch tk.Type() {
+	case token.KeywordSelect, token.KeywordValues, token.KeywordWith:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package planner
+
+import (
+	"math"
+
+	"github.com/tomarrell/lbadd/internal/database/stats"
+)
+
+// Cost model. The unit of cost is reading a single row sequentially.
+const (
+	// seqRowCost is the cost of reading a row during a full scan.
+	seqRowCost = 1.0
+	// randomRowCost is the cost of reading a row that was found with an
+	// index.
+	randomRowCost = 4.0
+	// cpuRowCost is the cost of comparing two rows.
+	cpuRowCost = 0.01
+	// hashBuildRowCost is the cost of inserting a row into a hash table.
+	hashBuildRowCost = 0.03
+	// hashProbeRowCost is the cost of probing a hash table with a row.
+	hashProbeRowCost = 0.01
+)
+
+// Default estimates for relations and columns without statistics.
+const (
+	defaultRows = 1000
+	// defaultRowsPerValue is the amount of rows that are assumed to share
+	// the same value in a column.
+	defaultRowsPerValue = 10
+	defaultEqualSel     = 0.1
+	defaultRangeSel     = 1.0 / 3
+	defaultNullSel      = 0.05
+	defaultOtherSel     = 0.5
+)
+
+// baseRows returns the amount of rows of the given relation.
+func baseRows(rel Relation) float64 {
+	if rel.Stats == nil {
+		return defaultRows
+	}
+	return float64(rel.Stats.Rows)
+}
+
+// columnStats returns the statistics of the given column of the given
+// relation, if they exist.
+func columnStats(rel Relation, col int) (stats.Column, bool) {
+	if rel.Stats == nil || col >= len(rel.Stats.Columns) {
+		return stats.Column{}, false
+	}
+	return rel.Stats.Columns[col], true
+}
+
+// distinct returns the amount of distinct values in the given column.
+func distinct(rel Relation, col int) float64 {
+	if c, ok := columnStats(rel, col); ok {
+		return math.Max(1, float64(c.Distinct))
+	}
+	return math.Max(1, baseRows(rel)/defaultRowsPerValue)
+}
+
+// filterSelectivity estimates the fraction of rows of the given relation that
+// satisfy the given filter.
+func filterSelectivity(rel Relation, f Filter) float64 {
+	c, ok := columnStats(rel, f.Column.Column)
+	switch f.Op {
+	case Equal:
+		switch {
+		case ok && f.Value != nil:
+			return c.EqualSelectivity(f.Value)
+		case ok:
+			return 1 / distinct(rel, f.Column.Column)
+		}
+		return defaultEqualSel
+	case Range:
+		if ok && (f.Low != nil || f.High != nil) {
+			return c.RangeSelectivity(f.Low, f.High)
+		}
+		return defaultRangeSel
+	case IsNull:
+		if ok {
+			return c.EqualSelectivity(nil)
+		}
+		return defaultNullSel
+	}
+	return defaultOtherSel
+}
+
+// joinSelectivity estimates the fraction of the cross product of two
+// relations, that satisfies the given join condition.
+func joinSelectivity(rels []Relation, j JoinCondition) float64 {
+	left := distinct(rels[j.Left.Relation], j.Left.Column)
+	right := distinct(rels[j.Right.Relation], j.Right.Column)
+	return 1 / math.Max(left, right)
+}
+
+// lookupCost returns the cost of looking up a key in an index of a relation
+// with the given amount of rows, and reading the given amount of matching
+// rows.
+func lookupCost(rows, matches float64) float64 {
+	return math.Log2(rows+2) + matches*randomRowCost
+}
@@ -0,0 +1,45 @@
+package planner
+
+//go:generate stringer -type=CTEHint
+
+// CTEHint is the hint of a common table expression, whether it should be
+// materialized.
+type CTEHint uint8
+
+// Known hints.
+const (
+	// NoHint lets the planner decide.
+	NoHint CTEHint = iota
+	// Materialized is AS MATERIALIZED.
+	Materialized
+	// NotMaterialized is AS NOT MATERIALIZED.
+	NotMaterialized
+)
+
+// CTE describes a common table expression of a query.
+type CTE struct {
+	Name string
+	// References is the amount of times the CTE is referenced in the query.
+	References int
+	Recursive  bool
+	// Volatile indicates that the CTE calls functions, that are not
+	// deterministic, so that every reference must read the same rows.
+	Volatile bool
+	Hint     CTEHint
+}
+
+// Materialize decides whether a CTE is computed once and stored in a
+// temporary table, or inlined into the query as a subquery at every
+// reference. Like in SQLite, recursive CTEs are always materialized, and
+// hints are followed. Otherwise, a CTE is materialized if it is referenced
+// more than once, since it would have to be computed once per reference, or
+// if it is volatile.
+func Materialize(c CTE) bool {
+	switch {
+	case c.Recursive:
+		return true
+	case c.Hint != NoHint:
+		return c.Hint == Materialized
+	}
+	return c.Volatile || c.References > 1
+}
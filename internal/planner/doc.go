@@ -0,0 +1,23 @@
+// Package planner implements a cost-based query planner. Given the relations
+// of a query, the filters on them and the conditions that join them, the
+// planner chooses an access path for every relation, the order in which the
+// relations are joined, and the algorithm of every join. The amount of rows
+// that every step produces is estimated with the statistics that ANALYZE
+// gathered. Relations that have not been analyzed are assumed to have a
+// default amount of rows.
+//
+// The join order is chosen with dynamic programming over all subsets of the
+// relations, if there are only a few of them. For larger joins, a greedy
+// algorithm is used, that repeatedly joins the two plans that are cheapest to
+// join.
+//
+// Materialize decides whether a common table expression is computed once into
+// a temporary table, or inlined at every reference.
+//
+// Plans also have logical operations, like a Selection or a Join with a
+// JoinType, whose predicates and projections are expressions (Expr). They
+// describe a query before the optimizer rewrote it, and may contain
+// subqueries.
+//
+// Explain describes a plan as the result of EXPLAIN QUERY PLAN.
+package planner
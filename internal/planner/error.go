@@ -0,0 +1,13 @@
+package planner
+
+// Error provides constant errors to the planner package.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Constant errors
+const (
+	ErrNoRelations      = Error("query has no relations")
+	ErrTooManyRelations = Error("query has too many relations")
+	ErrInvalidReference = Error("invalid reference")
+)
@@ -0,0 +1,286 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExplainColumns are the names of the columns of the result of EXPLAIN QUERY
+// PLAN.
+var ExplainColumns = []string{"id", "parent", "detail", "rows", "cost"}
+
+// ExplainRow is a row of the result of EXPLAIN QUERY PLAN, that describes a
+// single node of a plan.
+type ExplainRow struct {
+	// ID identifies the node. IDs are assigned in depth-first order,
+	// starting at 1.
+	ID int
+	// Parent is the ID of the parent node, or 0 for the root node.
+	Parent int
+	Detail string
+	Rows   float64
+	Cost   float64
+}
+
+// Values returns the values of this row, in the order of ExplainColumns.
+func (r ExplainRow) Values() []interface{} {
+	return []interface{}{r.ID, r.Parent, r.Detail, r.Rows, r.Cost}
+}
+
+// Explain returns the rows of the result of EXPLAIN QUERY PLAN for the given
+// plan, in depth-first order. The subqueries of a node follow its children,
+// each as the only child of a row with the detail "SUBQUERY".
+func Explain(n *Node) []ExplainRow {
+	var rows []ExplainRow
+	var visit func(l line, parent int)
+	visit = func(l line, parent int) {
+		id := len(rows) + 1
+		row := ExplainRow{
+			ID:     id,
+			Parent: parent,
+			Detail: l.detail(),
+		}
+		if l.node != nil {
+			row.Rows, row.Cost = l.node.Rows, l.node.Cost
+		}
+		rows = append(rows, row)
+		for _, child := range l.children() {
+			visit(child, id)
+		}
+	}
+	visit(line{node: n}, 0)
+	return rows
+}
+
+// line is a line of the description of a plan. It describes either a node,
+// or a subquery of the node of its parent line.
+type line struct {
+	node     *Node
+	subquery *Node
+}
+
+func (l line) detail() string {
+	if l.node == nil {
+		return "SUBQUERY"
+	}
+	return l.node.Detail()
+}
+
+// children returns the lines below this line, which are the children and
+// subqueries of a node, or the plan of a subquery.
+func (l line) children() []line {
+	if l.node == nil {
+		return []line{{node: l.subquery}}
+	}
+	var lines []line
+	for _, child := range l.node.Children {
+		lines = append(lines, line{node: child})
+	}
+	for _, subquery := range l.node.Subqueries() {
+		lines = append(lines, line{subquery: subquery})
+	}
+	return lines
+}
+
+// Subqueries returns the plans of the subqueries in the predicate and the
+// projections of this node, in the order in which they appear.
+func (n *Node) Subqueries() []*Node {
+	var plans []*Node
+	var visit func(e *Expr)
+	visit = func(e *Expr) {
+		if e == nil {
+			return
+		}
+		for _, arg := range e.Args {
+			visit(arg)
+		}
+		if e.Subquery != nil {
+			plans = append(plans, e.Subquery)
+		}
+	}
+	for _, e := range n.Exprs() {
+		visit(e)
+	}
+	return plans
+}
+
+// Exprs returns the expressions of this node, without the expressions of its
+// children: the predicate, the projections, the GROUP BY expressions, the
+// expressions of the order terms, and the values.
+func (n *Node) Exprs() []*Expr {
+	var exprs []*Expr
+	if n.Predicate != nil {
+		exprs = append(exprs, n.Predicate)
+	}
+	exprs = append(exprs, n.Projections...)
+	exprs = append(exprs, n.GroupBy...)
+	for _, t := range n.Order {
+		exprs = append(exprs, t.Expr)
+	}
+	for _, row := range n.Values {
+		exprs = append(exprs, row...)
+	}
+	return exprs
+}
+
+// Detail returns a description of this node, without its children.
+func (n *Node) Detail() string {
+	switch n.Op {
+	case FullScan:
+		return "SCAN " + n.relation() + n.columns()
+	case IndexScan:
+		return fmt.Sprintf("SEARCH %v USING INDEX %v%v", n.relation(), n.Index, n.columns())
+	case NestedLoopJoin:
+		return "NESTED LOOP JOIN"
+	case IndexNestedLoopJoin:
+		return "INDEX NESTED LOOP JOIN"
+	case HashJoin:
+		return "HASH JOIN"
+	case Selection:
+		return "FILTER " + n.Predicate.String()
+	case Projection:
+		exprs := make([]string, len(n.Projections))
+		for i, e := range n.Projections {
+			exprs[i] = e.String()
+		}
+		detail := "PROJECT " + strings.Join(exprs, ", ")
+		if n.Relation != "" {
+			detail += " AS " + n.Relation
+		}
+		return detail
+	case Limit:
+		if n.Offset != 0 {
+			return fmt.Sprintf("LIMIT %d OFFSET %d", n.Count, n.Offset)
+		}
+		return fmt.Sprintf("LIMIT %d", n.Count)
+	case Join:
+		detail := strings.ToUpper(strings.TrimSuffix(n.JoinType.String(), "Join")) + " JOIN"
+		if n.Predicate != nil {
+			detail += " ON " + n.Predicate.String()
+		}
+		return detail
+	case Sort:
+		terms := make([]string, len(n.Order))
+		for i, t := range n.Order {
+			terms[i] = t.String()
+		}
+		return "ORDER BY " + strings.Join(terms, ", ")
+	case Aggregate:
+		detail := "AGGREGATE"
+		if len(n.Projections) > 0 {
+			detail += " " + list(n.Projections)
+		}
+		if len(n.GroupBy) > 0 {
+			detail += " GROUP BY " + list(n.GroupBy)
+		}
+		return detail
+	case Distinct:
+		return "DISTINCT"
+	case Values:
+		if len(n.Values) == 1 {
+			return "SCAN CONSTANT ROW"
+		}
+		return fmt.Sprintf("SCAN %d CONSTANT ROWS", len(n.Values))
+	case Compound:
+		return "COMPOUND " + setOps[n.SetOp]
+	case With:
+		names := make([]string, len(n.Children)-1)
+		for i, child := range n.Children[:len(names)] {
+			names[i] = child.Relation
+		}
+		return "MATERIALIZE " + strings.Join(names, ", ")
+	case CommonTable:
+		if len(n.Children) > 1 {
+			return "RECURSIVE CTE " + n.relation() + n.columns()
+		}
+		return "CTE " + n.relation() + n.columns()
+	case Insert:
+		detail := "INSERT INTO " + n.qualified()
+		switch {
+		case n.Columns == nil:
+		case len(n.Columns) == 0:
+			detail += " DEFAULT VALUES"
+		default:
+			detail += " (" + strings.Join(n.Columns, ", ") + ")"
+		}
+		return detail
+	case Update:
+		setters := make([]string, len(n.Columns))
+		for i, name := range n.Columns {
+			setters[i] = name + " = " + n.Projections[i].String()
+		}
+		return "UPDATE " + n.qualified() + " SET " + strings.Join(setters, ", ")
+	case Delete:
+		return "DELETE FROM " + n.qualified()
+	}
+	return n.Op.String()
+}
+
+var setOps = map[SetOp]string{
+	UnionAll:  "UNION ALL",
+	Union:     "UNION",
+	Intersect: "INTERSECT",
+	Except:    "EXCEPT",
+}
+
+// qualified returns the Relation, qualified with the Schema, if it has one.
+func (n *Node) qualified() string {
+	if n.Schema == "" {
+		return n.Relation
+	}
+	return n.Schema + "." + n.Relation
+}
+
+// relation returns the relation that a scan reads, which is the qualified
+// table, followed by the Relation as alias, if the scan has a Table.
+func (n *Node) relation() string {
+	if n.Table == "" {
+		return n.qualified()
+	}
+	table := n.Table
+	if n.Schema != "" {
+		table = n.Schema + "." + table
+	}
+	return table + " AS " + n.Relation
+}
+
+// columns returns the columns that a scan reads in parentheses, or an empty
+// string if it reads all columns.
+func (n *Node) columns() string {
+	if n.Columns == nil {
+		return ""
+	}
+	return " (" + strings.Join(n.Columns, ", ") + ")"
+}
+
+// String renders the plan as a tree, the way the SQLite shell renders the
+// result of EXPLAIN QUERY PLAN. The estimates are omitted, if they are 0,
+// like in logical plans.
+//
+//  QUERY PLAN
+//  `--HASH JOIN (rows=100 cost=2000)
+//     |--SCAN big (rows=1000 cost=1000)
+//     `--SCAN small (rows=10 cost=10)
+func (n *Node) String() string {
+	var b strings.Builder
+	b.WriteString("QUERY PLAN\n")
+
+	var visit func(l line, prefix string, last bool)
+	visit = func(l line, prefix string, last bool) {
+		branch, indent := "|--", "|  "
+		if last {
+			branch, indent = "`--", "   "
+		}
+		fmt.Fprintf(&b, "%v%v%v", prefix, branch, l.detail())
+		if l.node != nil && (l.node.Rows != 0 || l.node.Cost != 0) {
+			fmt.Fprintf(&b, " (rows=%.0f cost=%.0f)", l.node.Rows, l.node.Cost)
+		}
+		b.WriteByte('\n')
+		children := l.children()
+		for i, child := range children {
+			visit(child, prefix+indent, i == len(children)-1)
+		}
+	}
+	visit(line{node: n}, "", true)
+	return b.String()
+}
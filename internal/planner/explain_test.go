@@ -0,0 +1,128 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPlan() *Node {
+	return &Node{
+		Op:   HashJoin,
+		Rows: 50,
+		Cost: 1250.5,
+		Children: []*Node{
+			{
+				Op:   IndexNestedLoopJoin,
+				Rows: 100,
+				Cost: 1100,
+				Children: []*Node{
+					{Op: FullScan, Relation: "customers", Rows: 10, Cost: 1000},
+					{Op: IndexScan, Relation: "orders", Index: "orders_customer", Rows: 10, Cost: 10},
+				},
+			},
+			{Op: FullScan, Relation: "products", Rows: 5, Cost: 50},
+		},
+	}
+}
+
+func TestExplain(t *testing.T) {
+	assert.Equal(t, []ExplainRow{
+		{ID: 1, Parent: 0, Detail: "HASH JOIN", Rows: 50, Cost: 1250.5},
+		{ID: 2, Parent: 1, Detail: "INDEX NESTED LOOP JOIN", Rows: 100, Cost: 1100},
+		{ID: 3, Parent: 2, Detail: "SCAN customers", Rows: 10, Cost: 1000},
+		{ID: 4, Parent: 2, Detail: "SEARCH orders USING INDEX orders_customer", Rows: 10, Cost: 10},
+		{ID: 5, Parent: 1, Detail: "SCAN products", Rows: 5, Cost: 50},
+	}, Explain(testPlan()))
+
+	row := Explain(testPlan())[0]
+	assert.Len(t, row.Values(), len(ExplainColumns))
+}
+
+func TestNode_String(t *testing.T) {
+	assert.Equal(t, `QUERY PLAN
+`+"`"+`--HASH JOIN (rows=50 cost=1250)
+   |--INDEX NESTED LOOP JOIN (rows=100 cost=1100)
+   |  |--SCAN customers (rows=10 cost=1000)
+   |  `+"`"+`--SEARCH orders USING INDEX orders_customer (rows=10 cost=10)
+   `+"`"+`--SCAN products (rows=5 cost=50)
+`, testPlan().String())
+}
+
+func TestNode_String_Logical(t *testing.T) {
+	subquery := &Node{
+		Op:        Selection,
+		Predicate: NewExpr(EqExpr, Ref("orders", "customer"), Ref("customers", "id")),
+		Children:  []*Node{{Op: FullScan, Relation: "orders"}},
+	}
+	plan := &Node{
+		Op:    Limit,
+		Count: 10,
+		Children: []*Node{{
+			Op:          Projection,
+			Projections: []*Expr{Ref("customers", "name")},
+			Children: []*Node{{
+				Op:        Selection,
+				Predicate: Exists(subquery),
+				Children: []*Node{{
+					Op:        Join,
+					JoinType:  LeftJoin,
+					Predicate: NewExpr(EqExpr, Ref("customers", "country"), Ref("countries", "code")),
+					Children: []*Node{
+						{Op: FullScan, Relation: "customers", Columns: []string{"country", "id", "name"}},
+						{Op: IndexScan, Relation: "countries", Index: "countries_code", Columns: []string{}},
+					},
+				}},
+			}},
+		}},
+	}
+
+	assert.Equal(t, `QUERY PLAN
+`+"`"+`--LIMIT 10
+   `+"`"+`--PROJECT customers.name
+      `+"`"+`--FILTER EXISTS (SUBQUERY)
+         |--LEFT JOIN ON customers.country = countries.code
+         |  |--SCAN customers (country, id, name)
+         |  `+"`"+`--SEARCH countries USING INDEX countries_code ()
+         `+"`"+`--SUBQUERY
+            `+"`"+`--FILTER orders.customer = customers.id
+               `+"`"+`--SCAN orders
+`, plan.String())
+
+	rows := Explain(plan)
+	assert.Len(t, rows, 9)
+	assert.Equal(t, ExplainRow{ID: 7, Parent: 3, Detail: "SUBQUERY"}, rows[6])
+	assert.Equal(t, ExplainRow{ID: 8, Parent: 7, Detail: "FILTER orders.customer = customers.id"}, rows[7])
+}
+
+func TestNode_Detail(t *testing.T) {
+	a := Ref("t", "a")
+	tests := []struct {
+		name string
+		node *Node
+		want string
+	}{
+		{"alias", &Node{Op: FullScan, Schema: "main", Table: "users", Relation: "u"}, "SCAN main.users AS u"},
+		{"sort", &Node{Op: Sort, Order: []OrderTerm{{Expr: a, NullsFirst: true}, {Expr: Ref("t", "b"), Desc: true}}}, "ORDER BY t.a, t.b DESC"},
+		{"nulls", &Node{Op: Sort, Order: []OrderTerm{{Expr: a}}}, "ORDER BY t.a NULLS LAST"},
+		{"aggregate", &Node{Op: Aggregate, Projections: []*Expr{Func("count")}, GroupBy: []*Expr{a}}, "AGGREGATE count(*) GROUP BY t.a"},
+		{"group", &Node{Op: Aggregate, GroupBy: []*Expr{a}}, "AGGREGATE GROUP BY t.a"},
+		{"distinct", &Node{Op: Distinct}, "DISTINCT"},
+		{"values", &Node{Op: Values, Values: [][]*Expr{{Const(int64(1))}, {Const(int64(2))}}}, "SCAN 2 CONSTANT ROWS"},
+		{"value", &Node{Op: Values, Values: [][]*Expr{{Const(int64(1))}}}, "SCAN CONSTANT ROW"},
+		{"compound", &Node{Op: Compound, SetOp: UnionAll}, "COMPOUND UNION ALL"},
+		{"with", &Node{Op: With, Children: []*Node{{Op: CommonTable, Relation: "a"}, {Op: CommonTable, Relation: "b"}, {Op: FullScan, Relation: "b"}}}, "MATERIALIZE a, b"},
+		{"cte", &Node{Op: CommonTable, Table: "cnt", Relation: "c", Columns: []string{"x"}}, "CTE cnt AS c (x)"},
+		{"recursive cte", &Node{Op: CommonTable, Relation: "cnt", Children: []*Node{{Op: Values}, {Op: Projection}}}, "RECURSIVE CTE cnt"},
+		{"insert", &Node{Op: Insert, Relation: "t", Columns: []string{"a", "b"}}, "INSERT INTO t (a, b)"},
+		{"insert default values", &Node{Op: Insert, Relation: "t", Columns: []string{}}, "INSERT INTO t DEFAULT VALUES"},
+		{"subquery", &Node{Op: Projection, Relation: "s", Projections: []*Expr{{Op: StarExpr}}}, "PROJECT * AS s"},
+		{"update", &Node{Op: Update, Schema: "main", Relation: "t", Columns: []string{"a"}, Projections: []*Expr{NewExpr(AddExpr, a, Const(int64(1)))}}, "UPDATE main.t SET a = t.a + 1"},
+		{"delete", &Node{Op: Delete, Relation: "t"}, "DELETE FROM t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.node.Detail())
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package planner
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//go:generate stringer -type=ExprOp
+
+// ExprOp is the operation of an expression.
+type ExprOp uint8
+
+// Supported operations of expressions.
+const (
+	// ColumnExpr is the value of the Column of the expression.
+	ColumnExpr ExprOp = iota
+	// ConstExpr is the constant Value of the expression.
+	ConstExpr
+	// EqExpr, NeExpr, LtExpr, LeExpr, GtExpr and GeExpr compare their two
+	// arguments.
+	EqExpr
+	NeExpr
+	LtExpr
+	LeExpr
+	GtExpr
+	GeExpr
+	// AddExpr, SubExpr, MulExpr and DivExpr compute their two arguments.
+	AddExpr
+	SubExpr
+	MulExpr
+	DivExpr
+	// AndExpr and OrExpr combine all of their arguments, NotExpr negates its
+	// single argument.
+	AndExpr
+	OrExpr
+	NotExpr
+	// IsNullExpr checks whether its single argument is NULL.
+	IsNullExpr
+	// ExistsExpr checks whether the Subquery of the expression returns any
+	// row.
+	ExistsExpr
+	// InExpr checks whether its first argument is one of the values, that
+	// the Subquery of the expression returns, or, without a Subquery, one of
+	// its other arguments.
+	InExpr
+	// ModExpr computes the remainder of the division of its two arguments.
+	ModExpr
+	// ConcatExpr concatenates its two arguments as TEXT.
+	ConcatExpr
+	// BitAndExpr, BitOrExpr, ShiftLeftExpr and ShiftRightExpr compute their
+	// two arguments bitwise.
+	BitAndExpr
+	BitOrExpr
+	ShiftLeftExpr
+	ShiftRightExpr
+	// NegExpr negates its single argument, and BitNotExpr inverts its bits.
+	NegExpr
+	BitNotExpr
+	// IsExpr and IsNotExpr compare their two arguments like EqExpr and
+	// NeExpr, but NULL is equal to NULL.
+	IsExpr
+	IsNotExpr
+	// LikeExpr matches its first argument with the pattern in its second
+	// argument, with the operator in the Name of the expression, which is
+	// LIKE, GLOB, REGEXP or MATCH. A third argument is the escape character
+	// of LIKE.
+	LikeExpr
+	// CaseExpr evaluates to the second argument of the first pair of
+	// arguments, whose first argument is true. An argument after the last
+	// pair is the value, if no first argument is true, otherwise it is NULL.
+	CaseExpr
+	// CastExpr converts its single argument to the type with the Name of the
+	// expression.
+	CastExpr
+	// CollateExpr compares its single argument with the collation with the
+	// Name of the expression.
+	CollateExpr
+	// FuncExpr calls the function with the Name of the expression with its
+	// arguments. Distinct is set for an aggregate function, that only
+	// aggregates distinct values. count(*) is count without arguments.
+	FuncExpr
+	// SubqueryExpr is the value of the single column of the first row, that
+	// the Subquery of the expression returns, or NULL if it returns no row.
+	SubqueryExpr
+	// StarExpr stands for all columns of the Relation of its Column in a
+	// Projection, or for all columns, if the relation is empty.
+	StarExpr
+	// RaiseExpr is RAISE in a trigger, which fails the trigger with the
+	// conflict resolution algorithm in the Name of the expression, which is
+	// IGNORE, ROLLBACK, ABORT or FAIL, and the message in its Value.
+	RaiseExpr
+)
+
+// Column references a column of a relation of a logical plan.
+type Column struct {
+	// Relation is the name of the relation, that the scan of the relation
+	// has, or empty if the column is not qualified.
+	Relation string
+	Name     string
+}
+
+func (c Column) String() string {
+	if c.Relation == "" {
+		return c.Name
+	}
+	return c.Relation + "." + c.Name
+}
+
+// Expr is an expression of a logical plan.
+type Expr struct {
+	Op ExprOp
+	// Column is the column of a ColumnExpr.
+	Column Column
+	// Value is the value of a ConstExpr, which is an int64, a float64, a
+	// string, a []byte, a bool, or nil for NULL.
+	Value interface{}
+	Args  []*Expr
+	// Name is the name of the function of a FuncExpr, the operator of a
+	// LikeExpr, the type of a CastExpr, the collation of a CollateExpr, or
+	// the algorithm of a RaiseExpr.
+	Name string
+	// Distinct is set, if the aggregate function of a FuncExpr only
+	// aggregates distinct values.
+	Distinct bool
+	// Subquery is the plan of the subquery of an ExistsExpr or an InExpr.
+	// The plan of an InExpr has a Projection of a single expression as
+	// root.
+	Subquery *Node
+}
+
+// Ref creates an expression, that references the column with the given name
+// of the given relation.
+func Ref(relation, name string) *Expr {
+	return &Expr{Op: ColumnExpr, Column: Column{Relation: relation, Name: name}}
+}
+
+// Const creates a constant expression with the given value, which is an
+// int64, a float64, a string, a []byte, a bool, or nil for NULL.
+func Const(value interface{}) *Expr {
+	return &Expr{Op: ConstExpr, Value: value}
+}
+
+// NewExpr creates an expression with the given operation and arguments.
+func NewExpr(op ExprOp, args ...*Expr) *Expr {
+	return &Expr{Op: op, Args: args}
+}
+
+// Func creates a FuncExpr, that calls the function with the given name with
+// the given arguments.
+func Func(name string, args ...*Expr) *Expr {
+	return &Expr{Op: FuncExpr, Name: name, Args: args}
+}
+
+// Exists creates an ExistsExpr with the given subquery.
+func Exists(subquery *Node) *Expr {
+	return &Expr{Op: ExistsExpr, Subquery: subquery}
+}
+
+// In creates an InExpr, that checks whether the given value is one of the
+// values of the given subquery.
+func In(value *Expr, subquery *Node) *Expr {
+	return &Expr{Op: InExpr, Args: []*Expr{value}, Subquery: subquery}
+}
+
+// IsTrue reports whether the expression is the constant TRUE.
+func (e *Expr) IsTrue() bool {
+	return e != nil && e.Op == ConstExpr && e.Value == true
+}
+
+// String renders the expression in SQL syntax. Subqueries are rendered as
+// (SUBQUERY), and described below their node by (*Node).String.
+func (e *Expr) String() string {
+	switch e.Op {
+	case ColumnExpr:
+		return e.Column.String()
+	case ConstExpr:
+		return formatValue(e.Value)
+	case NotExpr:
+		return "NOT " + e.arg(0)
+	case NegExpr:
+		return "-" + e.arg(0)
+	case BitNotExpr:
+		return "~" + e.arg(0)
+	case IsNullExpr:
+		return e.arg(0) + " IS NULL"
+	case ExistsExpr:
+		return "EXISTS (SUBQUERY)"
+	case InExpr:
+		if e.Subquery == nil {
+			return e.arg(0) + " IN (" + list(e.Args[1:]) + ")"
+		}
+		return e.arg(0) + " IN (SUBQUERY)"
+	case SubqueryExpr:
+		return "(SUBQUERY)"
+	case LikeExpr:
+		s := e.arg(0) + " " + e.Name + " " + e.arg(1)
+		if len(e.Args) > 2 {
+			s += " ESCAPE " + e.arg(2)
+		}
+		return s
+	case CaseExpr:
+		var b strings.Builder
+		b.WriteString("CASE")
+		for i := 0; i+1 < len(e.Args); i += 2 {
+			fmt.Fprintf(&b, " WHEN %v THEN %v", e.Args[i], e.Args[i+1])
+		}
+		if len(e.Args)%2 == 1 {
+			fmt.Fprintf(&b, " ELSE %v", e.Args[len(e.Args)-1])
+		}
+		b.WriteString(" END")
+		return b.String()
+	case CastExpr:
+		return "CAST(" + e.Args[0].String() + " AS " + e.Name + ")"
+	case CollateExpr:
+		return e.arg(0) + " COLLATE " + e.Name
+	case FuncExpr:
+		if len(e.Args) == 0 && strings.EqualFold(e.Name, "count") {
+			return e.Name + "(*)"
+		}
+		if e.Distinct {
+			return e.Name + "(DISTINCT " + list(e.Args) + ")"
+		}
+		return e.Name + "(" + list(e.Args) + ")"
+	case StarExpr:
+		if e.Column.Relation == "" {
+			return "*"
+		}
+		return e.Column.Relation + ".*"
+	case RaiseExpr:
+		if e.Value == nil {
+			return "RAISE(" + e.Name + ")"
+		}
+		return "RAISE(" + e.Name + ", " + formatValue(e.Value) + ")"
+	}
+	if symbol, ok := exprSymbols[e.Op]; ok {
+		args := make([]string, len(e.Args))
+		for i := range e.Args {
+			args[i] = e.arg(i)
+		}
+		return strings.Join(args, " "+symbol+" ")
+	}
+	return e.Op.String()
+}
+
+// arg renders the argument with the given index, in parentheses if it binds
+// less tightly than this expression. Operations are left-associative, so an
+// argument after the first one with the same precedence is put in
+// parentheses too, unless it is the same associative operation.
+func (e *Expr) arg(i int) string {
+	arg := e.Args[i]
+	p, argP := precedence(e.Op), precedence(arg.Op)
+	if argP < p || (i > 0 && argP == p && !(arg.Op == e.Op && associative(e.Op))) {
+		return "(" + arg.String() + ")"
+	}
+	return arg.String()
+}
+
+func associative(op ExprOp) bool {
+	return op == AndExpr || op == OrExpr || op == AddExpr || op == MulExpr || op == ConcatExpr
+}
+
+// list renders the given expressions, separated by commas.
+func list(exprs []*Expr) string {
+	s := make([]string, len(exprs))
+	for i, e := range exprs {
+		s[i] = e.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+var exprSymbols = map[ExprOp]string{
+	EqExpr:  "=",
+	NeExpr:  "!=",
+	LtExpr:  "<",
+	LeExpr:  "<=",
+	GtExpr:  ">",
+	GeExpr:  ">=",
+	AddExpr: "+",
+	SubExpr: "-",
+	MulExpr: "*",
+	DivExpr: "/",
+	AndExpr: "AND",
+	OrExpr:  "OR",
+
+	ModExpr:        "%",
+	ConcatExpr:     "||",
+	BitAndExpr:     "&",
+	BitOrExpr:      "|",
+	ShiftLeftExpr:  "<<",
+	ShiftRightExpr: ">>",
+	IsExpr:         "IS",
+	IsNotExpr:      "IS NOT",
+}
+
+// precedence returns how tightly the given operation binds, like in SQLite.
+func precedence(op ExprOp) int {
+	switch op {
+	case OrExpr:
+		return 1
+	case AndExpr:
+		return 2
+	case NotExpr:
+		return 3
+	case EqExpr, NeExpr, IsExpr, IsNotExpr, IsNullExpr, InExpr, LikeExpr:
+		return 4
+	case LtExpr, LeExpr, GtExpr, GeExpr:
+		return 5
+	case BitAndExpr, BitOrExpr, ShiftLeftExpr, ShiftRightExpr:
+		return 6
+	case AddExpr, SubExpr:
+		return 7
+	case MulExpr, DivExpr, ModExpr:
+		return 8
+	case ConcatExpr:
+		return 9
+	case CollateExpr:
+		return 10
+	}
+	return 11
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		return "X'" + strings.ToUpper(hex.EncodeToString(v)) + "'"
+	}
+	return fmt.Sprint(v)
+}
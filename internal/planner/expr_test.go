@@ -0,0 +1,55 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpr_String(t *testing.T) {
+	a, b, c := Ref("t", "a"), Ref("t", "b"), Ref("t", "c")
+	subquery := &Node{Op: FullScan, Relation: "u"}
+
+	tests := []struct {
+		name string
+		expr *Expr
+		want string
+	}{
+		{"column", a, "t.a"},
+		{"null", Const(nil), "NULL"},
+		{"bool", Const(false), "FALSE"},
+		{"string", Const("it's"), "'it''s'"},
+		{"float", Const(2.5), "2.5"},
+		{"int", Const(int64(-3)), "-3"},
+		{"precedence", NewExpr(MulExpr, NewExpr(AddExpr, a, b), c), "(t.a + t.b) * t.c"},
+		{"no parentheses", NewExpr(AddExpr, a, NewExpr(MulExpr, b, c)), "t.a + t.b * t.c"},
+		{"left associative", NewExpr(SubExpr, a, NewExpr(SubExpr, b, c)), "t.a - (t.b - t.c)"},
+		{"associative", NewExpr(AndExpr, NewExpr(EqExpr, a, b), NewExpr(AndExpr, NewExpr(GtExpr, c, Const(int64(1))), NewExpr(IsNullExpr, a))), "t.a = t.b AND t.c > 1 AND t.a IS NULL"},
+		{"or in and", NewExpr(AndExpr, NewExpr(OrExpr, a, b), c), "(t.a OR t.b) AND t.c"},
+		{"not", NewExpr(NotExpr, NewExpr(EqExpr, a, b)), "NOT t.a = t.b"},
+		{"not or", NewExpr(NotExpr, NewExpr(OrExpr, a, b)), "NOT (t.a OR t.b)"},
+		{"exists", NewExpr(NotExpr, Exists(subquery)), "NOT EXISTS (SUBQUERY)"},
+		{"in", In(a, subquery), "t.a IN (SUBQUERY)"},
+		{"in list", NewExpr(InExpr, a, Const(int64(1)), Const("x")), "t.a IN (1, 'x')"},
+		{"unqualified", Ref("", "a"), "a"},
+		{"blob", Const([]byte{0x0a, 0xff}), "X'0AFF'"},
+		{"concat", NewExpr(ConcatExpr, a, NewExpr(ConcatExpr, b, c)), "t.a || t.b || t.c"},
+		{"comparisons", NewExpr(EqExpr, a, NewExpr(LtExpr, b, c)), "t.a = t.b < t.c"},
+		{"negate", NewExpr(NegExpr, NewExpr(AddExpr, a, b)), "-(t.a + t.b)"},
+		{"is not", NewExpr(IsNotExpr, a, Const(nil)), "t.a IS NOT NULL"},
+		{"like", &Expr{Op: LikeExpr, Name: "LIKE", Args: []*Expr{a, Const("a!%"), Const("!")}}, "t.a LIKE 'a!%' ESCAPE '!'"},
+		{"case", NewExpr(CaseExpr, NewExpr(EqExpr, a, b), Const(int64(1)), Const(int64(0))), "CASE WHEN t.a = t.b THEN 1 ELSE 0 END"},
+		{"cast", &Expr{Op: CastExpr, Name: "INTEGER", Args: []*Expr{NewExpr(AddExpr, a, b)}}, "CAST(t.a + t.b AS INTEGER)"},
+		{"collate", &Expr{Op: CollateExpr, Name: "NOCASE", Args: []*Expr{a}}, "t.a COLLATE NOCASE"},
+		{"raise", &Expr{Op: RaiseExpr, Name: "ABORT", Value: "it's wrong"}, "RAISE(ABORT, 'it''s wrong')"},
+		{"raise ignore", &Expr{Op: RaiseExpr, Name: "IGNORE"}, "RAISE(IGNORE)"},
+		{"count star", Func("count"), "count(*)"},
+		{"distinct", &Expr{Op: FuncExpr, Name: "sum", Args: []*Expr{a}, Distinct: true}, "sum(DISTINCT t.a)"},
+		{"star", &Expr{Op: StarExpr, Column: Column{Relation: "t"}}, "t.*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.expr.String())
+		})
+	}
+}
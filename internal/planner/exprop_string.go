@@ -0,0 +1,58 @@
+// Code generated by "stringer -type=ExprOp ./internal/planner"; DO NOT EDIT.
+
+package planner
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[ColumnExpr-0]
+	_ = x[ConstExpr-1]
+	_ = x[EqExpr-2]
+	_ = x[NeExpr-3]
+	_ = x[LtExpr-4]
+	_ = x[LeExpr-5]
+	_ = x[GtExpr-6]
+	_ = x[GeExpr-7]
+	_ = x[AddExpr-8]
+	_ = x[SubExpr-9]
+	_ = x[MulExpr-10]
+	_ = x[DivExpr-11]
+	_ = x[AndExpr-12]
+	_ = x[OrExpr-13]
+	_ = x[NotExpr-14]
+	_ = x[IsNullExpr-15]
+	_ = x[ExistsExpr-16]
+	_ = x[InExpr-17]
+	_ = x[ModExpr-18]
+	_ = x[ConcatExpr-19]
+	_ = x[BitAndExpr-20]
+	_ = x[BitOrExpr-21]
+	_ = x[ShiftLeftExpr-22]
+	_ = x[ShiftRightExpr-23]
+	_ = x[NegExpr-24]
+	_ = x[BitNotExpr-25]
+	_ = x[IsExpr-26]
+	_ = x[IsNotExpr-27]
+	_ = x[LikeExpr-28]
+	_ = x[CaseExpr-29]
+	_ = x[CastExpr-30]
+	_ = x[CollateExpr-31]
+	_ = x[FuncExpr-32]
+	_ = x[SubqueryExpr-33]
+	_ = x[StarExpr-34]
+	_ = x[RaiseExpr-35]
+}
+
+const _ExprOp_name = "ColumnExprConstExprEqExprNeExprLtExprLeExprGtExprGeExprAddExprSubExprMulExprDivExprAndExprOrExprNotExprIsNullExprExistsExprInExprModExprConcatExprBitAndExprBitOrExprShiftLeftExprShiftRightExprNegExprBitNotExprIsExprIsNotExprLikeExprCaseExprCastExprCollateExprFuncExprSubqueryExprStarExprRaiseExpr"
+
+var _ExprOp_index = [...]uint16{0, 10, 19, 25, 31, 37, 43, 49, 55, 62, 69, 76, 83, 90, 96, 103, 113, 123, 129, 136, 146, 156, 165, 178, 192, 199, 209, 215, 224, 232, 240, 248, 259, 267, 279, 287, 296}
+
+func (i ExprOp) String() string {
+	if i >= ExprOp(len(_ExprOp_index)-1) {
+		return "ExprOp(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _ExprOp_name[_ExprOp_index[i]:_ExprOp_index[i+1]]
+}
@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=JoinType ./internal/planner"; DO NOT EDIT.
+
+package planner
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[InnerJoin-0]
+	_ = x[LeftJoin-1]
+	_ = x[SemiJoin-2]
+	_ = x[AntiJoin-3]
+}
+
+const _JoinType_name = "InnerJoinLeftJoinSemiJoinAntiJoin"
+
+var _JoinType_index = [...]uint8{0, 9, 17, 25, 33}
+
+func (i JoinType) String() string {
+	if i >= JoinType(len(_JoinType_index)-1) {
+		return "JoinType(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _JoinType_name[_JoinType_index[i]:_JoinType_index[i+1]]
+}
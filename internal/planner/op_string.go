@@ -0,0 +1,41 @@
+// Code generated by "stringer -type=Op ./internal/planner"; DO NOT EDIT.
+
+package planner
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[FullScan-0]
+	_ = x[IndexScan-1]
+	_ = x[NestedLoopJoin-2]
+	_ = x[IndexNestedLoopJoin-3]
+	_ = x[HashJoin-4]
+	_ = x[Selection-5]
+	_ = x[Projection-6]
+	_ = x[Limit-7]
+	_ = x[Join-8]
+	_ = x[Sort-9]
+	_ = x[Aggregate-10]
+	_ = x[Distinct-11]
+	_ = x[Values-12]
+	_ = x[Compound-13]
+	_ = x[With-14]
+	_ = x[CommonTable-15]
+	_ = x[Insert-16]
+	_ = x[Update-17]
+	_ = x[Delete-18]
+}
+
+const _Op_name = "FullScanIndexScanNestedLoopJoinIndexNestedLoopJoinHashJoinSelectionProjectionLimitJoinSortAggregateDistinctValuesCompoundWithCommonTableInsertUpdateDelete"
+
+var _Op_index = [...]uint8{0, 8, 17, 31, 50, 58, 67, 77, 82, 86, 90, 99, 107, 113, 121, 125, 136, 142, 148, 154}
+
+func (i Op) String() string {
+	if i >= Op(len(_Op_index)-1) {
+		return "Op(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Op_name[_Op_index[i]:_Op_index[i+1]]
+}
@@ -0,0 +1,198 @@
+package planner
+
+//go:generate stringer -type=Op
+
+// Op is an operation of a plan.
+type Op uint8
+
+// Supported operations.
+const (
+	// FullScan reads all rows of a relation.
+	FullScan Op = iota
+	// IndexScan reads the rows of a relation, whose indexed values match the
+	// filters of the relation.
+	IndexScan
+	// NestedLoopJoin joins every row of the first child with every row of the
+	// second child, which is materialized.
+	NestedLoopJoin
+	// IndexNestedLoopJoin looks up the matching rows of the second child,
+	// which is an IndexScan, for every row of the first child.
+	IndexNestedLoopJoin
+	// HashJoin builds a hash table of the second child, and probes it with
+	// every row of the first child.
+	HashJoin
+
+	// The following operations are logical operations, that describe what a
+	// query computes, but not how. They are rewritten by the optimizer,
+	// before the physical operations above are chosen.
+
+	// Selection passes on the rows of its child, for which the Predicate is
+	// true.
+	Selection
+	// Projection computes the Projections for every row of its child.
+	Projection
+	// Limit passes on at most Count rows of its child, after skipping Offset
+	// rows.
+	Limit
+	// Join joins its two children with the Predicate, as described by the
+	// JoinType.
+	Join
+	// Sort orders the rows of its child by the Order terms.
+	Sort
+	// Aggregate groups the rows of its child by the GroupBy expressions, and
+	// computes the aggregate functions in the Projections for every group.
+	// Without GroupBy expressions, all rows are a single group.
+	Aggregate
+	// Distinct passes on the rows of its child, that are not equal to a row
+	// before them.
+	Distinct
+	// Values produces the Values, which are rows of expressions.
+	Values
+	// Compound combines the rows of its children with the SetOp.
+	Compound
+	// With computes the common table expressions of its first children,
+	// which are CommonTables, once, and returns the rows of its last child,
+	// in which a FullScan of the Relation of a CommonTable reads its rows.
+	With
+	// CommonTable returns the rows of a common table expression, which are
+	// the rows of its first child, whose columns are renamed to the Columns,
+	// unless they are nil. Below a With, the Relation is the name of the
+	// CTE, and its rows are materialized. Elsewhere, the CTE is inlined, and
+	// its Relation and Table are those of the scan, that it replaces. A
+	// recursive CTE has a second child, which is run with every row of the
+	// CTE as the only row of the Relation, and whose rows are added to the
+	// CTE with the SetOp, which is Union or UnionAll, in the Order, and
+	// within the Count and Offset of the recursive SELECT.
+	CommonTable
+
+	// The following operations are the roots of the plans of statements,
+	// that modify a relation. They return no rows.
+
+	// Insert inserts the rows of its child into the Columns of the Relation.
+	// If Columns is nil, the rows have a value for every column. If Columns
+	// is empty, the rows have no values, and every column is set to its
+	// default, like for DEFAULT VALUES.
+	Insert
+	// Update sets the Columns of the rows of the Relation, that its child
+	// returns, to the Projections.
+	Update
+	// Delete deletes the rows of the Relation, that its child returns.
+	Delete
+)
+
+//go:generate stringer -type=SetOp
+
+// SetOp is the set operation of a Compound.
+type SetOp uint8
+
+// Supported set operations.
+const (
+	// UnionAll returns the rows of all children.
+	UnionAll SetOp = iota
+	// Union returns the rows of all children without duplicates.
+	Union
+	// Intersect returns the rows of the first child, that every other child
+	// returns too, without duplicates.
+	Intersect
+	// Except returns the rows of the first child, that no other child
+	// returns, without duplicates.
+	Except
+)
+
+//go:generate stringer -type=JoinType
+
+// JoinType is the type of a logical Join.
+type JoinType uint8
+
+// Supported join types.
+const (
+	// InnerJoin returns the matching pairs of rows of both children.
+	InnerJoin JoinType = iota
+	// LeftJoin returns the matching pairs of rows of both children, and the
+	// rows of the first child without a match, with NULL for the columns of
+	// the second child.
+	LeftJoin
+	// SemiJoin returns the rows of the first child, that match any row of
+	// the second child.
+	SemiJoin
+	// AntiJoin returns the rows of the first child, that match no row of
+	// the second child.
+	AntiJoin
+)
+
+// Node is a node of a plan tree.
+type Node struct {
+	Op Op
+	// Relation is the name of the relation that a scan reads, or that an
+	// Insert, Update or Delete modifies. For a Projection, it is the alias
+	// of a subquery in a FROM clause, whose columns are the result of the
+	// Projection.
+	Relation string
+	// Schema is the schema name, that the Relation is qualified with, or
+	// empty if it is not qualified.
+	Schema string
+	// Table is the name of the table, that a scan reads, if the Relation is
+	// an alias of it, or empty if it is the Relation itself.
+	Table string
+	// Index is the name of the index that an IndexScan uses.
+	Index string
+	// Columns are the columns of the relation, that a scan reads. If nil,
+	// all columns are read. For a Projection, they are the names of the
+	// computed columns, and for an Insert or Update, the written columns.
+	Columns []string
+	// Children are the inputs of a join, or the input of a logical
+	// operation.
+	Children []*Node
+	// Predicate is the condition of a Selection or a Join. A Join without
+	// a predicate joins all rows.
+	Predicate *Expr
+	// Projections are the expressions, that a Projection computes, the
+	// aggregate functions of an Aggregate, or the values of the Columns of
+	// an Update.
+	Projections []*Expr
+	// GroupBy are the expressions, that an Aggregate groups by.
+	GroupBy []*Expr
+	// Order are the terms, that a Sort orders by.
+	Order []OrderTerm
+	// Values are the rows of Values.
+	Values [][]*Expr
+	// JoinType is the type of a Join.
+	JoinType JoinType
+	// SetOp is the set operation of a Compound.
+	SetOp SetOp
+	// Count is the maximum amount of rows of a Limit, or negative for no
+	// maximum. Offset is the amount of rows that a Limit skips.
+	Count, Offset int64
+	// Rows is the estimated amount of rows that the node produces. For the
+	// inner side of an IndexNestedLoopJoin, it is the amount per lookup.
+	Rows float64
+	// Cost is the estimated cost of executing the node, including its
+	// children. For the inner side of an IndexNestedLoopJoin, it is the cost
+	// per lookup.
+	Cost float64
+}
+
+// OrderTerm is a term of a Sort.
+type OrderTerm struct {
+	Expr *Expr
+	Desc bool
+	// NullsFirst sorts NULL before all other values. Like in SQLite, it is
+	// the default for ascending terms.
+	NullsFirst bool
+}
+
+// String renders the term in SQL syntax. NULLS FIRST and NULLS LAST are only
+// rendered, if they are not the default.
+func (t OrderTerm) String() string {
+	s := t.Expr.String()
+	if t.Desc {
+		s += " DESC"
+	}
+	switch {
+	case t.NullsFirst && t.Desc:
+		s += " NULLS FIRST"
+	case !t.NullsFirst && !t.Desc:
+		s += " NULLS LAST"
+	}
+	return s
+}
@@ -0,0 +1,348 @@
+package planner
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// dpLimit is the maximum amount of relations, for which the join order is
+// chosen with dynamic programming.
+const dpLimit = 10
+
+// planner holds the state of planning a single query.
+type planner struct {
+	q Query
+	// filters holds the filters of every relation.
+	filters [][]Filter
+	// rows caches the estimated amount of rows of joins of sets of
+	// relations.
+	rows map[uint64]float64
+}
+
+// candidate is a plan for a set of relations.
+type candidate struct {
+	set  uint64
+	node *Node
+}
+
+// Plan chooses the cheapest plan for the given query.
+func Plan(q Query) (*Node, error) {
+	if len(q.Relations) == 0 {
+		return nil, ErrNoRelations
+	}
+	if len(q.Relations) > 64 {
+		return nil, fmt.Errorf("%w: %d", ErrTooManyRelations, len(q.Relations))
+	}
+
+	p := &planner{
+		q:       q,
+		filters: make([][]Filter, len(q.Relations)),
+		rows:    make(map[uint64]float64),
+	}
+	for _, f := range q.Filters {
+		if !p.valid(f.Column) {
+			return nil, fmt.Errorf("%w: filter on %+v", ErrInvalidReference, f.Column)
+		}
+		p.filters[f.Column.Relation] = append(p.filters[f.Column.Relation], f)
+	}
+	for _, j := range q.Joins {
+		if !p.valid(j.Left) || !p.valid(j.Right) || j.Left.Relation == j.Right.Relation {
+			return nil, fmt.Errorf("%w: join of %+v and %+v", ErrInvalidReference, j.Left, j.Right)
+		}
+	}
+
+	base := make([]candidate, len(q.Relations))
+	for i := range q.Relations {
+		base[i] = candidate{
+			set:  1 << uint(i),
+			node: p.accessPath(i),
+		}
+	}
+
+	if len(base) <= dpLimit {
+		return p.dynamic(base).node, nil
+	}
+	return p.greedy(base).node, nil
+}
+
+func (p *planner) valid(ref ColumnRef) bool {
+	return ref.Relation >= 0 && ref.Relation < len(p.q.Relations) && ref.Column >= 0
+}
+
+// accessPath returns the cheapest way to read the given relation.
+func (p *planner) accessPath(rel int) *Node {
+	r := p.q.Relations[rel]
+	base := baseRows(r)
+	rows := p.setRows(1 << uint(rel))
+
+	best := &Node{
+		Op:       FullScan,
+		Relation: r.Name,
+		Rows:     rows,
+		Cost:     base*seqRowCost + base*float64(len(p.filters[rel]))*cpuRowCost,
+	}
+	for _, ix := range r.Indexes {
+		matches, ok := p.indexMatches(rel, ix)
+		if !ok {
+			continue
+		}
+		if cost := lookupCost(base, matches); cost < best.Cost {
+			best = &Node{
+				Op:       IndexScan,
+				Relation: r.Name,
+				Index:    ix.Name,
+				Rows:     rows,
+				Cost:     cost,
+			}
+		}
+	}
+	return best
+}
+
+// indexMatches estimates the amount of rows of the given relation, that are
+// found by looking up the filters of the relation in the given index. The
+// index can be used, if there are equality filters on its first columns,
+// optionally followed by a range filter.
+func (p *planner) indexMatches(rel int, ix Index) (float64, bool) {
+	r := p.q.Relations[rel]
+	matches := baseRows(r)
+
+	equal := 0
+	for _, col := range ix.Columns {
+		f, ok := p.filter(rel, col, Equal)
+		if !ok {
+			break
+		}
+		matches *= filterSelectivity(r, f)
+		equal++
+	}
+	if equal > 0 && ix.Stats != nil && equal <= len(ix.Stats.RowsPerKey) {
+		matches = math.Min(matches, ix.Stats.RowsPerKey[equal-1])
+	}
+	if equal > 0 && equal == len(ix.Columns) && ix.Unique {
+		matches = math.Min(matches, 1)
+	}
+
+	if equal < len(ix.Columns) {
+		if f, ok := p.filter(rel, ix.Columns[equal], Range); ok {
+			matches *= filterSelectivity(r, f)
+			return matches, true
+		}
+	}
+	return matches, equal > 0
+}
+
+// filter returns a filter with the given operation on the given column.
+func (p *planner) filter(rel, col int, op FilterOp) (Filter, bool) {
+	for _, f := range p.filters[rel] {
+		if f.Column.Column == col && f.Op == op {
+			return f, true
+		}
+	}
+	return Filter{}, false
+}
+
+// setRows estimates the amount of rows of the join of the given set of
+// relations, after all filters and join conditions have been applied.
+func (p *planner) setRows(set uint64) float64 {
+	if rows, ok := p.rows[set]; ok {
+		return rows
+	}
+
+	rows := 1.0
+	for rel := range p.q.Relations {
+		if set&(1<<uint(rel)) == 0 {
+			continue
+		}
+		r := p.q.Relations[rel]
+		rows *= baseRows(r)
+		for _, f := range p.filters[rel] {
+			rows *= filterSelectivity(r, f)
+		}
+	}
+	for _, j := range p.q.Joins {
+		if set&(1<<uint(j.Left.Relation)) != 0 && set&(1<<uint(j.Right.Relation)) != 0 {
+			rows *= joinSelectivity(p.q.Relations, j)
+		}
+	}
+	p.rows[set] = rows
+	return rows
+}
+
+// conditions returns the join conditions between the given sets of
+// relations, oriented so that Left is in the outer set.
+func (p *planner) conditions(outer, inner uint64) []JoinCondition {
+	var conds []JoinCondition
+	for _, j := range p.q.Joins {
+		l, r := uint64(1)<<uint(j.Left.Relation), uint64(1)<<uint(j.Right.Relation)
+		switch {
+		case outer&l != 0 && inner&r != 0:
+			conds = append(conds, j)
+		case outer&r != 0 && inner&l != 0:
+			conds = append(conds, JoinCondition{Left: j.Right, Right: j.Left})
+		}
+	}
+	return conds
+}
+
+// join returns the cheapest plan that joins the given outer and inner plan.
+func (p *planner) join(outer, inner candidate) candidate {
+	set := outer.set | inner.set
+	rows := p.setRows(set)
+	conds := p.conditions(outer.set, inner.set)
+	o, i := outer.node, inner.node
+
+	best := &Node{
+		Op:       NestedLoopJoin,
+		Children: []*Node{o, i},
+		Rows:     rows,
+		Cost:     o.Cost + i.Cost + o.Rows*i.Rows*cpuRowCost,
+	}
+	if len(conds) > 0 {
+		// the inner side is the build side of the hash table
+		if cost := o.Cost + i.Cost + o.Rows*hashProbeRowCost + i.Rows*hashBuildRowCost; cost < best.Cost {
+			best = &Node{
+				Op:       HashJoin,
+				Children: []*Node{o, i},
+				Rows:     rows,
+				Cost:     cost,
+			}
+		}
+		if lookup, ok := p.indexLookup(inner.set, conds); ok {
+			if cost := o.Cost + o.Rows*lookup.Cost; cost < best.Cost {
+				best = &Node{
+					Op:       IndexNestedLoopJoin,
+					Children: []*Node{o, lookup},
+					Rows:     rows,
+					Cost:     cost,
+				}
+			}
+		}
+	}
+	return candidate{set, best}
+}
+
+// indexLookup returns an IndexScan of the given inner set, which must be a
+// single relation, that looks up the rows that match a single row of the
+// outer side. The first column of the index must be joined with the outer
+// side.
+func (p *planner) indexLookup(inner uint64, conds []JoinCondition) (*Node, bool) {
+	if bits.OnesCount64(inner) != 1 {
+		return nil, false
+	}
+	rel := bits.TrailingZeros64(inner)
+	r := p.q.Relations[rel]
+	base := baseRows(r)
+
+	var best *Node
+	for _, ix := range r.Indexes {
+		if len(ix.Columns) == 0 {
+			continue
+		}
+		joined := false
+		for _, c := range conds {
+			if c.Right.Column == ix.Columns[0] {
+				joined = true
+			}
+		}
+		if !joined {
+			continue
+		}
+
+		matches := base / distinct(r, ix.Columns[0])
+		if ix.Stats != nil && len(ix.Stats.RowsPerKey) > 0 {
+			matches = ix.Stats.RowsPerKey[0]
+		}
+		if ix.Unique && len(ix.Columns) == 1 {
+			matches = math.Min(matches, 1)
+		}
+		cost := lookupCost(base, matches)
+		if best == nil || cost < best.Cost {
+			rows := matches
+			for _, f := range p.filters[rel] {
+				rows *= filterSelectivity(r, f)
+			}
+			best = &Node{
+				Op:       IndexScan,
+				Relation: r.Name,
+				Index:    ix.Name,
+				Rows:     rows,
+				Cost:     cost,
+			}
+		}
+	}
+	return best, best != nil
+}
+
+// connected returns whether there is a join condition between the given sets.
+func (p *planner) connected(a, b uint64) bool {
+	return len(p.conditions(a, b)) > 0
+}
+
+// dynamic finds the cheapest plan by computing the cheapest plan of every
+// subset of the relations from the cheapest plans of its partitions. Cross
+// products are only considered for sets that cannot be partitioned into
+// joined sets.
+func (p *planner) dynamic(base []candidate) candidate {
+	best := make(map[uint64]candidate)
+	for _, c := range base {
+		best[c.set] = c
+	}
+
+	all := uint64(1)<<uint(len(base)) - 1
+	for size := 2; size <= len(base); size++ {
+		for set := uint64(1); set <= all; set++ {
+			if bits.OnesCount64(set) != size {
+				continue
+			}
+			for _, crossProducts := range []bool{false, true} {
+				// enumerate all non-empty proper subsets as the outer side
+				for outer := (set - 1) & set; outer > 0; outer = (outer - 1) & set {
+					inner := set &^ outer
+					if !crossProducts && !p.connected(outer, inner) {
+						continue
+					}
+					c := p.join(best[outer], best[inner])
+					if current, ok := best[set]; !ok || c.node.Cost < current.node.Cost {
+						best[set] = c
+					}
+				}
+				if _, ok := best[set]; ok {
+					break
+				}
+			}
+		}
+	}
+	return best[all]
+}
+
+// greedy finds a plan by repeatedly joining the two plans, that are the
+// cheapest to join. Joined plans are preferred over cross products.
+func (p *planner) greedy(plans []candidate) candidate {
+	plans = append([]candidate{}, plans...)
+	for len(plans) > 1 {
+		var best candidate
+		bestI, bestJ := -1, -1
+		bestConnected := false
+		for i := range plans {
+			for j := range plans {
+				if i == j {
+					continue
+				}
+				connected := p.connected(plans[i].set, plans[j].set)
+				if bestConnected && !connected {
+					continue
+				}
+				c := p.join(plans[i], plans[j])
+				if bestI < 0 || (connected && !bestConnected) || c.node.Cost < best.node.Cost {
+					best, bestI, bestJ, bestConnected = c, i, j, connected
+				}
+			}
+		}
+
+		plans[bestI] = best
+		plans = append(plans[:bestJ], plans[bestJ+1:]...)
+	}
+	return plans[0]
+}
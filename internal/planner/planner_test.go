@@ -0,0 +1,289 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tomarrell/lbadd/internal/database/stats"
+)
+
+// table returns the statistics of a table with the given amount of rows and
+// the given amount of distinct values in every column.
+func table(rows int64, distinct ...int64) *stats.Table {
+	t := &stats.Table{Rows: rows}
+	for _, d := range distinct {
+		t.Columns = append(t.Columns, stats.Column{
+			Distinct: d,
+			Histogram: stats.Histogram{
+				{Upper: []byte("\xff"), Count: rows, Distinct: d},
+			},
+		})
+	}
+	return t
+}
+
+// relations returns the names of all relations in the plan, in the order in
+// which they are read.
+func relations(n *Node) []string {
+	if len(n.Children) == 0 {
+		return []string{n.Relation}
+	}
+	var names []string
+	for _, child := range n.Children {
+		names = append(names, relations(child)...)
+	}
+	return names
+}
+
+func TestPlan_AccessPath(t *testing.T) {
+	users := Relation{
+		Name:  "users",
+		Stats: table(100000, 100000, 2),
+		Indexes: []Index{
+			{Name: "users_id", Columns: []int{0}, Unique: true},
+			{Name: "users_active", Columns: []int{1}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		filters []Filter
+		op      Op
+		index   string
+		rows    float64
+	}{
+		{"no filter", nil, FullScan, "", 100000},
+		{"unique", []Filter{{Column: ColumnRef{0, 0}, Op: Equal}}, IndexScan, "users_id", 1},
+		{"range", []Filter{{Column: ColumnRef{0, 0}, Op: Range}}, FullScan, "", 100000.0 / 3},
+		{"not selective", []Filter{{Column: ColumnRef{0, 1}, Op: Equal}}, FullScan, "", 50000},
+		{"other", []Filter{{Column: ColumnRef{0, 0}, Op: Other}}, FullScan, "", 50000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Plan(Query{Relations: []Relation{users}, Filters: tt.filters})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.op, plan.Op)
+			assert.Equal(t, tt.index, plan.Index)
+			assert.InDelta(t, tt.rows, plan.Rows, 1e-6)
+		})
+	}
+}
+
+func TestPlan_AccessPath_IndexStats(t *testing.T) {
+	// without statistics, the index looks selective, but the statistics of
+	// the index show that every key has many rows
+	rel := Relation{
+		Name:    "events",
+		Indexes: []Index{{Name: "events_type", Columns: []int{0}}},
+	}
+	q := Query{
+		Relations: []Relation{rel},
+		Filters:   []Filter{{Column: ColumnRef{0, 0}, Op: Equal}},
+	}
+
+	plan, err := Plan(q)
+	assert.NoError(t, err)
+	assert.Equal(t, IndexScan, plan.Op)
+
+	q.Relations[0].Indexes[0].Stats = &stats.Index{Rows: 1000, RowsPerKey: []float64{500}}
+	q.Relations[0].Stats = table(1000, 2)
+	plan, err = Plan(q)
+	assert.NoError(t, err)
+	assert.Equal(t, FullScan, plan.Op)
+}
+
+func TestPlan_IndexNestedLoopJoin(t *testing.T) {
+	q := Query{
+		Relations: []Relation{
+			{
+				Name:    "orders",
+				Stats:   table(1000000, 1000000, 10000),
+				Indexes: []Index{{Name: "orders_customer", Columns: []int{1}}},
+			},
+			{
+				Name:  "customers",
+				Stats: table(10000, 10000, 100),
+			},
+		},
+		Filters: []Filter{{Column: ColumnRef{1, 1}, Op: Equal}},
+		Joins:   []JoinCondition{{Left: ColumnRef{0, 1}, Right: ColumnRef{1, 0}}},
+	}
+
+	plan, err := Plan(q)
+	assert.NoError(t, err)
+	assert.Equal(t, IndexNestedLoopJoin, plan.Op)
+	assert.Equal(t, []string{"customers", "orders"}, relations(plan))
+	assert.Equal(t, "orders_customer", plan.Children[1].Index)
+	assert.InDelta(t, 100, plan.Children[1].Rows, 1e-6, "rows per lookup")
+	assert.InDelta(t, 10000, plan.Rows, 1e-6)
+}
+
+func TestPlan_HashJoin(t *testing.T) {
+	q := Query{
+		Relations: []Relation{
+			{Name: "big", Stats: table(100000, 1000)},
+			{Name: "small", Stats: table(1000, 1000)},
+		},
+		Joins: []JoinCondition{{Left: ColumnRef{0, 0}, Right: ColumnRef{1, 0}}},
+	}
+
+	plan, err := Plan(q)
+	assert.NoError(t, err)
+	assert.Equal(t, HashJoin, plan.Op)
+	assert.Equal(t, []string{"big", "small"}, relations(plan), "the smaller relation must be the build side")
+	assert.InDelta(t, 100000, plan.Rows, 1e-6)
+}
+
+func TestPlan_NestedLoopJoin(t *testing.T) {
+	q := Query{
+		Relations: []Relation{
+			{Name: "a", Stats: table(10)},
+			{Name: "b", Stats: table(20)},
+		},
+	}
+
+	plan, err := Plan(q)
+	assert.NoError(t, err)
+	assert.Equal(t, NestedLoopJoin, plan.Op)
+	assert.InDelta(t, 200, plan.Rows, 1e-6)
+}
+
+// chain returns a query that joins n relations in a chain, where the size of
+// the relations alternates.
+func chain(n int) Query {
+	var q Query
+	for i := 0; i < n; i++ {
+		rows := int64(1000 * (1 + i%3))
+		q.Relations = append(q.Relations, Relation{
+			Name:    fmt.Sprintf("r%02d", i),
+			Stats:   table(rows, rows, rows/10),
+			Indexes: []Index{{Name: fmt.Sprintf("r%02d_pk", i), Columns: []int{0}, Unique: true}},
+		})
+		if i > 0 {
+			q.Joins = append(q.Joins, JoinCondition{
+				Left:  ColumnRef{i - 1, 1},
+				Right: ColumnRef{i, 0},
+			})
+		}
+	}
+	q.Filters = []Filter{{Column: ColumnRef{n - 1, 1}, Op: Equal}}
+	return q
+}
+
+// assertNoCrossProducts asserts that every join in the plan has a join
+// condition between its children.
+func assertNoCrossProducts(t *testing.T, q Query, n *Node) {
+	t.Helper()
+	if len(n.Children) == 0 {
+		return
+	}
+	assert.NotEqual(t, NestedLoopJoin, n.Op, "plan must not contain cross products")
+
+	index := make(map[string]int)
+	for i, r := range q.Relations {
+		index[r.Name] = i
+	}
+	set := func(n *Node) uint64 {
+		var s uint64
+		for _, name := range relations(n) {
+			s |= 1 << uint(index[name])
+		}
+		return s
+	}
+	p := &planner{q: q}
+	assert.True(t, p.connected(set(n.Children[0]), set(n.Children[1])))
+
+	for _, child := range n.Children {
+		assertNoCrossProducts(t, q, child)
+	}
+}
+
+func newTestPlanner(q Query) *planner {
+	p := &planner{
+		q:       q,
+		filters: make([][]Filter, len(q.Relations)),
+		rows:    make(map[uint64]float64),
+	}
+	for _, f := range q.Filters {
+		p.filters[f.Column.Relation] = append(p.filters[f.Column.Relation], f)
+	}
+	return p
+}
+
+func TestPlan_JoinOrder(t *testing.T) {
+	for _, n := range []int{3, dpLimit, dpLimit + 5} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			q := chain(n)
+			plan, err := Plan(q)
+			assert.NoError(t, err)
+
+			names := relations(plan)
+			sort.Strings(names)
+			var want []string
+			for _, r := range q.Relations {
+				want = append(want, r.Name)
+			}
+			assert.Equal(t, want, names)
+			assertNoCrossProducts(t, q, plan)
+
+			// the plan must be cheaper than joining the relations in the
+			// order of the query
+			p := newTestPlanner(q)
+			naive := candidate{1, p.accessPath(0)}
+			for i := 1; i < n; i++ {
+				naive = p.join(naive, candidate{1 << uint(i), p.accessPath(i)})
+			}
+			assert.Less(t, plan.Cost, naive.node.Cost)
+		})
+	}
+}
+
+func TestPlan_GreedyIsNotBetterThanDynamic(t *testing.T) {
+	q := chain(8)
+	p := newTestPlanner(q)
+	var base []candidate
+	for i := range q.Relations {
+		base = append(base, candidate{1 << uint(i), p.accessPath(i)})
+	}
+
+	dynamic := p.dynamic(base)
+	greedy := p.greedy(base)
+	assert.LessOrEqual(t, dynamic.node.Cost, greedy.node.Cost)
+}
+
+func TestPlan_Error(t *testing.T) {
+	_, err := Plan(Query{})
+	assert.Equal(t, ErrNoRelations, err)
+
+	_, err = Plan(Query{Relations: make([]Relation, 65)})
+	assert.True(t, errors.Is(err, ErrTooManyRelations))
+
+	rels := []Relation{{Name: "a"}, {Name: "b"}}
+	_, err = Plan(Query{Relations: rels, Filters: []Filter{{Column: ColumnRef{2, 0}}}})
+	assert.True(t, errors.Is(err, ErrInvalidReference))
+	_, err = Plan(Query{Relations: rels, Joins: []JoinCondition{{Left: ColumnRef{0, 0}, Right: ColumnRef{0, 1}}}})
+	assert.True(t, errors.Is(err, ErrInvalidReference))
+}
+
+func TestMaterialize(t *testing.T) {
+	tests := []struct {
+		cte  CTE
+		want bool
+	}{
+		{CTE{References: 1}, false},
+		{CTE{References: 2}, true},
+		{CTE{References: 1, Volatile: true}, true},
+		{CTE{References: 1, Recursive: true}, true},
+		{CTE{References: 1, Recursive: true, Hint: NotMaterialized}, true},
+		{CTE{References: 1, Hint: Materialized}, true},
+		{CTE{References: 3, Hint: NotMaterialized}, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%+v", tt.cte), func(t *testing.T) {
+			assert.Equal(t, tt.want, Materialize(tt.cte))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package planner
+
+import "github.com/tomarrell/lbadd/internal/database/stats"
+
+// Query describes the relations that a query reads, and how they are filtered
+// and joined.
+type Query struct {
+	Relations []Relation
+	// Filters are predicates on a single column of a single relation.
+	Filters []Filter
+	// Joins are equality conditions between columns of two relations.
+	Joins []JoinCondition
+}
+
+// Relation is a table that a query reads.
+type Relation struct {
+	Name string
+	// Stats holds the statistics of the table, or nil if it has not been
+	// analyzed.
+	Stats   *stats.Table
+	Indexes []Index
+}
+
+// Index is an index on a relation, that can be used to access it.
+type Index struct {
+	Name string
+	// Columns holds the positions of the indexed columns in the relation.
+	Columns []int
+	Unique  bool
+	// Stats holds the statistics of the index, or nil if it has not been
+	// analyzed.
+	Stats *stats.Index
+}
+
+// ColumnRef references a column of a relation of a query.
+type ColumnRef struct {
+	// Relation is the position of the relation in the query.
+	Relation int
+	// Column is the position of the column in the relation.
+	Column int
+}
+
+// FilterOp is the kind of comparison of a filter.
+type FilterOp uint8
+
+// Supported filter operations.
+const (
+	// Equal compares the column with a single value.
+	Equal FilterOp = iota
+	// Range compares the column with a lower and an upper bound.
+	Range
+	// IsNull checks whether the column is NULL.
+	IsNull
+	// Other is any other predicate on the column, which cannot be used to
+	// access an index.
+	Other
+)
+
+// Filter is a predicate on a single column.
+type Filter struct {
+	Column ColumnRef
+	Op     FilterOp
+	// Value is the value that the column is compared with by Equal, or nil if
+	// it is not known while planning, e.g. because it is a parameter.
+	Value []byte
+	// Low and High are the bounds of Range, nil if the range is unbounded in
+	// that direction. If both are nil, the bounds are not known.
+	Low, High []byte
+}
+
+// JoinCondition is an equality condition between two columns of two different
+// relations.
+type JoinCondition struct {
+	Left, Right ColumnRef
+}
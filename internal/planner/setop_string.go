@@ -0,0 +1,26 @@
+// Code generated by "stringer -type=SetOp ./internal/planner"; DO NOT EDIT.
+
+package planner
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[UnionAll-0]
+	_ = x[Union-1]
+	_ = x[Intersect-2]
+	_ = x[Except-3]
+}
+
+const _SetOp_name = "UnionAllUnionIntersectExcept"
+
+var _SetOp_index = [...]uint8{0, 8, 13, 22, 28}
+
+func (i SetOp) String() string {
+	if i >= SetOp(len(_SetOp_index)-1) {
+		return "SetOp(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _SetOp_name[_SetOp_index[i]:_SetOp_index[i+1]]
+}